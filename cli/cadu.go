@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
 	"github.com/ravisuhag/astro/pkg/tmsc"
@@ -38,17 +39,21 @@ func caduWrapCmd() *cobra.Command {
 		inputFmt  string
 		outputFmt string
 		randomize bool
+		coding    caduCoding
 	)
 
 	cmd := &cobra.Command{
 		Use:   "wrap [file]",
 		Short: "Wrap a TM frame into a CADU",
-		Long:  "Prepend the Attached Sync Marker and optionally apply CCSDS pseudo-randomization to produce a CADU.",
+		Long:  "Optionally Reed-Solomon encode and pseudo-randomize a TM frame, prepend the Attached Sync Marker, and optionally convolutionally encode the result to produce a CADU.",
 		Example: `  # Wrap a TM frame (hex input)
   astro tm encode --scid 26 --vcid 1 --data 0102030405 | astro cadu wrap --input hex
 
   # Wrap with randomization
-  astro tm encode --scid 26 --vcid 1 --data 0102030405 | astro cadu wrap --input hex --randomize`,
+  astro tm encode --scid 26 --vcid 1 --data 0102030405 | astro cadu wrap --input hex --randomize
+
+  # RS(255,223) with interleaving depth 5 and a rate 1/2 inner code
+  astro cadu wrap --input bin --randomize --rs 16 --interleave 5 --conv 1/2 frame.bin`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			data, err := readInput(args, inputFmt)
@@ -56,23 +61,35 @@ func caduWrapCmd() *cobra.Command {
 				return err
 			}
 
-			cadu := tmsc.WrapCADU(data, nil, randomize)
+			p, err := coding.profile(len(data), randomize)
+			if err != nil {
+				return err
+			}
+			cadu, err := p.Encode(data)
+			if err != nil {
+				return fmt.Errorf("wrapping CADU: %w", err)
+			}
+			cadu = append(cadu, p.FlushEncoder()...)
 
 			switch outputFmt {
 			case "hex":
 				fmt.Println(hex.EncodeToString(cadu))
 			case "json":
-				j := caduToJSON(cadu, randomize)
+				j := caduToJSON(cadu, p)
 				b, err := json.MarshalIndent(j, "", "  ")
 				if err != nil {
 					return err
 				}
 				fmt.Println(string(b))
 			case "text":
-				fmt.Printf("CADU (%d bytes)\n", len(cadu))
-				fmt.Printf("  ASM: %s\n", hex.EncodeToString(cadu[:4]))
-				fmt.Printf("  Frame Data: %d bytes\n", len(cadu)-4)
+				fmt.Printf("CADU (%d bytes)\n", p.CADULen())
+				fmt.Printf("  ASM: %s\n", hex.EncodeToString(p.ASM()))
+				fmt.Printf("  Frame Data: %d bytes\n", p.CADULen()-len(p.ASM()))
 				fmt.Printf("  Randomized: %v\n", randomize)
+				printCADUCoding(p)
+				if rate, ok := p.Convolutional(); ok {
+					fmt.Printf("  Channel Symbols: %d bytes (rate %s)\n", len(cadu), rate)
+				}
 			default:
 				return fmt.Errorf("unknown format: %s", outputFmt)
 			}
@@ -83,6 +100,7 @@ func caduWrapCmd() *cobra.Command {
 	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
 	cmd.Flags().StringVar(&outputFmt, "format", "hex", "Output format: text, json, or hex")
 	cmd.Flags().BoolVar(&randomize, "randomize", false, "Apply CCSDS pseudo-randomization")
+	coding.addFlags(cmd, true)
 
 	return cmd
 }
@@ -92,17 +110,21 @@ func caduUnwrapCmd() *cobra.Command {
 		inputFmt    string
 		outputFmt   string
 		derandomize bool
+		coding      caduCoding
 	)
 
 	cmd := &cobra.Command{
 		Use:   "unwrap [file]",
 		Short: "Unwrap a CADU to extract the TM frame",
-		Long:  "Strip the Attached Sync Marker and optionally de-randomize to extract the TM Transfer Frame data.",
+		Long:  "Optionally convolutionally decode, strip the Attached Sync Marker, and optionally de-randomize and Reed-Solomon decode to extract the TM Transfer Frame data.",
 		Example: `  # Unwrap a CADU
   astro cadu unwrap --input hex cadu.hex
 
   # Unwrap with de-randomization
-  cat cadu.hex | astro cadu unwrap --input hex --derandomize`,
+  cat cadu.hex | astro cadu unwrap --input hex --derandomize
+
+  # Decode RS(255,223) with interleaving depth 5 and a rate 1/2 inner code
+  astro cadu unwrap --input bin --derandomize --rs 16 --interleave 5 --conv 1/2 symbols.bin`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			data, err := readInput(args, inputFmt)
@@ -110,10 +132,29 @@ func caduUnwrapCmd() *cobra.Command {
 				return err
 			}
 
-			frame, err := tmsc.UnwrapCADU(data, nil, derandomize)
+			frameLen, err := coding.frameLen(len(data))
+			if err != nil {
+				return err
+			}
+			if frameLen <= 0 {
+				return fmt.Errorf("unwrapping CADU: %w", tmsc.ErrDataTooShort)
+			}
+			p, err := coding.profile(frameLen, derandomize)
 			if err != nil {
 				return fmt.Errorf("unwrapping CADU: %w", err)
 			}
+			results := append(p.DecodeHard(data), p.FlushDecoder()...)
+			if len(results) == 0 {
+				return fmt.Errorf("unwrapping CADU: %w", tmsc.ErrDataTooShort)
+			}
+			if len(results) > 1 {
+				return fmt.Errorf("unwrapping CADU: input holds %d CADUs; use astro cadu sync for a stream", len(results))
+			}
+			r := results[0]
+			if r.ASMErrors > 0 && !coding.coded() {
+				return fmt.Errorf("unwrapping CADU: %w", tmsc.ErrSyncMarkerMismatch)
+			}
+			frame := r.Frame
 
 			switch outputFmt {
 			case "hex":
@@ -124,6 +165,16 @@ func caduUnwrapCmd() *cobra.Command {
 					"frame_bytes":  len(frame),
 					"derandomized": derandomize,
 				}
+				if coding.coded() {
+					j["asm_errors"] = r.ASMErrors
+				}
+				if coding.rs != 0 {
+					j["rs_corrected"] = r.Corrected
+					j["rs_uncorrectable"] = r.Uncorrectable
+				}
+				if coding.conv != "none" {
+					j["path_metric"] = r.PathMetric
+				}
 				b, err := json.MarshalIndent(j, "", "  ")
 				if err != nil {
 					return err
@@ -132,10 +183,21 @@ func caduUnwrapCmd() *cobra.Command {
 			case "text":
 				fmt.Printf("Extracted Frame (%d bytes)\n", len(frame))
 				fmt.Printf("  Derandomized: %v\n", derandomize)
+				printCADUCoding(p)
+				if r.ASMErrors > 0 {
+					fmt.Printf("  ASM Errors: %d bits\n", r.ASMErrors)
+				}
+				if r.PathMetric > 0 {
+					fmt.Printf("  Path Metric: %d\n", r.PathMetric)
+				}
+				printCADUCorrections(r)
 				fmt.Print(hexDump(frame, "  "))
 			default:
 				return fmt.Errorf("unknown format: %s", outputFmt)
 			}
+			if !r.OK() {
+				return fmt.Errorf("unwrapping CADU: %w", tmsc.ErrUncorrectable)
+			}
 			return nil
 		},
 	}
@@ -143,6 +205,7 @@ func caduUnwrapCmd() *cobra.Command {
 	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
 	cmd.Flags().StringVar(&outputFmt, "format", "hex", "Output format: text, json, or hex")
 	cmd.Flags().BoolVar(&derandomize, "derandomize", false, "Apply CCSDS de-randomization")
+	coding.addFlags(cmd, true)
 
 	return cmd
 }
@@ -175,20 +238,33 @@ func caduInspectCmd() *cobra.Command {
 
 func caduSyncCmd() *cobra.Command {
 	var (
-		inputFmt  string
-		outputFmt string
-		frameLen  int
+		inputFmt     string
+		outputFmt    string
+		frameLen     int
+		searchErrors int
+		lockErrors   int
+		checkFrames  int
+		flywheel     int
+		inverted     bool
+		derandomize  bool
+		coding       caduCoding
 	)
 
 	cmd := &cobra.Command{
 		Use:   "sync [file]",
-		Short: "Scan a byte stream for ASM markers and extract CADUs",
-		Long:  "Scan a raw byte stream for CCSDS Attached Sync Markers (0x1ACFFC1D), extract aligned CADUs of the given frame length.",
+		Short: "Scan a bitstream for ASM markers and extract CADUs",
+		Long:  "Scan a raw bitstream for CCSDS Attached Sync Markers (0x1ACFFC1D) at any bit offset, tolerating bit errors and inverted polarity, and extract aligned CADUs of the given frame length.",
 		Example: `  # Sync and extract CADUs from binary stream
   astro cadu sync --input bin --frame-len 1115 capture.bin
 
   # Sync from hex with JSON output
-  astro cadu sync --input hex --frame-len 17 stream.hex --format json`,
+  astro cadu sync --input hex --frame-len 17 stream.hex --format json
+
+  # Tolerate marker errors in a noisy capture
+  astro cadu sync --input bin --frame-len 1115 --search-errors 2 --lock-errors 6 capture.bin
+
+  # Sync and RS decode each CADU
+  astro cadu sync --input bin --frame-len 1279 --derandomize --rs 16 --interleave 5 capture.bin`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if frameLen <= 0 {
@@ -200,13 +276,42 @@ func caduSyncCmd() *cobra.Command {
 				return err
 			}
 
-			return syncCADUs(data, frameLen, outputFmt)
+			asm, err := coding.marker()
+			if err != nil {
+				return err
+			}
+			var p *tmsc.CodingProfile
+			if derandomize || coding.rs != 0 {
+				if p, err = coding.profile(frameLen-len(asm)-coding.parityLen(), derandomize); err != nil {
+					return fmt.Errorf("--frame-len does not match the coding: %w", err)
+				}
+			}
+
+			sync, err := tmsc.NewSynchronizer(bytes.NewReader(data), frameLen,
+				tmsc.WithSyncASM(asm),
+				tmsc.WithSearchErrors(searchErrors),
+				tmsc.WithLockErrors(lockErrors),
+				tmsc.WithCheckFrames(checkFrames),
+				tmsc.WithFlywheelFrames(flywheel),
+				tmsc.WithPolarityDetection(inverted))
+			if err != nil {
+				return fmt.Errorf("--frame-len must exceed the ASM length: %w", err)
+			}
+
+			return syncCADUs(sync, len(asm), p, len(data), outputFmt)
 		},
 	}
 
 	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
 	cmd.Flags().StringVar(&outputFmt, "format", "text", "Output format: text, json, or hex")
 	cmd.Flags().IntVar(&frameLen, "frame-len", 0, "Total CADU length in bytes including ASM (required)")
+	cmd.Flags().IntVar(&searchErrors, "search-errors", 0, "ASM bit errors tolerated while searching")
+	cmd.Flags().IntVar(&lockErrors, "lock-errors", 4, "ASM bit errors tolerated once acquired")
+	cmd.Flags().IntVar(&checkFrames, "check-frames", 1, "Markers to confirm before lock")
+	cmd.Flags().IntVar(&flywheel, "flywheel-frames", 3, "Consecutive missed markers tolerated in lock")
+	cmd.Flags().BoolVar(&inverted, "detect-inverted", true, "Also search for the inverted ASM")
+	cmd.Flags().BoolVar(&derandomize, "derandomize", false, "Apply CCSDS de-randomization to each CADU")
+	coding.addFlags(cmd, false)
 
 	_ = cmd.MarkFlagRequired("frame-len")
 
@@ -214,18 +319,155 @@ func caduSyncCmd() *cobra.Command {
 }
 
 type caduJSON struct {
-	ASM       string `json:"asm"`
-	FrameData string `json:"frame_data"`
-	TotalLen  int    `json:"total_bytes"`
-	Randomize bool   `json:"randomized"`
+	ASM            string `json:"asm"`
+	FrameData      string `json:"frame_data,omitempty"`
+	TotalLen       int    `json:"total_bytes"`
+	Randomize      bool   `json:"randomized"`
+	RSE            int    `json:"rs_e,omitempty"`
+	Interleave     int    `json:"interleave,omitempty"`
+	VirtualFill    int    `json:"virtual_fill,omitempty"`
+	DualBasis      bool   `json:"dual_basis,omitempty"`
+	ConvRate       string `json:"conv_rate,omitempty"`
+	ChannelSymbols string `json:"channel_symbols,omitempty"`
+}
+
+func caduToJSON(cadu []byte, p *tmsc.CodingProfile) caduJSON {
+	asm := p.ASM()
+	j := caduJSON{
+		ASM:         hex.EncodeToString(asm),
+		TotalLen:    len(cadu),
+		Randomize:   p.Randomized(),
+		VirtualFill: p.VirtualFill(),
+		DualBasis:   p.DualBasis(),
+	}
+	j.RSE, j.Interleave = p.RS()
+	if rate, ok := p.Convolutional(); ok {
+		j.ConvRate = rate.String()
+		j.ChannelSymbols = hex.EncodeToString(cadu)
+	} else {
+		j.FrameData = hex.EncodeToString(cadu[len(asm):])
+	}
+	return j
+}
+
+// caduCoding holds the coding profile flags shared by the cadu commands.
+type caduCoding struct {
+	asm        string
+	rs         int
+	interleave int
+	dualBasis  bool
+	conv       string
+	convTail   bool
+}
+
+var caduConvRates = map[string]tmsc.ConvRate{
+	"1/2": tmsc.ConvRate1_2,
+	"2/3": tmsc.ConvRate2_3,
+	"3/4": tmsc.ConvRate3_4,
+	"5/6": tmsc.ConvRate5_6,
+	"7/8": tmsc.ConvRate7_8,
 }
 
-func caduToJSON(cadu []byte, randomized bool) caduJSON {
-	return caduJSON{
-		ASM:       hex.EncodeToString(cadu[:4]),
-		FrameData: hex.EncodeToString(cadu[4:]),
-		TotalLen:  len(cadu),
-		Randomize: randomized,
+func (c *caduCoding) addFlags(cmd *cobra.Command, conv bool) {
+	cmd.Flags().StringVar(&c.asm, "asm", "1acffc1d", "Attached Sync Marker (hex)")
+	cmd.Flags().IntVar(&c.rs, "rs", 0, "Reed-Solomon error correction capability: 0 (none), 8, or 16")
+	cmd.Flags().IntVar(&c.interleave, "interleave", 1, "Reed-Solomon interleaving depth: 1, 2, 3, 4, 5, or 8")
+	cmd.Flags().BoolVar(&c.dualBasis, "dual-basis", false, "Use the dual-basis Reed-Solomon symbol representation")
+	c.conv = "none"
+	if conv {
+		cmd.Flags().StringVar(&c.conv, "conv", "none", "Convolutional inner code rate: none, 1/2, 2/3, 3/4, 5/6, or 7/8")
+		cmd.Flags().BoolVar(&c.convTail, "conv-tail", false, "Terminate the convolutional code of each CADU with 6 zero bits instead of one continuous stream")
+	}
+}
+
+// coded reports whether an RS or convolutional code is selected.
+func (c *caduCoding) coded() bool {
+	return c.rs != 0 || c.conv != "none"
+}
+
+func (c *caduCoding) marker() ([]byte, error) {
+	asm, err := hex.DecodeString(c.asm)
+	if err != nil || len(asm) == 0 {
+		return nil, fmt.Errorf("invalid --asm %q: must be non-empty hex", c.asm)
+	}
+	return asm, nil
+}
+
+// parityLen returns the RS parity bytes added to each CADU.
+func (c *caduCoding) parityLen() int {
+	return 2 * c.rs * c.interleave
+}
+
+// frameLen returns the Transfer Frame length carried by n input bytes:
+// a CADU, or its channel symbols with a convolutional code.
+func (c *caduCoding) frameLen(n int) (int, error) {
+	asm, err := c.marker()
+	if err != nil {
+		return 0, err
+	}
+	caduLen := n
+	if c.conv != "none" {
+		rate, ok := caduConvRates[c.conv]
+		if !ok {
+			return 0, fmt.Errorf("unknown --conv rate: %s (use none, 1/2, 2/3, 3/4, 5/6, or 7/8)", c.conv)
+		}
+		conv, err := tmsc.NewConvCodec(rate, tmsc.WithTail(c.convTail))
+		if err != nil {
+			return 0, err
+		}
+		caduLen = 0
+		for (conv.EncodedSymbols(caduLen+1)+7)/8 <= n {
+			caduLen++
+		}
+	}
+	return caduLen - len(asm) - c.parityLen(), nil
+}
+
+func (c *caduCoding) profile(frameLen int, randomize bool) (*tmsc.CodingProfile, error) {
+	asm, err := c.marker()
+	if err != nil {
+		return nil, err
+	}
+	opts := []tmsc.ProfileOption{
+		tmsc.WithProfileASM(asm),
+		tmsc.WithRandomization(randomize),
+	}
+	if c.rs != 0 {
+		opts = append(opts, tmsc.WithReedSolomon(c.rs, c.interleave, tmsc.WithDualBasis(c.dualBasis)))
+	}
+	if c.conv != "none" {
+		rate, ok := caduConvRates[c.conv]
+		if !ok {
+			return nil, fmt.Errorf("unknown --conv rate: %s (use none, 1/2, 2/3, 3/4, 5/6, or 7/8)", c.conv)
+		}
+		opts = append(opts, tmsc.WithConvolutional(rate, tmsc.WithTail(c.convTail)))
+	}
+	return tmsc.NewCodingProfile(frameLen, opts...)
+}
+
+func printCADUCoding(p *tmsc.CodingProfile) {
+	if e, depth := p.RS(); e != 0 {
+		basis := "conventional"
+		if p.DualBasis() {
+			basis = "dual"
+		}
+		fmt.Printf("  Reed-Solomon: E=%d, I=%d, virtual fill %d, %s basis\n", e, depth, p.VirtualFill(), basis)
+	}
+}
+
+func printCADUCorrections(r *tmsc.CADUResult) {
+	if r.Corrected == nil {
+		return
+	}
+	fmt.Printf("  Corrected: %d symbols %v\n", r.TotalCorrected(), r.Corrected)
+	if !r.OK() {
+		var bad []int
+		for i, u := range r.Uncorrectable {
+			if u {
+				bad = append(bad, i)
+			}
+		}
+		fmt.Printf("  Uncorrectable Codewords: %v\n", bad)
 	}
 }
 
@@ -263,52 +505,70 @@ func printCADUInspect(data []byte) {
 	fmt.Print(hexDump(data, "  "))
 }
 
-func syncCADUs(data []byte, frameLen int, outputFmt string) error {
-	asm := tmsc.DefaultASM()
+func syncCADUs(sync *tmsc.Synchronizer, asmLen int, p *tmsc.CodingProfile, streamLen int, outputFmt string) error {
 	found := 0
-	offset := 0
 
-	for offset+frameLen <= len(data) {
-		// Search for ASM
-		idx := bytes.Index(data[offset:], asm)
-		if idx < 0 {
+	for {
+		c, err := sync.Next()
+		if errors.Is(err, io.EOF) {
 			break
 		}
-
-		asmPos := offset + idx
-		if asmPos+frameLen > len(data) {
-			fmt.Fprintf(os.Stderr, "Warning: ASM at offset %d but insufficient data for full CADU (%d bytes needed, %d available)\n",
-				asmPos, frameLen, len(data)-asmPos)
-			break
+		if err != nil {
+			return fmt.Errorf("syncing CADUs: %w", err)
 		}
-
-		cadu := data[asmPos : asmPos+frameLen]
+		cadu := c.CADU
 		found++
 
+		var r *tmsc.CADUResult
+		if p != nil {
+			results := p.DecodeHard(cadu)
+			if len(results) == 0 {
+				return fmt.Errorf("CADU #%d: %w", found, tmsc.ErrDataTooShort)
+			}
+			r = results[0]
+		}
+
 		switch outputFmt {
 		case "json":
 			j := map[string]any{
-				"index":  found,
-				"offset": asmPos,
-				"asm":    hex.EncodeToString(cadu[:4]),
-				"cadu":   hex.EncodeToString(cadu),
-				"length": frameLen,
+				"index":      found,
+				"offset":     c.BitOffset / 8,
+				"bit_offset": c.BitOffset,
+				"asm":        hex.EncodeToString(cadu[:asmLen]),
+				"asm_errors": c.ASMErrors,
+				"inverted":   c.Inverted,
+				"state":      c.State.String(),
+				"cadu":       hex.EncodeToString(cadu),
+				"length":     len(cadu),
+			}
+			if r != nil {
+				j["frame_data"] = hex.EncodeToString(r.Frame)
+				if r.Corrected != nil {
+					j["rs_corrected"] = r.Corrected
+					j["rs_uncorrectable"] = r.Uncorrectable
+				}
 			}
 			b, _ := json.Marshal(j)
 			fmt.Println(string(b))
 		case "hex":
 			fmt.Println(hex.EncodeToString(cadu))
 		case "text":
-			fmt.Printf("--- CADU #%d (offset %d, %d bytes) ---\n", found, asmPos, frameLen)
-			fmt.Printf("  ASM: %s\n", hex.EncodeToString(cadu[:4]))
-			fmt.Printf("  Frame: %d bytes\n", frameLen-4)
+			fmt.Printf("--- CADU #%d (bit offset %d, %d bytes) ---\n", found, c.BitOffset, len(cadu))
+			fmt.Printf("  ASM: %s (%d bit errors)\n", hex.EncodeToString(cadu[:asmLen]), c.ASMErrors)
+			fmt.Printf("  Sync: %s", c.State)
+			if c.Inverted {
+				fmt.Print(", inverted")
+			}
+			fmt.Println()
+			fmt.Printf("  Frame: %d bytes\n", len(cadu)-asmLen)
+			if r != nil {
+				printCADUCorrections(r)
+			}
 		}
-
-		offset = asmPos + frameLen
 	}
 
 	if outputFmt == "text" {
-		fmt.Printf("\nFound %d CADU(s) in %d bytes.\n", found, len(data))
+		fmt.Printf("\nFound %d CADU(s) in %d bytes.\n", found, streamLen)
 	}
 	return nil
 }
@@ -0,0 +1,728 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+	"github.com/spf13/cobra"
+)
+
+func cfdpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cfdp <command>",
+		Short: "CCSDS File Delivery Protocol operations",
+		Long:  "Send and receive files with CFDP entities, and inspect and generate CFDP PDUs (CCSDS 727.0-B-5).",
+		Annotations: map[string]string{
+			"group": "protocol",
+		},
+	}
+
+	cmd.AddCommand(
+		cfdpPutCmd(),
+		cfdpListenCmd(),
+		cfdpInspectCmd(),
+		cfdpGenCmd(),
+	)
+
+	return cmd
+}
+
+// cfdpLinkFlags selects how an entity exchanges PDUs.
+type cfdpLinkFlags struct {
+	link   string
+	in     string
+	out    string
+	local  string
+	remote string
+}
+
+func (f *cfdpLinkFlags) register(cmd *cobra.Command, in, out string) {
+	cmd.Flags().StringVar(&f.link, "link", "file", "PDU link: file or udp")
+	cmd.Flags().StringVar(&f.in, "in", in, "File or pipe to read PDUs from, - for stdin (file link)")
+	cmd.Flags().StringVar(&f.out, "out", out, "File or pipe to write PDUs to, - for stdout (file link)")
+	cmd.Flags().StringVar(&f.local, "local", "127.0.0.1:4560", "Local UDP address (udp link)")
+	cmd.Flags().StringVar(&f.remote, "remote", "127.0.0.1:4561", "Remote UDP address (udp link)")
+}
+
+// open opens the link. The output of a file link is opened before its
+// input, and the output of the receiving side after, so that a pair of
+// named pipes opens without deadlock.
+func (f *cfdpLinkFlags) open(outFirst bool) (*cfdpLink, error) {
+	switch f.link {
+	case "file":
+		l := &cfdpLink{}
+		steps := []func() error{l.openOut(f.out), l.openIn(f.in)}
+		if !outFirst {
+			steps[0], steps[1] = steps[1], steps[0]
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				l.Close()
+				return nil, err
+			}
+		}
+		return l, nil
+	case "udp":
+		return openUDPLink(f.local, f.remote)
+	default:
+		return nil, fmt.Errorf("unknown --link: %s (use 'file' or 'udp')", f.link)
+	}
+}
+
+// cfdpLink is a cfdp.Transport over a byte stream or UDP. Incoming PDUs
+// are read in the background, so ReceivePDU never blocks. It returns
+// io.EOF once the input has ended.
+type cfdpLink struct {
+	send    func([]byte) error
+	pdus    chan []byte
+	closers []io.Closer
+}
+
+func (l *cfdpLink) SendPDU(pdu []byte) error {
+	if l.send == nil {
+		return nil // no return channel
+	}
+	return l.send(pdu)
+}
+
+func (l *cfdpLink) ReceivePDU() ([]byte, error) {
+	select {
+	case pdu, ok := <-l.pdus:
+		if !ok {
+			return nil, io.EOF
+		}
+		return pdu, nil
+	default:
+		return nil, cfdp.ErrNoPDU
+	}
+}
+
+func (l *cfdpLink) Close() error {
+	var errs []error
+	for _, c := range l.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (l *cfdpLink) openOut(name string) func() error {
+	return func() error {
+		switch name {
+		case "":
+			return nil
+		case "-":
+			l.send = writePDU(os.Stdout)
+			return nil
+		}
+		f, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("opening --out: %w", err)
+		}
+		l.closers = append(l.closers, f)
+		l.send = writePDU(f)
+		return nil
+	}
+}
+
+func (l *cfdpLink) openIn(name string) func() error {
+	return func() error {
+		var r io.Reader
+		switch name {
+		case "":
+			return nil
+		case "-":
+			r = os.Stdin
+		default:
+			f, err := os.Open(name)
+			if err != nil {
+				return fmt.Errorf("opening --in: %w", err)
+			}
+			l.closers = append(l.closers, f)
+			r = f
+		}
+		l.pdus = make(chan []byte, 64)
+		go readPDUs(bufio.NewReader(r), l.pdus)
+		return nil
+	}
+}
+
+func writePDU(w io.Writer) func([]byte) error {
+	return func(pdu []byte) error {
+		_, err := w.Write(pdu)
+		return err
+	}
+}
+
+// readPDUs splits a stream of concatenated PDUs, closing pdus when the
+// stream ends or breaks.
+func readPDUs(r *bufio.Reader, pdus chan<- []byte) {
+	defer close(pdus)
+	for {
+		head, err := r.Peek(4)
+		if err != nil {
+			return
+		}
+		pdu := make([]byte, cfdp.PDUSizer(head))
+		if _, err := io.ReadFull(r, pdu); err != nil {
+			return
+		}
+		pdus <- pdu
+	}
+}
+
+func openUDPLink(local, remote string) (*cfdpLink, error) {
+	laddr, err := net.ResolveUDPAddr("udp", local)
+	if err != nil {
+		return nil, fmt.Errorf("resolving --local: %w", err)
+	}
+	raddr, err := net.ResolveUDPAddr("udp", remote)
+	if err != nil {
+		return nil, fmt.Errorf("resolving --remote: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	l := &cfdpLink{
+		send: func(pdu []byte) error {
+			_, err := conn.WriteToUDP(pdu, raddr)
+			return err
+		},
+		pdus:    make(chan []byte, 64),
+		closers: []io.Closer{conn},
+	}
+	go func() {
+		defer close(l.pdus)
+		buf := make([]byte, 65536+64)
+		for {
+			n, _, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			l.pdus <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	return l, nil
+}
+
+// cfdpEntityFlags holds the entity parameters shared by put and listen.
+type cfdpEntityFlags struct {
+	id         uint64
+	filestore  string
+	stateDir   string
+	segment    int
+	class      int
+	closure    bool
+	checksum   string
+	crc        bool
+	burst      int
+	interval   time.Duration
+	inactivity time.Duration
+	ackTimeout time.Duration
+	nakTimeout time.Duration
+	nakNow     bool
+}
+
+func (f *cfdpEntityFlags) register(cmd *cobra.Command, entity uint64) {
+	cmd.Flags().Uint64Var(&f.id, "entity", entity, "Local entity ID")
+	cmd.Flags().StringVar(&f.filestore, "filestore", ".", "Filestore root directory")
+	cmd.Flags().StringVar(&f.stateDir, "state-dir", "", "Directory to save transaction state in, to resume after a restart")
+	cmd.Flags().IntVar(&f.segment, "segment", cfdp.DefaultMaxFileSegment, "File data bytes per File Data PDU")
+	cmd.Flags().IntVar(&f.class, "class", 1, "Class of service: 1 (unacknowledged) or 2 (acknowledged)")
+	cmd.Flags().BoolVar(&f.closure, "closure", false, "Class 1: request a Finished PDU from the receiver")
+	cmd.Flags().StringVar(&f.checksum, "checksum", "crc32", "File checksum: modular, crc32c, crc32, or null")
+	cmd.Flags().BoolVar(&f.crc, "crc", false, "Append a CRC-16 to each PDU")
+	cmd.Flags().IntVar(&f.burst, "burst", cfdp.DefaultBurst, "File Data PDUs sent per poll")
+	cmd.Flags().DurationVar(&f.interval, "interval", 10*time.Millisecond, "Poll interval while the link is idle")
+	cmd.Flags().DurationVar(&f.inactivity, "inactivity", cfdp.DefaultInactivityTimeout, "Transaction inactivity timeout")
+	cmd.Flags().DurationVar(&f.ackTimeout, "ack-timeout", cfdp.DefaultACKTimeout, "Class 2 positive ACK timeout")
+	cmd.Flags().DurationVar(&f.nakTimeout, "nak-timeout", cfdp.DefaultNAKTimeout, "Class 2 NAK timeout")
+}
+
+// entity creates the entity. remote is the ID of the entity at the
+// other end of the link. ended, if not nil, is called for each
+// Transaction-Finished and Abandoned indication.
+func (f *cfdpEntityFlags) entity(remote uint64, tr cfdp.Transport, ended func(cfdp.Indication)) (*cfdp.Entity, error) {
+	ct, err := parseChecksumType(f.checksum)
+	if err != nil {
+		return nil, err
+	}
+	if f.class != 1 && f.class != 2 {
+		return nil, fmt.Errorf("invalid --class: %d (use 1 or 2)", f.class)
+	}
+	fs, err := cfdp.NewFilestore(f.filestore)
+	if err != nil {
+		return nil, fmt.Errorf("opening filestore: %w", err)
+	}
+	opts := []cfdp.EntityOption{
+		cfdp.WithRemoteEntity(cfdp.RemoteEntity{
+			ID:               cfdp.EntityID(remote),
+			MaxFileSegment:   f.segment,
+			Acknowledged:     f.class == 2,
+			ClosureRequested: f.closure,
+			ChecksumType:     ct,
+			CRC:              f.crc,
+			ACKTimeout:       f.ackTimeout,
+			NAKTimeout:       f.nakTimeout,
+			ImmediateNAK:     f.nakNow,
+		}),
+		cfdp.WithBurst(f.burst),
+		cfdp.WithInactivityTimeout(f.inactivity),
+		cfdp.WithIndicationHandler(func(i cfdp.Indication) {
+			logIndication(i)
+			if ended != nil && (i.Type == cfdp.IndicationTransactionFinished || i.Type == cfdp.IndicationAbandoned) {
+				ended(i)
+			}
+		}),
+	}
+	if f.stateDir != "" {
+		opts = append(opts, cfdp.WithStateDir(f.stateDir))
+	}
+	e := cfdp.NewEntity(cfdp.EntityID(f.id), fs, tr, opts...)
+	if err := e.Restore(); err != nil {
+		fmt.Fprintf(os.Stderr, "restoring state: %v\n", err)
+	}
+	return e, nil
+}
+
+func parseChecksumType(s string) (cfdp.ChecksumType, error) {
+	switch s {
+	case "modular":
+		return cfdp.ChecksumModular, nil
+	case "crc32c":
+		return cfdp.ChecksumCRC32C, nil
+	case "crc32":
+		return cfdp.ChecksumCRC32, nil
+	case "null":
+		return cfdp.ChecksumNull, nil
+	default:
+		return 0, fmt.Errorf("unknown --checksum: %s (use 'modular', 'crc32c', 'crc32', or 'null')", s)
+	}
+}
+
+// cfdpActions maps --filestore-request action names to actions.
+var cfdpActions = map[string]cfdp.FilestoreAction{
+	"create-file":  cfdp.CreateFile,
+	"delete-file":  cfdp.DeleteFile,
+	"rename-file":  cfdp.RenameFile,
+	"append-file":  cfdp.AppendFile,
+	"replace-file": cfdp.ReplaceFile,
+	"create-dir":   cfdp.CreateDirectory,
+	"remove-dir":   cfdp.RemoveDirectory,
+	"deny-file":    cfdp.DenyFile,
+	"deny-dir":     cfdp.DenyDirectory,
+}
+
+// parseFilestoreRequest parses "action:first[:second]".
+func parseFilestoreRequest(s string) (cfdp.FilestoreRequest, error) {
+	parts := strings.Split(s, ":")
+	action, ok := cfdpActions[parts[0]]
+	if !ok || len(parts) < 2 || len(parts) > 3 {
+		return cfdp.FilestoreRequest{}, fmt.Errorf("invalid --filestore-request: %s (use action:first[:second])", s)
+	}
+	req := cfdp.FilestoreRequest{Action: action, FirstName: parts[1]}
+	if len(parts) == 3 {
+		req.SecondName = parts[2]
+	}
+	return req, nil
+}
+
+// logIndication reports entity indications on stderr, leaving stdout
+// free to carry PDUs.
+func logIndication(i cfdp.Indication) {
+	msg := fmt.Sprintf("[%s] %s", i.Transaction, i.Type)
+	switch i.Type {
+	case cfdp.IndicationMetadataRecv:
+		msg += fmt.Sprintf(": %s -> %s (%d bytes)", i.SourceFile, i.DestFile, i.FileSize)
+		for _, m := range i.Messages {
+			msg += fmt.Sprintf(", message %q", m)
+		}
+	case cfdp.IndicationFileSegmentRecv:
+		return
+	case cfdp.IndicationTransactionFinished:
+		msg += fmt.Sprintf(": %s, %s, %s", i.Condition, i.Delivery, i.Status)
+		for _, r := range i.FilestoreResponses {
+			line := fmt.Sprintf("%s %s: status %d %s", r.Action, strings.TrimSpace(r.FirstName+" "+r.SecondName), r.Status, r.Message)
+			msg += "\n  " + strings.TrimSpace(line)
+		}
+	case cfdp.IndicationFault, cfdp.IndicationAbandoned, cfdp.IndicationSuspended:
+		msg += ": " + i.Condition.String()
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// runEntity polls the entity and handles incoming PDUs until done
+// reports true or the command is interrupted. inputDone is set once the
+// link input has ended.
+func runEntity(cmd *cobra.Command, e *cfdp.Entity, interval time.Duration, done func(inputDone bool) bool) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	inputDone := false
+	for !done(inputDone) {
+		if err := e.Poll(); err != nil {
+			return err
+		}
+		idle := true
+		for {
+			err := e.Receive()
+			if errors.Is(err, cfdp.ErrNoPDU) {
+				break
+			}
+			if errors.Is(err, io.EOF) {
+				inputDone = true
+				break
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "skipping PDU: %v\n", err)
+			}
+			idle = false
+		}
+		if idle {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	}
+	return nil
+}
+
+func cfdpPutCmd() *cobra.Command {
+	var (
+		link     cfdpLinkFlags
+		flags    cfdpEntityFlags
+		dest     uint64
+		destFile string
+		fsReqs   []string
+		messages []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "put [file]",
+		Short: "Send a file to a remote entity",
+		Long: "Send a file from the filestore to a remote CFDP entity and wait until the transaction finishes. " +
+			"Without a file, only metadata is sent: filestore requests and messages to user.",
+		Example: `  # Send a file over a pipe to a local receiver
+  astro cfdp put image.raw --dest-entity 2 | astro cfdp listen --entity 2 --filestore rx
+
+  # Class 2 over a UDP loopback
+  astro cfdp put image.raw --dest-entity 2 --class 2 --link udp --local :4560 --remote :4561
+
+  # Deliver, then replace the running copy on the receiver
+  astro cfdp put patch.bin --dest-entity 2 --dest-file sw/patch.tmp --closure --in down.fifo --out up.fifo \
+    --filestore-request replace-file:sw/app.bin:sw/patch.tmp --filestore-request delete-file:sw/patch.tmp
+
+  # Capture the PDUs of a transfer for inspection
+  astro cfdp put image.raw --dest-entity 2 --out pdus.bin`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := cfdp.PutRequest{Destination: cfdp.EntityID(dest), DestFile: destFile}
+			if len(args) > 0 {
+				req.SourceFile = args[0]
+			}
+			for _, s := range fsReqs {
+				r, err := parseFilestoreRequest(s)
+				if err != nil {
+					return err
+				}
+				req.FilestoreRequests = append(req.FilestoreRequests, r)
+			}
+			for _, m := range messages {
+				req.Messages = append(req.Messages, []byte(m))
+			}
+
+			l, err := link.open(true)
+			if err != nil {
+				return err
+			}
+			defer l.Close()
+			var result *cfdp.Indication
+			e, err := flags.entity(dest, l, func(i cfdp.Indication) { result = &i })
+			if err != nil {
+				return err
+			}
+			defer e.Close()
+
+			id, err := e.Put(req)
+			if err != nil {
+				return fmt.Errorf("put: %w", err)
+			}
+			if err := runEntity(cmd, e, flags.interval, func(bool) bool { return e.Pending() == 0 }); err != nil {
+				return err
+			}
+			switch {
+			case result == nil:
+				return fmt.Errorf("transaction %s interrupted", id)
+			case result.Type == cfdp.IndicationAbandoned:
+				return fmt.Errorf("transaction %s abandoned: %s", id, result.Condition)
+			case result.Condition != cfdp.NoError:
+				return fmt.Errorf("transaction %s: %s", id, result.Condition)
+			}
+			return nil
+		},
+	}
+
+	link.register(cmd, "", "-")
+	flags.register(cmd, 1)
+	cmd.Flags().Uint64Var(&dest, "dest-entity", 2, "Destination entity ID")
+	cmd.Flags().StringVar(&destFile, "dest-file", "", "Destination file name (default: the source file name)")
+	cmd.Flags().StringArrayVar(&fsReqs, "filestore-request", nil, "Filestore request action:first[:second], repeatable")
+	cmd.Flags().StringArrayVar(&messages, "message", nil, "Message to user, repeatable")
+
+	return cmd
+}
+
+func cfdpListenCmd() *cobra.Command {
+	var (
+		link   cfdpLinkFlags
+		flags  cfdpEntityFlags
+		source uint64
+		count  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "listen",
+		Short: "Receive files from remote entities",
+		Long: "Run a CFDP entity that receives files into the filestore and executes their filestore requests. " +
+			"It runs until interrupted, until --count transactions have finished, or, on a file link, " +
+			"until the input ends and no transaction is left.",
+		Example: `  # Receive from a pipe into ./rx
+  astro cfdp put image.raw --dest-entity 2 | astro cfdp listen --entity 2 --filestore rx
+
+  # Replay a captured PDU stream
+  astro cfdp listen --entity 2 --filestore rx --in pdus.bin
+
+  # Class 2 over a UDP loopback, answering on the return channel
+  astro cfdp listen --entity 2 --filestore rx --class 2 --link udp --local :4561 --remote :4560
+
+  # Both directions over named pipes
+  mkfifo up.fifo down.fifo
+  astro cfdp listen --entity 2 --filestore rx --in up.fifo --out down.fifo --count 1`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l, err := link.open(false)
+			if err != nil {
+				return err
+			}
+			defer l.Close()
+			finished := 0
+			e, err := flags.entity(source, l, func(cfdp.Indication) { finished++ })
+			if err != nil {
+				return err
+			}
+			defer e.Close()
+
+			return runEntity(cmd, e, flags.interval, func(inputDone bool) bool {
+				if count > 0 && finished >= count {
+					return true
+				}
+				return inputDone && e.Pending() == 0
+			})
+		},
+	}
+
+	link.register(cmd, "-", "")
+	flags.register(cmd, 2)
+	cmd.Flags().Uint64Var(&source, "source-entity", 1, "Entity ID of the sender, for the parameters of answers to it")
+	cmd.Flags().IntVar(&count, "count", 0, "Exit after this many transactions finish (0: no limit)")
+	cmd.Flags().BoolVar(&flags.nakNow, "immediate-nak", false, "Class 2: send a NAK as soon as a gap in the file data is seen")
+
+	return cmd
+}
+
+// cfdpPDUJSON is the JSON-serializable representation of a decoded CFDP PDU.
+type cfdpPDUJSON struct {
+	PDU                string          `json:"pdu"`
+	Direction          string          `json:"direction"`
+	Mode               string          `json:"mode"`
+	CRC                bool            `json:"crc"`
+	LargeFile          bool            `json:"large_file"`
+	SourceID           uint64          `json:"source_id"`
+	SeqNum             uint64          `json:"seq_num"`
+	DestinationID      uint64          `json:"destination_id"`
+	Length             int             `json:"length"`
+	Condition          string          `json:"condition,omitempty"`
+	ClosureRequested   bool            `json:"closure_requested,omitempty"`
+	ChecksumType       string          `json:"checksum_type,omitempty"`
+	FileSize           *uint64         `json:"file_size,omitempty"`
+	SourceFile         string          `json:"source_file,omitempty"`
+	DestFile           string          `json:"dest_file,omitempty"`
+	FilestoreRequests  []string        `json:"filestore_requests,omitempty"`
+	Messages           []string        `json:"messages,omitempty"`
+	Offset             *uint64         `json:"offset,omitempty"`
+	Data               string          `json:"data,omitempty"`
+	Checksum           *uint32         `json:"checksum,omitempty"`
+	Delivery           string          `json:"delivery,omitempty"`
+	Status             string          `json:"status,omitempty"`
+	FilestoreResponses []string        `json:"filestore_responses,omitempty"`
+	Acknowledged       string          `json:"acknowledged,omitempty"`
+	TransactionStatus  string          `json:"transaction_status,omitempty"`
+	ScopeStart         *uint64         `json:"scope_start,omitempty"`
+	ScopeEnd           *uint64         `json:"scope_end,omitempty"`
+	Segments           []cfdpRangeJSON `json:"segments,omitempty"`
+	Response           string          `json:"response,omitempty"`
+	Progress           *uint64         `json:"progress,omitempty"`
+}
+
+type cfdpRangeJSON struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+func toCFDPPDUJSON(p cfdp.PDU, length int) cfdpPDUJSON {
+	h := p.PDUHeader()
+	j := cfdpPDUJSON{
+		Direction:     "toward_receiver",
+		Mode:          "unacknowledged",
+		CRC:           h.CRC,
+		LargeFile:     h.LargeFile,
+		SourceID:      uint64(h.SourceID),
+		SeqNum:        h.SeqNum,
+		DestinationID: uint64(h.DestinationID),
+		Length:        length,
+	}
+	if h.Direction == cfdp.TowardSender {
+		j.Direction = "toward_sender"
+	}
+	if h.Mode == cfdp.Acknowledged {
+		j.Mode = "acknowledged"
+	}
+
+	switch p := p.(type) {
+	case *cfdp.MetadataPDU:
+		j.PDU = "metadata"
+		j.ClosureRequested = p.ClosureRequested
+		j.ChecksumType = p.ChecksumType.String()
+		j.FileSize = &p.FileSize
+		j.SourceFile = p.SourceFileName
+		j.DestFile = p.DestFileName
+		for _, r := range p.FilestoreRequests {
+			j.FilestoreRequests = append(j.FilestoreRequests, strings.TrimSpace(r.Action.String()+" "+r.FirstName+" "+r.SecondName))
+		}
+		for _, m := range p.MessagesToUser {
+			j.Messages = append(j.Messages, hex.EncodeToString(m))
+		}
+	case *cfdp.FileDataPDU:
+		j.PDU = "file_data"
+		j.Offset = &p.Offset
+		j.Data = hex.EncodeToString(p.Data)
+	case *cfdp.EOFPDU:
+		j.PDU = "eof"
+		j.Condition = p.Condition.String()
+		j.Checksum = &p.Checksum
+		j.FileSize = &p.FileSize
+	case *cfdp.FinishedPDU:
+		j.PDU = "finished"
+		j.Condition = p.Condition.String()
+		j.Delivery = p.Delivery.String()
+		j.Status = p.Status.String()
+		for _, r := range p.FilestoreResponses {
+			j.FilestoreResponses = append(j.FilestoreResponses,
+				fmt.Sprintf("%s %s: status %d", r.Action, strings.TrimSpace(r.FirstName+" "+r.SecondName), r.Status))
+		}
+	case *cfdp.ACKPDU:
+		j.PDU = "ack"
+		j.Acknowledged = p.Directive.String()
+		j.Condition = p.Condition.String()
+		j.TransactionStatus = p.Status.String()
+	case *cfdp.NAKPDU:
+		j.PDU = "nak"
+		j.ScopeStart = &p.ScopeStart
+		j.ScopeEnd = &p.ScopeEnd
+		j.Segments = make([]cfdpRangeJSON, len(p.Segments))
+		for i, r := range p.Segments {
+			j.Segments[i] = cfdpRangeJSON{Start: r.Start, End: r.End}
+		}
+	case *cfdp.PromptPDU:
+		j.PDU = "prompt"
+		j.Response = "nak"
+		if p.Response == cfdp.PromptKeepAlive {
+			j.Response = "keep_alive"
+		}
+	case *cfdp.KeepAlivePDU:
+		j.PDU = "keep_alive"
+		j.Progress = &p.Progress
+	}
+	return j
+}
+
+func cfdpInspectCmd() *cobra.Command {
+	var inputFmt, outputFmt string
+
+	cmd := &cobra.Command{
+		Use:   "inspect [file]",
+		Short: "Decode a stream of CFDP PDUs",
+		Long:  "Decode concatenated CFDP PDUs from a file or stdin, printing the header and fields of each one.",
+		Example: `  # Inspect a captured transfer
+  astro cfdp inspect --input bin pdus.bin
+
+  # Inspect generated PDUs as JSON
+  astro cfdp gen --size 4096 | astro cfdp inspect --input bin --format json
+
+  # Inspect a single PDU given as hex
+  echo "24000a13 0102 0a0b0c0d 0304 04 00 00000000 00000064" | astro cfdp inspect`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readInput(args, inputFmt)
+			if err != nil {
+				return err
+			}
+			if outputFmt != "text" && outputFmt != "json" {
+				return fmt.Errorf("unknown format: %s (use 'text' or 'json')", outputFmt)
+			}
+
+			count := 0
+			offset := 0
+			for offset < len(data) {
+				size := cfdp.PDUSizer(data[offset:])
+				if size < 0 {
+					return fmt.Errorf("PDU #%d at offset %d: incomplete PDU header", count+1, offset)
+				}
+				if size > len(data)-offset {
+					return fmt.Errorf("PDU #%d at offset %d: incomplete PDU (need %d bytes, have %d)",
+						count+1, offset, size, len(data)-offset)
+				}
+				p, err := cfdp.DecodePDU(data[offset : offset+size])
+				if err != nil {
+					return fmt.Errorf("PDU #%d at offset %d: %w", count+1, offset, err)
+				}
+
+				count++
+				switch outputFmt {
+				case "json":
+					b, err := json.Marshal(toCFDPPDUJSON(p, size))
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(b))
+				case "text":
+					fmt.Printf("--- PDU #%d (offset %d, %d bytes) ---\n", count, offset, size)
+					fmt.Println(p.Humanize())
+				}
+				offset += size
+			}
+
+			if outputFmt == "text" {
+				fmt.Printf("\nDecoded %d PDU(s), %d bytes total.\n", count, offset)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
+	cmd.Flags().StringVar(&outputFmt, "format", "text", "Output format: text or json")
+
+	return cmd
+}
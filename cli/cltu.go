@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -30,30 +31,104 @@ func cltuCmd() *cobra.Command {
 	return cmd
 }
 
+// cltuCoding describes the channel code and sequences of a CLTU as
+// selected by the --coding and --tail flags.
+type cltuCoding struct {
+	name     string
+	ldpc     *tcsc.LDPCCodec // nil for BCH
+	startSeq []byte
+	tailSeq  []byte // nil if the CLTU has no tail sequence
+}
+
+func newCLTUCoding(name string, ldpcTail bool) (cltuCoding, error) {
+	c := cltuCoding{name: name}
+	switch name {
+	case "bch":
+		c.startSeq = tcsc.DefaultStartSequence()
+		c.tailSeq = tcsc.DefaultTailSequence()
+		return c, nil
+	case "ldpc64":
+		c.ldpc = tcsc.NewLDPC128_64()
+	case "ldpc256":
+		c.ldpc = tcsc.NewLDPC512_256()
+	default:
+		return c, fmt.Errorf("unknown coding: %s", name)
+	}
+	c.startSeq = tcsc.LDPCStartSequence()
+	if ldpcTail {
+		c.tailSeq = tcsc.LDPCTailSequence()
+	}
+	return c, nil
+}
+
+func (c cltuCoding) infoLen() int {
+	if c.ldpc != nil {
+		return c.ldpc.InfoLen()
+	}
+	return tcsc.InfoBytes
+}
+
+func (c cltuCoding) codeblockLen() int {
+	if c.ldpc != nil {
+		return c.ldpc.CodeblockLen()
+	}
+	return tcsc.CodeblockBytes
+}
+
+// randomized reports whether frames are randomized: always with LDPC,
+// otherwise as requested.
+func (c cltuCoding) randomized(requested bool) bool {
+	return c.ldpc != nil || requested
+}
+
+func (c cltuCoding) wrap(frame []byte, randomize bool) ([]byte, error) {
+	if c.ldpc != nil {
+		return tcsc.WrapLDPCCLTU(frame, c.ldpc, c.startSeq, c.tailSeq)
+	}
+	return tcsc.WrapCLTU(frame, c.startSeq, c.tailSeq, randomize)
+}
+
+func (c cltuCoding) unwrap(cltu []byte, derandomize bool) ([]byte, int, error) {
+	if c.ldpc != nil {
+		return tcsc.UnwrapLDPCCLTU(cltu, c.ldpc, c.startSeq, c.tailSeq)
+	}
+	return tcsc.UnwrapCLTU(cltu, c.startSeq, c.tailSeq, derandomize)
+}
+
 func cltuWrapCmd() *cobra.Command {
 	var (
 		inputFmt  string
 		outputFmt string
+		coding    string
+		tail      bool
 		randomize bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "wrap [file]",
 		Short: "Wrap a TC frame into a CLTU",
-		Long:  "Pad, BCH-encode, and add start/tail sequences to produce a CLTU from TC Transfer Frame data.",
+		Long:  "Pad, encode with BCH or LDPC, and add start/tail sequences to produce a CLTU from TC Transfer Frame data.",
 		Example: `  # Wrap a TC frame
   astro tc encode --scid 26 --vcid 1 --data 0102030405 | astro cltu wrap --input hex
 
   # Wrap with randomization
-  astro tc encode --scid 26 --vcid 1 --data 0102030405 | astro cltu wrap --input hex --randomize`,
+  astro tc encode --scid 26 --vcid 1 --data 0102030405 | astro cltu wrap --input hex --randomize
+
+  # Wrap into LDPC (128,64) codeblocks with the optional tail sequence
+  astro tc encode --scid 26 --vcid 1 --data 0102030405 | astro cltu wrap --input hex --coding ldpc64 --tail`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newCLTUCoding(coding, tail)
+			if err != nil {
+				return err
+			}
+
 			data, err := readInput(args, inputFmt)
 			if err != nil {
 				return err
 			}
 
-			cltu, err := tcsc.WrapCLTU(data, nil, nil, randomize)
+			cltu, err := c.wrap(data, randomize)
 			if err != nil {
 				return fmt.Errorf("wrapping CLTU: %w", err)
 			}
@@ -62,22 +137,24 @@ func cltuWrapCmd() *cobra.Command {
 			case "hex":
 				fmt.Println(hex.EncodeToString(cltu))
 			case "json":
-				j := cltuToJSON(cltu, randomize)
+				j := cltuToJSON(cltu, c, c.randomized(randomize))
 				b, err := json.MarshalIndent(j, "", "  ")
 				if err != nil {
 					return err
 				}
 				fmt.Println(string(b))
 			case "text":
-				startSeq := tcsc.DefaultStartSequence()
-				tailSeq := tcsc.DefaultTailSequence()
-				bodyLen := len(cltu) - len(startSeq) - len(tailSeq)
-				numBlocks := bodyLen / tcsc.CodeblockBytes
+				bodyLen := len(cltu) - len(c.startSeq) - len(c.tailSeq)
 				fmt.Printf("CLTU (%d bytes)\n", len(cltu))
-				fmt.Printf("  Start Sequence: %s\n", hex.EncodeToString(cltu[:len(startSeq)]))
-				fmt.Printf("  Codeblocks: %d (%d bytes each)\n", numBlocks, tcsc.CodeblockBytes)
-				fmt.Printf("  Tail Sequence: %s\n", hex.EncodeToString(cltu[len(cltu)-len(tailSeq):]))
-				fmt.Printf("  Randomized: %v\n", randomize)
+				fmt.Printf("  Coding: %s\n", c.name)
+				fmt.Printf("  Start Sequence: %s\n", hex.EncodeToString(cltu[:len(c.startSeq)]))
+				fmt.Printf("  Codeblocks: %d (%d bytes each)\n", bodyLen/c.codeblockLen(), c.codeblockLen())
+				if len(c.tailSeq) > 0 {
+					fmt.Printf("  Tail Sequence: %s\n", hex.EncodeToString(cltu[len(cltu)-len(c.tailSeq):]))
+				} else {
+					fmt.Println("  Tail Sequence: none")
+				}
+				fmt.Printf("  Randomized: %v\n", c.randomized(randomize))
 			default:
 				return fmt.Errorf("unknown format: %s", outputFmt)
 			}
@@ -87,7 +164,9 @@ func cltuWrapCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
 	cmd.Flags().StringVar(&outputFmt, "format", "hex", "Output format: text, json, or hex")
-	cmd.Flags().BoolVar(&randomize, "randomize", false, "Apply CCSDS pseudo-randomization")
+	cmd.Flags().StringVar(&coding, "coding", "bch", "Channel code: bch, ldpc64, or ldpc256")
+	cmd.Flags().BoolVar(&tail, "tail", false, "Append the optional LDPC tail sequence")
+	cmd.Flags().BoolVar(&randomize, "randomize", false, "Apply CCSDS pseudo-randomization (always applied with LDPC)")
 
 	return cmd
 }
@@ -96,26 +175,36 @@ func cltuUnwrapCmd() *cobra.Command {
 	var (
 		inputFmt    string
 		outputFmt   string
+		coding      string
+		tail        bool
 		derandomize bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "unwrap [file]",
 		Short: "Unwrap a CLTU to extract the TC frame",
-		Long:  "Validate start/tail sequences, BCH-decode codeblocks, and optionally de-randomize to extract TC Transfer Frame data.",
+		Long:  "Validate start/tail sequences, decode BCH or LDPC codeblocks, and optionally de-randomize to extract TC Transfer Frame data.",
 		Example: `  # Unwrap a CLTU
   astro cltu unwrap --input hex cltu.hex
 
   # Unwrap with de-randomization
-  cat cltu.hex | astro cltu unwrap --input hex --derandomize`,
+  cat cltu.hex | astro cltu unwrap --input hex --derandomize
+
+  # Unwrap LDPC (512,256) codeblocks without a tail sequence
+  astro cltu unwrap --input hex --coding ldpc256 cltu.hex`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newCLTUCoding(coding, tail)
+			if err != nil {
+				return err
+			}
+
 			data, err := readInput(args, inputFmt)
 			if err != nil {
 				return err
 			}
 
-			frame, corrections, err := tcsc.UnwrapCLTU(data, nil, nil, derandomize)
+			frame, corrections, err := c.unwrap(data, derandomize)
 			if err != nil {
 				return fmt.Errorf("unwrapping CLTU: %w", err)
 			}
@@ -127,8 +216,9 @@ func cltuUnwrapCmd() *cobra.Command {
 				j := map[string]any{
 					"frame_data":   hex.EncodeToString(frame),
 					"frame_bytes":  len(frame),
+					"coding":       c.name,
 					"corrections":  corrections,
-					"derandomized": derandomize,
+					"derandomized": c.randomized(derandomize),
 				}
 				b, err := json.MarshalIndent(j, "", "  ")
 				if err != nil {
@@ -137,8 +227,9 @@ func cltuUnwrapCmd() *cobra.Command {
 				fmt.Println(string(b))
 			case "text":
 				fmt.Printf("Extracted Frame (%d bytes)\n", len(frame))
-				fmt.Printf("  BCH Corrections: %d\n", corrections)
-				fmt.Printf("  Derandomized: %v\n", derandomize)
+				fmt.Printf("  Coding: %s\n", c.name)
+				fmt.Printf("  Corrections: %d\n", corrections)
+				fmt.Printf("  Derandomized: %v\n", c.randomized(derandomize))
 				fmt.Print(hexDump(frame, "  "))
 			default:
 				return fmt.Errorf("unknown format: %s", outputFmt)
@@ -149,20 +240,28 @@ func cltuUnwrapCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
 	cmd.Flags().StringVar(&outputFmt, "format", "hex", "Output format: text, json, or hex")
-	cmd.Flags().BoolVar(&derandomize, "derandomize", false, "Apply CCSDS de-randomization")
+	cmd.Flags().StringVar(&coding, "coding", "bch", "Channel code: bch, ldpc64, or ldpc256")
+	cmd.Flags().BoolVar(&tail, "tail", false, "Expect the optional LDPC tail sequence")
+	cmd.Flags().BoolVar(&derandomize, "derandomize", false, "Apply CCSDS de-randomization (always applied with LDPC)")
 
 	return cmd
 }
 
 func cltuInspectCmd() *cobra.Command {
-	var inputFmt string
+	var (
+		inputFmt string
+		coding   string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "inspect [file]",
 		Short: "Inspect a CLTU with annotated breakdown",
-		Long:  "Display an annotated breakdown of a CLTU showing start/tail sequences, codeblock boundaries, and BCH parity.",
+		Long:  "Display an annotated breakdown of a CLTU showing start/tail sequences, codeblock boundaries, and parity.",
 		Example: `  # Inspect a CLTU
-  astro tc encode --scid 26 --vcid 1 --data 0102030405 | astro cltu wrap --input hex | astro cltu inspect --input hex`,
+  astro tc encode --scid 26 --vcid 1 --data 0102030405 | astro cltu wrap --input hex | astro cltu inspect --input hex
+
+  # Inspect an LDPC CLTU
+  astro cltu gen --count 1 --coding ldpc64 --format hex | astro cltu inspect --input hex --coding ldpc64`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			data, err := readInput(args, inputFmt)
@@ -170,17 +269,25 @@ func cltuInspectCmd() *cobra.Command {
 				return err
 			}
 
-			printCLTUInspect(data)
+			// The LDPC tail sequence is optional; expect it if present.
+			c, err := newCLTUCoding(coding, bytes.HasSuffix(data, tcsc.LDPCTailSequence()))
+			if err != nil {
+				return err
+			}
+
+			printCLTUInspect(data, c)
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
+	cmd.Flags().StringVar(&coding, "coding", "bch", "Channel code: bch, ldpc64, or ldpc256")
 
 	return cmd
 }
 
 type cltuJSON struct {
+	Coding        string `json:"coding"`
 	StartSequence string `json:"start_sequence"`
 	TailSequence  string `json:"tail_sequence"`
 	NumCodeblocks int    `json:"num_codeblocks"`
@@ -189,15 +296,14 @@ type cltuJSON struct {
 	CLTU          string `json:"cltu"`
 }
 
-func cltuToJSON(cltu []byte, randomized bool) cltuJSON {
-	startSeq := tcsc.DefaultStartSequence()
-	tailSeq := tcsc.DefaultTailSequence()
-	bodyLen := len(cltu) - len(startSeq) - len(tailSeq)
-	numBlocks := bodyLen / tcsc.CodeblockBytes
+func cltuToJSON(cltu []byte, c cltuCoding, randomized bool) cltuJSON {
+	bodyLen := len(cltu) - len(c.startSeq) - len(c.tailSeq)
+	numBlocks := bodyLen / c.codeblockLen()
 
 	return cltuJSON{
-		StartSequence: hex.EncodeToString(cltu[:len(startSeq)]),
-		TailSequence:  hex.EncodeToString(cltu[len(cltu)-len(tailSeq):]),
+		Coding:        c.name,
+		StartSequence: hex.EncodeToString(cltu[:len(c.startSeq)]),
+		TailSequence:  hex.EncodeToString(cltu[len(cltu)-len(c.tailSeq):]),
 		NumCodeblocks: numBlocks,
 		TotalLen:      len(cltu),
 		Randomized:    randomized,
@@ -205,9 +311,8 @@ func cltuToJSON(cltu []byte, randomized bool) cltuJSON {
 	}
 }
 
-func printCLTUInspect(data []byte) {
-	startSeq := tcsc.DefaultStartSequence()
-	tailSeq := tcsc.DefaultTailSequence()
+func printCLTUInspect(data []byte, c cltuCoding) {
+	startSeq, tailSeq := c.startSeq, c.tailSeq
 
 	fmt.Println("CLTU Inspector")
 	fmt.Println(strings.Repeat("─", 60))
@@ -232,31 +337,36 @@ func printCLTUInspect(data []byte) {
 		return
 	}
 
-	tailMatch := hex.EncodeToString(data[len(data)-len(tailSeq):]) == hex.EncodeToString(tailSeq)
-	fmt.Printf("Tail Sequence (%d bytes): %s", len(tailSeq), hex.EncodeToString(data[len(data)-len(tailSeq):]))
-	if tailMatch {
-		fmt.Println(" [VALID]")
+	if len(tailSeq) == 0 {
+		fmt.Println("Tail Sequence: none")
 	} else {
-		fmt.Printf(" [MISMATCH — expected %s]\n", hex.EncodeToString(tailSeq))
+		tailMatch := hex.EncodeToString(data[len(data)-len(tailSeq):]) == hex.EncodeToString(tailSeq)
+		fmt.Printf("Tail Sequence (%d bytes): %s", len(tailSeq), hex.EncodeToString(data[len(data)-len(tailSeq):]))
+		if tailMatch {
+			fmt.Println(" [VALID]")
+		} else {
+			fmt.Printf(" [MISMATCH — expected %s]\n", hex.EncodeToString(tailSeq))
+		}
 	}
 
 	// Codeblocks
+	cbLen, infoLen := c.codeblockLen(), c.infoLen()
 	body := data[len(startSeq) : len(data)-len(tailSeq)]
-	numBlocks := len(body) / tcsc.CodeblockBytes
-	remainder := len(body) % tcsc.CodeblockBytes
+	numBlocks := len(body) / cbLen
+	remainder := len(body) % cbLen
 
 	fmt.Println(strings.Repeat("─", 60))
-	fmt.Printf("Codeblocks: %d (%d bytes each = %d info + 1 parity)\n",
-		numBlocks, tcsc.CodeblockBytes, tcsc.InfoBytes)
+	fmt.Printf("Codeblocks: %d (%d bytes each = %d info + %d parity)\n",
+		numBlocks, cbLen, infoLen, cbLen-infoLen)
 	if remainder > 0 {
 		fmt.Printf("  Warning: %d trailing bytes after codeblocks\n", remainder)
 	}
 
 	for i := range numBlocks {
-		cb := body[i*tcsc.CodeblockBytes : (i+1)*tcsc.CodeblockBytes]
-		info := cb[:tcsc.InfoBytes]
-		parity := cb[tcsc.InfoBytes]
-		fmt.Printf("  Block %d: info=%s parity=%02x\n", i+1, hex.EncodeToString(info), parity)
+		cb := body[i*cbLen : (i+1)*cbLen]
+		info := cb[:infoLen]
+		parity := cb[infoLen:]
+		fmt.Printf("  Block %d: info=%s parity=%s\n", i+1, hex.EncodeToString(info), hex.EncodeToString(parity))
 	}
 
 	// Full dump
@@ -0,0 +1,756 @@
+package cli
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/cop"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/spf13/cobra"
+)
+
+func copCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cop <command>",
+		Short: "Communications Operation Procedure-1 operations",
+		Long:  "Simulate COP-1 links and encode, decode, and inspect CLCWs (CCSDS 232.1-B-2).",
+		Annotations: map[string]string{
+			"group": "protocol",
+		},
+	}
+
+	cmd.AddCommand(
+		copSimulateCmd(),
+		copCLCWCmd(),
+	)
+
+	return cmd
+}
+
+func copCLCWCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clcw <command>",
+		Short: "Communications Link Control Word operations",
+		Long:  "Encode, decode, and inspect 4-byte CLCWs carried in the Operational Control Field.",
+	}
+
+	cmd.AddCommand(
+		clcwDecodeCmd(),
+		clcwEncodeCmd(),
+		clcwInspectCmd(),
+	)
+
+	return cmd
+}
+
+// clcwJSON is the JSON-serializable representation of a CLCW.
+type clcwJSON struct {
+	ControlWordType   uint8  `json:"control_word_type"`
+	Version           uint8  `json:"version"`
+	StatusField       uint8  `json:"status_field"`
+	COPInEffect       uint8  `json:"cop_in_effect"`
+	VirtualChannelID  uint8  `json:"virtual_channel_id"`
+	NoRFAvailableFlag bool   `json:"no_rf_available"`
+	NoBitLockFlag     bool   `json:"no_bit_lock"`
+	LockoutFlag       bool   `json:"lockout"`
+	WaitFlag          bool   `json:"wait"`
+	RetransmitFlag    bool   `json:"retransmit"`
+	FARMBCounter      uint8  `json:"farm_b_counter"`
+	ReportValue       uint8  `json:"report_value"`
+	Raw               string `json:"raw"`
+}
+
+func toCLCWJSON(c *cop.CLCW, raw []byte) clcwJSON {
+	return clcwJSON{
+		ControlWordType:   c.ControlWordType,
+		Version:           c.Version,
+		StatusField:       c.StatusField,
+		COPInEffect:       c.COPInEffect,
+		VirtualChannelID:  c.VirtualChannelID,
+		NoRFAvailableFlag: c.NoRFAvailableFlag,
+		NoBitLockFlag:     c.NoBitLockFlag,
+		LockoutFlag:       c.LockoutFlag,
+		WaitFlag:          c.WaitFlag,
+		RetransmitFlag:    c.RetransmitFlag,
+		FARMBCounter:      c.FARMBCounter,
+		ReportValue:       c.ReportValue,
+		Raw:               hex.EncodeToString(raw),
+	}
+}
+
+func copName(c uint8) string {
+	if c == 1 {
+		return "COP-1"
+	}
+	return "Reserved"
+}
+
+func clcwDecodeCmd() *cobra.Command {
+	var inputFmt, outputFmt string
+
+	cmd := &cobra.Command{
+		Use:   "decode [file]",
+		Short: "Decode a CLCW",
+		Long:  "Decode a binary or hex-encoded 4-byte CLCW and print its fields.",
+		Example: `  # Decode from hex stdin
+  astro cop clcw encode --vcid 1 --vr 42 --retransmit | astro cop clcw decode --input hex
+
+  # Decode with JSON output
+  echo 01042a2a | astro cop clcw decode --input hex --format json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readInput(args, inputFmt)
+			if err != nil {
+				return err
+			}
+
+			var clcw cop.CLCW
+			if err := clcw.Decode(data); err != nil {
+				return fmt.Errorf("decoding CLCW: %w", err)
+			}
+
+			return printCLCW(&clcw, data[:4], outputFmt)
+		},
+	}
+
+	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
+	cmd.Flags().StringVar(&outputFmt, "format", "text", "Output format: text, json, or hex")
+
+	return cmd
+}
+
+func clcwEncodeCmd() *cobra.Command {
+	var (
+		vcid       uint8
+		vr         uint8
+		status     uint8
+		copInEff   uint8
+		farmB      uint8
+		noRF       bool
+		noBitLock  bool
+		lockout    bool
+		wait       bool
+		retransmit bool
+		outputFmt  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "encode",
+		Short: "Construct a CLCW from fields",
+		Long:  "Build a 4-byte CCSDS CLCW from its fields.",
+		Example: `  # CLCW for VC 1 acknowledging up to frame 41
+  astro cop clcw encode --vcid 1 --vr 42
+
+  # FARM-1 in lockout
+  astro cop clcw encode --vcid 1 --vr 42 --lockout
+
+  # Encode with JSON output
+  astro cop clcw encode --vcid 1 --vr 42 --retransmit --farm-b 2 --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if vcid > 63 {
+				return fmt.Errorf("--vcid must be 0-63, got %d", vcid)
+			}
+			if status > 7 || copInEff > 3 || farmB > 3 {
+				return fmt.Errorf("--status must be 0-7, --cop and --farm-b 0-3")
+			}
+
+			clcw := &cop.CLCW{
+				StatusField:       status,
+				COPInEffect:       copInEff,
+				VirtualChannelID:  vcid,
+				NoRFAvailableFlag: noRF,
+				NoBitLockFlag:     noBitLock,
+				LockoutFlag:       lockout,
+				WaitFlag:          wait,
+				RetransmitFlag:    retransmit,
+				FARMBCounter:      farmB,
+				ReportValue:       vr,
+			}
+			encoded, err := clcw.Encode()
+			if err != nil {
+				return fmt.Errorf("encoding CLCW: %w", err)
+			}
+
+			return printCLCW(clcw, encoded, outputFmt)
+		},
+	}
+
+	cmd.Flags().Uint8Var(&vcid, "vcid", 0, "Virtual Channel ID (0-63)")
+	cmd.Flags().Uint8Var(&vr, "vr", 0, "Report value V(R) (0-255)")
+	cmd.Flags().Uint8Var(&status, "status", 0, "Mission-specific status field (0-7)")
+	cmd.Flags().Uint8Var(&copInEff, "cop", 1, "COP in effect (1 = COP-1)")
+	cmd.Flags().Uint8Var(&farmB, "farm-b", 0, "FARM-B counter (0-3)")
+	cmd.Flags().BoolVar(&noRF, "no-rf", false, "Set No RF Available flag")
+	cmd.Flags().BoolVar(&noBitLock, "no-bitlock", false, "Set No Bit Lock flag")
+	cmd.Flags().BoolVar(&lockout, "lockout", false, "Set Lockout flag")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Set Wait flag")
+	cmd.Flags().BoolVar(&retransmit, "retransmit", false, "Set Retransmit flag")
+	cmd.Flags().StringVar(&outputFmt, "format", "hex", "Output format: text, json, or hex")
+
+	return cmd
+}
+
+func clcwInspectCmd() *cobra.Command {
+	var inputFmt string
+
+	cmd := &cobra.Command{
+		Use:   "inspect [file]",
+		Short: "Pretty-print a CLCW with bit layout",
+		Long:  "Display an annotated breakdown of a CLCW showing each field, its bit position, and a hex dump.",
+		Example: `  # Inspect from pipe
+  astro cop clcw encode --vcid 1 --vr 42 --lockout | astro cop clcw inspect --input hex
+
+  # Inspect binary file
+  astro cop clcw inspect --input bin ocf.bin`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readInput(args, inputFmt)
+			if err != nil {
+				return err
+			}
+
+			var clcw cop.CLCW
+			if err := clcw.Decode(data); err != nil {
+				return fmt.Errorf("decoding CLCW: %w", err)
+			}
+
+			printCLCWInspect(&clcw, data[:4])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
+
+	return cmd
+}
+
+func printCLCW(c *cop.CLCW, raw []byte, format string) error {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(toCLCWJSON(c, raw), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "hex":
+		fmt.Println(hex.EncodeToString(raw))
+	case "text":
+		fmt.Println("CLCW:")
+		fmt.Println(c.Humanize())
+	default:
+		return fmt.Errorf("unknown format: %s (use 'text', 'json', or 'hex')", format)
+	}
+	return nil
+}
+
+func printCLCWInspect(c *cop.CLCW, raw []byte) {
+	fmt.Println("CLCW Inspector")
+	fmt.Println(strings.Repeat("─", 60))
+
+	fmt.Println("Word (4 bytes)")
+	fmt.Printf("  Control Word Type .... %d (bit 0)\n", c.ControlWordType)
+	fmt.Printf("  Version .............. %d (bits 1-2)\n", c.Version)
+	fmt.Printf("  Status Field ......... %d (bits 3-5)\n", c.StatusField)
+	fmt.Printf("  COP in Effect ........ %d (%s, bits 6-7)\n", c.COPInEffect, copName(c.COPInEffect))
+	fmt.Printf("  Virtual Channel ID ... %d (bits 8-13)\n", c.VirtualChannelID)
+	fmt.Printf("  Reserved ............. %d (bits 14-15)\n", c.Reserved)
+
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Println("Flags")
+	fmt.Printf("  No RF Available ...... %v (bit 16)\n", c.NoRFAvailableFlag)
+	fmt.Printf("  No Bit Lock .......... %v (bit 17)\n", c.NoBitLockFlag)
+	fmt.Printf("  Lockout .............. %v (bit 18)\n", c.LockoutFlag)
+	fmt.Printf("  Wait ................. %v (bit 19)\n", c.WaitFlag)
+	fmt.Printf("  Retransmit ........... %v (bit 20)\n", c.RetransmitFlag)
+	fmt.Printf("  FARM-B Counter ....... %d (bits 21-22)\n", c.FARMBCounter)
+
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Printf("Report Value V(R): %d (bits 24-31)\n", c.ReportValue)
+	fmt.Printf("FARM-1 State: %s\n", clcwFARMState(c))
+
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Printf("Raw CLCW (%d bytes)\n", len(raw))
+	fmt.Print(hexDump(raw, "  "))
+}
+
+// clcwFARMState summarizes the FARM-1 state implied by the CLCW flags.
+func clcwFARMState(c *cop.CLCW) string {
+	switch {
+	case c.LockoutFlag:
+		return cop.FARMLockout.String()
+	case c.WaitFlag:
+		return cop.FARMWait.String()
+	default:
+		return cop.FARMOpen.String()
+	}
+}
+
+// clcwFlags lists the set FARM-1 flags, e.g. "lockout,retransmit".
+func clcwFlags(c *cop.CLCW) string {
+	var flags []string
+	if c.LockoutFlag {
+		flags = append(flags, "lockout")
+	}
+	if c.WaitFlag {
+		flags = append(flags, "wait")
+	}
+	if c.RetransmitFlag {
+		flags = append(flags, "retransmit")
+	}
+	if len(flags) == 0 {
+		return "-"
+	}
+	return strings.Join(flags, ",")
+}
+
+// --- COP-1 link simulation ---
+
+// simClock is the simulated time base shared by FOP-1 and the timeline.
+type simClock struct{ now time.Time }
+
+func (c *simClock) Now() time.Time { return c.now }
+
+// copSimConfig holds the parameters of astro cop simulate.
+type copSimConfig struct {
+	frames     int
+	window     uint8
+	t1         time.Duration
+	limit      int
+	tick       time.Duration
+	drop       float64
+	clcwDrop   float64
+	lockoutAt  int
+	recover    string
+	maxSteps   int
+	seed       uint64
+	scid       uint16
+	vcid       uint8
+	timeoutSus bool
+}
+
+// copSimEvent is one line of the simulation timeline.
+type copSimEvent struct {
+	Time   float64 `json:"t"`
+	Step   int     `json:"step"`
+	Source string  `json:"source"`
+	Event  string  `json:"event"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// copSimSummary reports the outcome of a simulation run.
+type copSimSummary struct {
+	Delivered       int     `json:"delivered"`
+	Requested       int     `json:"requested"`
+	Transmissions   int     `json:"transmissions"`
+	Retransmissions int     `json:"retransmissions"`
+	BCFrames        int     `json:"bc_frames"`
+	UplinkDrops     int     `json:"uplink_drops"`
+	CLCWDrops       int     `json:"clcw_drops"`
+	Alerts          int     `json:"alerts"`
+	Recoveries      int     `json:"recoveries"`
+	Steps           int     `json:"steps"`
+	Elapsed         float64 `json:"elapsed_seconds"`
+	FOPState        string  `json:"fop_state"`
+	FARMState       string  `json:"farm_state"`
+	Completed       bool    `json:"completed"`
+}
+
+// copSim runs a FOP-1/FARM-1 pair over a simulated lossy link in
+// discrete steps. Each step sends new frames while the window allows,
+// delivers the uplink, returns one CLCW and services T1.
+type copSim struct {
+	cfg   copSimConfig
+	rng   *rand.Rand
+	clock *simClock
+	start time.Time
+	step  int
+
+	vc   *tcdl.VirtualChannel
+	svc  *cop.SequenceControlledService
+	farm *cop.FARM
+
+	next     int            // index of the next payload to send
+	sent     map[uint8]bool // N(S) values transmitted since the last (re)initiation
+	lastCLCW *cop.CLCW
+	notes    []cop.Notification
+	alerted  bool
+	resumed  bool // a suspended service was resumed since the last initiation
+	fopState cop.FOPState
+	farmSt   cop.FARMState
+
+	events  []copSimEvent
+	summary copSimSummary
+}
+
+func newCopSim(cfg copSimConfig) *copSim {
+	start := time.Unix(0, 0)
+	s := &copSim{
+		cfg:   cfg,
+		rng:   rand.New(rand.NewPCG(cfg.seed, 0)),
+		clock: &simClock{now: start},
+		start: start,
+		vc:    tcdl.NewVirtualChannel(cfg.vcid, int(cfg.window)+2),
+		farm:  cop.NewFARM(cfg.vcid, cfg.window),
+		sent:  make(map[uint8]bool),
+	}
+	timeout := cop.TimeoutAlert
+	if cfg.timeoutSus {
+		timeout = cop.TimeoutSuspend
+	}
+	s.svc = cop.NewSequenceControlledService(cfg.scid, cfg.vcid, cfg.window, s.vc,
+		cop.WithFOPOptions(
+			cop.WithClock(s.clock),
+			cop.WithT1(cfg.t1),
+			cop.WithTransmissionLimit(cfg.limit),
+			cop.WithTimeoutType(timeout),
+			cop.WithNotifyHandler(func(n cop.Notification) {
+				s.notes = append(s.notes, n)
+			}),
+		))
+	s.fopState = s.svc.FOP().State()
+	s.farmSt = s.farm.State()
+	s.summary.Requested = cfg.frames
+	return s
+}
+
+func (s *copSim) log(source, event, detail string) {
+	s.events = append(s.events, copSimEvent{
+		Time:   s.clock.now.Sub(s.start).Seconds(),
+		Step:   s.step,
+		Source: source,
+		Event:  event,
+		Detail: detail,
+	})
+}
+
+// observe logs FOP-1 notifications, errors and state transitions.
+func (s *copSim) observe(err error) {
+	if err != nil {
+		s.log("fop", "error", err.Error())
+	}
+	for _, n := range s.notes {
+		switch n.Kind {
+		case cop.NotifyAlert:
+			s.summary.Alerts++
+			s.alerted = true
+			s.log("fop", "alert", n.Alert.String())
+		case cop.NotifySuspend:
+			s.alerted = true
+			s.log("fop", "suspend", "suspended in "+n.SuspendState.String())
+		case cop.NotifyInitiated:
+			s.log("fop", "initiated", "AD service active")
+		}
+	}
+	s.notes = nil
+
+	if st := s.svc.FOP().State(); st != s.fopState {
+		s.log("fop", "state", s.fopState.String()+" → "+st.String())
+		s.fopState = st
+	}
+	if st := s.farm.State(); st != s.farmSt {
+		s.log("farm", "state", s.farmSt.String()+" → "+st.String())
+		s.farmSt = st
+	}
+}
+
+func (s *copSim) run() copSimSummary {
+	s.log("operator", "directive", "Initiate AD Service (without CLCW check)")
+	s.observe(s.svc.InitiateAD())
+
+	for s.step = 0; s.step < s.cfg.maxSteps; s.step++ {
+		if s.done() {
+			s.summary.Completed = true
+			break
+		}
+		if s.alerted && !s.recoverLink() {
+			break
+		}
+		s.sendNew()
+		s.uplink()
+		if s.cfg.lockoutAt > 0 && s.step == s.cfg.lockoutAt {
+			s.injectLockout()
+		}
+		s.downlink()
+
+		s.clock.now = s.clock.now.Add(s.cfg.tick)
+		s.observe(s.svc.CheckTimer())
+	}
+
+	s.summary.Steps = s.step
+	s.summary.Elapsed = s.clock.now.Sub(s.start).Seconds()
+	s.summary.FOPState = s.svc.FOP().State().String()
+	s.summary.FARMState = s.farm.State().String()
+	return s.summary
+}
+
+func (s *copSim) done() bool {
+	fop := s.svc.FOP()
+	return s.summary.Delivered >= s.cfg.frames && fop.PendingCount() == 0 && fop.State() == cop.FOPActive
+}
+
+// sendNew offers new Type-AD frames until the FOP-1 window is full.
+func (s *copSim) sendNew() {
+	for s.next < s.cfg.frames {
+		payload := []byte(fmt.Sprintf("cmd-%04d", s.next))
+		if err := s.svc.Send(payload); err != nil {
+			return
+		}
+		s.next++
+	}
+}
+
+// uplink delivers the frames FOP-1 released, dropping some at random.
+func (s *copSim) uplink() {
+	for s.vc.HasFrames() {
+		frame, err := s.vc.Next()
+		if err != nil {
+			return
+		}
+
+		kind, desc := "AD", fmt.Sprintf("N(S)=%d", frame.Header.FrameSequenceNum)
+		switch {
+		case tcdl.IsControlFrame(frame):
+			kind, desc = "BC", controlDesc(frame.DataField)
+			s.summary.BCFrames++
+		case tcdl.IsBypass(frame):
+			kind = "BD"
+		default:
+			s.summary.Transmissions++
+			if s.sent[frame.Header.FrameSequenceNum] {
+				s.summary.Retransmissions++
+				kind = "AD (retx)"
+			}
+			s.sent[frame.Header.FrameSequenceNum] = true
+		}
+
+		if s.rng.Float64() < s.cfg.drop {
+			s.summary.UplinkDrops++
+			s.log("uplink", kind, desc+" dropped")
+			continue
+		}
+
+		accepted, err := s.farm.ProcessTCFrame(frame)
+		switch {
+		case accepted && kind == "BC":
+			s.log("uplink", kind, desc+" executed")
+		case accepted:
+			if frame.Header.BypassFlag == 0 {
+				s.summary.Delivered++
+			}
+			s.log("uplink", kind, fmt.Sprintf("%s accepted, V(R)=%d", desc, s.farm.VR()))
+		default:
+			s.log("uplink", kind, fmt.Sprintf("%s rejected (%s)", desc, farmRejectReason(err)))
+		}
+	}
+	s.observe(nil)
+}
+
+// injectLockout delivers a frame far outside the FARM-1 window, as a
+// corrupted or misrouted command would.
+func (s *copSim) injectLockout() {
+	ns := s.farm.VR() + 128
+	_, err := s.farm.ProcessFrame(0, 0, ns)
+	s.log("uplink", "AD (rogue)", fmt.Sprintf("N(S)=%d injected, rejected (%s)", ns, farmRejectReason(err)))
+	s.observe(nil)
+}
+
+// downlink returns the current CLCW to FOP-1 unless it is lost.
+func (s *copSim) downlink() {
+	clcw := s.farm.GenerateCLCW()
+	desc := fmt.Sprintf("V(R)=%d flags=%s FARM-B=%d", clcw.ReportValue, clcwFlags(clcw), clcw.FARMBCounter)
+	if s.rng.Float64() < s.cfg.clcwDrop {
+		s.summary.CLCWDrops++
+		s.log("downlink", "CLCW", desc+" dropped")
+		return
+	}
+	s.lastCLCW = clcw
+	s.log("downlink", "CLCW", desc)
+	s.observe(s.svc.ProcessCLCW(clcw))
+}
+
+// recoverLink re-initiates the AD service after an alert following the
+// operator procedure selected by --recover. "auto" unlocks after a
+// lockout and otherwise resynchronizes FARM-1 with Set V(R); "unlock"
+// always unlocks. A suspended service is resumed once before it is
+// re-initiated. It returns false when the simulation should stop.
+func (s *copSim) recoverLink() bool {
+	s.alerted = false
+	fop := s.svc.FOP()
+	if s.cfg.recover == "none" {
+		s.log("operator", "stop", "no recovery procedure selected")
+		return false
+	}
+	if _, suspended := fop.SuspendState(); suspended {
+		// Resume once; if the link is still down, start over.
+		if !s.resumed {
+			s.resumed = true
+			s.log("operator", "directive", "Resume AD Service")
+			s.observe(s.svc.Resume())
+			return true
+		}
+		s.log("operator", "directive", "Terminate AD Service")
+		s.svc.Terminate()
+		s.observe(nil)
+	}
+	s.resumed = false
+
+	// Frames purged by the alert are offered again from the first one
+	// FARM-1 has not accepted.
+	s.next = s.summary.Delivered
+	s.sent = make(map[uint8]bool)
+	s.summary.Recoveries++
+
+	lockout := s.lastCLCW != nil && s.lastCLCW.LockoutFlag
+	switch {
+	case lockout || s.cfg.recover == "unlock":
+		// Align V(S) with the last reported V(R) so the Unlock can complete.
+		if s.lastCLCW != nil {
+			_ = fop.SetVS(s.lastCLCW.ReportValue)
+		}
+		s.log("operator", "directive", fmt.Sprintf("Set V(S)=%d, Initiate AD Service with Unlock", fop.VS()))
+		s.observe(s.svc.InitiateADWithUnlock())
+	default:
+		vr := fop.VS()
+		s.log("operator", "directive", fmt.Sprintf("Initiate AD Service with Set V(R)=%d", vr))
+		s.observe(s.svc.InitiateADWithSetVR(vr))
+	}
+	return true
+}
+
+func farmRejectReason(err error) string {
+	switch {
+	case errors.Is(err, cop.ErrFARMReject):
+		return "out of sequence"
+	case errors.Is(err, cop.ErrFARMDuplicate):
+		return "duplicate"
+	case errors.Is(err, cop.ErrFARMLockout):
+		return "lockout"
+	case errors.Is(err, cop.ErrFARMWait):
+		return "wait"
+	default:
+		return err.Error()
+	}
+}
+
+func controlDesc(data []byte) string {
+	cmd, vr, err := cop.DecodeControlCommand(data)
+	switch {
+	case err != nil:
+		return "invalid control command"
+	case cmd == cop.ControlUnlock:
+		return "Unlock"
+	default:
+		return fmt.Sprintf("Set V(R)=%d", vr)
+	}
+}
+
+func copSimulateCmd() *cobra.Command {
+	var (
+		cfg       copSimConfig
+		outputFmt string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate a COP-1 link between FOP-1 and FARM-1",
+		Long: `Run a FOP-1/FARM-1 pair over a simulated lossy link and print a timeline
+of frames, CLCWs, alerts and state transitions.
+
+The simulation advances in steps of --tick. In each step FOP-1 sends new
+Type-AD frames while its window allows, the uplink delivers or drops them,
+FARM-1 returns one CLCW, and T1 is serviced. After an alert the simulated
+operator re-initiates the AD service per --recover.`,
+		Example: `  # 20 frames over a link that drops 20% of uplink frames
+  astro cop simulate --frames 20 --drop 0.2
+
+  # Rehearse lockout recovery: corrupt the sequence at step 5
+  astro cop simulate --frames 10 --lockout-at 5
+
+  # Tight T1 and limit with lossy return link, JSON timeline
+  astro cop simulate --drop 0.3 --clcw-drop 0.3 --t1 2s --limit 2 --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case cfg.frames < 1:
+				return fmt.Errorf("--frames must be at least 1")
+			case cfg.window < 1 || cfg.window > 127:
+				return fmt.Errorf("--window must be 1-127, got %d", cfg.window)
+			case cfg.t1 <= 0 || cfg.tick <= 0:
+				return fmt.Errorf("--t1 and --tick must be positive")
+			case cfg.limit < 1:
+				return fmt.Errorf("--limit must be at least 1")
+			case cfg.drop < 0 || cfg.drop >= 1 || cfg.clcwDrop < 0 || cfg.clcwDrop >= 1:
+				return fmt.Errorf("--drop and --clcw-drop must be in [0, 1)")
+			case cfg.vcid > 63:
+				return fmt.Errorf("--vcid must be 0-63, got %d", cfg.vcid)
+			}
+			switch cfg.recover {
+			case "auto", "unlock", "none":
+			default:
+				return fmt.Errorf("unknown --recover: %s (use 'auto', 'unlock', or 'none')", cfg.recover)
+			}
+
+			sim := newCopSim(cfg)
+			summary := sim.run()
+			return printCopSim(sim.events, summary, outputFmt)
+		},
+	}
+
+	cmd.Flags().IntVar(&cfg.frames, "frames", 20, "Number of Type-AD frames to deliver")
+	cmd.Flags().Uint8Var(&cfg.window, "window", 10, "Sliding window width (FOP-1 K and FARM-1 W)")
+	cmd.Flags().DurationVar(&cfg.t1, "t1", cop.DefaultT1, "T1 retransmission timer")
+	cmd.Flags().IntVar(&cfg.limit, "limit", cop.DefaultTransmissionLimit, "Transmission limit")
+	cmd.Flags().BoolVar(&cfg.timeoutSus, "suspend", false, "Suspend instead of alerting on T1 expiry at the limit")
+	cmd.Flags().DurationVar(&cfg.tick, "tick", time.Second, "Simulated time per step")
+	cmd.Flags().Float64Var(&cfg.drop, "drop", 0.1, "Uplink frame drop rate (0-1)")
+	cmd.Flags().Float64Var(&cfg.clcwDrop, "clcw-drop", 0, "Return-link CLCW drop rate (0-1)")
+	cmd.Flags().IntVar(&cfg.lockoutAt, "lockout-at", 0, "Inject an out-of-window frame at this step (0 = never)")
+	cmd.Flags().StringVar(&cfg.recover, "recover", "auto", "Recovery after an alert: auto, unlock, or none")
+	cmd.Flags().IntVar(&cfg.maxSteps, "max-steps", 1000, "Stop after this many steps")
+	cmd.Flags().Uint64Var(&cfg.seed, "seed", 1, "Random seed for the link model")
+	cmd.Flags().Uint16Var(&cfg.scid, "scid", 26, "Spacecraft ID (0-1023)")
+	cmd.Flags().Uint8Var(&cfg.vcid, "vcid", 0, "Virtual Channel ID (0-63)")
+	cmd.Flags().StringVar(&outputFmt, "format", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func printCopSim(events []copSimEvent, summary copSimSummary, format string) error {
+	switch format {
+	case "json":
+		out := struct {
+			Timeline []copSimEvent `json:"timeline"`
+			Summary  copSimSummary `json:"summary"`
+		}{events, summary}
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "text":
+		fmt.Println("COP-1 Link Simulation")
+		fmt.Println(strings.Repeat("─", 60))
+		for _, e := range events {
+			fmt.Printf("%8.1fs  %-9s %-10s %s\n", e.Time, e.Source, e.Event, e.Detail)
+		}
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Println("Summary")
+		fmt.Printf("  Delivered ............ %d / %d\n", summary.Delivered, summary.Requested)
+		fmt.Printf("  AD Transmissions ..... %d (%d retransmissions)\n", summary.Transmissions, summary.Retransmissions)
+		fmt.Printf("  BC Frames ............ %d\n", summary.BCFrames)
+		fmt.Printf("  Uplink Drops ......... %d\n", summary.UplinkDrops)
+		fmt.Printf("  CLCW Drops ........... %d\n", summary.CLCWDrops)
+		fmt.Printf("  Alerts ............... %d (%d recoveries)\n", summary.Alerts, summary.Recoveries)
+		fmt.Printf("  Elapsed .............. %.1fs (%d steps)\n", summary.Elapsed, summary.Steps)
+		fmt.Printf("  Final FOP-1 State .... %s\n", summary.FOPState)
+		fmt.Printf("  Final FARM-1 State ... %s\n", summary.FARMState)
+		fmt.Printf("  Completed ............ %v\n", summary.Completed)
+	default:
+		return fmt.Errorf("unknown format: %s (use 'text' or 'json')", format)
+	}
+	return nil
+}
@@ -7,11 +7,11 @@ import (
 	"io"
 	"os"
 
+	"github.com/ravisuhag/astro/pkg/cfdp"
 	"github.com/ravisuhag/astro/pkg/crc"
 	"github.com/ravisuhag/astro/pkg/epp"
 	"github.com/ravisuhag/astro/pkg/spp"
 	"github.com/ravisuhag/astro/pkg/tcdl"
-	"github.com/ravisuhag/astro/pkg/tcsc"
 	"github.com/ravisuhag/astro/pkg/tmdl"
 	"github.com/ravisuhag/astro/pkg/tmsc"
 	"github.com/spf13/cobra"
@@ -243,6 +243,7 @@ func caduGenCmd() *cobra.Command {
 		dataSize  int
 		randomize bool
 		outputFmt string
+		coding    caduCoding
 	)
 
 	cmd := &cobra.Command{
@@ -253,9 +254,13 @@ func caduGenCmd() *cobra.Command {
   astro cadu gen --scid 1 --vcid 0 --count 100 --data-size 1024
 
   # Generate randomized CADUs and sync them back
-  astro cadu gen --scid 1 --count 10 --data-size 100 --format bin | astro cadu sync --input bin --frame-len 112`,
+  astro cadu gen --scid 1 --count 10 --data-size 100 --format bin | astro cadu sync --input bin --frame-len 112
+
+  # Generate RS(255,223) coded CADUs with interleaving depth 2
+  astro cadu gen --count 10 --data-size 434 --randomize --rs 16 --interleave 2 --format bin > coded.bin`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var caduSize int
+			var p *tmsc.CodingProfile
 
 			for i := range count {
 				data := randomBytes(dataSize)
@@ -283,10 +288,21 @@ func caduGenCmd() *cobra.Command {
 					return fmt.Errorf("CADU #%d: %w", i+1, err)
 				}
 
-				cadu := tmsc.WrapCADU(frameBytes, nil, randomize)
+				if p == nil {
+					if p, err = coding.profile(len(frameBytes), randomize); err != nil {
+						return err
+					}
+				}
+				cadu, err := p.Encode(frameBytes)
+				if err != nil {
+					return fmt.Errorf("CADU #%d: %w", i+1, err)
+				}
+				if i == count-1 {
+					cadu = append(cadu, p.FlushEncoder()...)
+				}
 
 				if i == 0 {
-					caduSize = len(cadu)
+					caduSize = p.ChannelLen()
 				}
 
 				if err := writeGenOutput(cadu, outputFmt); err != nil {
@@ -305,6 +321,7 @@ func caduGenCmd() *cobra.Command {
 	cmd.Flags().IntVar(&dataSize, "data-size", 1024, "TM frame data field size in bytes")
 	cmd.Flags().BoolVar(&randomize, "randomize", false, "Apply CCSDS pseudo-randomization")
 	cmd.Flags().StringVar(&outputFmt, "format", "bin", "Output format: bin or hex")
+	coding.addFlags(cmd, true)
 
 	return cmd
 }
@@ -315,6 +332,8 @@ func cltuGenCmd() *cobra.Command {
 		vcid      uint8
 		count     int
 		dataSize  int
+		coding    string
+		tail      bool
 		randomize bool
 		outputFmt string
 	)
@@ -322,13 +341,21 @@ func cltuGenCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "gen",
 		Short: "Generate synthetic CLTUs",
-		Long:  "Generate a stream of synthetic CLTUs (BCH-encoded TC frames with start/tail sequences).",
+		Long:  "Generate a stream of synthetic CLTUs (BCH- or LDPC-encoded TC frames with start/tail sequences).",
 		Example: `  # Generate 10 CLTUs
   astro cltu gen --scid 26 --vcid 1 --count 10 --data-size 64
 
   # Generate and inspect the first one
-  astro cltu gen --scid 26 --vcid 1 --count 1 --data-size 32 --format hex | astro cltu inspect --input hex`,
+  astro cltu gen --scid 26 --vcid 1 --count 1 --data-size 32 --format hex | astro cltu inspect --input hex
+
+  # Generate LDPC (512,256) CLTUs
+  astro cltu gen --scid 26 --vcid 1 --count 10 --coding ldpc256`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newCLTUCoding(coding, tail)
+			if err != nil {
+				return err
+			}
+
 			for i := range count {
 				data := randomBytes(dataSize)
 
@@ -343,7 +370,7 @@ func cltuGenCmd() *cobra.Command {
 					return fmt.Errorf("CLTU #%d: %w", i+1, err)
 				}
 
-				cltu, err := tcsc.WrapCLTU(frameBytes, nil, nil, randomize)
+				cltu, err := c.wrap(frameBytes, randomize)
 				if err != nil {
 					return fmt.Errorf("CLTU #%d: %w", i+1, err)
 				}
@@ -362,7 +389,9 @@ func cltuGenCmd() *cobra.Command {
 	cmd.Flags().Uint8Var(&vcid, "vcid", 0, "Virtual Channel ID (0-63)")
 	cmd.Flags().IntVar(&count, "count", 10, "Number of CLTUs to generate")
 	cmd.Flags().IntVar(&dataSize, "data-size", 64, "TC frame data field size in bytes")
-	cmd.Flags().BoolVar(&randomize, "randomize", false, "Apply CCSDS pseudo-randomization")
+	cmd.Flags().StringVar(&coding, "coding", "bch", "Channel code: bch, ldpc64, or ldpc256")
+	cmd.Flags().BoolVar(&tail, "tail", false, "Append the optional LDPC tail sequence")
+	cmd.Flags().BoolVar(&randomize, "randomize", false, "Apply CCSDS pseudo-randomization (always applied with LDPC)")
 	cmd.Flags().StringVar(&outputFmt, "format", "bin", "Output format: bin or hex")
 
 	return cmd
@@ -425,6 +454,118 @@ func eppGenCmd() *cobra.Command {
 	return cmd
 }
 
+func cfdpGenCmd() *cobra.Command {
+	var (
+		source    uint64
+		dest      uint64
+		seq       uint64
+		size      int
+		segment   int
+		fileName  string
+		checksum  string
+		class     int
+		closure   bool
+		crcFlag   bool
+		largeFile bool
+		outputFmt string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate the PDUs of a synthetic CFDP file transfer",
+		Long: "Generate the Metadata, File Data and EOF PDUs that send a file of random data, " +
+			"as a sending entity would on a lossless link.",
+		Example: `  # Generate a 4 KiB transfer in 1 KiB segments
+  astro cfdp gen --size 4096 --segment 1024
+
+  # Inspect the generated PDUs
+  astro cfdp gen --size 100 --segment 40 | astro cfdp inspect --input bin
+
+  # Feed a receiver without a sender
+  astro cfdp gen --size 4096 --file test.bin | astro cfdp listen --entity 2 --filestore rx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ct, err := parseChecksumType(checksum)
+			if err != nil {
+				return err
+			}
+			if segment <= 0 {
+				return fmt.Errorf("invalid --segment: %d", segment)
+			}
+			mode := cfdp.Unacknowledged
+			switch class {
+			case 1:
+			case 2:
+				mode = cfdp.Acknowledged
+				closure = true
+			default:
+				return fmt.Errorf("invalid --class: %d (use 1 or 2)", class)
+			}
+
+			h := cfdp.Header{
+				Version:        cfdp.Version,
+				Mode:           mode,
+				CRC:            crcFlag,
+				LargeFile:      largeFile,
+				EntityIDLength: cfdp.DefaultEntityIDLength,
+				SeqNumLength:   cfdp.DefaultSeqNumLength,
+				SourceID:       cfdp.EntityID(source),
+				SeqNum:         seq,
+				DestinationID:  cfdp.EntityID(dest),
+			}
+			if err := h.Validate(); err != nil {
+				return err
+			}
+			data := randomBytes(size)
+			sum, err := cfdp.NewChecksum(ct)
+			if err != nil {
+				return err
+			}
+			sum.Write(data)
+
+			pdus := []cfdp.PDU{&cfdp.MetadataPDU{
+				Header:           h,
+				ClosureRequested: closure,
+				ChecksumType:     ct,
+				FileSize:         uint64(size),
+				SourceFileName:   fileName,
+				DestFileName:     fileName,
+			}}
+			for off := 0; off < size; off += segment {
+				pdus = append(pdus, &cfdp.FileDataPDU{Header: h, Offset: uint64(off), Data: data[off:min(off+segment, size)]})
+			}
+			pdus = append(pdus, &cfdp.EOFPDU{Header: h, Checksum: sum.Sum32(), FileSize: uint64(size)})
+
+			for i, p := range pdus {
+				encoded, err := p.Encode()
+				if err != nil {
+					return fmt.Errorf("PDU #%d: %w", i+1, err)
+				}
+				if err := writeGenOutput(encoded, outputFmt); err != nil {
+					return err
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "Generated %d PDU(s), transaction %d/%d, %d file bytes\n", len(pdus), source, seq, size)
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint64Var(&source, "source-entity", 1, "Source entity ID")
+	cmd.Flags().Uint64Var(&dest, "dest-entity", 2, "Destination entity ID")
+	cmd.Flags().Uint64Var(&seq, "seq", 1, "Transaction sequence number")
+	cmd.Flags().IntVar(&size, "size", 4096, "File size in bytes")
+	cmd.Flags().IntVar(&segment, "segment", cfdp.DefaultMaxFileSegment, "File data bytes per File Data PDU")
+	cmd.Flags().StringVar(&fileName, "file", "gen.bin", "Source and destination file name")
+	cmd.Flags().StringVar(&checksum, "checksum", "crc32", "File checksum: modular, crc32c, crc32, or null")
+	cmd.Flags().IntVar(&class, "class", 1, "Transmission mode: 1 (unacknowledged) or 2 (acknowledged)")
+	cmd.Flags().BoolVar(&closure, "closure", false, "Set the closure requested flag")
+	cmd.Flags().BoolVar(&crcFlag, "crc", false, "Append a CRC-16 to each PDU")
+	cmd.Flags().BoolVar(&largeFile, "large-file", false, "Use 64-bit file sizes and offsets")
+	cmd.Flags().StringVar(&outputFmt, "format", "bin", "Output format: bin or hex")
+
+	return cmd
+}
+
 // writeGenOutput writes encoded data in the specified format.
 // bin writes raw bytes to stdout; hex writes one hex line per item.
 func writeGenOutput(data []byte, format string) error {
@@ -20,6 +20,9 @@ var protocols = map[string]string{
 	"cltu": "cltu.md",
 	"usdl": "usdl.md",
 	"aos":  "aos.md",
+	"cop":  "cop.md",
+	"pus":  "pus.md",
+	"cfdp": "cfdp.md",
 }
 
 func manualCmd(docsFS embed.FS) *cobra.Command {
@@ -58,6 +61,9 @@ func printManualIndex() error {
 	sb.WriteString("| Command Link Transmission Units | `astro manual cltu` |\n")
 	sb.WriteString("| Unified Space Data Link Protocol | `astro manual usdl` |\n")
 	sb.WriteString("| AOS Space Data Link Protocol | `astro manual aos` |\n")
+	sb.WriteString("| Communications Operation Procedure-1 | `astro manual cop` |\n")
+	sb.WriteString("| Packet Utilization Standard | `astro manual pus` |\n")
+	sb.WriteString("| CCSDS File Delivery Protocol | `astro manual cfdp` |\n")
 
 	out, err := printer.Markdown(sb.String())
 	if err != nil {
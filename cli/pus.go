@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/spf13/cobra"
+)
+
+func pusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pus <command>",
+		Short: "Packet Utilization Standard operations",
+		Long:  "Work with ECSS Packet Utilization Standard services (ECSS-E-ST-70-41C).",
+		Annotations: map[string]string{
+			"group": "protocol",
+		},
+	}
+
+	cmd.AddCommand(
+		pusScheduleCmd(),
+	)
+
+	return cmd
+}
+
+func pusScheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule <command>",
+		Short: "ST[11] time-based schedule operations",
+		Long:  "Inspect ST[11] time-based schedule requests and reports.",
+	}
+
+	cmd.AddCommand(
+		pusScheduleDumpCmd(),
+	)
+
+	return cmd
+}
+
+// pusConfigFlags holds the mission parameters shared by the pus commands.
+type pusConfigFlags struct {
+	variant      string
+	coarse       uint8
+	fine         uint8
+	pField       bool
+	errorControl string
+}
+
+func (f *pusConfigFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.variant, "variant", "c", "PUS variant: a, b, or c")
+	cmd.Flags().Uint8Var(&f.coarse, "coarse", 4, "CUC coarse time octets (1-4)")
+	cmd.Flags().Uint8Var(&f.fine, "fine", 2, "CUC fine time octets (0-3)")
+	cmd.Flags().BoolVar(&f.pField, "pfield", false, "Time fields carry the CUC P-field")
+	cmd.Flags().StringVar(&f.errorControl, "error-control", "crc", "Packet error control: crc, checksum, or none")
+}
+
+func (f *pusConfigFlags) config() (pus.Config, error) {
+	var v pus.Variant
+	switch strings.ToLower(f.variant) {
+	case "a":
+		v = pus.PUSA
+	case "b":
+		v = pus.PUSB
+	case "c":
+		v = pus.PUSC
+	default:
+		return pus.Config{}, fmt.Errorf("unknown --variant: %s (use 'a', 'b', or 'c')", f.variant)
+	}
+	cfg := pus.DefaultConfig(v)
+	cfg.Time = pus.TimeFormat{CoarseBytes: f.coarse, FineBytes: f.fine, PField: f.pField}
+	switch f.errorControl {
+	case "crc":
+		cfg.ErrorControl = pus.ErrorControlCRC
+	case "checksum":
+		cfg.ErrorControl = pus.ErrorControlChecksum
+	case "none":
+		cfg.ErrorControl = pus.ErrorControlNone
+	default:
+		return pus.Config{}, fmt.Errorf("unknown --error-control: %s (use 'crc', 'checksum', or 'none')", f.errorControl)
+	}
+	return cfg, cfg.Validate()
+}
+
+// activityJSON is the JSON-serializable representation of a scheduled activity.
+type activityJSON struct {
+	ReleaseTime    string `json:"release_time"`
+	SubSchedule    uint16 `json:"sub_schedule"`
+	Group          uint16 `json:"group"`
+	SourceID       uint16 `json:"source_id"`
+	APID           uint16 `json:"apid"`
+	SequenceCount  uint16 `json:"sequence_count"`
+	ServiceType    uint8  `json:"service_type"`
+	ServiceSubtype uint8  `json:"service_subtype"`
+	AppData        string `json:"app_data"`
+}
+
+func toActivityJSON(a *pus.Activity) activityJSON {
+	id, _ := a.ID()
+	st, sst, _ := pus.Service(a.Request)
+	return activityJSON{
+		ReleaseTime:    a.ReleaseTime.Time().UTC().Format(time.RFC3339Nano),
+		SubSchedule:    a.SubSchedule,
+		Group:          a.Group,
+		SourceID:       id.SourceID,
+		APID:           id.APID,
+		SequenceCount:  id.SequenceCount,
+		ServiceType:    st,
+		ServiceSubtype: sst,
+		AppData:        hex.EncodeToString(a.Request.UserData),
+	}
+}
+
+func pusScheduleDumpCmd() *cobra.Command {
+	var (
+		inputFmt, outputFmt string
+		flags               pusConfigFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dump [file]",
+		Short: "List the activities of a schedule request or report",
+		Long: "Decode a TC[11,4] insert request or a TM[11,10] schedule detail report and list its " +
+			"time-tagged telecommands in release order.",
+		Example: `  # Dump a detail report captured as hex
+  astro pus schedule dump --input hex report.hex
+
+  # PUS-A mission with a 4+3 CUC and the ISO checksum
+  astro pus schedule dump --variant a --fine 3 --error-control checksum request.hex
+
+  # JSON output
+  astro pus schedule dump --format json report.hex | jq '.[].release_time'`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := flags.config()
+			if err != nil {
+				return err
+			}
+			data, err := readInput(args, inputFmt)
+			if err != nil {
+				return err
+			}
+
+			pkt, err := pus.Decode(data, cfg)
+			if err != nil {
+				return fmt.Errorf("decoding packet: %w", err)
+			}
+			st, sst, err := pus.Service(pkt)
+			if err != nil {
+				return err
+			}
+			if st != pus.ServiceScheduling || (sst != pus.SchedInsert && sst != pus.SchedDetailReport) {
+				return fmt.Errorf("packet is ST[%d,%d], want ST[11,4] or ST[11,10]", st, sst)
+			}
+			acts, err := pus.DecodeActivities(pkt.UserData, cfg)
+			if err != nil {
+				return fmt.Errorf("decoding activities: %w", err)
+			}
+
+			return printSchedule(acts, outputFmt)
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().StringVar(&inputFmt, "input", "hex", "Input format: hex or bin")
+	cmd.Flags().StringVar(&outputFmt, "format", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func printSchedule(acts []pus.Activity, format string) error {
+	switch format {
+	case "json":
+		out := make([]activityJSON, len(acts))
+		for i := range acts {
+			out[i] = toActivityJSON(&acts[i])
+		}
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "text":
+		fmt.Printf("%d activities\n", len(acts))
+		for i := range acts {
+			fmt.Println(strings.Repeat("─", 60))
+			fmt.Printf("Activity %d\n", i+1)
+			fmt.Println(acts[i].Humanize())
+		}
+	default:
+		return fmt.Errorf("unknown format: %s (use 'text' or 'json')", format)
+	}
+	return nil
+}
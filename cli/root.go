@@ -30,6 +30,9 @@ func New(docsFS embed.FS) *cobra.Command {
 	cmd.AddCommand(cltuCmd())
 	cmd.AddCommand(usdlCmd())
 	cmd.AddCommand(aosCmd())
+	cmd.AddCommand(copCmd())
+	cmd.AddCommand(pusCmd())
+	cmd.AddCommand(cfdpCmd())
 	cmd.AddCommand(manualCmd(docsFS))
 
 	mgr := commander.New(cmd)
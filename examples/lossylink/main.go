@@ -11,7 +11,7 @@
 //   - FHP-based resync: after frame loss, the receiver re-synchronizes
 //     to the next packet boundary using the First Header Pointer
 //
-// The transmission chain per CCSDS 131.0-B-4:
+// The transmission chain per CCSDS 131.0-B-4, described by a tmsc.CodingProfile:
 //
 //	Frame (128 bytes) → RS encode (128→160 bytes) → Randomize → ASM → CADU
 //	CADU → ASM strip → De-randomize → RS decode (160→128 bytes) → Frame
@@ -89,10 +89,17 @@ func main() {
 
 	link := newNoisyLink(12345) // fixed seed for reproducibility
 
-	// Reed-Solomon codec: RS(255,223) can correct up to 16 symbol errors.
-	// We use it to protect each frame against bit errors from the noisy channel.
-	// Frames shorter than 223 bytes are zero-padded before encoding (virtual fill).
-	rs := tmsc.NewRS255_223()
+	// Coding profile: RS(255,223) corrects up to 16 symbol errors per frame.
+	// Frames shorter than 223 bytes are carried in shortened codewords: the
+	// missing bytes are virtual fill, encoded as zeros but never transmitted.
+	profile, err := tmsc.NewCodingProfile(frameLength,
+		tmsc.WithReedSolomon(16, 1),
+		tmsc.WithRandomization(true),
+	)
+	if err != nil {
+		fmt.Printf("ERROR creating coding profile: %v\n", err)
+		return
+	}
 
 	// =================================================================
 	// SPACECRAFT: generate packets and transmit CADUs
@@ -139,20 +146,19 @@ func main() {
 	}
 	_ = vcp.Flush()
 
-	// Wrap all frames as CADUs with RS encoding and push through the noisy link.
+	// Encode all frames as CADUs (RS encode, randomize, ASM) and push
+	// them through the noisy link.
 	var receivedCADUs [][]byte
 	totalFrames := 0
 	for scPhysical.HasPendingFrames() {
 		frame, _ := scPhysical.GetNextFrame()
 		encoded, _ := frame.Encode()
 
-		// RS encode: pad frame to 223 bytes (virtual fill), encode to 255 bytes.
-		rsData := make([]byte, rs.DataLen())
-		copy(rsData, encoded)
-		rsEncoded, _ := rs.Encode(rsData)
-
-		// Wrap as CADU: randomize + ASM.
-		cadu := tmsc.WrapCADU(rsEncoded, nil, true)
+		cadu, err := profile.Encode(encoded)
+		if err != nil {
+			fmt.Printf("  ERROR encoding CADU: %v\n", err)
+			continue
+		}
 		totalFrames++
 
 		if arrived, ok := link.transmit(cadu); ok {
@@ -161,8 +167,8 @@ func main() {
 	}
 
 	fmt.Printf("  Sent: %d packets (%d bytes) in %d frames\n", numPackets, sentBytes, totalFrames)
-	fmt.Printf("  Each frame: %d bytes → RS(%d bytes) → CADU(%d bytes)\n",
-		frameLength, rs.DataLen()+rs.NRoots(), 4+rs.DataLen()+rs.NRoots())
+	fmt.Printf("  Each frame: %d bytes → RS(%d bytes, %d virtual fill) → CADU(%d bytes)\n",
+		frameLength, profile.CADULen()-4, profile.VirtualFill(), profile.CADULen())
 	fmt.Printf("\nRF Link statistics:\n")
 	fmt.Printf("  Delivered intact:  %d frames\n", link.delivered)
 	fmt.Printf("  Dropped (lost):    %d frames\n", link.dropped)
@@ -197,28 +203,28 @@ func main() {
 	vcGapsTotal := 0
 
 	for _, cadu := range receivedCADUs {
-		// Step 1: Unwrap CADU — strip ASM, de-randomize.
-		rsData, err := tmsc.UnwrapCADU(cadu, nil, true)
-		if err != nil {
+		// Step 1: Decode CADU — strip ASM, de-randomize, RS decode.
+		results := profile.DecodeHard(cadu)
+		if len(results) == 0 {
 			crcRejects++
 			continue
 		}
+		result := results[0]
 
-		// Step 2: RS decode — correct symbol errors from the noisy channel.
-		corrected, corr, err := rs.Decode(rsData)
-		if err != nil {
+		// Step 2: Check the RS result — symbol errors from the noisy
+		// channel are corrected, or the frame is flagged uncorrectable.
+		if !result.OK() {
 			rsFailed++
 			fmt.Printf("  [RS FAIL] Uncorrectable errors in frame\n")
 			continue
 		}
-		if corr > 0 {
+		if corr := result.TotalCorrected(); corr > 0 {
 			rsCorrections += corr
 			fmt.Printf("  [RS OK] Corrected %d symbol errors\n", corr)
 		}
 
-		// Step 3: Extract original frame (strip virtual fill) and decode.
-		frameData := corrected[:frameLength]
-		frame, err := tmdl.DecodeTMTransferFrame(frameData)
+		// Step 3: Decode the Transfer Frame (virtual fill already removed).
+		frame, err := tmdl.DecodeTMTransferFrame(result.Frame)
 		if err != nil {
 			crcRejects++
 			continue
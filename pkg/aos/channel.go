@@ -96,6 +96,10 @@ func (d *FrameGapDetector) VCFrameGap() int {
 	return d.lastVCGap
 }
 
+// OCFProvider supplies the Operational Control Field of outgoing frames.
+// See MasterChannel.SetOCFProvider.
+type OCFProvider = sdl.OCFProvider
+
 // MasterChannel manages AOS Transfer Frames for a Master Channel
 // identified by SCID.
 type MasterChannel struct {
@@ -104,6 +108,9 @@ type MasterChannel struct {
 	mux      *VirtualChannelMultiplexer
 	channels map[uint8]*VirtualChannel
 	detector *FrameGapDetector
+	ocf      OCFProvider
+	lastOCF  []byte // last valid provider report
+	ocfErr   error  // last OCF insertion problem, see OCFError
 }
 
 // NewMasterChannel creates a new Master Channel for the given spacecraft ID.
@@ -144,15 +151,32 @@ func (mc *MasterChannel) VCFrameGap() int {
 	return mc.detector.VCFrameGap()
 }
 
-// GetNextFrame retrieves the next frame from the multiplexer.
+// SetOCFProvider installs a provider that fills the OCF of every frame
+// leaving this Master Channel, idle frames included. Frames without an
+// OCF are passed through unchanged. Pass nil to remove the provider.
+//
+// On the spacecraft side this closes the COP-1 loop: pass the OCF method
+// of a cop.CLCWReporter and every frame reports the current CLCW.
+func (mc *MasterChannel) SetOCFProvider(p OCFProvider) {
+	mc.ocf = p
+}
+
+// GetNextFrame retrieves the next frame from the multiplexer. A frame
+// taken off the multiplexer is always returned: a problem filling its
+// OCF is reported by OCFError instead.
 func (mc *MasterChannel) GetNextFrame() (*TransferFrame, error) {
-	return mc.mux.Next()
+	frame, err := mc.mux.Next()
+	if err != nil {
+		return nil, err
+	}
+	mc.insertOCF(frame)
+	return frame, nil
 }
 
 // GetNextFrameOrIdle returns the next frame or an OID idle frame if
 // no Virtual Channel has pending data.
 func (mc *MasterChannel) GetNextFrameOrIdle() (*TransferFrame, error) {
-	frame, err := mc.mux.Next()
+	frame, err := mc.GetNextFrame()
 	if err == nil {
 		return frame, nil
 	}
@@ -162,7 +186,41 @@ func (mc *MasterChannel) GetNextFrameOrIdle() (*TransferFrame, error) {
 	if mc.config.FrameLength == 0 {
 		return nil, sdl.ErrNoFramesAvailable
 	}
-	return NewIdleFrame(mc.scid, mc.config)
+	idle, err := NewIdleFrame(mc.scid, mc.config)
+	if err != nil {
+		return nil, err
+	}
+	mc.insertOCF(idle)
+	return idle, nil
+}
+
+// OCFError returns the problem met by the last OCF insertion, or nil if
+// the provider's report was inserted. A report that is not OCFSize bytes
+// gives ErrInvalidOCFLength, and the last valid report is inserted
+// instead. A frame whose FECF cannot be recomputed keeps its previous OCF.
+func (mc *MasterChannel) OCFError() error { return mc.ocfErr }
+
+// insertOCF overwrites the frame's OCF with the provider's report and
+// recomputes the FECF. It never fails the frame; see OCFError.
+func (mc *MasterChannel) insertOCF(frame *TransferFrame) {
+	if mc.ocf == nil || len(frame.OCF) != OCFSize {
+		return
+	}
+	mc.ocfErr = nil
+	if ocf := mc.ocf(); len(ocf) == OCFSize {
+		mc.lastOCF = append([]byte(nil), ocf...)
+	} else {
+		mc.ocfErr = ErrInvalidOCFLength
+	}
+	if mc.lastOCF == nil {
+		return
+	}
+	prevOCF, prevFEC := frame.OCF, frame.FECF
+	frame.OCF = append([]byte(nil), mc.lastOCF...)
+	if err := recomputeFECF(frame); err != nil {
+		frame.OCF, frame.FECF = prevOCF, prevFEC
+		mc.ocfErr = err
+	}
 }
 
 // scidByte returns the 8-bit AOS Spacecraft Identifier.
@@ -216,12 +274,20 @@ func (pc *PhysicalChannel) GetNextFrameOrIdle() (*TransferFrame, error) {
 	if pc.config.FrameLength == 0 {
 		return nil, sdl.ErrNoFramesAvailable
 	}
-	var scid uint8
-	for _, mc := range pc.masterChannels {
-		scid = mc.scidByte()
+	var mc *MasterChannel
+	for _, m := range pc.masterChannels {
+		mc = m
 		break
 	}
-	return NewIdleFrame(scid, pc.config)
+	if mc == nil {
+		return NewIdleFrame(0, pc.config)
+	}
+	idle, err := NewIdleFrame(mc.scidByte(), pc.config)
+	if err != nil {
+		return nil, err
+	}
+	mc.insertOCF(idle)
+	return idle, nil
 }
 
 // AddFrame demultiplexes an inbound frame to the appropriate Master Channel.
@@ -0,0 +1,220 @@
+// Package cfdp implements the CCSDS File Delivery Protocol (CFDP) as
+// specified in CCSDS 727.0-B-5.
+//
+// The PDU codec covers the fixed PDU header and the Metadata, File Data,
+// EOF, Finished, ACK, NAK, Prompt and Keep Alive PDUs with their TLV
+// parameters, including the large file flag that widens file sizes and
+// offsets to 64 bits.
+//
+// An Entity is a CFDP protocol entity. It sends files from its Filestore
+// to remote entities and receives files into it, exchanging PDUs over a
+// Transport. Transports are provided for the Space Packet Protocol, the
+// Encapsulation Packet Protocol and the USLP MAP access service.
+//
+// Supported procedures:
+//   - Class 1: unacknowledged transfer, with optional transaction closure
+//   - Class 2: acknowledged transfer with deferred, immediate, prompted
+//     and asynchronous NAKs, keep-alive and positive ACK timers
+//   - Suspend, resume and cancel, fault handlers, and transaction state
+//     saved to disk to survive restarts
+//   - File checksums: modular, CRC-32C, CRC-32 (IEEE 802.3) and null
+//   - Filestore requests and messages to user
+package cfdp
+
+import (
+	"strconv"
+)
+
+// Version is the CFDP protocol version number carried in the PDU header
+// (version 2 of the protocol, encoded as 001).
+const Version uint8 = 1
+
+// EntityID identifies a CFDP entity.
+type EntityID uint64
+
+// TransactionID identifies a transaction: the source entity and the
+// sequence number it assigned.
+type TransactionID struct {
+	Source EntityID
+	Seq    uint64
+}
+
+// String returns the transaction ID as source:seq.
+func (id TransactionID) String() string {
+	return strconv.FormatUint(uint64(id.Source), 10) + ":" + strconv.FormatUint(id.Seq, 10)
+}
+
+// PDUType distinguishes file directive PDUs from file data PDUs.
+type PDUType uint8
+
+// PDU types.
+const (
+	PDUFileDirective PDUType = 0
+	PDUFileData      PDUType = 1
+)
+
+// Direction is the direction of a PDU within a transaction.
+type Direction uint8
+
+// Directions.
+const (
+	TowardReceiver Direction = 0
+	TowardSender   Direction = 1
+)
+
+// TransmissionMode selects acknowledged (Class 2) or unacknowledged
+// (Class 1) transfer.
+type TransmissionMode uint8
+
+// Transmission modes.
+const (
+	Acknowledged   TransmissionMode = 0
+	Unacknowledged TransmissionMode = 1
+)
+
+// String returns the transmission mode name.
+func (m TransmissionMode) String() string {
+	switch m {
+	case Acknowledged:
+		return "Acknowledged"
+	case Unacknowledged:
+		return "Unacknowledged"
+	default:
+		return "Unknown"
+	}
+}
+
+// DirectiveCode identifies a file directive PDU (CCSDS 727.0-B-5 Table 5-4).
+type DirectiveCode uint8
+
+// File directive codes.
+const (
+	DirectiveEOF       DirectiveCode = 0x04
+	DirectiveFinished  DirectiveCode = 0x05
+	DirectiveACK       DirectiveCode = 0x06
+	DirectiveMetadata  DirectiveCode = 0x07
+	DirectiveNAK       DirectiveCode = 0x08
+	DirectivePrompt    DirectiveCode = 0x09
+	DirectiveKeepAlive DirectiveCode = 0x0C
+)
+
+// String returns the directive name.
+func (d DirectiveCode) String() string {
+	switch d {
+	case DirectiveEOF:
+		return "EOF"
+	case DirectiveFinished:
+		return "Finished"
+	case DirectiveACK:
+		return "ACK"
+	case DirectiveMetadata:
+		return "Metadata"
+	case DirectiveNAK:
+		return "NAK"
+	case DirectivePrompt:
+		return "Prompt"
+	case DirectiveKeepAlive:
+		return "Keep Alive"
+	default:
+		return "Unknown(0x" + strconv.FormatUint(uint64(d), 16) + ")"
+	}
+}
+
+// ConditionCode reports the outcome of a transaction (CCSDS 727.0-B-5 Table 5-5).
+type ConditionCode uint8
+
+// Condition codes.
+const (
+	NoError                 ConditionCode = 0
+	PositiveACKLimitReached ConditionCode = 1
+	KeepAliveLimitReached   ConditionCode = 2
+	InvalidTransmissionMode ConditionCode = 3
+	FilestoreRejection      ConditionCode = 4
+	FileChecksumFailure     ConditionCode = 5
+	FileSizeError           ConditionCode = 6
+	NAKLimitReached         ConditionCode = 7
+	InactivityDetected      ConditionCode = 8
+	InvalidFileStructure    ConditionCode = 9
+	CheckLimitReached       ConditionCode = 10
+	UnsupportedChecksumType ConditionCode = 11
+	SuspendRequestReceived  ConditionCode = 14
+	CancelRequestReceived   ConditionCode = 15
+)
+
+// String returns the condition name.
+func (c ConditionCode) String() string {
+	switch c {
+	case NoError:
+		return "No error"
+	case PositiveACKLimitReached:
+		return "Positive ACK limit reached"
+	case KeepAliveLimitReached:
+		return "Keep alive limit reached"
+	case InvalidTransmissionMode:
+		return "Invalid transmission mode"
+	case FilestoreRejection:
+		return "Filestore rejection"
+	case FileChecksumFailure:
+		return "File checksum failure"
+	case FileSizeError:
+		return "File size error"
+	case NAKLimitReached:
+		return "NAK limit reached"
+	case InactivityDetected:
+		return "Inactivity detected"
+	case InvalidFileStructure:
+		return "Invalid file structure"
+	case CheckLimitReached:
+		return "Check limit reached"
+	case UnsupportedChecksumType:
+		return "Unsupported checksum type"
+	case SuspendRequestReceived:
+		return "Suspend request received"
+	case CancelRequestReceived:
+		return "Cancel request received"
+	default:
+		return "Unknown(" + strconv.Itoa(int(c)) + ")"
+	}
+}
+
+// DeliveryCode reports whether the destination received all file data.
+type DeliveryCode uint8
+
+// Delivery codes.
+const (
+	DataComplete   DeliveryCode = 0
+	DataIncomplete DeliveryCode = 1
+)
+
+// String returns the delivery code name.
+func (d DeliveryCode) String() string {
+	if d == DataComplete {
+		return "Complete"
+	}
+	return "Incomplete"
+}
+
+// FileStatus reports what the destination did with the received file.
+type FileStatus uint8
+
+// File status codes.
+const (
+	FileDiscarded        FileStatus = 0 // discarded deliberately
+	FileStoreRejected    FileStatus = 1 // discarded due to filestore rejection
+	FileRetained         FileStatus = 2 // retained in the filestore successfully
+	FileStatusUnreported FileStatus = 3
+)
+
+// String returns the file status name.
+func (s FileStatus) String() string {
+	switch s {
+	case FileDiscarded:
+		return "Discarded"
+	case FileStoreRejected:
+		return "Filestore rejection"
+	case FileRetained:
+		return "Retained"
+	default:
+		return "Unreported"
+	}
+}
@@ -0,0 +1,52 @@
+package cfdp_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+)
+
+func TestNewChecksum_Modular(t *testing.T) {
+	h, err := cfdp.NewChecksum(cfdp.ChecksumModular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Words 0x01020304 + 0x05060700 (zero-padded tail).
+	h.Write([]byte{1, 2})
+	h.Write([]byte{3, 4, 5})
+	h.Write([]byte{6, 7})
+	if got := h.Sum32(); got != 0x06080A04 {
+		t.Errorf("sum %#08x", got)
+	}
+
+	h.Reset()
+	h.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 2})
+	if got := h.Sum32(); got != 1 {
+		t.Errorf("wrapped sum %#08x", got)
+	}
+}
+
+func TestNewChecksum_CRC(t *testing.T) {
+	data := []byte("123456789")
+	for _, tc := range []struct {
+		typ  cfdp.ChecksumType
+		want uint32
+	}{
+		{cfdp.ChecksumCRC32C, 0xE3069283},
+		{cfdp.ChecksumCRC32, 0xCBF43926},
+		{cfdp.ChecksumNull, 0},
+	} {
+		h, err := cfdp.NewChecksum(tc.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write(data)
+		if got := h.Sum32(); got != tc.want {
+			t.Errorf("%s: %#08x, want %#08x", tc.typ, got, tc.want)
+		}
+	}
+	if _, err := cfdp.NewChecksum(cfdp.ChecksumProximity1); !errors.Is(err, cfdp.ErrUnsupportedChecksum) {
+		t.Errorf("expected ErrUnsupportedChecksum, got %v", err)
+	}
+}
@@ -0,0 +1,599 @@
+package cfdp
+
+import (
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// segment is a byte range [start, end) of a file.
+type segment struct {
+	start, end uint64
+}
+
+// segments is a sorted list of disjoint ranges.
+type segments []segment
+
+// add adds [start, end), merging overlapping and adjacent ranges.
+func (s segments) add(start, end uint64) segments {
+	if start >= end {
+		return s
+	}
+	i, _ := slices.BinarySearchFunc(s, start, func(g segment, v uint64) int {
+		switch {
+		case g.end < v:
+			return -1
+		case g.start > v:
+			return 1
+		default:
+			return 0
+		}
+	})
+	j := i
+	for j < len(s) && s[j].start <= end {
+		start = min(start, s[j].start)
+		end = max(end, s[j].end)
+		j++
+	}
+	return slices.Replace(s, i, j, segment{start, end})
+}
+
+// remove removes [start, end), splitting ranges as needed.
+func (s segments) remove(start, end uint64) segments {
+	if start >= end {
+		return s
+	}
+	var out segments
+	for _, g := range s {
+		if g.end <= start || g.start >= end {
+			out = append(out, g)
+			continue
+		}
+		if g.start < start {
+			out = append(out, segment{g.start, start})
+		}
+		if g.end > end {
+			out = append(out, segment{end, g.end})
+		}
+	}
+	return out
+}
+
+// complete reports whether [0, size) is covered.
+func (s segments) complete(size uint64) bool {
+	if size == 0 {
+		return true
+	}
+	return len(s) > 0 && s[0].start == 0 && s[0].end >= size
+}
+
+// gaps returns the ranges of [0, size) not covered.
+func (s segments) gaps(size uint64) []segment {
+	var out []segment
+	var next uint64
+	for _, g := range s {
+		if g.start >= size {
+			break
+		}
+		if g.start > next {
+			out = append(out, segment{next, g.start})
+		}
+		next = g.end
+	}
+	if next < size {
+		out = append(out, segment{next, size})
+	}
+	return out
+}
+
+// extent returns the end of the last range.
+func (s segments) extent() uint64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].end
+}
+
+// total returns the number of bytes covered.
+func (s segments) total() uint64 {
+	var n uint64
+	for _, g := range s {
+		n += g.end - g.start
+	}
+	return n
+}
+
+// receiver is the receiving side of a transaction
+// (CCSDS 727.0-B-5 Sections 4.6.1.2 and 4.6.4.2).
+type receiver struct {
+	id          TransactionID
+	header      Header       // for PDUs sent toward the sender
+	remote      RemoteEntity // the sending entity
+	metadata    *MetadataPDU
+	tmp         *os.File // file data, created on the first File Data PDU
+	received    segments
+	eof         *EOFPDU
+	deadline    time.Time // inactivity deadline
+	check       time.Time // Class 1: check timer after EOF with data missing
+	checks      int
+	nakTimer    time.Time // Class 2: NAK timer after EOF with data missing
+	nakCount    int
+	nakReceived uint64       // Class 2: bytes received when the last NAK was sent
+	keepAlive   time.Time    // Class 2: next Keep Alive PDU
+	fin         *FinishedPDU // Class 2: sent, awaiting its ACK
+	ackTimer    time.Time
+	ackCount    int
+	suspended   bool
+	dirty       bool // state changed since last saved
+}
+
+// newReceiver starts a receiving transaction for the PDU header h.
+func (e *Entity) newReceiver(h *Header) *receiver {
+	now := e.clock.Now()
+	r := &receiver{
+		id:       h.Transaction(),
+		header:   *h,
+		remote:   e.remote(h.SourceID),
+		deadline: now.Add(e.inactivity),
+	}
+	r.header.Type = PDUFileDirective
+	r.header.Direction = TowardSender
+	r.header.SegmentMetadata = false
+	if r.remote.KeepAliveInterval > 0 {
+		r.keepAlive = now.Add(r.remote.KeepAliveInterval)
+	}
+	return r
+}
+
+// acknowledged reports whether the transaction is Class 2.
+func (r *receiver) acknowledged() bool {
+	return r.header.Mode == Acknowledged
+}
+
+// handle processes a PDU sent toward the receiver.
+func (r *receiver) handle(e *Entity, p PDU) error {
+	now := e.clock.Now()
+	r.deadline = now.Add(e.inactivity)
+	r.dirty = true
+
+	if r.fin != nil {
+		// Class 2 Finished sent: only its ACK and retransmitted EOFs matter.
+		switch p := p.(type) {
+		case *ACKPDU:
+			if p.Directive == DirectiveFinished {
+				r.complete(e, r.fin.Condition)
+			}
+		case *EOFPDU:
+			return r.ackEOF(e, p)
+		}
+		return nil
+	}
+
+	switch p := p.(type) {
+	case *MetadataPDU:
+		if r.metadata != nil {
+			return nil
+		}
+		r.metadata = p
+		e.notes = append(e.notes, Indication{
+			Type:        IndicationMetadataRecv,
+			Transaction: r.id,
+			SourceFile:  p.SourceFileName,
+			DestFile:    p.DestFileName,
+			FileSize:    p.FileSize,
+			Messages:    p.MessagesToUser,
+		})
+		if r.eof != nil {
+			return r.checkComplete(e)
+		}
+	case *FileDataPDU:
+		extent := r.received.extent()
+		if err := r.write(e, p); err != nil {
+			return r.fault(e, FilestoreRejection)
+		}
+		e.notes = append(e.notes, Indication{
+			Type:        IndicationFileSegmentRecv,
+			Transaction: r.id,
+			Offset:      p.Offset,
+			Length:      len(p.Data),
+		})
+		if r.eof != nil {
+			if r.received.extent() > r.eof.FileSize {
+				if err := r.fault(e, FileSizeError); err != nil || r.done(e) {
+					return err
+				}
+			}
+			return r.checkComplete(e)
+		}
+		if r.acknowledged() && r.remote.ImmediateNAK && p.Offset > extent {
+			return r.sendNAK(e)
+		}
+	case *EOFPDU:
+		if r.acknowledged() {
+			if err := r.ackEOF(e, p); err != nil {
+				return err
+			}
+		}
+		if r.eof != nil {
+			return nil
+		}
+		r.eof = p
+		e.notes = append(e.notes, Indication{Type: IndicationEOFRecv, Transaction: r.id, FileSize: p.FileSize})
+		if p.Condition != NoError {
+			// Notice of cancellation from the sender.
+			return r.finish(e, p.Condition, FileDiscarded)
+		}
+		if r.received.extent() > p.FileSize {
+			if err := r.fault(e, FileSizeError); err != nil || r.done(e) {
+				return err
+			}
+		}
+		if err := r.checkComplete(e); err != nil || r.done(e) {
+			return err
+		}
+		if r.acknowledged() {
+			return r.sendNAK(e)
+		}
+		r.check = now.Add(e.checkTimeout)
+	case *PromptPDU:
+		if !r.acknowledged() {
+			return nil
+		}
+		if p.Response == PromptKeepAlive {
+			return r.sendKeepAlive(e)
+		}
+		return r.sendNAK(e)
+	case *ACKPDU:
+		return nil
+	default:
+		return ErrInvalidPDU
+	}
+	return nil
+}
+
+// ackEOF acknowledges an EOF PDU.
+func (r *receiver) ackEOF(e *Entity, eof *EOFPDU) error {
+	status := TransactionActive
+	if r.fin != nil {
+		status = TransactionTerminated
+	}
+	return e.send(&ACKPDU{Header: r.header, Directive: DirectiveEOF, Condition: eof.Condition, Status: status})
+}
+
+// sendNAK requests the metadata, if missing, and the file data missing
+// up to the EOF file size, or up to the data received so far before
+// EOF. Long lists are split over several NAK PDUs. It restarts the NAK
+// timer.
+func (r *receiver) sendNAK(e *Entity) error {
+	end := r.received.extent()
+	if r.eof != nil {
+		end = r.eof.FileSize
+	}
+	var reqs []SegmentRequest
+	if r.metadata == nil {
+		reqs = append(reqs, SegmentRequest{})
+	}
+	for _, g := range r.received.gaps(end) {
+		reqs = append(reqs, SegmentRequest{Start: g.start, End: g.end})
+	}
+	r.nakTimer = e.clock.Now().Add(r.remote.NAKTimeout)
+	r.nakReceived = r.received.total()
+	r.dirty = true
+
+	start := uint64(0)
+	for {
+		n := min(len(reqs), DefaultNAKSegments)
+		scopeEnd := end
+		if n < len(reqs) {
+			scopeEnd = reqs[n-1].End
+		}
+		nak := &NAKPDU{Header: r.header, ScopeStart: start, ScopeEnd: scopeEnd, Segments: reqs[:n]}
+		if err := e.send(nak); err != nil {
+			return err
+		}
+		reqs = reqs[n:]
+		start = scopeEnd
+		if len(reqs) == 0 {
+			return nil
+		}
+	}
+}
+
+// sendKeepAlive reports the extent of the data received.
+func (r *receiver) sendKeepAlive(e *Entity) error {
+	return e.send(&KeepAlivePDU{Header: r.header, Progress: r.received.extent()})
+}
+
+// write stores a file segment in the temporary file.
+func (r *receiver) write(e *Entity, p *FileDataPDU) error {
+	if r.tmp == nil {
+		tmp, err := e.fs.createTemp()
+		if err != nil {
+			return err
+		}
+		r.tmp = tmp
+	}
+	if _, err := r.tmp.WriteAt(p.Data, int64(p.Offset)); err != nil {
+		return err
+	}
+	r.received = r.received.add(p.Offset, p.Offset+uint64(len(p.Data)))
+	return nil
+}
+
+// checkComplete completes the transaction once the metadata and all
+// file data up to the EOF file size have arrived.
+func (r *receiver) checkComplete(e *Entity) error {
+	if r.metadata == nil || !r.received.complete(r.eof.FileSize) {
+		return nil
+	}
+	if r.metadata.SourceFileName == "" {
+		return r.deliver(e)
+	}
+	sum, err := NewChecksum(r.metadata.ChecksumType)
+	if err != nil {
+		if err := r.fault(e, UnsupportedChecksumType); err != nil || r.done(e) {
+			return err
+		}
+		return r.deliver(e)
+	}
+	if r.eof.FileSize > 0 {
+		if _, err := io.Copy(sum, io.NewSectionReader(r.tmp, 0, int64(r.eof.FileSize))); err != nil {
+			return r.fault(e, FilestoreRejection)
+		}
+	}
+	if sum.Sum32() != r.eof.Checksum {
+		if err := r.fault(e, FileChecksumFailure); err != nil || r.done(e) {
+			return err
+		}
+	}
+	return r.deliver(e)
+}
+
+// deliver moves the file into place, executes the filestore requests
+// and ends the transaction.
+func (r *receiver) deliver(e *Entity) error {
+	status := FileRetained
+	if r.metadata.SourceFileName != "" {
+		var err error
+		if r.tmp == nil {
+			// An empty file has no file data PDUs.
+			if r.tmp, err = e.fs.createTemp(); err != nil {
+				return r.finishWith(e, FilestoreRejection, DataComplete, FileStoreRejected, nil)
+			}
+		}
+		name := r.tmp.Name()
+		err = r.tmp.Truncate(int64(r.eof.FileSize))
+		if err == nil {
+			err = r.tmp.Close()
+		}
+		r.tmp = nil
+		if err == nil {
+			err = e.fs.commit(name, r.metadata.DestFileName)
+		}
+		if err != nil {
+			os.Remove(name)
+			return r.finishWith(e, FilestoreRejection, DataComplete, FileStoreRejected, nil)
+		}
+	}
+
+	// A failed request stops the rest; the responses report it.
+	var resps []FilestoreResponse
+	failed := false
+	for _, req := range r.metadata.FilestoreRequests {
+		if failed {
+			resps = append(resps, FilestoreResponse{Action: req.Action, Status: FilestoreNotPerformed,
+				FirstName: req.FirstName, SecondName: req.SecondName})
+			continue
+		}
+		resp := e.fs.Execute(req)
+		resps = append(resps, resp)
+		failed = resp.Status != FilestoreSuccess
+	}
+	if r.metadata.SourceFileName == "" {
+		status = FileStatusUnreported
+	}
+	return r.finishWith(e, NoError, DataComplete, status, resps)
+}
+
+// poll services the transaction timers and sends Keep Alive PDUs.
+func (r *receiver) poll(e *Entity, now time.Time) error {
+	if r.suspended {
+		return nil
+	}
+	if r.fin != nil {
+		if now.Before(r.ackTimer) {
+			return nil
+		}
+		if r.ackCount >= r.remote.ACKLimit {
+			return r.fault(e, PositiveACKLimitReached)
+		}
+		r.ackCount++
+		r.ackTimer = now.Add(r.remote.ACKTimeout)
+		r.dirty = true
+		return e.send(r.fin)
+	}
+
+	if r.eof != nil && !r.acknowledged() && !r.check.IsZero() && !now.Before(r.check) {
+		r.checks++
+		if r.checks >= e.checkLimit {
+			r.check = time.Time{}
+			if err := r.fault(e, CheckLimitReached); err != nil || r.done(e) {
+				return err
+			}
+		} else {
+			r.check = now.Add(e.checkTimeout)
+		}
+	}
+	if r.eof != nil && r.acknowledged() && !r.nakTimer.IsZero() && !now.Before(r.nakTimer) {
+		if r.received.total() > r.nakReceived {
+			r.nakCount = 0
+		}
+		r.nakCount++
+		if r.nakCount >= r.remote.NAKLimit {
+			r.nakTimer = time.Time{}
+			if err := r.fault(e, NAKLimitReached); err != nil || r.done(e) {
+				return err
+			}
+		} else if err := r.sendNAK(e); err != nil {
+			return err
+		}
+	}
+	if r.acknowledged() && !r.keepAlive.IsZero() && !now.Before(r.keepAlive) {
+		r.keepAlive = now.Add(r.remote.KeepAliveInterval)
+		if err := r.sendKeepAlive(e); err != nil {
+			return err
+		}
+	}
+	if now.After(r.deadline) {
+		r.deadline = now.Add(e.inactivity)
+		return r.fault(e, InactivityDetected)
+	}
+	return nil
+}
+
+// fault applies the fault handler for condition c. With the Ignore
+// handler the transaction carries on.
+func (r *receiver) fault(e *Entity, c ConditionCode) error {
+	var overrides []FaultHandlerOverride
+	if r.metadata != nil {
+		overrides = r.metadata.FaultHandlers
+	}
+	switch e.faultHandler(overrides, c) {
+	case HandlerIgnore:
+		e.notes = append(e.notes, Indication{Type: IndicationFault, Transaction: r.id, Condition: c})
+		if r.fin != nil {
+			r.ackCount = 0
+		}
+		return nil
+	case HandlerSuspend:
+		r.suspend(e, c)
+		return nil
+	case HandlerAbandon:
+		r.discard()
+		r.end(e)
+		e.notes = append(e.notes, Indication{Type: IndicationAbandoned, Transaction: r.id, Condition: c})
+		return nil
+	default:
+		return r.cancel(e, c)
+	}
+}
+
+// cancel discards the file and ends the transaction with condition c.
+// Once the Finished PDU is sent the file is already delivered, and the
+// transaction just ends.
+func (r *receiver) cancel(e *Entity, c ConditionCode) error {
+	if r.fin != nil {
+		r.complete(e, c)
+		return nil
+	}
+	return r.finish(e, c, FileDiscarded)
+}
+
+func (r *receiver) suspend(e *Entity, c ConditionCode) {
+	if r.suspended {
+		return
+	}
+	r.suspended = true
+	r.dirty = true
+	e.notes = append(e.notes, Indication{Type: IndicationSuspended, Transaction: r.id, Condition: c})
+}
+
+func (r *receiver) resume(e *Entity, now time.Time) {
+	if !r.suspended {
+		return
+	}
+	r.suspended = false
+	r.deadline = now.Add(e.inactivity)
+	if !r.check.IsZero() {
+		r.check = now.Add(e.checkTimeout)
+	}
+	if !r.nakTimer.IsZero() {
+		r.nakTimer = now.Add(r.remote.NAKTimeout)
+	}
+	if !r.keepAlive.IsZero() {
+		r.keepAlive = now.Add(r.remote.KeepAliveInterval)
+	}
+	r.ackTimer = now.Add(r.remote.ACKTimeout)
+	r.dirty = true
+	e.notes = append(e.notes, Indication{Type: IndicationResumed, Transaction: r.id, Offset: r.received.extent()})
+}
+
+// done reports whether the transaction has ended or, in Class 2, sent
+// its Finished PDU.
+func (r *receiver) done(e *Entity) bool {
+	return r.fin != nil || e.receivers[r.id] != r
+}
+
+// finish discards any received data and ends the transaction.
+func (r *receiver) finish(e *Entity, c ConditionCode, status FileStatus) error {
+	r.discard()
+	return r.finishWith(e, c, DataIncomplete, status, nil)
+}
+
+// finishWith reports the outcome of the transaction. It sends the
+// Finished PDU in Class 1 when the sender requested closure, and in
+// Class 2 unless the sender cancelled. A Class 2 transaction then waits
+// for the ACK of its Finished PDU before it ends.
+func (r *receiver) finishWith(e *Entity, c ConditionCode, d DeliveryCode, status FileStatus, resps []FilestoreResponse) error {
+	fin := &FinishedPDU{Header: r.header, Condition: c, Delivery: d, Status: status, FilestoreResponses: resps}
+	if c != NoError {
+		id := e.id
+		fin.FaultLocation = &id
+	}
+	closure := r.metadata != nil && r.metadata.ClosureRequested
+	if r.acknowledged() {
+		closure = r.eof == nil || r.eof.Condition == NoError
+	}
+	r.fin = fin
+	if !closure || !r.acknowledged() {
+		r.complete(e, c)
+		if !closure {
+			return nil
+		}
+		return e.send(fin)
+	}
+
+	r.ackCount = 1
+	r.ackTimer = e.clock.Now().Add(r.remote.ACKTimeout)
+	r.dirty = true
+	return e.send(fin)
+}
+
+// complete ends the transaction with a Transaction-Finished indication
+// built from the Finished PDU, with condition c.
+func (r *receiver) complete(e *Entity, c ConditionCode) {
+	r.end(e)
+	e.notes = append(e.notes, Indication{
+		Type:               IndicationTransactionFinished,
+		Transaction:        r.id,
+		Condition:          c,
+		Delivery:           r.fin.Delivery,
+		Status:             r.fin.Status,
+		FilestoreResponses: r.fin.FilestoreResponses,
+	})
+}
+
+// end removes the transaction and its saved state.
+func (r *receiver) end(e *Entity) {
+	r.close()
+	delete(e.receivers, r.id)
+	e.forget(r.id)
+}
+
+// discard removes the temporary file.
+func (r *receiver) discard() {
+	if r.tmp != nil {
+		name := r.tmp.Name()
+		r.tmp.Close()
+		os.Remove(name)
+		r.tmp = nil
+	}
+}
+
+func (r *receiver) close() {
+	if r.tmp != nil {
+		r.tmp.Close()
+		r.tmp = nil
+	}
+}
@@ -0,0 +1,552 @@
+package cfdp
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Clock supplies the current time to the transaction timers.
+// Inject a fake clock with WithClock for deterministic tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Default entity parameters.
+const (
+	DefaultEntityIDLength    = 2
+	DefaultSeqNumLength      = 4
+	DefaultMaxFileSegment    = 1024
+	DefaultInactivityTimeout = 30 * time.Second
+	DefaultCheckTimeout      = 10 * time.Second
+	DefaultCheckLimit        = 3
+	DefaultBurst             = 16
+	DefaultACKTimeout        = 10 * time.Second
+	DefaultACKLimit          = 3
+	DefaultNAKTimeout        = 10 * time.Second
+	DefaultNAKLimit          = 3
+	DefaultNAKSegments       = 64
+)
+
+// RemoteEntity holds the management information for transactions with
+// one remote entity (CCSDS 727.0-B-5 Section 8.2).
+//
+// Timer and limit fields left zero take the package defaults. The NAK
+// and keep-alive fields apply to transactions the remote entity sends.
+type RemoteEntity struct {
+	ID                EntityID
+	MaxFileSegment    int           // file data bytes per File Data PDU; default DefaultMaxFileSegment
+	Acknowledged      bool          // send in Class 2 instead of Class 1
+	ClosureRequested  bool          // Class 1: ask the receiver for a Finished PDU
+	ChecksumType      ChecksumType  // checksum of files sent to the entity
+	CRC               bool          // append a CRC-16 to PDUs sent to the entity
+	ACKTimeout        time.Duration // positive ACK timer for EOF and Finished PDUs
+	ACKLimit          int           // EOF or Finished transmissions before the ACK limit fault
+	NAKTimeout        time.Duration // wait for retransmitted data before the next NAK
+	NAKLimit          int           // NAKs without progress before the NAK limit fault
+	ImmediateNAK      bool          // send a NAK as soon as a gap in the file data is seen
+	KeepAliveInterval time.Duration // receiver: period of Keep Alive PDUs; zero disables
+	KeepAliveLimit    uint64        // sender: bytes the receiver may lag; zero disables
+}
+
+// IndicationType identifies a CFDP user indication (CCSDS 727.0-B-5 Section 3.5).
+type IndicationType int
+
+const (
+	IndicationTransaction         IndicationType = iota // Put request accepted
+	IndicationEOFSent                                   // sender: EOF PDU sent
+	IndicationTransactionFinished                       // transaction complete, see Condition, Delivery and Status
+	IndicationMetadataRecv                              // receiver: Metadata PDU received
+	IndicationFileSegmentRecv                           // receiver: File Data PDU received
+	IndicationEOFRecv                                   // receiver: EOF PDU received
+	IndicationFault                                     // fault ignored, see Condition
+	IndicationAbandoned                                 // transaction abandoned, see Condition
+	IndicationSuspended                                 // transaction suspended, see Condition
+	IndicationResumed                                   // transaction resumed
+)
+
+// String returns the indication name.
+func (t IndicationType) String() string {
+	switch t {
+	case IndicationTransaction:
+		return "Transaction"
+	case IndicationEOFSent:
+		return "EOF-Sent"
+	case IndicationTransactionFinished:
+		return "Transaction-Finished"
+	case IndicationMetadataRecv:
+		return "Metadata-Recv"
+	case IndicationFileSegmentRecv:
+		return "File-Segment-Recv"
+	case IndicationEOFRecv:
+		return "EOF-Recv"
+	case IndicationFault:
+		return "Fault"
+	case IndicationAbandoned:
+		return "Abandoned"
+	case IndicationSuspended:
+		return "Suspended"
+	case IndicationResumed:
+		return "Resumed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Indication is delivered to the handler installed with
+// WithIndicationHandler. The handler is called without the entity's
+// lock held, so it may call back into the entity.
+type Indication struct {
+	Type               IndicationType
+	Transaction        TransactionID
+	Condition          ConditionCode       // Fault, Abandoned, Suspended and Transaction-Finished
+	Delivery           DeliveryCode        // Transaction-Finished
+	Status             FileStatus          // Transaction-Finished
+	FilestoreResponses []FilestoreResponse // Transaction-Finished
+	SourceFile         string              // Metadata-Recv
+	DestFile           string              // Metadata-Recv
+	FileSize           uint64              // Metadata-Recv and EOF-Recv
+	Messages           [][]byte            // Metadata-Recv
+	Offset             uint64              // File-Segment-Recv; Resumed: progress
+	Length             int                 // File-Segment-Recv
+}
+
+// EntityOption configures optional entity parameters.
+type EntityOption func(*Entity)
+
+// WithClock sets the clock used by the transaction timers.
+func WithClock(c Clock) EntityOption {
+	return func(e *Entity) {
+		e.clock = c
+	}
+}
+
+// WithIndicationHandler installs a callback for user indications.
+func WithIndicationHandler(h func(Indication)) EntityOption {
+	return func(e *Entity) {
+		e.handler = h
+	}
+}
+
+// WithRemoteEntity sets the parameters for transactions with a remote
+// entity. Entities without one use the defaults.
+func WithRemoteEntity(r RemoteEntity) EntityOption {
+	return func(e *Entity) {
+		e.remotes[r.ID] = r
+	}
+}
+
+// WithIDLengths sets the entity ID and sequence number lengths, in
+// bytes, of PDUs the entity originates.
+func WithIDLengths(entityID, seqNum uint8) EntityOption {
+	return func(e *Entity) {
+		e.idLength = entityID
+		e.seqLength = seqNum
+	}
+}
+
+// WithInactivityTimeout sets how long a transaction may go without
+// receiving a PDU before the Inactivity fault.
+func WithInactivityTimeout(d time.Duration) EntityOption {
+	return func(e *Entity) {
+		e.inactivity = d
+	}
+}
+
+// WithCheckTimeout sets how long a Class 1 receiver waits for missing
+// file data after EOF, and how many times it waits before the Check
+// Limit Reached fault.
+func WithCheckTimeout(d time.Duration, limit int) EntityOption {
+	return func(e *Entity) {
+		e.checkTimeout = d
+		e.checkLimit = limit
+	}
+}
+
+// WithFaultHandler sets the default handler for fault condition c.
+// Faults without a handler cancel the transaction. A Metadata PDU
+// fault handler override takes precedence for its transaction.
+func WithFaultHandler(c ConditionCode, h HandlerCode) EntityOption {
+	return func(e *Entity) {
+		e.faultHandlers[c] = h
+	}
+}
+
+// WithBurst sets the number of File Data PDUs each transaction sends
+// per call to Poll.
+func WithBurst(n int) EntityOption {
+	return func(e *Entity) {
+		e.burst = n
+	}
+}
+
+// PutRequest asks the entity to send a file (CCSDS 727.0-B-5 Section 3.4.2).
+// An empty SourceFile sends only metadata, such as filestore requests
+// and messages to user.
+type PutRequest struct {
+	Destination       EntityID
+	SourceFile        string
+	DestFile          string // defaults to SourceFile
+	Acknowledged      *bool  // overrides the remote entity setting
+	ClosureRequested  *bool  // overrides the remote entity setting
+	Messages          [][]byte
+	FilestoreRequests []FilestoreRequest
+	FaultHandlers     []FaultHandlerOverride
+	FlowLabel         []byte
+}
+
+// Entity is a CFDP protocol entity. It originates transactions with Put,
+// consumes PDUs with Handle or Receive, and advances transmissions and
+// timers with Poll.
+//
+// Usage:
+//  1. Create with NewEntity, then Restore if WithStateDir is set
+//  2. Call Put to send a file
+//  3. Call Receive (or Handle) for each incoming PDU
+//  4. Call Poll periodically to send file data and service timers
+//  5. Call Close to save transaction state on shutdown
+type Entity struct {
+	mu            sync.Mutex
+	id            EntityID
+	fs            *Filestore
+	transport     Transport
+	clock         Clock
+	remotes       map[EntityID]RemoteEntity
+	idLength      uint8
+	seqLength     uint8
+	inactivity    time.Duration
+	checkTimeout  time.Duration
+	checkLimit    int
+	burst         int
+	faultHandlers map[ConditionCode]HandlerCode
+	stateDir      string // empty: transactions are not persisted
+	nextSeq       uint64
+	senders       map[TransactionID]*sender
+	receivers     map[TransactionID]*receiver
+	handler       func(Indication)
+	notes         []Indication // indications awaiting dispatch
+}
+
+// NewEntity creates an entity with the given ID, filestore and transport.
+func NewEntity(id EntityID, fs *Filestore, transport Transport, opts ...EntityOption) *Entity {
+	e := &Entity{
+		id:            id,
+		fs:            fs,
+		transport:     transport,
+		clock:         systemClock{},
+		remotes:       make(map[EntityID]RemoteEntity),
+		idLength:      DefaultEntityIDLength,
+		seqLength:     DefaultSeqNumLength,
+		inactivity:    DefaultInactivityTimeout,
+		checkTimeout:  DefaultCheckTimeout,
+		checkLimit:    DefaultCheckLimit,
+		burst:         DefaultBurst,
+		faultHandlers: make(map[ConditionCode]HandlerCode),
+		nextSeq:       1,
+		senders:       make(map[TransactionID]*sender),
+		receivers:     make(map[TransactionID]*receiver),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ID returns the entity ID.
+func (e *Entity) ID() EntityID {
+	return e.id
+}
+
+// Pending returns the number of active transactions.
+func (e *Entity) Pending() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.senders) + len(e.receivers)
+}
+
+// remote returns the parameters for transactions with id.
+func (e *Entity) remote(id EntityID) RemoteEntity {
+	r, ok := e.remotes[id]
+	if !ok {
+		r = RemoteEntity{ID: id}
+	}
+	if r.MaxFileSegment <= 0 {
+		r.MaxFileSegment = DefaultMaxFileSegment
+	}
+	if r.ACKTimeout <= 0 {
+		r.ACKTimeout = DefaultACKTimeout
+	}
+	if r.ACKLimit <= 0 {
+		r.ACKLimit = DefaultACKLimit
+	}
+	if r.NAKTimeout <= 0 {
+		r.NAKTimeout = DefaultNAKTimeout
+	}
+	if r.NAKLimit <= 0 {
+		r.NAKLimit = DefaultNAKLimit
+	}
+	return r
+}
+
+// Put starts a transaction sending a file and returns its ID. The
+// Metadata PDU is sent immediately; file data and EOF follow on Poll.
+func (e *Entity) Put(req PutRequest) (TransactionID, error) {
+	e.mu.Lock()
+	defer e.dispatch()
+	defer e.mu.Unlock()
+
+	s, err := e.newSender(req)
+	if err != nil {
+		return TransactionID{}, err
+	}
+	e.nextSeq++
+	if err := e.send(&s.metadata); err != nil {
+		s.close()
+		return TransactionID{}, err
+	}
+	e.senders[s.id] = s
+	e.notes = append(e.notes, Indication{Type: IndicationTransaction, Transaction: s.id})
+	return s.id, errors.Join(e.saveEntity(), e.save(s))
+}
+
+// Cancel cancels an active transaction. A sender sends an EOF PDU with
+// the Cancel Request Received condition; a receiver discards the file.
+func (e *Entity) Cancel(id TransactionID) error {
+	e.mu.Lock()
+	defer e.dispatch()
+	defer e.mu.Unlock()
+
+	if s, ok := e.senders[id]; ok {
+		return s.cancel(e, CancelRequestReceived)
+	}
+	if r, ok := e.receivers[id]; ok {
+		return r.cancel(e, CancelRequestReceived)
+	}
+	return ErrUnknownTransaction
+}
+
+// Suspend suspends an active transaction. A suspended transaction sends
+// nothing on its own and its timers stop, but it still handles PDUs.
+func (e *Entity) Suspend(id TransactionID) error {
+	e.mu.Lock()
+	defer e.dispatch()
+	defer e.mu.Unlock()
+
+	t, err := e.transaction(id)
+	if err != nil {
+		return err
+	}
+	t.suspend(e, SuspendRequestReceived)
+	return e.save(t)
+}
+
+// Resume resumes a suspended transaction, restarting its timers.
+func (e *Entity) Resume(id TransactionID) error {
+	e.mu.Lock()
+	defer e.dispatch()
+	defer e.mu.Unlock()
+
+	t, err := e.transaction(id)
+	if err != nil {
+		return err
+	}
+	t.resume(e, e.clock.Now())
+	return e.save(t)
+}
+
+// Prompt asks the receiver of an acknowledged transaction sent by this
+// entity for a NAK or Keep Alive PDU.
+func (e *Entity) Prompt(id TransactionID, resp PromptResponse) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, ok := e.senders[id]
+	if !ok {
+		return ErrUnknownTransaction
+	}
+	if s.header.Mode != Acknowledged {
+		return ErrUnsupportedMode
+	}
+	return e.send(&PromptPDU{Header: s.header, Response: resp})
+}
+
+// RequestData sends an asynchronous NAK for the file data still missing
+// from an acknowledged transaction this entity receives.
+func (e *Entity) RequestData(id TransactionID) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.receivers[id]
+	if !ok {
+		return ErrUnknownTransaction
+	}
+	if r.header.Mode != Acknowledged {
+		return ErrUnsupportedMode
+	}
+	return r.sendNAK(e)
+}
+
+// Transactions returns the IDs of the active transactions.
+func (e *Entity) Transactions() []TransactionID {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := make([]TransactionID, 0, len(e.senders)+len(e.receivers))
+	for id := range e.senders {
+		ids = append(ids, id)
+	}
+	for id := range e.receivers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// transaction is the state common to senders and receivers.
+type transaction interface {
+	suspend(e *Entity, c ConditionCode)
+	resume(e *Entity, now time.Time)
+	state() *savedTransaction
+	close()
+}
+
+// transaction returns the active transaction with the ID.
+func (e *Entity) transaction(id TransactionID) (transaction, error) {
+	if s, ok := e.senders[id]; ok {
+		return s, nil
+	}
+	if r, ok := e.receivers[id]; ok {
+		return r, nil
+	}
+	return nil, ErrUnknownTransaction
+}
+
+// Receive reads one PDU from the transport and handles it. It returns
+// ErrNoPDU from a non-blocking transport with nothing waiting.
+func (e *Entity) Receive() error {
+	pdu, err := e.transport.ReceivePDU()
+	if err != nil {
+		return err
+	}
+	return e.Handle(pdu)
+}
+
+// Handle processes one encoded PDU.
+func (e *Entity) Handle(data []byte) error {
+	p, err := DecodePDU(data)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.dispatch()
+	defer e.mu.Unlock()
+
+	h := p.PDUHeader()
+	if h.Direction == TowardSender {
+		if h.SourceID != e.id {
+			return ErrNotForEntity
+		}
+		s, ok := e.senders[h.Transaction()]
+		if !ok {
+			return e.unknownSender(p)
+		}
+		return s.handle(e, p)
+	}
+
+	if h.DestinationID != e.id {
+		return ErrNotForEntity
+	}
+	r, ok := e.receivers[h.Transaction()]
+	if !ok {
+		if _, ok := p.(*ACKPDU); ok {
+			// A retransmitted ACK of a Finished PDU already acknowledged.
+			return nil
+		}
+		r = e.newReceiver(h)
+		e.receivers[r.id] = r
+	}
+	return r.handle(e, p)
+}
+
+// unknownSender answers a PDU toward a sender with no such transaction.
+// A Finished PDU whose ACK was lost is acknowledged again so that the
+// receiver can complete (CCSDS 727.0-B-5 Section 4.6.4.2.4).
+func (e *Entity) unknownSender(p PDU) error {
+	fin, ok := p.(*FinishedPDU)
+	if !ok || fin.Header.Mode != Acknowledged {
+		return ErrUnknownTransaction
+	}
+	h := fin.Header
+	h.Direction = TowardReceiver
+	return e.send(&ACKPDU{Header: h, Directive: DirectiveFinished, Condition: fin.Condition, Status: TransactionTerminated})
+}
+
+// Poll sends pending file data and EOF PDUs and services the
+// transaction timers. An error in one transaction does not stop the
+// others; all errors are returned joined.
+func (e *Entity) Poll() error {
+	e.mu.Lock()
+	defer e.dispatch()
+	defer e.mu.Unlock()
+
+	var errs []error
+	now := e.clock.Now()
+	for _, s := range e.senders {
+		if err := s.poll(e, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, r := range e.receivers {
+		if err := r.poll(e, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := e.saveDirty(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// send encodes and transmits a PDU.
+func (e *Entity) send(p PDU) error {
+	data, err := p.Encode()
+	if err != nil {
+		return err
+	}
+	return e.transport.SendPDU(data)
+}
+
+// faultHandler returns the handler for condition c, taking overrides
+// from the transaction's Metadata PDU before the entity defaults.
+func (e *Entity) faultHandler(overrides []FaultHandlerOverride, c ConditionCode) HandlerCode {
+	for _, o := range overrides {
+		if o.Condition == c {
+			return o.Handler
+		}
+	}
+	if h, ok := e.faultHandlers[c]; ok {
+		return h
+	}
+	return HandlerCancel
+}
+
+// dispatch delivers queued indications to the handler. It must be
+// called without e.mu held.
+func (e *Entity) dispatch() {
+	e.mu.Lock()
+	notes := e.notes
+	e.notes = nil
+	handler := e.handler
+	e.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	for _, n := range notes {
+		handler(n)
+	}
+}
@@ -0,0 +1,739 @@
+package cfdp_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// pipe is one direction of an in-memory link. filter, when set, may
+// drop (return nil) or alter PDUs in transit.
+type pipe struct {
+	queue  [][]byte
+	filter func([]byte) []byte
+}
+
+type endpoint struct {
+	out, in *pipe
+}
+
+func (e endpoint) SendPDU(pdu []byte) error {
+	if e.out.filter != nil {
+		if pdu = e.out.filter(pdu); pdu == nil {
+			return nil
+		}
+	}
+	e.out.queue = append(e.out.queue, pdu)
+	return nil
+}
+
+func (e endpoint) ReceivePDU() ([]byte, error) {
+	if len(e.in.queue) == 0 {
+		return nil, cfdp.ErrNoPDU
+	}
+	pdu := e.in.queue[0]
+	e.in.queue = e.in.queue[1:]
+	return pdu, nil
+}
+
+type testLink struct {
+	clock          *fakeClock
+	up, down       *pipe
+	src, dst       *cfdp.Entity
+	srcFS, dstFS   *cfdp.Filestore
+	srcInd, dstInd []cfdp.Indication
+}
+
+func newTestLink(t *testing.T, remote cfdp.RemoteEntity, opts ...cfdp.EntityOption) *testLink {
+	t.Helper()
+	l := &testLink{clock: &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}, up: &pipe{}, down: &pipe{}}
+	l.srcFS, _ = cfdp.NewFilestore(t.TempDir())
+	l.dstFS, _ = cfdp.NewFilestore(t.TempDir())
+	remote.ID = 2
+	l.src = cfdp.NewEntity(1, l.srcFS, endpoint{out: l.up, in: l.down}, append([]cfdp.EntityOption{
+		cfdp.WithClock(l.clock),
+		cfdp.WithRemoteEntity(remote),
+		cfdp.WithIndicationHandler(func(i cfdp.Indication) { l.srcInd = append(l.srcInd, i) }),
+	}, opts...)...)
+	l.dst = cfdp.NewEntity(2, l.dstFS, endpoint{out: l.down, in: l.up}, append([]cfdp.EntityOption{
+		cfdp.WithClock(l.clock),
+		cfdp.WithIndicationHandler(func(i cfdp.Indication) { l.dstInd = append(l.dstInd, i) }),
+	}, opts...)...)
+	return l
+}
+
+// run polls both entities and delivers PDUs until the link is idle.
+func (l *testLink) run(t *testing.T) {
+	t.Helper()
+	for range 1000 {
+		if err := l.src.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.dst.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		moved := false
+		for _, e := range []*cfdp.Entity{l.dst, l.src} {
+			for {
+				err := e.Receive()
+				if errors.Is(err, cfdp.ErrNoPDU) {
+					break
+				}
+				// PDUs may arrive after their transaction ended.
+				if err != nil && !errors.Is(err, cfdp.ErrUnknownTransaction) {
+					t.Fatal(err)
+				}
+				moved = true
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+	t.Fatal("link did not go idle")
+}
+
+func finished(inds []cfdp.Indication) *cfdp.Indication {
+	for i := range inds {
+		if inds[i].Type == cfdp.IndicationTransactionFinished {
+			return &inds[i]
+		}
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, fs *cfdp.Filestore, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(fs.Root(), name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestEntity_Class1Transfer(t *testing.T) {
+	for _, ct := range []cfdp.ChecksumType{cfdp.ChecksumModular, cfdp.ChecksumCRC32C, cfdp.ChecksumCRC32, cfdp.ChecksumNull} {
+		t.Run(ct.String(), func(t *testing.T) {
+			l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 100, ClosureRequested: true, ChecksumType: ct, CRC: true},
+				cfdp.WithBurst(4))
+			data := testData(1001)
+			writeFile(t, l.srcFS, "image.raw", data)
+
+			id, err := l.src.Put(cfdp.PutRequest{
+				Destination: 2,
+				SourceFile:  "image.raw",
+				DestFile:    "/downlink/image.raw",
+				Messages:    [][]byte{[]byte("hi")},
+				FilestoreRequests: []cfdp.FilestoreRequest{
+					{Action: cfdp.CreateDirectory, FirstName: "archive"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != (cfdp.TransactionID{Source: 1, Seq: 1}) {
+				t.Errorf("transaction %v", id)
+			}
+			l.run(t)
+
+			got, err := os.ReadFile(filepath.Join(l.dstFS.Root(), "downlink", "image.raw"))
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("received file: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(l.dstFS.Root(), "archive")); err != nil {
+				t.Errorf("filestore request not executed: %v", err)
+			}
+
+			fin := finished(l.srcInd)
+			if fin == nil || fin.Transaction != id || fin.Condition != cfdp.NoError ||
+				fin.Delivery != cfdp.DataComplete || fin.Status != cfdp.FileRetained || len(fin.FilestoreResponses) != 1 {
+				t.Errorf("sender finished %+v", fin)
+			}
+			if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.NoError {
+				t.Errorf("receiver finished %+v", fin)
+			}
+			var types []cfdp.IndicationType
+			segs := 0
+			for _, i := range l.dstInd {
+				if i.Type == cfdp.IndicationFileSegmentRecv {
+					segs++
+					continue
+				}
+				types = append(types, i.Type)
+				if i.Type == cfdp.IndicationMetadataRecv && (i.DestFile != "/downlink/image.raw" || string(i.Messages[0]) != "hi") {
+					t.Errorf("metadata indication %+v", i)
+				}
+			}
+			if segs != 11 || !slices.Equal(types, []cfdp.IndicationType{
+				cfdp.IndicationMetadataRecv, cfdp.IndicationEOFRecv, cfdp.IndicationTransactionFinished,
+			}) {
+				t.Errorf("receiver indications %v, %d segments", types, segs)
+			}
+			if l.src.Pending() != 0 || l.dst.Pending() != 0 {
+				t.Errorf("pending %d/%d", l.src.Pending(), l.dst.Pending())
+			}
+		})
+	}
+}
+
+func TestEntity_NoClosure(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{ChecksumType: cfdp.ChecksumCRC32})
+	writeFile(t, l.srcFS, "a", testData(10))
+	writeFile(t, l.srcFS, "empty", nil)
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "empty"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+
+	if len(l.down.queue) != 0 {
+		t.Errorf("unexpected PDUs toward sender")
+	}
+	for _, name := range []string{"a", "empty"} {
+		if _, err := os.Stat(filepath.Join(l.dstFS.Root(), name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NoError || fin.Status != cfdp.FileStatusUnreported {
+		t.Errorf("sender finished %+v", fin)
+	}
+}
+
+func TestEntity_OutOfOrder(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 16})
+	data := testData(100)
+	writeFile(t, l.srcFS, "f", data)
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	slices.Reverse(l.up.queue)
+	l.run(t)
+
+	got, _ := os.ReadFile(filepath.Join(l.dstFS.Root(), "f"))
+	if !bytes.Equal(got, data) {
+		t.Errorf("file not reassembled")
+	}
+}
+
+func TestEntity_CheckLimit(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 16, ClosureRequested: true},
+		cfdp.WithCheckTimeout(time.Second, 2))
+	writeFile(t, l.srcFS, "f", testData(100))
+	n := 0
+	l.up.filter = func(pdu []byte) []byte {
+		if p, _ := cfdp.DecodePDU(pdu); p.PDUHeader().Type == cfdp.PDUFileData {
+			if n++; n == 3 {
+				return nil
+			}
+		}
+		return pdu
+	}
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if finished(l.dstInd) != nil {
+		t.Fatal("finished with data missing")
+	}
+
+	l.clock.Advance(time.Second)
+	l.run(t)
+	if finished(l.dstInd) != nil {
+		t.Fatal("finished before the check limit")
+	}
+	l.clock.Advance(time.Second)
+	l.run(t)
+
+	fin := finished(l.srcInd)
+	if fin == nil || fin.Condition != cfdp.CheckLimitReached || fin.Delivery != cfdp.DataIncomplete ||
+		fin.Status != cfdp.FileDiscarded {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if _, err := os.Stat(filepath.Join(l.dstFS.Root(), "f")); err == nil {
+		t.Error("incomplete file delivered")
+	}
+	entries, _ := os.ReadDir(l.dstFS.Root())
+	if len(entries) != 0 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+}
+
+func TestEntity_ChecksumFailure(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{ClosureRequested: true, ChecksumType: cfdp.ChecksumCRC32})
+	writeFile(t, l.srcFS, "f", testData(50))
+	l.up.filter = func(pdu []byte) []byte {
+		if p, _ := cfdp.DecodePDU(pdu); p.PDUHeader().Type == cfdp.PDUFileData {
+			p.(*cfdp.FileDataPDU).Data[0] ^= 0xFF
+			pdu, _ = p.Encode()
+		}
+		return pdu
+	}
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.FileChecksumFailure {
+		t.Errorf("sender finished %+v", fin)
+	}
+
+	// With the Ignore handler the file is kept and a Fault is indicated.
+	l.srcInd, l.dstInd = nil, nil
+	if _, err := l.src.Put(cfdp.PutRequest{
+		Destination:   2,
+		SourceFile:    "f",
+		DestFile:      "g",
+		FaultHandlers: []cfdp.FaultHandlerOverride{{Condition: cfdp.FileChecksumFailure, Handler: cfdp.HandlerIgnore}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NoError || fin.Status != cfdp.FileRetained {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if l.dstInd[len(l.dstInd)-2].Type != cfdp.IndicationFault {
+		t.Errorf("no fault indication: %+v", l.dstInd)
+	}
+}
+
+func TestEntity_Cancel(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10}, cfdp.WithBurst(2))
+	writeFile(t, l.srcFS, "f", testData(100))
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.CancelRequestReceived {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.CancelRequestReceived || fin.Status != cfdp.FileDiscarded {
+		t.Errorf("receiver finished %+v", fin)
+	}
+	if err := l.src.Cancel(id); !errors.Is(err, cfdp.ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+}
+
+func TestEntity_Inactivity(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{ClosureRequested: true}, cfdp.WithInactivityTimeout(time.Minute))
+	writeFile(t, l.srcFS, "f", testData(10))
+	l.up.filter = func(pdu []byte) []byte {
+		if p, _ := cfdp.DecodePDU(pdu); p.PDUHeader().Type == cfdp.PDUFileDirective {
+			if _, eof := p.(*cfdp.EOFPDU); eof {
+				return nil
+			}
+		}
+		return pdu
+	}
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	l.clock.Advance(2 * time.Minute)
+	l.run(t)
+
+	if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.InactivityDetected {
+		t.Errorf("receiver finished %+v", fin)
+	}
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.InactivityDetected {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if l.src.Pending() != 0 || l.dst.Pending() != 0 {
+		t.Errorf("pending %d/%d", l.src.Pending(), l.dst.Pending())
+	}
+}
+
+func TestEntity_Handle_Errors(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{})
+	h := testHeader()
+	h.DestinationID = 9
+	data, _ := (&cfdp.EOFPDU{Header: h}).Encode()
+	if err := l.dst.Handle(data); !errors.Is(err, cfdp.ErrNotForEntity) {
+		t.Errorf("expected ErrNotForEntity, got %v", err)
+	}
+	h = testHeader()
+	h.Direction = cfdp.TowardSender
+	h.SourceID = 1
+	data, _ = (&cfdp.FinishedPDU{Header: h}).Encode()
+	if err := l.src.Handle(data); !errors.Is(err, cfdp.ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+	writeFile(t, l.srcFS, "f", testData(10))
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Prompt(id, cfdp.PromptNAK); !errors.Is(err, cfdp.ErrUnsupportedMode) {
+		t.Errorf("expected ErrUnsupportedMode, got %v", err)
+	}
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "../etc/passwd"}); !errors.Is(err, cfdp.ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath, got %v", err)
+	}
+}
+
+// directives records the file directive PDUs sent through a pipe.
+func directives(p *pipe) *[]cfdp.DirectiveCode {
+	var codes []cfdp.DirectiveCode
+	p.filter = func(pdu []byte) []byte {
+		switch p, _ := cfdp.DecodePDU(pdu); p.(type) {
+		case *cfdp.ACKPDU:
+			codes = append(codes, cfdp.DirectiveACK)
+		case *cfdp.NAKPDU:
+			codes = append(codes, cfdp.DirectiveNAK)
+		case *cfdp.FinishedPDU:
+			codes = append(codes, cfdp.DirectiveFinished)
+		case *cfdp.KeepAlivePDU:
+			codes = append(codes, cfdp.DirectiveKeepAlive)
+		}
+		return pdu
+	}
+	return &codes
+}
+
+// dropOnce drops the first PDU matching drop.
+func dropOnce(drop func(cfdp.PDU) bool) func([]byte) []byte {
+	seen := make(map[string]bool)
+	return func(pdu []byte) []byte {
+		p, _ := cfdp.DecodePDU(pdu)
+		if drop(p) && !seen[string(pdu)] {
+			seen[string(pdu)] = true
+			return nil
+		}
+		return pdu
+	}
+}
+
+func fileDataAt(offsets ...uint64) func(cfdp.PDU) bool {
+	return func(p cfdp.PDU) bool {
+		fd, ok := p.(*cfdp.FileDataPDU)
+		return ok && slices.Contains(offsets, fd.Offset)
+	}
+}
+
+func indicated(inds []cfdp.Indication, t cfdp.IndicationType) *cfdp.Indication {
+	for i := range inds {
+		if inds[i].Type == t {
+			return &inds[i]
+		}
+	}
+	return nil
+}
+
+func TestEntity_Class2Transfer(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 100, Acknowledged: true, ChecksumType: cfdp.ChecksumCRC32})
+	data := testData(1001)
+	writeFile(t, l.srcFS, "f", data)
+	lost := fileDataAt(200, 700)
+	l.up.filter = dropOnce(func(p cfdp.PDU) bool {
+		_, md := p.(*cfdp.MetadataPDU)
+		return md || lost(p)
+	})
+	sent := directives(l.down)
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if !slices.Equal(*sent, []cfdp.DirectiveCode{cfdp.DirectiveACK, cfdp.DirectiveNAK, cfdp.DirectiveFinished}) {
+		t.Errorf("directives toward sender %v", *sent)
+	}
+
+	got, err := os.ReadFile(filepath.Join(l.dstFS.Root(), "f"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("received file: %v", err)
+	}
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NoError || fin.Status != cfdp.FileRetained {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.NoError {
+		t.Errorf("receiver finished %+v", fin)
+	}
+	if l.src.Pending() != 0 || l.dst.Pending() != 0 {
+		t.Errorf("pending %d/%d", l.src.Pending(), l.dst.Pending())
+	}
+}
+
+func TestEntity_ImmediateNAK(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10, Acknowledged: true}, cfdp.WithBurst(1),
+		cfdp.WithRemoteEntity(cfdp.RemoteEntity{ID: 1, ImmediateNAK: true}))
+	writeFile(t, l.srcFS, "f", testData(50))
+	l.up.filter = dropOnce(fileDataAt(10))
+	sent := directives(l.down)
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := l.src.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.run(t)
+
+	// The NAK follows the segment after the gap, before EOF.
+	if len(*sent) == 0 || (*sent)[0] != cfdp.DirectiveNAK {
+		t.Errorf("directives toward sender %v", *sent)
+	}
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NoError {
+		t.Errorf("sender finished %+v", fin)
+	}
+}
+
+func TestEntity_Prompt(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10, Acknowledged: true}, cfdp.WithBurst(1))
+	writeFile(t, l.srcFS, "f", testData(40))
+	l.up.filter = dropOnce(fileDataAt(10))
+	sent := directives(l.down)
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := l.src.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.src.Prompt(id, cfdp.PromptKeepAlive); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Prompt(id, cfdp.PromptNAK); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if err := l.dst.Receive(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(*sent, []cfdp.DirectiveCode{cfdp.DirectiveKeepAlive, cfdp.DirectiveNAK}) {
+		t.Fatalf("directives toward sender %v", *sent)
+	}
+	l.run(t)
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NoError {
+		t.Errorf("sender finished %+v", fin)
+	}
+}
+
+func TestEntity_NAKLimit(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10, Acknowledged: true},
+		cfdp.WithRemoteEntity(cfdp.RemoteEntity{ID: 1, NAKTimeout: time.Second, NAKLimit: 2}))
+	writeFile(t, l.srcFS, "f", testData(50))
+	lost := fileDataAt(20)
+	l.up.filter = func(pdu []byte) []byte {
+		if p, _ := cfdp.DecodePDU(pdu); lost(p) {
+			return nil
+		}
+		return pdu
+	}
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	l.clock.Advance(time.Second)
+	l.run(t)
+	if finished(l.dstInd) != nil {
+		t.Fatal("finished before the NAK limit")
+	}
+	l.clock.Advance(time.Second)
+	l.run(t)
+
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NAKLimitReached || fin.Status != cfdp.FileDiscarded {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.NAKLimitReached {
+		t.Errorf("receiver finished %+v", fin)
+	}
+	if _, err := os.Stat(filepath.Join(l.dstFS.Root(), "f")); err == nil {
+		t.Error("incomplete file delivered")
+	}
+}
+
+func TestEntity_ACKLimit(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{Acknowledged: true, ACKTimeout: time.Second, ACKLimit: 2})
+	writeFile(t, l.srcFS, "f", testData(10))
+	l.down.filter = func([]byte) []byte { return nil }
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	for range 4 {
+		l.clock.Advance(time.Second)
+		l.run(t)
+	}
+
+	// The ACK limit cancels; the cancel EOF goes unacknowledged too.
+	ab := indicated(l.srcInd, cfdp.IndicationAbandoned)
+	if ab == nil || ab.Condition != cfdp.PositiveACKLimitReached {
+		t.Errorf("sender indications %+v", l.srcInd)
+	}
+	if l.src.Pending() != 0 {
+		t.Errorf("sender pending %d", l.src.Pending())
+	}
+}
+
+func TestEntity_LostFinishedACK(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{Acknowledged: true},
+		cfdp.WithRemoteEntity(cfdp.RemoteEntity{ID: 1, ACKTimeout: time.Second}))
+	writeFile(t, l.srcFS, "f", testData(10))
+	l.up.filter = dropOnce(func(p cfdp.PDU) bool {
+		ack, ok := p.(*cfdp.ACKPDU)
+		return ok && ack.Directive == cfdp.DirectiveFinished
+	})
+	sent := directives(l.down)
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if finished(l.srcInd) == nil || finished(l.dstInd) != nil || l.dst.Pending() != 1 {
+		t.Fatal("receiver should wait for the Finished ACK")
+	}
+	if !slices.Equal(*sent, []cfdp.DirectiveCode{cfdp.DirectiveACK, cfdp.DirectiveFinished}) {
+		t.Errorf("directives toward sender %v", *sent)
+	}
+
+	// The retransmitted Finished PDU is acknowledged again.
+	l.clock.Advance(time.Second)
+	l.run(t)
+	if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.NoError || fin.Status != cfdp.FileRetained {
+		t.Errorf("receiver finished %+v", fin)
+	}
+	if l.dst.Pending() != 0 {
+		t.Errorf("receiver pending %d", l.dst.Pending())
+	}
+}
+
+func TestEntity_KeepAliveLimit(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 50, Acknowledged: true, KeepAliveLimit: 100},
+		cfdp.WithBurst(1),
+		cfdp.WithRemoteEntity(cfdp.RemoteEntity{ID: 1, KeepAliveInterval: time.Second}),
+		cfdp.WithFaultHandler(cfdp.KeepAliveLimitReached, cfdp.HandlerSuspend))
+	writeFile(t, l.srcFS, "f", testData(1000))
+	l.up.filter = func(pdu []byte) []byte {
+		if p, _ := cfdp.DecodePDU(pdu); p.PDUHeader().Type == cfdp.PDUFileData {
+			return nil
+		}
+		return pdu
+	}
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.dst.Receive(); err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		if err := l.src.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.clock.Advance(time.Second)
+	if err := l.dst.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Receive(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := indicated(l.srcInd, cfdp.IndicationSuspended)
+	if s == nil || s.Transaction != id || s.Condition != cfdp.KeepAliveLimitReached {
+		t.Fatalf("sender indications %+v", l.srcInd)
+	}
+	l.up.queue = nil
+	if err := l.src.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.up.queue) != 0 {
+		t.Error("suspended transaction sent PDUs")
+	}
+}
+
+func TestEntity_SuspendResume(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10, Acknowledged: true}, cfdp.WithBurst(2))
+	data := testData(100)
+	writeFile(t, l.srcFS, "f", data)
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Suspend(id); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if indicated(l.dstInd, cfdp.IndicationEOFRecv) != nil {
+		t.Fatal("suspended transaction sent EOF")
+	}
+
+	if err := l.src.Resume(id); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+	if r := indicated(l.srcInd, cfdp.IndicationResumed); r == nil || r.Offset != 20 {
+		t.Errorf("resumed %+v", r)
+	}
+	got, _ := os.ReadFile(filepath.Join(l.dstFS.Root(), "f"))
+	if !bytes.Equal(got, data) {
+		t.Error("file not delivered after resume")
+	}
+	if err := l.src.Suspend(id); !errors.Is(err, cfdp.ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+}
+
+func TestEntity_Class2Cancel(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10, Acknowledged: true}, cfdp.WithBurst(2))
+	writeFile(t, l.srcFS, "f", testData(100))
+	sent := directives(l.down)
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	l.run(t)
+
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.CancelRequestReceived {
+		t.Errorf("sender finished %+v", fin)
+	}
+	if fin := finished(l.dstInd); fin == nil || fin.Condition != cfdp.CancelRequestReceived || fin.Status != cfdp.FileDiscarded {
+		t.Errorf("receiver finished %+v", fin)
+	}
+	if !slices.Equal(*sent, []cfdp.DirectiveCode{cfdp.DirectiveACK}) {
+		t.Errorf("directives toward sender %v", *sent)
+	}
+	if l.src.Pending() != 0 || l.dst.Pending() != 0 {
+		t.Errorf("pending %d/%d", l.src.Pending(), l.dst.Pending())
+	}
+}
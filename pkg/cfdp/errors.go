@@ -0,0 +1,53 @@
+package cfdp
+
+import "errors"
+
+var (
+	// ErrDataTooShort indicates the data is too short to decode a PDU.
+	ErrDataTooShort = errors.New("data too short to decode PDU")
+
+	// ErrInvalidVersion indicates the PDU header version is not the CFDP version.
+	ErrInvalidVersion = errors.New("invalid CFDP version")
+
+	// ErrInvalidFieldLength indicates an entity ID or sequence number length outside 1-8 bytes.
+	ErrInvalidFieldLength = errors.New("invalid field length: must be 1-8 bytes")
+
+	// ErrFieldOverflow indicates a value does not fit its field.
+	ErrFieldOverflow = errors.New("value exceeds field width")
+
+	// ErrPDUTooLarge indicates the PDU data field exceeds 65535 bytes.
+	ErrPDUTooLarge = errors.New("PDU data field exceeds 65535 bytes")
+
+	// ErrCRCMismatch indicates the PDU CRC does not verify.
+	ErrCRCMismatch = errors.New("PDU CRC mismatch")
+
+	// ErrUnknownDirective indicates an unsupported file directive code.
+	ErrUnknownDirective = errors.New("unknown file directive")
+
+	// ErrInvalidPDU indicates a malformed PDU data field.
+	ErrInvalidPDU = errors.New("invalid PDU data field")
+
+	// ErrNameTooLong indicates a file name or LV value exceeds 255 bytes.
+	ErrNameTooLong = errors.New("LV value exceeds 255 bytes")
+
+	// ErrUnsupportedChecksum indicates a checksum type the entity cannot compute.
+	ErrUnsupportedChecksum = errors.New("unsupported checksum type")
+
+	// ErrInvalidPath indicates a file name outside the filestore root.
+	ErrInvalidPath = errors.New("file name outside the filestore")
+
+	// ErrUnknownTransaction indicates no active transaction has the ID.
+	ErrUnknownTransaction = errors.New("unknown transaction")
+
+	// ErrPDUExceedsSDU indicates a PDU longer than the transport's fixed SDU size.
+	ErrPDUExceedsSDU = errors.New("PDU exceeds SDU size")
+
+	// ErrNoPDU indicates a non-blocking transport has no PDU to deliver.
+	ErrNoPDU = errors.New("no PDU available")
+
+	// ErrUnsupportedMode indicates an operation that needs an acknowledged (Class 2) transaction.
+	ErrUnsupportedMode = errors.New("operation requires acknowledged mode")
+
+	// ErrNotForEntity indicates a PDU addressed to another entity.
+	ErrNotForEntity = errors.New("PDU not addressed to this entity")
+)
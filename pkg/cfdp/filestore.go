@@ -0,0 +1,230 @@
+package cfdp
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Filestore is the local filestore of an entity, rooted at a directory.
+// File names in PDUs and filestore requests are resolved relative to the
+// root and may not escape it.
+type Filestore struct {
+	root string
+}
+
+// NewFilestore returns a filestore rooted at dir, which must exist.
+func NewFilestore(dir string) (*Filestore, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "filestore", Path: dir, Err: fs.ErrInvalid}
+	}
+	return &Filestore{root: root}, nil
+}
+
+// Root returns the absolute path of the filestore root.
+func (f *Filestore) Root() string {
+	return f.root
+}
+
+// Path resolves a CFDP file name to a local path. A leading "/" is
+// taken as the filestore root.
+func (f *Filestore) Path(name string) (string, error) {
+	rel := filepath.FromSlash(strings.TrimLeft(name, "/"))
+	if rel == "" || !filepath.IsLocal(rel) {
+		return "", ErrInvalidPath
+	}
+	return filepath.Join(f.root, rel), nil
+}
+
+// Open opens a file for reading.
+func (f *Filestore) Open(name string) (*os.File, error) {
+	p, err := f.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Create creates or truncates a file, creating its parent directories.
+func (f *Filestore) Create(name string) (*os.File, error) {
+	p, err := f.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(p)
+}
+
+// createTemp creates a temporary file in the root to receive file data.
+func (f *Filestore) createTemp() (*os.File, error) {
+	return os.CreateTemp(f.root, ".cfdp-*")
+}
+
+// commit moves a received temporary file to name.
+func (f *Filestore) commit(tmp, name string) error {
+	p, err := f.Path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Filestore response status codes (CCSDS 727.0-B-5 Table 5-18).
+const (
+	statusCreateNotAllowed   FilestoreStatus = 1 // create file: not allowed
+	statusFileDoesNotExist   FilestoreStatus = 1 // delete, rename, append, replace
+	statusDeleteNotAllowed   FilestoreStatus = 2
+	statusOldExists          FilestoreStatus = 2 // rename: new file name already exists
+	statusRenameNotAllowed   FilestoreStatus = 3
+	statusSecondDoesNotExist FilestoreStatus = 2 // append, replace: second file does not exist
+	statusAppendNotAllowed   FilestoreStatus = 3
+	statusDirCannotCreate    FilestoreStatus = 1
+	statusDirDoesNotExist    FilestoreStatus = 1
+	statusDirNotAllowed      FilestoreStatus = 2
+)
+
+// Execute performs a filestore request and returns its response.
+func (f *Filestore) Execute(r FilestoreRequest) FilestoreResponse {
+	resp := FilestoreResponse{Action: r.Action, FirstName: r.FirstName, SecondName: r.SecondName}
+	first, err := f.Path(r.FirstName)
+	if err != nil {
+		resp.Status = FilestoreNotPerformed
+		resp.Message = err.Error()
+		return resp
+	}
+	var second string
+	if r.Action.twoNames() {
+		if second, err = f.Path(r.SecondName); err != nil {
+			resp.Status = FilestoreNotPerformed
+			resp.Message = err.Error()
+			return resp
+		}
+	}
+	fail := func(status FilestoreStatus, err error) FilestoreResponse {
+		resp.Status = status
+		resp.Message = err.Error()
+		return resp
+	}
+	switch r.Action {
+	case CreateFile:
+		file, err := f.Create(r.FirstName)
+		if err != nil {
+			return fail(statusCreateNotAllowed, err)
+		}
+		file.Close()
+	case DeleteFile:
+		if err := os.Remove(first); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fail(statusFileDoesNotExist, err)
+			}
+			return fail(statusDeleteNotAllowed, err)
+		}
+	case RenameFile:
+		if _, err := os.Stat(first); err != nil {
+			return fail(statusFileDoesNotExist, err)
+		}
+		if _, err := os.Stat(second); err == nil {
+			return fail(statusOldExists, fs.ErrExist)
+		}
+		if err := os.Rename(first, second); err != nil {
+			return fail(statusRenameNotAllowed, err)
+		}
+	case AppendFile:
+		if err := appendFile(first, second); err != nil {
+			return fail(twoFileStatus(first, second, err), err)
+		}
+	case ReplaceFile:
+		if err := replaceFile(first, second); err != nil {
+			return fail(twoFileStatus(first, second, err), err)
+		}
+	case CreateDirectory:
+		if err := os.MkdirAll(first, 0o755); err != nil {
+			return fail(statusDirCannotCreate, err)
+		}
+	case RemoveDirectory:
+		if err := os.Remove(first); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fail(statusDirDoesNotExist, err)
+			}
+			return fail(statusDirNotAllowed, err)
+		}
+	case DenyFile, DenyDirectory:
+		if err := os.Remove(first); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fail(statusDeleteNotAllowed, err)
+		}
+	default:
+		resp.Status = FilestoreNotPerformed
+		return resp
+	}
+	resp.Status = FilestoreSuccess
+	return resp
+}
+
+// twoFileStatus maps an append or replace failure to its status code.
+func twoFileStatus(first, second string, err error) FilestoreStatus {
+	if _, e := os.Stat(first); e != nil {
+		return statusFileDoesNotExist
+	}
+	if _, e := os.Stat(second); e != nil {
+		return statusSecondDoesNotExist
+	}
+	return statusAppendNotAllowed
+}
+
+// appendFile appends the contents of src to dst.
+func appendFile(dst, src string) error {
+	if _, err := os.Stat(dst); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// replaceFile replaces the contents of dst with those of src.
+func replaceFile(dst, src string) error {
+	if _, err := os.Stat(dst); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
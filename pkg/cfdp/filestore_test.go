@@ -0,0 +1,79 @@
+package cfdp_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+)
+
+func TestFilestore_Path(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := cfdp.NewFilestore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := fs.Path("/a/b.txt")
+	if err != nil || p != filepath.Join(fs.Root(), "a", "b.txt") {
+		t.Errorf("path %q, %v", p, err)
+	}
+	for _, name := range []string{"", "../x", "a/../../x"} {
+		if _, err := fs.Path(name); !errors.Is(err, cfdp.ErrInvalidPath) {
+			t.Errorf("%q: expected ErrInvalidPath, got %v", name, err)
+		}
+	}
+	if _, err := cfdp.NewFilestore(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing root")
+	}
+}
+
+func TestFilestore_Execute(t *testing.T) {
+	fs, _ := cfdp.NewFilestore(t.TempDir())
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(fs.Root(), name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(fs.Root(), name))
+		return string(data)
+	}
+	exec := func(r cfdp.FilestoreRequest) cfdp.FilestoreStatus {
+		return fs.Execute(r).Status
+	}
+
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.CreateDirectory, FirstName: "d"}); s != cfdp.FilestoreSuccess {
+		t.Errorf("create directory: %d", s)
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.CreateFile, FirstName: "d/f"}); s != cfdp.FilestoreSuccess {
+		t.Errorf("create file: %d", s)
+	}
+	write("a", "head-")
+	write("b", "tail")
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.AppendFile, FirstName: "a", SecondName: "b"}); s != cfdp.FilestoreSuccess || read("a") != "head-tail" {
+		t.Errorf("append: %d %q", s, read("a"))
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.ReplaceFile, FirstName: "a", SecondName: "b"}); s != cfdp.FilestoreSuccess || read("a") != "tail" {
+		t.Errorf("replace: %d %q", s, read("a"))
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.RenameFile, FirstName: "a", SecondName: "b"}); s != 2 {
+		t.Errorf("rename onto existing file: %d", s)
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.RenameFile, FirstName: "a", SecondName: "c"}); s != cfdp.FilestoreSuccess || read("c") != "tail" {
+		t.Errorf("rename: %d", s)
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.DeleteFile, FirstName: "a"}); s != 1 {
+		t.Errorf("delete missing file: %d", s)
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.DenyFile, FirstName: "a"}); s != cfdp.FilestoreSuccess {
+		t.Errorf("deny missing file: %d", s)
+	}
+	if s := exec(cfdp.FilestoreRequest{Action: cfdp.RemoveDirectory, FirstName: "d"}); s != 2 {
+		t.Errorf("remove non-empty directory: %d", s)
+	}
+	if r := fs.Execute(cfdp.FilestoreRequest{Action: cfdp.DeleteFile, FirstName: "../x"}); r.Status != cfdp.FilestoreNotPerformed || r.Message == "" {
+		t.Errorf("escaping path: %+v", r)
+	}
+}
@@ -0,0 +1,237 @@
+package cfdp
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/ravisuhag/astro/pkg/crc"
+)
+
+/*
+Fixed PDU Header (CCSDS 727.0-B-5 Section 5.1):
+
+Octet 0:
++---------+---+---+---+---+---+
+| Version |PDU|Dir|TM |CRC|LF |
+|  (3b)   |Typ|   |   |   |   |
++---------+---+---+---+---+---+
+
+Octets 1-2: PDU Data Field Length (16 bits, including the CRC)
+
+Octet 3:
++---+-------------+---+-------------+
+|Seg| Len Entity  |SM | Len Seq Num |
+|Ctl| IDs - 1 (3b)|   |  - 1 (3b)   |
++---+-------------+---+-------------+
+
+Then the source entity ID, transaction sequence number and destination
+entity ID, each of the length given in octet 3.
+*/
+
+// MinHeaderSize is the size of a fixed PDU header with one-byte entity
+// IDs and sequence number.
+const MinHeaderSize = 7
+
+// Header is the fixed PDU header.
+type Header struct {
+	Version             uint8
+	Type                PDUType
+	Direction           Direction
+	Mode                TransmissionMode
+	CRC                 bool // PDU ends with a CRC-16
+	LargeFile           bool // file sizes and offsets are 64 bits
+	DataLength          uint16
+	SegmentationControl bool  // record boundaries respected
+	EntityIDLength      uint8 // bytes, 1-8
+	SegmentMetadata     bool  // file data PDUs carry segment metadata
+	SeqNumLength        uint8 // bytes, 1-8
+	SourceID            EntityID
+	SeqNum              uint64
+	DestinationID       EntityID
+}
+
+// Size returns the encoded header size in bytes.
+func (h *Header) Size() int {
+	return 4 + 2*int(h.EntityIDLength) + int(h.SeqNumLength)
+}
+
+// Transaction returns the ID of the transaction the PDU belongs to.
+func (h *Header) Transaction() TransactionID {
+	return TransactionID{Source: h.SourceID, Seq: h.SeqNum}
+}
+
+// fss returns the size of file size and offset fields.
+func (h *Header) fss() int {
+	if h.LargeFile {
+		return 8
+	}
+	return 4
+}
+
+// Validate checks the field lengths and that the IDs fit them.
+func (h *Header) Validate() error {
+	if h.Version != Version {
+		return ErrInvalidVersion
+	}
+	if h.EntityIDLength < 1 || h.EntityIDLength > 8 || h.SeqNumLength < 1 || h.SeqNumLength > 8 {
+		return ErrInvalidFieldLength
+	}
+	if !fitsBytes(uint64(h.SourceID), h.EntityIDLength) || !fitsBytes(uint64(h.DestinationID), h.EntityIDLength) ||
+		!fitsBytes(h.SeqNum, h.SeqNumLength) {
+		return ErrFieldOverflow
+	}
+	return nil
+}
+
+// encode returns the header followed by data, setting DataLength and
+// appending the CRC when the CRC flag is set.
+func (h *Header) encode(data []byte) ([]byte, error) {
+	n := len(data)
+	if h.CRC {
+		n += 2
+	}
+	if n > 0xFFFF {
+		return nil, ErrPDUTooLarge
+	}
+	h.DataLength = uint16(n)
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 4, h.Size()+n)
+	out[0] = h.Version<<5 | byte(h.Type)<<4 | byte(h.Direction)<<3 | byte(h.Mode)<<2 | b2u(h.CRC)<<1 | b2u(h.LargeFile)
+	binary.BigEndian.PutUint16(out[1:], h.DataLength)
+	out[3] = b2u(h.SegmentationControl)<<7 | (h.EntityIDLength-1)<<4 | b2u(h.SegmentMetadata)<<3 | (h.SeqNumLength - 1)
+	out = appendBytes(out, uint64(h.SourceID), h.EntityIDLength)
+	out = appendBytes(out, h.SeqNum, h.SeqNumLength)
+	out = appendBytes(out, uint64(h.DestinationID), h.EntityIDLength)
+	out = append(out, data...)
+	if h.CRC {
+		out = binary.BigEndian.AppendUint16(out, crc.ComputeCRC16(out))
+	}
+	return out, nil
+}
+
+// PDUSizer returns the total length of the PDU at the start of data, or
+// -1 if data is too short to hold the fixed part of the header. It
+// splits a stream of concatenated PDUs.
+func PDUSizer(data []byte) int {
+	if len(data) < 4 {
+		return -1
+	}
+	idLen := int(data[3]>>4&0x07) + 1
+	seqLen := int(data[3]&0x07) + 1
+	return 4 + 2*idLen + seqLen + int(binary.BigEndian.Uint16(data[1:]))
+}
+
+// DecodeHeader decodes the fixed PDU header and returns the PDU data
+// field, with the CRC verified and removed. Bytes after the PDU, such as
+// the fill of a fixed-length SDU, are ignored.
+func DecodeHeader(data []byte) (*Header, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, ErrDataTooShort
+	}
+	h := &Header{
+		Version:             data[0] >> 5,
+		Type:                PDUType(data[0] >> 4 & 1),
+		Direction:           Direction(data[0] >> 3 & 1),
+		Mode:                TransmissionMode(data[0] >> 2 & 1),
+		CRC:                 data[0]>>1&1 == 1,
+		LargeFile:           data[0]&1 == 1,
+		DataLength:          binary.BigEndian.Uint16(data[1:]),
+		SegmentationControl: data[3]>>7 == 1,
+		EntityIDLength:      data[3]>>4&0x07 + 1,
+		SegmentMetadata:     data[3]>>3&1 == 1,
+		SeqNumLength:        data[3]&0x07 + 1,
+	}
+	if h.Version != Version {
+		return nil, nil, ErrInvalidVersion
+	}
+	size := h.Size()
+	if len(data) < size+int(h.DataLength) {
+		return nil, nil, ErrDataTooShort
+	}
+	off := 4
+	h.SourceID = EntityID(readBytes(data[off:], h.EntityIDLength))
+	off += int(h.EntityIDLength)
+	h.SeqNum = readBytes(data[off:], h.SeqNumLength)
+	off += int(h.SeqNumLength)
+	h.DestinationID = EntityID(readBytes(data[off:], h.EntityIDLength))
+
+	body := data[size : size+int(h.DataLength)]
+	if h.CRC {
+		if len(body) < 2 {
+			return nil, nil, ErrDataTooShort
+		}
+		n := size + len(body) - 2
+		if crc.ComputeCRC16(data[:n]) != binary.BigEndian.Uint16(data[n:]) {
+			return nil, nil, ErrCRCMismatch
+		}
+		body = body[:len(body)-2]
+	}
+	return h, body, nil
+}
+
+// Humanize returns a human-readable representation of the header.
+func (h *Header) Humanize() string {
+	typ := "File Directive"
+	if h.Type == PDUFileData {
+		typ = "File Data"
+	}
+	dir := "Toward Receiver"
+	if h.Direction == TowardSender {
+		dir = "Toward Sender"
+	}
+	return strings.Join([]string{
+		"  Version: " + strconv.Itoa(int(h.Version)),
+		"  PDU Type: " + typ,
+		"  Direction: " + dir,
+		"  Transmission Mode: " + h.Mode.String(),
+		"  CRC: " + strconv.FormatBool(h.CRC),
+		"  Large File: " + strconv.FormatBool(h.LargeFile),
+		"  Data Length: " + strconv.Itoa(int(h.DataLength)),
+		"  Segmentation Control: " + strconv.FormatBool(h.SegmentationControl),
+		"  Segment Metadata: " + strconv.FormatBool(h.SegmentMetadata),
+		"  Source Entity: " + strconv.FormatUint(uint64(h.SourceID), 10),
+		"  Sequence Number: " + strconv.FormatUint(h.SeqNum, 10),
+		"  Destination Entity: " + strconv.FormatUint(uint64(h.DestinationID), 10),
+	}, "\n")
+}
+
+// fitsBytes reports whether v fits in n bytes.
+func fitsBytes(v uint64, n uint8) bool {
+	return n >= 8 || v < 1<<(8*n)
+}
+
+// appendBytes appends the n low-order bytes of v, big-endian.
+func appendBytes(out []byte, v uint64, n uint8) []byte {
+	for i := int(n) - 1; i >= 0; i-- {
+		out = append(out, byte(v>>(8*i)))
+	}
+	return out
+}
+
+// readBytes reads an n-byte big-endian value.
+func readBytes(data []byte, n uint8) uint64 {
+	var v uint64
+	for _, b := range data[:n] {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// bytesFor returns the number of bytes needed to hold v, at least 1.
+func bytesFor(v uint64) uint8 {
+	n := uint8(1)
+	for n < 8 && v >= 1<<(8*n) {
+		n++
+	}
+	return n
+}
+
+func b2u(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
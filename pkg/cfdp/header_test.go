@@ -0,0 +1,123 @@
+package cfdp_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+)
+
+func testHeader() cfdp.Header {
+	return cfdp.Header{
+		Version:        cfdp.Version,
+		Mode:           cfdp.Unacknowledged,
+		EntityIDLength: 2,
+		SeqNumLength:   4,
+		SourceID:       0x0102,
+		SeqNum:         0x0A0B0C0D,
+		DestinationID:  0x0304,
+	}
+}
+
+func TestDecodeHeader_Layout(t *testing.T) {
+	h := testHeader()
+	h.CRC = true
+	p := &cfdp.EOFPDU{Header: h, Checksum: 0xDEADBEEF, FileSize: 100}
+	data, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// version 001, directive, toward receiver, unacknowledged, CRC
+	if data[0] != 0x26 {
+		t.Errorf("octet 0 = %#02x", data[0])
+	}
+	if data[3] != 0x13 {
+		t.Errorf("octet 3 = %#02x", data[3])
+	}
+	if len(data) != 4+2+4+2+10+2 {
+		t.Fatalf("length %d", len(data))
+	}
+
+	got, body, err := cfdp.DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SourceID != 0x0102 || got.SeqNum != 0x0A0B0C0D || got.DestinationID != 0x0304 ||
+		!got.CRC || got.Mode != cfdp.Unacknowledged || got.DataLength != 12 {
+		t.Errorf("header %+v", got)
+	}
+	if len(body) != 10 || body[0] != byte(cfdp.DirectiveEOF) {
+		t.Errorf("body %x", body)
+	}
+	if got.Transaction() != (cfdp.TransactionID{Source: 0x0102, Seq: 0x0A0B0C0D}) {
+		t.Errorf("transaction %v", got.Transaction())
+	}
+}
+
+func TestDecodeHeader_IgnoresFill(t *testing.T) {
+	h := testHeader()
+	p := &cfdp.EOFPDU{Header: h}
+	data, _ := p.Encode()
+	padded := append(data, make([]byte, 32)...)
+	if _, err := cfdp.DecodePDU(padded); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeHeader_Errors(t *testing.T) {
+	h := testHeader()
+	h.CRC = true
+	data, _ := (&cfdp.EOFPDU{Header: h}).Encode()
+
+	if _, _, err := cfdp.DecodeHeader(data[:3]); !errors.Is(err, cfdp.ErrDataTooShort) {
+		t.Errorf("short: %v", err)
+	}
+	if _, _, err := cfdp.DecodeHeader(data[:len(data)-1]); !errors.Is(err, cfdp.ErrDataTooShort) {
+		t.Errorf("truncated: %v", err)
+	}
+	bad := append([]byte(nil), data...)
+	bad[len(bad)-3] ^= 0xFF
+	if _, _, err := cfdp.DecodeHeader(bad); !errors.Is(err, cfdp.ErrCRCMismatch) {
+		t.Errorf("corrupt: %v", err)
+	}
+	bad = append([]byte(nil), data...)
+	bad[0] &^= 0xE0
+	if _, _, err := cfdp.DecodeHeader(bad); !errors.Is(err, cfdp.ErrInvalidVersion) {
+		t.Errorf("version: %v", err)
+	}
+}
+
+func TestPDUSizer(t *testing.T) {
+	h := testHeader()
+	h.CRC = true
+	eof, _ := (&cfdp.EOFPDU{Header: h, FileSize: 10}).Encode()
+	fd, _ := (&cfdp.FileDataPDU{Header: testHeader(), Data: []byte("data")}).Encode()
+	stream := append(append([]byte(nil), eof...), fd...)
+
+	if n := cfdp.PDUSizer(stream); n != len(eof) {
+		t.Errorf("first PDU %d bytes, want %d", n, len(eof))
+	}
+	if n := cfdp.PDUSizer(stream[len(eof):]); n != len(fd) {
+		t.Errorf("second PDU %d bytes, want %d", n, len(fd))
+	}
+	if n := cfdp.PDUSizer(eof[:3]); n != -1 {
+		t.Errorf("short data: %d", n)
+	}
+}
+
+func TestHeader_Validate(t *testing.T) {
+	h := testHeader()
+	h.EntityIDLength = 1
+	if err := h.Validate(); !errors.Is(err, cfdp.ErrFieldOverflow) {
+		t.Errorf("overflow: %v", err)
+	}
+	h.EntityIDLength = 9
+	if err := h.Validate(); !errors.Is(err, cfdp.ErrInvalidFieldLength) {
+		t.Errorf("length: %v", err)
+	}
+	h = testHeader()
+	h.Version = 0
+	if err := h.Validate(); !errors.Is(err, cfdp.ErrInvalidVersion) {
+		t.Errorf("version: %v", err)
+	}
+}
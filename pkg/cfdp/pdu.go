@@ -0,0 +1,673 @@
+package cfdp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// PDU is a decoded CFDP protocol data unit.
+type PDU interface {
+	// PDUHeader returns the fixed PDU header.
+	PDUHeader() *Header
+
+	// Encode returns the encoded PDU.
+	Encode() ([]byte, error)
+
+	// Humanize returns a human-readable representation of the PDU.
+	Humanize() string
+}
+
+// DecodePDU decodes a PDU of any supported type.
+func DecodePDU(data []byte) (PDU, error) {
+	h, body, err := DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if h.Type == PDUFileData {
+		return decodeFileData(h, body)
+	}
+	if len(body) < 1 {
+		return nil, ErrInvalidPDU
+	}
+	switch DirectiveCode(body[0]) {
+	case DirectiveMetadata:
+		return decodeMetadata(h, body[1:])
+	case DirectiveEOF:
+		return decodeEOF(h, body[1:])
+	case DirectiveFinished:
+		return decodeFinished(h, body[1:])
+	case DirectiveACK:
+		return decodeACK(h, body[1:])
+	case DirectiveNAK:
+		return decodeNAK(h, body[1:])
+	case DirectivePrompt:
+		return decodePrompt(h, body[1:])
+	case DirectiveKeepAlive:
+		return decodeKeepAlive(h, body[1:])
+	default:
+		return nil, ErrUnknownDirective
+	}
+}
+
+// appendFSS appends a file size or offset in the width selected by the
+// large file flag.
+func appendFSS(out []byte, h *Header, v uint64) ([]byte, error) {
+	if !h.LargeFile {
+		if v > 0xFFFFFFFF {
+			return nil, ErrFieldOverflow
+		}
+		return binary.BigEndian.AppendUint32(out, uint32(v)), nil
+	}
+	return binary.BigEndian.AppendUint64(out, v), nil
+}
+
+func readFSS(data []byte, h *Header) (uint64, []byte, error) {
+	n := h.fss()
+	if len(data) < n {
+		return 0, nil, ErrInvalidPDU
+	}
+	return readBytes(data, uint8(n)), data[n:], nil
+}
+
+// MetadataPDU opens a transaction (CCSDS 727.0-B-5 Section 5.2.5).
+type MetadataPDU struct {
+	Header            Header
+	ClosureRequested  bool
+	ChecksumType      ChecksumType
+	FileSize          uint64
+	SourceFileName    string // empty when no file is transferred
+	DestFileName      string
+	FilestoreRequests []FilestoreRequest
+	MessagesToUser    [][]byte
+	FaultHandlers     []FaultHandlerOverride
+	FlowLabel         []byte
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *MetadataPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *MetadataPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	out := []byte{byte(DirectiveMetadata), b2u(p.ClosureRequested)<<6 | byte(p.ChecksumType)&0x0F}
+	out, err := appendFSS(out, &p.Header, p.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	if out, err = appendLV(out, p.SourceFileName); err != nil {
+		return nil, err
+	}
+	if out, err = appendLV(out, p.DestFileName); err != nil {
+		return nil, err
+	}
+	for i := range p.FilestoreRequests {
+		v, err := p.FilestoreRequests[i].encode()
+		if err != nil {
+			return nil, err
+		}
+		if out, err = appendTLV(out, TLVFilestoreRequest, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range p.MessagesToUser {
+		if out, err = appendTLV(out, TLVMessageToUser, m); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range p.FaultHandlers {
+		out = append(out, TLVFaultHandlerOverride, 1, byte(f.Condition)<<4|byte(f.Handler)&0x0F)
+	}
+	if p.FlowLabel != nil {
+		if out, err = appendTLV(out, TLVFlowLabel, p.FlowLabel); err != nil {
+			return nil, err
+		}
+	}
+	return p.Header.encode(out)
+}
+
+func decodeMetadata(h *Header, data []byte) (*MetadataPDU, error) {
+	if len(data) < 1 {
+		return nil, ErrInvalidPDU
+	}
+	p := &MetadataPDU{
+		Header:           *h,
+		ClosureRequested: data[0]>>6&1 == 1,
+		ChecksumType:     ChecksumType(data[0] & 0x0F),
+	}
+	var err error
+	rest := data[1:]
+	if p.FileSize, rest, err = readFSS(rest, h); err != nil {
+		return nil, err
+	}
+	if p.SourceFileName, rest, err = readLV(rest); err != nil {
+		return nil, err
+	}
+	if p.DestFileName, rest, err = readLV(rest); err != nil {
+		return nil, err
+	}
+	tlvs, err := decodeTLVs(rest)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tlvs {
+		switch t.Type {
+		case TLVFilestoreRequest:
+			r, err := decodeFilestoreRequest(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			p.FilestoreRequests = append(p.FilestoreRequests, r)
+		case TLVMessageToUser:
+			p.MessagesToUser = append(p.MessagesToUser, t.Value)
+		case TLVFaultHandlerOverride:
+			if len(t.Value) != 1 {
+				return nil, ErrInvalidPDU
+			}
+			p.FaultHandlers = append(p.FaultHandlers, FaultHandlerOverride{
+				Condition: ConditionCode(t.Value[0] >> 4),
+				Handler:   HandlerCode(t.Value[0] & 0x0F),
+			})
+		case TLVFlowLabel:
+			p.FlowLabel = t.Value
+		}
+	}
+	return p, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *MetadataPDU) Humanize() string {
+	lines := []string{
+		"Metadata PDU",
+		p.Header.Humanize(),
+		"  Closure Requested: " + strconv.FormatBool(p.ClosureRequested),
+		"  Checksum Type: " + p.ChecksumType.String(),
+		"  File Size: " + strconv.FormatUint(p.FileSize, 10),
+		"  Source File: " + p.SourceFileName,
+		"  Destination File: " + p.DestFileName,
+	}
+	for _, r := range p.FilestoreRequests {
+		lines = append(lines, "  Filestore Request: "+r.Action.String()+" "+strings.TrimSpace(r.FirstName+" "+r.SecondName))
+	}
+	for _, m := range p.MessagesToUser {
+		lines = append(lines, "  Message To User: "+hex.EncodeToString(m))
+	}
+	for _, f := range p.FaultHandlers {
+		lines = append(lines, "  Fault Handler: "+f.Condition.String()+" -> "+strconv.Itoa(int(f.Handler)))
+	}
+	if p.FlowLabel != nil {
+		lines = append(lines, "  Flow Label: "+hex.EncodeToString(p.FlowLabel))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RecordContinuation locates a file segment within a record when
+// segment metadata is present.
+type RecordContinuation uint8
+
+// Record continuation states.
+const (
+	RecordNoBoundary RecordContinuation = 0 // neither start nor end of a record
+	RecordStart      RecordContinuation = 1 // first octet is the start of a record
+	RecordEnd        RecordContinuation = 2 // last octet is the end of a record
+	RecordStartEnd   RecordContinuation = 3 // segment is a whole record
+)
+
+// FileDataPDU carries a file segment (CCSDS 727.0-B-5 Section 5.3).
+type FileDataPDU struct {
+	Header             Header
+	RecordContinuation RecordContinuation // segment metadata only
+	SegmentMetadata    []byte             // segment metadata only, up to 63 bytes
+	Offset             uint64
+	Data               []byte
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *FileDataPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *FileDataPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileData
+	var out []byte
+	if p.Header.SegmentMetadata {
+		if len(p.SegmentMetadata) > 63 {
+			return nil, ErrFieldOverflow
+		}
+		out = append(out, byte(p.RecordContinuation)<<6|byte(len(p.SegmentMetadata)))
+		out = append(out, p.SegmentMetadata...)
+	}
+	out, err := appendFSS(out, &p.Header, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return p.Header.encode(append(out, p.Data...))
+}
+
+func decodeFileData(h *Header, data []byte) (*FileDataPDU, error) {
+	p := &FileDataPDU{Header: *h}
+	if h.SegmentMetadata {
+		if len(data) < 1 || len(data) < 1+int(data[0]&0x3F) {
+			return nil, ErrInvalidPDU
+		}
+		n := int(data[0] & 0x3F)
+		p.RecordContinuation = RecordContinuation(data[0] >> 6)
+		p.SegmentMetadata = data[1 : 1+n]
+		data = data[1+n:]
+	}
+	var err error
+	if p.Offset, data, err = readFSS(data, h); err != nil {
+		return nil, err
+	}
+	p.Data = data
+	return p, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *FileDataPDU) Humanize() string {
+	lines := []string{
+		"File Data PDU",
+		p.Header.Humanize(),
+	}
+	if p.Header.SegmentMetadata {
+		lines = append(lines,
+			"  Record Continuation: "+strconv.Itoa(int(p.RecordContinuation)),
+			"  Segment Metadata: "+hex.EncodeToString(p.SegmentMetadata))
+	}
+	lines = append(lines,
+		"  Offset: "+strconv.FormatUint(p.Offset, 10),
+		"  Length: "+strconv.Itoa(len(p.Data)))
+	return strings.Join(lines, "\n")
+}
+
+// EOFPDU ends the file data of a transaction (CCSDS 727.0-B-5 Section 5.2.2).
+type EOFPDU struct {
+	Header        Header
+	Condition     ConditionCode
+	Checksum      uint32
+	FileSize      uint64
+	FaultLocation *EntityID // set when Condition is not NoError
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *EOFPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *EOFPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	out := []byte{byte(DirectiveEOF), byte(p.Condition) << 4}
+	out = binary.BigEndian.AppendUint32(out, p.Checksum)
+	out, err := appendFSS(out, &p.Header, p.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	if p.Condition != NoError && p.FaultLocation != nil {
+		out = appendEntityIDTLV(out, *p.FaultLocation)
+	}
+	return p.Header.encode(out)
+}
+
+func decodeEOF(h *Header, data []byte) (*EOFPDU, error) {
+	if len(data) < 5 {
+		return nil, ErrInvalidPDU
+	}
+	p := &EOFPDU{
+		Header:    *h,
+		Condition: ConditionCode(data[0] >> 4),
+		Checksum:  binary.BigEndian.Uint32(data[1:]),
+	}
+	rest := data[5:]
+	var err error
+	if p.FileSize, rest, err = readFSS(rest, h); err != nil {
+		return nil, err
+	}
+	if p.FaultLocation, err = faultLocation(rest); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// faultLocation decodes an optional entity ID TLV.
+func faultLocation(data []byte) (*EntityID, error) {
+	tlvs, err := decodeTLVs(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tlvs {
+		if t.Type == TLVEntityID {
+			id, err := decodeEntityIDTLV(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			return &id, nil
+		}
+	}
+	return nil, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *EOFPDU) Humanize() string {
+	lines := []string{
+		"EOF PDU",
+		p.Header.Humanize(),
+		"  Condition: " + p.Condition.String(),
+		"  Checksum: 0x" + strconv.FormatUint(uint64(p.Checksum), 16),
+		"  File Size: " + strconv.FormatUint(p.FileSize, 10),
+	}
+	if p.FaultLocation != nil {
+		lines = append(lines, "  Fault Location: "+strconv.FormatUint(uint64(*p.FaultLocation), 10))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FinishedPDU reports the end of a transaction at the receiving entity
+// (CCSDS 727.0-B-5 Section 5.2.3).
+type FinishedPDU struct {
+	Header             Header
+	Condition          ConditionCode
+	Delivery           DeliveryCode
+	Status             FileStatus
+	FilestoreResponses []FilestoreResponse
+	FaultLocation      *EntityID
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *FinishedPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *FinishedPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	out := []byte{byte(DirectiveFinished), byte(p.Condition)<<4 | byte(p.Delivery)&1<<2 | byte(p.Status)&0x03}
+	for i := range p.FilestoreResponses {
+		v, err := p.FilestoreResponses[i].encode()
+		if err != nil {
+			return nil, err
+		}
+		if out, err = appendTLV(out, TLVFilestoreResponse, v); err != nil {
+			return nil, err
+		}
+	}
+	if p.Condition != NoError && p.FaultLocation != nil {
+		out = appendEntityIDTLV(out, *p.FaultLocation)
+	}
+	return p.Header.encode(out)
+}
+
+func decodeFinished(h *Header, data []byte) (*FinishedPDU, error) {
+	if len(data) < 1 {
+		return nil, ErrInvalidPDU
+	}
+	p := &FinishedPDU{
+		Header:    *h,
+		Condition: ConditionCode(data[0] >> 4),
+		Delivery:  DeliveryCode(data[0] >> 2 & 1),
+		Status:    FileStatus(data[0] & 0x03),
+	}
+	tlvs, err := decodeTLVs(data[1:])
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tlvs {
+		switch t.Type {
+		case TLVFilestoreResponse:
+			r, err := decodeFilestoreResponse(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			p.FilestoreResponses = append(p.FilestoreResponses, r)
+		case TLVEntityID:
+			id, err := decodeEntityIDTLV(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			p.FaultLocation = &id
+		}
+	}
+	return p, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *FinishedPDU) Humanize() string {
+	lines := []string{
+		"Finished PDU",
+		p.Header.Humanize(),
+		"  Condition: " + p.Condition.String(),
+		"  Delivery: " + p.Delivery.String(),
+		"  File Status: " + p.Status.String(),
+	}
+	for _, r := range p.FilestoreResponses {
+		lines = append(lines, "  Filestore Response: "+r.Action.String()+" status "+strconv.Itoa(int(r.Status)))
+	}
+	if p.FaultLocation != nil {
+		lines = append(lines, "  Fault Location: "+strconv.FormatUint(uint64(*p.FaultLocation), 10))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TransactionStatus is the state of the acknowledged transaction at the
+// entity sending an ACK PDU (CCSDS 727.0-B-5 Table 5-8).
+type TransactionStatus uint8
+
+// Transaction status codes.
+const (
+	TransactionUndefined    TransactionStatus = 0
+	TransactionActive       TransactionStatus = 1
+	TransactionTerminated   TransactionStatus = 2
+	TransactionUnrecognized TransactionStatus = 3
+)
+
+// String returns the transaction status name.
+func (s TransactionStatus) String() string {
+	switch s {
+	case TransactionUndefined:
+		return "Undefined"
+	case TransactionActive:
+		return "Active"
+	case TransactionTerminated:
+		return "Terminated"
+	case TransactionUnrecognized:
+		return "Unrecognized"
+	default:
+		return "Unknown(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// ACKPDU acknowledges an EOF or Finished PDU (CCSDS 727.0-B-5 Section 5.2.4).
+type ACKPDU struct {
+	Header    Header
+	Directive DirectiveCode // DirectiveEOF or DirectiveFinished
+	Condition ConditionCode // of the acknowledged PDU
+	Status    TransactionStatus
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *ACKPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU. The directive subtype is 1 for the
+// ACK of a Finished PDU and 0 otherwise.
+func (p *ACKPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	var subtype byte
+	if p.Directive == DirectiveFinished {
+		subtype = 1
+	}
+	out := []byte{byte(DirectiveACK), byte(p.Directive)<<4 | subtype, byte(p.Condition)<<4 | byte(p.Status)&0x03}
+	return p.Header.encode(out)
+}
+
+func decodeACK(h *Header, data []byte) (*ACKPDU, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidPDU
+	}
+	return &ACKPDU{
+		Header:    *h,
+		Directive: DirectiveCode(data[0] >> 4),
+		Condition: ConditionCode(data[1] >> 4),
+		Status:    TransactionStatus(data[1] & 0x03),
+	}, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *ACKPDU) Humanize() string {
+	return strings.Join([]string{
+		"ACK PDU",
+		p.Header.Humanize(),
+		"  Acknowledged: " + p.Directive.String(),
+		"  Condition: " + p.Condition.String(),
+		"  Transaction Status: " + p.Status.String(),
+	}, "\n")
+}
+
+// SegmentRequest is a range [Start, End) of file data requested for
+// retransmission. The range 0-0 requests the Metadata PDU.
+type SegmentRequest struct {
+	Start, End uint64
+}
+
+// NAKPDU requests retransmission of the file data missing within its
+// scope (CCSDS 727.0-B-5 Section 5.2.6).
+type NAKPDU struct {
+	Header     Header
+	ScopeStart uint64
+	ScopeEnd   uint64
+	Segments   []SegmentRequest
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *NAKPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *NAKPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	out, err := appendFSS([]byte{byte(DirectiveNAK)}, &p.Header, p.ScopeStart)
+	if err != nil {
+		return nil, err
+	}
+	if out, err = appendFSS(out, &p.Header, p.ScopeEnd); err != nil {
+		return nil, err
+	}
+	for _, r := range p.Segments {
+		if out, err = appendFSS(out, &p.Header, r.Start); err != nil {
+			return nil, err
+		}
+		if out, err = appendFSS(out, &p.Header, r.End); err != nil {
+			return nil, err
+		}
+	}
+	return p.Header.encode(out)
+}
+
+func decodeNAK(h *Header, data []byte) (*NAKPDU, error) {
+	p := &NAKPDU{Header: *h}
+	var err error
+	if p.ScopeStart, data, err = readFSS(data, h); err != nil {
+		return nil, err
+	}
+	if p.ScopeEnd, data, err = readFSS(data, h); err != nil {
+		return nil, err
+	}
+	if len(data)%(2*h.fss()) != 0 {
+		return nil, ErrInvalidPDU
+	}
+	for len(data) > 0 {
+		var r SegmentRequest
+		r.Start, data, _ = readFSS(data, h)
+		r.End, data, _ = readFSS(data, h)
+		p.Segments = append(p.Segments, r)
+	}
+	return p, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *NAKPDU) Humanize() string {
+	lines := []string{
+		"NAK PDU",
+		p.Header.Humanize(),
+		"  Scope: " + strconv.FormatUint(p.ScopeStart, 10) + "-" + strconv.FormatUint(p.ScopeEnd, 10),
+	}
+	for _, r := range p.Segments {
+		lines = append(lines, "  Segment Request: "+strconv.FormatUint(r.Start, 10)+"-"+strconv.FormatUint(r.End, 10))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PromptResponse is the response a Prompt PDU asks for.
+type PromptResponse uint8
+
+// Prompt responses.
+const (
+	PromptNAK       PromptResponse = 0
+	PromptKeepAlive PromptResponse = 1
+)
+
+// PromptPDU asks the receiver for a NAK or Keep Alive PDU
+// (CCSDS 727.0-B-5 Section 5.2.7).
+type PromptPDU struct {
+	Header   Header
+	Response PromptResponse
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *PromptPDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *PromptPDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	return p.Header.encode([]byte{byte(DirectivePrompt), byte(p.Response&1) << 7})
+}
+
+func decodePrompt(h *Header, data []byte) (*PromptPDU, error) {
+	if len(data) < 1 {
+		return nil, ErrInvalidPDU
+	}
+	return &PromptPDU{Header: *h, Response: PromptResponse(data[0] >> 7)}, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *PromptPDU) Humanize() string {
+	resp := "NAK"
+	if p.Response == PromptKeepAlive {
+		resp = "Keep Alive"
+	}
+	return strings.Join([]string{
+		"Prompt PDU",
+		p.Header.Humanize(),
+		"  Response Required: " + resp,
+	}, "\n")
+}
+
+// KeepAlivePDU reports the receiver's progress (CCSDS 727.0-B-5 Section 5.2.8).
+type KeepAlivePDU struct {
+	Header   Header
+	Progress uint64
+}
+
+// PDUHeader returns the fixed PDU header.
+func (p *KeepAlivePDU) PDUHeader() *Header { return &p.Header }
+
+// Encode returns the encoded PDU.
+func (p *KeepAlivePDU) Encode() ([]byte, error) {
+	p.Header.Type = PDUFileDirective
+	out, err := appendFSS([]byte{byte(DirectiveKeepAlive)}, &p.Header, p.Progress)
+	if err != nil {
+		return nil, err
+	}
+	return p.Header.encode(out)
+}
+
+func decodeKeepAlive(h *Header, data []byte) (*KeepAlivePDU, error) {
+	progress, _, err := readFSS(data, h)
+	if err != nil {
+		return nil, err
+	}
+	return &KeepAlivePDU{Header: *h, Progress: progress}, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *KeepAlivePDU) Humanize() string {
+	return strings.Join([]string{
+		"Keep Alive PDU",
+		p.Header.Humanize(),
+		"  Progress: " + strconv.FormatUint(p.Progress, 10),
+	}, "\n")
+}
@@ -0,0 +1,186 @@
+package cfdp_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+)
+
+func roundTrip(t *testing.T, p cfdp.PDU) cfdp.PDU {
+	t.Helper()
+	data, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cfdp.DecodePDU(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestMetadataPDU_RoundTrip(t *testing.T) {
+	p := &cfdp.MetadataPDU{
+		Header:           testHeader(),
+		ClosureRequested: true,
+		ChecksumType:     cfdp.ChecksumCRC32,
+		FileSize:         123456,
+		SourceFileName:   "/src/image.raw",
+		DestFileName:     "dst/image.raw",
+		FilestoreRequests: []cfdp.FilestoreRequest{
+			{Action: cfdp.CreateDirectory, FirstName: "dst"},
+			{Action: cfdp.RenameFile, FirstName: "a", SecondName: "b"},
+		},
+		MessagesToUser: [][]byte{[]byte("hello")},
+		FaultHandlers:  []cfdp.FaultHandlerOverride{{Condition: cfdp.FileChecksumFailure, Handler: cfdp.HandlerIgnore}},
+		FlowLabel:      []byte{0x42},
+	}
+	got, ok := roundTrip(t, p).(*cfdp.MetadataPDU)
+	if !ok {
+		t.Fatal("not a Metadata PDU")
+	}
+	p.Header.DataLength = got.Header.DataLength
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("got %+v\nwant %+v", got, p)
+	}
+	if !strings.Contains(got.Humanize(), "Destination File: dst/image.raw") {
+		t.Errorf("humanize:\n%s", got.Humanize())
+	}
+}
+
+func TestFileDataPDU_RoundTrip(t *testing.T) {
+	h := testHeader()
+	h.LargeFile = true
+	h.SegmentMetadata = true
+	p := &cfdp.FileDataPDU{
+		Header:             h,
+		RecordContinuation: cfdp.RecordStartEnd,
+		SegmentMetadata:    []byte{1, 2, 3},
+		Offset:             1 << 40,
+		Data:               []byte("segment"),
+	}
+	got := roundTrip(t, p).(*cfdp.FileDataPDU)
+	if got.Offset != 1<<40 || !bytes.Equal(got.Data, p.Data) || got.RecordContinuation != cfdp.RecordStartEnd ||
+		!bytes.Equal(got.SegmentMetadata, p.SegmentMetadata) || got.Header.Type != cfdp.PDUFileData {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestFileDataPDU_OffsetOverflow(t *testing.T) {
+	p := &cfdp.FileDataPDU{Header: testHeader(), Offset: 1 << 32}
+	if _, err := p.Encode(); !errors.Is(err, cfdp.ErrFieldOverflow) {
+		t.Errorf("expected ErrFieldOverflow, got %v", err)
+	}
+}
+
+func TestEOFPDU_RoundTrip(t *testing.T) {
+	loc := cfdp.EntityID(0x0304)
+	p := &cfdp.EOFPDU{
+		Header:        testHeader(),
+		Condition:     cfdp.CancelRequestReceived,
+		Checksum:      0x01020304,
+		FileSize:      77,
+		FaultLocation: &loc,
+	}
+	got := roundTrip(t, p).(*cfdp.EOFPDU)
+	if got.Condition != cfdp.CancelRequestReceived || got.Checksum != 0x01020304 || got.FileSize != 77 ||
+		got.FaultLocation == nil || *got.FaultLocation != loc {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestFinishedPDU_RoundTrip(t *testing.T) {
+	h := testHeader()
+	h.Direction = cfdp.TowardSender
+	p := &cfdp.FinishedPDU{
+		Header:    h,
+		Condition: cfdp.NoError,
+		Delivery:  cfdp.DataComplete,
+		Status:    cfdp.FileRetained,
+		FilestoreResponses: []cfdp.FilestoreResponse{
+			{Action: cfdp.DeleteFile, Status: 1, FirstName: "x", Message: "no such file"},
+			{Action: cfdp.AppendFile, Status: cfdp.FilestoreNotPerformed, FirstName: "a", SecondName: "b"},
+		},
+	}
+	got := roundTrip(t, p).(*cfdp.FinishedPDU)
+	p.Header.DataLength = got.Header.DataLength
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("got %+v\nwant %+v", got, p)
+	}
+}
+
+func TestACKPDU_RoundTrip(t *testing.T) {
+	p := &cfdp.ACKPDU{
+		Header:    testHeader(),
+		Directive: cfdp.DirectiveFinished,
+		Condition: cfdp.FileChecksumFailure,
+		Status:    cfdp.TransactionTerminated,
+	}
+	got := roundTrip(t, p).(*cfdp.ACKPDU)
+	p.Header.DataLength = got.Header.DataLength
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("got %+v\nwant %+v", got, p)
+	}
+}
+
+func TestNAKPDU_RoundTrip(t *testing.T) {
+	for _, large := range []bool{false, true} {
+		h := testHeader()
+		h.Direction = cfdp.TowardSender
+		h.LargeFile = large
+		p := &cfdp.NAKPDU{
+			Header:     h,
+			ScopeStart: 0,
+			ScopeEnd:   5000,
+			Segments:   []cfdp.SegmentRequest{{Start: 0, End: 0}, {Start: 100, End: 200}, {Start: 4000, End: 5000}},
+		}
+		got := roundTrip(t, p).(*cfdp.NAKPDU)
+		p.Header.DataLength = got.Header.DataLength
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("large %v: got %+v\nwant %+v", large, got, p)
+		}
+		if !strings.Contains(got.Humanize(), "Segment Request: 100-200") {
+			t.Errorf("humanize:\n%s", got.Humanize())
+		}
+	}
+}
+
+func TestPromptPDU_RoundTrip(t *testing.T) {
+	for _, r := range []cfdp.PromptResponse{cfdp.PromptNAK, cfdp.PromptKeepAlive} {
+		got := roundTrip(t, &cfdp.PromptPDU{Header: testHeader(), Response: r}).(*cfdp.PromptPDU)
+		if got.Response != r {
+			t.Errorf("response %v, want %v", got.Response, r)
+		}
+	}
+}
+
+func TestKeepAlivePDU_RoundTrip(t *testing.T) {
+	h := testHeader()
+	h.Direction = cfdp.TowardSender
+	got := roundTrip(t, &cfdp.KeepAlivePDU{Header: h, Progress: 123456}).(*cfdp.KeepAlivePDU)
+	if got.Progress != 123456 {
+		t.Errorf("progress %d", got.Progress)
+	}
+	p := &cfdp.KeepAlivePDU{Header: testHeader(), Progress: 1 << 32}
+	if _, err := p.Encode(); !errors.Is(err, cfdp.ErrFieldOverflow) {
+		t.Errorf("expected ErrFieldOverflow, got %v", err)
+	}
+}
+
+func TestDecodePDU_Errors(t *testing.T) {
+	data, _ := (&cfdp.EOFPDU{Header: testHeader()}).Encode()
+	bad := append([]byte(nil), data...)
+	bad[12] = 0x0A // directive code follows the 12-byte header
+	if _, err := cfdp.DecodePDU(bad); !errors.Is(err, cfdp.ErrUnknownDirective) {
+		t.Errorf("expected ErrUnknownDirective, got %v", err)
+	}
+
+	long := &cfdp.MetadataPDU{Header: testHeader(), SourceFileName: strings.Repeat("x", 256)}
+	if _, err := long.Encode(); !errors.Is(err, cfdp.ErrNameTooLong) {
+		t.Errorf("expected ErrNameTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package cfdp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// entityStateFile holds the entity state in the state directory. Each
+// active transaction is saved next to it as "<source>-<seq>.json".
+const entityStateFile = "entity.json"
+
+// WithStateDir saves the state of active transactions in dir so that
+// they survive a restart. Call Restore after NewEntity to reload them.
+func WithStateDir(dir string) EntityOption {
+	return func(e *Entity) {
+		e.stateDir = dir
+	}
+}
+
+// entityState is the saved entity state.
+type entityState struct {
+	NextSeq uint64 `json:"next_seq"`
+}
+
+// savedTransaction is the saved state of a transaction. Timers are not
+// saved; they restart when the transaction is restored.
+type savedTransaction struct {
+	Sender      bool         `json:"sender"`
+	Header      Header       `json:"header"`
+	Metadata    *MetadataPDU `json:"metadata,omitempty"`
+	EOF         *EOFPDU      `json:"eof,omitempty"`
+	Finished    *FinishedPDU `json:"finished,omitempty"`
+	Suspended   bool         `json:"suspended,omitempty"`
+	Offset      uint64       `json:"offset,omitempty"`       // sender: data sent
+	EOFAcked    bool         `json:"eof_acked,omitempty"`    // sender
+	MetadataNAK bool         `json:"metadata_nak,omitempty"` // sender
+	Retransmit  [][2]uint64  `json:"retransmit,omitempty"`   // sender: ranges to resend
+	Received    [][2]uint64  `json:"received,omitempty"`     // receiver: ranges received
+	TempFile    string       `json:"temp_file,omitempty"`    // receiver: file data, in the filestore root
+}
+
+func (s segments) ranges() [][2]uint64 {
+	out := make([][2]uint64, len(s))
+	for i, g := range s {
+		out[i] = [2]uint64{g.start, g.end}
+	}
+	return out
+}
+
+func rangeSegments(r [][2]uint64) segments {
+	var s segments
+	for _, g := range r {
+		s = s.add(g[0], g[1])
+	}
+	return s
+}
+
+func (s *sender) state() *savedTransaction {
+	s.dirty = false
+	return &savedTransaction{
+		Sender:      true,
+		Header:      s.header,
+		Metadata:    &s.metadata,
+		EOF:         s.eof,
+		Suspended:   s.suspended,
+		Offset:      s.offset,
+		EOFAcked:    s.eofAcked,
+		MetadataNAK: s.metadataNAK,
+		Retransmit:  s.retransmit.ranges(),
+	}
+}
+
+func (r *receiver) state() *savedTransaction {
+	r.dirty = false
+	st := &savedTransaction{
+		Header:    r.header,
+		Metadata:  r.metadata,
+		EOF:       r.eof,
+		Finished:  r.fin,
+		Suspended: r.suspended,
+		Received:  r.received.ranges(),
+	}
+	if r.tmp != nil {
+		st.TempFile = filepath.Base(r.tmp.Name())
+	}
+	return st
+}
+
+// stateFile returns the path of the saved state of transaction id.
+func (e *Entity) stateFile(id TransactionID) string {
+	return filepath.Join(e.stateDir, fmt.Sprintf("%d-%d.json", id.Source, id.Seq))
+}
+
+// writeState writes v as JSON to path, replacing it atomically.
+func (e *Entity) writeState(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(e.stateDir, 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// save saves the state of a transaction.
+func (e *Entity) save(t transaction) error {
+	if e.stateDir == "" {
+		return nil
+	}
+	st := t.state()
+	return e.writeState(e.stateFile(st.Header.Transaction()), st)
+}
+
+// saveEntity saves the entity state.
+func (e *Entity) saveEntity() error {
+	if e.stateDir == "" {
+		return nil
+	}
+	return e.writeState(filepath.Join(e.stateDir, entityStateFile), entityState{NextSeq: e.nextSeq})
+}
+
+// saveDirty saves the transactions changed since they were last saved.
+func (e *Entity) saveDirty() error {
+	if e.stateDir == "" {
+		return nil
+	}
+	var errs []error
+	for _, s := range e.senders {
+		if s.dirty {
+			errs = append(errs, e.save(s))
+		}
+	}
+	for _, r := range e.receivers {
+		if r.dirty {
+			errs = append(errs, e.save(r))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// forget removes the saved state of an ended transaction.
+func (e *Entity) forget(id TransactionID) {
+	if e.stateDir != "" {
+		os.Remove(e.stateFile(id))
+	}
+}
+
+// Restore reloads the transactions saved in the state directory set
+// with WithStateDir, restarting their timers. A transaction that cannot
+// be restored is skipped and its error returned with the others.
+func (e *Entity) Restore() error {
+	if e.stateDir == "" {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var es entityState
+	data, err := os.ReadFile(filepath.Join(e.stateDir, entityStateFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &es); err != nil {
+			return err
+		}
+		e.nextSeq = max(e.nextSeq, es.NextSeq)
+	}
+
+	entries, err := os.ReadDir(e.stateDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var errs []error
+	now := e.clock.Now()
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == entityStateFile || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(e.stateDir, name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var st savedTransaction
+		if err := json.Unmarshal(data, &st); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := e.restore(&st, now); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restore recreates a saved transaction.
+func (e *Entity) restore(st *savedTransaction, now time.Time) error {
+	if st.Sender {
+		s, err := e.restoreSender(st, now)
+		if err != nil {
+			return err
+		}
+		e.senders[s.id] = s
+		return nil
+	}
+	r, err := e.restoreReceiver(st, now)
+	if err != nil {
+		return err
+	}
+	e.receivers[r.id] = r
+	return nil
+}
+
+func (e *Entity) restoreSender(st *savedTransaction, now time.Time) (*sender, error) {
+	if st.Metadata == nil {
+		return nil, ErrInvalidPDU
+	}
+	remote := e.remote(st.Header.DestinationID)
+	remote.Acknowledged = st.Header.Mode == Acknowledged
+	sum, err := NewChecksum(st.Metadata.ChecksumType)
+	if err != nil {
+		return nil, err
+	}
+	s := &sender{
+		id:          st.Header.Transaction(),
+		header:      st.Header,
+		remote:      remote,
+		metadata:    *st.Metadata,
+		size:        st.Metadata.FileSize,
+		offset:      st.Offset,
+		sum:         sum,
+		eof:         st.EOF,
+		eofAcked:    st.EOFAcked,
+		retransmit:  rangeSegments(st.Retransmit),
+		metadataNAK: st.MetadataNAK,
+		ackTimer:    now.Add(remote.ACKTimeout),
+		ackCount:    1,
+		deadline:    now.Add(e.inactivity),
+		suspended:   st.Suspended,
+	}
+	if s.metadata.SourceFileName != "" {
+		if s.file, err = e.fs.Open(s.metadata.SourceFileName); err != nil {
+			return nil, err
+		}
+		if s.eof == nil && s.offset > 0 {
+			// Recompute the checksum of the data already sent.
+			if _, err := io.Copy(s.sum, io.NewSectionReader(s.file, 0, int64(s.offset))); err != nil {
+				s.close()
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (e *Entity) restoreReceiver(st *savedTransaction, now time.Time) (*receiver, error) {
+	r := e.newReceiver(&st.Header)
+	r.metadata = st.Metadata
+	r.eof = st.EOF
+	r.fin = st.Finished
+	r.suspended = st.Suspended
+	r.received = rangeSegments(st.Received)
+	if st.TempFile != "" {
+		if filepath.Base(st.TempFile) != st.TempFile {
+			return nil, ErrInvalidPath
+		}
+		tmp, err := os.OpenFile(filepath.Join(e.fs.Root(), st.TempFile), os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		r.tmp = tmp
+	}
+	switch {
+	case r.fin != nil:
+		r.ackCount = 1
+		r.ackTimer = now.Add(r.remote.ACKTimeout)
+	case r.eof != nil && r.acknowledged():
+		r.nakTimer = now.Add(r.remote.NAKTimeout)
+	case r.eof != nil:
+		r.check = now.Add(e.checkTimeout)
+	}
+	return r, nil
+}
+
+// Close saves the state of the active transactions, if WithStateDir is
+// set, and closes their files. Without a state directory, received data
+// is discarded. The entity must not be used after Close.
+func (e *Entity) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var errs []error
+	for id, s := range e.senders {
+		errs = append(errs, e.save(s))
+		s.close()
+		delete(e.senders, id)
+	}
+	for id, r := range e.receivers {
+		errs = append(errs, e.save(r))
+		if e.stateDir == "" {
+			r.discard()
+		}
+		r.close()
+		delete(e.receivers, id)
+	}
+	return errors.Join(errs...)
+}
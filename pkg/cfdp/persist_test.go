@@ -0,0 +1,136 @@
+package cfdp_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+)
+
+// reopen replaces both entities of the link with new ones saving their
+// state in srcDir and dstDir, and restores them.
+func (l *testLink) reopen(t *testing.T, remote cfdp.RemoteEntity, srcDir, dstDir string) {
+	t.Helper()
+	remote.ID = 2
+	l.src = cfdp.NewEntity(1, l.srcFS, endpoint{out: l.up, in: l.down},
+		cfdp.WithClock(l.clock),
+		cfdp.WithRemoteEntity(remote),
+		cfdp.WithBurst(2),
+		cfdp.WithStateDir(srcDir),
+		cfdp.WithIndicationHandler(func(i cfdp.Indication) { l.srcInd = append(l.srcInd, i) }),
+	)
+	l.dst = cfdp.NewEntity(2, l.dstFS, endpoint{out: l.down, in: l.up},
+		cfdp.WithClock(l.clock),
+		cfdp.WithStateDir(dstDir),
+		cfdp.WithIndicationHandler(func(i cfdp.Indication) { l.dstInd = append(l.dstInd, i) }),
+	)
+	if err := l.src.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.dst.Restore(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEntity_Restore(t *testing.T) {
+	remote := cfdp.RemoteEntity{MaxFileSegment: 10, Acknowledged: true, ChecksumType: cfdp.ChecksumCRC32}
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	l := newTestLink(t, remote)
+	l.reopen(t, remote, srcDir, dstDir)
+
+	data := testData(100)
+	writeFile(t, l.srcFS, "f", data)
+	l.up.filter = dropOnce(fileDataAt(10))
+	id, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := l.src.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 4 {
+		if err := l.dst.Receive(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.dst.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l.reopen(t, remote, srcDir, dstDir)
+	if l.src.Pending() != 1 || l.dst.Pending() != 1 {
+		t.Fatalf("restored %d/%d transactions", l.src.Pending(), l.dst.Pending())
+	}
+	if ids := l.src.Transactions(); !slices.Equal(ids, []cfdp.TransactionID{id}) {
+		t.Errorf("transactions %v", ids)
+	}
+	l.run(t)
+
+	got, err := os.ReadFile(filepath.Join(l.dstFS.Root(), "f"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("received file: %v", err)
+	}
+	if fin := finished(l.srcInd); fin == nil || fin.Condition != cfdp.NoError {
+		t.Errorf("sender finished %+v", fin)
+	}
+	for _, dir := range []string{srcDir, dstDir} {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if e.Name() != "entity.json" {
+				t.Errorf("state left in %s: %s", dir, e.Name())
+			}
+		}
+	}
+
+	// The sequence number survives the restart.
+	l.reopen(t, remote, srcDir, dstDir)
+	id, err = l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f", DestFile: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Seq != 2 {
+		t.Errorf("sequence number %d after restart", id.Seq)
+	}
+}
+
+func TestEntity_Close(t *testing.T) {
+	l := newTestLink(t, cfdp.RemoteEntity{MaxFileSegment: 10})
+	writeFile(t, l.srcFS, "f", testData(100))
+	if _, err := l.src.Put(cfdp.PutRequest{Destination: 2, SourceFile: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.src.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := l.dst.Receive(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.dst.Pending() != 1 {
+		t.Fatalf("pending %d", l.dst.Pending())
+	}
+	if err := l.dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a state directory the received data is discarded.
+	if l.dst.Pending() != 0 {
+		t.Errorf("pending %d", l.dst.Pending())
+	}
+	entries, _ := os.ReadDir(l.dstFS.Root())
+	if len(entries) != 0 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+}
@@ -0,0 +1,369 @@
+package cfdp
+
+import (
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+// sender is the sending side of a transaction
+// (CCSDS 727.0-B-5 Sections 4.6.1.1 and 4.6.4.1).
+type sender struct {
+	id          TransactionID
+	header      Header
+	remote      RemoteEntity
+	metadata    MetadataPDU
+	file        *os.File // nil for a metadata-only transaction
+	size        uint64
+	offset      uint64 // next offset to send for the first time
+	sum         hash.Hash32
+	eof         *EOFPDU // sent EOF PDU, kept for retransmission
+	eofAcked    bool
+	retransmit  segments // Class 2: ranges requested by NAK
+	metadataNAK bool     // Class 2: Metadata PDU requested by NAK
+	progress    uint64   // Class 2: progress from the last Keep Alive PDU
+	ackTimer    time.Time
+	ackCount    int
+	deadline    time.Time // inactivity deadline once EOF is sent
+	suspended   bool
+	dirty       bool // state changed since last saved
+}
+
+// newSender opens the source file of req and assigns the next sequence number.
+func (e *Entity) newSender(req PutRequest) (*sender, error) {
+	remote := e.remote(req.Destination)
+	if req.Acknowledged != nil {
+		remote.Acknowledged = *req.Acknowledged
+	}
+	closure := remote.ClosureRequested || remote.Acknowledged
+	if req.ClosureRequested != nil && !remote.Acknowledged {
+		closure = *req.ClosureRequested
+	}
+	sum, err := NewChecksum(remote.ChecksumType)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sender{
+		id:     TransactionID{Source: e.id, Seq: e.nextSeq & seqMask(e.seqLength)},
+		remote: remote,
+		sum:    sum,
+	}
+	if req.SourceFile != "" {
+		if s.file, err = e.fs.Open(req.SourceFile); err != nil {
+			return nil, err
+		}
+		info, err := s.file.Stat()
+		if err != nil {
+			s.file.Close()
+			return nil, err
+		}
+		s.size = uint64(info.Size())
+	}
+	mode := Unacknowledged
+	if remote.Acknowledged {
+		mode = Acknowledged
+	}
+	s.header = Header{
+		Version:        Version,
+		Mode:           mode,
+		CRC:            remote.CRC,
+		LargeFile:      s.size > 0xFFFFFFFF,
+		EntityIDLength: e.idLength,
+		SeqNumLength:   e.seqLength,
+		SourceID:       e.id,
+		SeqNum:         s.id.Seq,
+		DestinationID:  req.Destination,
+	}
+	if err := s.header.Validate(); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	dest := req.DestFile
+	if dest == "" {
+		dest = req.SourceFile
+	}
+	s.metadata = MetadataPDU{
+		Header:            s.header,
+		ClosureRequested:  closure,
+		ChecksumType:      remote.ChecksumType,
+		FileSize:          s.size,
+		SourceFileName:    req.SourceFile,
+		DestFileName:      dest,
+		FilestoreRequests: req.FilestoreRequests,
+		MessagesToUser:    req.Messages,
+		FaultHandlers:     req.FaultHandlers,
+		FlowLabel:         req.FlowLabel,
+	}
+	if req.SourceFile == "" {
+		s.metadata.DestFileName = ""
+	}
+	return s, nil
+}
+
+// seqMask returns the largest sequence number that fits n bytes.
+func seqMask(n uint8) uint64 {
+	if n >= 8 {
+		return ^uint64(0)
+	}
+	return 1<<(8*n) - 1
+}
+
+// acknowledged reports whether the transaction is Class 2.
+func (s *sender) acknowledged() bool {
+	return s.header.Mode == Acknowledged
+}
+
+// poll sends up to a burst of file data PDUs, retransmissions first,
+// then the EOF PDU, and services the ACK and inactivity timers.
+func (s *sender) poll(e *Entity, now time.Time) error {
+	if s.suspended {
+		return nil
+	}
+	if s.eof != nil {
+		if s.acknowledged() && !s.eofAcked && !now.Before(s.ackTimer) {
+			if err := s.ackExpired(e, now); err != nil || s.done(e) {
+				return err
+			}
+		}
+		if s.metadata.ClosureRequested && now.After(s.deadline) {
+			return s.fault(e, InactivityDetected)
+		}
+		if s.eof.Condition != NoError {
+			return nil // cancelled: no more file data
+		}
+	}
+
+	if s.metadataNAK {
+		if err := e.send(&s.metadata); err != nil {
+			return err
+		}
+		s.metadataNAK = false
+		s.dirty = true
+	}
+	budget := e.burst
+	for budget > 0 && len(s.retransmit) > 0 {
+		g := s.retransmit[0]
+		n := min(uint64(s.remote.MaxFileSegment), g.end-g.start)
+		if _, err := s.sendData(e, g.start, n); err != nil {
+			return err
+		}
+		s.retransmit = s.retransmit.remove(g.start, g.start+n)
+		s.dirty = true
+		budget--
+	}
+	for budget > 0 && s.offset < s.size {
+		n := min(uint64(s.remote.MaxFileSegment), s.size-s.offset)
+		data, err := s.sendData(e, s.offset, n)
+		if err != nil {
+			return err
+		}
+		s.sum.Write(data)
+		s.offset += n
+		s.dirty = true
+		budget--
+	}
+	if s.offset < s.size || s.eof != nil {
+		return nil
+	}
+	return s.sendEOF(e, NoError, now)
+}
+
+// sendData sends the n bytes of file data at offset.
+func (s *sender) sendData(e *Entity, offset, n uint64) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := s.file.ReadAt(buf, int64(offset)); err != nil && err != io.EOF {
+		s.abort(e, FilestoreRejection)
+		return nil, err
+	}
+	return buf, e.send(&FileDataPDU{Header: s.header, Offset: offset, Data: buf})
+}
+
+// sendEOF sends the EOF PDU. In Class 1 without closure the transaction
+// ends here; otherwise it waits for the ACK or the Finished PDU.
+func (s *sender) sendEOF(e *Entity, c ConditionCode, now time.Time) error {
+	s.eof = &EOFPDU{Header: s.header, Condition: c, Checksum: s.sum.Sum32(), FileSize: s.size}
+	if c != NoError {
+		s.eof.FileSize = s.offset
+		id := e.id
+		s.eof.FaultLocation = &id
+	}
+	s.eofAcked = false
+	s.ackCount = 1
+	s.ackTimer = now.Add(s.remote.ACKTimeout)
+	s.deadline = now.Add(e.inactivity)
+	s.dirty = true
+	if err := e.send(s.eof); err != nil {
+		return err
+	}
+	e.notes = append(e.notes, Indication{Type: IndicationEOFSent, Transaction: s.id})
+	if s.acknowledged() || (c == NoError && s.metadata.ClosureRequested) {
+		return nil
+	}
+	delivery := DataComplete
+	if c != NoError {
+		delivery = DataIncomplete
+	}
+	s.finish(e, Indication{Condition: c, Delivery: delivery, Status: FileStatusUnreported})
+	return nil
+}
+
+// ackExpired retransmits the EOF PDU, or raises the Positive ACK Limit
+// Reached fault once the limit is reached. An unacknowledged EOF of a
+// cancelled transaction abandons it.
+func (s *sender) ackExpired(e *Entity, now time.Time) error {
+	if s.ackCount >= s.remote.ACKLimit {
+		if s.eof.Condition != NoError {
+			s.abort(e, PositiveACKLimitReached)
+			return nil
+		}
+		return s.fault(e, PositiveACKLimitReached)
+	}
+	s.ackCount++
+	s.ackTimer = now.Add(s.remote.ACKTimeout)
+	s.dirty = true
+	return e.send(s.eof)
+}
+
+// handle processes a PDU sent toward the sender.
+func (s *sender) handle(e *Entity, p PDU) error {
+	s.deadline = e.clock.Now().Add(e.inactivity)
+	s.dirty = true
+	switch p := p.(type) {
+	case *FinishedPDU:
+		if s.acknowledged() {
+			ack := &ACKPDU{Header: s.header, Directive: DirectiveFinished, Condition: p.Condition, Status: TransactionTerminated}
+			if err := e.send(ack); err != nil {
+				return err
+			}
+		}
+		s.finish(e, Indication{
+			Condition:          p.Condition,
+			Delivery:           p.Delivery,
+			Status:             p.Status,
+			FilestoreResponses: p.FilestoreResponses,
+		})
+	case *ACKPDU:
+		if p.Directive != DirectiveEOF || s.eof == nil {
+			return ErrInvalidPDU
+		}
+		s.eofAcked = true
+		if s.eof.Condition != NoError {
+			// Cancellation acknowledged; no Finished PDU follows.
+			s.finish(e, Indication{Condition: s.eof.Condition, Delivery: DataIncomplete, Status: FileStatusUnreported})
+		}
+	case *NAKPDU:
+		for _, r := range p.Segments {
+			if r.Start == 0 && r.End == 0 {
+				s.metadataNAK = true
+				continue
+			}
+			// Only data already sent can be missing.
+			s.retransmit = s.retransmit.add(r.Start, min(r.End, s.offset))
+		}
+	case *KeepAlivePDU:
+		s.progress = p.Progress
+		if s.remote.KeepAliveLimit > 0 && s.offset > p.Progress && s.offset-p.Progress > s.remote.KeepAliveLimit {
+			return s.fault(e, KeepAliveLimitReached)
+		}
+	default:
+		return ErrInvalidPDU
+	}
+	return nil
+}
+
+// fault applies the fault handler for condition c.
+func (s *sender) fault(e *Entity, c ConditionCode) error {
+	switch e.faultHandler(s.metadata.FaultHandlers, c) {
+	case HandlerIgnore:
+		e.notes = append(e.notes, Indication{Type: IndicationFault, Transaction: s.id, Condition: c})
+		now := e.clock.Now()
+		s.deadline = now.Add(e.inactivity)
+		s.ackCount = 0
+		s.ackTimer = now.Add(s.remote.ACKTimeout)
+		return nil
+	case HandlerSuspend:
+		s.suspend(e, c)
+		return nil
+	case HandlerAbandon:
+		s.abort(e, c)
+		return nil
+	default:
+		return s.cancel(e, c)
+	}
+}
+
+// cancel ends the transaction with an EOF PDU carrying condition c. In
+// Class 1 a transaction already past EOF just ends.
+func (s *sender) cancel(e *Entity, c ConditionCode) error {
+	if s.eof != nil {
+		if s.eof.Condition != NoError {
+			return nil // already cancelling
+		}
+		if !s.acknowledged() {
+			s.finish(e, Indication{Condition: c, Delivery: DataIncomplete, Status: FileStatusUnreported})
+			return nil
+		}
+	}
+	s.suspended = false
+	err := s.sendEOF(e, c, e.clock.Now())
+	if err != nil {
+		s.abort(e, c)
+	}
+	return err
+}
+
+func (s *sender) suspend(e *Entity, c ConditionCode) {
+	if s.suspended {
+		return
+	}
+	s.suspended = true
+	s.dirty = true
+	e.notes = append(e.notes, Indication{Type: IndicationSuspended, Transaction: s.id, Condition: c})
+}
+
+func (s *sender) resume(e *Entity, now time.Time) {
+	if !s.suspended {
+		return
+	}
+	s.suspended = false
+	s.ackTimer = now.Add(s.remote.ACKTimeout)
+	s.deadline = now.Add(e.inactivity)
+	s.dirty = true
+	e.notes = append(e.notes, Indication{Type: IndicationResumed, Transaction: s.id, Offset: s.offset})
+}
+
+// done reports whether the transaction has ended.
+func (s *sender) done(e *Entity) bool {
+	return e.senders[s.id] != s
+}
+
+// abort abandons the transaction without sending anything.
+func (s *sender) abort(e *Entity, c ConditionCode) {
+	s.end(e)
+	e.notes = append(e.notes, Indication{Type: IndicationAbandoned, Transaction: s.id, Condition: c})
+}
+
+// finish ends the transaction with a Transaction-Finished indication.
+func (s *sender) finish(e *Entity, ind Indication) {
+	s.end(e)
+	ind.Type = IndicationTransactionFinished
+	ind.Transaction = s.id
+	e.notes = append(e.notes, ind)
+}
+
+// end removes the transaction and its saved state.
+func (s *sender) end(e *Entity) {
+	s.close()
+	delete(e.senders, s.id)
+	e.forget(s.id)
+}
+
+func (s *sender) close() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
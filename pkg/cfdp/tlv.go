@@ -0,0 +1,234 @@
+package cfdp
+
+import (
+	"strconv"
+)
+
+// TLV types (CCSDS 727.0-B-5 Table 5-3).
+const (
+	TLVFilestoreRequest     uint8 = 0x00
+	TLVFilestoreResponse    uint8 = 0x01
+	TLVMessageToUser        uint8 = 0x02
+	TLVFaultHandlerOverride uint8 = 0x04
+	TLVFlowLabel            uint8 = 0x05
+	TLVEntityID             uint8 = 0x06
+)
+
+// TLV is a type-length-value parameter of a file directive PDU.
+type TLV struct {
+	Type  uint8
+	Value []byte
+}
+
+// appendTLV appends t, whose value must not exceed 255 bytes.
+func appendTLV(out []byte, typ uint8, value []byte) ([]byte, error) {
+	if len(value) > 0xFF {
+		return nil, ErrNameTooLong
+	}
+	out = append(out, typ, byte(len(value)))
+	return append(out, value...), nil
+}
+
+// decodeTLVs splits data into TLVs.
+func decodeTLVs(data []byte) ([]TLV, error) {
+	var out []TLV
+	for len(data) > 0 {
+		if len(data) < 2 || len(data) < 2+int(data[1]) {
+			return nil, ErrInvalidPDU
+		}
+		n := int(data[1])
+		out = append(out, TLV{Type: data[0], Value: data[2 : 2+n]})
+		data = data[2+n:]
+	}
+	return out, nil
+}
+
+// appendLV appends a length-value field.
+func appendLV(out []byte, v string) ([]byte, error) {
+	if len(v) > 0xFF {
+		return nil, ErrNameTooLong
+	}
+	out = append(out, byte(len(v)))
+	return append(out, v...), nil
+}
+
+// readLV reads a length-value field and returns the rest of data.
+func readLV(data []byte) (string, []byte, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", nil, ErrInvalidPDU
+	}
+	n := int(data[0])
+	return string(data[1 : 1+n]), data[1+n:], nil
+}
+
+// FilestoreAction is the action of a filestore request (CCSDS 727.0-B-5 Table 5-16).
+type FilestoreAction uint8
+
+// Filestore actions.
+const (
+	CreateFile      FilestoreAction = 0
+	DeleteFile      FilestoreAction = 1
+	RenameFile      FilestoreAction = 2
+	AppendFile      FilestoreAction = 3 // append the second file to the first
+	ReplaceFile     FilestoreAction = 4 // replace the first file with the second
+	CreateDirectory FilestoreAction = 5
+	RemoveDirectory FilestoreAction = 6
+	DenyFile        FilestoreAction = 7 // delete the file if it exists
+	DenyDirectory   FilestoreAction = 8 // remove the directory if it exists
+)
+
+// String returns the action name.
+func (a FilestoreAction) String() string {
+	switch a {
+	case CreateFile:
+		return "Create File"
+	case DeleteFile:
+		return "Delete File"
+	case RenameFile:
+		return "Rename File"
+	case AppendFile:
+		return "Append File"
+	case ReplaceFile:
+		return "Replace File"
+	case CreateDirectory:
+		return "Create Directory"
+	case RemoveDirectory:
+		return "Remove Directory"
+	case DenyFile:
+		return "Deny File"
+	case DenyDirectory:
+		return "Deny Directory"
+	default:
+		return "Unknown(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// twoNames reports whether the action takes a second file name.
+func (a FilestoreAction) twoNames() bool {
+	return a == RenameFile || a == AppendFile || a == ReplaceFile
+}
+
+// FilestoreRequest asks the receiving entity to act on its filestore
+// once the file is delivered.
+type FilestoreRequest struct {
+	Action     FilestoreAction
+	FirstName  string
+	SecondName string // rename, append and replace only
+}
+
+func (r *FilestoreRequest) encode() ([]byte, error) {
+	out, err := appendLV([]byte{byte(r.Action) << 4}, r.FirstName)
+	if err != nil {
+		return nil, err
+	}
+	if r.Action.twoNames() {
+		return appendLV(out, r.SecondName)
+	}
+	return out, nil
+}
+
+func decodeFilestoreRequest(v []byte) (FilestoreRequest, error) {
+	if len(v) < 1 {
+		return FilestoreRequest{}, ErrInvalidPDU
+	}
+	r := FilestoreRequest{Action: FilestoreAction(v[0] >> 4)}
+	first, rest, err := readLV(v[1:])
+	if err != nil {
+		return r, err
+	}
+	r.FirstName = first
+	if r.Action.twoNames() {
+		if r.SecondName, _, err = readLV(rest); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// FilestoreStatus is the status of a filestore response. Zero is
+// success and 15 is "not performed"; the other values depend on the
+// action (CCSDS 727.0-B-5 Table 5-18).
+type FilestoreStatus uint8
+
+// Filestore response statuses.
+const (
+	FilestoreSuccess      FilestoreStatus = 0
+	FilestoreNotPerformed FilestoreStatus = 15
+)
+
+// FilestoreResponse reports the outcome of a filestore request.
+type FilestoreResponse struct {
+	Action     FilestoreAction
+	Status     FilestoreStatus
+	FirstName  string
+	SecondName string
+	Message    string
+}
+
+func (r *FilestoreResponse) encode() ([]byte, error) {
+	out, err := appendLV([]byte{byte(r.Action)<<4 | byte(r.Status)&0x0F}, r.FirstName)
+	if err != nil {
+		return nil, err
+	}
+	if r.Action.twoNames() {
+		if out, err = appendLV(out, r.SecondName); err != nil {
+			return nil, err
+		}
+	}
+	return appendLV(out, r.Message)
+}
+
+func decodeFilestoreResponse(v []byte) (FilestoreResponse, error) {
+	if len(v) < 1 {
+		return FilestoreResponse{}, ErrInvalidPDU
+	}
+	r := FilestoreResponse{Action: FilestoreAction(v[0] >> 4), Status: FilestoreStatus(v[0] & 0x0F)}
+	var err error
+	rest := v[1:]
+	if r.FirstName, rest, err = readLV(rest); err != nil {
+		return r, err
+	}
+	if r.Action.twoNames() {
+		if r.SecondName, rest, err = readLV(rest); err != nil {
+			return r, err
+		}
+	}
+	if len(rest) > 0 {
+		if r.Message, _, err = readLV(rest); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// HandlerCode selects the action taken on a fault (CCSDS 727.0-B-5 Section 4.8).
+type HandlerCode uint8
+
+// Fault handler codes.
+const (
+	HandlerCancel  HandlerCode = 1
+	HandlerSuspend HandlerCode = 2
+	HandlerIgnore  HandlerCode = 3
+	HandlerAbandon HandlerCode = 4
+)
+
+// FaultHandlerOverride overrides the fault handler of a condition for
+// one transaction.
+type FaultHandlerOverride struct {
+	Condition ConditionCode
+	Handler   HandlerCode
+}
+
+// appendEntityIDTLV appends the fault location TLV of entity id.
+func appendEntityIDTLV(out []byte, id EntityID) []byte {
+	n := bytesFor(uint64(id))
+	out = append(out, TLVEntityID, n)
+	return appendBytes(out, uint64(id), n)
+}
+
+func decodeEntityIDTLV(v []byte) (EntityID, error) {
+	if len(v) < 1 || len(v) > 8 {
+		return 0, ErrInvalidPDU
+	}
+	return EntityID(readBytes(v, uint8(len(v)))), nil
+}
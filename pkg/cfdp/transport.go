@@ -0,0 +1,117 @@
+package cfdp
+
+import (
+	"errors"
+
+	"github.com/ravisuhag/astro/pkg/epp"
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+// Transport carries encoded PDUs between entities. CFDP assumes the
+// underlying service delivers whole PDUs, possibly with trailing fill,
+// but not necessarily all of them or in order (CCSDS 727.0-B-5 Section 2.2).
+type Transport interface {
+	// SendPDU sends one encoded PDU.
+	SendPDU(pdu []byte) error
+
+	// ReceivePDU returns the next PDU. A non-blocking transport returns
+	// ErrNoPDU when none is waiting.
+	ReceivePDU() ([]byte, error)
+}
+
+// SPPTransport carries PDUs as the user data of space packets on one APID.
+type SPPTransport struct {
+	service *spp.Service
+	apid    uint16
+	opts    []spp.SendOption
+}
+
+// NewSPPTransport returns a transport sending PDUs on apid. Packets
+// received on other APIDs are skipped.
+func NewSPPTransport(s *spp.Service, apid uint16, opts ...spp.SendOption) *SPPTransport {
+	return &SPPTransport{service: s, apid: apid, opts: opts}
+}
+
+// SendPDU sends one encoded PDU in a space packet.
+func (t *SPPTransport) SendPDU(pdu []byte) error {
+	return t.service.SendBytes(t.apid, pdu, t.opts...)
+}
+
+// ReceivePDU returns the user data of the next packet on the APID.
+func (t *SPPTransport) ReceivePDU() ([]byte, error) {
+	for {
+		apid, data, err := t.service.ReceiveBytes()
+		if err != nil {
+			return nil, err
+		}
+		if apid == t.apid {
+			return data, nil
+		}
+	}
+}
+
+// EPPTransport carries PDUs as the data zone of encapsulation packets
+// with one protocol ID.
+type EPPTransport struct {
+	service    *epp.Service
+	protocolID uint8
+	opts       []epp.PacketOption
+}
+
+// NewEPPTransport returns a transport sending PDUs with protocolID.
+// Idle packets and packets with other protocol IDs are skipped.
+func NewEPPTransport(s *epp.Service, protocolID uint8, opts ...epp.PacketOption) *EPPTransport {
+	return &EPPTransport{service: s, protocolID: protocolID, opts: opts}
+}
+
+// SendPDU sends one encoded PDU in an encapsulation packet.
+func (t *EPPTransport) SendPDU(pdu []byte) error {
+	return t.service.SendBytes(t.protocolID, pdu, t.opts...)
+}
+
+// ReceivePDU returns the data zone of the next packet with the protocol ID.
+func (t *EPPTransport) ReceivePDU() ([]byte, error) {
+	for {
+		pid, data, err := t.service.ReceiveBytes()
+		if err != nil {
+			return nil, err
+		}
+		if pid == t.protocolID {
+			return data, nil
+		}
+	}
+}
+
+// MAPATransport carries one PDU per MAP access SDU of a USLP MAP channel.
+type MAPATransport struct {
+	service *usdl.MAPAccessService
+	sduSize int
+}
+
+// NewMAPATransport returns a transport over a MAP access service with
+// the given SDU size. Shorter PDUs are zero-filled to the SDU size; the
+// fill is ignored on receipt because the PDU header carries its length.
+func NewMAPATransport(s *usdl.MAPAccessService, sduSize int) *MAPATransport {
+	return &MAPATransport{service: s, sduSize: sduSize}
+}
+
+// SendPDU sends one encoded PDU as a MAP access SDU.
+func (t *MAPATransport) SendPDU(pdu []byte) error {
+	if len(pdu) > t.sduSize {
+		return ErrPDUExceedsSDU
+	}
+	sdu := make([]byte, t.sduSize)
+	copy(sdu, pdu)
+	return t.service.Send(sdu)
+}
+
+// ReceivePDU returns the next MAP access SDU, or ErrNoPDU when the
+// virtual channel is empty.
+func (t *MAPATransport) ReceivePDU() ([]byte, error) {
+	data, err := t.service.Receive()
+	if errors.Is(err, usdl.ErrNoFramesAvailable) {
+		return nil, ErrNoPDU
+	}
+	return data, err
+}
@@ -0,0 +1,95 @@
+package cfdp_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cfdp"
+	"github.com/ravisuhag/astro/pkg/epp"
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+func testPDU(t *testing.T) []byte {
+	t.Helper()
+	data, err := (&cfdp.FileDataPDU{Header: testHeader(), Offset: 8, Data: []byte("file data")}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSPPTransport(t *testing.T) {
+	var buf bytes.Buffer
+	svc := spp.NewService(&buf, spp.ServiceConfig{PacketType: spp.PacketTypeTM})
+	other := cfdp.NewSPPTransport(svc, 0x21)
+	tr := cfdp.NewSPPTransport(svc, 0x20)
+
+	pdu := testPDU(t)
+	if err := other.SendPDU([]byte("elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SendPDU(pdu); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tr.ReceivePDU()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pdu) {
+		t.Errorf("got %x", got)
+	}
+}
+
+func TestEPPTransport(t *testing.T) {
+	var buf bytes.Buffer
+	svc := epp.NewService(&buf, epp.ServiceConfig{})
+	tr := cfdp.NewEPPTransport(svc, epp.ProtocolIDUserDef)
+
+	pdu := testPDU(t)
+	if err := svc.SendBytes(epp.ProtocolIDIPE, []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SendPDU(pdu); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tr.ReceivePDU()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pdu) {
+		t.Errorf("got %x", got)
+	}
+}
+
+func TestMAPATransport(t *testing.T) {
+	vc := usdl.NewVirtualChannel(1, 16)
+	svc := usdl.NewMAPAccessService(100, 1, 2, 64, vc, usdl.ChannelConfig{}, usdl.NewFrameCounter())
+	tr := cfdp.NewMAPATransport(svc, 64)
+
+	if _, err := tr.ReceivePDU(); !errors.Is(err, cfdp.ErrNoPDU) {
+		t.Errorf("empty channel: expected ErrNoPDU, got %v", err)
+	}
+	pdu := testPDU(t)
+	if err := tr.SendPDU(pdu); err != nil {
+		t.Fatal(err)
+	}
+	sdu, err := tr.ReceivePDU()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sdu) != 64 {
+		t.Errorf("SDU length %d", len(sdu))
+	}
+	p, err := cfdp.DecodePDU(sdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.(*cfdp.FileDataPDU).Data) != "file data" {
+		t.Errorf("got %+v", p)
+	}
+	if err := tr.SendPDU(make([]byte, 65)); !errors.Is(err, cfdp.ErrPDUExceedsSDU) {
+		t.Errorf("expected ErrPDUExceedsSDU, got %v", err)
+	}
+}
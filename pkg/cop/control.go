@@ -0,0 +1,43 @@
+package cop
+
+// Control command opcodes carried in the data field of Type-BC frames
+// per CCSDS 232.0-B-4 Section 4.1.3.3.
+const (
+	UnlockOpcode = 0x00 // Unlock: 1 octet
+	SetVROpcode  = 0x82 // Set V(R): 3 octets (0x82, 0x00, V(R))
+)
+
+// UnlockCommand returns the data field of an Unlock control command.
+// Send it in a TC frame with the bypass and control command flags set.
+func UnlockCommand() []byte {
+	return []byte{UnlockOpcode}
+}
+
+// SetVRCommand returns the data field of a Set V(R) control command
+// that sets FARM-1's V(R) to vr.
+func SetVRCommand(vr uint8) []byte {
+	return []byte{SetVROpcode, 0x00, vr}
+}
+
+// ControlCommandType identifies a decoded control command.
+type ControlCommandType int
+
+const (
+	ControlUnlock ControlCommandType = iota // Unlock
+	ControlSetVR                            // Set V(R)
+)
+
+// DecodeControlCommand decodes the data field of a Type-BC frame.
+// For Set V(R) the new V(R) value is returned as well.
+// Returns ErrInvalidControlCommand for any other content, including
+// trailing octets after a valid command.
+func DecodeControlCommand(data []byte) (ControlCommandType, uint8, error) {
+	switch {
+	case len(data) == 1 && data[0] == UnlockOpcode:
+		return ControlUnlock, 0, nil
+	case len(data) == 3 && data[0] == SetVROpcode && data[1] == 0x00:
+		return ControlSetVR, data[2], nil
+	default:
+		return 0, 0, ErrInvalidControlCommand
+	}
+}
@@ -0,0 +1,24 @@
+package cop_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cop"
+)
+
+func TestDecodeControlCommand(t *testing.T) {
+	cmd, _, err := cop.DecodeControlCommand(cop.UnlockCommand())
+	if err != nil || cmd != cop.ControlUnlock {
+		t.Errorf("Unlock: cmd=%v err=%v", cmd, err)
+	}
+
+	cmd, vr, err := cop.DecodeControlCommand(cop.SetVRCommand(0xAB))
+	if err != nil || cmd != cop.ControlSetVR || vr != 0xAB {
+		t.Errorf("Set V(R): cmd=%v vr=%d err=%v", cmd, vr, err)
+	}
+
+	if _, _, err := cop.DecodeControlCommand([]byte{0x82, 0x00, 0x01, 0x02}); !errors.Is(err, cop.ErrInvalidControlCommand) {
+		t.Errorf("trailing data: expected ErrInvalidControlCommand, got %v", err)
+	}
+}
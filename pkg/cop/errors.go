@@ -18,9 +18,48 @@ var (
 	// ErrFOPWindowFull indicates the FOP-1 send window is full.
 	ErrFOPWindowFull = errors.New("FOP-1: send window full, waiting for acknowledgment")
 
+	// ErrFOPNotActive indicates a Type-AD frame was offered while the AD service is not running.
+	ErrFOPNotActive = errors.New("FOP-1: AD service not active")
+
+	// ErrFOPInvalidDirective indicates a directive is not permitted in the current FOP-1 state.
+	ErrFOPInvalidDirective = errors.New("FOP-1: directive not permitted in current state")
+
+	// ErrFOPInvalidParameter indicates a directive parameter is out of range.
+	ErrFOPInvalidParameter = errors.New("FOP-1: invalid directive parameter")
+
+	// ErrFOPBDBusy indicates a Type-BD frame is already waiting for transmission.
+	ErrFOPBDBusy = errors.New("FOP-1: Type-BD frame already pending")
+
+	// ErrFOPInvalidNR indicates a CLCW reported N(R) outside [NN(R), V(S)].
+	ErrFOPInvalidNR = errors.New("FOP-1: CLCW N(R) outside acknowledgment window")
+
+	// ErrFOPInvalidCLCW indicates CLCW flags inconsistent with the FOP-1 state.
+	ErrFOPInvalidCLCW = errors.New("FOP-1: CLCW inconsistent with FOP state")
+
+	// ErrFOPLimitReached indicates retransmission was requested after the transmission limit.
+	ErrFOPLimitReached = errors.New("FOP-1: transmission limit reached")
+
+	// ErrFOPTimeout indicates T1 expired with the transmission limit reached.
+	ErrFOPTimeout = errors.New("FOP-1: T1 expired at transmission limit")
+
+	// ErrFOPSuspended indicates the AD service was suspended on T1 expiry.
+	ErrFOPSuspended = errors.New("FOP-1: AD service suspended")
+
 	// ErrFARMReject indicates FARM-1 rejected a frame (out of window).
 	ErrFARMReject = errors.New("FARM-1: frame rejected, sequence number outside window")
 
+	// ErrFARMWait indicates FARM-1 discarded a frame because it is in the Wait state.
+	ErrFARMWait = errors.New("FARM-1: wait state, receive buffer full")
+
+	// ErrFARMDuplicate indicates FARM-1 discarded a frame already accepted (negative window).
+	ErrFARMDuplicate = errors.New("FARM-1: frame discarded, sequence number already accepted")
+
+	// ErrFARMChannelMismatch indicates a frame addressed to another virtual channel or spacecraft.
+	ErrFARMChannelMismatch = errors.New("FARM-1: frame belongs to another virtual channel")
+
+	// ErrInvalidControlCommand indicates a Type-BC frame whose data field is not a valid Unlock or Set V(R).
+	ErrInvalidControlCommand = errors.New("invalid control command")
+
 	// ErrFARMLockout indicates FARM-1 is in lockout state.
 	ErrFARMLockout = errors.New("FARM-1: lockout state, requires unlock command")
 )
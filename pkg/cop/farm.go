@@ -1,73 +1,156 @@
 package cop
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/tcdl"
+)
 
 // FARMState represents the FARM-1 state machine state.
 type FARMState int
 
 const (
 	FARMOpen    FARMState = iota // S1: accepting frames in window
-	FARMWait                     // S2: wait state
+	FARMWait                     // S2: wait state (receive buffer full)
 	FARMLockout                  // S3: lockout (requires ground unlock)
 )
 
+// String returns the CCSDS state name.
+func (s FARMState) String() string {
+	switch s {
+	case FARMOpen:
+		return "Open"
+	case FARMWait:
+		return "Wait"
+	case FARMLockout:
+		return "Lockout"
+	default:
+		return "Unknown"
+	}
+}
+
+// FARMOption configures optional FARM-1 parameters.
+type FARMOption func(*FARM)
+
+// WithBufferCapacity limits the number of accepted Type-AD frames the
+// receiving buffer can hold. When the buffer is full, FARM-1 enters the
+// Wait state; ReleaseBuffer frees space and leaves it. Zero (the
+// default) means the buffer never fills.
+func WithBufferCapacity(n int) FARMOption {
+	return func(f *FARM) {
+		f.bufferCap = n
+	}
+}
+
+// WithSpacecraftID makes ProcessTCFrame also reject frames whose
+// Spacecraft ID differs from scid. By default any SCID is accepted.
+func WithSpacecraftID(scid uint16) FARMOption {
+	return func(f *FARM) {
+		f.scid = scid
+		f.checkSCID = true
+	}
+}
+
 // FARM implements the Frame Acceptance and Reporting Mechanism (FARM-1)
-// per CCSDS 232.1-B-2 Section 5.
+// per CCSDS 232.1-B-2 Section 6.
 //
 // FARM-1 runs on the spacecraft side. It validates incoming TC frame
-// sequence numbers and generates CLCW status reports for the return link.
+// sequence numbers, executes Unlock and Set V(R) control commands, and
+// generates CLCW status reports for the return link.
 type FARM struct {
 	mu           sync.Mutex
 	state        FARMState
 	vr           uint8 // V(R): next expected frame sequence number
 	farmBCounter uint8 // Type-B acceptance counter (2 bits, wraps at 4)
-	windowWidth  uint8 // W: positive sliding window width
+	windowWidth  uint8 // W: positive (and negative) sliding window width
 	vcid         uint8
+	scid         uint16
+	checkSCID    bool // reject frames of other spacecraft
 	lockout      bool
 	wait         bool
 	retransmit   bool
+	bufferCap    int // receiving buffer capacity in frames, 0 = unlimited
+	buffered     int // accepted frames not yet released
 }
 
 // NewFARM creates a new FARM-1 instance for the given VCID.
 // windowWidth is the positive sliding window size (typically 10).
-func NewFARM(vcid uint8, windowWidth uint8) *FARM {
-	return &FARM{
+func NewFARM(vcid uint8, windowWidth uint8, opts ...FARMOption) *FARM {
+	f := &FARM{
 		state:       FARMOpen,
 		vcid:        vcid,
 		windowWidth: windowWidth,
 	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
 // ProcessFrame validates an incoming TC frame per FARM-1 rules.
 // Returns whether the frame was accepted.
 //
-// Type-B (bypass) frames are always accepted.
-// Type-A (sequence-controlled) frames are checked against V(R):
-//   - N(S) == V(R): accepted, V(R) incremented
-//   - N(S) within window but != V(R): rejected, retransmit flag set
-//   - N(S) outside window: rejected, lockout entered
+// Type-BD (bypass data) frames are always accepted.
+// Type-AD (sequence-controlled) frames are checked against V(R):
+//   - N(S) == V(R): accepted, V(R) incremented (Wait entered if no buffer)
+//   - N(S) within positive window but != V(R): rejected, retransmit flag set
+//   - N(S) within negative window: discarded as a duplicate
+//   - N(S) outside both windows: rejected, lockout entered
+//
+// Control command frames carry their directive in the data field;
+// pass them to ProcessTCFrame or ProcessControlCommand instead. Here
+// they are rejected with ErrInvalidControlCommand.
 //
 // bypassFlag: 0=Type-A, 1=Type-B
 // controlCommandFlag: 0=data, 1=control command
 // frameSeqNum: N(S) from the frame header
 func (f *FARM) ProcessFrame(bypassFlag, controlCommandFlag uint8, frameSeqNum uint8) (bool, error) {
+	return f.process(bypassFlag, controlCommandFlag, frameSeqNum, nil)
+}
+
+// ProcessTCFrame validates a decoded TC Transfer Frame, executing
+// the control command in the data field of Type-BC frames. Frames of
+// another virtual channel, or of another spacecraft when
+// WithSpacecraftID is set, are rejected with ErrFARMChannelMismatch
+// and leave the FARM unchanged.
+func (f *FARM) ProcessTCFrame(frame *tcdl.TCTransferFrame) (bool, error) {
+	h := frame.Header
+	if h.VirtualChannelID != f.vcid || (f.checkSCID && h.SpacecraftID != f.scid) {
+		return false, ErrFARMChannelMismatch
+	}
+	return f.process(h.BypassFlag, h.ControlCommandFlag, h.FrameSequenceNum, frame.DataField)
+}
+
+// ProcessControlCommand executes the data field of a Type-BC frame.
+//   - Unlock: clears Lockout, Wait and Retransmit; enters Open
+//   - Set V(R): sets V(R) and clears Wait and Retransmit; ignored
+//     (other than counting) while in Lockout
+//
+// Both increment the FARM-B counter. Malformed data is discarded
+// with ErrInvalidControlCommand.
+func (f *FARM) ProcessControlCommand(data []byte) (bool, error) {
+	return f.process(1, 1, 0, data)
+}
+
+func (f *FARM) process(bypassFlag, controlCommandFlag, frameSeqNum uint8, data []byte) (bool, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	// Type-B frames: always accept
-	if bypassFlag == 1 {
-		if controlCommandFlag == 0 {
-			f.farmBCounter = (f.farmBCounter + 1) & 0x03
+	if controlCommandFlag == 1 {
+		// Control commands are only valid as Type-BC frames.
+		if bypassFlag != 1 {
+			return false, ErrInvalidControlCommand
 		}
-		return true, nil
+		return f.processControlCommand(data)
 	}
 
-	// Control commands (Type-A with ControlCommand=1)
-	if controlCommandFlag == 1 {
-		return f.processControlCommand(frameSeqNum), nil
+	// Type-BD frames: always accept
+	if bypassFlag == 1 {
+		f.farmBCounter = (f.farmBCounter + 1) & 0x03
+		return true, nil
 	}
 
-	// Type-A data frame
+	// Type-AD data frame
 	if f.state == FARMLockout {
 		return false, ErrFARMLockout
 	}
@@ -75,9 +158,22 @@ func (f *FARM) ProcessFrame(bypassFlag, controlCommandFlag uint8, frameSeqNum ui
 	ns := frameSeqNum
 
 	if ns == f.vr {
+		if f.state == FARMWait {
+			return false, ErrFARMWait
+		}
+		if f.bufferCap > 0 && f.buffered >= f.bufferCap {
+			// No buffer space: discard and ask for retransmission later
+			f.state = FARMWait
+			f.wait = true
+			f.retransmit = true
+			return false, ErrFARMWait
+		}
 		// In sequence: accept and advance
 		f.vr++
 		f.retransmit = false
+		if f.bufferCap > 0 {
+			f.buffered++
+		}
 		return true, nil
 	}
 
@@ -89,6 +185,11 @@ func (f *FARM) ProcessFrame(bypassFlag, controlCommandFlag uint8, frameSeqNum ui
 		return false, ErrFARMReject
 	}
 
+	// Check if within negative window: V(R) - W <= N(S) < V(R) (mod 256)
+	if back := (f.vr - ns) & 0xFF; back > 0 && back <= uint8(f.windowWidth) {
+		return false, ErrFARMDuplicate
+	}
+
 	// Outside window: lockout
 	f.state = FARMLockout
 	f.lockout = true
@@ -96,18 +197,51 @@ func (f *FARM) ProcessFrame(bypassFlag, controlCommandFlag uint8, frameSeqNum ui
 	return false, ErrFARMLockout
 }
 
-// processControlCommand handles unlock and set-V(R) directives.
-// Per CCSDS 232.1-B-2, control commands use specific reserved patterns.
-// Unlock: clears lockout. Set V(R): sets V(R) to the frame sequence number.
-func (f *FARM) processControlCommand(frameSeqNum uint8) bool {
-	// Unlock directive (BC frame with ControlCommand=1, Bypass=0)
-	// Clears lockout and resets state to Open
-	f.state = FARMOpen
-	f.lockout = false
+// processControlCommand executes an Unlock or Set V(R) directive
+// per CCSDS 232.1-B-2 Section 6.3 (events E7 and E8).
+func (f *FARM) processControlCommand(data []byte) (bool, error) {
+	cmd, vr, err := DecodeControlCommand(data)
+	if err != nil {
+		return false, err
+	}
+
+	f.farmBCounter = (f.farmBCounter + 1) & 0x03
+
+	switch cmd {
+	case ControlUnlock:
+		f.state = FARMOpen
+		f.lockout = false
+		f.wait = false
+		f.retransmit = false
+	case ControlSetVR:
+		if f.state == FARMLockout {
+			return true, nil
+		}
+		f.state = FARMOpen
+		f.wait = false
+		f.retransmit = false
+		f.vr = vr
+	}
+	return true, nil
+}
+
+// ReleaseBuffer signals that the higher layer has consumed one accepted
+// frame from the receiving buffer. If FARM-1 is in Wait, it returns to
+// Open (buffer release signal, event E10).
+func (f *FARM) ReleaseBuffer() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.buffered > 0 {
+		f.buffered--
+	}
+	if !f.wait {
+		return
+	}
 	f.wait = false
-	f.retransmit = false
-	f.vr = frameSeqNum
-	return true
+	if f.state == FARMWait {
+		f.state = FARMOpen
+	}
 }
 
 // GenerateCLCW returns a CLCW reflecting the current FARM-1 state.
@@ -141,3 +275,10 @@ func (f *FARM) VR() uint8 {
 	defer f.mu.Unlock()
 	return f.vr
 }
+
+// Buffered returns the number of accepted frames not yet released.
+func (f *FARM) Buffered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buffered
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/ravisuhag/astro/pkg/cop"
+	"github.com/ravisuhag/astro/pkg/tcdl"
 )
 
 func TestFARM_TypeA_InSequence(t *testing.T) {
@@ -89,16 +90,208 @@ func TestFARM_ControlCommand_Unlock(t *testing.T) {
 	_, _ = farm.ProcessFrame(0, 0, 0)
 	_, _ = farm.ProcessFrame(0, 0, 100) // lockout
 
-	accepted, _ := farm.ProcessFrame(0, 1, 5)
-	if !accepted {
-		t.Error("control command should be accepted")
+	accepted, err := farm.ProcessControlCommand(cop.UnlockCommand())
+	if err != nil || !accepted {
+		t.Fatalf("unlock: accepted=%v err=%v", accepted, err)
 	}
 	if farm.State() != cop.FARMOpen {
 		t.Errorf("state = %d, want FARMOpen", farm.State())
 	}
+	if farm.VR() != 1 {
+		t.Errorf("Unlock must keep V(R): got %d, want 1", farm.VR())
+	}
+
+	accepted, err = farm.ProcessControlCommand(cop.SetVRCommand(5))
+	if err != nil || !accepted {
+		t.Fatalf("set V(R): accepted=%v err=%v", accepted, err)
+	}
 	if farm.VR() != 5 {
 		t.Errorf("V(R) = %d, want 5", farm.VR())
 	}
+
+	clcw := farm.GenerateCLCW()
+	if clcw.LockoutFlag || clcw.FARMBCounter != 2 {
+		t.Errorf("CLCW lockout=%v FARMB=%d, want false and 2", clcw.LockoutFlag, clcw.FARMBCounter)
+	}
+}
+
+func TestFARM_ControlCommand_SetVRIgnoredInLockout(t *testing.T) {
+	farm := cop.NewFARM(1, 10)
+	_, _ = farm.ProcessFrame(0, 0, 100) // lockout
+
+	accepted, err := farm.ProcessControlCommand(cop.SetVRCommand(100))
+	if err != nil || !accepted {
+		t.Fatalf("set V(R): accepted=%v err=%v", accepted, err)
+	}
+	if farm.State() != cop.FARMLockout || farm.VR() != 0 {
+		t.Errorf("state=%v V(R)=%d, want Lockout and 0", farm.State(), farm.VR())
+	}
+	if farm.GenerateCLCW().FARMBCounter != 1 {
+		t.Error("FARM-B counter should count the BC frame")
+	}
+}
+
+func TestFARM_ControlCommand_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"unknown opcode", []byte{0x01}},
+		{"unlock with trailing octet", []byte{0x00, 0x00}},
+		{"set V(R) too short", []byte{0x82, 0x00}},
+		{"set V(R) bad second octet", []byte{0x82, 0x01, 0x05}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			farm := cop.NewFARM(1, 10)
+			accepted, err := farm.ProcessControlCommand(tt.data)
+			if accepted || !errors.Is(err, cop.ErrInvalidControlCommand) {
+				t.Errorf("accepted=%v err=%v, want ErrInvalidControlCommand", accepted, err)
+			}
+			if farm.GenerateCLCW().FARMBCounter != 0 {
+				t.Error("malformed BC frame must not be counted")
+			}
+		})
+	}
+}
+
+func TestFARM_ControlCommand_RequiresBypass(t *testing.T) {
+	farm := cop.NewFARM(1, 10)
+	accepted, err := farm.ProcessFrame(0, 1, 5)
+	if accepted || !errors.Is(err, cop.ErrInvalidControlCommand) {
+		t.Errorf("accepted=%v err=%v, want ErrInvalidControlCommand", accepted, err)
+	}
+}
+
+func TestFARM_ProcessTCFrame(t *testing.T) {
+	farm := cop.NewFARM(1, 10)
+
+	ad, err := tcdl.NewTCTransferFrame(42, 1, []byte{0xAA}, tcdl.WithSequenceNumber(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted, err := farm.ProcessTCFrame(ad); err != nil || !accepted {
+		t.Fatalf("AD frame: accepted=%v err=%v", accepted, err)
+	}
+
+	bc, err := tcdl.NewTCTransferFrame(42, 1, cop.SetVRCommand(40),
+		tcdl.WithBypass(), tcdl.WithControlCommand())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := bc.Encode()
+	decoded, err := tcdl.DecodeTCTransferFrame(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted, err := farm.ProcessTCFrame(decoded); err != nil || !accepted {
+		t.Fatalf("BC frame: accepted=%v err=%v", accepted, err)
+	}
+	if farm.VR() != 40 {
+		t.Errorf("V(R) = %d, want 40", farm.VR())
+	}
+}
+
+func TestFARM_ProcessTCFrame_OtherChannel(t *testing.T) {
+	farm := cop.NewFARM(1, 10, cop.WithSpacecraftID(42))
+
+	// Frames of VC 2 or spacecraft 43 must not touch VC 1's state: an
+	// out-of-window N(S) here would otherwise lock VC 1 out.
+	for _, tt := range []struct {
+		name   string
+		scid   uint16
+		vcid   uint8
+		bypass bool
+	}{
+		{"AD on VC 2", 42, 2, false},
+		{"BD on VC 2", 42, 2, true},
+		{"AD from spacecraft 43", 43, 1, false},
+	} {
+		opts := []tcdl.FrameOption{tcdl.WithSequenceNumber(100)}
+		if tt.bypass {
+			opts = append(opts, tcdl.WithBypass())
+		}
+		frame, err := tcdl.NewTCTransferFrame(tt.scid, tt.vcid, []byte{0xAA}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accepted, err := farm.ProcessTCFrame(frame); accepted || !errors.Is(err, cop.ErrFARMChannelMismatch) {
+			t.Errorf("%s: accepted=%v err=%v, want ErrFARMChannelMismatch", tt.name, accepted, err)
+		}
+	}
+	clcw := farm.GenerateCLCW()
+	if farm.State() != cop.FARMOpen || farm.VR() != 0 || clcw.LockoutFlag || clcw.FARMBCounter != 0 {
+		t.Errorf("state = %v, V(R) = %d, CLCW = %+v", farm.State(), farm.VR(), clcw)
+	}
+
+	// Without WithSpacecraftID any spacecraft is accepted.
+	open := cop.NewFARM(1, 10)
+	frame, _ := tcdl.NewTCTransferFrame(43, 1, []byte{0xAA}, tcdl.WithSequenceNumber(0))
+	if accepted, err := open.ProcessTCFrame(frame); err != nil || !accepted {
+		t.Errorf("any SCID: accepted=%v err=%v", accepted, err)
+	}
+}
+
+func TestFARM_NegativeWindow_Duplicate(t *testing.T) {
+	farm := cop.NewFARM(1, 10)
+	for i := range 3 {
+		_, _ = farm.ProcessFrame(0, 0, uint8(i))
+	}
+
+	accepted, err := farm.ProcessFrame(0, 0, 1)
+	if accepted || !errors.Is(err, cop.ErrFARMDuplicate) {
+		t.Errorf("accepted=%v err=%v, want ErrFARMDuplicate", accepted, err)
+	}
+	if farm.State() != cop.FARMOpen {
+		t.Errorf("duplicate must not cause lockout, state = %v", farm.State())
+	}
+}
+
+func TestFARM_Wait_BufferFull(t *testing.T) {
+	farm := cop.NewFARM(1, 10, cop.WithBufferCapacity(2))
+	_, _ = farm.ProcessFrame(0, 0, 0)
+	_, _ = farm.ProcessFrame(0, 0, 1)
+
+	accepted, err := farm.ProcessFrame(0, 0, 2)
+	if accepted || !errors.Is(err, cop.ErrFARMWait) {
+		t.Fatalf("accepted=%v err=%v, want ErrFARMWait", accepted, err)
+	}
+	if farm.State() != cop.FARMWait {
+		t.Errorf("state = %v, want Wait", farm.State())
+	}
+	clcw := farm.GenerateCLCW()
+	if !clcw.WaitFlag || !clcw.RetransmitFlag || clcw.ReportValue != 2 {
+		t.Errorf("CLCW wait=%v retransmit=%v V(R)=%d", clcw.WaitFlag, clcw.RetransmitFlag, clcw.ReportValue)
+	}
+
+	farm.ReleaseBuffer()
+	if farm.State() != cop.FARMOpen || farm.GenerateCLCW().WaitFlag {
+		t.Errorf("state = %v after release, want Open with Wait cleared", farm.State())
+	}
+
+	accepted, err = farm.ProcessFrame(0, 0, 2)
+	if err != nil || !accepted {
+		t.Fatalf("retransmitted frame: accepted=%v err=%v", accepted, err)
+	}
+	if farm.GenerateCLCW().RetransmitFlag {
+		t.Error("Retransmit flag should clear on acceptance")
+	}
+	if farm.Buffered() != 2 {
+		t.Errorf("Buffered = %d, want 2", farm.Buffered())
+	}
+}
+
+func TestFARM_Wait_UnlockClears(t *testing.T) {
+	farm := cop.NewFARM(1, 10, cop.WithBufferCapacity(1))
+	_, _ = farm.ProcessFrame(0, 0, 0)
+	_, _ = farm.ProcessFrame(0, 0, 1) // wait
+
+	_, _ = farm.ProcessControlCommand(cop.UnlockCommand())
+	clcw := farm.GenerateCLCW()
+	if farm.State() != cop.FARMOpen || clcw.WaitFlag || clcw.RetransmitFlag {
+		t.Errorf("state=%v wait=%v retransmit=%v after unlock", farm.State(), clcw.WaitFlag, clcw.RetransmitFlag)
+	}
 }
 
 func TestFARM_GenerateCLCW(t *testing.T) {
@@ -1,89 +1,410 @@
 package cop
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
-// FOPState represents the FOP-1 state machine state.
+// FOPState represents the FOP-1 state machine state
+// per CCSDS 232.1-B-2 Section 5.1.5.
 type FOPState int
 
 const (
-	FOPActive  FOPState = iota // S1: active, accepting frames
-	FOPInitial                  // S6: initial (not started)
+	FOPActive                FOPState = iota // S1: active, accepting frames
+	FOPRetransmitWithoutWait                 // S2: retransmitting, FARM not in Wait
+	FOPRetransmitWithWait                    // S3: retransmission held while FARM is in Wait
+	FOPInitializingWithoutBC                 // S4: Initiate AD with CLCW check in progress
+	FOPInitializingWithBC                    // S5: Initiate AD with Unlock or Set V(R) in progress
+	FOPInitial                               // S6: initial (not started)
+)
+
+// String returns the CCSDS state name.
+func (s FOPState) String() string {
+	switch s {
+	case FOPActive:
+		return "Active"
+	case FOPRetransmitWithoutWait:
+		return "Retransmit without Wait"
+	case FOPRetransmitWithWait:
+		return "Retransmit with Wait"
+	case FOPInitializingWithoutBC:
+		return "Initializing without BC Frame"
+	case FOPInitializingWithBC:
+		return "Initializing with BC Frame"
+	case FOPInitial:
+		return "Initial"
+	default:
+		return "Unknown"
+	}
+}
+
+// AlertCode identifies the reason for an Alert raised by FOP-1
+// per CCSDS 232.1-B-2 Section 5.2.4.
+type AlertCode int
+
+const (
+	AlertLimit   AlertCode = iota + 1 // retransmission requested with the transmission limit reached
+	AlertT1                           // T1 expired with the transmission limit reached
+	AlertLockout                      // CLCW reported FARM-1 Lockout
+	AlertNNR                          // CLCW N(R) outside [NN(R), V(S)]
+	AlertCLCW                         // CLCW flags inconsistent with the FOP-1 state
+	AlertTerm                         // Terminate AD Service directive
+)
+
+// String returns the CCSDS alert name.
+func (a AlertCode) String() string {
+	switch a {
+	case AlertLimit:
+		return "limit"
+	case AlertT1:
+		return "T1"
+	case AlertLockout:
+		return "lockout"
+	case AlertNNR:
+		return "NN(R)"
+	case AlertCLCW:
+		return "CLCW"
+	case AlertTerm:
+		return "term"
+	default:
+		return "unknown"
+	}
+}
+
+// TimeoutType selects the FOP-1 reaction when T1 expires with the
+// transmission limit reached.
+type TimeoutType int
+
+const (
+	TimeoutAlert   TimeoutType = iota // Alert(T1), AD service terminated
+	TimeoutSuspend                    // AD service suspended until Resume
+)
+
+// NotificationKind identifies the kind of asynchronous FOP-1 notification.
+type NotificationKind int
+
+const (
+	NotifyAlert     NotificationKind = iota // AD service aborted, see Notification.Alert
+	NotifySuspend                           // AD service suspended, see Notification.SuspendState
+	NotifyInitiated                         // pending Initiate AD directive completed
+)
+
+// Notification is delivered to the handler installed with
+// WithNotifyHandler. The handler is called without FOP-1's lock
+// held, so it may call back into the FOP.
+type Notification struct {
+	Kind         NotificationKind
+	Alert        AlertCode // set when Kind is NotifyAlert
+	SuspendState FOPState  // state restored by Resume; set when Kind is NotifySuspend
+}
+
+// Clock supplies the current time to the T1 timer.
+// Inject a fake clock with WithClock for deterministic tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Default FOP-1 management parameters.
+const (
+	DefaultT1                = 5 * time.Second
+	DefaultTransmissionLimit = 3
 )
 
+// FOPOption configures optional FOP-1 parameters.
+type FOPOption func(*FOP)
+
+// WithClock sets the clock used by the T1 timer.
+func WithClock(c Clock) FOPOption {
+	return func(f *FOP) {
+		f.clock = c
+	}
+}
+
+// WithT1 sets the initial value of the T1 retransmission timer.
+func WithT1(d time.Duration) FOPOption {
+	return func(f *FOP) {
+		f.t1 = d
+	}
+}
+
+// WithTransmissionLimit sets the maximum number of transmissions of
+// each Type-AD or Type-BC frame.
+func WithTransmissionLimit(n int) FOPOption {
+	return func(f *FOP) {
+		f.limit = n
+	}
+}
+
+// WithTimeoutType sets the reaction to T1 expiry at the transmission limit.
+func WithTimeoutType(tt TimeoutType) FOPOption {
+	return func(f *FOP) {
+		f.timeoutType = tt
+	}
+}
+
+// WithNotifyHandler installs a callback for Alert, Suspend and
+// initiation-complete notifications.
+func WithNotifyHandler(h func(Notification)) FOPOption {
+	return func(f *FOP) {
+		f.handler = h
+	}
+}
+
 // SentFrame tracks a transmitted Type-A frame awaiting acknowledgment.
 type SentFrame struct {
 	SequenceNum uint8
 	Data        []byte // encoded frame bytes for retransmission
+	pending     bool   // not yet handed out since queued or marked for retransmission
 }
 
 // FOP implements the Flight Operations Procedure (FOP-1)
-// per CCSDS 232.1-B-2 Section 4.
+// per CCSDS 232.1-B-2 Section 5.
 //
-// FOP-1 runs on the ground side. It manages Type-A (sequence-controlled)
-// frame transmission with sliding window acknowledgment via CLCW.
+// FOP-1 runs on the ground side. It manages Type-AD (sequence-controlled)
+// frame transmission with sliding window acknowledgment via CLCW,
+// retransmits on CLCW request or T1 expiry up to the transmission limit,
+// and carries Type-BD and Type-BC frames alongside.
 //
 // Usage:
 //  1. Create with NewFOP
-//  2. Call Initialize() to start
-//  3. Call TransmitFrame() to queue Type-A frames
+//  2. Call Initialize() or an Initiate AD directive to start
+//  3. Call TransmitFrame() to queue Type-AD frames
 //  4. Call GetNextFrame() to get the next frame to send
 //  5. Call ProcessCLCW() when a CLCW arrives on the TM return link
+//  6. Call CheckTimer() periodically to service T1
 type FOP struct {
 	mu           sync.Mutex
 	state        FOPState
-	vs           uint8        // V(S): next sequence number to assign
-	nnr          uint8        // N(N)R: last acknowledged sequence number from CLCW
-	sentQueue    []SentFrame  // frames sent, awaiting acknowledgment
-	waitQueue    [][]byte     // encoded frames waiting to be transmitted
-	windowWidth  uint8        // FW: sliding window width
+	vs           uint8       // V(S): next sequence number to assign
+	nnr          uint8       // NN(R): last acknowledged sequence number from CLCW
+	sentQueue    []SentFrame // frames sent, awaiting acknowledgment
+	windowWidth  uint8       // K: FOP sliding window width
 	scid         uint16
 	vcid         uint8
+	t1           time.Duration // T1_Initial
+	limit        int           // Transmission_Limit
+	timeoutType  TimeoutType   // Timeout_Type
+	txCount      int           // Transmission_Count
+	clock        Clock
+	timerRunning bool
+	timerStart   time.Time
+	suspended    bool     // Suspend_State != 0
+	suspendState FOPState // state to resume into
+	bcFrame      []byte   // Type-BC frame of a pending Initiate AD directive
+	bcPending    bool     // bcFrame awaiting (re)transmission
+	bdFrame      []byte   // Type-BD frame awaiting transmission
+	handler      func(Notification)
+	notes        []Notification // notifications awaiting dispatch
 }
 
-// NewFOP creates a new FOP-1 instance.
-// windowWidth is the sliding window size (must match FARM's window width).
-func NewFOP(scid uint16, vcid uint8, windowWidth uint8) *FOP {
-	return &FOP{
+// NewFOP creates a new FOP-1 instance in the Initial state.
+// windowWidth is the sliding window size (must not exceed FARM's window width).
+func NewFOP(scid uint16, vcid uint8, windowWidth uint8, opts ...FOPOption) *FOP {
+	f := &FOP{
 		state:       FOPInitial,
 		scid:        scid,
 		vcid:        vcid,
 		windowWidth: windowWidth,
+		t1:          DefaultT1,
+		limit:       DefaultTransmissionLimit,
+		clock:       systemClock{},
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+	return f
 }
 
 // Initialize starts FOP-1, setting it to Active state.
-// Sets V(S) to the given initial sequence number.
+// Sets V(S) to the given initial sequence number. It is shorthand for
+// SetVS followed by InitiateAD and may be called in any state.
 func (f *FOP) Initialize(initialVS uint8) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.vs = initialVS
 	f.nnr = initialVS
-	f.sentQueue = nil
-	f.waitQueue = nil
+	f.purge()
+	f.suspended = false
+	f.state = FOPActive
+}
+
+// SetVS sets V(S) and NN(R) (Set V(S) directive). It is only accepted
+// in the Initial state while the AD service is not suspended.
+func (f *FOP) SetVS(vs uint8) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial || f.suspended {
+		return ErrFOPInvalidDirective
+	}
+	f.vs = vs
+	f.nnr = vs
+	return nil
+}
+
+// InitiateAD starts the AD service without CLCW check and enters
+// the Active state immediately.
+func (f *FOP) InitiateAD() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial {
+		return ErrFOPInvalidDirective
+	}
+	f.initialize()
 	f.state = FOPActive
+	return nil
+}
+
+// InitiateADWithCLCWCheck starts the AD service with CLCW check. FOP-1
+// waits in Initializing without BC Frame until a CLCW reports
+// N(R) = V(S) with no Lockout, Wait or Retransmit flags, then becomes
+// Active. If T1 expires first, the directive fails with Alert(T1).
+func (f *FOP) InitiateADWithCLCWCheck() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial {
+		return ErrFOPInvalidDirective
+	}
+	f.initialize()
+	f.restartTimer()
+	f.state = FOPInitializingWithoutBC
+	return nil
+}
+
+// InitiateADWithUnlock starts the AD service by transmitting an Unlock
+// Type-BC frame. bcFrame is the encoded TC frame carrying UnlockCommand.
+// FOP-1 becomes Active once a CLCW confirms the unlock; the BC frame is
+// retransmitted on T1 expiry up to the transmission limit.
+func (f *FOP) InitiateADWithUnlock(bcFrame []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial {
+		return ErrFOPInvalidDirective
+	}
+	f.initialize()
+	f.startBC(bcFrame)
+	return nil
+}
+
+// InitiateADWithSetVR starts the AD service by transmitting a Set V(R)
+// Type-BC frame. V(S) and NN(R) are set to vr; bcFrame is the encoded TC
+// frame carrying SetVRCommand(vr).
+func (f *FOP) InitiateADWithSetVR(vr uint8, bcFrame []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial {
+		return ErrFOPInvalidDirective
+	}
+	f.initialize()
+	f.vs = vr
+	f.nnr = vr
+	f.startBC(bcFrame)
+	return nil
+}
+
+// Terminate terminates the AD service. Outside the Initial state
+// this raises Alert(term) and purges all queued Type-A frames.
+func (f *FOP) Terminate() {
+	f.mu.Lock()
+	defer f.dispatch()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial {
+		f.alert(AlertTerm)
+	}
+	f.suspended = false
+}
+
+// Resume resumes a suspended AD service, returning FOP-1 to the state
+// it was in when T1 expired and restarting the timer.
+func (f *FOP) Resume() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != FOPInitial || !f.suspended {
+		return ErrFOPInvalidDirective
+	}
+	f.state = f.suspendState
+	f.suspended = false
+	f.restartTimer()
+	return nil
+}
+
+// SetSlidingWindow sets the FOP sliding window width K.
+func (f *FOP) SetSlidingWindow(k uint8) error {
+	if k == 0 {
+		return ErrFOPInvalidParameter
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.windowWidth = k
+	return nil
 }
 
-// TransmitFrame queues an encoded Type-A frame for transmission.
+// SetT1 sets the initial value of the T1 retransmission timer.
+func (f *FOP) SetT1(d time.Duration) error {
+	if d <= 0 {
+		return ErrFOPInvalidParameter
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.t1 = d
+	return nil
+}
+
+// SetTransmissionLimit sets the maximum number of transmissions of
+// each Type-AD or Type-BC frame.
+func (f *FOP) SetTransmissionLimit(n int) error {
+	if n < 1 {
+		return ErrFOPInvalidParameter
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.limit = n
+	return nil
+}
+
+// SetTimeoutType sets the reaction to T1 expiry at the transmission limit.
+func (f *FOP) SetTimeoutType(tt TimeoutType) error {
+	if tt != TimeoutAlert && tt != TimeoutSuspend {
+		return ErrFOPInvalidParameter
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.timeoutType = tt
+	return nil
+}
+
+// TransmitFrame queues an encoded Type-AD frame for transmission.
 // The frame will be assigned the next sequence number V(S).
-// Returns ErrFOPWindowFull if the sliding window is exhausted.
+// Returns ErrFOPNotActive unless the AD service is running and
+// ErrFOPWindowFull if the sliding window is exhausted.
 func (f *FOP) TransmitFrame(encodedFrame []byte) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if f.state != FOPActive {
-		return ErrFOPLockout
+	switch f.state {
+	case FOPActive, FOPRetransmitWithoutWait, FOPRetransmitWithWait:
+	default:
+		return ErrFOPNotActive
 	}
 
-	// Check if window is full: V(S) - N(N)R >= FW
+	// Check if window is full: V(S) - NN(R) >= K
 	outstanding := (f.vs - f.nnr) & 0xFF
 	if outstanding >= f.windowWidth {
 		return ErrFOPWindowFull
 	}
 
+	if len(f.sentQueue) == 0 {
+		f.txCount = 1
+	}
+
 	// Assign sequence number and queue
 	sf := SentFrame{
 		SequenceNum: f.vs,
 		Data:        encodedFrame,
+		pending:     true,
 	}
 	f.sentQueue = append(f.sentQueue, sf)
 	f.vs++
@@ -91,58 +412,214 @@ func (f *FOP) TransmitFrame(encodedFrame []byte) error {
 	return nil
 }
 
-// GetNextFrame returns the next frame to transmit.
-// First serves new frames from the wait queue, then retransmissions
-// if the retransmit flag was set by ProcessCLCW.
+// TransmitBDFrame queues an encoded Type-BD frame. Type-BD frames
+// bypass the sliding window and are accepted in every state.
+// Returns ErrFOPBDBusy if the previous BD frame has not been sent yet.
+func (f *FOP) TransmitBDFrame(encodedFrame []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.bdFrame != nil {
+		return ErrFOPBDBusy
+	}
+	f.bdFrame = encodedFrame
+	return nil
+}
+
+// GetNextFrame returns the next frame to transmit and its N(S).
+// A pending Type-BC frame is served first, then a Type-BD frame, then
+// Type-AD frames in sequence order: retransmissions before new frames.
+// Type-AD frames are held while FARM-1 reports Wait. Handing out a
+// Type-AD or Type-BC frame restarts T1. The sequence number is 0 for
+// Type-B frames.
 func (f *FOP) GetNextFrame() ([]byte, uint8, bool) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if len(f.waitQueue) > 0 {
-		data := f.waitQueue[0]
-		f.waitQueue = f.waitQueue[1:]
+	if f.bcPending {
+		f.bcPending = false
+		f.restartTimer()
+		return f.bcFrame, 0, true
+	}
+
+	if f.bdFrame != nil {
+		data := f.bdFrame
+		f.bdFrame = nil
 		return data, 0, true
 	}
 
+	if f.state != FOPActive && f.state != FOPRetransmitWithoutWait {
+		return nil, 0, false
+	}
+	for i := range f.sentQueue {
+		if f.sentQueue[i].pending {
+			f.sentQueue[i].pending = false
+			f.restartTimer()
+			return f.sentQueue[i].Data, f.sentQueue[i].SequenceNum, true
+		}
+	}
+
 	return nil, 0, false
 }
 
-// ProcessCLCW processes a CLCW received on the TM return link.
-// Acknowledges frames, detects lockout, and triggers retransmission.
+// ProcessCLCW processes a CLCW received on the TM return link
+// per the FOP-1 state table (CCSDS 232.1-B-2 Table 5-1).
+// Acknowledges frames, detects lockout, triggers retransmission and
+// completes pending Initiate AD directives. If the CLCW raises an
+// Alert, FOP-1 enters the Initial state and the matching error
+// (ErrFOPLockout, ErrFOPInvalidNR, ErrFOPInvalidCLCW or
+// ErrFOPLimitReached) is returned.
 func (f *FOP) ProcessCLCW(clcw *CLCW) error {
 	f.mu.Lock()
+	defer f.dispatch()
 	defer f.mu.Unlock()
 
+	nr := clcw.ReportValue
+	clean := nr == f.vs && !clcw.RetransmitFlag && !clcw.WaitFlag
+
+	switch f.state {
+	case FOPInitial:
+		return nil
+	case FOPInitializingWithBC:
+		// Lockout is expected until the Unlock or Set V(R) lands.
+		if clean && !clcw.LockoutFlag {
+			f.bcFrame = nil
+			f.bcPending = false
+			f.completeInitiation()
+		}
+		return nil
+	}
+
 	if clcw.LockoutFlag {
-		f.state = FOPInitial
-		return ErrFOPLockout
+		return f.alert(AlertLockout)
 	}
 
-	// Acknowledge frames: remove all sent frames with seq < V(R)
-	vr := clcw.ReportValue
-	f.nnr = vr
+	if f.state == FOPInitializingWithoutBC {
+		if clean {
+			f.completeInitiation()
+		}
+		return nil
+	}
 
-	var remaining []SentFrame
-	for _, sf := range f.sentQueue {
-		// Frame is acknowledged if its seq num is before V(R)
-		diff := (vr - sf.SequenceNum) & 0xFF
-		if diff == 0 || diff > 128 {
-			// Not yet acknowledged (seq >= V(R) in modular arithmetic)
-			remaining = append(remaining, sf)
+	// N(R) must lie within [NN(R), V(S)].
+	if (nr-f.nnr)&0xFF > (f.vs-f.nnr)&0xFF {
+		return f.alert(AlertNNR)
+	}
+	progressed := nr != f.nnr
+	if progressed {
+		f.removeAcknowledged(nr)
+	}
+
+	if nr == f.vs {
+		// All outstanding frames acknowledged.
+		if clcw.RetransmitFlag || clcw.WaitFlag {
+			return f.alert(AlertCLCW)
 		}
+		f.timerRunning = false
+		f.state = FOPActive
+		return nil
 	}
-	f.sentQueue = remaining
 
-	// If retransmit flag is set, re-queue unacknowledged frames
-	if clcw.RetransmitFlag && len(f.sentQueue) > 0 {
-		for _, sf := range f.sentQueue {
-			f.waitQueue = append(f.waitQueue, sf.Data)
+	if !clcw.RetransmitFlag {
+		if clcw.WaitFlag {
+			return f.alert(AlertCLCW)
 		}
+		if progressed {
+			f.restartTimer()
+			f.state = FOPActive
+		}
+		return nil
 	}
 
+	if f.limit == 1 {
+		return f.alert(AlertLimit)
+	}
+
+	if progressed {
+		if clcw.WaitFlag {
+			f.restartTimer()
+			f.state = FOPRetransmitWithWait
+		} else {
+			f.initiateRetransmission()
+			f.state = FOPRetransmitWithoutWait
+		}
+		return nil
+	}
+
+	switch f.state {
+	case FOPActive:
+		if f.txCount >= f.limit {
+			return f.alert(AlertLimit)
+		}
+		if clcw.WaitFlag {
+			f.state = FOPRetransmitWithWait
+		} else {
+			f.initiateRetransmission()
+			f.state = FOPRetransmitWithoutWait
+		}
+	case FOPRetransmitWithoutWait:
+		if clcw.WaitFlag {
+			f.state = FOPRetransmitWithWait
+		}
+	case FOPRetransmitWithWait:
+		if !clcw.WaitFlag {
+			if f.txCount < f.limit {
+				f.initiateRetransmission()
+			}
+			f.state = FOPRetransmitWithoutWait
+		}
+	}
 	return nil
 }
 
+// CheckTimer services the T1 timer and should be called periodically.
+// On expiry below the transmission limit, outstanding Type-AD frames
+// (or the pending Type-BC frame) are queued for retransmission. At the
+// limit, FOP-1 raises Alert(T1) and returns ErrFOPTimeout, or suspends
+// and returns ErrFOPSuspended when the timeout type is TimeoutSuspend.
+func (f *FOP) CheckTimer() error {
+	f.mu.Lock()
+	defer f.dispatch()
+	defer f.mu.Unlock()
+
+	if !f.timerRunning || f.clock.Now().Sub(f.timerStart) < f.t1 {
+		return nil
+	}
+	f.timerRunning = false
+
+	switch f.state {
+	case FOPActive, FOPRetransmitWithoutWait:
+		if f.txCount < f.limit {
+			f.initiateRetransmission()
+			f.state = FOPRetransmitWithoutWait
+			return nil
+		}
+	case FOPRetransmitWithWait:
+		if f.txCount < f.limit {
+			f.txCount++
+			f.restartTimer()
+			return nil
+		}
+	case FOPInitializingWithBC:
+		if f.txCount < f.limit {
+			f.txCount++
+			f.bcPending = true
+			f.restartTimer()
+			return nil
+		}
+		// Suspension is not available while initializing with a BC frame.
+		return f.alert(AlertT1)
+	}
+
+	if f.timeoutType == TimeoutSuspend {
+		f.suspended = true
+		f.suspendState = f.state
+		f.state = FOPInitial
+		f.notes = append(f.notes, Notification{Kind: NotifySuspend, SuspendState: f.suspendState})
+		return ErrFOPSuspended
+	}
+	return f.alert(AlertT1)
+}
+
 // State returns the current FOP-1 state.
 func (f *FOP) State() FOPState {
 	f.mu.Lock()
@@ -157,9 +634,134 @@ func (f *FOP) VS() uint8 {
 	return f.vs
 }
 
+// NNR returns NN(R), the last acknowledged sequence number.
+func (f *FOP) NNR() uint8 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nnr
+}
+
+// TransmissionCount returns the current Transmission_Count.
+func (f *FOP) TransmissionCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.txCount
+}
+
+// SuspendState returns the state a suspended AD service resumes into,
+// and whether the AD service is suspended.
+func (f *FOP) SuspendState() (FOPState, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.suspendState, f.suspended
+}
+
 // PendingCount returns the number of unacknowledged frames.
 func (f *FOP) PendingCount() int {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	return len(f.sentQueue)
 }
+
+// initialize clears all state for an Initiate AD directive.
+// V(S) is kept, so a preceding Set V(S) takes effect.
+func (f *FOP) initialize() {
+	f.purge()
+	f.nnr = f.vs
+	f.suspended = false
+	f.txCount = 1
+}
+
+// startBC queues a Type-BC frame and enters Initializing with BC Frame.
+func (f *FOP) startBC(bcFrame []byte) {
+	f.bcFrame = bcFrame
+	f.bcPending = true
+	f.restartTimer()
+	f.state = FOPInitializingWithBC
+}
+
+// completeInitiation finishes a pending Initiate AD directive.
+func (f *FOP) completeInitiation() {
+	f.timerRunning = false
+	f.state = FOPActive
+	f.notes = append(f.notes, Notification{Kind: NotifyInitiated})
+}
+
+// removeAcknowledged drops frames with N(S) in [NN(R), nr) from the
+// sent queue and advances NN(R) to nr.
+func (f *FOP) removeAcknowledged(nr uint8) {
+	acked := (nr - f.nnr) & 0xFF
+	var remaining []SentFrame
+	for _, sf := range f.sentQueue {
+		if (sf.SequenceNum-f.nnr)&0xFF >= acked {
+			remaining = append(remaining, sf)
+		}
+	}
+	f.sentQueue = remaining
+	f.nnr = nr
+	f.txCount = 1
+}
+
+// initiateRetransmission marks every unacknowledged frame for
+// retransmission and restarts T1.
+func (f *FOP) initiateRetransmission() {
+	f.txCount++
+	for i := range f.sentQueue {
+		f.sentQueue[i].pending = true
+	}
+	f.restartTimer()
+}
+
+func (f *FOP) restartTimer() {
+	f.timerRunning = true
+	f.timerStart = f.clock.Now()
+}
+
+// purge discards all queued Type-A frames and stops T1.
+func (f *FOP) purge() {
+	f.sentQueue = nil
+	f.bcFrame = nil
+	f.bcPending = false
+	f.timerRunning = false
+}
+
+// alert aborts the AD service, enters the Initial state and queues an
+// Alert notification. It returns the error matching the alert code.
+func (f *FOP) alert(code AlertCode) error {
+	f.purge()
+	f.suspended = false
+	f.state = FOPInitial
+	f.notes = append(f.notes, Notification{Kind: NotifyAlert, Alert: code})
+
+	switch code {
+	case AlertLockout:
+		return ErrFOPLockout
+	case AlertNNR:
+		return ErrFOPInvalidNR
+	case AlertCLCW:
+		return ErrFOPInvalidCLCW
+	case AlertLimit:
+		return ErrFOPLimitReached
+	case AlertT1:
+		return ErrFOPTimeout
+	default:
+		return nil
+	}
+}
+
+// dispatch delivers queued notifications to the handler. It must be
+// called without f.mu held.
+func (f *FOP) dispatch() {
+	f.mu.Lock()
+	notes := f.notes
+	f.notes = nil
+	handler := f.handler
+	f.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	for _, n := range notes {
+		handler(n)
+	}
+}
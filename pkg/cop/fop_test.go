@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ravisuhag/astro/pkg/cop"
 )
@@ -127,3 +128,347 @@ func TestFOP_FARM_Integration(t *testing.T) {
 		t.Errorf("PendingCount = %d, want 0", fop.PendingCount())
 	}
 }
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestFOP_GetNextFrame_SequenceOrder(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+	fop.Initialize(5)
+
+	_ = fop.TransmitFrame([]byte("a"))
+	_ = fop.TransmitFrame([]byte("b"))
+
+	for i, want := range []string{"a", "b"} {
+		data, seq, ok := fop.GetNextFrame()
+		if !ok || string(data) != want || seq != uint8(5+i) {
+			t.Errorf("frame %d = (%q, %d, %v), want (%q, %d, true)", i, data, seq, ok, want, 5+i)
+		}
+	}
+	if _, _, ok := fop.GetNextFrame(); ok {
+		t.Error("expected no more frames")
+	}
+}
+
+func TestFOP_T1_Retransmission(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	fop := cop.NewFOP(42, 1, 10, cop.WithClock(clk), cop.WithT1(time.Second))
+	fop.Initialize(0)
+
+	_ = fop.TransmitFrame([]byte("frame-0"))
+	fop.GetNextFrame()
+
+	clk.Advance(500 * time.Millisecond)
+	if err := fop.CheckTimer(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := fop.GetNextFrame(); ok {
+		t.Fatal("unexpected retransmission before T1 expiry")
+	}
+
+	clk.Advance(time.Second)
+	if err := fop.CheckTimer(); err != nil {
+		t.Fatal(err)
+	}
+	if fop.State() != cop.FOPRetransmitWithoutWait {
+		t.Errorf("state = %v, want Retransmit without Wait", fop.State())
+	}
+	data, _, ok := fop.GetNextFrame()
+	if !ok || string(data) != "frame-0" {
+		t.Errorf("retransmission = (%q, %v), want frame-0", data, ok)
+	}
+	if fop.TransmissionCount() != 2 {
+		t.Errorf("TransmissionCount = %d, want 2", fop.TransmissionCount())
+	}
+
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 1})
+	if fop.State() != cop.FOPActive || fop.PendingCount() != 0 {
+		t.Errorf("after ack: state=%v pending=%d", fop.State(), fop.PendingCount())
+	}
+}
+
+func TestFOP_T1_LimitAlert(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	var notes []cop.Notification
+	fop := cop.NewFOP(42, 1, 10,
+		cop.WithClock(clk),
+		cop.WithT1(time.Second),
+		cop.WithTransmissionLimit(2),
+		cop.WithNotifyHandler(func(n cop.Notification) { notes = append(notes, n) }),
+	)
+	fop.Initialize(0)
+	_ = fop.TransmitFrame([]byte("frame-0"))
+	fop.GetNextFrame()
+
+	clk.Advance(time.Second)
+	if err := fop.CheckTimer(); err != nil {
+		t.Fatal(err)
+	}
+	fop.GetNextFrame()
+
+	clk.Advance(time.Second)
+	if err := fop.CheckTimer(); !errors.Is(err, cop.ErrFOPTimeout) {
+		t.Fatalf("expected ErrFOPTimeout, got %v", err)
+	}
+	if fop.State() != cop.FOPInitial || fop.PendingCount() != 0 {
+		t.Errorf("state=%v pending=%d, want Initial with empty queue", fop.State(), fop.PendingCount())
+	}
+	if len(notes) != 1 || notes[0].Kind != cop.NotifyAlert || notes[0].Alert != cop.AlertT1 {
+		t.Errorf("notifications = %+v, want one Alert(T1)", notes)
+	}
+}
+
+func TestFOP_T1_SuspendResume(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	fop := cop.NewFOP(42, 1, 10,
+		cop.WithClock(clk),
+		cop.WithT1(time.Second),
+		cop.WithTransmissionLimit(1),
+		cop.WithTimeoutType(cop.TimeoutSuspend),
+	)
+	fop.Initialize(0)
+	_ = fop.TransmitFrame([]byte("frame-0"))
+	fop.GetNextFrame()
+
+	clk.Advance(time.Second)
+	if err := fop.CheckTimer(); !errors.Is(err, cop.ErrFOPSuspended) {
+		t.Fatalf("expected ErrFOPSuspended, got %v", err)
+	}
+	if ss, ok := fop.SuspendState(); !ok || ss != cop.FOPActive {
+		t.Errorf("SuspendState = (%v, %v), want (Active, true)", ss, ok)
+	}
+	if fop.PendingCount() != 1 {
+		t.Errorf("suspension must keep the sent queue, pending = %d", fop.PendingCount())
+	}
+	if err := fop.SetVS(9); !errors.Is(err, cop.ErrFOPInvalidDirective) {
+		t.Errorf("SetVS while suspended: got %v", err)
+	}
+
+	if err := fop.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if fop.State() != cop.FOPActive {
+		t.Errorf("state after Resume = %v, want Active", fop.State())
+	}
+	if err := fop.Resume(); !errors.Is(err, cop.ErrFOPInvalidDirective) {
+		t.Errorf("second Resume: got %v", err)
+	}
+}
+
+func TestFOP_InitiateADWithCLCWCheck(t *testing.T) {
+	var notes []cop.Notification
+	fop := cop.NewFOP(42, 1, 10, cop.WithNotifyHandler(func(n cop.Notification) {
+		notes = append(notes, n)
+	}))
+	if err := fop.SetVS(7); err != nil {
+		t.Fatal(err)
+	}
+	if err := fop.InitiateADWithCLCWCheck(); err != nil {
+		t.Fatal(err)
+	}
+	if fop.State() != cop.FOPInitializingWithoutBC {
+		t.Fatalf("state = %v, want Initializing without BC Frame", fop.State())
+	}
+	if err := fop.TransmitFrame([]byte("x")); !errors.Is(err, cop.ErrFOPNotActive) {
+		t.Errorf("TransmitFrame while initializing: got %v", err)
+	}
+
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 3})
+	if fop.State() != cop.FOPInitializingWithoutBC {
+		t.Errorf("mismatching N(R) must not complete initiation")
+	}
+
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 7})
+	if fop.State() != cop.FOPActive {
+		t.Errorf("state = %v, want Active", fop.State())
+	}
+	if len(notes) != 1 || notes[0].Kind != cop.NotifyInitiated {
+		t.Errorf("notifications = %+v, want one NotifyInitiated", notes)
+	}
+}
+
+func TestFOP_InitiateADWithCLCWCheck_Timeout(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	fop := cop.NewFOP(42, 1, 10, cop.WithClock(clk), cop.WithT1(time.Second))
+	_ = fop.InitiateADWithCLCWCheck()
+
+	clk.Advance(time.Second)
+	if err := fop.CheckTimer(); !errors.Is(err, cop.ErrFOPTimeout) {
+		t.Errorf("expected ErrFOPTimeout, got %v", err)
+	}
+	if fop.State() != cop.FOPInitial {
+		t.Errorf("state = %v, want Initial", fop.State())
+	}
+}
+
+func TestFOP_InitiateADWithUnlock(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	fop := cop.NewFOP(42, 1, 10, cop.WithClock(clk), cop.WithT1(time.Second))
+	bc := []byte("unlock")
+
+	if err := fop.InitiateADWithUnlock(bc); err != nil {
+		t.Fatal(err)
+	}
+	if err := fop.InitiateADWithUnlock(bc); !errors.Is(err, cop.ErrFOPInvalidDirective) {
+		t.Errorf("second Initiate: got %v", err)
+	}
+
+	data, _, ok := fop.GetNextFrame()
+	if !ok || !bytes.Equal(data, bc) {
+		t.Fatalf("GetNextFrame = (%q, %v), want BC frame", data, ok)
+	}
+
+	// Lockout is still reported until the BC frame arrives.
+	if err := fop.ProcessCLCW(&cop.CLCW{LockoutFlag: true}); err != nil {
+		t.Errorf("lockout while initializing with BC: got %v", err)
+	}
+
+	clk.Advance(time.Second)
+	_ = fop.CheckTimer()
+	data, _, ok = fop.GetNextFrame()
+	if !ok || !bytes.Equal(data, bc) {
+		t.Errorf("expected BC retransmission after T1, got (%q, %v)", data, ok)
+	}
+
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 0})
+	if fop.State() != cop.FOPActive {
+		t.Errorf("state = %v, want Active", fop.State())
+	}
+}
+
+func TestFOP_InitiateADWithSetVR(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+	if err := fop.InitiateADWithSetVR(200, []byte("setvr")); err != nil {
+		t.Fatal(err)
+	}
+	if fop.VS() != 200 {
+		t.Errorf("V(S) = %d, want 200", fop.VS())
+	}
+	fop.GetNextFrame()
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 200})
+	if fop.State() != cop.FOPActive {
+		t.Errorf("state = %v, want Active", fop.State())
+	}
+}
+
+func TestFOP_Terminate(t *testing.T) {
+	var notes []cop.Notification
+	fop := cop.NewFOP(42, 1, 10, cop.WithNotifyHandler(func(n cop.Notification) {
+		notes = append(notes, n)
+	}))
+	fop.Initialize(0)
+	_ = fop.TransmitFrame([]byte("frame"))
+
+	fop.Terminate()
+	if fop.State() != cop.FOPInitial || fop.PendingCount() != 0 {
+		t.Errorf("state=%v pending=%d after Terminate", fop.State(), fop.PendingCount())
+	}
+	if len(notes) != 1 || notes[0].Alert != cop.AlertTerm {
+		t.Errorf("notifications = %+v, want Alert(term)", notes)
+	}
+}
+
+func TestFOP_BDFrame(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+
+	// Type-BD frames are accepted even before the AD service starts.
+	if err := fop.TransmitBDFrame([]byte("bd-0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fop.TransmitBDFrame([]byte("bd-1")); !errors.Is(err, cop.ErrFOPBDBusy) {
+		t.Errorf("expected ErrFOPBDBusy, got %v", err)
+	}
+	data, _, ok := fop.GetNextFrame()
+	if !ok || string(data) != "bd-0" {
+		t.Errorf("GetNextFrame = (%q, %v), want bd-0", data, ok)
+	}
+	if err := fop.TransmitBDFrame([]byte("bd-1")); err != nil {
+		t.Errorf("BD slot should be free again: %v", err)
+	}
+}
+
+func TestFOP_RetransmitWithWait(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+	fop.Initialize(0)
+	_ = fop.TransmitFrame([]byte("frame-0"))
+	_ = fop.TransmitFrame([]byte("frame-1"))
+	fop.GetNextFrame()
+	fop.GetNextFrame()
+
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 0, RetransmitFlag: true, WaitFlag: true})
+	if fop.State() != cop.FOPRetransmitWithWait {
+		t.Fatalf("state = %v, want Retransmit with Wait", fop.State())
+	}
+	_ = fop.TransmitFrame([]byte("frame-2"))
+	if _, _, ok := fop.GetNextFrame(); ok {
+		t.Error("Type-AD frames must be held while FARM-1 waits")
+	}
+
+	_ = fop.ProcessCLCW(&cop.CLCW{ReportValue: 0, RetransmitFlag: true})
+	if fop.State() != cop.FOPRetransmitWithoutWait {
+		t.Fatalf("state = %v, want Retransmit without Wait", fop.State())
+	}
+	data, _, ok := fop.GetNextFrame()
+	if !ok || string(data) != "frame-0" {
+		t.Errorf("GetNextFrame = (%q, %v), want frame-0", data, ok)
+	}
+}
+
+func TestFOP_ProcessCLCW_Alerts(t *testing.T) {
+	tests := []struct {
+		name string
+		clcw cop.CLCW
+		want error
+	}{
+		{"N(R) beyond V(S)", cop.CLCW{ReportValue: 5}, cop.ErrFOPInvalidNR},
+		{"wait without retransmit", cop.CLCW{ReportValue: 0, WaitFlag: true}, cop.ErrFOPInvalidCLCW},
+		{"retransmit with all acknowledged", cop.CLCW{ReportValue: 2, RetransmitFlag: true}, cop.ErrFOPInvalidCLCW},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fop := cop.NewFOP(42, 1, 10)
+			fop.Initialize(0)
+			_ = fop.TransmitFrame([]byte("frame-0"))
+			_ = fop.TransmitFrame([]byte("frame-1"))
+
+			if err := fop.ProcessCLCW(&tt.clcw); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if fop.State() != cop.FOPInitial {
+				t.Errorf("state = %v, want Initial", fop.State())
+			}
+		})
+	}
+}
+
+func TestFOP_TransmissionLimitOne(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+	fop.Initialize(0)
+	if err := fop.SetTransmissionLimit(1); err != nil {
+		t.Fatal(err)
+	}
+	_ = fop.TransmitFrame([]byte("frame-0"))
+
+	err := fop.ProcessCLCW(&cop.CLCW{ReportValue: 0, RetransmitFlag: true})
+	if !errors.Is(err, cop.ErrFOPLimitReached) {
+		t.Errorf("expected ErrFOPLimitReached, got %v", err)
+	}
+}
+
+func TestFOP_SetParameters_Invalid(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+	if err := fop.SetSlidingWindow(0); !errors.Is(err, cop.ErrFOPInvalidParameter) {
+		t.Errorf("SetSlidingWindow(0): got %v", err)
+	}
+	if err := fop.SetT1(0); !errors.Is(err, cop.ErrFOPInvalidParameter) {
+		t.Errorf("SetT1(0): got %v", err)
+	}
+	if err := fop.SetTransmissionLimit(0); !errors.Is(err, cop.ErrFOPInvalidParameter) {
+		t.Errorf("SetTransmissionLimit(0): got %v", err)
+	}
+	if err := fop.SetTimeoutType(cop.TimeoutType(9)); !errors.Is(err, cop.ErrFOPInvalidParameter) {
+		t.Errorf("SetTimeoutType(9): got %v", err)
+	}
+}
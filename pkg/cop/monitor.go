@@ -0,0 +1,291 @@
+package cop
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+// CLCWEventKind identifies the CLCW field whose change raised a CLCWEvent.
+type CLCWEventKind int
+
+const (
+	EventLockout     CLCWEventKind = iota // Lockout flag set or cleared
+	EventWait                             // Wait flag set or cleared
+	EventRetransmit                       // Retransmit flag set or cleared
+	EventNoRF                             // No RF Available flag set or cleared
+	EventNoBitLock                        // No Bit Lock flag set or cleared
+	EventFARMB                            // FARM-B counter changed
+	EventReportValue                      // Report Value V(R) changed
+)
+
+// String returns a short name for the event kind.
+func (k CLCWEventKind) String() string {
+	switch k {
+	case EventLockout:
+		return "Lockout"
+	case EventWait:
+		return "Wait"
+	case EventRetransmit:
+		return "Retransmit"
+	case EventNoRF:
+		return "No RF Available"
+	case EventNoBitLock:
+		return "No Bit Lock"
+	case EventFARMB:
+		return "FARM-B Counter"
+	case EventReportValue:
+		return "Report Value"
+	default:
+		return "Unknown"
+	}
+}
+
+// CLCWEvent reports a transition in the CLCWs received for one virtual
+// channel. For flag events Set tells whether the flag was raised or
+// cleared; for counter events it is always true.
+type CLCWEvent struct {
+	Kind     CLCWEventKind
+	VCID     uint8
+	Set      bool
+	Previous CLCW // report before the transition (zero on the first report)
+	Current  CLCW // report that raised the event
+}
+
+// VRSample is one entry of a virtual channel's V(R) history.
+type VRSample struct {
+	Value uint8
+	At    time.Time
+}
+
+// VCStatus is the COP-1 status of one virtual channel as seen in the
+// most recent CLCW.
+type VCStatus struct {
+	VCID         uint8
+	Last         CLCW
+	LastReport   time.Time
+	Reports      int        // CLCWs received for this VC
+	FARMBChanges int        // observed FARM-B counter changes (Type-B acceptances)
+	VRHistory    []VRSample // V(R) values, oldest first, one per change
+}
+
+// CLCWProcessor consumes CLCWs for one virtual channel. Both *FOP and
+// *SequenceControlledService implement it.
+type CLCWProcessor interface {
+	ProcessCLCW(clcw *CLCW) error
+}
+
+// DefaultVRHistory is the number of V(R) samples kept per virtual channel.
+const DefaultVRHistory = 32
+
+// MonitorOption configures a CLCWMonitor.
+type MonitorOption func(*CLCWMonitor)
+
+// WithEventHandler installs a callback for CLCW transitions. The
+// handler runs after the monitor is unlocked and may query it.
+func WithEventHandler(h func(CLCWEvent)) MonitorOption {
+	return func(m *CLCWMonitor) {
+		m.handler = h
+	}
+}
+
+// WithVRHistory sets how many V(R) samples are kept per virtual channel.
+// Zero or less keeps every sample.
+func WithVRHistory(n int) MonitorOption {
+	return func(m *CLCWMonitor) {
+		m.historyLen = n
+	}
+}
+
+// WithMonitorClock sets the clock used to timestamp reports.
+func WithMonitorClock(c Clock) MonitorOption {
+	return func(m *CLCWMonitor) {
+		m.clock = c
+	}
+}
+
+// CLCWMonitor extracts CLCWs from the Operational Control Field of
+// return-link frames and tracks the COP-1 status of each virtual
+// channel per CCSDS 232.0-B-4 Section 4.2.1.
+//
+// Each CLCW is compared with the previous one for the same VCID and a
+// CLCWEvent is raised for every flag that changed, for FARM-B counter
+// changes and for V(R) changes. The first report for a VCID is compared
+// against a clear CLCW: set flags raise events, counters do not.
+//
+// CLCWs with COP-1 in effect are passed to the processor attached to
+// their VCID, typically the FOP-1 instance or SequenceControlledService
+// for that channel.
+type CLCWMonitor struct {
+	mu         sync.Mutex
+	status     map[uint8]*VCStatus
+	processors map[uint8]CLCWProcessor
+	handler    func(CLCWEvent)
+	historyLen int
+	clock      Clock
+}
+
+// NewCLCWMonitor creates a monitor with no virtual channels attached.
+func NewCLCWMonitor(opts ...MonitorOption) *CLCWMonitor {
+	m := &CLCWMonitor{
+		status:     make(map[uint8]*VCStatus),
+		processors: make(map[uint8]CLCWProcessor),
+		historyLen: DefaultVRHistory,
+		clock:      systemClock{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Attach routes CLCWs reporting on vcid to p. Pass nil to detach.
+func (m *CLCWMonitor) Attach(vcid uint8, p CLCWProcessor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p == nil {
+		delete(m.processors, vcid)
+		return
+	}
+	m.processors[vcid] = p
+}
+
+// ProcessCLCW records a CLCW, raises events for its transitions and
+// passes it to the processor attached to its VCID. Returns the
+// processor's error, e.g. ErrFOPLockout.
+func (m *CLCWMonitor) ProcessCLCW(clcw *CLCW) error {
+	m.mu.Lock()
+	events := m.record(clcw)
+	p := m.processors[clcw.VirtualChannelID]
+	handler := m.handler
+	m.mu.Unlock()
+
+	var err error
+	if p != nil && clcw.COPInEffect == 1 {
+		err = p.ProcessCLCW(clcw)
+	}
+	if handler != nil {
+		for _, e := range events {
+			handler(e)
+		}
+	}
+	return err
+}
+
+// ProcessOCF decodes a 4-byte Operational Control Field and processes
+// it as a CLCW. Type-2 reports (control word type 1) and short input
+// are ignored.
+func (m *CLCWMonitor) ProcessOCF(ocf []byte) error {
+	if len(ocf) < 4 || ocf[0]&0x80 != 0 {
+		return nil
+	}
+	var clcw CLCW
+	if err := clcw.Decode(ocf); err != nil {
+		return err
+	}
+	return m.ProcessCLCW(&clcw)
+}
+
+// ProcessTMFrame processes the CLCW carried in a decoded TM Transfer
+// Frame. Frames without an OCF are ignored.
+func (m *CLCWMonitor) ProcessTMFrame(frame *tmdl.TMTransferFrame) error {
+	if !frame.Header.OCFFlag {
+		return nil
+	}
+	return m.ProcessOCF(frame.OperationalControl)
+}
+
+// ProcessAOSFrame processes the CLCW carried in a decoded AOS Transfer
+// Frame. Frames without an OCF are ignored.
+func (m *CLCWMonitor) ProcessAOSFrame(frame *aos.TransferFrame) error {
+	return m.ProcessOCF(frame.OCF)
+}
+
+// ProcessUSLPFrame processes the CLCW carried in a USLP Transfer Frame
+// decoded with usdl.DecodeTransferFrameWithOCF. Frames without an OCF
+// are ignored.
+func (m *CLCWMonitor) ProcessUSLPFrame(frame *usdl.TransferFrame) error {
+	return m.ProcessOCF(frame.OCF)
+}
+
+// Status returns a snapshot of the status of vcid, and false if no
+// CLCW has been received for it.
+func (m *CLCWMonitor) Status(vcid uint8) (VCStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.status[vcid]
+	if !ok {
+		return VCStatus{}, false
+	}
+	snapshot := *st
+	snapshot.VRHistory = slices.Clone(st.VRHistory)
+	return snapshot, true
+}
+
+// VCIDs returns the virtual channels reported so far, in ascending order.
+func (m *CLCWMonitor) VCIDs() []uint8 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]uint8, 0, len(m.status))
+	for id := range m.status {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// record updates the status of the CLCW's VC and returns the events
+// raised by the transition. The caller holds m.mu.
+func (m *CLCWMonitor) record(clcw *CLCW) []CLCWEvent {
+	now := m.clock.Now()
+	vcid := clcw.VirtualChannelID
+	st, seen := m.status[vcid]
+	if !seen {
+		st = &VCStatus{VCID: vcid}
+		m.status[vcid] = st
+	}
+	prev := st.Last
+	cur := *clcw
+
+	var events []CLCWEvent
+	raise := func(kind CLCWEventKind, set bool) {
+		events = append(events, CLCWEvent{Kind: kind, VCID: vcid, Set: set, Previous: prev, Current: cur})
+	}
+	flags := []struct {
+		kind    CLCWEventKind
+		was, is bool
+	}{
+		{EventLockout, prev.LockoutFlag, cur.LockoutFlag},
+		{EventWait, prev.WaitFlag, cur.WaitFlag},
+		{EventRetransmit, prev.RetransmitFlag, cur.RetransmitFlag},
+		{EventNoRF, prev.NoRFAvailableFlag, cur.NoRFAvailableFlag},
+		{EventNoBitLock, prev.NoBitLockFlag, cur.NoBitLockFlag},
+	}
+	for _, f := range flags {
+		if f.was != f.is {
+			raise(f.kind, f.is)
+		}
+	}
+	if seen && prev.FARMBCounter != cur.FARMBCounter {
+		st.FARMBChanges++
+		raise(EventFARMB, true)
+	}
+	if !seen || prev.ReportValue != cur.ReportValue {
+		if seen {
+			raise(EventReportValue, true)
+		}
+		st.VRHistory = append(st.VRHistory, VRSample{Value: cur.ReportValue, At: now})
+		if m.historyLen > 0 && len(st.VRHistory) > m.historyLen {
+			st.VRHistory = slices.Delete(st.VRHistory, 0, len(st.VRHistory)-m.historyLen)
+		}
+	}
+
+	st.Last = cur
+	st.LastReport = now
+	st.Reports++
+	return events
+}
@@ -0,0 +1,188 @@
+package cop_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/cop"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+func TestCLCWMonitor_TracksStatusPerVC(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(100, 0)}
+	m := cop.NewCLCWMonitor(cop.WithMonitorClock(clk))
+
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 2, ReportValue: 5})
+	clk.Advance(time.Second)
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 2, ReportValue: 7})
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 2, ReportValue: 7})
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 1, LockoutFlag: true})
+
+	if got := m.VCIDs(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("VCIDs = %v, want [1 2]", got)
+	}
+
+	st, ok := m.Status(2)
+	if !ok {
+		t.Fatal("no status for VC2")
+	}
+	if st.Reports != 3 || st.Last.ReportValue != 7 || !st.LastReport.Equal(time.Unix(101, 0)) {
+		t.Errorf("VC2 reports=%d V(R)=%d last=%v", st.Reports, st.Last.ReportValue, st.LastReport)
+	}
+	if len(st.VRHistory) != 2 || st.VRHistory[0].Value != 5 || st.VRHistory[1].Value != 7 {
+		t.Errorf("VC2 V(R) history = %+v, want 5 then 7", st.VRHistory)
+	}
+
+	st, _ = m.Status(1)
+	if !st.Last.LockoutFlag {
+		t.Error("VC1 should report lockout")
+	}
+	if _, ok := m.Status(3); ok {
+		t.Error("VC3 never reported")
+	}
+}
+
+func TestCLCWMonitor_Events(t *testing.T) {
+	var events []cop.CLCWEvent
+	m := cop.NewCLCWMonitor(cop.WithEventHandler(func(e cop.CLCWEvent) {
+		events = append(events, e)
+	}))
+
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 1, ReportValue: 3})
+	if len(events) != 0 {
+		t.Fatalf("clear first report raised %d events", len(events))
+	}
+
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 1, ReportValue: 3,
+		RetransmitFlag: true, NoBitLockFlag: true, FARMBCounter: 1})
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 1, ReportValue: 4, FARMBCounter: 1})
+
+	want := []struct {
+		kind cop.CLCWEventKind
+		set  bool
+	}{
+		{cop.EventRetransmit, true},
+		{cop.EventNoBitLock, true},
+		{cop.EventFARMB, true},
+		{cop.EventRetransmit, false},
+		{cop.EventNoBitLock, false},
+		{cop.EventReportValue, true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		if events[i].Kind != w.kind || events[i].Set != w.set || events[i].VCID != 1 {
+			t.Errorf("event %d = %v set=%v, want %v set=%v", i, events[i].Kind, events[i].Set, w.kind, w.set)
+		}
+	}
+	if last := events[len(events)-1]; last.Previous.ReportValue != 3 || last.Current.ReportValue != 4 {
+		t.Errorf("V(R) event %d -> %d, want 3 -> 4", last.Previous.ReportValue, last.Current.ReportValue)
+	}
+
+	st, _ := m.Status(1)
+	if st.FARMBChanges != 1 {
+		t.Errorf("FARMBChanges = %d, want 1", st.FARMBChanges)
+	}
+}
+
+func TestCLCWMonitor_FirstReportRaisesSetFlags(t *testing.T) {
+	var kinds []cop.CLCWEventKind
+	m := cop.NewCLCWMonitor(cop.WithEventHandler(func(e cop.CLCWEvent) {
+		kinds = append(kinds, e.Kind)
+	}))
+	_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 0, LockoutFlag: true, NoRFAvailableFlag: true, ReportValue: 9, FARMBCounter: 2})
+	if len(kinds) != 2 || kinds[0] != cop.EventLockout || kinds[1] != cop.EventNoRF {
+		t.Errorf("events = %v, want [Lockout No RF Available]", kinds)
+	}
+}
+
+func TestCLCWMonitor_VRHistoryBounded(t *testing.T) {
+	m := cop.NewCLCWMonitor(cop.WithVRHistory(3))
+	for vr := range 10 {
+		_ = m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, ReportValue: uint8(vr)})
+	}
+	st, _ := m.Status(0)
+	if len(st.VRHistory) != 3 || st.VRHistory[0].Value != 7 || st.VRHistory[2].Value != 9 {
+		t.Errorf("history = %+v, want V(R) 7..9", st.VRHistory)
+	}
+}
+
+func TestCLCWMonitor_FeedsFOP(t *testing.T) {
+	fop := cop.NewFOP(42, 1, 10)
+	_ = fop.InitiateAD()
+	for i := range 3 {
+		_ = fop.TransmitFrame([]byte{byte(i)})
+	}
+
+	m := cop.NewCLCWMonitor()
+	m.Attach(1, fop)
+
+	// VC2 reports must not reach the VC1 FOP.
+	if err := m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 2, LockoutFlag: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 1, ReportValue: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if fop.NNR() != 2 {
+		t.Errorf("NN(R) = %d, want 2", fop.NNR())
+	}
+
+	err := m.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 1, LockoutFlag: true, ReportValue: 2})
+	if !errors.Is(err, cop.ErrFOPLockout) {
+		t.Errorf("expected ErrFOPLockout from FOP, got %v", err)
+	}
+}
+
+func TestCLCWMonitor_Frames(t *testing.T) {
+	farm := cop.NewFARM(3, 10)
+	_, _ = farm.ProcessFrame(0, 0, 0)
+	ocf := clcwOCF(t, farm)
+	m := cop.NewCLCWMonitor()
+
+	tmData, _ := tmdl.NewTMTransferFrame(42, 0, []byte{0x00}, nil, ocf)
+	tmEncoded, _ := tmData.Encode()
+	tm, err := tmdl.DecodeTMTransferFrame(tmEncoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ProcessTMFrame(tm); err != nil {
+		t.Fatal(err)
+	}
+
+	af, _ := aos.NewTransferFrame(42, 0, []byte{0x00}, aos.WithOCF(ocf), aos.WithFECF())
+	afEncoded, _ := af.Encode()
+	decodedAOS, err := aos.DecodeTransferFrame(afEncoded, 0, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ProcessAOSFrame(decodedAOS); err != nil {
+		t.Fatal(err)
+	}
+
+	uf, _ := usdl.NewTransferFrame(42, 0, 0, []byte{0x00}, usdl.WithOCF(ocf))
+	ufEncoded, _ := uf.Encode()
+	decodedUSLP, err := usdl.DecodeTransferFrameWithOCF(ufEncoded, usdl.FECSize16, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ProcessUSLPFrame(decodedUSLP); err != nil {
+		t.Fatal(err)
+	}
+
+	noOCF, _ := tmdl.NewTMTransferFrame(42, 0, []byte{0x00}, nil, nil)
+	_ = m.ProcessTMFrame(noOCF)
+	_ = m.ProcessOCF([]byte{0x80, 0, 0, 0}) // Type-2 report
+
+	st, ok := m.Status(3)
+	if !ok || st.Reports != 3 || st.Last.ReportValue != 1 {
+		t.Errorf("VC3 status = %+v, want 3 reports with V(R)=1", st)
+	}
+	if len(m.VCIDs()) != 1 {
+		t.Errorf("VCIDs = %v, want only VC3", m.VCIDs())
+	}
+}
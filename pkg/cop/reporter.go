@@ -0,0 +1,63 @@
+package cop
+
+import "sync"
+
+// CLCWReporter supplies CLCWs from one or more FARM-1 instances for the
+// Operational Control Field of return-link frames, per CCSDS 232.0-B-4
+// Section 4.2.1.
+//
+// Each call reports the current state of the next registered FARM in
+// round-robin order, so with several virtual channels every VC's CLCW
+// is sent in turn. Install OCF as the OCF provider of a tmdl, aos or
+// usdl Master Channel to close the COP-1 loop:
+//
+//	reporter := cop.NewCLCWReporter(farm1, farm2)
+//	mc.SetOCFProvider(reporter.OCF)
+type CLCWReporter struct {
+	mu    sync.Mutex
+	farms []*FARM
+	next  int
+}
+
+// NewCLCWReporter creates a reporter over the given FARMs.
+func NewCLCWReporter(farms ...*FARM) *CLCWReporter {
+	return &CLCWReporter{farms: append([]*FARM(nil), farms...)}
+}
+
+// Add registers another FARM. It joins the rotation after the
+// currently registered FARMs.
+func (r *CLCWReporter) Add(farm *FARM) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.farms = append(r.farms, farm)
+}
+
+// Next returns the CLCW of the next FARM in the rotation, or nil if no
+// FARM is registered.
+func (r *CLCWReporter) Next() *CLCW {
+	r.mu.Lock()
+	if len(r.farms) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	farm := r.farms[r.next%len(r.farms)]
+	r.next = (r.next + 1) % len(r.farms)
+	r.mu.Unlock()
+
+	return farm.GenerateCLCW()
+}
+
+// OCF returns the encoded CLCW of the next FARM in the rotation, or nil
+// if no FARM is registered. Its signature matches the OCF provider of
+// the tmdl, aos and usdl Master Channels.
+func (r *CLCWReporter) OCF() []byte {
+	clcw := r.Next()
+	if clcw == nil {
+		return nil
+	}
+	ocf, err := clcw.Encode()
+	if err != nil {
+		return nil
+	}
+	return ocf
+}
@@ -0,0 +1,63 @@
+package cop_test
+
+import (
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/cop"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+)
+
+func TestCLCWReporter_Empty(t *testing.T) {
+	r := cop.NewCLCWReporter()
+	if r.Next() != nil || r.OCF() != nil {
+		t.Error("reporter without FARMs must report nothing")
+	}
+}
+
+func TestCLCWReporter_RotatesVCs(t *testing.T) {
+	r := cop.NewCLCWReporter(cop.NewFARM(1, 10), cop.NewFARM(2, 10))
+	r.Add(cop.NewFARM(5, 10))
+
+	want := []uint8{1, 2, 5, 1, 2}
+	for i, vcid := range want {
+		var clcw cop.CLCW
+		if err := clcw.Decode(r.OCF()); err != nil {
+			t.Fatal(err)
+		}
+		if clcw.VirtualChannelID != vcid {
+			t.Errorf("report %d: VCID = %d, want %d", i, clcw.VirtualChannelID, vcid)
+		}
+	}
+}
+
+func TestCLCWReporter_ClosesLoopThroughMasterChannel(t *testing.T) {
+	uplink := tcdl.NewVirtualChannel(1, 16)
+	svc := cop.NewSequenceControlledService(42, 1, 10, uplink)
+	farm := cop.NewFARM(1, 10)
+
+	config := tmdl.ChannelConfig{FrameLength: 64, HasOCF: true, HasFEC: true}
+	mc := tmdl.NewMasterChannel(42, config)
+	mc.AddVirtualChannel(tmdl.NewVirtualChannel(0, 16), 1)
+	mc.SetOCFProvider(cop.NewCLCWReporter(farm).OCF)
+
+	_ = svc.InitiateAD()
+	for i := range 3 {
+		_ = svc.Send([]byte{byte(i)})
+	}
+	for _, f := range drain(t, uplink) {
+		_, _ = farm.ProcessTCFrame(f)
+	}
+
+	// An idle downlink frame carries the CLCW acknowledging all three.
+	frame, err := mc.GetNextFrameOrIdle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ProcessTMFrame(frame); err != nil {
+		t.Fatal(err)
+	}
+	if svc.FOP().PendingCount() != 0 || svc.FOP().NNR() != 3 {
+		t.Errorf("pending=%d NN(R)=%d, want 0 and 3", svc.FOP().PendingCount(), svc.FOP().NNR())
+	}
+}
@@ -0,0 +1,302 @@
+package cop
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+// ServiceOption configures a SequenceControlledService.
+type ServiceOption func(*SequenceControlledService)
+
+// WithMAPID adds an unsegmented segment header carrying mapID to every
+// Type-AD and Type-BD frame, as used by the MAP services.
+func WithMAPID(mapID uint8) ServiceOption {
+	return func(s *SequenceControlledService) {
+		s.mapID = mapID
+		s.segmented = true
+	}
+}
+
+// WithFOPOptions passes options through to the underlying FOP-1.
+func WithFOPOptions(opts ...FOPOption) ServiceOption {
+	return func(s *SequenceControlledService) {
+		s.fopOpts = append(s.fopOpts, opts...)
+	}
+}
+
+// SequenceControlledService is the ground-side COP-1 service for one TC
+// virtual channel per CCSDS 232.0-B-4 Section 2.3.2.
+//
+// It builds Type-AD frames with N(S) assigned by FOP-1, Type-BD frames,
+// and the Type-BC frames of the Initiate AD directives, and delivers them
+// all through the same tcdl.VirtualChannel in the order FOP-1 releases
+// them. CLCWs taken from the OCF of TM, AOS or USLP frames acknowledge
+// frames and trigger retransmissions.
+//
+// Every call that can release frames moves them into the virtual channel
+// before returning. If the channel is full, the frame is held and moved
+// on the next call. A notify handler passed through WithFOPOptions runs
+// while the service is locked and must not call back into it.
+type SequenceControlledService struct {
+	mu        sync.Mutex
+	scid      uint16
+	vcid      uint8
+	mapID     uint8
+	segmented bool
+	fop       *FOP
+	fopOpts   []FOPOption
+	vc        *tcdl.VirtualChannel
+	held      *tcdl.TCTransferFrame // released by FOP-1, not yet accepted by vc
+}
+
+// NewSequenceControlledService creates a COP-1 service for the given
+// spacecraft and virtual channel. windowWidth is the FOP-1 sliding
+// window width. Frames are delivered to vc. FOP-1 starts in the Initial
+// state; issue one of the Initiate AD directives before sending.
+func NewSequenceControlledService(scid uint16, vcid uint8, windowWidth uint8, vc *tcdl.VirtualChannel, opts ...ServiceOption) *SequenceControlledService {
+	s := &SequenceControlledService{
+		scid: scid,
+		vcid: vcid,
+		vc:   vc,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.fop = NewFOP(scid, vcid, windowWidth, s.fopOpts...)
+	return s
+}
+
+// FOP returns the underlying FOP-1 for state inspection and the
+// management directives (SetT1, SetTransmissionLimit, ...).
+func (s *SequenceControlledService) FOP() *FOP {
+	return s.fop
+}
+
+// Send builds a Type-AD frame carrying data with N(S) = V(S) and hands
+// it to FOP-1. Returns ErrFOPWindowFull when the sliding window is
+// exhausted and ErrFOPNotActive before the AD service is initiated.
+func (s *SequenceControlledService) Send(data []byte) error {
+	if len(data) == 0 {
+		return tcdl.ErrEmptyData
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opts := append(s.segmentOpts(), tcdl.WithSequenceNumber(s.fop.VS()))
+	encoded, err := s.build(data, opts...)
+	if err != nil {
+		return err
+	}
+	if err := s.fop.TransmitFrame(encoded); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// SendExpedited builds a Type-BD frame carrying data and hands it to
+// FOP-1. Returns ErrFOPBDBusy while the previous BD frame is held.
+func (s *SequenceControlledService) SendExpedited(data []byte) error {
+	if len(data) == 0 {
+		return tcdl.ErrEmptyData
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opts := append(s.segmentOpts(), tcdl.WithBypass())
+	encoded, err := s.build(data, opts...)
+	if err != nil {
+		return err
+	}
+	if err := s.fop.TransmitBDFrame(encoded); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// InitiateAD starts the AD service without CLCW check.
+func (s *SequenceControlledService) InitiateAD() error {
+	return s.fop.InitiateAD()
+}
+
+// InitiateADWithCLCWCheck starts the AD service once a CLCW confirms
+// that FARM-1's V(R) equals V(S).
+func (s *SequenceControlledService) InitiateADWithCLCWCheck() error {
+	return s.fop.InitiateADWithCLCWCheck()
+}
+
+// InitiateADWithUnlock builds an Unlock Type-BC frame and starts the
+// AD service with it.
+func (s *SequenceControlledService) InitiateADWithUnlock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded, err := s.build(UnlockCommand(), tcdl.WithBypass(), tcdl.WithControlCommand())
+	if err != nil {
+		return err
+	}
+	if err := s.fop.InitiateADWithUnlock(encoded); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// InitiateADWithSetVR builds a Set V(R) Type-BC frame and starts the
+// AD service with V(S) = vr.
+func (s *SequenceControlledService) InitiateADWithSetVR(vr uint8) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded, err := s.build(SetVRCommand(vr), tcdl.WithBypass(), tcdl.WithControlCommand())
+	if err != nil {
+		return err
+	}
+	if err := s.fop.InitiateADWithSetVR(vr, encoded); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// Terminate terminates the AD service and drops any held frame.
+func (s *SequenceControlledService) Terminate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = nil
+	s.fop.Terminate()
+}
+
+// Resume resumes a suspended AD service and releases any frames it holds.
+func (s *SequenceControlledService) Resume() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.fop.Resume(); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// ProcessCLCW passes a CLCW reporting on this virtual channel to FOP-1
+// and moves any retransmissions into the virtual channel. CLCWs for
+// other virtual channels, or without COP-1 in effect, are ignored.
+func (s *SequenceControlledService) ProcessCLCW(clcw *CLCW) error {
+	if clcw.VirtualChannelID != s.vcid || clcw.COPInEffect != 1 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.fop.ProcessCLCW(clcw)
+	if ferr := s.flush(); ferr != nil && err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// ProcessOCF decodes a 4-byte Operational Control Field and processes
+// it as a CLCW. Type-2 reports (control word type 1) are ignored.
+func (s *SequenceControlledService) ProcessOCF(ocf []byte) error {
+	if len(ocf) < 4 || ocf[0]&0x80 != 0 {
+		return nil
+	}
+	var clcw CLCW
+	if err := clcw.Decode(ocf); err != nil {
+		return err
+	}
+	return s.ProcessCLCW(&clcw)
+}
+
+// ProcessTMFrame processes the CLCW carried in a TM Transfer Frame's OCF.
+func (s *SequenceControlledService) ProcessTMFrame(frame *tmdl.TMTransferFrame) error {
+	return s.ProcessOCF(frame.OperationalControl)
+}
+
+// ProcessAOSFrame processes the CLCW carried in an AOS Transfer Frame's OCF.
+func (s *SequenceControlledService) ProcessAOSFrame(frame *aos.TransferFrame) error {
+	return s.ProcessOCF(frame.OCF)
+}
+
+// ProcessUSLPFrame processes the CLCW carried in a USLP Transfer Frame's OCF.
+func (s *SequenceControlledService) ProcessUSLPFrame(frame *usdl.TransferFrame) error {
+	return s.ProcessOCF(frame.OCF)
+}
+
+// CheckTimer services FOP-1's T1 timer and moves any retransmissions
+// into the virtual channel. Call it periodically.
+func (s *SequenceControlledService) CheckTimer() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.fop.CheckTimer()
+	if ferr := s.flush(); ferr != nil && err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// Flush moves every frame FOP-1 is ready to release into the virtual
+// channel. A frame that does not fit stays held until the next call.
+func (s *SequenceControlledService) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *SequenceControlledService) flush() error {
+	for {
+		if s.held == nil {
+			data, _, ok := s.fop.GetNextFrame()
+			if !ok {
+				return nil
+			}
+			frame, err := s.decode(data)
+			if err != nil {
+				return err
+			}
+			s.held = frame
+		}
+		if err := s.vc.Add(s.held); err != nil {
+			if errors.Is(err, tcdl.ErrBufferFull) {
+				return nil
+			}
+			return err
+		}
+		s.held = nil
+	}
+}
+
+func (s *SequenceControlledService) segmentOpts() []tcdl.FrameOption {
+	if !s.segmented {
+		return nil
+	}
+	sh := tcdl.SegmentHeader{SequenceFlags: tcdl.SegUnsegmented, MAPID: s.mapID}
+	return []tcdl.FrameOption{tcdl.WithSegmentHeader(sh)}
+}
+
+func (s *SequenceControlledService) build(data []byte, opts ...tcdl.FrameOption) ([]byte, error) {
+	frame, err := tcdl.NewTCTransferFrame(s.scid, s.vcid, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return frame.Encode()
+}
+
+// decode restores a frame released by FOP-1. Control frames never
+// carry a segment header.
+func (s *SequenceControlledService) decode(data []byte) (*tcdl.TCTransferFrame, error) {
+	frame, err := tcdl.DecodeTCTransferFrame(data)
+	if err != nil {
+		return nil, err
+	}
+	if s.segmented && frame.Header.ControlCommandFlag == 0 {
+		return tcdl.DecodeTCTransferFrameWithSegmentHeader(data)
+	}
+	return frame, nil
+}
@@ -0,0 +1,223 @@
+package cop_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/cop"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+// drain returns every frame queued in vc.
+func drain(t *testing.T, vc *tcdl.VirtualChannel) []*tcdl.TCTransferFrame {
+	t.Helper()
+	var frames []*tcdl.TCTransferFrame
+	for vc.HasFrames() {
+		f, err := vc.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+func clcwOCF(t *testing.T, farm *cop.FARM) []byte {
+	t.Helper()
+	ocf, err := farm.GenerateCLCW().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ocf
+}
+
+func TestSequenceControlledService_AssignsSequenceNumbers(t *testing.T) {
+	vc := tcdl.NewVirtualChannel(1, 16)
+	svc := cop.NewSequenceControlledService(42, 1, 10, vc, cop.WithMAPID(3))
+
+	if err := svc.Send([]byte{0x01}); !errors.Is(err, cop.ErrFOPNotActive) {
+		t.Errorf("Send before Initiate AD: got %v", err)
+	}
+	if err := svc.FOP().SetVS(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.InitiateAD(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		if err := svc.Send([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	frames := drain(t, vc)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	for i, f := range frames {
+		if f.Header.FrameSequenceNum != uint8(10+i) {
+			t.Errorf("frame %d N(S) = %d, want %d", i, f.Header.FrameSequenceNum, 10+i)
+		}
+		if f.Header.BypassFlag != 0 || f.SegmentHeader == nil || f.SegmentHeader.MAPID != 3 {
+			t.Errorf("frame %d: bypass=%d segment header=%+v", i, f.Header.BypassFlag, f.SegmentHeader)
+		}
+	}
+}
+
+func TestSequenceControlledService_EndToEnd(t *testing.T) {
+	vc := tcdl.NewVirtualChannel(1, 16)
+	svc := cop.NewSequenceControlledService(42, 1, 10, vc)
+	farm := cop.NewFARM(1, 10)
+
+	_ = svc.InitiateAD()
+	for i := range 3 {
+		_ = svc.Send([]byte{byte(i)})
+	}
+
+	// Frame 1 is lost on the uplink.
+	for _, f := range drain(t, vc) {
+		if f.Header.FrameSequenceNum == 1 {
+			continue
+		}
+		_, _ = farm.ProcessTCFrame(f)
+	}
+
+	// CLCW with Retransmit set comes back in a TM frame OCF.
+	tm, err := tmdl.NewTMTransferFrame(42, 0, []byte{0x00}, nil, clcwOCF(t, farm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ProcessTMFrame(tm); err != nil {
+		t.Fatal(err)
+	}
+
+	retransmitted := drain(t, vc)
+	if len(retransmitted) != 2 || retransmitted[0].Header.FrameSequenceNum != 1 {
+		t.Fatalf("retransmissions = %d frames, want N(S) 1 and 2", len(retransmitted))
+	}
+	for _, f := range retransmitted {
+		if accepted, err := farm.ProcessTCFrame(f); !accepted || err != nil {
+			t.Fatalf("N(S)=%d: accepted=%v err=%v", f.Header.FrameSequenceNum, accepted, err)
+		}
+	}
+
+	af, err := aos.NewTransferFrame(42, 0, []byte{0x00}, aos.WithOCF(clcwOCF(t, farm)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ProcessAOSFrame(af); err != nil {
+		t.Fatal(err)
+	}
+	if svc.FOP().PendingCount() != 0 || svc.FOP().State() != cop.FOPActive {
+		t.Errorf("pending=%d state=%v, want 0 and Active", svc.FOP().PendingCount(), svc.FOP().State())
+	}
+}
+
+func TestSequenceControlledService_InitiateWithUnlock(t *testing.T) {
+	vc := tcdl.NewVirtualChannel(1, 16)
+	svc := cop.NewSequenceControlledService(42, 1, 10, vc)
+	farm := cop.NewFARM(1, 10)
+	_, _ = farm.ProcessFrame(0, 0, 100) // lockout
+
+	if err := svc.InitiateADWithUnlock(); err != nil {
+		t.Fatal(err)
+	}
+	frames := drain(t, vc)
+	if len(frames) != 1 || !tcdl.IsControlFrame(frames[0]) || !tcdl.IsBypass(frames[0]) {
+		t.Fatalf("expected one Type-BC frame, got %d frames", len(frames))
+	}
+	if !bytes.Equal(frames[0].DataField, cop.UnlockCommand()) {
+		t.Errorf("BC data = %x, want Unlock", frames[0].DataField)
+	}
+	_, _ = farm.ProcessTCFrame(frames[0])
+
+	uf, err := usdl.NewTransferFrame(42, 0, 0, []byte{0x00}, usdl.WithOCF(clcwOCF(t, farm)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ProcessUSLPFrame(uf); err != nil {
+		t.Fatal(err)
+	}
+	if svc.FOP().State() != cop.FOPActive {
+		t.Errorf("state = %v, want Active", svc.FOP().State())
+	}
+}
+
+func TestSequenceControlledService_SetVRAndExpedited(t *testing.T) {
+	vc := tcdl.NewVirtualChannel(1, 16)
+	svc := cop.NewSequenceControlledService(42, 1, 10, vc, cop.WithMAPID(0))
+	farm := cop.NewFARM(1, 10)
+
+	if err := svc.InitiateADWithSetVR(50); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SendExpedited([]byte{0xEE}); err != nil {
+		t.Fatal(err)
+	}
+	frames := drain(t, vc)
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want BC then BD", len(frames))
+	}
+	if !tcdl.IsControlFrame(frames[0]) || frames[0].SegmentHeader != nil {
+		t.Error("first frame should be a BC frame without segment header")
+	}
+	if tcdl.IsControlFrame(frames[1]) || !tcdl.IsBypass(frames[1]) {
+		t.Error("second frame should be a BD frame")
+	}
+	for _, f := range frames {
+		_, _ = farm.ProcessTCFrame(f)
+	}
+	if farm.VR() != 50 {
+		t.Errorf("FARM V(R) = %d, want 50", farm.VR())
+	}
+
+	_ = svc.ProcessCLCW(farm.GenerateCLCW())
+	if svc.FOP().State() != cop.FOPActive || svc.FOP().VS() != 50 {
+		t.Errorf("state=%v V(S)=%d, want Active and 50", svc.FOP().State(), svc.FOP().VS())
+	}
+}
+
+func TestSequenceControlledService_IgnoresOtherVC(t *testing.T) {
+	vc := tcdl.NewVirtualChannel(1, 16)
+	svc := cop.NewSequenceControlledService(42, 1, 10, vc)
+	_ = svc.InitiateAD()
+	_ = svc.Send([]byte{0x01})
+
+	err := svc.ProcessCLCW(&cop.CLCW{COPInEffect: 1, VirtualChannelID: 2, LockoutFlag: true})
+	if err != nil || svc.FOP().State() != cop.FOPActive {
+		t.Errorf("CLCW for VC2 must be ignored: err=%v state=%v", err, svc.FOP().State())
+	}
+}
+
+func TestSequenceControlledService_HeldWhenChannelFull(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	vc := tcdl.NewVirtualChannel(1, 1)
+	svc := cop.NewSequenceControlledService(42, 1, 10, vc,
+		cop.WithFOPOptions(cop.WithClock(clk), cop.WithT1(time.Second)))
+	_ = svc.InitiateAD()
+
+	if err := svc.Send([]byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Send([]byte{0x02}); err != nil {
+		t.Fatalf("second Send must not fail on a full channel: %v", err)
+	}
+	if vc.Len() != 1 {
+		t.Fatalf("vc.Len = %d, want 1", vc.Len())
+	}
+
+	_, _ = vc.Next()
+	if err := svc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := vc.Next()
+	if f == nil || f.Header.FrameSequenceNum != 1 {
+		t.Errorf("held frame not delivered after Flush")
+	}
+}
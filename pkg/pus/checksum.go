@@ -0,0 +1,37 @@
+package pus
+
+// Checksum computes the ISO/IEC 8473-1 checksum of data for placement
+// in the two octets that follow it, per ECSS-E-ST-70-41C Annex A.2. It
+// is the PUS alternative to the CRC packet error control field.
+func Checksum(data []byte) uint16 {
+	c0, c1 := fletcher(data)
+	ck1 := (255 - (c0+c1)%255) % 255
+	ck2 := c1
+	if ck1 == 0 {
+		ck1 = 255
+	}
+	if ck2 == 0 {
+		ck2 = 255
+	}
+	return uint16(ck1)<<8 | uint16(ck2)
+}
+
+// VerifyChecksum reports whether data, ending in its two checksum
+// octets, verifies. Both running sums are zero modulo 255 over a valid
+// packet.
+func VerifyChecksum(data []byte) bool {
+	if len(data) < 2 || data[len(data)-2] == 0 || data[len(data)-1] == 0 {
+		return false
+	}
+	c0, c1 := fletcher(data)
+	return c0 == 0 && c1 == 0
+}
+
+// fletcher returns the two ISO/IEC 8473 running sums modulo 255.
+func fletcher(data []byte) (c0, c1 int) {
+	for _, b := range data {
+		c0 = (c0 + int(b)) % 255
+		c1 = (c1 + c0) % 255
+	}
+	return c0, c1
+}
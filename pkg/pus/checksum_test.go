@@ -0,0 +1,32 @@
+package pus_test
+
+import (
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+func TestChecksum(t *testing.T) {
+	if got := pus.Checksum([]byte{0x01, 0x02}); got != 0xF804 {
+		t.Errorf("Checksum = %04X, want F804", got)
+	}
+
+	data := []byte("ECSS-E-ST-70-41C packet data field")
+	ck := pus.Checksum(data)
+	packet := append(data, byte(ck>>8), byte(ck))
+	if !pus.VerifyChecksum(packet) {
+		t.Error("valid checksum rejected")
+	}
+	packet[3] ^= 0x01
+	if pus.VerifyChecksum(packet) {
+		t.Error("corrupted packet accepted")
+	}
+
+	// Check octets are never zero: zero means the checksum is not in use.
+	if got := pus.Checksum(nil); got != 0xFFFF {
+		t.Errorf("Checksum(nil) = %04X, want FFFF", got)
+	}
+	if pus.VerifyChecksum([]byte{0x00, 0x00}) {
+		t.Error("zero check octets accepted")
+	}
+}
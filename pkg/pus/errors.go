@@ -0,0 +1,134 @@
+package pus
+
+import "errors"
+
+var (
+	// ErrDataTooShort indicates the data is too short for the secondary header.
+	ErrDataTooShort = errors.New("data too short to decode PUS secondary header")
+
+	// ErrInvalidVariant indicates the PUS variant is unknown.
+	ErrInvalidVariant = errors.New("invalid PUS variant: must be PUS-A, PUS-B or PUS-C")
+
+	// ErrInvalidFieldWidth indicates a counter or ID field width is not 0, 8 or 16 bits.
+	ErrInvalidFieldWidth = errors.New("invalid field width: must be 0, 8 or 16 bits")
+
+	// ErrInvalidSpare indicates the spare field is not a whole number of octets.
+	ErrInvalidSpare = errors.New("invalid spare field: must be a multiple of 8 bits")
+
+	// ErrInvalidTimeFormat indicates the CUC time field octet counts are out of range.
+	ErrInvalidTimeFormat = errors.New("invalid time format: CUC octet counts out of range")
+
+	// ErrVersionMismatch indicates the decoded PUS version number does not match the variant.
+	ErrVersionMismatch = errors.New("PUS version number does not match configured variant")
+
+	// ErrFieldOverflow indicates a header field value does not fit its configured width.
+	ErrFieldOverflow = errors.New("header field value exceeds configured width")
+
+	// ErrMissingTime indicates a TM secondary header has a time field but no time.
+	ErrMissingTime = errors.New("TM secondary header time not set")
+
+	// ErrMissingSecondaryHeader indicates the packet has no PUS secondary header.
+	ErrMissingSecondaryHeader = errors.New("packet has no PUS secondary header")
+
+	// ErrChecksumFailed indicates the ISO/IEC 8473 checksum does not verify.
+	ErrChecksumFailed = errors.New("ISO/IEC 8473 checksum verification failed")
+
+	// ErrInvalidSubtype indicates the message subtype is not defined for the service.
+	ErrInvalidSubtype = errors.New("invalid message subtype for service")
+
+	// ErrInvalidReport indicates a report's application data does not match its subtype.
+	ErrInvalidReport = errors.New("invalid report: application data does not match subtype")
+
+	// ErrNotVerificationReport indicates a packet is not an ST[1] TM report.
+	ErrNotVerificationReport = errors.New("packet is not an ST[1] verification report")
+
+	// ErrNotTelecommand indicates a tracked packet is not a PUS telecommand.
+	ErrNotTelecommand = errors.New("packet is not a PUS telecommand")
+
+	// ErrUnknownRequest indicates a verification report refers to an untracked request.
+	ErrUnknownRequest = errors.New("verification report for unknown request")
+
+	// ErrUnrequestedReport indicates a success report for a stage the TC did not acknowledge.
+	ErrUnrequestedReport = errors.New("success report for stage not requested by acknowledgement flags")
+
+	// ErrRequestClosed indicates a verification report arrived after the request completed or failed.
+	ErrRequestClosed = errors.New("verification report for completed or failed request")
+
+	// ErrServiceMismatch indicates a request is addressed to a different service.
+	ErrServiceMismatch = errors.New("request addressed to a different PUS service")
+
+	// ErrInvalidRequest indicates a request's application data is malformed.
+	ErrInvalidRequest = errors.New("invalid request: malformed application data")
+
+	// ErrParameterType indicates a value does not fit the parameter type, or the type is unknown.
+	ErrParameterType = errors.New("value does not fit parameter type")
+
+	// ErrParameterCount indicates the number of values does not match the definition.
+	ErrParameterCount = errors.New("number of values does not match definition")
+
+	// ErrDuplicateDefinition indicates a parameter, structure or event ID or name is already defined.
+	ErrDuplicateDefinition = errors.New("definition already exists")
+
+	// ErrUnknownParameter indicates a parameter ID is not defined.
+	ErrUnknownParameter = errors.New("unknown parameter")
+
+	// ErrUnknownStructure indicates a housekeeping structure ID is not defined.
+	ErrUnknownStructure = errors.New("unknown housekeeping structure")
+
+	// ErrStructureEnabled indicates a housekeeping structure with periodic generation enabled cannot be deleted.
+	ErrStructureEnabled = errors.New("housekeeping structure periodic generation enabled")
+
+	// ErrInvalidInterval indicates a zero collection interval.
+	ErrInvalidInterval = errors.New("invalid collection interval: must be positive")
+
+	// ErrUnknownEvent indicates an event definition ID is not defined.
+	ErrUnknownEvent = errors.New("unknown event definition")
+
+	// ErrInvalidSeverity indicates an event severity is not informative, low, medium or high.
+	ErrInvalidSeverity = errors.New("invalid event severity")
+
+	// ErrInvalidRate indicates a time report rate exponent above MaxTimeRateExp.
+	ErrInvalidRate = errors.New("invalid time report generation rate")
+
+	// ErrScheduleFull indicates the schedule cannot hold more activities.
+	ErrScheduleFull = errors.New("time-based schedule full")
+
+	// ErrReleaseTimePassed indicates a release time before the on-board time plus the time margin.
+	ErrReleaseTimePassed = errors.New("release time before on-board time plus margin")
+
+	// ErrUnknownActivity indicates no scheduled activity has the request ID.
+	ErrUnknownActivity = errors.New("unknown scheduled activity")
+
+	// ErrUnknownGroup indicates a schedule group ID is not defined.
+	ErrUnknownGroup = errors.New("unknown schedule group")
+
+	// ErrGroupInUse indicates a schedule group still holds activities.
+	ErrGroupInUse = errors.New("schedule group holds activities")
+
+	// ErrInvalidPartSize indicates a large message part size that is not positive.
+	ErrInvalidPartSize = errors.New("invalid large message part size")
+
+	// ErrUnknownTransaction indicates a large message part for a transaction that is not open.
+	ErrUnknownTransaction = errors.New("unknown large message transaction")
+
+	// ErrPartSequence indicates a large message part out of sequence.
+	ErrPartSequence = errors.New("large message part out of sequence")
+
+	// ErrMessageTooLarge indicates a reassembled large message exceeds the maximum size.
+	ErrMessageTooLarge = errors.New("large message exceeds maximum size")
+
+	// ErrStoreFull indicates a bounded packet store cannot hold the packet.
+	ErrStoreFull = errors.New("packet store full")
+
+	// ErrCorruptStore indicates a packet store file header is inconsistent with the file.
+	ErrCorruptStore = errors.New("corrupt packet store file")
+
+	// ErrUnknownStore indicates a packet store ID is not defined.
+	ErrUnknownStore = errors.New("unknown packet store")
+
+	// ErrStoreInUse indicates a packet store with storage enabled or a retrieval in progress cannot be deleted.
+	ErrStoreInUse = errors.New("packet store in use")
+
+	// ErrRetrievalInProgress indicates a packet store retrieval conflicts with one in progress.
+	ErrRetrievalInProgress = errors.New("packet store retrieval in progress")
+)
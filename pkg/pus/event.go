@@ -0,0 +1,288 @@
+package pus
+
+import (
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// ServiceEvent is the service type of ST[5] event reporting.
+const ServiceEvent uint8 = 5
+
+// ST[5] request and report subtypes (ECSS-E-ST-70-41C Section 6.5).
+// Subtypes 1 to 4 are the event reports, one per Severity.
+const (
+	EventEnableReports      uint8 = 5 // TC: enable report generation
+	EventDisableReports     uint8 = 6 // TC: disable report generation
+	EventReportDisabledList uint8 = 7 // TC: report the disabled event definitions
+	EventDisabledList       uint8 = 8 // TM: disabled event definitions
+)
+
+// Severity is the severity of an event. Its value is the subtype of
+// the event's report.
+type Severity uint8
+
+const (
+	SeverityInformative Severity = iota + 1 // TM[5,1]
+	SeverityLow                             // TM[5,2]
+	SeverityMedium                          // TM[5,3]
+	SeverityHigh                            // TM[5,4]
+)
+
+// String returns the severity name.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInformative:
+		return "Informative"
+	case SeverityLow:
+		return "Low"
+	case SeverityMedium:
+		return "Medium"
+	case SeverityHigh:
+		return "High"
+	default:
+		return "Unknown"
+	}
+}
+
+// EventDefinition defines an event. The report's auxiliary data
+// carries a value of each parameter in Params. Event definition IDs are
+// 16 bits on the wire.
+type EventDefinition struct {
+	ID       uint16
+	Name     string
+	Severity Severity
+	Params   []uint16
+}
+
+// AddEvent defines an event. Its parameters must be defined first.
+func (d *Dictionary) AddEvent(e EventDefinition) error {
+	if e.Severity < SeverityInformative || e.Severity > SeverityHigh {
+		return ErrInvalidSeverity
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.events[e.ID]; ok {
+		return ErrDuplicateDefinition
+	}
+	if err := d.checkParams(e.Params); err != nil {
+		return err
+	}
+	e.Params = slices.Clone(e.Params)
+	d.events[e.ID] = e
+	return nil
+}
+
+// Event returns the definition of event id.
+func (d *Dictionary) Event(id uint16) (EventDefinition, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.events[id]
+	return e, ok
+}
+
+// EventReport is a decoded TM[5,1] to TM[5,4] event report.
+type EventReport struct {
+	Event  EventDefinition
+	Values []ParameterValue
+}
+
+// DecodeEventReport decodes the application data of an event report of
+// the given subtype using the event definitions in d.
+func (d *Dictionary) DecodeEventReport(subtype uint8, data []byte) (*EventReport, error) {
+	if subtype < uint8(SeverityInformative) || subtype > uint8(SeverityHigh) {
+		return nil, ErrInvalidSubtype
+	}
+	if len(data) < 2 {
+		return nil, ErrInvalidReport
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.events[binary.BigEndian.Uint16(data)]
+	if !ok {
+		return nil, ErrUnknownEvent
+	}
+	if uint8(e.Severity) != subtype {
+		return nil, ErrInvalidReport
+	}
+	values, rest, err := d.decodeValues(e.Params, data[2:])
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidReport
+	}
+	return &EventReport{Event: e, Values: values}, nil
+}
+
+// Value returns the value of the named auxiliary parameter.
+func (r *EventReport) Value(name string) (any, bool) {
+	return lookupValue(r.Values, name)
+}
+
+// Humanize returns a human-readable representation of the report.
+func (r *EventReport) Humanize() string {
+	lines := []string{
+		"  Event: " + r.Event.Name + " (" + strconv.Itoa(int(r.Event.ID)) + ")",
+		"  Severity: " + r.Event.Severity.String(),
+	}
+	for _, v := range r.Values {
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DecodeDisabledEvents decodes the application data of a TM[5,8]
+// report into the disabled event definition IDs.
+func DecodeDisabledEvents(data []byte) ([]uint16, error) {
+	ids, rest, err := readIDs(data)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrInvalidReport
+	}
+	return ids, nil
+}
+
+// NewEnableEventsCommand builds TC[5,5] enabling the reports of events ids.
+func NewEnableEventsCommand(cfg Config, apid uint16, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceEvent, EventEnableReports, appendIDs(nil, ids), opts)
+}
+
+// NewDisableEventsCommand builds TC[5,6] disabling the reports of events ids.
+func NewDisableEventsCommand(cfg Config, apid uint16, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceEvent, EventDisableReports, appendIDs(nil, ids), opts)
+}
+
+// NewReportDisabledEventsCommand builds TC[5,7] requesting the list of
+// disabled event definitions.
+func NewReportDisabledEventsCommand(cfg Config, apid uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceEvent, EventReportDisabledList, nil, opts)
+}
+
+// EventReporter is the on-board ST[5] event reporting service. Every
+// event defined in its dictionary starts enabled.
+type EventReporter struct {
+	mu       sync.Mutex
+	dict     *Dictionary
+	rep      reporter
+	disabled map[uint16]bool
+}
+
+// NewEventReporter creates the event reporting service of application
+// apid with the event definitions of dict.
+func NewEventReporter(cfg Config, apid uint16, dict *Dictionary, opts ...ReporterOption) *EventReporter {
+	return &EventReporter{
+		dict:     dict,
+		rep:      newReporter(cfg, apid, opts),
+		disabled: make(map[uint16]bool),
+	}
+}
+
+// Raise reports event id with a value for each of its parameters. It
+// returns a nil packet if the event's reports are disabled.
+func (r *EventReporter) Raise(id uint16, values ...any) (*spp.SpacePacket, error) {
+	e, ok := r.dict.Event(id)
+	if !ok {
+		return nil, ErrUnknownEvent
+	}
+	if len(values) != len(e.Params) {
+		return nil, ErrParameterCount
+	}
+	data := binary.BigEndian.AppendUint16(nil, id)
+	for i, pid := range e.Params {
+		p, _ := r.dict.Parameter(pid)
+		var err error
+		if data, err = p.Type.Encode(data, values[i]); err != nil {
+			return nil, err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.disabled[id] {
+		return nil, nil
+	}
+	return r.rep.report(ServiceEvent, uint8(e.Severity), data)
+}
+
+// Enable enables the reports of events ids.
+func (r *EventReporter) Enable(ids ...uint16) error {
+	return r.setEnabled(ids, true)
+}
+
+// Disable disables the reports of events ids.
+func (r *EventReporter) Disable(ids ...uint16) error {
+	return r.setEnabled(ids, false)
+}
+
+func (r *EventReporter) setEnabled(ids []uint16, enabled bool) error {
+	for _, id := range ids {
+		if _, ok := r.dict.Event(id); !ok {
+			return ErrUnknownEvent
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if enabled {
+			delete(r.disabled, id)
+		} else {
+			r.disabled[id] = true
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether the reports of event id are enabled.
+func (r *EventReporter) Enabled(id uint16) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.disabled[id]
+}
+
+// Disabled returns the IDs of disabled events in ascending order.
+func (r *EventReporter) Disabled() []uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]uint16, 0, len(r.disabled))
+	for id := range r.disabled {
+		out = append(out, id)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Handle executes an ST[5] request and returns the reports it
+// generates.
+func (r *EventReporter) Handle(tc *spp.SpacePacket) ([]*spp.SpacePacket, error) {
+	sst, data, err := request(tc, ServiceEvent)
+	if err != nil {
+		return nil, err
+	}
+	switch sst {
+	case EventEnableReports, EventDisableReports:
+		ids, rest, err := readIDs(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) != 0 {
+			return nil, ErrInvalidRequest
+		}
+		return nil, r.setEnabled(ids, sst == EventEnableReports)
+	case EventReportDisabledList:
+		if len(data) != 0 {
+			return nil, ErrInvalidRequest
+		}
+		ids := r.Disabled()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		pkt, err := r.rep.report(ServiceEvent, EventDisabledList, appendIDs(nil, ids))
+		if err != nil {
+			return nil, err
+		}
+		return []*spp.SpacePacket{pkt}, nil
+	}
+	return nil, ErrInvalidSubtype
+}
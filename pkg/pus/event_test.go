@@ -0,0 +1,101 @@
+package pus_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+func TestEventReporter(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	d := newTestDictionary(t)
+	events := []pus.EventDefinition{
+		{ID: 100, Name: "BOOT", Severity: pus.SeverityInformative},
+		{ID: 200, Name: "UNDERVOLTAGE", Severity: pus.SeverityHigh, Params: []uint16{1, 2}},
+	}
+	for _, e := range events {
+		if err := d.AddEvent(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	er := pus.NewEventReporter(cfg, 0x30, d)
+
+	pkt, err := er.Raise(200, 21.75, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := downlink(t, cfg, pkt)
+	st, sst, _ := pus.Service(got)
+	if st != pus.ServiceEvent || sst != uint8(pus.SeverityHigh) {
+		t.Fatalf("service ST[%d,%d]", st, sst)
+	}
+	r, err := d.DecodeEventReport(sst, got.UserData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Event.Name != "UNDERVOLTAGE" {
+		t.Errorf("event %+v", r.Event)
+	}
+	if v, _ := r.Value("BAT_V"); v != float32(21.75) {
+		t.Errorf("BAT_V = %v", v)
+	}
+	if v, _ := r.Value("MODE"); v != uint8(2) {
+		t.Errorf("MODE = %v", v)
+	}
+
+	dis, _ := pus.NewDisableEventsCommand(cfg, 0x30, []uint16{100})
+	if _, err := er.Handle(dis); err != nil {
+		t.Fatal(err)
+	}
+	if pkt, err := er.Raise(100); pkt != nil || err != nil {
+		t.Errorf("disabled event raised: %v, %v", pkt, err)
+	}
+
+	list, _ := pus.NewReportDisabledEventsCommand(cfg, 0x30)
+	out, err := er.Handle(list)
+	if err != nil || len(out) != 1 {
+		t.Fatalf("disabled list: %d reports, %v", len(out), err)
+	}
+	ids, err := pus.DecodeDisabledEvents(downlink(t, cfg, out[0]).UserData)
+	if err != nil || !slices.Equal(ids, []uint16{100}) {
+		t.Errorf("disabled = %v, %v", ids, err)
+	}
+
+	en, _ := pus.NewEnableEventsCommand(cfg, 0x30, []uint16{100})
+	if _, err := er.Handle(en); err != nil {
+		t.Fatal(err)
+	}
+	if !er.Enabled(100) {
+		t.Error("event not re-enabled")
+	}
+}
+
+func TestEventReporter_Errors(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	d := newTestDictionary(t)
+	_ = d.AddEvent(pus.EventDefinition{ID: 1, Name: "OVERTEMP", Severity: pus.SeverityMedium, Params: []uint16{3}})
+	er := pus.NewEventReporter(cfg, 0x30, d)
+
+	if _, err := er.Raise(2); !errors.Is(err, pus.ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent, got %v", err)
+	}
+	if _, err := er.Raise(1); !errors.Is(err, pus.ErrParameterCount) {
+		t.Errorf("expected ErrParameterCount, got %v", err)
+	}
+	if _, err := er.Raise(1, 1<<20); !errors.Is(err, pus.ErrParameterType) {
+		t.Errorf("expected ErrParameterType, got %v", err)
+	}
+	if err := er.Disable(1, 2); !errors.Is(err, pus.ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent, got %v", err)
+	}
+	if !er.Enabled(1) {
+		t.Error("partially rejected disable changed state")
+	}
+
+	pkt, _ := er.Raise(1, 85)
+	if _, err := d.DecodeEventReport(uint8(pus.SeverityLow), pkt.UserData); !errors.Is(err, pus.ErrInvalidReport) {
+		t.Errorf("expected ErrInvalidReport for severity mismatch, got %v", err)
+	}
+}
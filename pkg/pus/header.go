@@ -0,0 +1,371 @@
+package pus
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/tcf"
+)
+
+/*
+PUS-C TM secondary header (ECSS-E-ST-70-41C Section 7.4.3.1):
+
++-----------+-----------+---------+---------+-----------+-------------+------+-------+
+| Version   | Time Ref  | Service | Service | Message   | Destination | Time | Spare |
+| (4b)      | Status(4b)| Type(8b)| Subtype | Counter   | ID          | CUC  |       |
+|           |           |         | (8b)    | (0/8/16b) | (0/8/16b)   |      |       |
++-----------+-----------+---------+---------+-----------+-------------+------+-------+
+
+PUS-C TC secondary header (ECSS-E-ST-70-41C Section 7.4.4.1):
+
++-----------+-----------+---------+---------+-----------+-------+
+| Version   | Ack Flags | Service | Service | Source ID | Spare |
+| (4b)      | (4b)      | Type(8b)| Subtype | (0/8/16b) |       |
+|           |           |         | (8b)    |           |       |
++-----------+-----------+---------+---------+-----------+-------+
+
+PUS-A and PUS-B replace the 4-bit version with a spare bit and a 3-bit
+version. Their TM header has 4 spare bits in place of the time
+reference status.
+*/
+
+// TMSecondaryHeader is the PUS TM packet secondary header. Config fixes
+// its layout; set it before decoding.
+type TMSecondaryHeader struct {
+	Config         Config
+	Version        uint8 // PUS version number
+	TimeRefStatus  uint8 // spacecraft time reference status (PUS-C, 4 bits)
+	ServiceType    uint8
+	ServiceSubtype uint8
+	MessageCounter uint16 // message type counter (PUS-A/B packet subcounter)
+	DestinationID  uint16
+	Time           *tcf.CUC
+}
+
+// TMOption configures a TMSecondaryHeader.
+type TMOption func(*TMSecondaryHeader)
+
+// WithMessageCounter sets the message type counter.
+func WithMessageCounter(n uint16) TMOption {
+	return func(h *TMSecondaryHeader) {
+		h.MessageCounter = n
+	}
+}
+
+// WithDestinationID sets the destination ID.
+func WithDestinationID(id uint16) TMOption {
+	return func(h *TMSecondaryHeader) {
+		h.DestinationID = id
+	}
+}
+
+// WithTimeRefStatus sets the spacecraft time reference status.
+func WithTimeRefStatus(s uint8) TMOption {
+	return func(h *TMSecondaryHeader) {
+		h.TimeRefStatus = s
+	}
+}
+
+// NewTMSecondaryHeader creates a TM secondary header for a report of
+// service type and subtype generated at t.
+func NewTMSecondaryHeader(cfg Config, serviceType, serviceSubtype uint8, t time.Time, opts ...TMOption) (*TMSecondaryHeader, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cuc, err := newTime(cfg.Time, t)
+	if err != nil {
+		return nil, err
+	}
+	h := &TMSecondaryHeader{
+		Config:         cfg,
+		Version:        cfg.Variant.VersionNumber(),
+		ServiceType:    serviceType,
+		ServiceSubtype: serviceSubtype,
+		Time:           cuc,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// newTime builds the CUC time code for t in format f.
+func newTime(f TimeFormat, t time.Time) (*tcf.CUC, error) {
+	return tcf.NewCUC(t,
+		tcf.WithCUCCoarseBytes(f.CoarseBytes),
+		tcf.WithCUCFineBytes(f.FineBytes),
+		tcf.WithCUCEpoch(f.epoch()))
+}
+
+// Size returns the encoded size in bytes.
+func (h *TMSecondaryHeader) Size() int { return h.Config.TMHeaderSize() }
+
+// Validate checks the header fields against the configuration.
+func (h *TMSecondaryHeader) Validate() error {
+	if err := h.Config.Validate(); err != nil {
+		return err
+	}
+	if h.Version >= 1<<h.Config.Variant.versionBits() || h.TimeRefStatus > 0x0F {
+		return ErrFieldOverflow
+	}
+	if !fits(h.MessageCounter, h.Config.CounterBits) || !fits(h.DestinationID, h.Config.DestinationBits) {
+		return ErrFieldOverflow
+	}
+	if h.Time == nil {
+		return ErrMissingTime
+	}
+	if h.Time.CoarseBytes != h.Config.Time.CoarseBytes || h.Time.FineBytes != h.Config.Time.FineBytes {
+		return ErrInvalidTimeFormat
+	}
+	return nil
+}
+
+// Encode serializes the header.
+func (h *TMSecondaryHeader) Encode() ([]byte, error) {
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, h.Size())
+	first := h.Version << 4
+	if h.Config.Variant == PUSC {
+		first |= h.TimeRefStatus & 0x0F
+	}
+	out = append(out, first, h.ServiceType, h.ServiceSubtype)
+	out = appendField(out, h.MessageCounter, h.Config.CounterBits)
+	out = appendField(out, h.DestinationID, h.Config.DestinationBits)
+	t, err := h.Time.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if !h.Config.Time.PField {
+		t = t[h.Time.PField.Size():]
+	}
+	out = append(out, t...)
+	return append(out, make([]byte, h.Config.TMSpareBits/8)...), nil
+}
+
+// Decode parses the header using h.Config.
+func (h *TMSecondaryHeader) Decode(data []byte) error {
+	cfg := h.Config
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(data) < cfg.TMHeaderSize() {
+		return ErrDataTooShort
+	}
+	v := cfg.Variant
+	version := data[0] >> 4 & (1<<v.versionBits() - 1)
+	if version != v.VersionNumber() {
+		return ErrVersionMismatch
+	}
+	*h = TMSecondaryHeader{
+		Config:         cfg,
+		Version:        version,
+		ServiceType:    data[1],
+		ServiceSubtype: data[2],
+	}
+	if v == PUSC {
+		h.TimeRefStatus = data[0] & 0x0F
+	}
+	off := 3
+	h.MessageCounter, off = readField(data, off, cfg.CounterBits)
+	h.DestinationID, off = readField(data, off, cfg.DestinationBits)
+	cuc, err := decodeTime(cfg.Time, data[off:off+cfg.Time.Size()])
+	if err != nil {
+		return err
+	}
+	h.Time = cuc
+	return nil
+}
+
+// decodeTime parses a CUC time field. An implicit time field is given
+// the P-field that format f would produce before decoding.
+func decodeTime(f TimeFormat, data []byte) (*tcf.CUC, error) {
+	if !f.PField {
+		tmpl, err := newTime(f, f.epoch())
+		if err != nil {
+			return nil, err
+		}
+		p, err := tmpl.PField.Encode()
+		if err != nil {
+			return nil, err
+		}
+		data = append(p, data...)
+	}
+	cuc, err := tcf.DecodeCUC(data, f.epoch())
+	if err != nil {
+		return nil, err
+	}
+	if cuc.CoarseBytes != f.CoarseBytes || cuc.FineBytes != f.FineBytes {
+		return nil, ErrInvalidTimeFormat
+	}
+	return cuc, nil
+}
+
+// Humanize returns a human-readable representation of the header.
+func (h *TMSecondaryHeader) Humanize() string {
+	lines := []string{
+		"  PUS Version: " + strconv.Itoa(int(h.Version)) + " (" + h.Config.Variant.String() + ")",
+		"  Service: ST[" + strconv.Itoa(int(h.ServiceType)) + "," + strconv.Itoa(int(h.ServiceSubtype)) + "]",
+	}
+	if h.Config.Variant == PUSC {
+		lines = append(lines, "  Time Reference Status: "+strconv.Itoa(int(h.TimeRefStatus)))
+	}
+	if h.Config.CounterBits > 0 {
+		lines = append(lines, "  Message Counter: "+strconv.Itoa(int(h.MessageCounter)))
+	}
+	if h.Config.DestinationBits > 0 {
+		lines = append(lines, "  Destination ID: "+strconv.Itoa(int(h.DestinationID)))
+	}
+	if h.Time != nil {
+		lines = append(lines, "  Time: "+h.Time.Time().UTC().Format(time.RFC3339Nano))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TCSecondaryHeader is the PUS TC packet secondary header. Config fixes
+// its layout; set it before decoding.
+type TCSecondaryHeader struct {
+	Config         Config
+	Version        uint8 // PUS version number
+	AckFlags       uint8 // Ack* flags
+	ServiceType    uint8
+	ServiceSubtype uint8
+	SourceID       uint16
+}
+
+// TCOption configures a TCSecondaryHeader.
+type TCOption func(*TCSecondaryHeader)
+
+// WithAckFlags sets the acknowledgement flags. Defaults to AckAll.
+func WithAckFlags(flags uint8) TCOption {
+	return func(h *TCSecondaryHeader) {
+		h.AckFlags = flags
+	}
+}
+
+// WithSourceID sets the source ID.
+func WithSourceID(id uint16) TCOption {
+	return func(h *TCSecondaryHeader) {
+		h.SourceID = id
+	}
+}
+
+// NewTCSecondaryHeader creates a TC secondary header for a request of
+// service type and subtype.
+func NewTCSecondaryHeader(cfg Config, serviceType, serviceSubtype uint8, opts ...TCOption) (*TCSecondaryHeader, error) {
+	h := &TCSecondaryHeader{
+		Config:         cfg,
+		Version:        cfg.Variant.VersionNumber(),
+		AckFlags:       AckAll,
+		ServiceType:    serviceType,
+		ServiceSubtype: serviceSubtype,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Size returns the encoded size in bytes.
+func (h *TCSecondaryHeader) Size() int { return h.Config.TCHeaderSize() }
+
+// Validate checks the header fields against the configuration.
+func (h *TCSecondaryHeader) Validate() error {
+	if err := h.Config.Validate(); err != nil {
+		return err
+	}
+	if h.Version >= 1<<h.Config.Variant.versionBits() || h.AckFlags > 0x0F {
+		return ErrFieldOverflow
+	}
+	if !fits(h.SourceID, h.Config.SourceBits) {
+		return ErrFieldOverflow
+	}
+	return nil
+}
+
+// Encode serializes the header.
+func (h *TCSecondaryHeader) Encode() ([]byte, error) {
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, h.Size())
+	out = append(out, h.Version<<4|h.AckFlags&0x0F, h.ServiceType, h.ServiceSubtype)
+	out = appendField(out, h.SourceID, h.Config.SourceBits)
+	return append(out, make([]byte, h.Config.TCSpareBits/8)...), nil
+}
+
+// Decode parses the header using h.Config.
+func (h *TCSecondaryHeader) Decode(data []byte) error {
+	cfg := h.Config
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(data) < cfg.TCHeaderSize() {
+		return ErrDataTooShort
+	}
+	v := cfg.Variant
+	version := data[0] >> 4 & (1<<v.versionBits() - 1)
+	if version != v.VersionNumber() {
+		return ErrVersionMismatch
+	}
+	*h = TCSecondaryHeader{
+		Config:         cfg,
+		Version:        version,
+		AckFlags:       data[0] & 0x0F,
+		ServiceType:    data[1],
+		ServiceSubtype: data[2],
+	}
+	h.SourceID, _ = readField(data, 3, cfg.SourceBits)
+	return nil
+}
+
+// Acknowledges reports whether the request asks for the verification
+// reports of stage flag.
+func (h *TCSecondaryHeader) Acknowledges(flag uint8) bool { return h.AckFlags&flag != 0 }
+
+// Humanize returns a human-readable representation of the header.
+func (h *TCSecondaryHeader) Humanize() string {
+	lines := []string{
+		"  PUS Version: " + strconv.Itoa(int(h.Version)) + " (" + h.Config.Variant.String() + ")",
+		"  Ack Flags: " + strconv.FormatUint(uint64(h.AckFlags)|0x10, 2)[1:],
+		"  Service: ST[" + strconv.Itoa(int(h.ServiceType)) + "," + strconv.Itoa(int(h.ServiceSubtype)) + "]",
+	}
+	if h.Config.SourceBits > 0 {
+		lines = append(lines, "  Source ID: "+strconv.Itoa(int(h.SourceID)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fits reports whether v fits in bits.
+func fits(v uint16, bits int) bool { return bits == 16 || v < 1<<bits }
+
+// appendField appends v as a big-endian field of bits (0, 8 or 16).
+func appendField(out []byte, v uint16, bits int) []byte {
+	switch bits {
+	case 8:
+		return append(out, uint8(v))
+	case 16:
+		return append(out, uint8(v>>8), uint8(v))
+	}
+	return out
+}
+
+// readField reads a big-endian field of bits at off and returns the
+// value and the offset after it.
+func readField(data []byte, off, bits int) (uint16, int) {
+	switch bits {
+	case 8:
+		return uint16(data[off]), off + 1
+	case 16:
+		return uint16(data[off])<<8 | uint16(data[off+1]), off + 2
+	}
+	return 0, off
+}
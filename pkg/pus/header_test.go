@@ -0,0 +1,152 @@
+package pus_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+func TestTMSecondaryHeader_PUSC(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	h, err := pus.NewTMSecondaryHeader(cfg, 3, 25, testTime,
+		pus.WithMessageCounter(0x1234), pus.WithDestinationID(7), pus.WithTimeRefStatus(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := h.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != h.Size() || h.Size() != 13 {
+		t.Fatalf("size = %d, encoded %d bytes", h.Size(), len(b))
+	}
+	if want := []byte{0x21, 3, 25, 0x12, 0x34, 0x00, 0x07}; !bytes.Equal(b[:7], want) {
+		t.Errorf("header % X, want % X", b[:7], want)
+	}
+
+	got := &pus.TMSecondaryHeader{Config: cfg}
+	if err := got.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.ServiceType != 3 || got.ServiceSubtype != 25 || got.MessageCounter != 0x1234 ||
+		got.DestinationID != 7 || got.TimeRefStatus != 1 || got.Version != 2 {
+		t.Errorf("decoded %+v", got)
+	}
+	if d := got.Time.Time().Sub(testTime); d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("time = %v, want %v", got.Time.Time(), testTime)
+	}
+}
+
+func TestTMSecondaryHeader_Layouts(t *testing.T) {
+	epoch := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		cfg   pus.Config
+		first byte
+		size  int
+	}{
+		{"PUS-A", pus.DefaultConfig(pus.PUSA), 0x00, 11},
+		{"PUS-B", pus.DefaultConfig(pus.PUSB), 0x10, 11},
+		{"PUS-C spare and P-field", pus.Config{
+			Variant:     pus.PUSC,
+			CounterBits: 16,
+			TMSpareBits: 8,
+			Time:        pus.TimeFormat{CoarseBytes: 4, FineBytes: 1, PField: true},
+		}, 0x20, 12},
+		{"PUS-C agency epoch", pus.Config{
+			Variant: pus.PUSC,
+			Time:    pus.TimeFormat{CoarseBytes: 3, Epoch: epoch},
+		}, 0x20, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := pus.NewTMSecondaryHeader(tt.cfg, 5, 1, testTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := h.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b) != tt.size || b[0] != tt.first {
+				t.Errorf("encoded % X, want %d bytes starting %02X", b, tt.size, tt.first)
+			}
+			got := &pus.TMSecondaryHeader{Config: tt.cfg}
+			if err := got.Decode(b); err != nil {
+				t.Fatal(err)
+			}
+			if got.ServiceType != 5 || got.ServiceSubtype != 1 || got.Time.CoarseTime != h.Time.CoarseTime {
+				t.Errorf("decoded %+v", got)
+			}
+		})
+	}
+}
+
+func TestTCSecondaryHeader(t *testing.T) {
+	for _, v := range []pus.Variant{pus.PUSA, pus.PUSB, pus.PUSC} {
+		cfg := pus.DefaultConfig(v)
+		cfg.TCSpareBits = 8
+		h, err := pus.NewTCSecondaryHeader(cfg, 17, 1,
+			pus.WithAckFlags(pus.AckAcceptance|pus.AckCompletion), pus.WithSourceID(42))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := h.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != h.Size() {
+			t.Errorf("%s: size %d, encoded %d", v, h.Size(), len(b))
+		}
+		if want := v.VersionNumber()<<4 | 0x09; b[0] != want {
+			t.Errorf("%s: first octet %02X, want %02X", v, b[0], want)
+		}
+		got := &pus.TCSecondaryHeader{Config: cfg}
+		if err := got.Decode(b); err != nil {
+			t.Fatal(err)
+		}
+		if got.SourceID != 42 || !got.Acknowledges(pus.AckCompletion) || got.Acknowledges(pus.AckStart) {
+			t.Errorf("%s: decoded %+v", v, got)
+		}
+	}
+}
+
+func TestSecondaryHeader_Errors(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	h, _ := pus.NewTCSecondaryHeader(cfg, 17, 1)
+	b, _ := h.Encode()
+
+	other := &pus.TCSecondaryHeader{Config: pus.DefaultConfig(pus.PUSB)}
+	if err := other.Decode(b); !errors.Is(err, pus.ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	short := &pus.TCSecondaryHeader{Config: cfg}
+	if err := short.Decode(b[:3]); !errors.Is(err, pus.ErrDataTooShort) {
+		t.Errorf("expected ErrDataTooShort, got %v", err)
+	}
+
+	narrow := pus.DefaultConfig(pus.PUSA)
+	if _, err := pus.NewTCSecondaryHeader(narrow, 17, 1, pus.WithSourceID(300)); !errors.Is(err, pus.ErrFieldOverflow) {
+		t.Errorf("expected ErrFieldOverflow, got %v", err)
+	}
+
+	bad := []struct {
+		cfg  pus.Config
+		want error
+	}{
+		{pus.Config{Time: pus.TimeFormat{CoarseBytes: 4}}, pus.ErrInvalidVariant},
+		{pus.Config{Variant: pus.PUSC, SourceBits: 12, Time: pus.TimeFormat{CoarseBytes: 4}}, pus.ErrInvalidFieldWidth},
+		{pus.Config{Variant: pus.PUSC, TMSpareBits: 4, Time: pus.TimeFormat{CoarseBytes: 4}}, pus.ErrInvalidSpare},
+		{pus.Config{Variant: pus.PUSC, Time: pus.TimeFormat{CoarseBytes: 5}}, pus.ErrInvalidTimeFormat},
+	}
+	for _, tt := range bad {
+		if err := tt.cfg.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.cfg, err, tt.want)
+		}
+	}
+}
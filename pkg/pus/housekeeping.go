@@ -0,0 +1,482 @@
+package pus
+
+import (
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// ServiceHousekeeping is the service type of ST[3] housekeeping.
+const ServiceHousekeeping uint8 = 3
+
+// ST[3] message subtypes (ECSS-E-ST-70-41C Section 6.3).
+const (
+	HKCreateStructure  uint8 = 1  // TC: create a housekeeping parameter report structure
+	HKDeleteStructures uint8 = 3  // TC: delete structures
+	HKEnablePeriodic   uint8 = 5  // TC: enable periodic generation
+	HKDisablePeriodic  uint8 = 6  // TC: disable periodic generation
+	HKParameterReport  uint8 = 25 // TM: housekeeping parameter report
+	HKGenerateOneShot  uint8 = 27 // TC: generate one-shot reports
+	HKModifyInterval   uint8 = 31 // TC: modify collection intervals
+)
+
+// HousekeepingStructure is a housekeeping parameter report structure.
+// Super-commutated sample groups are not supported: each report carries
+// one sample of each parameter.
+//
+// On the wire the structure ID and parameter IDs are 16 bits and the
+// collection interval 32 bits.
+type HousekeepingStructure struct {
+	ID       uint16
+	Interval uint32   // collection interval in minimum sampling intervals
+	Params   []uint16 // parameter IDs in report order
+	Enabled  bool     // periodic generation
+}
+
+// encode serializes the TC[3,1] application data.
+func (s HousekeepingStructure) encode() []byte {
+	out := binary.BigEndian.AppendUint16(nil, s.ID)
+	out = binary.BigEndian.AppendUint32(out, s.Interval)
+	return appendIDs(out, s.Params)
+}
+
+func decodeStructure(data []byte) (HousekeepingStructure, error) {
+	if len(data) < 6 {
+		return HousekeepingStructure{}, ErrInvalidRequest
+	}
+	s := HousekeepingStructure{
+		ID:       binary.BigEndian.Uint16(data),
+		Interval: binary.BigEndian.Uint32(data[2:]),
+	}
+	params, rest, err := readIDs(data[6:])
+	if err != nil {
+		return HousekeepingStructure{}, err
+	}
+	if len(rest) != 0 {
+		return HousekeepingStructure{}, ErrInvalidRequest
+	}
+	s.Params = params
+	return s, nil
+}
+
+// AddStructure defines a housekeeping structure. Its parameters must
+// be defined first.
+func (d *Dictionary) AddStructure(s HousekeepingStructure) error {
+	if s.Interval == 0 {
+		return ErrInvalidInterval
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.structures[s.ID]; ok {
+		return ErrDuplicateDefinition
+	}
+	if err := d.checkParams(s.Params); err != nil {
+		return err
+	}
+	s.Params = slices.Clone(s.Params)
+	d.structures[s.ID] = s
+	return nil
+}
+
+// RemoveStructure deletes a housekeeping structure definition.
+func (d *Dictionary) RemoveStructure(id uint16) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.structures, id)
+}
+
+// Structure returns the housekeeping structure id.
+func (d *Dictionary) Structure(id uint16) (HousekeepingStructure, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.structures[id]
+	return s, ok
+}
+
+// Structures returns every housekeeping structure ordered by ID.
+func (d *Dictionary) Structures() []HousekeepingStructure {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]HousekeepingStructure, 0, len(d.structures))
+	for _, s := range d.structures {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b HousekeepingStructure) int { return int(a.ID) - int(b.ID) })
+	return out
+}
+
+// HousekeepingReport is a decoded TM[3,25] housekeeping parameter report.
+type HousekeepingReport struct {
+	StructureID uint16
+	Values      []ParameterValue
+}
+
+// DecodeHousekeepingReport decodes the application data of a TM[3,25]
+// report using the structure definitions in d.
+func (d *Dictionary) DecodeHousekeepingReport(data []byte) (*HousekeepingReport, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidReport
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := &HousekeepingReport{StructureID: binary.BigEndian.Uint16(data)}
+	s, ok := d.structures[r.StructureID]
+	if !ok {
+		return nil, ErrUnknownStructure
+	}
+	values, rest, err := d.decodeValues(s.Params, data[2:])
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidReport
+	}
+	r.Values = values
+	return r, nil
+}
+
+// Value returns the value of the named parameter.
+func (r *HousekeepingReport) Value(name string) (any, bool) {
+	return lookupValue(r.Values, name)
+}
+
+// Humanize returns a human-readable representation of the report.
+func (r *HousekeepingReport) Humanize() string {
+	lines := []string{"  Structure ID: " + strconv.Itoa(int(r.StructureID))}
+	for _, v := range r.Values {
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lookupValue(values []ParameterValue, name string) (any, bool) {
+	for _, v := range values {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return nil, false
+}
+
+// NewCreateHousekeepingCommand builds TC[3,1] creating s. The ground
+// should add s to its Dictionary to decode the resulting reports.
+func NewCreateHousekeepingCommand(cfg Config, apid uint16, s HousekeepingStructure, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceHousekeeping, HKCreateStructure, s.encode(), opts)
+}
+
+// NewDeleteHousekeepingCommand builds TC[3,3] deleting structures ids.
+func NewDeleteHousekeepingCommand(cfg Config, apid uint16, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceHousekeeping, HKDeleteStructures, appendIDs(nil, ids), opts)
+}
+
+// NewEnableHousekeepingCommand builds TC[3,5] enabling periodic
+// generation of structures ids.
+func NewEnableHousekeepingCommand(cfg Config, apid uint16, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceHousekeeping, HKEnablePeriodic, appendIDs(nil, ids), opts)
+}
+
+// NewDisableHousekeepingCommand builds TC[3,6] disabling periodic
+// generation of structures ids.
+func NewDisableHousekeepingCommand(cfg Config, apid uint16, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceHousekeeping, HKDisablePeriodic, appendIDs(nil, ids), opts)
+}
+
+// NewOneShotHousekeepingCommand builds TC[3,27] generating one report
+// of each structure in ids.
+func NewOneShotHousekeepingCommand(cfg Config, apid uint16, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceHousekeeping, HKGenerateOneShot, appendIDs(nil, ids), opts)
+}
+
+// NewModifyHousekeepingIntervalCommand builds TC[3,31] setting the
+// collection interval of structure id.
+func NewModifyHousekeepingIntervalCommand(cfg Config, apid uint16, id uint16, interval uint32, opts ...TCOption) (*spp.SpacePacket, error) {
+	data := binary.BigEndian.AppendUint16(nil, 1)
+	data = binary.BigEndian.AppendUint16(data, id)
+	data = binary.BigEndian.AppendUint32(data, interval)
+	return newCommand(cfg, apid, ServiceHousekeeping, HKModifyInterval, data, opts)
+}
+
+type hkStructure struct {
+	HousekeepingStructure
+	countdown uint32 // minimum sampling intervals until the next report
+}
+
+// Housekeeping is the on-board ST[3] housekeeping service. It holds the
+// current parameter values, executes ST[3] requests and generates
+// TM[3,25] reports.
+//
+// The flight software calls Tick once per minimum sampling interval; a
+// structure with periodic generation enabled is reported every
+// Interval ticks, the first report one interval after it is enabled.
+type Housekeeping struct {
+	mu         sync.Mutex
+	dict       *Dictionary
+	rep        reporter
+	structures map[uint16]*hkStructure
+	values     map[uint16][]byte
+}
+
+// NewHousekeeping creates the housekeeping service of application apid
+// with the parameters and structures of dict.
+func NewHousekeeping(cfg Config, apid uint16, dict *Dictionary, opts ...ReporterOption) *Housekeeping {
+	h := &Housekeeping{
+		dict:       dict,
+		rep:        newReporter(cfg, apid, opts),
+		structures: make(map[uint16]*hkStructure),
+		values:     make(map[uint16][]byte),
+	}
+	for _, s := range dict.Structures() {
+		h.structures[s.ID] = &hkStructure{HousekeepingStructure: s, countdown: s.Interval}
+	}
+	return h
+}
+
+// Set updates the current value of parameter id.
+func (h *Housekeeping) Set(id uint16, v any) error {
+	p, ok := h.dict.Parameter(id)
+	if !ok {
+		return ErrUnknownParameter
+	}
+	b, err := p.Type.Encode(nil, v)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.values[id] = b
+	return nil
+}
+
+// Value returns the current value of parameter id. Parameters never
+// set read as zero.
+func (h *Housekeeping) Value(id uint16) (any, bool) {
+	p, ok := h.dict.Parameter(id)
+	if !ok {
+		return nil, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, err := p.Type.Decode(h.value(p))
+	return v, err == nil
+}
+
+func (h *Housekeeping) value(p Parameter) []byte {
+	if b, ok := h.values[p.ID]; ok {
+		return b
+	}
+	return make([]byte, p.Type.Size())
+}
+
+// Create adds a housekeeping structure. Periodic generation starts if
+// s.Enabled is set.
+func (h *Housekeeping) Create(s HousekeepingStructure) error {
+	if s.Interval == 0 {
+		return ErrInvalidInterval
+	}
+	for _, id := range s.Params {
+		if _, ok := h.dict.Parameter(id); !ok {
+			return ErrUnknownParameter
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.structures[s.ID]; ok {
+		return ErrDuplicateDefinition
+	}
+	s.Params = slices.Clone(s.Params)
+	h.structures[s.ID] = &hkStructure{HousekeepingStructure: s, countdown: s.Interval}
+	return nil
+}
+
+// Delete removes structures. Structures with periodic generation
+// enabled cannot be deleted; nothing is deleted if any ID is rejected.
+func (h *Housekeeping) Delete(ids ...uint16) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range ids {
+		s, ok := h.structures[id]
+		if !ok {
+			return ErrUnknownStructure
+		}
+		if s.Enabled {
+			return ErrStructureEnabled
+		}
+	}
+	for _, id := range ids {
+		delete(h.structures, id)
+	}
+	return nil
+}
+
+// Enable starts periodic generation of structures ids.
+func (h *Housekeeping) Enable(ids ...uint16) error {
+	return h.setEnabled(ids, true)
+}
+
+// Disable stops periodic generation of structures ids.
+func (h *Housekeeping) Disable(ids ...uint16) error {
+	return h.setEnabled(ids, false)
+}
+
+func (h *Housekeeping) setEnabled(ids []uint16, enabled bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := h.structures[id]; !ok {
+			return ErrUnknownStructure
+		}
+	}
+	for _, id := range ids {
+		s := h.structures[id]
+		if enabled && !s.Enabled {
+			s.countdown = s.Interval
+		}
+		s.Enabled = enabled
+	}
+	return nil
+}
+
+// ModifyInterval sets the collection interval of structure id and
+// restarts its countdown.
+func (h *Housekeeping) ModifyInterval(id uint16, interval uint32) error {
+	if interval == 0 {
+		return ErrInvalidInterval
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.structures[id]
+	if !ok {
+		return ErrUnknownStructure
+	}
+	s.Interval, s.countdown = interval, interval
+	return nil
+}
+
+// Structure returns the structure id.
+func (h *Housekeeping) Structure(id uint16) (HousekeepingStructure, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.structures[id]
+	if !ok {
+		return HousekeepingStructure{}, false
+	}
+	out := s.HousekeepingStructure
+	out.Params = slices.Clone(out.Params)
+	return out, true
+}
+
+// Report generates a TM[3,25] report of structure id now, whether or
+// not periodic generation is enabled.
+func (h *Housekeeping) Report(id uint16) (*spp.SpacePacket, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.structures[id]
+	if !ok {
+		return nil, ErrUnknownStructure
+	}
+	return h.report(s)
+}
+
+func (h *Housekeeping) report(s *hkStructure) (*spp.SpacePacket, error) {
+	data := binary.BigEndian.AppendUint16(nil, s.ID)
+	for _, id := range s.Params {
+		p, _ := h.dict.Parameter(id)
+		data = append(data, h.value(p)...)
+	}
+	return h.rep.report(ServiceHousekeeping, HKParameterReport, data)
+}
+
+// Tick advances one minimum sampling interval and returns the reports
+// that fell due, ordered by structure ID.
+func (h *Housekeeping) Tick() ([]*spp.SpacePacket, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var due []*hkStructure
+	for _, s := range h.structures {
+		if !s.Enabled {
+			continue
+		}
+		if s.countdown--; s.countdown == 0 {
+			s.countdown = s.Interval
+			due = append(due, s)
+		}
+	}
+	slices.SortFunc(due, func(a, b *hkStructure) int { return int(a.ID) - int(b.ID) })
+	var out []*spp.SpacePacket
+	for _, s := range due {
+		pkt, err := h.report(s)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, pkt)
+	}
+	return out, nil
+}
+
+// Handle executes an ST[3] request and returns the reports it
+// generates.
+func (h *Housekeeping) Handle(tc *spp.SpacePacket) ([]*spp.SpacePacket, error) {
+	sst, data, err := request(tc, ServiceHousekeeping)
+	if err != nil {
+		return nil, err
+	}
+	switch sst {
+	case HKCreateStructure:
+		s, err := decodeStructure(data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, h.Create(s)
+	case HKModifyInterval:
+		return nil, h.modifyIntervals(data)
+	}
+
+	ids, rest, err := readIDs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidRequest
+	}
+	switch sst {
+	case HKDeleteStructures:
+		return nil, h.Delete(ids...)
+	case HKEnablePeriodic:
+		return nil, h.Enable(ids...)
+	case HKDisablePeriodic:
+		return nil, h.Disable(ids...)
+	case HKGenerateOneShot:
+		var out []*spp.SpacePacket
+		for _, id := range ids {
+			pkt, err := h.Report(id)
+			if err != nil {
+				return out, err
+			}
+			out = append(out, pkt)
+		}
+		return out, nil
+	}
+	return nil, ErrInvalidSubtype
+}
+
+func (h *Housekeeping) modifyIntervals(data []byte) error {
+	if len(data) < 2 {
+		return ErrInvalidRequest
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) != 6*n {
+		return ErrInvalidRequest
+	}
+	for i := 0; i < n; i++ {
+		rec := data[6*i:]
+		if err := h.ModifyInterval(binary.BigEndian.Uint16(rec), binary.BigEndian.Uint32(rec[2:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
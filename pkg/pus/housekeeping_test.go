@@ -0,0 +1,176 @@
+package pus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+func newTestDictionary(t *testing.T) *pus.Dictionary {
+	t.Helper()
+	d, err := pus.NewDictionary(
+		pus.Parameter{ID: 1, Name: "BAT_V", Type: pus.ParamFloat32},
+		pus.Parameter{ID: 2, Name: "MODE", Type: pus.ParamUint8},
+		pus.Parameter{ID: 3, Name: "TEMP", Type: pus.ParamInt16},
+		pus.Parameter{ID: 4, Name: "HEATER", Type: pus.ParamBool},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// downlink round-trips a TM packet and returns its decoded form.
+func downlink(t *testing.T, cfg pus.Config, pkt *spp.SpacePacket) *spp.SpacePacket {
+	t.Helper()
+	b, err := pus.Encode(pkt, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pus.Decode(b, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestHousekeeping_Periodic(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	d := newTestDictionary(t)
+	if err := d.AddStructure(pus.HousekeepingStructure{ID: 10, Interval: 2, Params: []uint16{1, 2, 3}, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	clk := &fakeClock{now: testTime}
+	hk := pus.NewHousekeeping(cfg, 0x20, d, pus.WithReportClock(clk), pus.WithDestination(7))
+	_ = hk.Set(1, 28.5)
+	_ = hk.Set(2, 3)
+	_ = hk.Set(3, -40)
+
+	if out, _ := hk.Tick(); len(out) != 0 {
+		t.Fatalf("report before interval: %d", len(out))
+	}
+	var reports []*spp.SpacePacket
+	for range 3 {
+		out, err := hk.Tick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, out...)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("%d reports in 4 ticks, want 2", len(reports))
+	}
+
+	for i, pkt := range reports {
+		got := downlink(t, cfg, pkt)
+		h := got.SecondaryHeader.(*pus.TMSecondaryHeader)
+		if h.ServiceType != pus.ServiceHousekeeping || h.ServiceSubtype != pus.HKParameterReport ||
+			h.MessageCounter != uint16(i) || h.DestinationID != 7 {
+			t.Errorf("report %d header %+v", i, h)
+		}
+		r, err := d.DecodeHousekeepingReport(got.UserData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := r.Value("BAT_V"); v != float32(28.5) {
+			t.Errorf("BAT_V = %v", v)
+		}
+		if v, _ := r.Value("TEMP"); v != int16(-40) {
+			t.Errorf("TEMP = %v", v)
+		}
+		if r.StructureID != 10 || len(r.Values) != 3 {
+			t.Errorf("report %+v", r)
+		}
+	}
+}
+
+func TestHousekeeping_Requests(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	d := newTestDictionary(t)
+	hk := pus.NewHousekeeping(cfg, 0x20, d)
+	_ = hk.Set(4, true)
+
+	s := pus.HousekeepingStructure{ID: 5, Interval: 3, Params: []uint16{4, 2}}
+	handle := func(tc *spp.SpacePacket, err error) []*spp.SpacePacket {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := hk.Handle(tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	handle(pus.NewCreateHousekeepingCommand(cfg, 0x20, s))
+	if err := d.AddStructure(s); err != nil { // ground side
+		t.Fatal(err)
+	}
+	handle(pus.NewEnableHousekeepingCommand(cfg, 0x20, []uint16{5}))
+	handle(pus.NewModifyHousekeepingIntervalCommand(cfg, 0x20, 5, 1))
+	if got, _ := hk.Structure(5); !got.Enabled || got.Interval != 1 {
+		t.Errorf("structure %+v", got)
+	}
+	if out, _ := hk.Tick(); len(out) != 1 {
+		t.Errorf("%d reports after interval change, want 1", len(out))
+	}
+
+	out := handle(pus.NewOneShotHousekeepingCommand(cfg, 0x20, []uint16{5}))
+	if len(out) != 1 {
+		t.Fatalf("%d one-shot reports", len(out))
+	}
+	r, err := d.DecodeHousekeepingReport(downlink(t, cfg, out[0]).UserData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Value("HEATER"); v != true {
+		t.Errorf("HEATER = %v", v)
+	}
+
+	del, _ := pus.NewDeleteHousekeepingCommand(cfg, 0x20, []uint16{5})
+	if _, err := hk.Handle(del); !errors.Is(err, pus.ErrStructureEnabled) {
+		t.Errorf("expected ErrStructureEnabled, got %v", err)
+	}
+	handle(pus.NewDisableHousekeepingCommand(cfg, 0x20, []uint16{5}))
+	handle(del, nil)
+	if _, ok := hk.Structure(5); ok {
+		t.Error("structure not deleted")
+	}
+}
+
+func TestHousekeeping_Errors(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	d := newTestDictionary(t)
+	hk := pus.NewHousekeeping(cfg, 0x20, d)
+
+	if err := hk.Set(2, 300); !errors.Is(err, pus.ErrParameterType) {
+		t.Errorf("expected ErrParameterType, got %v", err)
+	}
+	if err := hk.Set(99, 1); !errors.Is(err, pus.ErrUnknownParameter) {
+		t.Errorf("expected ErrUnknownParameter, got %v", err)
+	}
+	if err := hk.Create(pus.HousekeepingStructure{ID: 1, Params: []uint16{1}}); !errors.Is(err, pus.ErrInvalidInterval) {
+		t.Errorf("expected ErrInvalidInterval, got %v", err)
+	}
+	_ = hk.Create(pus.HousekeepingStructure{ID: 1, Interval: 1, Params: []uint16{1}})
+	if err := hk.Create(pus.HousekeepingStructure{ID: 1, Interval: 1}); !errors.Is(err, pus.ErrDuplicateDefinition) {
+		t.Errorf("expected ErrDuplicateDefinition, got %v", err)
+	}
+	if err := hk.Enable(1, 2); !errors.Is(err, pus.ErrUnknownStructure) {
+		t.Errorf("expected ErrUnknownStructure, got %v", err)
+	}
+	if s, _ := hk.Structure(1); s.Enabled {
+		t.Error("partially rejected enable changed state")
+	}
+
+	ev, _ := pus.NewEnableEventsCommand(cfg, 0x20, []uint16{1})
+	if _, err := hk.Handle(ev); !errors.Is(err, pus.ErrServiceMismatch) {
+		t.Errorf("expected ErrServiceMismatch, got %v", err)
+	}
+	if _, err := d.DecodeHousekeepingReport([]byte{0, 1, 0}); !errors.Is(err, pus.ErrUnknownStructure) {
+		t.Errorf("expected ErrUnknownStructure, got %v", err)
+	}
+}
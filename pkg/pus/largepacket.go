@@ -0,0 +1,418 @@
+package pus
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// ServiceLargePacket is the service type of ST[13] large packet transfer.
+const ServiceLargePacket uint8 = 13
+
+// ST[13] message subtypes (ECSS-E-ST-70-41C Section 6.13).
+const (
+	LargeDownFirst        uint8 = 1  // TM: first downlink part report
+	LargeDownIntermediate uint8 = 2  // TM: intermediate downlink part report
+	LargeDownLast         uint8 = 3  // TM: last downlink part report
+	LargeUpFirst          uint8 = 9  // TC: first uplink part
+	LargeUpIntermediate   uint8 = 10 // TC: intermediate uplink part
+	LargeUpLast           uint8 = 11 // TC: last uplink part
+	LargeUpAbort          uint8 = 16 // TM: large packet uplink abortion report
+)
+
+// largePartHeaderSize is the transaction ID (16) and part sequence number (32).
+const largePartHeaderSize = 6
+
+// PartPosition is the place of a part in a large message.
+type PartPosition uint8
+
+// Part positions.
+const (
+	PartFirst PartPosition = iota + 1
+	PartIntermediate
+	PartLast
+)
+
+// String returns the position name.
+func (p PartPosition) String() string {
+	switch p {
+	case PartFirst:
+		return "First"
+	case PartIntermediate:
+		return "Intermediate"
+	case PartLast:
+		return "Last"
+	default:
+		return "Unknown"
+	}
+}
+
+// downSubtype returns the TM subtype carrying a part at position p.
+func (p PartPosition) downSubtype() uint8 { return LargeDownFirst + uint8(p) - 1 }
+
+// upSubtype returns the TC subtype carrying a part at position p.
+func (p PartPosition) upSubtype() uint8 { return LargeUpFirst + uint8(p) - 1 }
+
+// LargePart is one part of a large message. Parts of a transaction are
+// numbered from 1 and sent in order.
+type LargePart struct {
+	Position       PartPosition
+	TransactionID  uint16
+	SequenceNumber uint32
+	Data           []byte
+}
+
+// Encode returns the application data of the part: transaction ID
+// (16), part sequence number (32) and the part data.
+func (p *LargePart) Encode() []byte {
+	out := binary.BigEndian.AppendUint16(nil, p.TransactionID)
+	out = binary.BigEndian.AppendUint32(out, p.SequenceNumber)
+	return append(out, p.Data...)
+}
+
+// Humanize returns a human-readable representation of the part.
+func (p *LargePart) Humanize() string {
+	return strings.Join([]string{
+		"  Position: " + p.Position.String(),
+		"  Transaction ID: " + strconv.Itoa(int(p.TransactionID)),
+		"  Sequence Number: " + strconv.FormatUint(uint64(p.SequenceNumber), 10),
+		"  Data Length: " + strconv.Itoa(len(p.Data)),
+	}, "\n")
+}
+
+// DecodeLargePart decodes the application data of an ST[13] downlink
+// part report or uplink part request of the given subtype.
+func DecodeLargePart(subtype uint8, data []byte) (*LargePart, error) {
+	var pos PartPosition
+	switch {
+	case subtype >= LargeDownFirst && subtype <= LargeDownLast:
+		pos = PartPosition(subtype - LargeDownFirst + 1)
+	case subtype >= LargeUpFirst && subtype <= LargeUpLast:
+		pos = PartPosition(subtype - LargeUpFirst + 1)
+	default:
+		return nil, ErrInvalidSubtype
+	}
+	if len(data) < largePartHeaderSize {
+		return nil, ErrInvalidReport
+	}
+	return &LargePart{
+		Position:       pos,
+		TransactionID:  binary.BigEndian.Uint16(data),
+		SequenceNumber: binary.BigEndian.Uint32(data[2:]),
+		Data:           slices.Clone(data[largePartHeaderSize:]),
+	}, nil
+}
+
+// SplitLarge splits data into the parts of transaction id, each
+// carrying at most partSize bytes. A message is always at least a first
+// and a last part; when data fits a single part the last part is empty.
+func SplitLarge(id uint16, data []byte, partSize int) ([]LargePart, error) {
+	if partSize <= 0 {
+		return nil, ErrInvalidPartSize
+	}
+	n := max(2, (len(data)+partSize-1)/partSize)
+	parts := make([]LargePart, n)
+	for i := range parts {
+		lo := min(i*partSize, len(data))
+		hi := min(lo+partSize, len(data))
+		parts[i] = LargePart{
+			Position:       PartIntermediate,
+			TransactionID:  id,
+			SequenceNumber: uint32(i + 1),
+			Data:           data[lo:hi],
+		}
+	}
+	parts[0].Position = PartFirst
+	parts[n-1].Position = PartLast
+	return parts, nil
+}
+
+// NewLargeUplinkCommands splits data into the TC[13,9], TC[13,10] and
+// TC[13,11] requests of transaction id, in sending order.
+func NewLargeUplinkCommands(cfg Config, apid, id uint16, data []byte, partSize int, opts ...TCOption) ([]*spp.SpacePacket, error) {
+	parts, err := SplitLarge(id, data, partSize)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*spp.SpacePacket, len(parts))
+	for i := range parts {
+		if out[i], err = newCommand(cfg, apid, ServiceLargePacket, parts[i].Position.upSubtype(), parts[i].Encode(), opts); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// AbortReason is the failure reason of a TM[13,16] uplink abortion report.
+type AbortReason uint16
+
+// Abort reasons.
+const (
+	AbortTimeout  AbortReason = iota + 1 // no part received within the reception timeout
+	AbortSequence                        // part out of sequence
+	AbortTooLarge                        // message exceeds the maximum size
+)
+
+// String returns the reason name.
+func (r AbortReason) String() string {
+	switch r {
+	case AbortTimeout:
+		return "Timeout"
+	case AbortSequence:
+		return "Sequence"
+	case AbortTooLarge:
+		return "TooLarge"
+	default:
+		return "Unknown(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// LargeAbortReport is a decoded TM[13,16] uplink abortion report.
+type LargeAbortReport struct {
+	TransactionID uint16
+	Reason        AbortReason
+}
+
+// Encode returns the application data of the report: transaction ID
+// (16) and failure reason (16).
+func (r *LargeAbortReport) Encode() []byte {
+	out := binary.BigEndian.AppendUint16(nil, r.TransactionID)
+	return binary.BigEndian.AppendUint16(out, uint16(r.Reason))
+}
+
+// DecodeLargeAbortReport decodes the application data of a TM[13,16] report.
+func DecodeLargeAbortReport(data []byte) (*LargeAbortReport, error) {
+	if len(data) != 4 {
+		return nil, ErrInvalidReport
+	}
+	return &LargeAbortReport{
+		TransactionID: binary.BigEndian.Uint16(data),
+		Reason:        AbortReason(binary.BigEndian.Uint16(data[2:])),
+	}, nil
+}
+
+// ReassemblerOption configures a Reassembler.
+type ReassemblerOption func(*Reassembler)
+
+// WithReassemblyClock sets the clock that times part reception.
+func WithReassemblyClock(c Clock) ReassemblerOption {
+	return func(r *Reassembler) {
+		r.clock = c
+	}
+}
+
+// WithMaxMessageSize limits the size of a reassembled message. A
+// transaction that grows beyond n bytes is aborted.
+func WithMaxMessageSize(n int) ReassemblerOption {
+	return func(r *Reassembler) {
+		r.maxSize = n
+	}
+}
+
+type transaction struct {
+	next     uint32
+	data     []byte
+	deadline time.Time
+}
+
+// Reassembler rebuilds large messages from their parts. The ground uses
+// it on ST[13] downlink part reports and LargeUplink on uplink parts.
+//
+// A first part opens a transaction, replacing any open transaction with
+// the same ID. Each later part must carry the next sequence number; an
+// out-of-sequence part aborts the transaction. A transaction with no
+// part within the reception timeout is aborted by CheckTimeouts.
+type Reassembler struct {
+	mu      sync.Mutex
+	clock   Clock
+	timeout time.Duration
+	maxSize int
+	open    map[uint16]*transaction
+}
+
+// NewReassembler creates a reassembler aborting transactions that
+// receive no part within timeout. A zero timeout never aborts.
+func NewReassembler(timeout time.Duration, opts ...ReassemblerOption) *Reassembler {
+	r := &Reassembler{
+		clock:   systemClock{},
+		timeout: timeout,
+		open:    make(map[uint16]*transaction),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Add adds a part and returns the complete message when p is the last
+// part of its transaction, or nil.
+func (r *Reassembler) Add(p *LargePart) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tx, ok := r.open[p.TransactionID]
+	if p.Position == PartFirst {
+		tx, ok = &transaction{next: p.SequenceNumber}, true
+		r.open[p.TransactionID] = tx
+	}
+	if !ok {
+		return nil, ErrUnknownTransaction
+	}
+	if p.SequenceNumber != tx.next {
+		delete(r.open, p.TransactionID)
+		return nil, ErrPartSequence
+	}
+	if r.maxSize > 0 && len(tx.data)+len(p.Data) > r.maxSize {
+		delete(r.open, p.TransactionID)
+		return nil, ErrMessageTooLarge
+	}
+	tx.data = append(tx.data, p.Data...)
+	tx.next++
+	if p.Position == PartLast {
+		delete(r.open, p.TransactionID)
+		return tx.data, nil
+	}
+	if r.timeout > 0 {
+		tx.deadline = r.clock.Now().Add(r.timeout)
+	}
+	return nil, nil
+}
+
+// CheckTimeouts aborts the transactions whose reception timeout has
+// expired and returns their IDs in ascending order.
+func (r *Reassembler) CheckTimeouts() []uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock.Now()
+	var expired []uint16
+	for id, tx := range r.open {
+		if !tx.deadline.IsZero() && !now.Before(tx.deadline) {
+			expired = append(expired, id)
+			delete(r.open, id)
+		}
+	}
+	slices.Sort(expired)
+	return expired
+}
+
+// Pending returns the IDs of the open transactions in ascending order.
+func (r *Reassembler) Pending() []uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]uint16, 0, len(r.open))
+	for id := range r.open {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// LargeDownlink is the on-board ST[13] large packet downlink service.
+// It splits messages too large for one packet into downlink part
+// reports, numbering transactions from 1.
+type LargeDownlink struct {
+	mu       sync.Mutex
+	rep      reporter
+	partSize int
+	next     uint16
+}
+
+// NewLargeDownlink creates the large packet downlink service of
+// application apid sending at most partSize bytes per part.
+func NewLargeDownlink(cfg Config, apid uint16, partSize int, opts ...ReporterOption) *LargeDownlink {
+	return &LargeDownlink{rep: newReporter(cfg, apid, opts), partSize: partSize, next: 1}
+}
+
+// Send returns the TM[13,1], TM[13,2] and TM[13,3] reports carrying
+// data, in sending order.
+func (d *LargeDownlink) Send(data []byte) ([]*spp.SpacePacket, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	parts, err := SplitLarge(d.next, data, d.partSize)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*spp.SpacePacket, len(parts))
+	for i := range parts {
+		if out[i], err = d.rep.report(ServiceLargePacket, parts[i].Position.downSubtype(), parts[i].Encode()); err != nil {
+			return nil, err
+		}
+	}
+	d.next++
+	return out, nil
+}
+
+// LargeUplink is the on-board ST[13] large packet uplink service. It
+// reassembles TC[13,9] to TC[13,11] requests and reports each aborted
+// transaction with TM[13,16].
+type LargeUplink struct {
+	mu  sync.Mutex
+	rep reporter
+	r   *Reassembler
+}
+
+// NewLargeUplink creates the large packet uplink service of application
+// apid reassembling messages with r.
+func NewLargeUplink(cfg Config, apid uint16, r *Reassembler, opts ...ReporterOption) *LargeUplink {
+	return &LargeUplink{rep: newReporter(cfg, apid, opts), r: r}
+}
+
+// Handle executes an ST[13] uplink part request. It returns the
+// reassembled message when tc carries the last part. A part out of
+// sequence or beyond the maximum message size aborts its transaction:
+// Handle returns the abortion report together with ErrPartSequence or
+// ErrMessageTooLarge.
+func (u *LargeUplink) Handle(tc *spp.SpacePacket) ([]byte, []*spp.SpacePacket, error) {
+	sst, data, err := request(tc, ServiceLargePacket)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sst < LargeUpFirst || sst > LargeUpLast {
+		return nil, nil, ErrInvalidSubtype
+	}
+	p, err := DecodeLargePart(sst, data)
+	if err != nil {
+		return nil, nil, ErrInvalidRequest
+	}
+	msg, err := u.r.Add(p)
+	var reason AbortReason
+	switch {
+	case errors.Is(err, ErrPartSequence):
+		reason = AbortSequence
+	case errors.Is(err, ErrMessageTooLarge):
+		reason = AbortTooLarge
+	default:
+		return msg, nil, err
+	}
+	pkt, rerr := u.abort(p.TransactionID, reason)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+	return nil, []*spp.SpacePacket{pkt}, err
+}
+
+// CheckTimeouts aborts the transactions whose reception timeout has
+// expired and returns their abortion reports.
+func (u *LargeUplink) CheckTimeouts() ([]*spp.SpacePacket, error) {
+	var out []*spp.SpacePacket
+	for _, id := range u.r.CheckTimeouts() {
+		pkt, err := u.abort(id, AbortTimeout)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, pkt)
+	}
+	return out, nil
+}
+
+func (u *LargeUplink) abort(id uint16, reason AbortReason) (*spp.SpacePacket, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	r := LargeAbortReport{TransactionID: id, Reason: reason}
+	return u.rep.report(ServiceLargePacket, LargeUpAbort, r.Encode())
+}
@@ -0,0 +1,151 @@
+package pus_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+func TestSplitLarge(t *testing.T) {
+	parts, err := pus.SplitLarge(7, []byte("abcdefgh"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 || parts[0].Position != pus.PartFirst || parts[1].Position != pus.PartIntermediate ||
+		parts[2].Position != pus.PartLast || string(parts[2].Data) != "gh" || parts[2].SequenceNumber != 3 {
+		t.Fatalf("parts %+v", parts)
+	}
+
+	small, _ := pus.SplitLarge(7, []byte("ab"), 3)
+	if len(small) != 2 || string(small[0].Data) != "ab" || len(small[1].Data) != 0 {
+		t.Errorf("single-part message split as %+v", small)
+	}
+	if _, err := pus.SplitLarge(7, nil, 0); !errors.Is(err, pus.ErrInvalidPartSize) {
+		t.Errorf("expected ErrInvalidPartSize, got %v", err)
+	}
+}
+
+func TestLargeDownlink_Reassemble(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	msg := bytes.Repeat([]byte("payload-product/"), 20)
+	d := pus.NewLargeDownlink(cfg, 0x20, 64)
+	pkts, err := d.Send(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkts) != 5 {
+		t.Fatalf("%d parts", len(pkts))
+	}
+
+	r := pus.NewReassembler(time.Minute)
+	var got []byte
+	for i, pkt := range pkts {
+		pkt = downlink(t, cfg, pkt)
+		st, sst, _ := pus.Service(pkt)
+		if st != pus.ServiceLargePacket {
+			t.Fatalf("service %d", st)
+		}
+		p, err := pus.DecodeLargePart(sst, pkt.UserData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.TransactionID != 1 {
+			t.Errorf("transaction %d", p.TransactionID)
+		}
+		got, err = r.Add(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got != nil) != (i == len(pkts)-1) {
+			t.Fatalf("message returned after part %d", i+1)
+		}
+	}
+	if !bytes.Equal(got, msg) {
+		t.Error("reassembled message differs")
+	}
+	if len(r.Pending()) != 0 {
+		t.Error("transaction still open")
+	}
+
+	next, _ := d.Send([]byte("x"))
+	p, _ := pus.DecodeLargePart(pus.LargeDownFirst, next[0].UserData)
+	if p.TransactionID != 2 {
+		t.Errorf("second transaction ID %d", p.TransactionID)
+	}
+}
+
+func TestReassembler_Errors(t *testing.T) {
+	clk := &fakeClock{now: testTime}
+	r := pus.NewReassembler(10*time.Second, pus.WithReassemblyClock(clk), pus.WithMaxMessageSize(4))
+	parts, _ := pus.SplitLarge(1, []byte("abcdef"), 2)
+
+	if _, err := r.Add(&parts[1]); !errors.Is(err, pus.ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+	_, _ = r.Add(&parts[0])
+	if _, err := r.Add(&parts[2]); !errors.Is(err, pus.ErrPartSequence) {
+		t.Errorf("expected ErrPartSequence, got %v", err)
+	}
+	_, _ = r.Add(&parts[0])
+	_, _ = r.Add(&parts[1])
+	if _, err := r.Add(&parts[2]); !errors.Is(err, pus.ErrMessageTooLarge) {
+		t.Errorf("expected ErrMessageTooLarge, got %v", err)
+	}
+
+	_, _ = r.Add(&parts[0])
+	clk.Advance(5 * time.Second)
+	if ids := r.CheckTimeouts(); len(ids) != 0 {
+		t.Errorf("aborted %v before the timeout", ids)
+	}
+	clk.Advance(5 * time.Second)
+	if ids := r.CheckTimeouts(); len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("aborted %v, want [1]", ids)
+	}
+}
+
+func TestLargeUplink(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	clk := &fakeClock{now: testTime}
+	u := pus.NewLargeUplink(cfg, 0x20, pus.NewReassembler(30*time.Second, pus.WithReassemblyClock(clk)))
+
+	msg := []byte("on-board software patch")
+	tcs, err := pus.NewLargeUplinkCommands(cfg, 0x20, 9, msg, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	for _, tc := range tcs {
+		if got, _, err = u.Handle(tc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("uplinked %q", got)
+	}
+
+	_, _, _ = u.Handle(tcs[0])
+	_, reports, err := u.Handle(tcs[2])
+	if !errors.Is(err, pus.ErrPartSequence) || len(reports) != 1 {
+		t.Fatalf("out of sequence: %d reports, %v", len(reports), err)
+	}
+	abort, err := pus.DecodeLargeAbortReport(reports[0].UserData)
+	if err != nil || abort.TransactionID != 9 || abort.Reason != pus.AbortSequence {
+		t.Errorf("abort report %+v, %v", abort, err)
+	}
+
+	_, _, _ = u.Handle(tcs[0])
+	clk.Advance(time.Minute)
+	reports, err = u.CheckTimeouts()
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("timeout: %d reports, %v", len(reports), err)
+	}
+	if _, sst, _ := pus.Service(reports[0]); sst != pus.LargeUpAbort {
+		t.Errorf("subtype %d", sst)
+	}
+	if abort, _ := pus.DecodeLargeAbortReport(reports[0].UserData); abort.Reason != pus.AbortTimeout {
+		t.Errorf("reason %s", abort.Reason)
+	}
+}
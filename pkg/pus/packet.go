@@ -0,0 +1,102 @@
+package pus
+
+import (
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// NewTMPacket creates a TM Space Packet carrying h and data. Add the
+// packet error control selected by h.Config with Encode.
+func NewTMPacket(apid uint16, h *TMSecondaryHeader, data []byte, opts ...spp.PacketOption) (*spp.SpacePacket, error) {
+	return newPacket(apid, spp.PacketTypeTM, h, h.Config, data, opts)
+}
+
+// NewTCPacket creates a TC Space Packet carrying h and data. Add the
+// packet error control selected by h.Config with Encode.
+func NewTCPacket(apid uint16, h *TCSecondaryHeader, data []byte, opts ...spp.PacketOption) (*spp.SpacePacket, error) {
+	return newPacket(apid, spp.PacketTypeTC, h, h.Config, data, opts)
+}
+
+func newPacket(apid uint16, typ uint8, h spp.SecondaryHeader, cfg Config, data []byte, opts []spp.PacketOption) (*spp.SpacePacket, error) {
+	opts = append([]spp.PacketOption{spp.WithSecondaryHeader(h)}, opts...)
+	if cfg.ErrorControl != ErrorControlNone {
+		opts = append(opts, spp.WithErrorControl())
+	}
+	return spp.NewSpacePacket(apid, typ, data, opts...)
+}
+
+// Encode serializes a packet built by NewTMPacket or NewTCPacket. With
+// ErrorControlChecksum the trailing error control field holds the
+// ISO/IEC 8473 checksum instead of the CRC.
+func Encode(pkt *spp.SpacePacket, cfg Config) ([]byte, error) {
+	data, err := pkt.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ErrorControl == ErrorControlChecksum && pkt.ErrorControl != nil {
+		ck := Checksum(data[:len(data)-2])
+		data[len(data)-2], data[len(data)-1] = byte(ck>>8), byte(ck)
+		*pkt.ErrorControl = ck
+	}
+	return data, nil
+}
+
+// Decode parses a PUS packet. The packet type selects a TM or TC
+// secondary header laid out by cfg, and cfg.ErrorControl selects the
+// error control field to verify.
+func Decode(data []byte, cfg Config) (*spp.SpacePacket, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var ph spp.PrimaryHeader
+	if len(data) < spp.PrimaryHeaderSize {
+		return nil, spp.ErrDataTooShort
+	}
+	if err := ph.Decode(data[:spp.PrimaryHeaderSize]); err != nil {
+		return nil, err
+	}
+	if ph.SecondaryHeaderFlag != 1 {
+		return nil, ErrMissingSecondaryHeader
+	}
+
+	var sh spp.SecondaryHeader = &TCSecondaryHeader{Config: cfg}
+	if ph.Type == spp.PacketTypeTM {
+		sh = &TMSecondaryHeader{Config: cfg}
+	}
+	opts := []spp.DecodeOption{spp.WithDecodeSecondaryHeader(sh)}
+
+	switch cfg.ErrorControl {
+	case ErrorControlCRC:
+		opts = append(opts, spp.WithDecodeErrorControl())
+	case ErrorControlChecksum:
+		n := spp.PrimaryHeaderSize + int(ph.PacketLength) + 1
+		if len(data) < n {
+			return nil, spp.ErrDataTooShort
+		}
+		if !VerifyChecksum(data[:n]) {
+			return nil, ErrChecksumFailed
+		}
+		pkt, err := spp.Decode(data[:n], opts...)
+		if err != nil {
+			return nil, err
+		}
+		if len(pkt.UserData) < 2 {
+			return nil, spp.ErrDataTooShort
+		}
+		ck := uint16(data[n-2])<<8 | uint16(data[n-1])
+		pkt.UserData = pkt.UserData[:len(pkt.UserData)-2]
+		pkt.ErrorControl = &ck
+		return pkt, nil
+	}
+	return spp.Decode(data, opts...)
+}
+
+// Service returns the service type and subtype of a PUS packet.
+func Service(pkt *spp.SpacePacket) (serviceType, serviceSubtype uint8, err error) {
+	switch h := pkt.SecondaryHeader.(type) {
+	case *TMSecondaryHeader:
+		return h.ServiceType, h.ServiceSubtype, nil
+	case *TCSecondaryHeader:
+		return h.ServiceType, h.ServiceSubtype, nil
+	}
+	return 0, 0, ErrMissingSecondaryHeader
+}
@@ -0,0 +1,88 @@
+package pus_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+func TestDecode_PicksHeaderByType(t *testing.T) {
+	for _, ec := range []pus.ErrorControl{pus.ErrorControlNone, pus.ErrorControlCRC, pus.ErrorControlChecksum} {
+		cfg := pus.DefaultConfig(pus.PUSC)
+		cfg.ErrorControl = ec
+
+		tmh, _ := pus.NewTMSecondaryHeader(cfg, 3, 25, testTime, pus.WithMessageCounter(9))
+		tm, err := pus.NewTMPacket(100, tmh, []byte{1, 2, 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tch, _ := pus.NewTCSecondaryHeader(cfg, 8, 1, pus.WithSourceID(5))
+		tc, err := pus.NewTCPacket(101, tch, []byte{4, 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, pkt := range []*spp.SpacePacket{tm, tc} {
+			b, err := pus.Encode(pkt, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := pus.Decode(b, cfg)
+			if err != nil {
+				t.Fatalf("error control %d: %v", ec, err)
+			}
+			if !bytes.Equal(got.UserData, pkt.UserData) {
+				t.Errorf("user data = % X, want % X", got.UserData, pkt.UserData)
+			}
+			st, sst, err := pus.Service(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch h := got.SecondaryHeader.(type) {
+			case *pus.TMSecondaryHeader:
+				if pkt != tm || st != 3 || sst != 25 || h.MessageCounter != 9 {
+					t.Errorf("TM decoded as %+v", h)
+				}
+			case *pus.TCSecondaryHeader:
+				if pkt != tc || st != 8 || sst != 1 || h.SourceID != 5 {
+					t.Errorf("TC decoded as %+v", h)
+				}
+			}
+			if (ec == pus.ErrorControlNone) != (got.ErrorControl == nil) {
+				t.Errorf("error control %d: field = %v", ec, got.ErrorControl)
+			}
+		}
+	}
+}
+
+func TestDecode_ErrorControl(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSA)
+	cfg.ErrorControl = pus.ErrorControlChecksum
+	h, _ := pus.NewTCSecondaryHeader(cfg, 17, 1)
+	pkt, _ := pus.NewTCPacket(1, h, []byte{0xAA})
+	b, _ := pus.Encode(pkt, cfg)
+	if !pus.VerifyChecksum(b) {
+		t.Fatal("encoded packet does not carry a valid checksum")
+	}
+	b[len(b)-3] ^= 0xFF
+	if _, err := pus.Decode(b, cfg); !errors.Is(err, pus.ErrChecksumFailed) {
+		t.Errorf("expected ErrChecksumFailed, got %v", err)
+	}
+
+	crc := pus.DefaultConfig(pus.PUSA)
+	pkt, _ = pus.NewTCPacket(1, &pus.TCSecondaryHeader{Config: crc, AckFlags: pus.AckAll, ServiceType: 17, ServiceSubtype: 1}, []byte{0xAA})
+	b, _ = pus.Encode(pkt, crc)
+	b[len(b)-3] ^= 0xFF
+	if _, err := pus.Decode(b, crc); !errors.Is(err, spp.ErrCRCValidationFailed) {
+		t.Errorf("expected ErrCRCValidationFailed, got %v", err)
+	}
+
+	plain, _ := spp.NewTCPacket(1, []byte{0xAA})
+	b, _ = plain.Encode()
+	if _, err := pus.Decode(b, crc); !errors.Is(err, pus.ErrMissingSecondaryHeader) {
+		t.Errorf("expected ErrMissingSecondaryHeader, got %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package pus
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// ParamType is the on-board representation of a parameter value. All
+// types are whole octets, big-endian.
+type ParamType uint8
+
+const (
+	ParamUint8 ParamType = iota + 1
+	ParamUint16
+	ParamUint32
+	ParamUint64
+	ParamInt8
+	ParamInt16
+	ParamInt32
+	ParamInt64
+	ParamFloat32 // IEEE 754 single precision
+	ParamFloat64 // IEEE 754 double precision
+	ParamBool    // one octet, zero is false
+)
+
+// String returns the type name.
+func (t ParamType) String() string {
+	switch t {
+	case ParamUint8:
+		return "uint8"
+	case ParamUint16:
+		return "uint16"
+	case ParamUint32:
+		return "uint32"
+	case ParamUint64:
+		return "uint64"
+	case ParamInt8:
+		return "int8"
+	case ParamInt16:
+		return "int16"
+	case ParamInt32:
+		return "int32"
+	case ParamInt64:
+		return "int64"
+	case ParamFloat32:
+		return "float32"
+	case ParamFloat64:
+		return "float64"
+	case ParamBool:
+		return "bool"
+	default:
+		return "unknown"
+	}
+}
+
+// Size returns the encoded size of a value in bytes, or 0 for an
+// unknown type.
+func (t ParamType) Size() int {
+	switch t {
+	case ParamUint8, ParamInt8, ParamBool:
+		return 1
+	case ParamUint16, ParamInt16:
+		return 2
+	case ParamUint32, ParamInt32, ParamFloat32:
+		return 4
+	case ParamUint64, ParamInt64, ParamFloat64:
+		return 8
+	}
+	return 0
+}
+
+// Encode appends v in the type's representation. Any Go integer or
+// float that fits the type is accepted.
+func (t ParamType) Encode(out []byte, v any) ([]byte, error) {
+	switch t {
+	case ParamUint8, ParamUint16, ParamUint32, ParamUint64:
+		u, ok := toUint(v)
+		if !ok || (t.Size() < 8 && u >= 1<<(8*t.Size())) {
+			return nil, ErrParameterType
+		}
+		return appendUint(out, u, t.Size()), nil
+	case ParamInt8, ParamInt16, ParamInt32, ParamInt64:
+		i, ok := toInt(v)
+		bits := 8 * t.Size()
+		if !ok || (bits < 64 && (i < -1<<(bits-1) || i >= 1<<(bits-1))) {
+			return nil, ErrParameterType
+		}
+		return appendUint(out, uint64(i), t.Size()), nil
+	case ParamFloat32:
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, ErrParameterType
+		}
+		return binary.BigEndian.AppendUint32(out, math.Float32bits(float32(f))), nil
+	case ParamFloat64:
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, ErrParameterType
+		}
+		return binary.BigEndian.AppendUint64(out, math.Float64bits(f)), nil
+	case ParamBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, ErrParameterType
+		}
+		if b {
+			return append(out, 1), nil
+		}
+		return append(out, 0), nil
+	}
+	return nil, ErrParameterType
+}
+
+// Decode reads a value of the type from the start of data. The value
+// has the Go type named by String.
+func (t ParamType) Decode(data []byte) (any, error) {
+	n := t.Size()
+	if n == 0 {
+		return nil, ErrParameterType
+	}
+	if len(data) < n {
+		return nil, ErrInvalidReport
+	}
+	var u uint64
+	for _, b := range data[:n] {
+		u = u<<8 | uint64(b)
+	}
+	switch t {
+	case ParamUint8:
+		return uint8(u), nil
+	case ParamUint16:
+		return uint16(u), nil
+	case ParamUint32:
+		return uint32(u), nil
+	case ParamUint64:
+		return u, nil
+	case ParamInt8:
+		return int8(u), nil
+	case ParamInt16:
+		return int16(u), nil
+	case ParamInt32:
+		return int32(u), nil
+	case ParamInt64:
+		return int64(u), nil
+	case ParamFloat32:
+		return math.Float32frombits(uint32(u)), nil
+	case ParamFloat64:
+		return math.Float64frombits(u), nil
+	default:
+		return u != 0, nil
+	}
+}
+
+// Parameter is an on-board parameter definition.
+type Parameter struct {
+	ID   uint16
+	Name string
+	Type ParamType
+}
+
+// ParameterValue is a decoded parameter value.
+type ParameterValue struct {
+	Parameter
+	Value any
+}
+
+// String returns "name = value".
+func (v ParameterValue) String() string {
+	return v.Name + " = " + formatValue(v.Value)
+}
+
+// Dictionary is the mission database shared by the spacecraft and the
+// ground: parameter definitions, housekeeping structures and event
+// definitions. The spacecraft services load their initial state from
+// it; the ground decodes reports with it.
+type Dictionary struct {
+	mu         sync.Mutex
+	params     map[uint16]Parameter
+	names      map[string]uint16
+	structures map[uint16]HousekeepingStructure
+	events     map[uint16]EventDefinition
+}
+
+// NewDictionary creates a dictionary holding params.
+func NewDictionary(params ...Parameter) (*Dictionary, error) {
+	d := &Dictionary{
+		params:     make(map[uint16]Parameter),
+		names:      make(map[string]uint16),
+		structures: make(map[uint16]HousekeepingStructure),
+		events:     make(map[uint16]EventDefinition),
+	}
+	for _, p := range params {
+		if err := d.AddParameter(p); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// AddParameter defines a parameter. IDs and names must be unique.
+func (d *Dictionary) AddParameter(p Parameter) error {
+	if p.Type.Size() == 0 {
+		return ErrParameterType
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.params[p.ID]; ok {
+		return ErrDuplicateDefinition
+	}
+	if _, ok := d.names[p.Name]; ok && p.Name != "" {
+		return ErrDuplicateDefinition
+	}
+	d.params[p.ID] = p
+	if p.Name != "" {
+		d.names[p.Name] = p.ID
+	}
+	return nil
+}
+
+// Parameter returns the definition of parameter id.
+func (d *Dictionary) Parameter(id uint16) (Parameter, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.params[id]
+	return p, ok
+}
+
+// ParameterByName returns the definition of the named parameter.
+func (d *Dictionary) ParameterByName(name string) (Parameter, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id, ok := d.names[name]
+	if !ok {
+		return Parameter{}, false
+	}
+	return d.params[id], true
+}
+
+// Parameters returns every parameter definition ordered by ID.
+func (d *Dictionary) Parameters() []Parameter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Parameter, 0, len(d.params))
+	for _, p := range d.params {
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b Parameter) int { return int(a.ID) - int(b.ID) })
+	return out
+}
+
+// checkParams reports whether every ID names a defined parameter.
+func (d *Dictionary) checkParams(ids []uint16) error {
+	for _, id := range ids {
+		if _, ok := d.params[id]; !ok {
+			return ErrUnknownParameter
+		}
+	}
+	return nil
+}
+
+// decodeValues reads the values of ids from data and returns them with
+// the remaining data.
+func (d *Dictionary) decodeValues(ids []uint16, data []byte) ([]ParameterValue, []byte, error) {
+	out := make([]ParameterValue, 0, len(ids))
+	for _, id := range ids {
+		p, ok := d.params[id]
+		if !ok {
+			return nil, nil, ErrUnknownParameter
+		}
+		v, err := p.Type.Decode(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, ParameterValue{Parameter: p, Value: v})
+		data = data[p.Type.Size():]
+	}
+	return out, data, nil
+}
+
+func appendUint(out []byte, u uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		out = append(out, byte(u>>(8*i)))
+	}
+	return out
+}
+
+func toUint(v any) (uint64, bool) {
+	switch x := v.(type) {
+	case uint8:
+		return uint64(x), true
+	case uint16:
+		return uint64(x), true
+	case uint32:
+		return uint64(x), true
+	case uint64:
+		return x, true
+	case uint:
+		return uint64(x), true
+	}
+	if i, ok := toInt(v); ok && i >= 0 {
+		return uint64(i), true
+	}
+	return 0, false
+}
+
+func toInt(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case int:
+		return int64(x), true
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), x <= math.MaxInt64
+	case uint:
+		return int64(x), uint64(x) <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	if u, ok := toUint(v); ok {
+		return float64(u), true
+	}
+	return 0, false
+}
+
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case bool:
+		return strconv.FormatBool(x)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	if i, ok := toInt(v); ok {
+		return strconv.FormatInt(i, 10)
+	}
+	if u, ok := toUint(v); ok {
+		return strconv.FormatUint(u, 10)
+	}
+	return "?"
+}
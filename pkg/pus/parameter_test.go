@@ -0,0 +1,100 @@
+package pus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+func TestParamType_RoundTrip(t *testing.T) {
+	tests := []struct {
+		typ  pus.ParamType
+		in   any
+		want any
+	}{
+		{pus.ParamUint8, 200, uint8(200)},
+		{pus.ParamUint16, uint16(0xBEEF), uint16(0xBEEF)},
+		{pus.ParamUint32, int64(70000), uint32(70000)},
+		{pus.ParamUint64, uint64(1 << 63), uint64(1 << 63)},
+		{pus.ParamInt8, -128, int8(-128)},
+		{pus.ParamInt16, int16(-2), int16(-2)},
+		{pus.ParamInt32, -100000, int32(-100000)},
+		{pus.ParamInt64, int64(-1), int64(-1)},
+		{pus.ParamFloat32, 1.5, float32(1.5)},
+		{pus.ParamFloat64, float32(-0.25), float64(-0.25)},
+		{pus.ParamBool, true, true},
+	}
+	for _, tt := range tests {
+		b, err := tt.typ.Encode(nil, tt.in)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.typ, err)
+		}
+		if len(b) != tt.typ.Size() {
+			t.Errorf("%s: encoded %d bytes, want %d", tt.typ, len(b), tt.typ.Size())
+		}
+		got, err := tt.typ.Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: decoded %v (%T), want %v (%T)", tt.typ, got, got, tt.want, tt.want)
+		}
+	}
+}
+
+func TestParamType_Errors(t *testing.T) {
+	bad := []struct {
+		typ pus.ParamType
+		v   any
+	}{
+		{pus.ParamUint8, 256},
+		{pus.ParamUint16, -1},
+		{pus.ParamInt8, 128},
+		{pus.ParamInt64, uint64(1 << 63)},
+		{pus.ParamFloat32, "1.0"},
+		{pus.ParamBool, 1},
+		{0, 1},
+	}
+	for _, tt := range bad {
+		if _, err := tt.typ.Encode(nil, tt.v); !errors.Is(err, pus.ErrParameterType) {
+			t.Errorf("%s %v: expected ErrParameterType, got %v", tt.typ, tt.v, err)
+		}
+	}
+	if _, err := pus.ParamUint32.Decode([]byte{1, 2}); !errors.Is(err, pus.ErrInvalidReport) {
+		t.Errorf("expected ErrInvalidReport, got %v", err)
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	d, err := pus.NewDictionary(
+		pus.Parameter{ID: 1, Name: "BAT_V", Type: pus.ParamFloat32},
+		pus.Parameter{ID: 2, Name: "MODE", Type: pus.ParamUint8},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := d.ParameterByName("MODE"); !ok || p.ID != 2 {
+		t.Errorf("ParameterByName = %+v, %v", p, ok)
+	}
+	if len(d.Parameters()) != 2 || d.Parameters()[0].Name != "BAT_V" {
+		t.Errorf("Parameters = %+v", d.Parameters())
+	}
+	for _, p := range []pus.Parameter{
+		{ID: 1, Name: "OTHER", Type: pus.ParamUint8},
+		{ID: 3, Name: "MODE", Type: pus.ParamUint8},
+	} {
+		if err := d.AddParameter(p); !errors.Is(err, pus.ErrDuplicateDefinition) {
+			t.Errorf("%+v: expected ErrDuplicateDefinition, got %v", p, err)
+		}
+	}
+	if err := d.AddParameter(pus.Parameter{ID: 4}); !errors.Is(err, pus.ErrParameterType) {
+		t.Errorf("expected ErrParameterType, got %v", err)
+	}
+	if err := d.AddStructure(pus.HousekeepingStructure{ID: 1, Interval: 1, Params: []uint16{9}}); !errors.Is(err, pus.ErrUnknownParameter) {
+		t.Errorf("expected ErrUnknownParameter, got %v", err)
+	}
+	if err := d.AddEvent(pus.EventDefinition{ID: 1, Severity: 5}); !errors.Is(err, pus.ErrInvalidSeverity) {
+		t.Errorf("expected ErrInvalidSeverity, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package pus implements the ECSS Packet Utilization Standard (PUS)
+// packet secondary headers carried in CCSDS Space Packets.
+//
+// The TM and TC secondary headers implement spp.SecondaryHeader:
+//   - TMSecondaryHeader carries the service type and subtype, message
+//     counter, destination ID and a CUC time field
+//   - TCSecondaryHeader carries the acknowledgement flags, service type
+//     and subtype and source ID
+//
+// Config selects the PUS variant and the mission-defined field widths,
+// spare bits and time format. Packets are protected by the CRC of the
+// Space Packet Protocol or by the ISO/IEC 8473 checksum.
+//
+// Services:
+//   - ST[1] request verification: VerificationReport encodes the
+//     reports and Tracker correlates them with sent telecommands
+//   - ST[3] housekeeping: Housekeeping generates parameter reports
+//     on board
+//   - ST[5] event reporting: EventReporter raises event reports
+//   - ST[9] time management: TimeReporter generates time reports
+//     from a CUCClock
+//   - ST[11] time-based scheduling: Schedule holds time-tagged
+//     telecommands and releases them when on-board time passes their
+//     release time
+//   - ST[13] large packet transfer: LargeDownlink and LargeUplink split
+//     and reassemble messages too large for one packet
+//   - ST[15] on-board storage and retrieval: Storage keeps TM packets
+//     in packet stores, held in memory (MemoryStore) or in a file
+//     (FileStore), and downlinks them on request
+//
+// A Dictionary holds the parameter, housekeeping structure and event
+// definitions shared by the on-board services and the ground, which
+// decodes their reports into named parameter values.
+//
+// Supported variants:
+//   - PUS-A: ESA PSS-07-101
+//   - PUS-B: ECSS-E-70-41A
+//   - PUS-C: ECSS-E-ST-70-41C
+package pus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/tcf"
+)
+
+// Variant selects the PUS edition and with it the secondary header layout.
+type Variant uint8
+
+const (
+	PUSA Variant = iota + 1 // ESA PSS-07-101, PUS version 0
+	PUSB                    // ECSS-E-70-41A, PUS version 1
+	PUSC                    // ECSS-E-ST-70-41C, PUS version 2
+)
+
+// String returns the variant name.
+func (v Variant) String() string {
+	switch v {
+	case PUSA:
+		return "PUS-A"
+	case PUSB:
+		return "PUS-B"
+	case PUSC:
+		return "PUS-C"
+	default:
+		return "Unknown"
+	}
+}
+
+// VersionNumber returns the PUS version number the variant carries in
+// its secondary headers.
+func (v Variant) VersionNumber() uint8 {
+	switch v {
+	case PUSB:
+		return 1
+	case PUSC:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// versionBits returns the width of the PUS version number field. PUS-A
+// and PUS-B precede it with a spare bit.
+func (v Variant) versionBits() uint {
+	if v == PUSC {
+		return 4
+	}
+	return 3
+}
+
+// Acknowledgement flags of the TC secondary header (ECSS-E-ST-70-41C
+// Section 7.4.4.1) requesting ST[1] verification reports.
+const (
+	AckAcceptance uint8 = 0b0001 // successful acceptance
+	AckStart      uint8 = 0b0010 // successful start of execution
+	AckProgress   uint8 = 0b0100 // successful progress of execution
+	AckCompletion uint8 = 0b1000 // successful completion of execution
+	AckAll        uint8 = 0b1111
+)
+
+// ErrorControl selects the packet error control field.
+type ErrorControl uint8
+
+const (
+	ErrorControlNone     ErrorControl = iota // no packet error control
+	ErrorControlCRC                          // CRC-16-CCITT, as spp.WithErrorControl
+	ErrorControlChecksum                     // ISO/IEC 8473 checksum
+)
+
+// TimeFormat configures the CUC time field of the TM secondary header.
+type TimeFormat struct {
+	CoarseBytes uint8     // 1-4 coarse time octets
+	FineBytes   uint8     // 0-3 fine time octets
+	Epoch       time.Time // zero selects the CCSDS epoch (Level 1)
+	PField      bool      // include the CUC P-field (explicit time code)
+}
+
+// Size returns the size of the time field in bytes.
+func (f TimeFormat) Size() int {
+	n := int(f.CoarseBytes) + int(f.FineBytes)
+	if f.PField {
+		n++
+	}
+	return n
+}
+
+// epoch returns the time code epoch.
+func (f TimeFormat) epoch() time.Time {
+	if f.Epoch.IsZero() {
+		return tcf.CCSDSEpoch
+	}
+	return f.Epoch
+}
+
+// validate checks the octet counts. The P-field is one octet, so only
+// the basic CUC octet counts are accepted.
+func (f TimeFormat) validate() error {
+	if f.CoarseBytes < 1 || f.CoarseBytes > 4 || f.FineBytes > 3 {
+		return ErrInvalidTimeFormat
+	}
+	return nil
+}
+
+// Config holds the mission parameters that fix the secondary header
+// layout. Both ends of the link must use the same Config.
+type Config struct {
+	Variant         Variant
+	CounterBits     int // TM message type counter (PUS-A/B packet subcounter): 0, 8 or 16
+	DestinationBits int // TM destination ID: 0, 8 or 16
+	SourceBits      int // TC source ID: 0, 8 or 16
+	TMSpareBits     int // trailing TM spare, multiple of 8
+	TCSpareBits     int // trailing TC spare, multiple of 8
+	StepIDBits      int // ST[1] progress step ID: 0, 8 or 16
+	FailureCodeBits int // ST[1] failure code: 0, 8 or 16
+	Time            TimeFormat
+	ErrorControl    ErrorControl
+}
+
+// DefaultConfig returns the variant's usual layout. PUS-C uses 16-bit
+// message counter, destination ID and source ID fields, a 4+2 octet CUC
+// time and the CRC. PUS-A and PUS-B use 8-bit fields, a 4+2 octet CUC
+// time and the CRC. All variants use an 8-bit ST[1] step ID and a
+// 16-bit failure code.
+func DefaultConfig(v Variant) Config {
+	c := Config{
+		Variant:         v,
+		StepIDBits:      8,
+		FailureCodeBits: 16,
+		Time:            TimeFormat{CoarseBytes: 4, FineBytes: 2},
+		ErrorControl:    ErrorControlCRC,
+	}
+	if v == PUSC {
+		c.CounterBits, c.DestinationBits, c.SourceBits = 16, 16, 16
+	} else {
+		c.CounterBits, c.DestinationBits, c.SourceBits = 8, 8, 8
+	}
+	return c
+}
+
+// Validate checks the configuration.
+func (c Config) Validate() error {
+	if c.Variant < PUSA || c.Variant > PUSC {
+		return ErrInvalidVariant
+	}
+	for _, w := range []int{c.CounterBits, c.DestinationBits, c.SourceBits, c.StepIDBits, c.FailureCodeBits} {
+		if w != 0 && w != 8 && w != 16 {
+			return ErrInvalidFieldWidth
+		}
+	}
+	if c.TMSpareBits < 0 || c.TMSpareBits%8 != 0 || c.TCSpareBits < 0 || c.TCSpareBits%8 != 0 {
+		return ErrInvalidSpare
+	}
+	return c.Time.validate()
+}
+
+// TMHeaderSize returns the size of the TM secondary header in bytes.
+func (c Config) TMHeaderSize() int {
+	return 3 + (c.CounterBits+c.DestinationBits+c.TMSpareBits)/8 + c.Time.Size()
+}
+
+// TCHeaderSize returns the size of the TC secondary header in bytes.
+func (c Config) TCHeaderSize() int {
+	return 3 + (c.SourceBits+c.TCSpareBits)/8
+}
+
+// Humanize returns a human-readable representation of the configuration.
+func (c Config) Humanize() string {
+	return c.Variant.String() +
+		", Counter: " + strconv.Itoa(c.CounterBits) + "b" +
+		", Destination: " + strconv.Itoa(c.DestinationBits) + "b" +
+		", Source: " + strconv.Itoa(c.SourceBits) + "b" +
+		", Time: CUC " + strconv.Itoa(int(c.Time.CoarseBytes)) + "+" + strconv.Itoa(int(c.Time.FineBytes))
+}
@@ -0,0 +1,742 @@
+package pus
+
+import (
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/tcf"
+)
+
+// ServiceScheduling is the service type of ST[11] time-based scheduling.
+const ServiceScheduling uint8 = 11
+
+// ST[11] message subtypes (ECSS-E-ST-70-41C Section 6.11).
+const (
+	SchedEnable              uint8 = 1  // TC: enable the schedule execution function
+	SchedDisable             uint8 = 2  // TC: disable the schedule execution function
+	SchedReset               uint8 = 3  // TC: reset the schedule
+	SchedInsert              uint8 = 4  // TC: insert activities
+	SchedDelete              uint8 = 5  // TC: delete activities by request ID
+	SchedTimeShift           uint8 = 7  // TC: time-shift activities by request ID
+	SchedDetailReport        uint8 = 10 // TM: schedule detail report
+	SchedTimeShiftAll        uint8 = 15 // TC: time-shift all activities
+	SchedReportAll           uint8 = 16 // TC: detail-report all activities
+	SchedEnableSubSchedules  uint8 = 20 // TC: enable sub-schedules
+	SchedDisableSubSchedules uint8 = 21 // TC: disable sub-schedules
+	SchedCreateGroups        uint8 = 22 // TC: create groups
+	SchedDeleteGroups        uint8 = 23 // TC: delete groups
+	SchedEnableGroups        uint8 = 24 // TC: enable groups
+	SchedDisableGroups       uint8 = 25 // TC: disable groups
+)
+
+// DefaultScheduleCapacity is the default maximum number of scheduled
+// activities.
+const DefaultScheduleCapacity = 1024
+
+// ActivityID identifies a scheduled activity by the source ID, APID
+// and sequence count of its request.
+type ActivityID struct {
+	SourceID      uint16
+	APID          uint16
+	SequenceCount uint16
+}
+
+// NewActivityID returns the ID of scheduling request pkt, a TC with a
+// TCSecondaryHeader.
+func NewActivityID(pkt *spp.SpacePacket) (ActivityID, error) {
+	h, ok := pkt.SecondaryHeader.(*TCSecondaryHeader)
+	if !ok || pkt.PrimaryHeader.Type != spp.PacketTypeTC {
+		return ActivityID{}, ErrNotTelecommand
+	}
+	return ActivityID{
+		SourceID:      h.SourceID,
+		APID:          pkt.PrimaryHeader.APID,
+		SequenceCount: pkt.PrimaryHeader.SequenceCount,
+	}, nil
+}
+
+// String returns the source ID, APID and sequence count.
+func (id ActivityID) String() string {
+	return "source " + strconv.Itoa(int(id.SourceID)) + " APID " + strconv.Itoa(int(id.APID)) +
+		" seq " + strconv.Itoa(int(id.SequenceCount))
+}
+
+func (id ActivityID) encode(out []byte, cfg Config) []byte {
+	out = appendField(out, id.SourceID, cfg.SourceBits)
+	out = binary.BigEndian.AppendUint16(out, id.APID)
+	return binary.BigEndian.AppendUint16(out, id.SequenceCount)
+}
+
+func encodeActivityIDs(cfg Config, ids []ActivityID) []byte {
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(ids)))
+	for _, id := range ids {
+		out = id.encode(out, cfg)
+	}
+	return out
+}
+
+func decodeActivityIDs(data []byte, cfg Config) ([]ActivityID, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidRequest
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	size := cfg.SourceBits/8 + 4
+	data = data[2:]
+	if len(data) != n*size {
+		return nil, ErrInvalidRequest
+	}
+	ids := make([]ActivityID, n)
+	for i := range ids {
+		off := i * size
+		ids[i].SourceID, off = readField(data, off, cfg.SourceBits)
+		ids[i].APID = binary.BigEndian.Uint16(data[off:])
+		ids[i].SequenceCount = binary.BigEndian.Uint16(data[off+2:])
+	}
+	return ids, nil
+}
+
+// Activity is a time-tagged telecommand on the schedule. Group 0 is
+// no group.
+type Activity struct {
+	SubSchedule uint16
+	Group       uint16
+	ReleaseTime *tcf.CUC
+	Request     *spp.SpacePacket
+}
+
+// ID returns the activity's request ID.
+func (a *Activity) ID() (ActivityID, error) {
+	return NewActivityID(a.Request)
+}
+
+// encode appends the activity as an instruction: sub-schedule ID (16),
+// group ID (16), release time in cfg.Time format and the encoded request.
+func (a *Activity) encode(out []byte, cfg Config) ([]byte, error) {
+	if a.ReleaseTime == nil || a.Request == nil {
+		return nil, ErrInvalidRequest
+	}
+	out = binary.BigEndian.AppendUint16(out, a.SubSchedule)
+	out = binary.BigEndian.AppendUint16(out, a.Group)
+	t, err := encodeTime(cfg.Time, a.ReleaseTime)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, t...)
+	req, err := Encode(a.Request, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, req...), nil
+}
+
+// Humanize returns a human-readable representation of the activity.
+func (a *Activity) Humanize() string {
+	lines := []string{
+		"  Release Time: " + a.ReleaseTime.Time().UTC().Format("2006-01-02T15:04:05.000000Z"),
+		"  Sub-schedule: " + strconv.Itoa(int(a.SubSchedule)),
+	}
+	if a.Group != 0 {
+		lines = append(lines, "  Group: "+strconv.Itoa(int(a.Group)))
+	}
+	if id, err := a.ID(); err == nil {
+		lines = append(lines, "  Request: "+id.String())
+	}
+	if st, sst, err := Service(a.Request); err == nil {
+		lines = append(lines, "  Service: ST["+strconv.Itoa(int(st))+","+strconv.Itoa(int(sst))+"]")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// EncodeActivities serializes activities as the application data of a
+// TC[11,4] insert request or a TM[11,10] detail report.
+func EncodeActivities(cfg Config, acts []Activity) ([]byte, error) {
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(acts)))
+	for i := range acts {
+		var err error
+		if out, err = acts[i].encode(out, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// DecodeActivities parses the application data of a TC[11,4] insert
+// request or a TM[11,10] detail report.
+func DecodeActivities(data []byte, cfg Config) ([]Activity, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidRequest
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	acts := make([]Activity, 0, n)
+	for range n {
+		if len(data) < 4+cfg.Time.Size()+spp.PrimaryHeaderSize {
+			return nil, ErrInvalidRequest
+		}
+		a := Activity{
+			SubSchedule: binary.BigEndian.Uint16(data),
+			Group:       binary.BigEndian.Uint16(data[2:]),
+		}
+		data = data[4:]
+		t, err := decodeTime(cfg.Time, data[:cfg.Time.Size()])
+		if err != nil {
+			return nil, err
+		}
+		a.ReleaseTime = t
+		data = data[cfg.Time.Size():]
+
+		var ph spp.PrimaryHeader
+		if err := ph.Decode(data[:spp.PrimaryHeaderSize]); err != nil {
+			return nil, err
+		}
+		size := spp.PrimaryHeaderSize + int(ph.PacketLength) + 1
+		if len(data) < size {
+			return nil, ErrInvalidRequest
+		}
+		if a.Request, err = Decode(data[:size], cfg); err != nil {
+			return nil, err
+		}
+		if _, err := a.ID(); err != nil {
+			return nil, err
+		}
+		data = data[size:]
+		acts = append(acts, a)
+	}
+	if len(data) != 0 {
+		return nil, ErrInvalidRequest
+	}
+	return acts, nil
+}
+
+// encodeTime serializes t in format f, dropping the P-field of an
+// implicit time code.
+func encodeTime(f TimeFormat, t *tcf.CUC) ([]byte, error) {
+	if t.CoarseBytes != f.CoarseBytes || t.FineBytes != f.FineBytes {
+		return nil, ErrInvalidTimeFormat
+	}
+	b, err := t.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if !f.PField {
+		b = b[t.PField.Size():]
+	}
+	return b, nil
+}
+
+// NewInsertActivitiesCommand builds TC[11,4] inserting acts into the
+// on-board schedule.
+func NewInsertActivitiesCommand(cfg Config, apid uint16, acts []Activity, opts ...TCOption) (*spp.SpacePacket, error) {
+	data, err := EncodeActivities(cfg, acts)
+	if err != nil {
+		return nil, err
+	}
+	return newCommand(cfg, apid, ServiceScheduling, SchedInsert, data, opts)
+}
+
+// NewDeleteActivitiesCommand builds TC[11,5] deleting activities ids.
+func NewDeleteActivitiesCommand(cfg Config, apid uint16, ids []ActivityID, opts ...TCOption) (*spp.SpacePacket, error) {
+	return newCommand(cfg, apid, ServiceScheduling, SchedDelete, encodeActivityIDs(cfg, ids), opts)
+}
+
+// NewTimeShiftCommand builds TC[11,7] shifting the release times of
+// activities ids by offset, or TC[11,15] shifting every activity when
+// ids is empty. The offset is encoded as signed 64-bit milliseconds.
+func NewTimeShiftCommand(cfg Config, apid uint16, offset time.Duration, ids []ActivityID, opts ...TCOption) (*spp.SpacePacket, error) {
+	data := binary.BigEndian.AppendUint64(nil, uint64(offset.Milliseconds()))
+	if len(ids) == 0 {
+		return newCommand(cfg, apid, ServiceScheduling, SchedTimeShiftAll, data, opts)
+	}
+	return newCommand(cfg, apid, ServiceScheduling, SchedTimeShift, append(data, encodeActivityIDs(cfg, ids)...), opts)
+}
+
+// NewScheduleCommand builds one of the ST[11] requests without
+// application data: SchedEnable, SchedDisable, SchedReset or
+// SchedReportAll.
+func NewScheduleCommand(cfg Config, apid uint16, subtype uint8, opts ...TCOption) (*spp.SpacePacket, error) {
+	switch subtype {
+	case SchedEnable, SchedDisable, SchedReset, SchedReportAll:
+		return newCommand(cfg, apid, ServiceScheduling, subtype, nil, opts)
+	}
+	return nil, ErrInvalidSubtype
+}
+
+// NewScheduleIDsCommand builds one of the ST[11] requests on a list of
+// sub-schedule or group IDs: SchedEnableSubSchedules,
+// SchedDisableSubSchedules, SchedCreateGroups, SchedDeleteGroups,
+// SchedEnableGroups or SchedDisableGroups.
+func NewScheduleIDsCommand(cfg Config, apid uint16, subtype uint8, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	switch subtype {
+	case SchedEnableSubSchedules, SchedDisableSubSchedules,
+		SchedCreateGroups, SchedDeleteGroups, SchedEnableGroups, SchedDisableGroups:
+		return newCommand(cfg, apid, ServiceScheduling, subtype, appendIDs(nil, ids), opts)
+	}
+	return nil, ErrInvalidSubtype
+}
+
+// ScheduleOption configures a Schedule.
+type ScheduleOption func(*Schedule)
+
+// WithScheduleCapacity sets the maximum number of scheduled activities.
+func WithScheduleCapacity(n int) ScheduleOption {
+	return func(s *Schedule) {
+		s.capacity = n
+	}
+}
+
+// WithTimeMargin sets the minimum lead time between the on-board time
+// and the release time of an inserted or time-shifted activity.
+func WithTimeMargin(d time.Duration) ScheduleOption {
+	return func(s *Schedule) {
+		s.margin = d
+	}
+}
+
+// WithScheduleReports configures the TM[11,10] detail reports.
+func WithScheduleReports(opts ...ReporterOption) ScheduleOption {
+	return func(s *Schedule) {
+		s.repOpts = append(s.repOpts, opts...)
+	}
+}
+
+type scheduled struct {
+	Activity
+	id ActivityID
+}
+
+// Schedule is the on-board ST[11] time-based schedule. It holds
+// time-tagged telecommands and releases them when the on-board time
+// reaches their release time.
+//
+// Activities belong to a sub-schedule and optionally to a group. Every
+// sub-schedule starts enabled; groups are created explicitly. When an
+// activity falls due while its sub-schedule or group is disabled it is
+// deleted without release. While the execution function is disabled
+// nothing is released and activities stay on the schedule.
+type Schedule struct {
+	mu         sync.Mutex
+	cfg        Config
+	clock      CUCClock
+	rep        reporter
+	repOpts    []ReporterOption
+	capacity   int
+	margin     time.Duration
+	enabled    bool
+	activities []*scheduled // by release time
+	disabled   map[uint16]bool
+	groups     map[uint16]bool // enabled state by group ID
+}
+
+// NewSchedule creates an enabled, empty schedule for application apid
+// reading the on-board time from clock.
+func NewSchedule(cfg Config, apid uint16, clock CUCClock, opts ...ScheduleOption) *Schedule {
+	s := &Schedule{
+		cfg:      cfg,
+		clock:    clock,
+		capacity: DefaultScheduleCapacity,
+		enabled:  true,
+		disabled: make(map[uint16]bool),
+		groups:   make(map[uint16]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.rep = newReporter(cfg, apid, s.repOpts)
+	return s
+}
+
+// Enable enables the schedule execution function.
+func (s *Schedule) Enable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = true
+}
+
+// Disable disables the schedule execution function.
+func (s *Schedule) Disable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = false
+}
+
+// Enabled reports whether the schedule execution function is enabled.
+func (s *Schedule) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// Reset deletes every activity and group, enables every sub-schedule
+// and disables the execution function.
+func (s *Schedule) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activities = nil
+	clear(s.disabled)
+	clear(s.groups)
+	s.enabled = false
+}
+
+// Insert adds activities to the schedule. Nothing is inserted if any
+// activity is rejected.
+func (s *Schedule) Insert(acts ...Activity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	earliest, err := s.earliest()
+	if err != nil {
+		return err
+	}
+	if len(s.activities)+len(acts) > s.capacity {
+		return ErrScheduleFull
+	}
+	added := make([]*scheduled, 0, len(acts))
+	seen := make(map[ActivityID]bool)
+	for _, a := range acts {
+		id, err := a.ID()
+		if err != nil {
+			return err
+		}
+		if a.ReleaseTime == nil {
+			return ErrInvalidRequest
+		}
+		if a.ReleaseTime.CoarseBytes != s.cfg.Time.CoarseBytes || a.ReleaseTime.FineBytes != s.cfg.Time.FineBytes {
+			return ErrInvalidTimeFormat
+		}
+		if a.ReleaseTime.Time().Before(earliest) {
+			return ErrReleaseTimePassed
+		}
+		if a.Group != 0 {
+			if _, ok := s.groups[a.Group]; !ok {
+				return ErrUnknownGroup
+			}
+		}
+		if seen[id] || s.find(id) >= 0 {
+			return ErrDuplicateDefinition
+		}
+		seen[id] = true
+		added = append(added, &scheduled{Activity: a, id: id})
+	}
+	for _, a := range added {
+		s.insert(a)
+	}
+	return nil
+}
+
+// Delete removes activities. Nothing is deleted if any ID is unknown.
+func (s *Schedule) Delete(ids ...ActivityID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if s.find(id) < 0 {
+			return ErrUnknownActivity
+		}
+	}
+	for _, id := range ids {
+		i := s.find(id)
+		s.activities = slices.Delete(s.activities, i, i+1)
+	}
+	return nil
+}
+
+// TimeShift moves the release times of activities ids by offset, or
+// of every activity when no ID is given. Nothing is shifted if an ID is
+// unknown or a shifted release time would fall before the time margin.
+func (s *Schedule) TimeShift(offset time.Duration, ids ...ActivityID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	earliest, err := s.earliest()
+	if err != nil {
+		return err
+	}
+	targets := s.activities
+	if len(ids) > 0 {
+		targets = make([]*scheduled, 0, len(ids))
+		for _, id := range ids {
+			i := s.find(id)
+			if i < 0 {
+				return ErrUnknownActivity
+			}
+			targets = append(targets, s.activities[i])
+		}
+	}
+	shifted := make([]*tcf.CUC, len(targets))
+	for i, a := range targets {
+		t := a.ReleaseTime.Time().Add(offset)
+		if t.Before(earliest) {
+			return ErrReleaseTimePassed
+		}
+		if shifted[i], err = newTime(s.cfg.Time, t); err != nil {
+			return err
+		}
+	}
+	for i, a := range targets {
+		a.ReleaseTime = shifted[i]
+	}
+	slices.SortStableFunc(s.activities, func(a, b *scheduled) int {
+		return a.ReleaseTime.Time().Compare(b.ReleaseTime.Time())
+	})
+	return nil
+}
+
+// EnableSubSchedules enables sub-schedules ids.
+func (s *Schedule) EnableSubSchedules(ids ...uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.disabled, id)
+	}
+}
+
+// DisableSubSchedules disables sub-schedules ids.
+func (s *Schedule) DisableSubSchedules(ids ...uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.disabled[id] = true
+	}
+}
+
+// CreateGroups creates enabled groups. Group 0 is reserved for
+// ungrouped activities.
+func (s *Schedule) CreateGroups(ids ...uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if id == 0 {
+			return ErrInvalidRequest
+		}
+		if _, ok := s.groups[id]; ok {
+			return ErrDuplicateDefinition
+		}
+	}
+	for _, id := range ids {
+		s.groups[id] = true
+	}
+	return nil
+}
+
+// DeleteGroups deletes groups that hold no activities.
+func (s *Schedule) DeleteGroups(ids ...uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := s.groups[id]; !ok {
+			return ErrUnknownGroup
+		}
+		for _, a := range s.activities {
+			if a.Group == id {
+				return ErrGroupInUse
+			}
+		}
+	}
+	for _, id := range ids {
+		delete(s.groups, id)
+	}
+	return nil
+}
+
+// EnableGroups enables groups ids.
+func (s *Schedule) EnableGroups(ids ...uint16) error {
+	return s.setGroups(ids, true)
+}
+
+// DisableGroups disables groups ids.
+func (s *Schedule) DisableGroups(ids ...uint16) error {
+	return s.setGroups(ids, false)
+}
+
+func (s *Schedule) setGroups(ids []uint16, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := s.groups[id]; !ok {
+			return ErrUnknownGroup
+		}
+	}
+	for _, id := range ids {
+		s.groups[id] = enabled
+	}
+	return nil
+}
+
+// Activities returns the scheduled activities in release order.
+func (s *Schedule) Activities() []Activity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Activity, len(s.activities))
+	for i, a := range s.activities {
+		out[i] = a.Activity
+	}
+	return out
+}
+
+// Len returns the number of scheduled activities.
+func (s *Schedule) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.activities)
+}
+
+// Release removes the activities whose release time has been reached
+// and returns the requests of those in enabled sub-schedules and
+// groups, in release order.
+func (s *Schedule) Release() ([]*spp.SpacePacket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return nil, nil
+	}
+	now, err := s.clock.Now()
+	if err != nil {
+		return nil, err
+	}
+	var out []*spp.SpacePacket
+	n := 0
+	for _, a := range s.activities {
+		if a.ReleaseTime.Time().After(now.Time()) {
+			break
+		}
+		n++
+		if s.disabled[a.SubSchedule] || (a.Group != 0 && !s.groups[a.Group]) {
+			continue
+		}
+		out = append(out, a.Request)
+	}
+	s.activities = s.activities[n:]
+	return out, nil
+}
+
+// Report generates a TM[11,10] detail report of every activity.
+func (s *Schedule) Report() (*spp.SpacePacket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acts := make([]Activity, len(s.activities))
+	for i, a := range s.activities {
+		acts[i] = a.Activity
+	}
+	data, err := EncodeActivities(s.cfg, acts)
+	if err != nil {
+		return nil, err
+	}
+	return s.rep.report(ServiceScheduling, SchedDetailReport, data)
+}
+
+// Handle executes an ST[11] request and returns the reports it
+// generates.
+func (s *Schedule) Handle(tc *spp.SpacePacket) ([]*spp.SpacePacket, error) {
+	sst, data, err := request(tc, ServiceScheduling)
+	if err != nil {
+		return nil, err
+	}
+	switch sst {
+	case SchedEnable, SchedDisable, SchedReset, SchedReportAll:
+		if len(data) != 0 {
+			return nil, ErrInvalidRequest
+		}
+	}
+	switch sst {
+	case SchedEnable:
+		s.Enable()
+		return nil, nil
+	case SchedDisable:
+		s.Disable()
+		return nil, nil
+	case SchedReset:
+		s.Reset()
+		return nil, nil
+	case SchedReportAll:
+		pkt, err := s.Report()
+		if err != nil {
+			return nil, err
+		}
+		return []*spp.SpacePacket{pkt}, nil
+	case SchedInsert:
+		acts, err := DecodeActivities(data, s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.Insert(acts...)
+	case SchedDelete:
+		ids, err := decodeActivityIDs(data, s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.Delete(ids...)
+	case SchedTimeShift, SchedTimeShiftAll:
+		if len(data) < 8 {
+			return nil, ErrInvalidRequest
+		}
+		offset := time.Duration(int64(binary.BigEndian.Uint64(data))) * time.Millisecond
+		if sst == SchedTimeShiftAll {
+			if len(data) != 8 {
+				return nil, ErrInvalidRequest
+			}
+			return nil, s.TimeShift(offset)
+		}
+		ids, err := decodeActivityIDs(data[8:], s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, ErrInvalidRequest
+		}
+		return nil, s.TimeShift(offset, ids...)
+	}
+
+	ids, rest, err := readIDs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidRequest
+	}
+	switch sst {
+	case SchedEnableSubSchedules:
+		s.EnableSubSchedules(ids...)
+		return nil, nil
+	case SchedDisableSubSchedules:
+		s.DisableSubSchedules(ids...)
+		return nil, nil
+	case SchedCreateGroups:
+		return nil, s.CreateGroups(ids...)
+	case SchedDeleteGroups:
+		return nil, s.DeleteGroups(ids...)
+	case SchedEnableGroups:
+		return nil, s.EnableGroups(ids...)
+	case SchedDisableGroups:
+		return nil, s.DisableGroups(ids...)
+	}
+	return nil, ErrInvalidSubtype
+}
+
+// earliest returns the earliest release time accepted now.
+func (s *Schedule) earliest() (time.Time, error) {
+	now, err := s.clock.Now()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Time().Add(s.margin), nil
+}
+
+// find returns the index of activity id, or -1.
+func (s *Schedule) find(id ActivityID) int {
+	return slices.IndexFunc(s.activities, func(a *scheduled) bool { return a.id == id })
+}
+
+// insert adds a keeping release order; equal release times keep
+// insertion order.
+func (s *Schedule) insert(a *scheduled) {
+	t := a.ReleaseTime.Time()
+	i, _ := slices.BinarySearchFunc(s.activities, t, func(e *scheduled, t time.Time) int {
+		if e.ReleaseTime.Time().After(t) {
+			return 1
+		}
+		return -1
+	})
+	s.activities = slices.Insert(s.activities, i, a)
+}
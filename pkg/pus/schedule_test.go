@@ -0,0 +1,222 @@
+package pus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/tcf"
+)
+
+type scheduleFixture struct {
+	cfg   pus.Config
+	clk   *fakeClock
+	sched *pus.Schedule
+}
+
+func newScheduleFixture(t *testing.T, opts ...pus.ScheduleOption) *scheduleFixture {
+	t.Helper()
+	cfg := pus.DefaultConfig(pus.PUSC)
+	clk := &fakeClock{now: testTime}
+	return &scheduleFixture{cfg: cfg, clk: clk, sched: pus.NewSchedule(cfg, 0x10, pus.NewCUCClock(clk, cfg.Time), opts...)}
+}
+
+// activity schedules TC ST[8,1] with sequence count seq at testTime+at.
+func (f *scheduleFixture) activity(t *testing.T, seq uint16, at time.Duration, sub, group uint16) pus.Activity {
+	t.Helper()
+	h, _ := pus.NewTCSecondaryHeader(f.cfg, 8, 1, pus.WithSourceID(3))
+	pkt, err := pus.NewTCPacket(0x42, h, []byte{byte(seq)}, spp.WithSequenceCount(seq))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := tcf.NewCUC(testTime.Add(at), tcf.WithCUCCoarseBytes(4), tcf.WithCUCFineBytes(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pus.Activity{SubSchedule: sub, Group: group, ReleaseTime: rt, Request: pkt}
+}
+
+func (f *scheduleFixture) release(t *testing.T) []uint16 {
+	t.Helper()
+	out, err := f.sched.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seqs []uint16
+	for _, pkt := range out {
+		seqs = append(seqs, pkt.PrimaryHeader.SequenceCount)
+	}
+	return seqs
+}
+
+func TestSchedule_InsertRequest(t *testing.T) {
+	f := newScheduleFixture(t)
+	acts := []pus.Activity{
+		f.activity(t, 2, 20*time.Second, 1, 0),
+		f.activity(t, 1, 10*time.Second, 1, 0),
+		f.activity(t, 3, 30*time.Second, 2, 0),
+	}
+	tc, err := pus.NewInsertActivitiesCommand(f.cfg, 0x10, acts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := pus.Encode(tc, f.cfg)
+	decoded, err := pus.Decode(b, f.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.sched.Handle(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if f.sched.Len() != 3 {
+		t.Fatalf("%d activities", f.sched.Len())
+	}
+
+	f.clk.Advance(15 * time.Second)
+	if got := f.release(t); len(got) != 1 || got[0] != 1 {
+		t.Errorf("released %v at +15s, want [1]", got)
+	}
+	f.clk.Advance(20 * time.Second)
+	if got := f.release(t); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("released %v at +35s, want [2 3]", got)
+	}
+	if f.sched.Len() != 0 {
+		t.Error("released activities still scheduled")
+	}
+}
+
+func TestSchedule_DetailReport(t *testing.T) {
+	f := newScheduleFixture(t)
+	if err := f.sched.CreateGroups(7); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.sched.Insert(f.activity(t, 1, time.Minute, 4, 7)); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := pus.NewScheduleCommand(f.cfg, 0x10, pus.SchedReportAll)
+	out, err := f.sched.Handle(req)
+	if err != nil || len(out) != 1 {
+		t.Fatalf("report: %d packets, %v", len(out), err)
+	}
+	got := downlink(t, f.cfg, out[0])
+	if _, sst, _ := pus.Service(got); sst != pus.SchedDetailReport {
+		t.Fatalf("subtype %d", sst)
+	}
+	acts, err := pus.DecodeActivities(got.UserData, f.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acts) != 1 || acts[0].SubSchedule != 4 || acts[0].Group != 7 ||
+		!acts[0].ReleaseTime.Time().Equal(testTime.Add(time.Minute)) {
+		t.Fatalf("activities %+v", acts)
+	}
+	if id, _ := acts[0].ID(); id != (pus.ActivityID{SourceID: 3, APID: 0x42, SequenceCount: 1}) {
+		t.Errorf("activity ID %s", id)
+	}
+}
+
+func TestSchedule_DeleteAndTimeShift(t *testing.T) {
+	f := newScheduleFixture(t)
+	_ = f.sched.Insert(
+		f.activity(t, 1, 10*time.Second, 0, 0),
+		f.activity(t, 2, 20*time.Second, 0, 0),
+		f.activity(t, 3, 30*time.Second, 0, 0),
+	)
+	id := func(seq uint16) pus.ActivityID { return pus.ActivityID{SourceID: 3, APID: 0x42, SequenceCount: seq} }
+
+	del, _ := pus.NewDeleteActivitiesCommand(f.cfg, 0x10, []pus.ActivityID{id(2)})
+	if _, err := f.sched.Handle(del); err != nil {
+		t.Fatal(err)
+	}
+	shift, _ := pus.NewTimeShiftCommand(f.cfg, 0x10, 25*time.Second, []pus.ActivityID{id(1)})
+	if _, err := f.sched.Handle(shift); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := pus.NewTimeShiftCommand(f.cfg, 0x10, -5*time.Second, nil)
+	if _, err := f.sched.Handle(all); err != nil {
+		t.Fatal(err)
+	}
+	acts := f.sched.Activities()
+	if len(acts) != 2 {
+		t.Fatalf("%d activities", len(acts))
+	}
+	first, _ := acts[0].ID()
+	if first != id(3) || !acts[1].ReleaseTime.Time().Equal(testTime.Add(30*time.Second)) {
+		t.Errorf("order after shift: %s first, second at %v", first, acts[1].ReleaseTime.Time())
+	}
+
+	if err := f.sched.TimeShift(-time.Minute); !errors.Is(err, pus.ErrReleaseTimePassed) {
+		t.Errorf("expected ErrReleaseTimePassed, got %v", err)
+	}
+	if err := f.sched.Delete(id(9)); !errors.Is(err, pus.ErrUnknownActivity) {
+		t.Errorf("expected ErrUnknownActivity, got %v", err)
+	}
+}
+
+func TestSchedule_SubSchedulesAndGroups(t *testing.T) {
+	f := newScheduleFixture(t)
+	_ = f.sched.CreateGroups(1)
+	_ = f.sched.Insert(
+		f.activity(t, 1, time.Second, 1, 0),
+		f.activity(t, 2, time.Second, 2, 0),
+		f.activity(t, 3, time.Second, 1, 1),
+	)
+	f.sched.DisableSubSchedules(2)
+	if err := f.sched.DisableGroups(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.sched.DeleteGroups(1); !errors.Is(err, pus.ErrGroupInUse) {
+		t.Errorf("expected ErrGroupInUse, got %v", err)
+	}
+
+	f.clk.Advance(time.Second)
+	if got := f.release(t); len(got) != 1 || got[0] != 1 {
+		t.Errorf("released %v, want [1]", got)
+	}
+	if f.sched.Len() != 0 {
+		t.Error("disabled activities kept after release time")
+	}
+	if err := f.sched.DeleteGroups(1); err != nil {
+		t.Error(err)
+	}
+	if err := f.sched.Insert(f.activity(t, 4, time.Minute, 0, 9)); !errors.Is(err, pus.ErrUnknownGroup) {
+		t.Errorf("expected ErrUnknownGroup, got %v", err)
+	}
+}
+
+func TestSchedule_EnableReset(t *testing.T) {
+	f := newScheduleFixture(t, pus.WithScheduleCapacity(2), pus.WithTimeMargin(5*time.Second))
+	if err := f.sched.Insert(f.activity(t, 1, time.Second, 0, 0)); !errors.Is(err, pus.ErrReleaseTimePassed) {
+		t.Errorf("expected ErrReleaseTimePassed inside margin, got %v", err)
+	}
+	_ = f.sched.Insert(f.activity(t, 1, 10*time.Second, 0, 0))
+	if err := f.sched.Insert(f.activity(t, 1, 20*time.Second, 0, 0)); !errors.Is(err, pus.ErrDuplicateDefinition) {
+		t.Errorf("expected ErrDuplicateDefinition, got %v", err)
+	}
+	if err := f.sched.Insert(f.activity(t, 2, 20*time.Second, 0, 0), f.activity(t, 3, 20*time.Second, 0, 0)); !errors.Is(err, pus.ErrScheduleFull) {
+		t.Errorf("expected ErrScheduleFull, got %v", err)
+	}
+
+	disable, _ := pus.NewScheduleCommand(f.cfg, 0x10, pus.SchedDisable)
+	_, _ = f.sched.Handle(disable)
+	f.clk.Advance(time.Minute)
+	if got := f.release(t); len(got) != 0 {
+		t.Errorf("released %v while disabled", got)
+	}
+	f.sched.Enable()
+	if got := f.release(t); len(got) != 1 {
+		t.Errorf("released %v after enable, want the overdue activity", got)
+	}
+
+	_ = f.sched.Insert(f.activity(t, 5, time.Hour, 0, 0))
+	reset, _ := pus.NewScheduleCommand(f.cfg, 0x10, pus.SchedReset)
+	_, _ = f.sched.Handle(reset)
+	if f.sched.Len() != 0 || f.sched.Enabled() {
+		t.Error("reset left activities or execution enabled")
+	}
+	if _, err := pus.NewScheduleCommand(f.cfg, 0x10, pus.SchedInsert); !errors.Is(err, pus.ErrInvalidSubtype) {
+		t.Errorf("expected ErrInvalidSubtype, got %v", err)
+	}
+}
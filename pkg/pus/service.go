@@ -0,0 +1,115 @@
+package pus
+
+import (
+	"encoding/binary"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// ReporterOption configures the TM reports generated by an on-board
+// service.
+type ReporterOption func(*reporter)
+
+// WithReportClock sets the clock that time-stamps reports.
+func WithReportClock(c Clock) ReporterOption {
+	return func(r *reporter) {
+		r.clock = c
+	}
+}
+
+// WithDestination sets the destination ID of reports.
+func WithDestination(id uint16) ReporterOption {
+	return func(r *reporter) {
+		r.destination = id
+	}
+}
+
+// reporter builds the TM packets of an on-board service and keeps its
+// message type counters (ECSS-E-ST-70-41C Section 7.4.3.1).
+type reporter struct {
+	cfg         Config
+	apid        uint16
+	clock       Clock
+	destination uint16
+	counters    map[uint16]uint16 // by service type and subtype
+}
+
+func newReporter(cfg Config, apid uint16, opts []ReporterOption) reporter {
+	r := reporter{
+		cfg:      cfg,
+		apid:     apid,
+		clock:    systemClock{},
+		counters: make(map[uint16]uint16),
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// report builds a TM[st,sst] packet carrying data and advances the
+// message type counter, wrapping at the configured width.
+func (r *reporter) report(st, sst uint8, data []byte) (*spp.SpacePacket, error) {
+	key := uint16(st)<<8 | uint16(sst)
+	h, err := NewTMSecondaryHeader(r.cfg, st, sst, r.clock.Now(),
+		WithMessageCounter(r.counters[key]), WithDestinationID(r.destination))
+	if err != nil {
+		return nil, err
+	}
+	pkt, err := NewTMPacket(r.apid, h, data)
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.CounterBits > 0 {
+		r.counters[key] = uint16((uint32(r.counters[key]) + 1) & (1<<r.cfg.CounterBits - 1))
+	}
+	return pkt, nil
+}
+
+// newCommand builds a TC[st,sst] packet carrying data.
+func newCommand(cfg Config, apid uint16, st, sst uint8, data []byte, opts []TCOption) (*spp.SpacePacket, error) {
+	h, err := NewTCSecondaryHeader(cfg, st, sst, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTCPacket(apid, h, data)
+}
+
+// request returns the subtype and application data of a TC addressed
+// to service st.
+func request(pkt *spp.SpacePacket, st uint8) (uint8, []byte, error) {
+	h, ok := pkt.SecondaryHeader.(*TCSecondaryHeader)
+	if !ok || pkt.PrimaryHeader.Type != spp.PacketTypeTC {
+		return 0, nil, ErrNotTelecommand
+	}
+	if h.ServiceType != st {
+		return 0, nil, ErrServiceMismatch
+	}
+	return h.ServiceSubtype, pkt.UserData, nil
+}
+
+// appendIDs appends a 16-bit count followed by the 16-bit IDs.
+func appendIDs(out []byte, ids []uint16) []byte {
+	out = binary.BigEndian.AppendUint16(out, uint16(len(ids)))
+	for _, id := range ids {
+		out = binary.BigEndian.AppendUint16(out, id)
+	}
+	return out
+}
+
+// readIDs reads a list written by appendIDs and returns the rest of data.
+func readIDs(data []byte) ([]uint16, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, ErrInvalidRequest
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < 2*n {
+		return nil, nil, ErrInvalidRequest
+	}
+	ids := make([]uint16, n)
+	for i := range ids {
+		ids[i] = binary.BigEndian.Uint16(data[2*i:])
+	}
+	return ids, data[2*n:], nil
+}
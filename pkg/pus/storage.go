@@ -0,0 +1,652 @@
+package pus
+
+import (
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// ServiceStorage is the service type of ST[15] on-board storage and retrieval.
+const ServiceStorage uint8 = 15
+
+// ST[15] message subtypes (ECSS-E-ST-70-41C Section 6.15).
+const (
+	StorageEnable        uint8 = 1  // TC: enable the storage function of packet stores
+	StorageDisable       uint8 = 2  // TC: disable the storage function of packet stores
+	StorageRetrieveRange uint8 = 9  // TC: start the by-time-range retrieval of a packet store
+	StorageDeleteUntil   uint8 = 11 // TC: delete packet store content up to a time
+	StorageReportSummary uint8 = 12 // TC: report the packet store summary
+	StorageSummaryReport uint8 = 13 // TM: packet store summary report
+	StorageSetOpenStart  uint8 = 14 // TC: change the open retrieval start time tag
+	StorageResumeOpen    uint8 = 15 // TC: resume the open retrieval of packet stores
+	StorageSuspendOpen   uint8 = 16 // TC: suspend the open retrieval of packet stores
+	StorageAbortRange    uint8 = 17 // TC: abort the by-time-range retrieval of packet stores
+	StorageCreate        uint8 = 20 // TC: create packet stores
+	StorageDelete        uint8 = 21 // TC: delete packet stores
+)
+
+// StoreDefinition describes a packet store to create.
+type StoreDefinition struct {
+	ID       uint16
+	Capacity uint32 // bytes
+	Type     StoreType
+}
+
+// StoreFactory creates the PacketStore of a TC[15,20] definition.
+type StoreFactory func(def StoreDefinition) (PacketStore, error)
+
+// MemoryStores is the StoreFactory creating MemoryStores.
+func MemoryStores(def StoreDefinition) (PacketStore, error) {
+	return NewMemoryStore(int64(def.Capacity), def.Type), nil
+}
+
+// StoreSummary is the entry of one packet store in a TM[15,13] summary
+// report. An empty store reports the time code epoch as its oldest and
+// newest packet times.
+type StoreSummary struct {
+	StoreID   uint16
+	Oldest    time.Time // time of the oldest stored packet
+	Newest    time.Time // time of the newest stored packet
+	OpenStart time.Time // open retrieval start time tag
+	Fill      uint8     // percentage of the capacity in use
+	OpenFill  uint8     // percentage of the capacity from the open retrieval start
+}
+
+// Humanize returns a human-readable representation of the summary.
+func (s *StoreSummary) Humanize() string {
+	const layout = "2006-01-02T15:04:05.000000Z"
+	return strings.Join([]string{
+		"  Store ID: " + strconv.Itoa(int(s.StoreID)),
+		"  Oldest: " + s.Oldest.UTC().Format(layout),
+		"  Newest: " + s.Newest.UTC().Format(layout),
+		"  Open Retrieval Start: " + s.OpenStart.UTC().Format(layout),
+		"  Fill: " + strconv.Itoa(int(s.Fill)) + "%",
+		"  From Open Retrieval Start: " + strconv.Itoa(int(s.OpenFill)) + "%",
+	}, "\n")
+}
+
+// DecodeStoreSummaries decodes the application data of a TM[15,13]
+// report: N(16) followed by N entries of store ID (16), oldest, newest
+// and open retrieval start times in cfg.Time format, fill (8) and fill
+// from the open retrieval start (8).
+func DecodeStoreSummaries(data []byte, cfg Config) ([]StoreSummary, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidReport
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	ts := cfg.Time.Size()
+	size := 4 + 3*ts
+	data = data[2:]
+	if len(data) != n*size {
+		return nil, ErrInvalidReport
+	}
+	out := make([]StoreSummary, n)
+	for i := range out {
+		rec := data[i*size:]
+		out[i].StoreID = binary.BigEndian.Uint16(rec)
+		for j, t := range []*time.Time{&out[i].Oldest, &out[i].Newest, &out[i].OpenStart} {
+			cuc, err := decodeTime(cfg.Time, rec[2+j*ts:2+(j+1)*ts])
+			if err != nil {
+				return nil, err
+			}
+			*t = cuc.Time()
+		}
+		out[i].Fill = rec[2+3*ts]
+		out[i].OpenFill = rec[3+3*ts]
+	}
+	return out, nil
+}
+
+// appendStoreTime appends t in format f, the epoch standing in for a zero time.
+func appendStoreTime(out []byte, f TimeFormat, t time.Time) ([]byte, error) {
+	if t.IsZero() {
+		t = f.epoch()
+	}
+	cuc, err := newTime(f, t)
+	if err != nil {
+		return nil, err
+	}
+	b, err := encodeTime(f, cuc)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, b...), nil
+}
+
+// readStoreTime reads a time in format f from the start of data.
+func readStoreTime(f TimeFormat, data []byte) (time.Time, []byte, error) {
+	if len(data) < f.Size() {
+		return time.Time{}, nil, ErrInvalidRequest
+	}
+	cuc, err := decodeTime(f, data[:f.Size()])
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return cuc.Time(), data[f.Size():], nil
+}
+
+// NewStorageCommand builds one of the ST[15] requests carrying a list
+// of packet store IDs: StorageEnable, StorageDisable,
+// StorageReportSummary, StorageResumeOpen, StorageSuspendOpen,
+// StorageAbortRange or StorageDelete. A summary request without IDs
+// reports every store.
+func NewStorageCommand(cfg Config, apid uint16, subtype uint8, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	switch subtype {
+	case StorageEnable, StorageDisable, StorageReportSummary, StorageResumeOpen,
+		StorageSuspendOpen, StorageAbortRange, StorageDelete:
+	default:
+		return nil, ErrInvalidSubtype
+	}
+	return newCommand(cfg, apid, ServiceStorage, subtype, appendIDs(nil, ids), opts)
+}
+
+// NewStorageTimeCommand builds TC[15,11] deleting the content of stores
+// ids up to t, or TC[15,14] moving their open retrieval start to t.
+func NewStorageTimeCommand(cfg Config, apid uint16, subtype uint8, t time.Time, ids []uint16, opts ...TCOption) (*spp.SpacePacket, error) {
+	if subtype != StorageDeleteUntil && subtype != StorageSetOpenStart {
+		return nil, ErrInvalidSubtype
+	}
+	data, err := appendStoreTime(nil, cfg.Time, t)
+	if err != nil {
+		return nil, err
+	}
+	return newCommand(cfg, apid, ServiceStorage, subtype, appendIDs(data, ids), opts)
+}
+
+// NewRetrieveRangeCommand builds TC[15,9] downlinking the packets of
+// store id time-stamped from from to to inclusive.
+func NewRetrieveRangeCommand(cfg Config, apid, id uint16, from, to time.Time, opts ...TCOption) (*spp.SpacePacket, error) {
+	data := binary.BigEndian.AppendUint16(nil, id)
+	data, err := appendStoreTime(data, cfg.Time, from)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = appendStoreTime(data, cfg.Time, to); err != nil {
+		return nil, err
+	}
+	return newCommand(cfg, apid, ServiceStorage, StorageRetrieveRange, data, opts)
+}
+
+// NewCreateStoresCommand builds TC[15,20] creating packet stores:
+// N(16) followed by N entries of store ID (16), capacity in bytes (32)
+// and type (8).
+func NewCreateStoresCommand(cfg Config, apid uint16, defs []StoreDefinition, opts ...TCOption) (*spp.SpacePacket, error) {
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(defs)))
+	for _, d := range defs {
+		data = binary.BigEndian.AppendUint16(data, d.ID)
+		data = binary.BigEndian.AppendUint32(data, d.Capacity)
+		data = append(data, byte(d.Type))
+	}
+	return newCommand(cfg, apid, ServiceStorage, StorageCreate, data, opts)
+}
+
+type packetStore struct {
+	store     PacketStore
+	enabled   bool
+	open      bool      // open retrieval in progress
+	openSeq   uint64    // next packet of the open retrieval
+	openTime  time.Time // open retrieval start time tag
+	ranging   bool      // by-time-range retrieval in progress
+	rangeSeq  uint64    // next packet of the by-time-range retrieval
+	rangeFrom time.Time
+	rangeTo   time.Time
+}
+
+// Storage is the on-board ST[15] storage and retrieval service. It
+// stores TM packets in packet stores and downlinks them on request.
+//
+// Each store has a storage function, enabled when the store is added,
+// and two retrievals that cannot run together. Open retrieval, started
+// by resuming it, downlinks every packet from the open retrieval start
+// on and follows new packets as they are stored. By-time-range
+// retrieval downlinks the packets time-stamped within a range and then
+// stops. The flight software calls Retrieve to collect the next packets
+// of the retrievals in progress, as downlink bandwidth allows.
+type Storage struct {
+	mu      sync.Mutex
+	cfg     Config
+	rep     reporter
+	factory StoreFactory
+	stores  map[uint16]*packetStore
+}
+
+// NewStorage creates the storage and retrieval service of application
+// apid. TC[15,20] creates stores with factory; a nil factory creates
+// MemoryStores.
+func NewStorage(cfg Config, apid uint16, factory StoreFactory, opts ...ReporterOption) *Storage {
+	if factory == nil {
+		factory = MemoryStores
+	}
+	return &Storage{
+		cfg:     cfg,
+		rep:     newReporter(cfg, apid, opts),
+		factory: factory,
+		stores:  make(map[uint16]*packetStore),
+	}
+}
+
+// AddStore adds store s under id, with its storage function enabled
+// and open retrieval suspended at its oldest packet.
+func (s *Storage) AddStore(id uint16, store PacketStore) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.stores[id]; ok {
+		return ErrDuplicateDefinition
+	}
+	s.stores[id] = &packetStore{store: store, enabled: true}
+	return nil
+}
+
+// Create creates and adds the stores defs, all or none.
+func (s *Storage) Create(defs ...StoreDefinition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[uint16]bool, len(defs))
+	for _, d := range defs {
+		if _, ok := s.stores[d.ID]; ok || seen[d.ID] {
+			return ErrDuplicateDefinition
+		}
+		if d.Type != StoreCircular && d.Type != StoreBounded {
+			return ErrInvalidRequest
+		}
+		seen[d.ID] = true
+	}
+	created := make([]PacketStore, 0, len(defs))
+	for _, d := range defs {
+		ps, err := s.factory(d)
+		if err != nil {
+			for _, c := range created {
+				c.Close()
+			}
+			return err
+		}
+		created = append(created, ps)
+	}
+	for i, d := range defs {
+		s.stores[d.ID] = &packetStore{store: created[i], enabled: true}
+	}
+	return nil
+}
+
+// Delete closes and removes stores ids, all or none. A store whose
+// storage function is enabled or with a retrieval in progress cannot
+// be deleted.
+func (s *Storage) Delete(ids ...uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		ps := s.stores[id]
+		if ps.enabled || ps.open || ps.ranging {
+			return ErrStoreInUse
+		}
+	}
+	var first error
+	for _, id := range ids {
+		if err := s.stores[id].store.Close(); err != nil && first == nil {
+			first = err
+		}
+		delete(s.stores, id)
+	}
+	return first
+}
+
+// Stores returns the store IDs in ascending order.
+func (s *Storage) Stores() []uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids()
+}
+
+func (s *Storage) ids() []uint16 {
+	ids := make([]uint16, 0, len(s.stores))
+	for id := range s.stores {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// check returns ErrUnknownStore unless every ID names a store.
+func (s *Storage) check(ids []uint16) error {
+	for _, id := range ids {
+		if _, ok := s.stores[id]; !ok {
+			return ErrUnknownStore
+		}
+	}
+	return nil
+}
+
+// update applies fn to stores ids, all or none.
+func (s *Storage) update(ids []uint16, fn func(*packetStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := fn(s.stores[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enable enables the storage function of stores ids.
+func (s *Storage) Enable(ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error { ps.enabled = true; return nil })
+}
+
+// Disable disables the storage function of stores ids.
+func (s *Storage) Disable(ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error { ps.enabled = false; return nil })
+}
+
+// Store stores TM packet pkt in store id, time-stamped with its
+// secondary header time. A store with its storage function disabled
+// drops the packet.
+func (s *Storage) Store(id uint16, pkt *spp.SpacePacket) error {
+	h, ok := pkt.SecondaryHeader.(*TMSecondaryHeader)
+	if !ok {
+		return ErrMissingSecondaryHeader
+	}
+	if h.Time == nil {
+		return ErrMissingTime
+	}
+	data, err := Encode(pkt, s.cfg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ps, ok := s.stores[id]
+	if !ok {
+		return ErrUnknownStore
+	}
+	if !ps.enabled {
+		return nil
+	}
+	return ps.store.Append(h.Time.Time(), data)
+}
+
+// StartRetrieval starts the by-time-range retrieval of the packets of
+// store id time-stamped from from to to inclusive.
+func (s *Storage) StartRetrieval(id uint16, from, to time.Time) error {
+	if to.Before(from) {
+		return ErrInvalidRequest
+	}
+	return s.update([]uint16{id}, func(ps *packetStore) error {
+		if ps.open || ps.ranging {
+			return ErrRetrievalInProgress
+		}
+		ps.ranging, ps.rangeSeq, ps.rangeFrom, ps.rangeTo = true, 0, from, to
+		return nil
+	})
+}
+
+// AbortRetrieval aborts the by-time-range retrieval of stores ids.
+func (s *Storage) AbortRetrieval(ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error { ps.ranging = false; return nil })
+}
+
+// ResumeOpen resumes the open retrieval of stores ids from where it
+// was suspended or from the open retrieval start.
+func (s *Storage) ResumeOpen(ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error {
+		if ps.ranging {
+			return ErrRetrievalInProgress
+		}
+		ps.open = true
+		return nil
+	})
+}
+
+// SuspendOpen suspends the open retrieval of stores ids.
+func (s *Storage) SuspendOpen(ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error { ps.open = false; return nil })
+}
+
+// SetOpenStart moves the open retrieval of stores ids to their first
+// packet time-stamped at or after t. The open retrieval must be suspended.
+func (s *Storage) SetOpenStart(t time.Time, ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error {
+		if ps.open {
+			return ErrRetrievalInProgress
+		}
+		ps.openSeq, ps.openTime = ps.store.Usage().NextSeq, t
+		return ps.store.Scan(0, func(p StoredPacket) bool {
+			if p.Time.Before(t) {
+				return true
+			}
+			ps.openSeq = p.Seq
+			return false
+		})
+	})
+}
+
+// DeleteUntil deletes the packets of stores ids up to the first packet
+// time-stamped after t.
+func (s *Storage) DeleteUntil(t time.Time, ids ...uint16) error {
+	return s.update(ids, func(ps *packetStore) error {
+		_, err := ps.store.DeleteUntil(t)
+		return err
+	})
+}
+
+// Retrieve returns up to limit packets of the retrievals in progress,
+// taking stores in ascending ID order. A by-time-range retrieval stops
+// once it has passed the newest packet.
+func (s *Storage) Retrieve(limit int) ([]*spp.SpacePacket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out [][]byte
+	for _, id := range s.ids() {
+		ps := s.stores[id]
+		switch {
+		case len(out) >= limit:
+		case ps.ranging:
+			done := true
+			err := ps.store.Scan(ps.rangeSeq, func(p StoredPacket) bool {
+				if len(out) >= limit {
+					done = false
+					return false
+				}
+				ps.rangeSeq = p.Seq + 1
+				if !p.Time.Before(ps.rangeFrom) && !p.Time.After(ps.rangeTo) {
+					out = append(out, p.Data)
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+			ps.ranging = !done
+		case ps.open:
+			err := ps.store.Scan(ps.openSeq, func(p StoredPacket) bool {
+				if len(out) >= limit {
+					return false
+				}
+				ps.openSeq, ps.openTime = p.Seq+1, p.Time
+				out = append(out, p.Data)
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	pkts := make([]*spp.SpacePacket, len(out))
+	for i, data := range out {
+		pkt, err := Decode(data, s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		pkts[i] = pkt
+	}
+	return pkts, nil
+}
+
+// Summary returns the summaries of stores ids, or of every store when
+// ids is empty.
+func (s *Storage) Summary(ids ...uint16) ([]StoreSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(ids); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		ids = s.ids()
+	}
+	out := make([]StoreSummary, len(ids))
+	for i, id := range ids {
+		ps := s.stores[id]
+		u := ps.store.Usage()
+		var pending int64
+		if err := ps.store.Scan(ps.openSeq, func(p StoredPacket) bool {
+			pending += int64(len(p.Data))
+			return true
+		}); err != nil {
+			return nil, err
+		}
+		out[i] = StoreSummary{
+			StoreID:   id,
+			Oldest:    u.Oldest,
+			Newest:    u.Newest,
+			OpenStart: ps.openTime,
+			Fill:      percent(u.Size, u.Capacity),
+			OpenFill:  percent(pending, u.Capacity),
+		}
+	}
+	return out, nil
+}
+
+func percent(n, capacity int64) uint8 {
+	if capacity <= 0 {
+		return 0
+	}
+	return uint8(min(100, n*100/capacity))
+}
+
+// Report generates a TM[15,13] summary report of stores ids, or of
+// every store when ids is empty.
+func (s *Storage) Report(ids ...uint16) (*spp.SpacePacket, error) {
+	sums, err := s.Summary(ids...)
+	if err != nil {
+		return nil, err
+	}
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(sums)))
+	for _, sum := range sums {
+		data = binary.BigEndian.AppendUint16(data, sum.StoreID)
+		for _, t := range []time.Time{sum.Oldest, sum.Newest, sum.OpenStart} {
+			if data, err = appendStoreTime(data, s.cfg.Time, t); err != nil {
+				return nil, err
+			}
+		}
+		data = append(data, sum.Fill, sum.OpenFill)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rep.report(ServiceStorage, StorageSummaryReport, data)
+}
+
+// Handle executes an ST[15] request and returns the reports it generates.
+func (s *Storage) Handle(tc *spp.SpacePacket) ([]*spp.SpacePacket, error) {
+	sst, data, err := request(tc, ServiceStorage)
+	if err != nil {
+		return nil, err
+	}
+	switch sst {
+	case StorageRetrieveRange:
+		if len(data) != 2+2*s.cfg.Time.Size() {
+			return nil, ErrInvalidRequest
+		}
+		from, rest, err := readStoreTime(s.cfg.Time, data[2:])
+		if err != nil {
+			return nil, err
+		}
+		to, _, err := readStoreTime(s.cfg.Time, rest)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.StartRetrieval(binary.BigEndian.Uint16(data), from, to)
+	case StorageCreate:
+		defs, err := decodeStoreDefinitions(data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.Create(defs...)
+	case StorageDeleteUntil, StorageSetOpenStart:
+		t, rest, err := readStoreTime(s.cfg.Time, data)
+		if err != nil {
+			return nil, err
+		}
+		ids, rest, err := readIDs(rest)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) != 0 {
+			return nil, ErrInvalidRequest
+		}
+		if sst == StorageDeleteUntil {
+			return nil, s.DeleteUntil(t, ids...)
+		}
+		return nil, s.SetOpenStart(t, ids...)
+	}
+
+	ids, rest, err := readIDs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidRequest
+	}
+	switch sst {
+	case StorageEnable:
+		return nil, s.Enable(ids...)
+	case StorageDisable:
+		return nil, s.Disable(ids...)
+	case StorageReportSummary:
+		pkt, err := s.Report(ids...)
+		if err != nil {
+			return nil, err
+		}
+		return []*spp.SpacePacket{pkt}, nil
+	case StorageResumeOpen:
+		return nil, s.ResumeOpen(ids...)
+	case StorageSuspendOpen:
+		return nil, s.SuspendOpen(ids...)
+	case StorageAbortRange:
+		return nil, s.AbortRetrieval(ids...)
+	case StorageDelete:
+		return nil, s.Delete(ids...)
+	}
+	return nil, ErrInvalidSubtype
+}
+
+func decodeStoreDefinitions(data []byte) ([]StoreDefinition, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidRequest
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) != 7*n {
+		return nil, ErrInvalidRequest
+	}
+	defs := make([]StoreDefinition, n)
+	for i := range defs {
+		rec := data[7*i:]
+		defs[i] = StoreDefinition{
+			ID:       binary.BigEndian.Uint16(rec),
+			Capacity: binary.BigEndian.Uint32(rec[2:]),
+			Type:     StoreType(rec[6]),
+		}
+	}
+	return defs, nil
+}
@@ -0,0 +1,166 @@
+package pus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// hkPacket returns a TM[3,25] report time-stamped testTime+at.
+func hkPacket(t *testing.T, cfg pus.Config, at time.Duration) *spp.SpacePacket {
+	t.Helper()
+	h, err := pus.NewTMSecondaryHeader(cfg, 3, 25, testTime.Add(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := pus.NewTMPacket(0x30, h, []byte{byte(at / time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkt
+}
+
+func retrieved(t *testing.T, s *pus.Storage, limit int) []byte {
+	t.Helper()
+	pkts, err := s.Retrieve(limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []byte
+	for _, p := range pkts {
+		out = append(out, p.UserData[0])
+	}
+	return out
+}
+
+func newTestStorage(t *testing.T) (pus.Config, *pus.Storage) {
+	t.Helper()
+	cfg := pus.DefaultConfig(pus.PUSC)
+	s := pus.NewStorage(cfg, 0x10, nil)
+	tc, _ := pus.NewCreateStoresCommand(cfg, 0x10, []pus.StoreDefinition{
+		{ID: 1, Capacity: 4096, Type: pus.StoreCircular},
+		{ID: 2, Capacity: 4096, Type: pus.StoreBounded},
+	})
+	if _, err := s.Handle(tc); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		if err := s.Store(1, hkPacket(t, cfg, time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg, s
+}
+
+func TestStorage_RangeRetrieval(t *testing.T) {
+	cfg, s := newTestStorage(t)
+	tc, _ := pus.NewRetrieveRangeCommand(cfg, 0x10, 1, testTime.Add(3*time.Second), testTime.Add(7*time.Second))
+	if _, err := s.Handle(tc); err != nil {
+		t.Fatal(err)
+	}
+	resume, _ := pus.NewStorageCommand(cfg, 0x10, pus.StorageResumeOpen, []uint16{1})
+	if _, err := s.Handle(resume); !errors.Is(err, pus.ErrRetrievalInProgress) {
+		t.Errorf("expected ErrRetrievalInProgress, got %v", err)
+	}
+
+	if got := retrieved(t, s, 3); string(got) != "\x03\x04\x05" {
+		t.Errorf("first batch %v", got)
+	}
+	if got := retrieved(t, s, 3); string(got) != "\x06\x07" {
+		t.Errorf("second batch %v", got)
+	}
+	if got := retrieved(t, s, 3); len(got) != 0 {
+		t.Errorf("retrieval continued with %v", got)
+	}
+	if _, err := s.Handle(resume); err != nil {
+		t.Errorf("resume after range retrieval: %v", err)
+	}
+}
+
+func TestStorage_OpenRetrieval(t *testing.T) {
+	cfg, s := newTestStorage(t)
+	set, _ := pus.NewStorageTimeCommand(cfg, 0x10, pus.StorageSetOpenStart, testTime.Add(8*time.Second), []uint16{1})
+	if _, err := s.Handle(set); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ResumeOpen(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := retrieved(t, s, 10); string(got) != "\x08\x09" {
+		t.Errorf("open retrieval %v", got)
+	}
+	_ = s.Store(1, hkPacket(t, cfg, 10*time.Second))
+	if got := retrieved(t, s, 10); string(got) != "\x0a" {
+		t.Errorf("open retrieval after store %v", got)
+	}
+	if err := s.SetOpenStart(testTime, 1); !errors.Is(err, pus.ErrRetrievalInProgress) {
+		t.Errorf("expected ErrRetrievalInProgress, got %v", err)
+	}
+	_ = s.SuspendOpen(1)
+	_ = s.Store(1, hkPacket(t, cfg, 11*time.Second))
+	if got := retrieved(t, s, 10); len(got) != 0 {
+		t.Errorf("suspended retrieval returned %v", got)
+	}
+}
+
+func TestStorage_SummaryAndDelete(t *testing.T) {
+	cfg, s := newTestStorage(t)
+	del, _ := pus.NewStorageTimeCommand(cfg, 0x10, pus.StorageDeleteUntil, testTime.Add(4*time.Second), []uint16{1})
+	if _, err := s.Handle(del); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.SetOpenStart(testTime.Add(7*time.Second), 1)
+
+	req, _ := pus.NewStorageCommand(cfg, 0x10, pus.StorageReportSummary, nil)
+	out, err := s.Handle(req)
+	if err != nil || len(out) != 1 {
+		t.Fatalf("summary: %d packets, %v", len(out), err)
+	}
+	sums, err := pus.DecodeStoreSummaries(downlink(t, cfg, out[0]).UserData, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 2 {
+		t.Fatalf("%d summaries", len(sums))
+	}
+	size := len(mustEncode(t, cfg, hkPacket(t, cfg, 0)))
+	got := sums[0]
+	if got.StoreID != 1 || !got.Oldest.Equal(testTime.Add(5*time.Second)) || !got.Newest.Equal(testTime.Add(9*time.Second)) ||
+		!got.OpenStart.Equal(testTime.Add(7*time.Second)) ||
+		int(got.Fill) != 5*size*100/4096 || int(got.OpenFill) != 3*size*100/4096 {
+		t.Errorf("summary %+v", got)
+	}
+	if sums[1].StoreID != 2 || sums[1].Fill != 0 {
+		t.Errorf("empty store summary %+v", sums[1])
+	}
+
+	if err := s.Delete(2); !errors.Is(err, pus.ErrStoreInUse) {
+		t.Errorf("expected ErrStoreInUse, got %v", err)
+	}
+	_ = s.Disable(2)
+	_ = s.Store(2, hkPacket(t, cfg, 0))
+	if sums, _ := s.Summary(2); sums[0].Fill != 0 {
+		t.Error("disabled store kept a packet")
+	}
+	if err := s.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Enable(2); !errors.Is(err, pus.ErrUnknownStore) {
+		t.Errorf("expected ErrUnknownStore, got %v", err)
+	}
+	if err := s.AddStore(1, pus.NewMemoryStore(10, pus.StoreBounded)); !errors.Is(err, pus.ErrDuplicateDefinition) {
+		t.Errorf("expected ErrDuplicateDefinition, got %v", err)
+	}
+}
+
+func mustEncode(t *testing.T, cfg pus.Config, pkt *spp.SpacePacket) []byte {
+	t.Helper()
+	b, err := pus.Encode(pkt, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,416 @@
+package pus
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StoreType selects what a packet store does when it is full.
+type StoreType uint8
+
+// Packet store types (ECSS-E-ST-70-41C Section 6.15.3.3).
+const (
+	StoreCircular StoreType = iota + 1 // the oldest packets are deleted to make room
+	StoreBounded                       // new packets are rejected
+)
+
+// String returns the store type name.
+func (t StoreType) String() string {
+	switch t {
+	case StoreCircular:
+		return "Circular"
+	case StoreBounded:
+		return "Bounded"
+	default:
+		return "Unknown"
+	}
+}
+
+// StoredPacket is a packet held in a packet store. Seq numbers the
+// packets of a store in storage order.
+type StoredPacket struct {
+	Seq  uint64
+	Time time.Time
+	Data []byte // the encoded packet
+}
+
+// StoreUsage summarizes the content of a packet store.
+type StoreUsage struct {
+	Count    int
+	Size     int64 // stored packet bytes
+	Capacity int64
+	Oldest   time.Time
+	Newest   time.Time
+	NextSeq  uint64 // sequence number of the next stored packet
+}
+
+// PacketStore holds packets in storage order on behalf of the ST[15]
+// storage and retrieval service. MemoryStore and FileStore implement it.
+type PacketStore interface {
+	// Append stores a packet time-stamped t. A full bounded store
+	// returns ErrStoreFull; a full circular store deletes its oldest
+	// packets.
+	Append(t time.Time, data []byte) error
+
+	// Scan calls fn for each packet with a sequence number of at least
+	// from, oldest first, until fn returns false.
+	Scan(from uint64, fn func(StoredPacket) bool) error
+
+	// DeleteUntil deletes the oldest packets up to the first packet
+	// time-stamped after t and returns the number deleted.
+	DeleteUntil(t time.Time) (int, error)
+
+	// Usage returns the store content summary.
+	Usage() StoreUsage
+
+	// Close releases the store's resources.
+	Close() error
+}
+
+// storeIndex tracks the packets of a store and applies its type when full.
+type storeIndex struct {
+	typ      StoreType
+	capacity int64
+	entries  []storeEntry
+	size     int64
+	next     uint64
+}
+
+type storeEntry struct {
+	seq  uint64
+	time time.Time
+	off  int64 // offset of the record, file stores only
+	size int64 // packet bytes
+}
+
+// reserve checks that n more bytes fit and returns how many of the
+// oldest entries must be dropped to make room.
+func (x *storeIndex) reserve(n int64) (int, error) {
+	if n > x.capacity {
+		return 0, ErrStoreFull
+	}
+	drop, free := 0, x.capacity-x.size
+	for free < n {
+		if x.typ != StoreCircular {
+			return 0, ErrStoreFull
+		}
+		free += x.entries[drop].size
+		drop++
+	}
+	return drop, nil
+}
+
+func (x *storeIndex) drop(n int) {
+	for _, e := range x.entries[:n] {
+		x.size -= e.size
+	}
+	x.entries = slices.Delete(x.entries, 0, n)
+}
+
+func (x *storeIndex) push(e storeEntry) {
+	x.entries = append(x.entries, e)
+	x.size += e.size
+	x.next = e.seq + 1
+}
+
+// until returns the number of leading entries time-stamped at or before t.
+func (x *storeIndex) until(t time.Time) int {
+	n := 0
+	for n < len(x.entries) && !x.entries[n].time.After(t) {
+		n++
+	}
+	return n
+}
+
+// from returns the position of the first entry with sequence number seq or later.
+func (x *storeIndex) from(seq uint64) int {
+	i, _ := slices.BinarySearchFunc(x.entries, seq, func(e storeEntry, s uint64) int {
+		switch {
+		case e.seq < s:
+			return -1
+		case e.seq > s:
+			return 1
+		}
+		return 0
+	})
+	return i
+}
+
+func (x *storeIndex) usage() StoreUsage {
+	u := StoreUsage{Count: len(x.entries), Size: x.size, Capacity: x.capacity, NextSeq: x.next}
+	if len(x.entries) > 0 {
+		u.Oldest, u.Newest = x.entries[0].time, x.entries[len(x.entries)-1].time
+	}
+	return u
+}
+
+// MemoryStore is a PacketStore held in memory.
+type MemoryStore struct {
+	mu   sync.Mutex
+	idx  storeIndex
+	data [][]byte
+}
+
+// NewMemoryStore creates an empty memory store holding up to capacity
+// packet bytes.
+func NewMemoryStore(capacity int64, typ StoreType) *MemoryStore {
+	return &MemoryStore{idx: storeIndex{typ: typ, capacity: capacity}}
+}
+
+// Append stores a packet time-stamped t.
+func (s *MemoryStore) Append(t time.Time, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drop, err := s.idx.reserve(int64(len(data)))
+	if err != nil {
+		return err
+	}
+	s.idx.drop(drop)
+	s.data = slices.Delete(s.data, 0, drop)
+	s.idx.push(storeEntry{seq: s.idx.next, time: t, size: int64(len(data))})
+	s.data = append(s.data, slices.Clone(data))
+	return nil
+}
+
+// Scan calls fn for each packet from sequence number from on.
+func (s *MemoryStore) Scan(from uint64, fn func(StoredPacket) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := s.idx.from(from); i < len(s.idx.entries); i++ {
+		e := s.idx.entries[i]
+		if !fn(StoredPacket{Seq: e.seq, Time: e.time, Data: s.data[i]}) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteUntil deletes the oldest packets time-stamped at or before t.
+func (s *MemoryStore) DeleteUntil(t time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.idx.until(t)
+	s.idx.drop(n)
+	s.data = slices.Delete(s.data, 0, n)
+	return n, nil
+}
+
+// Usage returns the store content summary.
+func (s *MemoryStore) Usage() StoreUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idx.usage()
+}
+
+// Close releases the stored packets.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idx.drop(len(s.idx.entries))
+	s.data = nil
+	return nil
+}
+
+// File store layout: an 8-byte header holding the offset of the oldest
+// live record, followed by records of sequence number (64), time in Unix
+// nanoseconds (64), packet length (32) and the packet.
+const (
+	fileStoreHeaderSize  = 8
+	fileRecordHeaderSize = 20
+)
+
+// FileStore is a PacketStore persisted in a file, the mass memory of a
+// simulated spacecraft or a ground archive. Deleted packets are skipped
+// by advancing the header; the file is compacted once they outweigh the
+// live packets.
+type FileStore struct {
+	mu   sync.Mutex
+	f    *os.File
+	idx  storeIndex
+	dead int64 // bytes of deleted records before the first live one
+	end  int64 // file offset after the last record
+}
+
+// OpenFileStore opens the packet store in file path, creating it if
+// needed, holding up to capacity packet bytes. Packets already in the
+// file are kept; a truncated last record is discarded. Packets beyond
+// capacity are deleted oldest first, whatever the store type.
+func OpenFileStore(path string, capacity int64, typ StoreType) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{f: f, idx: storeIndex{typ: typ, capacity: capacity}}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load rebuilds the index from the file.
+func (s *FileStore) load() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < fileStoreHeaderSize {
+		s.end = fileStoreHeaderSize
+		return s.writeHeader(fileStoreHeaderSize)
+	}
+	var hdr [fileRecordHeaderSize]byte
+	if _, err := s.f.ReadAt(hdr[:fileStoreHeaderSize], 0); err != nil {
+		return err
+	}
+	off := int64(binary.BigEndian.Uint64(hdr[:]))
+	if off < fileStoreHeaderSize || off > info.Size() {
+		return ErrCorruptStore
+	}
+	s.dead = off - fileStoreHeaderSize
+	for off+fileRecordHeaderSize <= info.Size() {
+		if _, err := s.f.ReadAt(hdr[:], off); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[16:]))
+		if off+fileRecordHeaderSize+size > info.Size() {
+			break
+		}
+		s.idx.push(storeEntry{
+			seq:  binary.BigEndian.Uint64(hdr[:]),
+			time: time.Unix(0, int64(binary.BigEndian.Uint64(hdr[8:]))),
+			off:  off,
+			size: size,
+		})
+		off += fileRecordHeaderSize + size
+	}
+	s.end = off
+	if off < info.Size() {
+		if err := s.f.Truncate(off); err != nil {
+			return err
+		}
+	}
+	if s.idx.size > s.idx.capacity {
+		n := 0
+		for over := s.idx.size - s.idx.capacity; over > 0; n++ {
+			over -= s.idx.entries[n].size
+		}
+		return s.drop(n)
+	}
+	return nil
+}
+
+func (s *FileStore) writeHeader(off int64) error {
+	var hdr [fileStoreHeaderSize]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(off))
+	_, err := s.f.WriteAt(hdr[:], 0)
+	return err
+}
+
+// drop deletes the n oldest records and compacts the file when the
+// deleted records outweigh the live ones.
+func (s *FileStore) drop(n int) error {
+	if n == 0 {
+		return nil
+	}
+	for _, e := range s.idx.entries[:n] {
+		s.dead += fileRecordHeaderSize + e.size
+	}
+	s.idx.drop(n)
+	if s.dead > s.end-fileStoreHeaderSize-s.dead {
+		return s.compact()
+	}
+	return s.writeHeader(fileStoreHeaderSize + s.dead)
+}
+
+// compact moves the live records to the start of the file.
+func (s *FileStore) compact() error {
+	src := fileStoreHeaderSize + s.dead
+	live := s.end - src
+	buf := make([]byte, live)
+	if _, err := s.f.ReadAt(buf, src); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err := s.writeHeader(fileStoreHeaderSize); err != nil {
+		return err
+	}
+	if _, err := s.f.WriteAt(buf, fileStoreHeaderSize); err != nil {
+		return err
+	}
+	if err := s.f.Truncate(fileStoreHeaderSize + live); err != nil {
+		return err
+	}
+	for i := range s.idx.entries {
+		s.idx.entries[i].off -= s.dead
+	}
+	s.end -= s.dead
+	s.dead = 0
+	return nil
+}
+
+// Append stores a packet time-stamped t.
+func (s *FileStore) Append(t time.Time, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drop, err := s.idx.reserve(int64(len(data)))
+	if err != nil {
+		return err
+	}
+	if err := s.drop(drop); err != nil {
+		return err
+	}
+	e := storeEntry{seq: s.idx.next, time: t, off: s.end, size: int64(len(data))}
+	rec := make([]byte, fileRecordHeaderSize, fileRecordHeaderSize+len(data))
+	binary.BigEndian.PutUint64(rec, e.seq)
+	binary.BigEndian.PutUint64(rec[8:], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint32(rec[16:], uint32(len(data)))
+	if _, err := s.f.WriteAt(append(rec, data...), s.end); err != nil {
+		return err
+	}
+	s.idx.push(e)
+	s.end += int64(len(rec) + len(data))
+	return nil
+}
+
+// Scan calls fn for each packet from sequence number from on.
+func (s *FileStore) Scan(from uint64, fn func(StoredPacket) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := s.idx.from(from); i < len(s.idx.entries); i++ {
+		e := s.idx.entries[i]
+		data := make([]byte, e.size)
+		if _, err := s.f.ReadAt(data, e.off+fileRecordHeaderSize); err != nil {
+			return err
+		}
+		if !fn(StoredPacket{Seq: e.seq, Time: e.time, Data: data}) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteUntil deletes the oldest packets time-stamped at or before t.
+func (s *FileStore) DeleteUntil(t time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.idx.until(t)
+	return n, s.drop(n)
+}
+
+// Usage returns the store content summary.
+func (s *FileStore) Usage() StoreUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idx.usage()
+}
+
+// Close closes the file. The stored packets remain in it.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
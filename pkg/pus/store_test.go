@@ -0,0 +1,126 @@
+package pus_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+func seqs(t *testing.T, s pus.PacketStore) []byte {
+	t.Helper()
+	var out []byte
+	if err := s.Scan(0, func(p pus.StoredPacket) bool {
+		out = append(out, p.Data[0])
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func fill(t *testing.T, s pus.PacketStore, from, to byte) {
+	t.Helper()
+	for i := from; i <= to; i++ {
+		if err := s.Append(testTime.Add(time.Duration(i)*time.Second), []byte{i, 0, 0, 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testPacketStore(t *testing.T, open func(typ pus.StoreType) pus.PacketStore) {
+	t.Run("Circular", func(t *testing.T) {
+		s := open(pus.StoreCircular)
+		defer s.Close()
+		fill(t, s, 1, 6)
+		if got := seqs(t, s); string(got) != "\x03\x04\x05\x06" {
+			t.Errorf("circular store holds %v, want [3 4 5 6]", got)
+		}
+		u := s.Usage()
+		if u.Count != 4 || u.Size != 16 || u.NextSeq != 6 ||
+			!u.Oldest.Equal(testTime.Add(3*time.Second)) || !u.Newest.Equal(testTime.Add(6*time.Second)) {
+			t.Errorf("usage %+v", u)
+		}
+		if err := s.Append(testTime, make([]byte, 17)); !errors.Is(err, pus.ErrStoreFull) {
+			t.Errorf("expected ErrStoreFull for an oversized packet, got %v", err)
+		}
+	})
+	t.Run("Bounded", func(t *testing.T) {
+		s := open(pus.StoreBounded)
+		defer s.Close()
+		fill(t, s, 1, 4)
+		if err := s.Append(testTime, []byte{5}); !errors.Is(err, pus.ErrStoreFull) {
+			t.Errorf("expected ErrStoreFull, got %v", err)
+		}
+		n, err := s.DeleteUntil(testTime.Add(2 * time.Second))
+		if err != nil || n != 2 {
+			t.Fatalf("deleted %d, %v", n, err)
+		}
+		fill(t, s, 5, 6)
+		if got := seqs(t, s); string(got) != "\x03\x04\x05\x06" {
+			t.Errorf("bounded store holds %v, want [3 4 5 6]", got)
+		}
+		var from []uint64
+		_ = s.Scan(4, func(p pus.StoredPacket) bool {
+			from = append(from, p.Seq)
+			return len(from) < 1
+		})
+		if len(from) != 1 || from[0] != 4 {
+			t.Errorf("scan from seq 4 returned %v", from)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testPacketStore(t, func(typ pus.StoreType) pus.PacketStore { return pus.NewMemoryStore(16, typ) })
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	testPacketStore(t, func(typ pus.StoreType) pus.PacketStore {
+		s, err := pus.OpenFileStore(filepath.Join(dir, typ.String()), 16, typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+func TestFileStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	s, err := pus.OpenFileStore(path, 64, pus.StoreCircular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fill(t, s, 1, 5)
+	if _, err := s.DeleteUntil(testTime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A record cut short by a crash is discarded.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.Write([]byte{0, 0, 0})
+	f.Close()
+
+	s, err = pus.OpenFileStore(path, 64, pus.StoreCircular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := seqs(t, s); string(got) != "\x02\x03\x04\x05" {
+		t.Errorf("reopened store holds %v, want [2 3 4 5]", got)
+	}
+	if u := s.Usage(); u.NextSeq != 5 {
+		t.Errorf("next sequence number %d", u.NextSeq)
+	}
+	fill(t, s, 6, 6)
+	if got := seqs(t, s); len(got) != 5 || got[4] != 6 {
+		t.Errorf("store after append holds %v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package pus
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/tcf"
+)
+
+// ServiceTimeManagement is the service type of ST[9] time management.
+const ServiceTimeManagement uint8 = 9
+
+// ST[9] message subtypes (ECSS-E-ST-70-41C Section 6.9).
+const (
+	TimeSetRate    uint8 = 1 // TC: set the time report generation rate
+	TimeCUCReport  uint8 = 2 // TM: CUC time report
+	MaxTimeRateExp       = 8 // largest exponential generation rate
+)
+
+// CUCClock supplies the on-board time as a CUC time code. Missions
+// read it from their time hardware; NewCUCClock adapts a Clock.
+type CUCClock interface {
+	Now() (*tcf.CUC, error)
+}
+
+// NewCUCClock returns a CUCClock reading c in time format f. A nil c
+// reads the system clock.
+func NewCUCClock(c Clock, f TimeFormat) CUCClock {
+	if c == nil {
+		c = systemClock{}
+	}
+	return cucClock{clock: c, format: f}
+}
+
+type cucClock struct {
+	clock  Clock
+	format TimeFormat
+}
+
+func (c cucClock) Now() (*tcf.CUC, error) { return newTime(c.format, c.clock.Now()) }
+
+// TimeReport is a decoded TM[9,2] CUC time report.
+type TimeReport struct {
+	Rate uint8    // exponential generation rate: one report every 2^Rate ticks
+	Time *tcf.CUC // on-board time, with its explicit P-field
+}
+
+// DecodeTimeReport decodes the application data of a TM[9,2] report.
+// The P-field selects the time code layout; cfg supplies the epoch of
+// an agency-defined (Level 2) time code.
+func DecodeTimeReport(data []byte, cfg Config) (*TimeReport, error) {
+	if len(data) < 1 {
+		return nil, ErrInvalidReport
+	}
+	cuc, err := tcf.DecodeCUC(data[1:], cfg.Time.epoch())
+	if err != nil {
+		return nil, err
+	}
+	return &TimeReport{Rate: data[0], Time: cuc}, nil
+}
+
+// Humanize returns a human-readable representation of the report.
+func (r *TimeReport) Humanize() string {
+	return strings.Join([]string{
+		"  Rate: 2^" + strconv.Itoa(int(r.Rate)),
+		"  Time: " + r.Time.Time().UTC().Format("2006-01-02T15:04:05.000000Z"),
+	}, "\n")
+}
+
+// NewSetTimeRateCommand builds TC[9,1] setting the time report
+// generation rate to one report every 2^exp ticks.
+func NewSetTimeRateCommand(cfg Config, apid uint16, exp uint8, opts ...TCOption) (*spp.SpacePacket, error) {
+	if exp > MaxTimeRateExp {
+		return nil, ErrInvalidRate
+	}
+	return newCommand(cfg, apid, ServiceTimeManagement, TimeSetRate, []byte{exp}, opts)
+}
+
+// TimeReporter is the on-board ST[9] time reporting service. The time
+// report is the spacecraft's time correlation anchor: the flight
+// software calls Tick on each transfer frame of the time reference
+// virtual channel and the reporter emits a TM[9,2] report every
+// 2^rate frames, starting with the first.
+type TimeReporter struct {
+	mu    sync.Mutex
+	clock CUCClock
+	rep   reporter
+	rate  uint8
+	count uint32
+}
+
+// NewTimeReporter creates the time reporting service of application
+// apid reading the on-board time from clock. The rate starts at 0, a
+// report every tick.
+func NewTimeReporter(cfg Config, apid uint16, clock CUCClock, opts ...ReporterOption) *TimeReporter {
+	return &TimeReporter{clock: clock, rep: newReporter(cfg, apid, opts)}
+}
+
+// SetRate sets the exponential generation rate and restarts the count.
+func (r *TimeReporter) SetRate(exp uint8) error {
+	if exp > MaxTimeRateExp {
+		return ErrInvalidRate
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rate, r.count = exp, 0
+	return nil
+}
+
+// Rate returns the exponential generation rate.
+func (r *TimeReporter) Rate() uint8 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rate
+}
+
+// Tick counts one frame and returns a time report when one is due, or nil.
+func (r *TimeReporter) Tick() (*spp.SpacePacket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := r.count == 0
+	r.count = (r.count + 1) % (1 << r.rate)
+	if !due {
+		return nil, nil
+	}
+	return r.report()
+}
+
+// Report generates a time report now.
+func (r *TimeReporter) Report() (*spp.SpacePacket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.report()
+}
+
+func (r *TimeReporter) report() (*spp.SpacePacket, error) {
+	now, err := r.clock.Now()
+	if err != nil {
+		return nil, err
+	}
+	t, err := now.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return r.rep.report(ServiceTimeManagement, TimeCUCReport, append([]byte{r.rate}, t...))
+}
+
+// Handle executes an ST[9] request.
+func (r *TimeReporter) Handle(tc *spp.SpacePacket) ([]*spp.SpacePacket, error) {
+	sst, data, err := request(tc, ServiceTimeManagement)
+	if err != nil {
+		return nil, err
+	}
+	if sst != TimeSetRate {
+		return nil, ErrInvalidSubtype
+	}
+	if len(data) != 1 {
+		return nil, ErrInvalidRequest
+	}
+	return nil, r.SetRate(data[0])
+}
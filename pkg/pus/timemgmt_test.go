@@ -0,0 +1,61 @@
+package pus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+)
+
+func TestTimeReporter(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	clk := &fakeClock{now: testTime}
+	tr := pus.NewTimeReporter(cfg, 0, pus.NewCUCClock(clk, cfg.Time))
+
+	rate, _ := pus.NewSetTimeRateCommand(cfg, 0, 2)
+	if _, err := tr.Handle(rate); err != nil {
+		t.Fatal(err)
+	}
+	var reports int
+	for i := range 8 {
+		pkt, err := tr.Tick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkt == nil {
+			continue
+		}
+		if i%4 != 0 {
+			t.Errorf("report on tick %d", i)
+		}
+		reports++
+
+		got := downlink(t, cfg, pkt)
+		st, sst, _ := pus.Service(got)
+		if st != pus.ServiceTimeManagement || sst != pus.TimeCUCReport {
+			t.Fatalf("service ST[%d,%d]", st, sst)
+		}
+		r, err := pus.DecodeTimeReport(got.UserData, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Rate != 2 {
+			t.Errorf("rate = %d", r.Rate)
+		}
+		if d := r.Time.Time().Sub(clk.now); d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("time = %v, want %v", r.Time.Time(), clk.now)
+		}
+		clk.Advance(time.Second)
+	}
+	if reports != 2 {
+		t.Errorf("%d reports in 8 ticks at rate 2^2", reports)
+	}
+
+	if err := tr.SetRate(9); !errors.Is(err, pus.ErrInvalidRate) {
+		t.Errorf("expected ErrInvalidRate, got %v", err)
+	}
+	if _, err := pus.NewSetTimeRateCommand(cfg, 0, 9); !errors.Is(err, pus.ErrInvalidRate) {
+		t.Errorf("expected ErrInvalidRate, got %v", err)
+	}
+}
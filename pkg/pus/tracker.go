@@ -0,0 +1,350 @@
+package pus
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// Clock supplies the current time to the verification tracker.
+// Inject a fake clock with WithTrackerClock for deterministic tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// CommandState is the verification state of a tracked telecommand.
+type CommandState uint8
+
+const (
+	CommandPending   CommandState = iota + 1 // sent, no report received
+	CommandAccepted                          // acceptance succeeded
+	CommandStarted                           // execution started
+	CommandCompleted                         // execution completed
+	CommandFailed                            // a stage reported failure
+	CommandTimedOut                          // an awaited report did not arrive in time
+)
+
+// String returns the state name.
+func (s CommandState) String() string {
+	switch s {
+	case CommandPending:
+		return "Pending"
+	case CommandAccepted:
+		return "Accepted"
+	case CommandStarted:
+		return "Started"
+	case CommandCompleted:
+		return "Completed"
+	case CommandFailed:
+		return "Failed"
+	case CommandTimedOut:
+		return "Timed Out"
+	default:
+		return "Unknown"
+	}
+}
+
+// CommandStatus is the verification status of a tracked telecommand.
+type CommandStatus struct {
+	Request        RequestID
+	ServiceType    uint8
+	ServiceSubtype uint8
+	AckFlags       uint8
+	State          CommandState
+	Stage          Stage    // stage of the last report
+	Steps          []uint16 // step IDs of successful progress reports
+	FailureCode    uint16
+	FailureData    []byte
+	RoutingFailure bool      // failure reported by ST[1,10]
+	Sent           time.Time // when the command was tracked
+	Updated        time.Time // when the last report arrived
+	Deadline       time.Time // zero when no report is awaited or the stage has no timeout
+}
+
+// Final reports whether the status will not change unless a failure
+// report arrives: the command completed, failed or timed out, or no
+// further success report was requested by its acknowledgement flags.
+func (s *CommandStatus) Final() bool {
+	switch s.State {
+	case CommandCompleted, CommandFailed, CommandTimedOut:
+		return true
+	}
+	return s.Awaited() == 0
+}
+
+// closed reports whether the command accepts no further reports. A
+// timed-out command still accepts late reports.
+func (s *CommandStatus) closed() bool {
+	return s.State == CommandCompleted || s.State == CommandFailed
+}
+
+// Awaited returns the next stage whose success report was requested,
+// or 0. For a timed-out command it is the stage that timed out.
+func (s *CommandStatus) Awaited() Stage {
+	if s.closed() {
+		return 0
+	}
+	for st := s.Stage + 1; st <= StageCompletion; st++ {
+		if s.AckFlags&st.AckFlag() != 0 {
+			return st
+		}
+	}
+	return 0
+}
+
+// Humanize returns a human-readable representation of the status.
+func (s *CommandStatus) Humanize() string {
+	lines := []string{
+		"  Request: " + s.Request.String(),
+		"  Service: ST[" + strconv.Itoa(int(s.ServiceType)) + "," + strconv.Itoa(int(s.ServiceSubtype)) + "]",
+		"  State: " + s.State.String(),
+	}
+	if s.Stage != 0 {
+		lines = append(lines, "  Stage: "+s.Stage.String())
+	}
+	if st := s.Awaited(); st != 0 {
+		lines = append(lines, "  Awaiting: "+st.String())
+	}
+	if len(s.Steps) > 0 {
+		steps := make([]string, len(s.Steps))
+		for i, id := range s.Steps {
+			steps[i] = strconv.Itoa(int(id))
+		}
+		lines = append(lines, "  Steps: "+strings.Join(steps, ", "))
+	}
+	if s.State == CommandFailed {
+		code := "  Failure Code: " + strconv.Itoa(int(s.FailureCode))
+		if s.RoutingFailure {
+			code += " (routing)"
+		}
+		lines = append(lines, code)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TrackerOption configures a Tracker.
+type TrackerOption func(*Tracker)
+
+// WithStageTimeout sets how long the tracker waits for the success
+// report of stage, measured from the previous report or from Track.
+// Zero disables the timeout, which is the default for every stage.
+func WithStageTimeout(stage Stage, d time.Duration) TrackerOption {
+	return func(t *Tracker) {
+		t.timeouts[stage] = d
+	}
+}
+
+// WithTrackerClock sets the clock used for timestamps and timeouts.
+func WithTrackerClock(c Clock) TrackerOption {
+	return func(t *Tracker) {
+		t.clock = c
+	}
+}
+
+// Tracker correlates ST[1] verification reports with the telecommands
+// they verify, per ECSS-E-ST-70-41C Section 6.1.
+//
+// Commands are tracked by request ID after they are sent. Success
+// reports for stages the command did not request in its
+// acknowledgement flags are rejected; failure reports are always
+// accepted. A stage whose success report is awaited times out when
+// CheckTimeouts runs past its deadline. A late report still updates a
+// timed-out command.
+type Tracker struct {
+	mu       sync.Mutex
+	cfg      Config
+	commands map[RequestID]*CommandStatus
+	timeouts map[Stage]time.Duration
+	clock    Clock
+}
+
+// NewTracker creates a tracker decoding reports laid out by cfg.
+func NewTracker(cfg Config, opts ...TrackerOption) *Tracker {
+	t := &Tracker{
+		cfg:      cfg,
+		commands: make(map[RequestID]*CommandStatus),
+		timeouts: make(map[Stage]time.Duration),
+		clock:    systemClock{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Track starts verifying a sent telecommand. pkt must carry a
+// TCSecondaryHeader and its final sequence count. Tracking a request ID
+// again replaces the earlier command, e.g. after the sequence count wraps.
+func (t *Tracker) Track(pkt *spp.SpacePacket) (RequestID, error) {
+	h, ok := pkt.SecondaryHeader.(*TCSecondaryHeader)
+	if !ok || pkt.PrimaryHeader.Type != spp.PacketTypeTC {
+		return RequestID{}, ErrNotTelecommand
+	}
+	id := NewRequestID(pkt)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	s := &CommandStatus{
+		Request:        id,
+		ServiceType:    h.ServiceType,
+		ServiceSubtype: h.ServiceSubtype,
+		AckFlags:       h.AckFlags,
+		State:          CommandPending,
+		Sent:           now,
+		Updated:        now,
+	}
+	t.arm(s)
+	t.commands[id] = s
+	return id, nil
+}
+
+// Handle decodes an ST[1] report packet, as returned by Decode, and
+// applies it to the command it verifies.
+func (t *Tracker) Handle(pkt *spp.SpacePacket) (CommandStatus, error) {
+	h, ok := pkt.SecondaryHeader.(*TMSecondaryHeader)
+	if !ok || h.ServiceType != ServiceVerification {
+		return CommandStatus{}, ErrNotVerificationReport
+	}
+	r, err := DecodeVerificationReport(h.ServiceSubtype, pkt.UserData, t.cfg)
+	if err != nil {
+		return CommandStatus{}, err
+	}
+	return t.HandleReport(r)
+}
+
+// HandleReport applies a decoded report to the command it verifies
+// and returns the updated status.
+func (t *Tracker) HandleReport(r *VerificationReport) (CommandStatus, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.commands[r.Request]
+	if !ok {
+		return CommandStatus{}, ErrUnknownRequest
+	}
+	if s.closed() {
+		return s.clone(), ErrRequestClosed
+	}
+	stage := r.Stage()
+	if r.Success() && s.AckFlags&stage.AckFlag() == 0 {
+		return s.clone(), ErrUnrequestedReport
+	}
+
+	s.Updated = t.clock.Now()
+	if !r.Success() {
+		s.State = CommandFailed
+		s.Stage = stage
+		s.FailureCode = r.FailureCode
+		s.FailureData = append([]byte(nil), r.FailureData...)
+		s.RoutingFailure = r.Subtype == VerifyRoutingFailure
+		if stage == StageProgress {
+			s.Steps = append(s.Steps, r.StepID)
+		}
+		s.Deadline = time.Time{}
+		return s.clone(), nil
+	}
+
+	// Reports may be lost or reordered: never move back a stage.
+	if stage > s.Stage {
+		s.Stage = stage
+	}
+	switch {
+	case s.Stage == StageCompletion:
+		s.State = CommandCompleted
+	case s.Stage >= StageStart:
+		s.State = CommandStarted
+	default:
+		s.State = CommandAccepted
+	}
+	if stage == StageProgress {
+		s.Steps = append(s.Steps, r.StepID)
+	}
+	t.arm(s)
+	return s.clone(), nil
+}
+
+// CheckTimeouts marks every command whose awaited report is overdue as
+// timed out and returns their statuses.
+func (t *Tracker) CheckTimeouts() []CommandStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	var out []CommandStatus
+	for _, s := range t.commands {
+		if s.State == CommandTimedOut || s.closed() || s.Deadline.IsZero() || now.Before(s.Deadline) {
+			continue
+		}
+		s.State = CommandTimedOut
+		s.Deadline = time.Time{}
+		out = append(out, s.clone())
+	}
+	sortStatuses(out)
+	return out
+}
+
+// Status returns the status of a tracked command.
+func (t *Tracker) Status(id RequestID) (CommandStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.commands[id]
+	if !ok {
+		return CommandStatus{}, false
+	}
+	return s.clone(), true
+}
+
+// Pending returns the commands that are not final, oldest first.
+func (t *Tracker) Pending() []CommandStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []CommandStatus
+	for _, s := range t.commands {
+		if !s.Final() {
+			out = append(out, s.clone())
+		}
+	}
+	sortStatuses(out)
+	return out
+}
+
+// Forget stops tracking a command.
+func (t *Tracker) Forget(id RequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.commands, id)
+}
+
+// arm sets the deadline of the next awaited stage.
+func (t *Tracker) arm(s *CommandStatus) {
+	s.Deadline = time.Time{}
+	if st := s.Awaited(); st != 0 && t.timeouts[st] > 0 {
+		s.Deadline = s.Updated.Add(t.timeouts[st])
+	}
+}
+
+func (s *CommandStatus) clone() CommandStatus {
+	c := *s
+	c.Steps = slices.Clone(s.Steps)
+	c.FailureData = slices.Clone(s.FailureData)
+	return c
+}
+
+func sortStatuses(out []CommandStatus) {
+	slices.SortFunc(out, func(a, b CommandStatus) int {
+		if c := a.Sent.Compare(b.Sent); c != 0 {
+			return c
+		}
+		if a.Request.PacketID != b.Request.PacketID {
+			return int(a.Request.PacketID) - int(b.Request.PacketID)
+		}
+		return int(a.Request.SequenceControl) - int(b.Request.SequenceControl)
+	})
+}
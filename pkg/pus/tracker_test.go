@@ -0,0 +1,190 @@
+package pus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// sendTC builds a TC and stamps its sequence count the way spp.Service does.
+func sendTC(t *testing.T, cfg pus.Config, seq uint16, ack uint8) *spp.SpacePacket {
+	t.Helper()
+	h, err := pus.NewTCSecondaryHeader(cfg, 8, 1, pus.WithAckFlags(ack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := pus.NewTCPacket(0x42, h, []byte{1}, spp.WithSequenceCount(seq))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkt
+}
+
+// report round-trips a verification report through its TM packet.
+func report(t *testing.T, cfg pus.Config, r *pus.VerificationReport) *spp.SpacePacket {
+	t.Helper()
+	pkt, err := pus.NewVerificationPacket(1, cfg, r, testTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pus.Encode(pkt, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pus.Decode(b, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestTracker_Lifecycle(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	tr := pus.NewTracker(cfg)
+	id, err := tr.Track(sendTC(t, cfg, 7, pus.AckAll))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Pending()) != 1 {
+		t.Fatal("command not pending")
+	}
+
+	steps := []struct {
+		r     pus.VerificationReport
+		state pus.CommandState
+	}{
+		{pus.VerificationReport{Subtype: pus.VerifyAcceptanceSuccess, Request: id}, pus.CommandAccepted},
+		{pus.VerificationReport{Subtype: pus.VerifyStartSuccess, Request: id}, pus.CommandStarted},
+		{pus.VerificationReport{Subtype: pus.VerifyProgressSuccess, Request: id, StepID: 1}, pus.CommandStarted},
+		{pus.VerificationReport{Subtype: pus.VerifyProgressSuccess, Request: id, StepID: 2}, pus.CommandStarted},
+		{pus.VerificationReport{Subtype: pus.VerifyCompletionSuccess, Request: id}, pus.CommandCompleted},
+	}
+	for _, s := range steps {
+		st, err := tr.Handle(report(t, cfg, &s.r))
+		if err != nil {
+			t.Fatalf("subtype %d: %v", s.r.Subtype, err)
+		}
+		if st.State != s.state {
+			t.Errorf("subtype %d: state %s, want %s", s.r.Subtype, st.State, s.state)
+		}
+	}
+	st, _ := tr.Status(id)
+	if !st.Final() || len(st.Steps) != 2 || st.Steps[1] != 2 {
+		t.Errorf("final status %+v", st)
+	}
+	if len(tr.Pending()) != 0 {
+		t.Error("completed command still pending")
+	}
+	late := &pus.VerificationReport{Subtype: pus.VerifyCompletionFailure, Request: id}
+	if _, err := tr.HandleReport(late); !errors.Is(err, pus.ErrRequestClosed) {
+		t.Errorf("expected ErrRequestClosed, got %v", err)
+	}
+}
+
+func TestTracker_Failure(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	tr := pus.NewTracker(cfg)
+	id, _ := tr.Track(sendTC(t, cfg, 1, pus.AckAcceptance))
+
+	// Failure reports are generated whatever the acknowledgement flags.
+	r := &pus.VerificationReport{Subtype: pus.VerifyStartFailure, Request: id, FailureCode: 0x0F0F, FailureData: []byte{0xDE, 0xAD}}
+	st, err := tr.Handle(report(t, cfg, r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != pus.CommandFailed || st.Stage != pus.StageStart || st.FailureCode != 0x0F0F ||
+		string(st.FailureData) != "\xDE\xAD" || st.RoutingFailure {
+		t.Errorf("status %+v", st)
+	}
+
+	id2, _ := tr.Track(sendTC(t, cfg, 2, pus.AckAll))
+	st, err = tr.HandleReport(&pus.VerificationReport{Subtype: pus.VerifyRoutingFailure, Request: id2, FailureCode: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != pus.CommandFailed || st.Stage != pus.StageAcceptance || !st.RoutingFailure {
+		t.Errorf("routing status %+v", st)
+	}
+}
+
+func TestTracker_AckFlags(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	tr := pus.NewTracker(cfg)
+	id, _ := tr.Track(sendTC(t, cfg, 3, pus.AckAcceptance|pus.AckCompletion))
+
+	if _, err := tr.HandleReport(&pus.VerificationReport{Subtype: pus.VerifyStartSuccess, Request: id}); !errors.Is(err, pus.ErrUnrequestedReport) {
+		t.Errorf("expected ErrUnrequestedReport, got %v", err)
+	}
+	st, _ := tr.HandleReport(&pus.VerificationReport{Subtype: pus.VerifyAcceptanceSuccess, Request: id})
+	if st.State != pus.CommandAccepted || st.Awaited() != pus.StageCompletion || st.Final() {
+		t.Errorf("status %+v awaiting %s", st, st.Awaited())
+	}
+
+	none, _ := tr.Track(sendTC(t, cfg, 4, 0))
+	if st, _ := tr.Status(none); !st.Final() {
+		t.Error("command requesting no reports is not final")
+	}
+
+	unknown := pus.RequestID{PacketID: 0x1842, SequenceControl: 0xC063}
+	if _, err := tr.HandleReport(&pus.VerificationReport{Subtype: pus.VerifyAcceptanceSuccess, Request: unknown}); !errors.Is(err, pus.ErrUnknownRequest) {
+		t.Errorf("expected ErrUnknownRequest, got %v", err)
+	}
+	tm, _ := pus.NewTMSecondaryHeader(cfg, 3, 25, testTime)
+	hk, _ := pus.NewTMPacket(1, tm, nil)
+	if _, err := tr.Handle(hk); !errors.Is(err, pus.ErrNotVerificationReport) {
+		t.Errorf("expected ErrNotVerificationReport, got %v", err)
+	}
+	if _, err := tr.Track(hk); !errors.Is(err, pus.ErrNotTelecommand) {
+		t.Errorf("expected ErrNotTelecommand, got %v", err)
+	}
+}
+
+func TestTracker_Timeouts(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	tr := pus.NewTracker(cfg,
+		pus.WithTrackerClock(clk),
+		pus.WithStageTimeout(pus.StageAcceptance, time.Second),
+		pus.WithStageTimeout(pus.StageCompletion, 10*time.Second))
+	id, _ := tr.Track(sendTC(t, cfg, 5, pus.AckAcceptance|pus.AckCompletion))
+
+	clk.Advance(500 * time.Millisecond)
+	if out := tr.CheckTimeouts(); len(out) != 0 {
+		t.Fatalf("timed out early: %+v", out)
+	}
+	if _, err := tr.HandleReport(&pus.VerificationReport{Subtype: pus.VerifyAcceptanceSuccess, Request: id}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The completion timeout runs from the acceptance report.
+	clk.Advance(9 * time.Second)
+	if out := tr.CheckTimeouts(); len(out) != 0 {
+		t.Fatalf("timed out early: %+v", out)
+	}
+	clk.Advance(time.Second)
+	out := tr.CheckTimeouts()
+	if len(out) != 1 || out[0].State != pus.CommandTimedOut || out[0].Awaited() != pus.StageCompletion {
+		t.Fatalf("timeouts %+v", out)
+	}
+	if len(tr.CheckTimeouts()) != 0 {
+		t.Error("timeout reported twice")
+	}
+
+	// A late report still settles the command.
+	st, err := tr.HandleReport(&pus.VerificationReport{Subtype: pus.VerifyCompletionSuccess, Request: id})
+	if err != nil || st.State != pus.CommandCompleted {
+		t.Errorf("late completion: %+v, %v", st, err)
+	}
+	tr.Forget(id)
+	if _, ok := tr.Status(id); ok {
+		t.Error("forgotten command still tracked")
+	}
+}
@@ -0,0 +1,224 @@
+package pus
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+// ServiceVerification is the service type of ST[1] request verification.
+const ServiceVerification uint8 = 1
+
+// ST[1] message subtypes (ECSS-E-ST-70-41C Section 6.1).
+const (
+	VerifyAcceptanceSuccess uint8 = 1
+	VerifyAcceptanceFailure uint8 = 2
+	VerifyStartSuccess      uint8 = 3
+	VerifyStartFailure      uint8 = 4
+	VerifyProgressSuccess   uint8 = 5
+	VerifyProgressFailure   uint8 = 6
+	VerifyCompletionSuccess uint8 = 7
+	VerifyCompletionFailure uint8 = 8
+	VerifyRoutingFailure    uint8 = 10
+)
+
+// RequestIDSize is the size of an encoded request ID in bytes.
+const RequestIDSize = 4
+
+// RequestID identifies a telecommand in ST[1] reports: the first four
+// octets of its primary header (ECSS-E-ST-70-41C Section 5.4.11.4).
+type RequestID struct {
+	PacketID        uint16 // version, type, secondary header flag and APID
+	SequenceControl uint16 // sequence flags and sequence count
+}
+
+// NewRequestID returns the request ID of pkt. Take it after the packet
+// is sent, once spp.Service has stamped its sequence count.
+func NewRequestID(pkt *spp.SpacePacket) RequestID {
+	ph := pkt.PrimaryHeader
+	return RequestID{
+		PacketID:        uint16(ph.Version&0x07)<<13 | uint16(ph.Type&0x01)<<12 | uint16(ph.SecondaryHeaderFlag&0x01)<<11 | ph.APID&0x07FF,
+		SequenceControl: uint16(ph.SequenceFlags&0x03)<<14 | ph.SequenceCount&0x3FFF,
+	}
+}
+
+// APID returns the application process ID of the request.
+func (r RequestID) APID() uint16 { return r.PacketID & 0x07FF }
+
+// SequenceCount returns the packet sequence count of the request.
+func (r RequestID) SequenceCount() uint16 { return r.SequenceControl & 0x3FFF }
+
+// String returns the APID and sequence count.
+func (r RequestID) String() string {
+	return "APID " + strconv.Itoa(int(r.APID())) + " seq " + strconv.Itoa(int(r.SequenceCount()))
+}
+
+// Stage is a request verification stage.
+type Stage uint8
+
+const (
+	StageAcceptance Stage = iota + 1
+	StageStart
+	StageProgress
+	StageCompletion
+)
+
+// String returns the stage name.
+func (s Stage) String() string {
+	switch s {
+	case StageAcceptance:
+		return "Acceptance"
+	case StageStart:
+		return "Start"
+	case StageProgress:
+		return "Progress"
+	case StageCompletion:
+		return "Completion"
+	default:
+		return "Unknown"
+	}
+}
+
+// AckFlag returns the TC acknowledgement flag requesting the stage's
+// success report.
+func (s Stage) AckFlag() uint8 {
+	switch s {
+	case StageAcceptance:
+		return AckAcceptance
+	case StageStart:
+		return AckStart
+	case StageProgress:
+		return AckProgress
+	case StageCompletion:
+		return AckCompletion
+	}
+	return 0
+}
+
+// VerificationReport is an ST[1] request verification report.
+type VerificationReport struct {
+	Subtype     uint8
+	Request     RequestID
+	StepID      uint16 // progress reports
+	FailureCode uint16 // failure reports
+	FailureData []byte // failure reports
+}
+
+// Stage returns the verification stage the report covers. Routing
+// failures are reported at acceptance.
+func (r *VerificationReport) Stage() Stage {
+	switch r.Subtype {
+	case VerifyAcceptanceSuccess, VerifyAcceptanceFailure, VerifyRoutingFailure:
+		return StageAcceptance
+	case VerifyStartSuccess, VerifyStartFailure:
+		return StageStart
+	case VerifyProgressSuccess, VerifyProgressFailure:
+		return StageProgress
+	case VerifyCompletionSuccess, VerifyCompletionFailure:
+		return StageCompletion
+	}
+	return 0
+}
+
+// Success reports whether the report is a success report.
+func (r *VerificationReport) Success() bool {
+	return r.Subtype%2 == 1 && r.Subtype <= VerifyCompletionSuccess
+}
+
+// Encode serializes the report's application data. cfg sets the step
+// ID and failure code widths.
+func (r *VerificationReport) Encode(cfg Config) ([]byte, error) {
+	if r.Stage() == 0 {
+		return nil, ErrInvalidSubtype
+	}
+	if !fits(r.StepID, cfg.StepIDBits) || !fits(r.FailureCode, cfg.FailureCodeBits) {
+		return nil, ErrFieldOverflow
+	}
+	out := binary.BigEndian.AppendUint16(nil, r.Request.PacketID)
+	out = binary.BigEndian.AppendUint16(out, r.Request.SequenceControl)
+	if r.Stage() == StageProgress {
+		out = appendField(out, r.StepID, cfg.StepIDBits)
+	}
+	if !r.Success() {
+		out = appendField(out, r.FailureCode, cfg.FailureCodeBits)
+		out = append(out, r.FailureData...)
+	}
+	return out, nil
+}
+
+// DecodeVerificationReport parses the application data of an ST[1]
+// report of the given subtype.
+func DecodeVerificationReport(subtype uint8, data []byte, cfg Config) (*VerificationReport, error) {
+	r := &VerificationReport{Subtype: subtype}
+	if r.Stage() == 0 {
+		return nil, ErrInvalidSubtype
+	}
+	n := RequestIDSize
+	if r.Stage() == StageProgress {
+		n += cfg.StepIDBits / 8
+	}
+	if !r.Success() {
+		n += cfg.FailureCodeBits / 8
+	}
+	if len(data) < n || (r.Success() && len(data) != n) {
+		return nil, ErrInvalidReport
+	}
+	r.Request = RequestID{
+		PacketID:        binary.BigEndian.Uint16(data),
+		SequenceControl: binary.BigEndian.Uint16(data[2:]),
+	}
+	off := RequestIDSize
+	if r.Stage() == StageProgress {
+		r.StepID, off = readField(data, off, cfg.StepIDBits)
+	}
+	if !r.Success() {
+		r.FailureCode, off = readField(data, off, cfg.FailureCodeBits)
+		if off < len(data) {
+			r.FailureData = append([]byte(nil), data[off:]...)
+		}
+	}
+	return r, nil
+}
+
+// NewVerificationPacket builds the ST[1] TM packet reporting r,
+// generated at t.
+func NewVerificationPacket(apid uint16, cfg Config, r *VerificationReport, t time.Time, opts ...TMOption) (*spp.SpacePacket, error) {
+	data, err := r.Encode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	h, err := NewTMSecondaryHeader(cfg, ServiceVerification, r.Subtype, t, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTMPacket(apid, h, data)
+}
+
+// Humanize returns a human-readable representation of the report.
+func (r *VerificationReport) Humanize() string {
+	outcome := "Success"
+	if !r.Success() {
+		outcome = "Failure"
+	}
+	if r.Subtype == VerifyRoutingFailure {
+		outcome = "Routing Failure"
+	}
+	lines := []string{
+		"  Report: ST[1," + strconv.Itoa(int(r.Subtype)) + "] " + r.Stage().String() + " " + outcome,
+		"  Request: " + r.Request.String(),
+	}
+	if r.Stage() == StageProgress {
+		lines = append(lines, "  Step ID: "+strconv.Itoa(int(r.StepID)))
+	}
+	if !r.Success() {
+		lines = append(lines, "  Failure Code: "+strconv.Itoa(int(r.FailureCode)))
+		if len(r.FailureData) > 0 {
+			lines = append(lines, "  Failure Data: "+hex.EncodeToString(r.FailureData))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,108 @@
+package pus_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/pus"
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+func TestNewRequestID(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	h, _ := pus.NewTCSecondaryHeader(cfg, 17, 1)
+	pkt, err := pus.NewTCPacket(0x123, h, nil, spp.WithSequenceCount(0x0ABC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := pkt.Encode()
+
+	id := pus.NewRequestID(pkt)
+	if id.PacketID != uint16(b[0])<<8|uint16(b[1]) || id.SequenceControl != uint16(b[2])<<8|uint16(b[3]) {
+		t.Errorf("request ID %04X %04X, want first octets % X", id.PacketID, id.SequenceControl, b[:4])
+	}
+	if id.APID() != 0x123 || id.SequenceCount() != 0x0ABC {
+		t.Errorf("APID %d seq %d", id.APID(), id.SequenceCount())
+	}
+}
+
+func TestVerificationReport_RoundTrip(t *testing.T) {
+	req := pus.RequestID{PacketID: 0x1964, SequenceControl: 0xC005}
+	tests := []struct {
+		name string
+		r    pus.VerificationReport
+		size int
+	}{
+		{"acceptance success", pus.VerificationReport{Subtype: pus.VerifyAcceptanceSuccess, Request: req}, 4},
+		{"progress success", pus.VerificationReport{Subtype: pus.VerifyProgressSuccess, Request: req, StepID: 3}, 5},
+		{"start failure", pus.VerificationReport{Subtype: pus.VerifyStartFailure, Request: req, FailureCode: 0x0102, FailureData: []byte{9, 8}}, 8},
+		{"progress failure", pus.VerificationReport{Subtype: pus.VerifyProgressFailure, Request: req, StepID: 2, FailureCode: 7}, 7},
+		{"routing failure", pus.VerificationReport{Subtype: pus.VerifyRoutingFailure, Request: req, FailureCode: 1}, 6},
+	}
+	cfg := pus.DefaultConfig(pus.PUSC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.r.Encode(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b) != tt.size {
+				t.Fatalf("encoded %d bytes, want %d", len(b), tt.size)
+			}
+			got, err := pus.DecodeVerificationReport(tt.r.Subtype, b, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Request != req || got.StepID != tt.r.StepID || got.FailureCode != tt.r.FailureCode ||
+				!bytes.Equal(got.FailureData, tt.r.FailureData) {
+				t.Errorf("decoded %+v, want %+v", got, tt.r)
+			}
+		})
+	}
+}
+
+func TestVerificationReport_Errors(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	if _, err := pus.DecodeVerificationReport(9, make([]byte, 4), cfg); !errors.Is(err, pus.ErrInvalidSubtype) {
+		t.Errorf("expected ErrInvalidSubtype, got %v", err)
+	}
+	if _, err := pus.DecodeVerificationReport(pus.VerifyAcceptanceSuccess, make([]byte, 5), cfg); !errors.Is(err, pus.ErrInvalidReport) {
+		t.Errorf("expected ErrInvalidReport for trailing data, got %v", err)
+	}
+	if _, err := pus.DecodeVerificationReport(pus.VerifyCompletionFailure, make([]byte, 5), cfg); !errors.Is(err, pus.ErrInvalidReport) {
+		t.Errorf("expected ErrInvalidReport for short failure, got %v", err)
+	}
+	r := &pus.VerificationReport{Subtype: pus.VerifyProgressSuccess, StepID: 256}
+	if _, err := r.Encode(cfg); !errors.Is(err, pus.ErrFieldOverflow) {
+		t.Errorf("expected ErrFieldOverflow, got %v", err)
+	}
+}
+
+func TestNewVerificationPacket(t *testing.T) {
+	cfg := pus.DefaultConfig(pus.PUSC)
+	r := &pus.VerificationReport{Subtype: pus.VerifyCompletionFailure, Request: pus.RequestID{PacketID: 0x1801, SequenceControl: 0xC001}, FailureCode: 42}
+	pkt, err := pus.NewVerificationPacket(1, cfg, r, testTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pus.Encode(pkt, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pus.Decode(b, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, sst, _ := pus.Service(got)
+	if st != pus.ServiceVerification || sst != pus.VerifyCompletionFailure {
+		t.Fatalf("service ST[%d,%d]", st, sst)
+	}
+	dr, err := pus.DecodeVerificationReport(sst, got.UserData, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.Request != r.Request || dr.FailureCode != 42 || dr.Stage() != pus.StageCompletion || dr.Success() {
+		t.Errorf("decoded %+v", dr)
+	}
+}
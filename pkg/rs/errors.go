@@ -0,0 +1,20 @@
+package rs
+
+import "errors"
+
+var (
+	// ErrInvalidParameters indicates code parameters that do not define a Reed-Solomon code.
+	ErrInvalidParameters = errors.New("invalid Reed-Solomon code parameters")
+
+	// ErrInvalidDataLength indicates the data length does not match the code parameters.
+	ErrInvalidDataLength = errors.New("data length does not match code parameters")
+
+	// ErrInvalidSymbol indicates a symbol value that does not fit the symbol size.
+	ErrInvalidSymbol = errors.New("symbol value exceeds the symbol size")
+
+	// ErrInvalidErasure indicates an erasure position outside the codeword.
+	ErrInvalidErasure = errors.New("erasure position outside the codeword")
+
+	// ErrUncorrectable indicates the codeword has more errors than the code can correct.
+	ErrUncorrectable = errors.New("uncorrectable errors: exceeds RS correction capability")
+)
@@ -0,0 +1,80 @@
+package rs
+
+// Galois Field GF(2^m) arithmetic for Reed-Solomon codes with symbols of
+// up to 8 bits.
+
+// field holds the lookup tables for GF(2^m).
+// exp is doubled to 2n entries to avoid modular reduction after log addition.
+type field struct {
+	m   int    // bits per symbol
+	n   int    // number of nonzero elements, 2^m - 1
+	exp []byte // exp[i] = α^i
+	log []byte // log[α^i] = i
+}
+
+// newField builds GF(2^m) from the field polynomial poly, given with its
+// x^m term. Returns ErrInvalidParameters unless 2 <= m <= 8 and poly is a
+// primitive polynomial of degree m.
+func newField(m, poly int) (*field, error) {
+	if m < 2 || m > 8 || poly>>m != 1 {
+		return nil, ErrInvalidParameters
+	}
+	n := 1<<m - 1
+	f := &field{m: m, n: n, exp: make([]byte, 2*n), log: make([]byte, n+1)}
+
+	// Build exp table: exp[i] = α^i. A primitive polynomial returns to 1
+	// only after all n nonzero elements.
+	x := 1
+	for i := range n {
+		if i > 0 && x == 1 {
+			return nil, ErrInvalidParameters
+		}
+		f.exp[i] = byte(x)
+		f.log[x] = byte(i)
+		x <<= 1
+		if x > n {
+			x ^= poly
+		}
+	}
+	// Duplicate for wraparound-free indexing
+	copy(f.exp[n:], f.exp[:n])
+	return f, nil
+}
+
+// mul returns a * b in GF(2^m).
+func (f *field) mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[int(f.log[a])+int(f.log[b])]
+}
+
+// inv returns the multiplicative inverse of a in GF(2^m).
+// Panics if a == 0.
+func (f *field) inv(a byte) byte {
+	if a == 0 {
+		panic("rs: inverse of 0: division by zero in GF(2^m)")
+	}
+	return f.exp[f.n-int(f.log[a])]
+}
+
+// pow returns α^i in GF(2^m).
+func (f *field) pow(i int) byte {
+	i %= f.n
+	if i < 0 {
+		i += f.n
+	}
+	return f.exp[i]
+}
+
+// eval evaluates polynomial p at point x using direct power accumulation.
+// p[0] is the constant term.
+func (f *field) eval(p []byte, x byte) byte {
+	val := byte(0)
+	xPow := byte(1)
+	for _, coeff := range p {
+		val ^= f.mul(coeff, xPow)
+		xPow = f.mul(xPow, x)
+	}
+	return val
+}
@@ -0,0 +1,122 @@
+package rs
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestField(t *testing.T, m, poly int) *field {
+	t.Helper()
+	f, err := newField(m, poly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestGFTables(t *testing.T) {
+	f := newTestField(t, 8, 0x187)
+	// α^0 = 1
+	if f.exp[0] != 1 {
+		t.Errorf("exp[0] = %d, want 1", f.exp[0])
+	}
+	// α^1 = 2
+	if f.exp[1] != 2 {
+		t.Errorf("exp[1] = %d, want 2", f.exp[1])
+	}
+	// α^255 = 1 (primitive element has order 255)
+	if f.exp[255] != 1 {
+		t.Errorf("exp[255] = %d, want 1", f.exp[255])
+	}
+	// log[1] = 0
+	if f.log[1] != 0 {
+		t.Errorf("log[1] = %d, want 0", f.log[1])
+	}
+}
+
+func TestGFMul(t *testing.T) {
+	f := newTestField(t, 8, 0x187)
+	// Multiplication by zero
+	if f.mul(0, 42) != 0 {
+		t.Error("mul(0, x) should be 0")
+	}
+	if f.mul(42, 0) != 0 {
+		t.Error("mul(x, 0) should be 0")
+	}
+	// Multiplication by 1
+	if f.mul(1, 42) != 42 {
+		t.Error("mul(1, x) should be x")
+	}
+	// Commutativity
+	if f.mul(37, 89) != f.mul(89, 37) {
+		t.Error("mul should be commutative")
+	}
+}
+
+func TestGFInverse(t *testing.T) {
+	for _, tt := range []struct{ m, poly int }{{8, 0x187}, {8, 0x11D}, {4, 0x13}, {2, 0x7}} {
+		f := newTestField(t, tt.m, tt.poly)
+		// a * a^{-1} = 1 for all nonzero a
+		for a := 1; a <= f.n; a++ {
+			if product := f.mul(byte(a), f.inv(byte(a))); product != 1 {
+				t.Errorf("GF(2^%d) %#x: mul(%d, inv(%d)) = %d, want 1", tt.m, tt.poly, a, a, product)
+			}
+		}
+	}
+}
+
+func TestGFFieldClosure(t *testing.T) {
+	for _, tt := range []struct{ m, poly int }{{8, 0x187}, {4, 0x13}} {
+		f := newTestField(t, tt.m, tt.poly)
+		// Verify all 2^m - 1 nonzero elements are generated by alpha
+		seen := make(map[byte]bool)
+		for i := range f.n {
+			seen[f.exp[i]] = true
+		}
+		if len(seen) != f.n {
+			t.Errorf("GF(2^%d): generator produced %d unique elements, want %d", tt.m, len(seen), f.n)
+		}
+		if seen[0] {
+			t.Error("Zero should not be generated by alpha powers")
+		}
+	}
+}
+
+func TestGFPow(t *testing.T) {
+	f := newTestField(t, 8, 0x187)
+	if f.pow(0) != 1 {
+		t.Errorf("pow(0) = %d, want 1", f.pow(0))
+	}
+	if f.pow(1) != 2 {
+		t.Errorf("pow(1) = %d, want 2", f.pow(1))
+	}
+	// Negative exponent wraps correctly
+	if f.pow(-1) != f.pow(254) {
+		t.Errorf("pow(-1) = %d, want pow(254) = %d", f.pow(-1), f.pow(254))
+	}
+
+	// In GF(16) with x^4 + x + 1, α^4 = α + 1.
+	g := newTestField(t, 4, 0x13)
+	if g.pow(4) != 3 || g.pow(15) != 1 {
+		t.Errorf("GF(16): pow(4) = %d, pow(15) = %d", g.pow(4), g.pow(15))
+	}
+}
+
+func TestGFInvalidField(t *testing.T) {
+	tests := []struct {
+		name string
+		m    int
+		poly int
+	}{
+		{"symbol too small", 1, 0x3},
+		{"symbol too large", 9, 0x211},
+		{"wrong degree", 8, 0x13},
+		{"not primitive", 8, 0x11B}, // AES polynomial: irreducible, α = x has order 51
+		{"reducible", 4, 0x15},
+	}
+	for _, tt := range tests {
+		if _, err := newField(tt.m, tt.poly); !errors.Is(err, ErrInvalidParameters) {
+			t.Errorf("%s: got %v, want ErrInvalidParameters", tt.name, err)
+		}
+	}
+}
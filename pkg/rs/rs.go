@@ -0,0 +1,439 @@
+// Package rs provides Reed-Solomon codes over GF(2^m) used across CCSDS
+// standards and by partner agencies.
+//
+// A code is defined by the symbol size m (2 to 8 bits), the field
+// polynomial, the first consecutive root (FCR), the primitive element and
+// the number of parity symbols (nroots). The generator polynomial is
+//
+//	g(x) = ∏(x - β^j) for j = FCR..FCR+nroots-1, where β = α^prim
+//
+// and a full-length codeword holds 2^m - 1 symbols, one per byte. Codes
+// may be shortened by leading zero symbols that are not transmitted.
+// Examples:
+//   - CCSDS TM (255,223) and (255,239) per CCSDS 131.0-B-4: m=8,
+//     polynomial 0x187, prim 11, FCR 128-nroots/2
+//   - AOS Frame Header Error Control RS(10,6) per CCSDS 732.0-B-4: m=4,
+//     polynomial 0x13, prim 1, FCR 6, shortened by 5
+//   - DVB (204,188): m=8, polynomial 0x11D, prim 1, FCR 0, shortened by 51
+//
+// The decoder corrects ν errors and ρ erasures at known positions as long
+// as 2ν + ρ <= nroots.
+package rs
+
+import (
+	"errors"
+	"slices"
+)
+
+// Codec holds precomputed state for a Reed-Solomon code.
+type Codec struct {
+	f      *field
+	nroots int    // number of parity symbols
+	fcr    int    // first consecutive root, as a power of β
+	prim   int    // β = α^prim
+	gen    []byte // generator polynomial coefficients (nroots+1 entries, monic)
+	pad    int    // leading zero symbols not transmitted
+}
+
+// Option configures a Codec.
+type Option func(*Codec)
+
+// WithShortening shortens each codeword by n leading zero data symbols
+// that are encoded but not transmitted. Encode then takes DataLen() =
+// 2^m - 1 - nroots - n symbols and returns CodewordLen() = 2^m - 1 - n
+// symbols. The default is 0.
+func WithShortening(n int) Option {
+	return func(c *Codec) {
+		c.pad = n
+	}
+}
+
+// NewCodec returns a Codec for the Reed-Solomon code over GF(2^symbolSize)
+// with field polynomial fieldPoly, given with its x^symbolSize term, and
+// nroots parity symbols. The generator roots are β^(fcr+i) for i =
+// 0..nroots-1, with β = α^prim.
+//
+// Returns ErrInvalidParameters unless 2 <= symbolSize <= 8, fieldPoly is
+// primitive, 0 <= fcr <= 2^symbolSize - 1, prim is coprime to
+// 2^symbolSize - 1, and nroots and the shortening leave at least one
+// data symbol.
+func NewCodec(symbolSize, fieldPoly, fcr, prim, nroots int, opts ...Option) (*Codec, error) {
+	f, err := newField(symbolSize, fieldPoly)
+	if err != nil {
+		return nil, err
+	}
+	c := &Codec{f: f, nroots: nroots, fcr: fcr, prim: prim}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if fcr < 0 || fcr > f.n || prim < 1 || prim > f.n || gcd(prim, f.n) != 1 ||
+		nroots < 1 || c.pad < 0 || c.DataLen() < 1 {
+		return nil, ErrInvalidParameters
+	}
+
+	// Build generator polynomial:
+	// g(x) = (x - β^FCR)(x - β^(FCR+1))...(x - β^(FCR+nroots-1))
+	gen := make([]byte, nroots+1)
+	gen[0] = 1
+
+	for i := range nroots {
+		root := c.root(i)
+		// Multiply gen by (x - root) = (x + root) in GF(2^m)
+		for j := i + 1; j > 0; j-- {
+			gen[j] = gen[j-1] ^ f.mul(gen[j], root)
+		}
+		gen[0] = f.mul(gen[0], root)
+	}
+	c.gen = gen
+
+	return c, nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// root returns the i-th root of the generator polynomial, β^(FCR+i).
+func (c *Codec) root(i int) byte {
+	return c.f.pow(c.prim * (c.fcr + i))
+}
+
+// SymbolSize returns the number of bits per symbol.
+func (c *Codec) SymbolSize() int { return c.f.m }
+
+// NRoots returns the number of parity symbols.
+func (c *Codec) NRoots() int { return c.nroots }
+
+// DataLen returns the data length per codeword (2^m - 1 - nroots - shortening).
+func (c *Codec) DataLen() int { return c.f.n - c.nroots - c.pad }
+
+// CodewordLen returns the transmitted codeword length (2^m - 1 - shortening).
+func (c *Codec) CodewordLen() int { return c.f.n - c.pad }
+
+// Shortening returns the number of leading zero symbols not transmitted.
+func (c *Codec) Shortening() int { return c.pad }
+
+// Encode appends nroots parity symbols to data and returns a codeword of
+// CodewordLen() symbols. The input must be exactly DataLen() symbols.
+// Returns ErrInvalidDataLength for a wrong length and ErrInvalidSymbol
+// for a symbol of more than SymbolSize() bits. The input slice is not
+// modified.
+func (c *Codec) Encode(data []byte) ([]byte, error) {
+	if len(data) != c.DataLen() {
+		return nil, ErrInvalidDataLength
+	}
+	if !c.fits(data) {
+		return nil, ErrInvalidSymbol
+	}
+	k := len(data)
+
+	// Systematic encoding: compute remainder of data * x^nroots / g(x).
+	// Leading zero symbols of a shortened code leave the remainder at 0.
+	parity := make([]byte, c.nroots)
+	for i := range k {
+		feedback := data[i] ^ parity[0]
+		if feedback != 0 {
+			for j := range c.nroots - 1 {
+				parity[j] = parity[j+1] ^ c.f.mul(feedback, c.gen[c.nroots-1-j])
+			}
+			parity[c.nroots-1] = c.f.mul(feedback, c.gen[0])
+		} else {
+			copy(parity, parity[1:])
+			parity[c.nroots-1] = 0
+		}
+	}
+
+	codeword := make([]byte, k+c.nroots)
+	copy(codeword, data)
+	copy(codeword[k:], parity)
+	return codeword, nil
+}
+
+// fits reports whether every symbol fits the symbol size.
+func (c *Codec) fits(symbols []byte) bool {
+	for _, s := range symbols {
+		if int(s) > c.f.n {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode corrects errors in a codeword of CodewordLen() symbols and
+// returns the corrected data (first DataLen() symbols), the number of
+// corrected symbol errors, and any error. Returns ErrUncorrectable if
+// errors exceed correction capability, or if the decoder places an error
+// in the shortened symbols, which are known to be zero. The input slice
+// is not modified.
+func (c *Codec) Decode(codeword []byte) ([]byte, int, error) {
+	return c.DecodeWithErasures(codeword, nil)
+}
+
+// DecodeWithErasures corrects errors and erasures in a codeword of
+// CodewordLen() symbols. Erasures are indices into the transmitted
+// codeword of symbols known to be unreliable; their received values are
+// ignored. The code corrects ν errors and ρ erasures as long as
+// 2ν + ρ <= NRoots(). Duplicate positions count once.
+//
+// Returns the corrected data, the number of symbols whose value changed,
+// and any error. Returns ErrInvalidErasure for a position outside the
+// codeword and ErrUncorrectable if the errors and erasures exceed the
+// correction capability. The input slice is not modified.
+func (c *Codec) DecodeWithErasures(codeword []byte, erasures []int) ([]byte, int, error) {
+	if len(codeword) != c.CodewordLen() {
+		return nil, 0, ErrInvalidDataLength
+	}
+	if !c.fits(codeword) {
+		return nil, 0, ErrInvalidSymbol
+	}
+	nn := c.f.n
+
+	// Map erasures to full-length codeword positions.
+	erased := make([]bool, nn)
+	var erasPos []int
+	for _, pos := range erasures {
+		if pos < 0 || pos >= len(codeword) {
+			return nil, 0, ErrInvalidErasure
+		}
+		if !erased[c.pad+pos] {
+			erased[c.pad+pos] = true
+			erasPos = append(erasPos, c.pad+pos)
+		}
+	}
+	if len(erasPos) > c.nroots {
+		return nil, 0, ErrUncorrectable
+	}
+
+	// Restore the shortened symbols to decode the full-length codeword.
+	work := make([]byte, nn)
+	copy(work[c.pad:], codeword)
+
+	// Step 1: Compute syndromes S_i = R(β^(FCR+i))
+	syndromes := make([]byte, c.nroots)
+	allZero := true
+	for i := range c.nroots {
+		s := byte(0)
+		root := c.root(i)
+		for j := range nn {
+			s = c.f.mul(s, root) ^ work[j]
+		}
+		syndromes[i] = s
+		if s != 0 {
+			allZero = false
+		}
+	}
+
+	if allZero {
+		return work[c.pad : nn-c.nroots], 0, nil
+	}
+
+	// Step 2: Berlekamp-Massey, seeded with the erasure locator, → errata
+	// locator polynomial σ(x) = Λ(x)·Γ(x)
+	sigma, nerrs, err := c.berlekampMassey(syndromes, c.erasureLocator(erasPos))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Step 3: Chien search → error and erasure positions
+	errPos := c.chienSearch(sigma, nerrs)
+	if errPos == nil {
+		return nil, 0, ErrUncorrectable
+	}
+	for _, pos := range errPos {
+		if pos < c.pad {
+			return nil, 0, ErrUncorrectable
+		}
+	}
+
+	// Step 4: Forney algorithm → errata magnitudes, correct in-place
+	corrected := c.forney(work, syndromes, sigma, errPos)
+
+	return work[c.pad : nn-c.nroots], corrected, nil
+}
+
+// DecodeWithReliability corrects a codeword of CodewordLen() symbols
+// using per-symbol reliability, higher meaning more reliable. If
+// errors-only decoding fails, it retries with the least reliable symbols
+// erased, two more at a time, up to NRoots()/2 erasures so that half of
+// the parity is left to detect a miscorrection. Only symbols less
+// reliable than the most reliable one are erased, so uniform reliability
+// gives errors-only decoding.
+//
+// Returns the corrected data, the number of symbols whose value changed,
+// and any error. Returns ErrInvalidDataLength if reliability does not
+// match the codeword length. The input slices are not modified.
+func (c *Codec) DecodeWithReliability(codeword, reliability []byte) ([]byte, int, error) {
+	if len(reliability) != len(codeword) {
+		return nil, 0, ErrInvalidDataLength
+	}
+	data, corrected, err := c.Decode(codeword)
+	if !errors.Is(err, ErrUncorrectable) {
+		return data, corrected, err
+	}
+
+	// Candidates for erasure, least reliable first.
+	top := slices.Max(reliability)
+	var order []int
+	for i, r := range reliability {
+		if r < top {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return int(reliability[a]) - int(reliability[b])
+	})
+
+	limit := min(c.nroots/2, len(order))
+	for n := min(2, limit); n > 0; n += 2 {
+		n = min(n, limit)
+		if data, corrected, err := c.DecodeWithErasures(codeword, order[:n]); err == nil {
+			return data, corrected, nil
+		}
+		if n == limit {
+			break
+		}
+	}
+	return nil, 0, ErrUncorrectable
+}
+
+// erasureLocator returns the erasure-locator polynomial
+// Γ(x) = ∏(1 - X_k·x), with X_k = β^(n-1-pos) for each erased codeword
+// symbol index pos.
+func (c *Codec) erasureLocator(erasPos []int) []byte {
+	gamma := make([]byte, len(erasPos)+1)
+	gamma[0] = 1
+	for k, pos := range erasPos {
+		x := c.f.pow(c.prim * ((c.f.n - 1) - pos))
+		for j := k + 1; j > 0; j-- {
+			gamma[j] ^= c.f.mul(x, gamma[j-1])
+		}
+	}
+	return gamma
+}
+
+// berlekampMassey computes the errata-locator polynomial using the
+// Berlekamp-Massey algorithm, starting from the erasure locator gamma.
+// Returns the polynomial, degree (number of errors plus erasures), and
+// any error.
+func (c *Codec) berlekampMassey(syndromes, gamma []byte) ([]byte, int, error) {
+	n := c.nroots
+	rho := len(gamma) - 1 // number of erasures
+	// σ(x): errata-locator polynomial
+	sigma := make([]byte, n+1)
+	copy(sigma, gamma)
+	// B(x): auxiliary polynomial
+	B := make([]byte, n+1)
+	copy(B, gamma)
+
+	L := rho // current number of assumed errors plus erasures
+
+	for k := rho; k < n; k++ {
+		// Compute discrepancy Δ
+		delta := byte(0)
+		for j := 0; j <= k; j++ {
+			delta ^= c.f.mul(sigma[j], syndromes[k-j])
+		}
+
+		// Shift B: B(x) = x * B(x)
+		copy(B[1:], B)
+		B[0] = 0
+
+		if delta != 0 {
+			T := make([]byte, n+1)
+			copy(T, sigma)
+
+			// σ(x) = σ(x) - Δ * B(x)
+			for j := range n + 1 {
+				sigma[j] ^= c.f.mul(delta, B[j])
+			}
+
+			if 2*L <= k+rho {
+				L = k + 1 + rho - L
+				// B(x) = Δ^{-1} * T(x)
+				inv := c.f.inv(delta)
+				for j := range n + 1 {
+					B[j] = c.f.mul(T[j], inv)
+				}
+			}
+		}
+	}
+
+	// 2ν + ρ must not exceed nroots, with ν = L - ρ errors.
+	if 2*L-rho > n {
+		return nil, 0, ErrUncorrectable
+	}
+
+	return sigma, L, nil
+}
+
+// chienSearch finds the roots of the error-locator polynomial σ(x)
+// by exhaustive evaluation. If σ(β^{-i}) == 0, the error is at codeword
+// symbol index (n-1-i), since symbol j corresponds to the coefficient of
+// x^(n-1-j). Returns the codeword symbol indices or nil if the count
+// doesn't match.
+func (c *Codec) chienSearch(sigma []byte, nerrs int) []int {
+	var positions []int
+
+	for i := range c.f.n {
+		if c.f.eval(sigma, c.f.pow(-c.prim*i)) == 0 {
+			positions = append(positions, (c.f.n-1)-i)
+		}
+	}
+
+	if len(positions) != nerrs {
+		return nil
+	}
+	return positions
+}
+
+// forney computes errata magnitudes using the Forney algorithm and
+// corrects the codeword in-place. Returns the number of symbols changed;
+// an erased symbol that was received correctly has magnitude zero.
+func (c *Codec) forney(codeword []byte, syndromes []byte, sigma []byte, errPos []int) int {
+	n := c.nroots
+
+	// Compute error-evaluator polynomial:
+	// Ω(x) = S(x) · σ(x) mod x^nroots
+	omega := make([]byte, n)
+	for i := range n {
+		val := byte(0)
+		for j := range i + 1 {
+			if j < len(sigma) {
+				val ^= c.f.mul(syndromes[i-j], sigma[j])
+			}
+		}
+		omega[i] = val
+	}
+
+	// Formal derivative of σ(x) in characteristic 2:
+	// σ'(x) = σ_1 + σ_3·x^2 + σ_5·x^4 + ... (only odd-indexed coefficients)
+	sigmaD := make([]byte, len(sigma))
+	for j := 1; j < len(sigma); j += 2 {
+		sigmaD[j-1] = sigma[j]
+	}
+
+	corrected := 0
+	for _, pos := range errPos {
+		// Symbol at codeword[pos] corresponds to x^(n-1-pos)
+		power := (c.f.n - 1) - pos
+		xiInv := c.f.pow(-c.prim * power) // X_i^{-1}, with X_i = β^power
+
+		omegaVal := c.f.eval(omega, xiInv)
+		sigmaDVal := c.f.eval(sigmaD, xiInv)
+
+		if sigmaDVal == 0 {
+			continue
+		}
+
+		// Forney: e_i = X_i^{1-FCR} · Ω(X_i^{-1}) / σ'(X_i^{-1})
+		magnitude := c.f.mul(c.f.mul(c.f.pow(c.prim*power*(1-c.fcr)), omegaVal), c.f.inv(sigmaDVal))
+		if magnitude != 0 {
+			codeword[pos] ^= magnitude
+			corrected++
+		}
+	}
+	return corrected
+}
@@ -0,0 +1,209 @@
+package rs_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/rs"
+)
+
+func newCodec(t *testing.T, m, poly, fcr, prim, nroots int, opts ...rs.Option) *rs.Codec {
+	t.Helper()
+	c, err := rs.NewCodec(m, poly, fcr, prim, nroots, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func randomSymbols(rng *rand.Rand, n, m int) []byte {
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(rng.IntN(1 << m))
+	}
+	return out
+}
+
+func TestCodec_Lengths(t *testing.T) {
+	tests := []struct {
+		name             string
+		c                *rs.Codec
+		dataLen, cwLen   int
+		nroots, symbolSz int
+	}{
+		{"CCSDS (255,223)", newCodec(t, 8, 0x187, 112, 11, 32), 223, 255, 32, 8},
+		{"AOS FHEC (10,6)", newCodec(t, 4, 0x13, 6, 1, 4, rs.WithShortening(5)), 6, 10, 4, 4},
+		{"DVB (204,188)", newCodec(t, 8, 0x11D, 0, 1, 16, rs.WithShortening(51)), 188, 204, 16, 8},
+		{"(255,251)", newCodec(t, 8, 0x11D, 0, 1, 4), 251, 255, 4, 8},
+	}
+	for _, tt := range tests {
+		if tt.c.DataLen() != tt.dataLen || tt.c.CodewordLen() != tt.cwLen ||
+			tt.c.NRoots() != tt.nroots || tt.c.SymbolSize() != tt.symbolSz {
+			t.Errorf("%s: DataLen() = %d, CodewordLen() = %d, NRoots() = %d, SymbolSize() = %d",
+				tt.name, tt.c.DataLen(), tt.c.CodewordLen(), tt.c.NRoots(), tt.c.SymbolSize())
+		}
+	}
+}
+
+func TestCodec_AOSGenerator(t *testing.T) {
+	// The Frame Header Error Control generator ∏(x + α^j), j = 6..9, is
+	// x^4 + α^3·x^3 + α·x^2 + α^3·x + 1: the roots pair up as reciprocals.
+	// Encoding a single 1 in the last data symbol leaves g(x) - x^4 as
+	// parity: α^3 = 8, α = 2, α^3 = 8, 1.
+	c := newCodec(t, 4, 0x13, 6, 1, 4, rs.WithShortening(5))
+	codeword, err := c.Encode([]byte{0, 0, 0, 0, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 0, 0, 0, 0, 1, 8, 2, 8, 1}
+	if !bytes.Equal(codeword, want) {
+		t.Errorf("codeword = %v, want %v", codeword, want)
+	}
+}
+
+func TestCodec_CorrectErrors(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 0))
+	tests := []struct {
+		name string
+		c    *rs.Codec
+	}{
+		{"CCSDS (255,223)", newCodec(t, 8, 0x187, 112, 11, 32)},
+		{"CCSDS (255,239) shortened", newCodec(t, 8, 0x187, 120, 11, 16, rs.WithShortening(100))},
+		{"AOS FHEC (10,6)", newCodec(t, 4, 0x13, 6, 1, 4, rs.WithShortening(5))},
+		{"DVB (204,188)", newCodec(t, 8, 0x11D, 0, 1, 16, rs.WithShortening(51))},
+		{"(255,251)", newCodec(t, 8, 0x11D, 0, 1, 4)},
+		{"GF(16) prim 2 FCR 3", newCodec(t, 4, 0x13, 3, 2, 6)},
+		{"GF(8) (7,3)", newCodec(t, 3, 0xB, 1, 1, 4)},
+	}
+	for _, tt := range tests {
+		m := tt.c.SymbolSize()
+		tcap := tt.c.NRoots() / 2
+		for trial := range 20 {
+			data := randomSymbols(rng, tt.c.DataLen(), m)
+			codeword, err := tt.c.Encode(data)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			nerrs := trial % (tcap + 1)
+			for _, pos := range rng.Perm(len(codeword))[:nerrs] {
+				codeword[pos] ^= byte(1 + rng.IntN(1<<m-1))
+			}
+			decoded, corr, err := tt.c.Decode(codeword)
+			if err != nil {
+				t.Fatalf("%s: %d errors: %v", tt.name, nerrs, err)
+			}
+			if corr != nerrs || !bytes.Equal(decoded, data) {
+				t.Fatalf("%s: %d errors: corrections = %d, data match %v",
+					tt.name, nerrs, corr, bytes.Equal(decoded, data))
+			}
+		}
+	}
+}
+
+func TestCodec_Uncorrectable(t *testing.T) {
+	c := newCodec(t, 8, 0x11D, 0, 1, 16, rs.WithShortening(51))
+	data := make([]byte, c.DataLen())
+	codeword, _ := c.Encode(data)
+	for i := range 9 {
+		codeword[20*i] ^= 0x55
+	}
+	if _, _, err := c.Decode(codeword); !errors.Is(err, rs.ErrUncorrectable) {
+		t.Errorf("got %v, want ErrUncorrectable", err)
+	}
+}
+
+func TestCodec_DecodeWithErasures(t *testing.T) {
+	rng := rand.New(rand.NewPCG(2, 0))
+	c := newCodec(t, 4, 0x13, 6, 1, 4, rs.WithShortening(5))
+	for _, tt := range []struct{ errors, erasures int }{{0, 4}, {1, 2}, {2, 0}, {0, 3}} {
+		data := randomSymbols(rng, c.DataLen(), 4)
+		codeword, _ := c.Encode(data)
+		positions := rng.Perm(len(codeword))[:tt.errors+tt.erasures]
+		for _, pos := range positions[:tt.errors] {
+			codeword[pos] ^= byte(1 + rng.IntN(15))
+		}
+		for _, pos := range positions[tt.errors:] {
+			codeword[pos] = 0
+		}
+
+		decoded, _, err := c.DecodeWithErasures(codeword, positions[tt.errors:])
+		if err != nil {
+			t.Fatalf("%d errors %d erasures: %v", tt.errors, tt.erasures, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%d errors %d erasures: data mismatch", tt.errors, tt.erasures)
+		}
+	}
+
+	codeword := make([]byte, c.CodewordLen())
+	if _, _, err := c.DecodeWithErasures(codeword, []int{10}); !errors.Is(err, rs.ErrInvalidErasure) {
+		t.Errorf("position 10: got %v, want ErrInvalidErasure", err)
+	}
+	if _, _, err := c.DecodeWithErasures(codeword, []int{0, 1, 2, 3, 4}); !errors.Is(err, rs.ErrUncorrectable) {
+		t.Errorf("5 erasures: got %v, want ErrUncorrectable", err)
+	}
+}
+
+func TestCodec_DecodeWithReliability(t *testing.T) {
+	c := newCodec(t, 8, 0x11D, 0, 1, 16, rs.WithShortening(51))
+	data := randomSymbols(rand.New(rand.NewPCG(3, 0)), c.DataLen(), 8)
+	codeword, _ := c.Encode(data)
+
+	// 10 errors exceed the 8 the code corrects; 4 of them are flagged.
+	reliability := bytes.Repeat([]byte{200}, len(codeword))
+	for i := range 10 {
+		codeword[15*i] ^= 0x0F
+		if i < 4 {
+			reliability[15*i] = 10
+		}
+	}
+	decoded, corr, err := c.DecodeWithReliability(codeword, reliability)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 10 || !bytes.Equal(decoded, data) {
+		t.Errorf("corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+	if _, _, err := c.DecodeWithReliability(codeword, reliability[1:]); !errors.Is(err, rs.ErrInvalidDataLength) {
+		t.Errorf("short reliability: got %v, want ErrInvalidDataLength", err)
+	}
+}
+
+func TestCodec_InvalidSymbol(t *testing.T) {
+	c := newCodec(t, 4, 0x13, 6, 1, 4, rs.WithShortening(5))
+	if _, err := c.Encode([]byte{0, 0, 0, 0, 0, 16}); !errors.Is(err, rs.ErrInvalidSymbol) {
+		t.Errorf("Encode: got %v, want ErrInvalidSymbol", err)
+	}
+	if _, _, err := c.Decode(make([]byte, 9)); !errors.Is(err, rs.ErrInvalidDataLength) {
+		t.Errorf("Decode length: got %v, want ErrInvalidDataLength", err)
+	}
+	if _, _, err := c.Decode([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0x20}); !errors.Is(err, rs.ErrInvalidSymbol) {
+		t.Errorf("Decode: got %v, want ErrInvalidSymbol", err)
+	}
+}
+
+func TestNewCodec_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name                       string
+		m, poly, fcr, prim, nroots int
+		opts                       []rs.Option
+	}{
+		{"symbol size", 9, 0x211, 0, 1, 16, nil},
+		{"not primitive", 8, 0x11B, 0, 1, 16, nil},
+		{"negative FCR", 8, 0x187, -1, 1, 16, nil},
+		{"FCR too large", 8, 0x187, 256, 1, 16, nil},
+		{"prim shares a factor", 8, 0x187, 0, 3, 16, nil},
+		{"prim zero", 8, 0x187, 0, 0, 16, nil},
+		{"no parity", 8, 0x187, 0, 1, 0, nil},
+		{"no data", 4, 0x13, 0, 1, 15, nil},
+		{"shortened to nothing", 4, 0x13, 6, 1, 4, []rs.Option{rs.WithShortening(11)}},
+		{"negative shortening", 4, 0x13, 6, 1, 4, []rs.Option{rs.WithShortening(-1)}},
+	}
+	for _, tt := range tests {
+		if _, err := rs.NewCodec(tt.m, tt.poly, tt.fcr, tt.prim, tt.nroots, tt.opts...); !errors.Is(err, rs.ErrInvalidParameters) {
+			t.Errorf("%s: got %v, want ErrInvalidParameters", tt.name, err)
+		}
+	}
+}
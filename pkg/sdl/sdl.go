@@ -17,3 +17,9 @@ type Service interface {
 // at data[0], or -1 if the data is too short to determine length.
 // Used by packet services to find packet boundaries within frame data.
 type PacketSizer func(data []byte) int
+
+// OCFProvider returns the 4-byte Operational Control Field to place in
+// the next outgoing frame, typically the current CLCW. A slice of any
+// other length is reported as an error by the Master Channel, which
+// inserts the last valid report instead; the frame is still sent.
+type OCFProvider func() []byte
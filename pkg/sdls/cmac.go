@@ -0,0 +1,69 @@
+package sdls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// cmacRb is the constant used to derive CMAC subkeys for a 128-bit block.
+const cmacRb = 0x87
+
+// CMAC computes the 16-byte AES-CMAC of message per NIST SP 800-38B
+// (RFC 4493). key must be 16, 24 or 32 bytes.
+func CMAC(key, message []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKeyLength
+	}
+	return cmac(block, message), nil
+}
+
+// cmac computes the CMAC of message with block.
+func cmac(block cipher.Block, message []byte) []byte {
+	bs := block.BlockSize()
+	k1 := make([]byte, bs)
+	block.Encrypt(k1, k1)
+	k1 = cmacShift(k1)
+	k2 := cmacShift(k1)
+
+	n := (len(message) + bs - 1) / bs
+	complete := n > 0 && len(message)%bs == 0
+	if n == 0 {
+		n = 1
+	}
+
+	last := make([]byte, bs)
+	tail := message[(n-1)*bs:]
+	if complete {
+		subtle.XORBytes(last, tail, k1)
+	} else {
+		copy(last, tail)
+		last[len(tail)] = 0x80
+		subtle.XORBytes(last, last, k2)
+	}
+
+	mac := make([]byte, bs)
+	for i := range n - 1 {
+		subtle.XORBytes(mac, mac, message[i*bs:(i+1)*bs])
+		block.Encrypt(mac, mac)
+	}
+	subtle.XORBytes(mac, mac, last)
+	block.Encrypt(mac, mac)
+	return mac
+}
+
+// cmacShift returns b shifted left by one bit, XORed with Rb when the
+// most significant bit was set.
+func cmacShift(b []byte) []byte {
+	out := make([]byte, len(b))
+	var carry byte
+	for i := len(b) - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry != 0 {
+		out[len(out)-1] ^= cmacRb
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package sdls_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC 4493 Section 4 test vectors.
+func TestCMAC_RFC4493(t *testing.T) {
+	key := mustHex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	msg := mustHex(t, "6bc1bee22e409f96e93d7e117393172a"+
+		"ae2d8a571e03ac9c9eb76fac45af8e51"+
+		"30c81c46a35ce411e5fbc1191a0a52ef"+
+		"f69f2445df4f9b17ad2b417be66c3710")
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+	for _, tt := range tests {
+		mac, err := sdls.CMAC(key, msg[:tt.n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mac, mustHex(t, tt.want)) {
+			t.Errorf("CMAC(%d bytes) = %x, want %s", tt.n, mac, tt.want)
+		}
+	}
+}
+
+func TestCMAC_InvalidKey(t *testing.T) {
+	if _, err := sdls.CMAC(make([]byte, 10), nil); !errors.Is(err, sdls.ErrInvalidKeyLength) {
+		t.Errorf("expected ErrInvalidKeyLength, got %v", err)
+	}
+}
@@ -0,0 +1,454 @@
+package sdls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Extended Procedure data fields. CCSDS 355.1-B-1 leaves several field
+// lengths to the mission; astro fixes them as follows.
+//
+//	OTAR             MKID(2) | IV(12) | GCM{ [KeyID(2) Key(32)]... } | MAC(16)
+//	Key Activation   KeyID(2)...           (also Deactivation, Destruction)
+//	Key Verification [KeyID(2) Challenge(16)]...
+//	  reply          [KeyID(2) IV(12) GCM{Challenge}(16) MAC(16)]...
+//	Key Inventory    FirstKeyID(2) LastKeyID(2)
+//	  reply          Count(2) [KeyID(2) State(1)]...
+//	Create SA        SPI(2) Service(1) Algorithm(1) IVLen(1) SNLen(1)
+//	                 PadLen(1) MACLen(1) Window(4) MaskLen(2) Mask
+//	Rekey SA         SPI(2) KeyID(2) [IV]
+//	Start SA         SPI(2) [VCID(1)...]
+//	Set ARSN         SPI(2) ARSN(8)
+//	Set ARSN Window  SPI(2) Window(4)
+//	Stop, Expire, Delete, SA Status, Read ARSN: SPI(2)
+//	  Read ARSN reply  SPI(2) ARSN(8) IV
+//	  SA Status reply  SPI(2) State(1)
+//	Log Status reply   Alarm(1) Count(2) Remaining(2)
+//	Dump Log reply     [Event(1) Ref(2) Time(4)]...
+//	Self-Test reply    Result(1), 0 = pass
+//
+// OTAR and Key Verification use AES-256-GCM.
+
+const (
+	// OTARKeyLength is the length of keys uploaded by OTAR.
+	OTARKeyLength = 32
+
+	// ChallengeLength is the length of a key verification challenge.
+	ChallengeLength = 16
+
+	otarTagSize = 16
+)
+
+// NewOTARCommand builds an Over-The-Air Rekeying command that uploads
+// keys encrypted under the master key masterKey, stored on board as
+// masterKeyID. iv must be 12 bytes and never reused under the master
+// key. Each key must be OTARKeyLength bytes.
+func NewOTARCommand(masterKeyID uint16, masterKey, iv []byte, keys ...Key) (*PDU, error) {
+	if len(iv) != GCMIVSize {
+		return nil, ErrInvalidIVLength
+	}
+	if len(keys) == 0 {
+		return nil, ErrInvalidPDU
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, 0, len(keys)*(2+OTARKeyLength))
+	for _, k := range keys {
+		if len(k.Value) != OTARKeyLength {
+			return nil, ErrInvalidKeyLength
+		}
+		plain = binary.BigEndian.AppendUint16(plain, k.ID)
+		plain = append(plain, k.Value...)
+	}
+	data := binary.BigEndian.AppendUint16(nil, masterKeyID)
+	data = append(data, iv...)
+	data = aead.Seal(data, iv, plain, data[:2])
+	return NewCommand(ProcOTAR, data), nil
+}
+
+// openOTAR decrypts the keys of an OTAR data field with the master key.
+func openOTAR(data, masterKey []byte) ([]Key, error) {
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	iv := data[2 : 2+GCMIVSize]
+	plain, err := aead.Open(nil, iv, data[2+GCMIVSize:], data[:2])
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+	if len(plain) == 0 || len(plain)%(2+OTARKeyLength) != 0 {
+		return nil, ErrInvalidPDU
+	}
+	var keys []Key
+	for len(plain) > 0 {
+		keys = append(keys, Key{
+			ID:    binary.BigEndian.Uint16(plain),
+			State: KeyPreActive,
+			Value: plain[2 : 2+OTARKeyLength],
+		})
+		plain = plain[2+OTARKeyLength:]
+	}
+	return keys, nil
+}
+
+// newGCM returns AES-GCM with a 12-byte nonce and 16-byte tag.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKeyLength
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyIDs encodes a list of key IDs.
+func keyIDs(proc Procedure, ids []uint16) *PDU {
+	data := make([]byte, 0, 2*len(ids))
+	for _, id := range ids {
+		data = binary.BigEndian.AppendUint16(data, id)
+	}
+	return NewCommand(proc, data)
+}
+
+// NewKeyActivationCommand builds a Key Activation command.
+func NewKeyActivationCommand(ids ...uint16) *PDU { return keyIDs(ProcKeyActivation, ids) }
+
+// NewKeyDeactivationCommand builds a Key Deactivation command.
+func NewKeyDeactivationCommand(ids ...uint16) *PDU { return keyIDs(ProcKeyDeactivation, ids) }
+
+// NewKeyDestructionCommand builds a Key Destruction command.
+func NewKeyDestructionCommand(ids ...uint16) *PDU { return keyIDs(ProcKeyDestruction, ids) }
+
+// parseKeyIDs decodes a list of key IDs.
+func parseKeyIDs(data []byte) ([]uint16, error) {
+	if len(data) == 0 || len(data)%2 != 0 {
+		return nil, ErrInvalidPDU
+	}
+	ids := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		ids = append(ids, binary.BigEndian.Uint16(data[i:]))
+	}
+	return ids, nil
+}
+
+// KeyChallenge asks the spacecraft to prove it holds a key.
+type KeyChallenge struct {
+	KeyID     uint16
+	Challenge [ChallengeLength]byte
+}
+
+// NewKeyVerificationCommand builds a Key Verification command.
+func NewKeyVerificationCommand(challenges ...KeyChallenge) *PDU {
+	data := make([]byte, 0, len(challenges)*(2+ChallengeLength))
+	for _, c := range challenges {
+		data = binary.BigEndian.AppendUint16(data, c.KeyID)
+		data = append(data, c.Challenge[:]...)
+	}
+	return NewCommand(ProcKeyVerification, data)
+}
+
+// KeyVerification is one entry of a Key Verification reply: the
+// challenge encrypted under the key being verified.
+type KeyVerification struct {
+	KeyID    uint16
+	IV       []byte
+	Response []byte // encrypted challenge and MAC
+}
+
+// Check reports whether the response is challenge encrypted under key.
+func (v KeyVerification) Check(key []byte, challenge [ChallengeLength]byte) bool {
+	aead, err := newGCM(key)
+	if err != nil || len(v.IV) != GCMIVSize {
+		return false
+	}
+	plain, err := aead.Open(nil, v.IV, v.Response, binary.BigEndian.AppendUint16(nil, v.KeyID))
+	return err == nil && subtle.ConstantTimeCompare(plain, challenge[:]) == 1
+}
+
+const keyVerificationEntry = 2 + GCMIVSize + ChallengeLength + otarTagSize
+
+// DecodeKeyVerificationReply parses a Key Verification reply.
+func DecodeKeyVerificationReply(p *PDU) ([]KeyVerification, error) {
+	if err := expectReply(p, ProcKeyVerification); err != nil {
+		return nil, err
+	}
+	if len(p.Data)%keyVerificationEntry != 0 {
+		return nil, ErrInvalidPDU
+	}
+	var out []KeyVerification
+	for d := p.Data; len(d) > 0; d = d[keyVerificationEntry:] {
+		out = append(out, KeyVerification{
+			KeyID:    binary.BigEndian.Uint16(d),
+			IV:       append([]byte(nil), d[2:2+GCMIVSize]...),
+			Response: append([]byte(nil), d[2+GCMIVSize:keyVerificationEntry]...),
+		})
+	}
+	return out, nil
+}
+
+// NewKeyInventoryCommand builds a Key Inventory command for the key IDs
+// first through last.
+func NewKeyInventoryCommand(first, last uint16) *PDU {
+	data := binary.BigEndian.AppendUint16(nil, first)
+	return NewCommand(ProcKeyInventory, binary.BigEndian.AppendUint16(data, last))
+}
+
+// KeyInventoryEntry is one key of a Key Inventory reply.
+type KeyInventoryEntry struct {
+	KeyID uint16
+	State KeyState
+}
+
+// DecodeKeyInventoryReply parses a Key Inventory reply.
+func DecodeKeyInventoryReply(p *PDU) ([]KeyInventoryEntry, error) {
+	if err := expectReply(p, ProcKeyInventory); err != nil {
+		return nil, err
+	}
+	if len(p.Data) < 2 {
+		return nil, ErrInvalidPDU
+	}
+	n := int(binary.BigEndian.Uint16(p.Data))
+	if len(p.Data) != 2+3*n {
+		return nil, ErrInvalidPDU
+	}
+	out := make([]KeyInventoryEntry, n)
+	for i := range out {
+		e := p.Data[2+3*i:]
+		out[i] = KeyInventoryEntry{KeyID: binary.BigEndian.Uint16(e), State: KeyState(e[2])}
+	}
+	return out, nil
+}
+
+// SAParams are the managed parameters carried by a Create SA command.
+type SAParams struct {
+	Service   ServiceType
+	Algorithm Algorithm
+	IVLength  int
+	SNLength  int
+	PadLength int
+	MACLength int
+	Window    uint32
+	AuthMask  []byte
+}
+
+// CreateSA builds the Unkeyed SA described by a Create SA command. The
+// ground uses it to mirror the SA it creates on board.
+func CreateSA(spi uint16, p SAParams) (*SecurityAssociation, error) {
+	sa, err := NewSecurityAssociation(spi, p.Service, p.Algorithm, nil,
+		WithIV(make([]byte, p.IVLength)),
+		WithSequenceNumberLength(p.SNLength),
+		WithPadLengthField(p.PadLength),
+		WithMACLength(p.MACLength),
+		WithReplayWindow(uint64(p.Window)),
+		WithAuthMask(p.AuthMask))
+	if err != nil {
+		return nil, err
+	}
+	sa.state = SAUnkeyed
+	return sa, nil
+}
+
+// NewCreateSACommand builds a Create SA command.
+func NewCreateSACommand(spi uint16, p SAParams) *PDU {
+	data := binary.BigEndian.AppendUint16(nil, spi)
+	data = append(data, uint8(p.Service), uint8(p.Algorithm),
+		uint8(p.IVLength), uint8(p.SNLength), uint8(p.PadLength), uint8(p.MACLength))
+	data = binary.BigEndian.AppendUint32(data, p.Window)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(p.AuthMask)))
+	return NewCommand(ProcCreateSA, append(data, p.AuthMask...))
+}
+
+// parseCreateSA decodes a Create SA data field.
+func parseCreateSA(data []byte) (uint16, SAParams, error) {
+	if len(data) < 14 {
+		return 0, SAParams{}, ErrInvalidPDU
+	}
+	n := int(binary.BigEndian.Uint16(data[12:]))
+	if len(data) != 14+n {
+		return 0, SAParams{}, ErrInvalidPDU
+	}
+	p := SAParams{
+		Service:   ServiceType(data[2]),
+		Algorithm: Algorithm(data[3]),
+		IVLength:  int(data[4]),
+		SNLength:  int(data[5]),
+		PadLength: int(data[6]),
+		MACLength: int(data[7]),
+		Window:    binary.BigEndian.Uint32(data[8:]),
+	}
+	if n > 0 {
+		p.AuthMask = append([]byte(nil), data[14:]...)
+	}
+	return binary.BigEndian.Uint16(data), p, nil
+}
+
+// NewRekeySACommand builds a Rekey SA command binding key keyID to the
+// SA. iv sets the SA's initial IV; nil keeps the zero IV.
+func NewRekeySACommand(spi, keyID uint16, iv []byte) *PDU {
+	data := binary.BigEndian.AppendUint16(nil, spi)
+	data = binary.BigEndian.AppendUint16(data, keyID)
+	return NewCommand(ProcRekeySA, append(data, iv...))
+}
+
+// NewStartSACommand builds a Start SA command. The SA is bound to
+// vcids when any are given.
+func NewStartSACommand(spi uint16, vcids ...uint8) *PDU {
+	return NewCommand(ProcStartSA, append(binary.BigEndian.AppendUint16(nil, spi), vcids...))
+}
+
+// NewStopSACommand builds a Stop SA command.
+func NewStopSACommand(spi uint16) *PDU { return spiCommand(ProcStopSA, spi) }
+
+// NewExpireSACommand builds an Expire SA command.
+func NewExpireSACommand(spi uint16) *PDU { return spiCommand(ProcExpireSA, spi) }
+
+// NewDeleteSACommand builds a Delete SA command.
+func NewDeleteSACommand(spi uint16) *PDU { return spiCommand(ProcDeleteSA, spi) }
+
+// NewReadARSNCommand builds a Read ARSN command.
+func NewReadARSNCommand(spi uint16) *PDU { return spiCommand(ProcReadARSN, spi) }
+
+// NewSAStatusCommand builds an SA Status Request command.
+func NewSAStatusCommand(spi uint16) *PDU { return spiCommand(ProcSAStatus, spi) }
+
+// NewSetARSNCommand builds a Set ARSN command.
+func NewSetARSNCommand(spi uint16, arsn uint64) *PDU {
+	return NewCommand(ProcSetARSN, binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint16(nil, spi), arsn))
+}
+
+// NewSetARSNWindowCommand builds a Set ARSN Window command.
+func NewSetARSNWindowCommand(spi uint16, window uint32) *PDU {
+	return NewCommand(ProcSetARSNWindow, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint16(nil, spi), window))
+}
+
+func spiCommand(proc Procedure, spi uint16) *PDU {
+	return NewCommand(proc, binary.BigEndian.AppendUint16(nil, spi))
+}
+
+// ARSNReport is a Read ARSN reply.
+type ARSNReport struct {
+	SPI  uint16
+	ARSN uint64
+	IV   []byte
+}
+
+// DecodeReadARSNReply parses a Read ARSN reply.
+func DecodeReadARSNReply(p *PDU) (ARSNReport, error) {
+	if err := expectReply(p, ProcReadARSN); err != nil {
+		return ARSNReport{}, err
+	}
+	if len(p.Data) < 10 {
+		return ARSNReport{}, ErrInvalidPDU
+	}
+	r := ARSNReport{
+		SPI:  binary.BigEndian.Uint16(p.Data),
+		ARSN: binary.BigEndian.Uint64(p.Data[2:]),
+	}
+	if len(p.Data) > 10 {
+		r.IV = append([]byte(nil), p.Data[10:]...)
+	}
+	return r, nil
+}
+
+// DecodeSAStatusReply parses an SA Status reply.
+func DecodeSAStatusReply(p *PDU) (spi uint16, state SAState, err error) {
+	if err := expectReply(p, ProcSAStatus); err != nil {
+		return 0, 0, err
+	}
+	if len(p.Data) != 3 {
+		return 0, 0, ErrInvalidPDU
+	}
+	return binary.BigEndian.Uint16(p.Data), SAState(p.Data[2]), nil
+}
+
+// NewPingCommand builds a Ping command.
+func NewPingCommand() *PDU { return NewCommand(ProcPing, nil) }
+
+// NewLogStatusCommand builds a Log Status command.
+func NewLogStatusCommand() *PDU { return NewCommand(ProcLogStatus, nil) }
+
+// NewDumpLogCommand builds a Dump Log command.
+func NewDumpLogCommand() *PDU { return NewCommand(ProcDumpLog, nil) }
+
+// NewEraseLogCommand builds an Erase Log command.
+func NewEraseLogCommand() *PDU { return NewCommand(ProcEraseLog, nil) }
+
+// NewSelfTestCommand builds a Self-Test command.
+func NewSelfTestCommand() *PDU { return NewCommand(ProcSelfTest, nil) }
+
+// NewAlarmFlagResetCommand builds an Alarm Flag Reset command.
+func NewAlarmFlagResetCommand() *PDU { return NewCommand(ProcAlarmFlagReset, nil) }
+
+// DecodeLogStatusReply parses a Log Status or Erase Log reply.
+func DecodeLogStatusReply(p *PDU) (LogStatus, error) {
+	if !p.Reply || (p.Procedure != ProcLogStatus && p.Procedure != ProcEraseLog) {
+		return LogStatus{}, ErrProcedureMismatch
+	}
+	if len(p.Data) != 5 {
+		return LogStatus{}, ErrInvalidPDU
+	}
+	return LogStatus{
+		Alarm:     p.Data[0] != 0,
+		Entries:   int(binary.BigEndian.Uint16(p.Data[1:])),
+		Remaining: int(binary.BigEndian.Uint16(p.Data[3:])),
+	}, nil
+}
+
+const logEntrySize = 7
+
+// DecodeDumpLogReply parses a Dump Log reply.
+func DecodeDumpLogReply(p *PDU) ([]LogEntry, error) {
+	if err := expectReply(p, ProcDumpLog); err != nil {
+		return nil, err
+	}
+	if len(p.Data)%logEntrySize != 0 {
+		return nil, ErrInvalidPDU
+	}
+	var out []LogEntry
+	for d := p.Data; len(d) > 0; d = d[logEntrySize:] {
+		out = append(out, LogEntry{
+			Event: LogEvent(d[0]),
+			Ref:   binary.BigEndian.Uint16(d[1:]),
+			Time:  time.Unix(int64(binary.BigEndian.Uint32(d[3:])), 0).UTC(),
+		})
+	}
+	return out, nil
+}
+
+// DecodeSelfTestReply parses a Self-Test reply and reports whether the
+// test passed.
+func DecodeSelfTestReply(p *PDU) (bool, error) {
+	if err := expectReply(p, ProcSelfTest); err != nil {
+		return false, err
+	}
+	if len(p.Data) != 1 {
+		return false, ErrInvalidPDU
+	}
+	return p.Data[0] == 0, nil
+}
+
+// expectReply checks that p is a reply to proc.
+func expectReply(p *PDU, proc Procedure) error {
+	if !p.Reply || p.Procedure != proc {
+		return ErrProcedureMismatch
+	}
+	return nil
+}
+
+// Humanize returns a human-readable representation of the parameters.
+func (p SAParams) Humanize() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, %s, IV: %d, SN: %d, Pad: %d, MAC: %d, Window: %d",
+		p.Service, p.Algorithm, p.IVLength, p.SNLength, p.PadLength, p.MACLength, p.Window)
+	if len(p.AuthMask) > 0 {
+		fmt.Fprintf(&b, ", Mask: %X", p.AuthMask)
+	}
+	return b.String()
+}
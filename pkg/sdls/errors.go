@@ -0,0 +1,83 @@
+package sdls
+
+import "errors"
+
+var (
+	// ErrDataTooShort indicates the frame data field is shorter than the SA's Security Header and Trailer.
+	ErrDataTooShort = errors.New("data too short for SDLS security header and trailer")
+
+	// ErrUnknownSPI indicates no Security Association is registered for the received SPI.
+	ErrUnknownSPI = errors.New("no security association for SPI")
+
+	// ErrDuplicateSPI indicates a Security Association with the same SPI is already registered.
+	ErrDuplicateSPI = errors.New("security association SPI already registered")
+
+	// ErrChannelMismatch indicates the frame's virtual channel is not bound to the Security Association.
+	ErrChannelMismatch = errors.New("virtual channel not bound to security association")
+
+	// ErrUnsupportedService indicates the service type is unknown or not available with the algorithm.
+	ErrUnsupportedService = errors.New("unsupported security service for algorithm")
+
+	// ErrUnsupportedAlgorithm indicates the cryptographic algorithm is unknown.
+	ErrUnsupportedAlgorithm = errors.New("unsupported cryptographic algorithm")
+
+	// ErrInvalidKeyLength indicates the key length does not suit the algorithm.
+	ErrInvalidKeyLength = errors.New("invalid key length for algorithm")
+
+	// ErrNoKey indicates the Security Association has no key material.
+	ErrNoKey = errors.New("security association has no key")
+
+	// ErrSANotOperational indicates the Security Association is not in the Operational state.
+	ErrSANotOperational = errors.New("security association not operational")
+
+	// ErrSAState indicates the SA management procedure is not permitted in the SA's current state.
+	ErrSAState = errors.New("SA management procedure not permitted in current SA state")
+
+	// ErrInvalidIVLength indicates the IV length does not suit the algorithm.
+	ErrInvalidIVLength = errors.New("invalid IV length for algorithm")
+
+	// ErrInvalidSequenceLength indicates the Sequence Number field length is outside 0-8 octets.
+	ErrInvalidSequenceLength = errors.New("sequence number length must be 0-8 octets")
+
+	// ErrInvalidPadLength indicates the Pad Length field length is outside 0-2 octets.
+	ErrInvalidPadLength = errors.New("pad length field must be 0-2 octets")
+
+	// ErrInvalidMACLength indicates the MAC length does not suit the algorithm.
+	ErrInvalidMACLength = errors.New("invalid MAC length for algorithm")
+
+	// ErrSequenceExhausted indicates the IV or sequence number counter cannot advance without wrapping.
+	ErrSequenceExhausted = errors.New("SDLS counter exhausted, security association must be rekeyed")
+
+	// ErrAuthenticationFailed indicates the received MAC does not verify.
+	ErrAuthenticationFailed = errors.New("SDLS MAC verification failed")
+
+	// ErrReplay indicates the received sequence number is outside the anti-replay window.
+	ErrReplay = errors.New("SDLS sequence number outside anti-replay window")
+
+	// ErrFrameTooLarge indicates the protected frame exceeds the maximum frame length.
+	ErrFrameTooLarge = errors.New("protected frame exceeds maximum frame length")
+
+	// ErrInvalidPDU indicates an Extended Procedures PDU or its data field is malformed.
+	ErrInvalidPDU = errors.New("invalid SDLS extended procedures PDU")
+
+	// ErrPDUTooLarge indicates the PDU data field exceeds the 16-bit length field.
+	ErrPDUTooLarge = errors.New("SDLS extended procedures PDU data too large")
+
+	// ErrUnsupportedProcedure indicates the procedure is not implemented on board.
+	ErrUnsupportedProcedure = errors.New("unsupported SDLS extended procedure")
+
+	// ErrProcedureMismatch indicates the PDU is not a reply to the expected procedure.
+	ErrProcedureMismatch = errors.New("PDU is not a reply to the expected procedure")
+
+	// ErrUnknownKey indicates no key with the given ID is in the key store.
+	ErrUnknownKey = errors.New("no key with ID in key store")
+
+	// ErrKeyExists indicates an OTAR key ID is already in the key store.
+	ErrKeyExists = errors.New("key ID already in key store")
+
+	// ErrKeyState indicates the key is not in the state the procedure requires.
+	ErrKeyState = errors.New("key not in required state")
+
+	// ErrInvalidKeyStore indicates the key store file is malformed.
+	ErrInvalidKeyStore = errors.New("invalid key store file")
+)
@@ -0,0 +1,307 @@
+package sdls
+
+import (
+	"encoding/binary"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/crc"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+// ApplyTC protects the data field of a TC Transfer Frame per CCSDS
+// 355.0-B-2 Section 3.4. The MAC covers the Primary Header, the Segment
+// Header when present, the Security Header and the data field. The
+// Frame Length and FECF are updated.
+func (sa *SecurityAssociation) ApplyTC(frame *tcdl.TCTransferFrame) error {
+	if !sa.Bound(frame.Header.VirtualChannelID) {
+		return ErrChannelMismatch
+	}
+	h := frame.Header
+	size, err := tcFrameSize(frame, len(frame.DataField)+sa.Overhead())
+	if err != nil {
+		return err
+	}
+	h.FrameLength = uint16(size - 1)
+	prefix, err := tcPrefix(h, frame.SegmentHeader)
+	if err != nil {
+		return err
+	}
+	field, err := sa.protect(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	frame.Header = h
+	frame.DataField = field
+	return refreshTC(frame)
+}
+
+// ApplyTM protects the data field of a TM Transfer Frame. The MAC
+// covers the Primary Header, the Secondary Header when present, the
+// Security Header and the data field; the OCF is not protected. The
+// FECF is updated. The data field grows by Overhead bytes.
+func (sa *SecurityAssociation) ApplyTM(frame *tmdl.TMTransferFrame) error {
+	if !sa.Bound(frame.Header.VirtualChannelID) {
+		return ErrChannelMismatch
+	}
+	prefix, err := tmPrefix(frame)
+	if err != nil {
+		return err
+	}
+	field, err := sa.protect(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	frame.DataField = field
+	return refreshTM(frame)
+}
+
+// ApplyAOS protects the data field of an AOS Transfer Frame. The MAC
+// covers the Primary Header, the Insert Zone, the Security Header and
+// the data field; the OCF is not protected. The FECF, when present, is
+// updated. The data field grows by Overhead bytes.
+func (sa *SecurityAssociation) ApplyAOS(frame *aos.TransferFrame) error {
+	if !sa.Bound(frame.Header.VCID) {
+		return ErrChannelMismatch
+	}
+	prefix, err := aosPrefix(frame)
+	if err != nil {
+		return err
+	}
+	field, err := sa.protect(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	frame.DataField = field
+	return refreshAOS(frame)
+}
+
+// ApplyUSLP protects the Transfer Frame Data Zone of a USLP Transfer
+// Frame. The MAC covers the Primary Header, the Insert Zone, the
+// Transfer Frame Data Field Header, the Security Header and the data
+// zone; the OCF is not protected. The Frame Length and FECF are updated.
+//
+// The Data Field Header stays in the clear so protected frames still
+// decode with usdl.DecodeTransferFrame.
+func (sa *SecurityAssociation) ApplyUSLP(frame *usdl.TransferFrame) error {
+	if !sa.Bound(frame.Header.VCID) {
+		return ErrChannelMismatch
+	}
+	h := frame.Header
+	size, err := uslpFrameSize(frame, len(frame.DataField)+sa.Overhead())
+	if err != nil {
+		return err
+	}
+	h.FrameLength = uint16(size - 1)
+	prefix, err := uslpPrefix(h, frame)
+	if err != nil {
+		return err
+	}
+	field, err := sa.protect(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	frame.Header = h
+	frame.DataField = field
+	return refreshUSLP(frame)
+}
+
+// processTC verifies and unwraps a protected TC frame with sa.
+func (sa *SecurityAssociation) processTC(frame *tcdl.TCTransferFrame) error {
+	prefix, err := tcPrefix(frame.Header, frame.SegmentHeader)
+	if err != nil {
+		return err
+	}
+	data, err := sa.verify(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	size, err := tcFrameSize(frame, len(data))
+	if err != nil {
+		return err
+	}
+	frame.Header.FrameLength = uint16(size - 1)
+	frame.DataField = data
+	return refreshTC(frame)
+}
+
+// processTM verifies and unwraps a protected TM frame with sa.
+func (sa *SecurityAssociation) processTM(frame *tmdl.TMTransferFrame) error {
+	prefix, err := tmPrefix(frame)
+	if err != nil {
+		return err
+	}
+	data, err := sa.verify(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	frame.DataField = data
+	return refreshTM(frame)
+}
+
+// processAOS verifies and unwraps a protected AOS frame with sa.
+func (sa *SecurityAssociation) processAOS(frame *aos.TransferFrame) error {
+	prefix, err := aosPrefix(frame)
+	if err != nil {
+		return err
+	}
+	data, err := sa.verify(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	frame.DataField = data
+	return refreshAOS(frame)
+}
+
+// processUSLP verifies and unwraps a protected USLP frame with sa.
+func (sa *SecurityAssociation) processUSLP(frame *usdl.TransferFrame) error {
+	prefix, err := uslpPrefix(frame.Header, frame)
+	if err != nil {
+		return err
+	}
+	data, err := sa.verify(prefix, frame.DataField)
+	if err != nil {
+		return err
+	}
+	size, err := uslpFrameSize(frame, len(data))
+	if err != nil {
+		return err
+	}
+	frame.Header.FrameLength = uint16(size - 1)
+	frame.DataField = data
+	return refreshUSLP(frame)
+}
+
+// tcFrameSize returns the total TC frame length for a data field of n bytes.
+func tcFrameSize(frame *tcdl.TCTransferFrame, n int) (int, error) {
+	size := tcdl.PrimaryHeaderSize + n + tcdl.FECSize
+	if frame.SegmentHeader != nil {
+		size++
+	}
+	if size > tcdl.MaxFrameLength {
+		return 0, ErrFrameTooLarge
+	}
+	return size, nil
+}
+
+// tcPrefix encodes the TC frame content ahead of the Security Header.
+func tcPrefix(h tcdl.PrimaryHeader, sh *tcdl.SegmentHeader) ([]byte, error) {
+	prefix, err := h.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if sh != nil {
+		b, err := sh.Encode()
+		if err != nil {
+			return nil, err
+		}
+		prefix = append(prefix, b...)
+	}
+	return prefix, nil
+}
+
+// refreshTC recomputes the TC frame's FECF.
+func refreshTC(frame *tcdl.TCTransferFrame) error {
+	encoded, err := frame.EncodeWithoutFEC()
+	if err != nil {
+		return err
+	}
+	frame.FrameErrorControl = crc.ComputeCRC16(encoded)
+	return nil
+}
+
+// tmPrefix encodes the TM frame content ahead of the Security Header.
+func tmPrefix(frame *tmdl.TMTransferFrame) ([]byte, error) {
+	prefix, err := frame.Header.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if frame.Header.FSHFlag {
+		sh, err := frame.SecondaryHeader.Encode()
+		if err != nil {
+			return nil, err
+		}
+		prefix = append(prefix, sh...)
+	}
+	return prefix, nil
+}
+
+// refreshTM recomputes the TM frame's FECF.
+func refreshTM(frame *tmdl.TMTransferFrame) error {
+	encoded, err := frame.EncodeWithoutFEC()
+	if err != nil {
+		return err
+	}
+	frame.FrameErrorControl = crc.ComputeCRC16(encoded)
+	return nil
+}
+
+// aosPrefix encodes the AOS frame content ahead of the Security Header.
+func aosPrefix(frame *aos.TransferFrame) ([]byte, error) {
+	prefix, err := frame.Header.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return append(prefix, frame.InsertZone...), nil
+}
+
+// refreshAOS recomputes the AOS frame's FECF when the frame has one.
+func refreshAOS(frame *aos.TransferFrame) error {
+	if !frame.HasFECF {
+		return nil
+	}
+	prefix, err := aosPrefix(frame)
+	if err != nil {
+		return err
+	}
+	encoded := concat(prefix, frame.DataField, frame.OCF)
+	frame.FECF = make([]byte, aos.FECFSize)
+	binary.BigEndian.PutUint16(frame.FECF, crc.ComputeCRC16(encoded))
+	return nil
+}
+
+// uslpFrameSize returns the total USLP frame length for a data zone of n bytes.
+func uslpFrameSize(frame *usdl.TransferFrame, n int) (int, error) {
+	size := frame.Header.Size() + len(frame.InsertZone) + usdl.DataFieldHeaderSize + n + len(frame.OCF)
+	if frame.UseCRC32 {
+		size += usdl.FECSize32
+	} else {
+		size += usdl.FECSize16
+	}
+	if size > 65536 {
+		return 0, ErrFrameTooLarge
+	}
+	return size, nil
+}
+
+// uslpPrefix encodes the USLP frame content ahead of the Security Header
+// using primary header h.
+func uslpPrefix(h usdl.PrimaryHeader, frame *usdl.TransferFrame) ([]byte, error) {
+	prefix, err := h.Encode()
+	if err != nil {
+		return nil, err
+	}
+	dfh, err := frame.DataFieldHeader.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return concat(prefix, frame.InsertZone, dfh), nil
+}
+
+// refreshUSLP recomputes the USLP frame's FECF.
+func refreshUSLP(frame *usdl.TransferFrame) error {
+	prefix, err := uslpPrefix(frame.Header, frame)
+	if err != nil {
+		return err
+	}
+	encoded := concat(prefix, frame.DataField, frame.OCF)
+	if frame.UseCRC32 {
+		frame.FECF = make([]byte, usdl.FECSize32)
+		binary.BigEndian.PutUint32(frame.FECF, crc.ComputeCRC32(encoded))
+	} else {
+		frame.FECF = make([]byte, usdl.FECSize16)
+		binary.BigEndian.PutUint16(frame.FECF, crc.ComputeCRC16(encoded))
+	}
+	return nil
+}
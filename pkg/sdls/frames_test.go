@@ -0,0 +1,183 @@
+package sdls_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/sdls"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+func TestApplyTC_SegmentHeaderAndTamper(t *testing.T) {
+	tx, store := saPair(t, 10, sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM)
+	payload := []byte("telecommand")
+	frame, _ := tcdl.NewTCTransferFrame(42, 2, payload,
+		tcdl.WithSegmentHeader(tcdl.SegmentHeader{SequenceFlags: tcdl.SegUnsegmented, MAPID: 3}))
+	if err := tx.ApplyTC(frame); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := frame.Encode()
+	if want := tcdl.PrimaryHeaderSize + 1 + len(payload) + tx.Overhead() + tcdl.FECSize; len(encoded) != want {
+		t.Errorf("frame length = %d, want %d", len(encoded), want)
+	}
+
+	rx, err := tcdl.DecodeTCTransferFrameWithSegmentHeader(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ProcessTC(rx); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rx.DataField, payload) {
+		t.Errorf("data = %q, want %q", rx.DataField, payload)
+	}
+	if int(rx.Header.FrameLength)+1 != tcdl.PrimaryHeaderSize+1+len(payload)+tcdl.FECSize {
+		t.Errorf("frame length not restored: %d", rx.Header.FrameLength)
+	}
+	if _, err := tcdl.DecodeTCTransferFrameWithSegmentHeader(mustEncodeTC(t, rx)); err != nil {
+		t.Errorf("unprotected frame does not decode: %v", err)
+	}
+
+	// A flipped MAP ID in the authenticated segment header must fail.
+	tx2, store2 := saPair(t, 10, sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM)
+	frame, _ = tcdl.NewTCTransferFrame(42, 2, payload,
+		tcdl.WithSegmentHeader(tcdl.SegmentHeader{SequenceFlags: tcdl.SegUnsegmented, MAPID: 3}))
+	_ = tx2.ApplyTC(frame)
+	frame.SegmentHeader.MAPID = 4
+	if _, err := store2.ProcessTC(frame); !errors.Is(err, sdls.ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed, got %v", err)
+	}
+}
+
+func mustEncodeTC(t *testing.T, f *tcdl.TCTransferFrame) []byte {
+	t.Helper()
+	b, err := f.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestApplyTM_OCFUnprotected(t *testing.T) {
+	tx, store := saPair(t, 11, sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA256)
+	payload := []byte("housekeeping")
+	frame, _ := tmdl.NewTMTransferFrame(42, 1, payload, []byte{0xAB, 0xCD}, []byte{1, 2, 3, 4})
+	if err := tx.ApplyTM(frame); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := frame.Encode()
+
+	// The OCF may be replaced after protection, e.g. by the master channel.
+	rx, err := tmdl.DecodeTMTransferFrame(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rx.OperationalControl = []byte{9, 9, 9, 9}
+	if _, err := store.ProcessTM(rx); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rx.DataField, payload) {
+		t.Errorf("data = %q, want %q", rx.DataField, payload)
+	}
+
+	// The secondary header is authenticated.
+	frame, _ = tmdl.NewTMTransferFrame(42, 1, payload, []byte{0xAB, 0xCD}, nil)
+	_ = tx.ApplyTM(frame)
+	frame.SecondaryHeader.DataField[0] = 0
+	if _, err := store.ProcessTM(frame); !errors.Is(err, sdls.ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed, got %v", err)
+	}
+}
+
+func TestApplyTM_AuthMask(t *testing.T) {
+	// Mask the MC frame count (byte 2) so it can be set after protection.
+	mask := []byte{0xFF, 0xFF, 0x00, 0xFF, 0xFF, 0xFF}
+	tx, store := saPair(t, 12, sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM, sdls.WithAuthMask(mask))
+	frame, _ := tmdl.NewTMTransferFrame(42, 1, []byte("science"), nil, nil)
+	_ = tx.ApplyTM(frame)
+	frame.Header.MCFrameCount = 77
+	if _, err := store.ProcessTM(frame); err != nil {
+		t.Errorf("masked field change rejected: %v", err)
+	}
+}
+
+func TestApplyAOS(t *testing.T) {
+	tx, store := saPair(t, 13, sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM, sdls.WithVCIDs(5))
+	payload := []byte("aos payload")
+	frame, _ := aos.NewTransferFrame(42, 5, payload,
+		aos.WithInsertZone([]byte{1, 2}), aos.WithOCF([]byte{0, 0, 0, 0}), aos.WithFECF())
+	if err := tx.ApplyAOS(frame); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := frame.Encode()
+	rx, err := aos.DecodeTransferFrame(encoded, 2, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ProcessAOS(rx); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rx.DataField, payload) {
+		t.Errorf("data = %q, want %q", rx.DataField, payload)
+	}
+	if _, err := aos.DecodeTransferFrame(mustEncodeAOS(t, rx), 2, true, true); err != nil {
+		t.Errorf("unprotected frame FECF invalid: %v", err)
+	}
+
+	other, _ := aos.NewTransferFrame(42, 6, payload)
+	if err := tx.ApplyAOS(other); !errors.Is(err, sdls.ErrChannelMismatch) {
+		t.Errorf("expected ErrChannelMismatch, got %v", err)
+	}
+}
+
+func mustEncodeAOS(t *testing.T, f *aos.TransferFrame) []byte {
+	t.Helper()
+	b, err := f.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestApplyUSLP(t *testing.T) {
+	for _, crc32 := range []bool{false, true} {
+		tx, store := saPair(t, 14, sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC)
+		payload := []byte("uslp data zone")
+		opts := []usdl.FrameOption{usdl.WithOCF([]byte{1, 2, 3, 4}), usdl.WithSequenceNumber(9)}
+		fecSize := usdl.FECSize16
+		if crc32 {
+			opts = append(opts, usdl.WithCRC32())
+			fecSize = usdl.FECSize32
+		}
+		frame, _ := usdl.NewTransferFrame(42, 1, 2, payload, opts...)
+		if err := tx.ApplyUSLP(frame); err != nil {
+			t.Fatal(err)
+		}
+		encoded, _ := frame.Encode()
+		if int(frame.Header.FrameLength)+1 != len(encoded) {
+			t.Errorf("frame length %d, encoded %d bytes", frame.Header.FrameLength+1, len(encoded))
+		}
+
+		rx, err := usdl.DecodeTransferFrameWithOCF(encoded, fecSize, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rx.DataFieldHeader.SequenceNumber != 9 {
+			t.Errorf("data field header not in the clear: %+v", rx.DataFieldHeader)
+		}
+		if _, err := store.ProcessUSLP(rx); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rx.DataField, payload) {
+			t.Errorf("data = %q, want %q", rx.DataField, payload)
+		}
+		plain, _ := rx.Encode()
+		if _, err := usdl.DecodeTransferFrameWithOCF(plain, fecSize, 0); err != nil {
+			t.Errorf("unprotected frame invalid: %v", err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package sdls
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// KeyState is the life-cycle state of a key per CCSDS 355.1-B-1
+// Section 4: keys arrive Pre-Active by OTAR, are Activated for use by
+// SAs, Deactivated when retired and finally Destroyed.
+type KeyState uint8
+
+const (
+	KeyPreActive   KeyState = iota + 1 // uploaded, not yet usable
+	KeyActive                          // usable by SAs and as a master key
+	KeyDeactivated                     // retired, may only be destroyed
+	KeyDestroyed                       // key material erased
+)
+
+// String returns the state name.
+func (s KeyState) String() string {
+	switch s {
+	case KeyPreActive:
+		return "pre-active"
+	case KeyActive:
+		return "active"
+	case KeyDeactivated:
+		return "deactivated"
+	case KeyDestroyed:
+		return "destroyed"
+	default:
+		return "unknown"
+	}
+}
+
+// parseKeyState is the inverse of KeyState.String.
+func parseKeyState(s string) (KeyState, bool) {
+	for st := KeyPreActive; st <= KeyDestroyed; st++ {
+		if st.String() == s {
+			return st, true
+		}
+	}
+	return 0, false
+}
+
+// Key is one entry of the on-board key store.
+type Key struct {
+	ID    uint16
+	State KeyState
+	Value []byte // nil once destroyed
+}
+
+// KeyStore persists keys by ID. Implementations must be safe for
+// concurrent use. Get returns ErrUnknownKey for a missing ID.
+type KeyStore interface {
+	Get(id uint16) (Key, error)
+	Put(k Key) error
+	Delete(id uint16) error
+	IDs() ([]uint16, error)
+}
+
+// MemoryKeyStore is a KeyStore held in memory.
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[uint16]Key
+}
+
+// NewMemoryKeyStore creates an empty in-memory key store.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[uint16]Key)}
+}
+
+// Get returns a copy of the key with the given ID.
+func (s *MemoryKeyStore) Get(id uint16) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrUnknownKey
+	}
+	k.Value = slices.Clone(k.Value)
+	return k, nil
+}
+
+// Put stores a copy of k, replacing any key with the same ID.
+func (s *MemoryKeyStore) Put(k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k.Value = slices.Clone(k.Value)
+	s.keys[k.ID] = k
+	return nil
+}
+
+// Delete removes the key with the given ID.
+func (s *MemoryKeyStore) Delete(id uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.keys[id]; ok {
+		clear(old.Value)
+	}
+	delete(s.keys, id)
+	return nil
+}
+
+// IDs returns the stored key IDs in ascending order.
+func (s *MemoryKeyStore) IDs() ([]uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]uint16, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// FileKeyStore is a KeyStore persisted as a JSON file. Every change
+// rewrites the file atomically with owner-only permissions, so a reset
+// during an update leaves either the old or the new key set.
+type FileKeyStore struct {
+	mem  *MemoryKeyStore
+	path string
+	mu   sync.Mutex
+}
+
+type fileKey struct {
+	ID    uint16 `json:"id"`
+	State string `json:"state"`
+	Value string `json:"value,omitempty"`
+}
+
+// NewFileKeyStore opens the key store at path, loading it if the file
+// exists. The file is created on the first change.
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	s := &FileKeyStore{mem: NewMemoryKeyStore(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []fileKey
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		st, ok := parseKeyState(e.State)
+		if !ok {
+			return nil, ErrInvalidKeyStore
+		}
+		v, err := hex.DecodeString(e.Value)
+		if err != nil {
+			return nil, ErrInvalidKeyStore
+		}
+		if len(v) == 0 {
+			v = nil
+		}
+		_ = s.mem.Put(Key{ID: e.ID, State: st, Value: v})
+	}
+	return s, nil
+}
+
+// Get returns a copy of the key with the given ID.
+func (s *FileKeyStore) Get(id uint16) (Key, error) { return s.mem.Get(id) }
+
+// IDs returns the stored key IDs in ascending order.
+func (s *FileKeyStore) IDs() ([]uint16, error) { return s.mem.IDs() }
+
+// Put stores k and rewrites the file. The key set in memory changes only
+// once the file has been written.
+func (s *FileKeyStore) Put(k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := s.snapshot()
+	keys[k.ID] = k
+	if err := s.save(keys); err != nil {
+		return err
+	}
+	return s.mem.Put(k)
+}
+
+// Delete removes the key with the given ID and rewrites the file. The key
+// is removed from memory, and zeroized, only once the file has been
+// written.
+func (s *FileKeyStore) Delete(id uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := s.snapshot()
+	delete(keys, id)
+	if err := s.save(keys); err != nil {
+		return err
+	}
+	return s.mem.Delete(id)
+}
+
+// snapshot returns a copy of the key set in memory.
+func (s *FileKeyStore) snapshot() map[uint16]Key {
+	ids, _ := s.mem.IDs()
+	keys := make(map[uint16]Key, len(ids))
+	for _, id := range ids {
+		keys[id], _ = s.mem.Get(id)
+	}
+	return keys
+}
+
+// save writes keys to a temporary file and renames it over the store.
+// The caller holds s.mu.
+func (s *FileKeyStore) save(keys map[uint16]Key) error {
+	ids := slices.Sorted(maps.Keys(keys))
+	entries := make([]fileKey, 0, len(ids))
+	for _, id := range ids {
+		k := keys[id]
+		entries = append(entries, fileKey{ID: k.ID, State: k.State.String(), Value: hex.EncodeToString(k.Value)})
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
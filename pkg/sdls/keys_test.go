@@ -0,0 +1,117 @@
+package sdls_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+)
+
+func TestMemoryKeyStore(t *testing.T) {
+	s := sdls.NewMemoryKeyStore()
+	if _, err := s.Get(1); !errors.Is(err, sdls.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	value := slices.Clone(testKey)
+	_ = s.Put(sdls.Key{ID: 2, State: sdls.KeyActive, Value: value})
+	_ = s.Put(sdls.Key{ID: 1, State: sdls.KeyPreActive, Value: testKey})
+	value[0] = 'x'
+	k, err := s.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k.Value, testKey) {
+		t.Error("store did not copy the key value")
+	}
+	if ids, _ := s.IDs(); !slices.Equal(ids, []uint16{1, 2}) {
+		t.Errorf("IDs = %v", ids)
+	}
+	_ = s.Delete(1)
+	if ids, _ := s.IDs(); !slices.Equal(ids, []uint16{2}) {
+		t.Errorf("IDs after delete = %v", ids)
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := sdls.NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(sdls.Key{ID: 1, State: sdls.KeyActive, Value: testKey}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(sdls.Key{ID: 7, State: sdls.KeyDestroyed}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reopened, err := sdls.NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := reopened.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.State != sdls.KeyActive || !bytes.Equal(k.Value, testKey) {
+		t.Errorf("reloaded key = %+v", k)
+	}
+	if k, _ := reopened.Get(7); k.State != sdls.KeyDestroyed || k.Value != nil {
+		t.Errorf("reloaded destroyed key = %+v", k)
+	}
+
+	_ = reopened.Delete(7)
+	again, _ := sdls.NewFileKeyStore(path)
+	if ids, _ := again.IDs(); !slices.Equal(ids, []uint16{1}) {
+		t.Errorf("IDs after delete = %v", ids)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"id":1,"state":"bogus"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdls.NewFileKeyStore(path); !errors.Is(err, sdls.ErrInvalidKeyStore) {
+		t.Errorf("expected ErrInvalidKeyStore, got %v", err)
+	}
+}
+
+func TestFileKeyStore_SaveFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	s, err := sdls.NewFileKeyStore(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := slices.Clone(testKey)
+	if err := s.Put(sdls.Key{ID: 1, State: sdls.KeyActive, Value: key}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(sdls.Key{ID: 2, State: sdls.KeyActive, Value: key}); err == nil {
+		t.Fatal("Put should fail when the file cannot be written")
+	}
+	if ids, _ := s.IDs(); !slices.Equal(ids, []uint16{1}) {
+		t.Errorf("IDs after failed Put = %v", ids)
+	}
+	if err := s.Delete(1); err == nil {
+		t.Fatal("Delete should fail when the file cannot be written")
+	}
+	if k, err := s.Get(1); err != nil || !bytes.Equal(k.Value, testKey) {
+		t.Errorf("key after failed Delete = %+v, %v", k, err)
+	}
+}
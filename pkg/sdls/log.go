@@ -0,0 +1,151 @@
+package sdls
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LogEvent identifies a security log entry.
+type LogEvent uint8
+
+const (
+	LogKeysUploaded    LogEvent = iota + 1 // OTAR accepted; Ref is the master key ID
+	LogKeyActivated                        // Ref is the key ID
+	LogKeyDeactivated                      // Ref is the key ID
+	LogKeyDestroyed                        // Ref is the key ID
+	LogSACreated                           // Ref is the SPI
+	LogSARekeyed                           // Ref is the SPI
+	LogSAStarted                           // Ref is the SPI
+	LogSAStopped                           // Ref is the SPI
+	LogSAExpired                           // Ref is the SPI
+	LogSADeleted                           // Ref is the SPI
+	LogARSNSet                             // Ref is the SPI
+	LogARSNWindowSet                       // Ref is the SPI
+	LogCommandRejected                     // Ref is the procedure
+	LogAuthFailure                         // frame MAC failed; Ref is the SPI
+	LogReplay                              // frame outside window; Ref is the SPI
+	LogUnknownSPI                          // frame for an unknown SPI; Ref is the SPI
+	LogSelfTestFailed                      // self-test known-answer test failed
+)
+
+var logEventNames = map[LogEvent]string{
+	LogKeysUploaded:    "Keys Uploaded",
+	LogKeyActivated:    "Key Activated",
+	LogKeyDeactivated:  "Key Deactivated",
+	LogKeyDestroyed:    "Key Destroyed",
+	LogSACreated:       "SA Created",
+	LogSARekeyed:       "SA Rekeyed",
+	LogSAStarted:       "SA Started",
+	LogSAStopped:       "SA Stopped",
+	LogSAExpired:       "SA Expired",
+	LogSADeleted:       "SA Deleted",
+	LogARSNSet:         "ARSN Set",
+	LogARSNWindowSet:   "ARSN Window Set",
+	LogCommandRejected: "Command Rejected",
+	LogAuthFailure:     "Authentication Failure",
+	LogReplay:          "Replay",
+	LogUnknownSPI:      "Unknown SPI",
+	LogSelfTestFailed:  "Self-Test Failed",
+}
+
+// String returns the event name.
+func (e LogEvent) String() string {
+	if name, ok := logEventNames[e]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Alarm reports whether the event raises the alarm flag.
+func (e LogEvent) Alarm() bool {
+	switch e {
+	case LogAuthFailure, LogReplay, LogUnknownSPI, LogSelfTestFailed:
+		return true
+	}
+	return false
+}
+
+// LogEntry is one security log record.
+type LogEntry struct {
+	Event LogEvent
+	Ref   uint16 // key ID, SPI or procedure, depending on Event
+	Time  time.Time
+}
+
+// Humanize returns a human-readable representation of the entry.
+func (e LogEntry) Humanize() string {
+	return fmt.Sprintf("%s %s (ref %d)", e.Time.UTC().Format(time.RFC3339), e.Event, e.Ref)
+}
+
+// LogStatus summarizes the security log.
+type LogStatus struct {
+	Alarm     bool
+	Entries   int
+	Remaining int
+}
+
+// DefaultLogCapacity is the default number of security log entries.
+const DefaultLogCapacity = 256
+
+// SecurityLog records security-relevant events on board per CCSDS
+// 355.1-B-1 Section 6. When full it drops the oldest entry. Events that
+// indicate an attack or fault raise an alarm flag, which stays set
+// until reset by ground command.
+type SecurityLog struct {
+	mu       sync.Mutex
+	entries  []LogEntry
+	capacity int
+	alarm    bool
+}
+
+// NewSecurityLog creates a log holding up to capacity entries;
+// DefaultLogCapacity when capacity is not positive.
+func NewSecurityLog(capacity int) *SecurityLog {
+	if capacity <= 0 {
+		capacity = DefaultLogCapacity
+	}
+	return &SecurityLog{capacity: capacity}
+}
+
+// Record appends an event.
+func (l *SecurityLog) Record(event LogEvent, ref uint16) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == l.capacity {
+		l.entries = slices.Delete(l.entries, 0, 1)
+	}
+	l.entries = append(l.entries, LogEntry{Event: event, Ref: ref, Time: time.Now()})
+	if event.Alarm() {
+		l.alarm = true
+	}
+}
+
+// Entries returns a copy of the log, oldest first.
+func (l *SecurityLog) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.entries)
+}
+
+// Status returns the alarm flag and log occupancy.
+func (l *SecurityLog) Status() LogStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LogStatus{Alarm: l.alarm, Entries: len(l.entries), Remaining: l.capacity - len(l.entries)}
+}
+
+// Erase removes every entry. The alarm flag is unchanged.
+func (l *SecurityLog) Erase() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// ResetAlarm clears the alarm flag.
+func (l *SecurityLog) ResetAlarm() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.alarm = false
+}
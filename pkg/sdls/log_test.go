@@ -0,0 +1,34 @@
+package sdls_test
+
+import (
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+)
+
+func TestSecurityLog(t *testing.T) {
+	l := sdls.NewSecurityLog(2)
+	l.Record(sdls.LogSACreated, 1)
+	if st := l.Status(); st.Alarm || st.Entries != 1 || st.Remaining != 1 {
+		t.Errorf("status = %+v", st)
+	}
+
+	l.Record(sdls.LogAuthFailure, 1)
+	l.Record(sdls.LogSAStarted, 1)
+	entries := l.Entries()
+	if len(entries) != 2 || entries[0].Event != sdls.LogAuthFailure || entries[1].Event != sdls.LogSAStarted {
+		t.Errorf("entries = %v", entries)
+	}
+	if !l.Status().Alarm {
+		t.Error("authentication failure did not raise the alarm")
+	}
+
+	l.Erase()
+	if st := l.Status(); !st.Alarm || st.Entries != 0 || st.Remaining != 2 {
+		t.Errorf("status after erase = %+v", st)
+	}
+	l.ResetAlarm()
+	if l.Status().Alarm {
+		t.Error("alarm not reset")
+	}
+}
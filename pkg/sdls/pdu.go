@@ -0,0 +1,227 @@
+package sdls
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+/*
+SDLS Extended Procedures PDU (CCSDS 355.1-B-1 Section 5.3):
+
++--------+--------+----------+-----------+----------------+----------------+
+| Type   | User   | Service  | Procedure | Length (16b)   | Data Field     |
+| (1b)   | Flag   | Group    | ID (4b)   | in bits        | (variable)     |
+|        | (1b)   | (2b)     |           |                |                |
++--------+--------+----------+-----------+----------------+----------------+
+
+Type: 0 = command, 1 = reply. The User Flag marks mission-defined
+procedures. EP PDUs travel as the user data of Space Packets on an APID
+reserved for SDLS: commands in TC packets, replies in TM packets.
+*/
+
+// PDUHeaderSize is the size of the EP PDU tag and length fields in bytes.
+const PDUHeaderSize = 3
+
+// MaxPDUDataLength is the largest EP PDU data field in bytes: the
+// 16-bit length field counts bits.
+const MaxPDUDataLength = 0xFFFF / 8
+
+// ServiceGroup is the 2-bit service group of an EP procedure.
+type ServiceGroup uint8
+
+const (
+	GroupKeyManagement     ServiceGroup = 0b00
+	GroupSAManagement      ServiceGroup = 0b01
+	GroupMonitoringControl ServiceGroup = 0b11
+)
+
+// String returns the service group name.
+func (g ServiceGroup) String() string {
+	switch g {
+	case GroupKeyManagement:
+		return "Key Management"
+	case GroupSAManagement:
+		return "SA Management"
+	case GroupMonitoringControl:
+		return "Security Monitoring & Control"
+	default:
+		return "Reserved"
+	}
+}
+
+// Procedure identifies an EP procedure: the service group in bits 4-5
+// and the procedure identifier in bits 0-3.
+type Procedure uint8
+
+// Key management procedures.
+const (
+	ProcOTAR            Procedure = 0x01
+	ProcKeyActivation   Procedure = 0x02
+	ProcKeyDeactivation Procedure = 0x03
+	ProcKeyVerification Procedure = 0x04
+	ProcKeyDestruction  Procedure = 0x06
+	ProcKeyInventory    Procedure = 0x07
+)
+
+// SA management procedures.
+const (
+	ProcReadARSN      Procedure = 0x10
+	ProcCreateSA      Procedure = 0x11
+	ProcDeleteSA      Procedure = 0x14
+	ProcSetARSNWindow Procedure = 0x15
+	ProcRekeySA       Procedure = 0x16
+	ProcExpireSA      Procedure = 0x19
+	ProcSetARSN       Procedure = 0x1A
+	ProcStartSA       Procedure = 0x1B
+	ProcStopSA        Procedure = 0x1E
+	ProcSAStatus      Procedure = 0x1F
+)
+
+// Security monitoring and control procedures.
+const (
+	ProcPing           Procedure = 0x31
+	ProcLogStatus      Procedure = 0x32
+	ProcDumpLog        Procedure = 0x33
+	ProcEraseLog       Procedure = 0x34
+	ProcSelfTest       Procedure = 0x35
+	ProcAlarmFlagReset Procedure = 0x37
+)
+
+var procedureNames = map[Procedure]string{
+	ProcOTAR:            "OTAR",
+	ProcKeyActivation:   "Key Activation",
+	ProcKeyDeactivation: "Key Deactivation",
+	ProcKeyVerification: "Key Verification",
+	ProcKeyDestruction:  "Key Destruction",
+	ProcKeyInventory:    "Key Inventory",
+	ProcReadARSN:        "Read ARSN",
+	ProcCreateSA:        "Create SA",
+	ProcDeleteSA:        "Delete SA",
+	ProcSetARSNWindow:   "Set ARSN Window",
+	ProcRekeySA:         "Rekey SA",
+	ProcExpireSA:        "Expire SA",
+	ProcSetARSN:         "Set ARSN",
+	ProcStartSA:         "Start SA",
+	ProcStopSA:          "Stop SA",
+	ProcSAStatus:        "SA Status",
+	ProcPing:            "Ping",
+	ProcLogStatus:       "Log Status",
+	ProcDumpLog:         "Dump Log",
+	ProcEraseLog:        "Erase Log",
+	ProcSelfTest:        "Self-Test",
+	ProcAlarmFlagReset:  "Alarm Flag Reset",
+}
+
+// Group returns the procedure's service group.
+func (p Procedure) Group() ServiceGroup { return ServiceGroup(p>>4) & 0x3 }
+
+// ID returns the 4-bit procedure identifier within the service group.
+func (p Procedure) ID() uint8 { return uint8(p) & 0x0F }
+
+// String returns the procedure name.
+func (p Procedure) String() string {
+	if name, ok := procedureNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Procedure 0x%02X", uint8(p))
+}
+
+// PDU is an SDLS Extended Procedures command or reply.
+type PDU struct {
+	Reply     bool      // Type bit: false = command, true = reply
+	UserFlag  bool      // Mission-defined procedure
+	Procedure Procedure // Service group and procedure identifier
+	Data      []byte    // Procedure-specific data field
+}
+
+// NewCommand creates a command PDU.
+func NewCommand(proc Procedure, data []byte) *PDU {
+	return &PDU{Procedure: proc, Data: data}
+}
+
+// NewReply creates a reply PDU.
+func NewReply(proc Procedure, data []byte) *PDU {
+	return &PDU{Reply: true, Procedure: proc, Data: data}
+}
+
+// Encode serializes the PDU.
+func (p *PDU) Encode() ([]byte, error) {
+	if len(p.Data) > MaxPDUDataLength {
+		return nil, ErrPDUTooLarge
+	}
+	tag := uint8(p.Procedure) & 0x3F
+	if p.Reply {
+		tag |= 0x80
+	}
+	if p.UserFlag {
+		tag |= 0x40
+	}
+	out := make([]byte, PDUHeaderSize, PDUHeaderSize+len(p.Data))
+	out[0] = tag
+	binary.BigEndian.PutUint16(out[1:], uint16(len(p.Data)*8))
+	return append(out, p.Data...), nil
+}
+
+// Decode parses a PDU from data. Bytes beyond the length field are
+// ignored.
+func (p *PDU) Decode(data []byte) error {
+	if len(data) < PDUHeaderSize {
+		return ErrInvalidPDU
+	}
+	bits := binary.BigEndian.Uint16(data[1:])
+	if bits%8 != 0 {
+		return ErrInvalidPDU
+	}
+	n := int(bits / 8)
+	if len(data) < PDUHeaderSize+n {
+		return ErrInvalidPDU
+	}
+	p.Reply = data[0]&0x80 != 0
+	p.UserFlag = data[0]&0x40 != 0
+	p.Procedure = Procedure(data[0] & 0x3F)
+	p.Data = append([]byte(nil), data[PDUHeaderSize:PDUHeaderSize+n]...)
+	return nil
+}
+
+// DecodePDU parses a PDU from data.
+func DecodePDU(data []byte) (*PDU, error) {
+	p := &PDU{}
+	if err := p.Decode(data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Humanize returns a human-readable representation of the PDU.
+func (p *PDU) Humanize() string {
+	kind := "Command"
+	if p.Reply {
+		kind = "Reply"
+	}
+	user := ""
+	if p.UserFlag {
+		user = " (user)"
+	}
+	return fmt.Sprintf("SDLS EP %s%s: %s [%s], Data: %d bytes",
+		kind, user, p.Procedure, p.Procedure.Group(), len(p.Data))
+}
+
+// NewEPPacket carries pdu as the user data of a Space Packet on apid:
+// commands in TC packets and replies in TM packets.
+func NewEPPacket(apid uint16, pdu *PDU, opts ...spp.PacketOption) (*spp.SpacePacket, error) {
+	data, err := pdu.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if pdu.Reply {
+		return spp.NewTMPacket(apid, data, opts...)
+	}
+	return spp.NewTCPacket(apid, data, opts...)
+}
+
+// DecodeEPPacket extracts the EP PDU from a Space Packet's user data.
+func DecodeEPPacket(pkt *spp.SpacePacket) (*PDU, error) {
+	return DecodePDU(pkt.UserData)
+}
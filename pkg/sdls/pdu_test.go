@@ -0,0 +1,75 @@
+package sdls_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+	"github.com/ravisuhag/astro/pkg/spp"
+)
+
+func TestPDU_EncodeDecode(t *testing.T) {
+	p := &sdls.PDU{Reply: true, Procedure: sdls.ProcSAStatus, Data: []byte{0x00, 0x05, 0x03}}
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Type=1, User=0, SG=01, PID=1111; length 24 bits.
+	if want := []byte{0x9F, 0x00, 0x18, 0x00, 0x05, 0x03}; !bytes.Equal(b, want) {
+		t.Errorf("encoded % X, want % X", b, want)
+	}
+	got, err := sdls.DecodePDU(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Reply || got.UserFlag || got.Procedure != sdls.ProcSAStatus || !bytes.Equal(got.Data, p.Data) {
+		t.Errorf("decoded %+v", got)
+	}
+	if got.Procedure.Group() != sdls.GroupSAManagement || got.Procedure.ID() != 0x0F {
+		t.Errorf("group %v id %d", got.Procedure.Group(), got.Procedure.ID())
+	}
+}
+
+func TestDecodePDU_Invalid(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x01, 0x00},             // short header
+		{0x01, 0x00, 0x04},       // length not whole bytes
+		{0x01, 0x00, 0x10, 0xAA}, // truncated data field
+	} {
+		if _, err := sdls.DecodePDU(data); !errors.Is(err, sdls.ErrInvalidPDU) {
+			t.Errorf("% X: expected ErrInvalidPDU, got %v", data, err)
+		}
+	}
+	big := sdls.NewCommand(sdls.ProcOTAR, make([]byte, sdls.MaxPDUDataLength+1))
+	if _, err := big.Encode(); !errors.Is(err, sdls.ErrPDUTooLarge) {
+		t.Errorf("expected ErrPDUTooLarge, got %v", err)
+	}
+}
+
+func TestEPPacket(t *testing.T) {
+	pkt, err := sdls.NewEPPacket(100, sdls.NewPingCommand())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.PrimaryHeader.Type != spp.PacketTypeTC {
+		t.Errorf("command packet type = %d", pkt.PrimaryHeader.Type)
+	}
+	encoded, _ := pkt.Encode()
+	rx, err := spp.Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdu, err := sdls.DecodeEPPacket(rx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdu.Procedure != sdls.ProcPing || pdu.Reply {
+		t.Errorf("decoded %s", pdu.Humanize())
+	}
+
+	reply, _ := sdls.NewEPPacket(100, sdls.NewReply(sdls.ProcPing, nil))
+	if reply.PrimaryHeader.Type != spp.PacketTypeTM {
+		t.Errorf("reply packet type = %d", reply.PrimaryHeader.Type)
+	}
+}
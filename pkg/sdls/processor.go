@@ -0,0 +1,431 @@
+package sdls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+)
+
+// EPOption configures an EPProcessor.
+type EPOption func(*EPProcessor)
+
+// WithSecurityLog sets the security log. Defaults to a log of
+// DefaultLogCapacity entries.
+func WithSecurityLog(l *SecurityLog) EPOption {
+	return func(p *EPProcessor) {
+		p.log = l
+	}
+}
+
+// EPProcessor is the on-board side of the SDLS Extended Procedures per
+// CCSDS 355.1-B-1. It executes key management, SA management and
+// security monitoring commands against a key store and an SA store and
+// records the outcome in the security log.
+//
+// Keys move Pre-Active → Active → Deactivated → Destroyed. An SA uses
+// only Active keys. SAs move Unkeyed → Keyed → Operational and back;
+// see SecurityAssociation.
+type EPProcessor struct {
+	mu   sync.Mutex
+	keys KeyStore
+	sas  *SAStore
+	log  *SecurityLog
+}
+
+// NewEPProcessor creates a processor acting on keys and sas. Master keys
+// used by OTAR must already be Active in keys.
+func NewEPProcessor(keys KeyStore, sas *SAStore, opts ...EPOption) *EPProcessor {
+	p := &EPProcessor{keys: keys, sas: sas}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.log == nil {
+		p.log = NewSecurityLog(DefaultLogCapacity)
+	}
+	return p
+}
+
+// Log returns the security log.
+func (p *EPProcessor) Log() *SecurityLog { return p.log }
+
+// Handle executes a command PDU. It returns the reply PDU for
+// procedures that have one and nil otherwise. A rejected command is
+// logged and changes no state.
+func (p *EPProcessor) Handle(cmd *PDU) (*PDU, error) {
+	if cmd.Reply || cmd.UserFlag {
+		p.log.Record(LogCommandRejected, uint16(cmd.Procedure))
+		return nil, ErrUnsupportedProcedure
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	reply, err := p.execute(cmd)
+	if err != nil {
+		p.log.Record(LogCommandRejected, uint16(cmd.Procedure))
+		return nil, err
+	}
+	return reply, nil
+}
+
+// HandlePacket executes the command carried by a TC Space Packet and
+// returns the reply as a TM Space Packet on the same APID, or nil when
+// the procedure has no reply.
+func (p *EPProcessor) HandlePacket(pkt *spp.SpacePacket) (*spp.SpacePacket, error) {
+	cmd, err := DecodeEPPacket(pkt)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := p.Handle(cmd)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	return NewEPPacket(pkt.PrimaryHeader.APID, reply)
+}
+
+// ProcessTC runs SAStore.ProcessTC and logs frames rejected for
+// authentication, replay or an unknown SPI.
+func (p *EPProcessor) ProcessTC(frame *tcdl.TCTransferFrame) (*SecurityAssociation, error) {
+	sa, err := p.sas.ProcessTC(frame)
+	if err != nil {
+		var spi uint16
+		if len(frame.DataField) >= SPISize {
+			spi = binary.BigEndian.Uint16(frame.DataField)
+		}
+		switch {
+		case errors.Is(err, ErrAuthenticationFailed):
+			p.log.Record(LogAuthFailure, spi)
+		case errors.Is(err, ErrReplay):
+			p.log.Record(LogReplay, spi)
+		case errors.Is(err, ErrUnknownSPI):
+			p.log.Record(LogUnknownSPI, spi)
+		}
+	}
+	return sa, err
+}
+
+// execute dispatches cmd. The caller holds p.mu.
+func (p *EPProcessor) execute(cmd *PDU) (*PDU, error) {
+	d := cmd.Data
+	switch cmd.Procedure {
+	case ProcOTAR:
+		return nil, p.otar(d)
+	case ProcKeyActivation:
+		return nil, p.keyTransition(d, KeyPreActive, KeyActive, LogKeyActivated)
+	case ProcKeyDeactivation:
+		return nil, p.keyTransition(d, KeyActive, KeyDeactivated, LogKeyDeactivated)
+	case ProcKeyDestruction:
+		return nil, p.keyTransition(d, KeyDeactivated, KeyDestroyed, LogKeyDestroyed)
+	case ProcKeyVerification:
+		return p.keyVerification(d)
+	case ProcKeyInventory:
+		return p.keyInventory(d)
+	case ProcCreateSA:
+		return nil, p.createSA(d)
+	case ProcRekeySA:
+		return nil, p.rekeySA(d)
+	case ProcStartSA:
+		return nil, p.withSA(d, 2, -1, LogSAStarted, func(sa *SecurityAssociation, arg []byte) error {
+			return sa.Start(arg...)
+		})
+	case ProcStopSA:
+		return nil, p.withSA(d, 2, 2, LogSAStopped, func(sa *SecurityAssociation, _ []byte) error {
+			return sa.Stop()
+		})
+	case ProcExpireSA:
+		return nil, p.withSA(d, 2, 2, LogSAExpired, func(sa *SecurityAssociation, _ []byte) error {
+			return sa.Expire()
+		})
+	case ProcDeleteSA:
+		return nil, p.withSA(d, 2, 2, LogSADeleted, func(sa *SecurityAssociation, _ []byte) error {
+			if sa.State() != SAUnkeyed {
+				return ErrSAState
+			}
+			p.sas.Remove(sa.SPI())
+			return nil
+		})
+	case ProcSetARSN:
+		return nil, p.withSA(d, 10, 10, LogARSNSet, func(sa *SecurityAssociation, arg []byte) error {
+			sa.SetARSN(binary.BigEndian.Uint64(arg))
+			return nil
+		})
+	case ProcSetARSNWindow:
+		return nil, p.withSA(d, 6, 6, LogARSNWindowSet, func(sa *SecurityAssociation, arg []byte) error {
+			sa.SetReplayWindow(uint64(binary.BigEndian.Uint32(arg)))
+			return nil
+		})
+	case ProcReadARSN:
+		sa, err := p.sa(d)
+		if err != nil {
+			return nil, err
+		}
+		data := binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint16(nil, sa.SPI()), sa.ARSN())
+		return NewReply(cmd.Procedure, append(data, sa.IV()...)), nil
+	case ProcSAStatus:
+		sa, err := p.sa(d)
+		if err != nil {
+			return nil, err
+		}
+		return NewReply(cmd.Procedure, append(binary.BigEndian.AppendUint16(nil, sa.SPI()), uint8(sa.State()))), nil
+	case ProcPing:
+		return NewReply(cmd.Procedure, nil), nil
+	case ProcLogStatus:
+		return NewReply(cmd.Procedure, encodeLogStatus(p.log.Status())), nil
+	case ProcDumpLog:
+		var data []byte
+		for _, e := range p.log.Entries() {
+			data = append(data, uint8(e.Event))
+			data = binary.BigEndian.AppendUint16(data, e.Ref)
+			data = binary.BigEndian.AppendUint32(data, uint32(e.Time.Unix()))
+		}
+		if len(data) > MaxPDUDataLength {
+			data = data[len(data)-MaxPDUDataLength/logEntrySize*logEntrySize:]
+		}
+		return NewReply(cmd.Procedure, data), nil
+	case ProcEraseLog:
+		p.log.Erase()
+		return NewReply(cmd.Procedure, encodeLogStatus(p.log.Status())), nil
+	case ProcSelfTest:
+		result := uint8(0)
+		if !selfTest() {
+			result = 1
+			p.log.Record(LogSelfTestFailed, 0)
+		}
+		return NewReply(cmd.Procedure, []byte{result}), nil
+	case ProcAlarmFlagReset:
+		p.log.ResetAlarm()
+		return nil, nil
+	default:
+		return nil, ErrUnsupportedProcedure
+	}
+}
+
+// otar stores the keys of an OTAR command as Pre-Active. No key is
+// stored unless all are new.
+func (p *EPProcessor) otar(d []byte) error {
+	if len(d) < 2+GCMIVSize+otarTagSize {
+		return ErrInvalidPDU
+	}
+	mkid := binary.BigEndian.Uint16(d)
+	mk, err := p.keys.Get(mkid)
+	if err != nil {
+		return err
+	}
+	if mk.State != KeyActive {
+		return ErrKeyState
+	}
+	keys, err := openOTAR(d, mk.Value)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if _, err := p.keys.Get(k.ID); err == nil {
+			return ErrKeyExists
+		} else if !errors.Is(err, ErrUnknownKey) {
+			return err
+		}
+	}
+	for _, k := range keys {
+		if err := p.keys.Put(k); err != nil {
+			return err
+		}
+	}
+	p.log.Record(LogKeysUploaded, mkid)
+	return nil
+}
+
+// keyTransition moves every listed key from state from to state to. No
+// key changes unless all are in state from.
+func (p *EPProcessor) keyTransition(d []byte, from, to KeyState, event LogEvent) error {
+	ids, err := parseKeyIDs(d)
+	if err != nil {
+		return err
+	}
+	keys := make([]Key, len(ids))
+	for i, id := range ids {
+		k, err := p.keys.Get(id)
+		if err != nil {
+			return err
+		}
+		if k.State != from {
+			return ErrKeyState
+		}
+		keys[i] = k
+	}
+	for _, k := range keys {
+		k.State = to
+		if to == KeyDestroyed {
+			clear(k.Value)
+			k.Value = nil
+		}
+		if err := p.keys.Put(k); err != nil {
+			return err
+		}
+		p.log.Record(event, k.ID)
+	}
+	return nil
+}
+
+// keyVerification encrypts each challenge under the named key with a
+// fresh random IV.
+func (p *EPProcessor) keyVerification(d []byte) (*PDU, error) {
+	const entry = 2 + ChallengeLength
+	if len(d) == 0 || len(d)%entry != 0 {
+		return nil, ErrInvalidPDU
+	}
+	var out []byte
+	for ; len(d) > 0; d = d[entry:] {
+		k, err := p.keys.Get(binary.BigEndian.Uint16(d))
+		if err != nil {
+			return nil, err
+		}
+		if k.State != KeyActive && k.State != KeyPreActive {
+			return nil, ErrKeyState
+		}
+		aead, err := newGCM(k.Value)
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, GCMIVSize)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+		out = append(out, d[:2]...)
+		out = append(out, iv...)
+		out = aead.Seal(out, iv, d[2:entry], d[:2])
+	}
+	if len(out) > MaxPDUDataLength {
+		return nil, ErrPDUTooLarge
+	}
+	return NewReply(ProcKeyVerification, out), nil
+}
+
+// keyInventory lists the keys with IDs in [first, last].
+func (p *EPProcessor) keyInventory(d []byte) (*PDU, error) {
+	if len(d) != 4 {
+		return nil, ErrInvalidPDU
+	}
+	first, last := binary.BigEndian.Uint16(d), binary.BigEndian.Uint16(d[2:])
+	ids, err := p.keys.IDs()
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	n := 0
+	for _, id := range ids {
+		if id < first || id > last || 2+len(body)+3 > MaxPDUDataLength {
+			continue
+		}
+		k, err := p.keys.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		body = append(binary.BigEndian.AppendUint16(body, id), uint8(k.State))
+		n++
+	}
+	return NewReply(ProcKeyInventory, append(binary.BigEndian.AppendUint16(nil, uint16(n)), body...)), nil
+}
+
+// createSA adds an Unkeyed SA.
+func (p *EPProcessor) createSA(d []byte) error {
+	spi, params, err := parseCreateSA(d)
+	if err != nil {
+		return err
+	}
+	sa, err := CreateSA(spi, params)
+	if err != nil {
+		return err
+	}
+	if err := p.sas.Add(sa); err != nil {
+		return err
+	}
+	p.log.Record(LogSACreated, spi)
+	return nil
+}
+
+// rekeySA binds an Active key to an Unkeyed SA.
+func (p *EPProcessor) rekeySA(d []byte) error {
+	if len(d) < 4 {
+		return ErrInvalidPDU
+	}
+	sa, err := p.sa(d[:2])
+	if err != nil {
+		return err
+	}
+	keyID := binary.BigEndian.Uint16(d[2:])
+	k, err := p.keys.Get(keyID)
+	if err != nil {
+		return err
+	}
+	if k.State != KeyActive {
+		return ErrKeyState
+	}
+	var iv []byte
+	if len(d) > 4 {
+		iv = d[4:]
+	}
+	if err := sa.Rekey(keyID, k.Value, iv); err != nil {
+		return err
+	}
+	p.log.Record(LogSARekeyed, sa.SPI())
+	return nil
+}
+
+// withSA runs fn on the SA named by the leading SPI of d. d must be
+// between lo and hi bytes long; a negative hi allows any length.
+func (p *EPProcessor) withSA(d []byte, lo, hi int, event LogEvent, fn func(*SecurityAssociation, []byte) error) error {
+	if len(d) < lo || (hi >= 0 && len(d) > hi) {
+		return ErrInvalidPDU
+	}
+	sa, err := p.sa(d[:2])
+	if err != nil {
+		return err
+	}
+	if err := fn(sa, d[2:]); err != nil {
+		return err
+	}
+	p.log.Record(event, sa.SPI())
+	return nil
+}
+
+// sa returns the SA named by a 2-byte SPI field.
+func (p *EPProcessor) sa(d []byte) (*SecurityAssociation, error) {
+	if len(d) != 2 {
+		return nil, ErrInvalidPDU
+	}
+	sa, ok := p.sas.Get(binary.BigEndian.Uint16(d))
+	if !ok {
+		return nil, ErrUnknownSPI
+	}
+	return sa, nil
+}
+
+func encodeLogStatus(s LogStatus) []byte {
+	data := []byte{0}
+	if s.Alarm {
+		data[0] = 1
+	}
+	data = binary.BigEndian.AppendUint16(data, uint16(s.Entries))
+	return binary.BigEndian.AppendUint16(data, uint16(s.Remaining))
+}
+
+// selfTest runs the RFC 4493 AES-CMAC known-answer test.
+func selfTest() bool {
+	key := []byte{
+		0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6,
+		0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c,
+	}
+	msg := []byte{
+		0x6b, 0xc1, 0xbe, 0xe2, 0x2e, 0x40, 0x9f, 0x96,
+		0xe9, 0x3d, 0x7e, 0x11, 0x73, 0x93, 0x17, 0x2a,
+	}
+	want := []byte{
+		0x07, 0x0a, 0x16, 0xb4, 0x6b, 0x4d, 0x41, 0x44,
+		0xf7, 0x9b, 0xdd, 0x9d, 0xd0, 0x4a, 0x28, 0x7c,
+	}
+	mac, err := CMAC(key, msg)
+	return err == nil && bytes.Equal(mac, want)
+}
@@ -0,0 +1,241 @@
+package sdls_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+	"github.com/ravisuhag/astro/pkg/spp"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+)
+
+var masterKey = []byte("master-key-0123456789abcdef01234")
+
+// newProcessor returns an on-board processor holding master key 1.
+func newProcessor(t *testing.T) (*sdls.EPProcessor, *sdls.SAStore) {
+	t.Helper()
+	keys := sdls.NewMemoryKeyStore()
+	if err := keys.Put(sdls.Key{ID: 1, State: sdls.KeyActive, Value: masterKey}); err != nil {
+		t.Fatal(err)
+	}
+	sas := sdls.NewSAStore()
+	return sdls.NewEPProcessor(keys, sas), sas
+}
+
+// mustHandle executes cmd and fails the test on error.
+func mustHandle(t *testing.T, p *sdls.EPProcessor, cmd *sdls.PDU) *sdls.PDU {
+	t.Helper()
+	reply, err := p.Handle(cmd)
+	if err != nil {
+		t.Fatalf("%s: %v", cmd.Procedure, err)
+	}
+	return reply
+}
+
+func TestEPProcessor_KeyManagement(t *testing.T) {
+	p, _ := newProcessor(t)
+	sessionKey := []byte("session-key-0123456789abcdef0123")
+	iv := make([]byte, sdls.GCMIVSize)
+
+	otar, err := sdls.NewOTARCommand(1, masterKey, iv,
+		sdls.Key{ID: 10, Value: sessionKey}, sdls.Key{ID: 11, Value: testKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustHandle(t, p, otar)
+	if _, err := p.Handle(otar); !errors.Is(err, sdls.ErrKeyExists) {
+		t.Errorf("repeated OTAR: expected ErrKeyExists, got %v", err)
+	}
+	forged, _ := sdls.NewOTARCommand(1, testKey, iv, sdls.Key{ID: 12, Value: testKey})
+	if _, err := p.Handle(forged); !errors.Is(err, sdls.ErrAuthenticationFailed) {
+		t.Errorf("forged OTAR: expected ErrAuthenticationFailed, got %v", err)
+	}
+
+	mustHandle(t, p, sdls.NewKeyActivationCommand(10))
+	if _, err := p.Handle(sdls.NewKeyActivationCommand(10, 11)); !errors.Is(err, sdls.ErrKeyState) {
+		t.Errorf("expected ErrKeyState, got %v", err)
+	}
+
+	inv, err := sdls.DecodeKeyInventoryReply(mustHandle(t, p, sdls.NewKeyInventoryCommand(10, 0xFFFF)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sdls.KeyInventoryEntry{{KeyID: 10, State: sdls.KeyActive}, {KeyID: 11, State: sdls.KeyPreActive}}
+	if len(inv) != 2 || inv[0] != want[0] || inv[1] != want[1] {
+		t.Errorf("inventory = %v, want %v", inv, want)
+	}
+
+	challenge := sdls.KeyChallenge{KeyID: 10, Challenge: [16]byte{1, 2, 3}}
+	ver, err := sdls.DecodeKeyVerificationReply(mustHandle(t, p, sdls.NewKeyVerificationCommand(challenge)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ver) != 1 || !ver[0].Check(sessionKey, challenge.Challenge) {
+		t.Error("key verification failed with the uploaded key")
+	}
+	if ver[0].Check(testKey, challenge.Challenge) {
+		t.Error("key verification passed with the wrong key")
+	}
+
+	mustHandle(t, p, sdls.NewKeyDeactivationCommand(10))
+	mustHandle(t, p, sdls.NewKeyDestructionCommand(10))
+	inv, _ = sdls.DecodeKeyInventoryReply(mustHandle(t, p, sdls.NewKeyInventoryCommand(10, 10)))
+	if len(inv) != 1 || inv[0].State != sdls.KeyDestroyed {
+		t.Errorf("inventory after destruction = %v", inv)
+	}
+}
+
+func TestEPProcessor_SALifecycle(t *testing.T) {
+	p, sas := newProcessor(t)
+	iv := make([]byte, sdls.GCMIVSize)
+	otar, _ := sdls.NewOTARCommand(1, masterKey, iv, sdls.Key{ID: 20, Value: testKey})
+	mustHandle(t, p, otar)
+
+	params := sdls.SAParams{
+		Service:   sdls.ServiceAuthenticatedEncryption,
+		Algorithm: sdls.AlgorithmAESGCM,
+		IVLength:  sdls.GCMIVSize,
+		MACLength: 16,
+		Window:    4,
+	}
+	mustHandle(t, p, sdls.NewCreateSACommand(5, params))
+	status := func() sdls.SAState {
+		_, st, err := sdls.DecodeSAStatusReply(mustHandle(t, p, sdls.NewSAStatusCommand(5)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	if st := status(); st != sdls.SAUnkeyed {
+		t.Errorf("state after create = %v", st)
+	}
+
+	// The key is still Pre-Active.
+	if _, err := p.Handle(sdls.NewRekeySACommand(5, 20, nil)); !errors.Is(err, sdls.ErrKeyState) {
+		t.Errorf("expected ErrKeyState, got %v", err)
+	}
+	mustHandle(t, p, sdls.NewKeyActivationCommand(20))
+	mustHandle(t, p, sdls.NewRekeySACommand(5, 20, nil))
+	if st := status(); st != sdls.SAKeyed {
+		t.Errorf("state after rekey = %v", st)
+	}
+
+	// Ground mirrors the SA.
+	ground, err := sdls.CreateSA(5, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ground.Rekey(20, testKey, nil)
+	_ = ground.Start(1)
+
+	frame, _ := tcdl.NewTCTransferFrame(42, 1, []byte("cmd"))
+	_ = ground.ApplyTC(frame)
+	if _, err := p.ProcessTC(frame); !errors.Is(err, sdls.ErrSANotOperational) {
+		t.Errorf("expected ErrSANotOperational, got %v", err)
+	}
+
+	mustHandle(t, p, sdls.NewStartSACommand(5, 1))
+	if sa, _ := sas.Get(5); !sa.Bound(1) || sa.Bound(2) {
+		t.Error("start did not bind the SA to VC 1")
+	}
+	frame, _ = tcdl.NewTCTransferFrame(42, 1, []byte("cmd"))
+	_ = ground.ApplyTC(frame)
+	replay, _ := tcdl.DecodeTCTransferFrame(mustEncodeTC(t, frame))
+	if _, err := p.ProcessTC(frame); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frame.DataField, []byte("cmd")) {
+		t.Errorf("data = %q", frame.DataField)
+	}
+	if _, err := p.ProcessTC(replay); !errors.Is(err, sdls.ErrReplay) {
+		t.Errorf("expected ErrReplay, got %v", err)
+	}
+
+	rep, err := sdls.DecodeReadARSNReply(mustHandle(t, p, sdls.NewReadARSNCommand(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rep.IV, ground.IV()) {
+		t.Errorf("on-board IV % X, ground % X", rep.IV, ground.IV())
+	}
+	mustHandle(t, p, sdls.NewSetARSNCommand(5, 7))
+	mustHandle(t, p, sdls.NewSetARSNWindowCommand(5, 32))
+	if sa, _ := sas.Get(5); sa.ARSN() != 7 || sa.ReplayWindow() != 32 {
+		t.Errorf("ARSN %d window %d", sa.ARSN(), sa.ReplayWindow())
+	}
+
+	if _, err := p.Handle(sdls.NewDeleteSACommand(5)); !errors.Is(err, sdls.ErrSAState) {
+		t.Errorf("delete while operational: expected ErrSAState, got %v", err)
+	}
+	mustHandle(t, p, sdls.NewStopSACommand(5))
+	mustHandle(t, p, sdls.NewExpireSACommand(5))
+	if st := status(); st != sdls.SAUnkeyed {
+		t.Errorf("state after expire = %v", st)
+	}
+	mustHandle(t, p, sdls.NewDeleteSACommand(5))
+	if _, err := p.Handle(sdls.NewSAStatusCommand(5)); !errors.Is(err, sdls.ErrUnknownSPI) {
+		t.Errorf("expected ErrUnknownSPI, got %v", err)
+	}
+}
+
+func TestEPProcessor_Monitoring(t *testing.T) {
+	p, _ := newProcessor(t)
+
+	// Commands and replies travel in Space Packets.
+	pkt, _ := sdls.NewEPPacket(0x7F, sdls.NewPingCommand())
+	reply, err := p.HandlePacket(pkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.PrimaryHeader.APID != 0x7F || reply.PrimaryHeader.Type != spp.PacketTypeTM {
+		t.Errorf("reply header = %+v", reply.PrimaryHeader)
+	}
+	if pdu, _ := sdls.DecodeEPPacket(reply); !pdu.Reply || pdu.Procedure != sdls.ProcPing {
+		t.Errorf("reply = %s", pdu.Humanize())
+	}
+
+	ok, err := sdls.DecodeSelfTestReply(mustHandle(t, p, sdls.NewSelfTestCommand()))
+	if err != nil || !ok {
+		t.Errorf("self-test = %v, %v", ok, err)
+	}
+
+	// A rejected command and a frame for an unknown SPI are logged.
+	_, _ = p.Handle(sdls.NewKeyActivationCommand(99))
+	frame, _ := tcdl.NewTCTransferFrame(42, 1, []byte{0x00, 0x09, 0xAA})
+	_, _ = p.ProcessTC(frame)
+
+	st, err := sdls.DecodeLogStatusReply(mustHandle(t, p, sdls.NewLogStatusCommand()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Alarm || st.Entries != 2 {
+		t.Errorf("log status = %+v", st)
+	}
+	entries, err := sdls.DecodeDumpLogReply(mustHandle(t, p, sdls.NewDumpLogCommand()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Event != sdls.LogCommandRejected ||
+		entries[1].Event != sdls.LogUnknownSPI || entries[1].Ref != 9 {
+		t.Errorf("log = %v", entries)
+	}
+
+	st, _ = sdls.DecodeLogStatusReply(mustHandle(t, p, sdls.NewEraseLogCommand()))
+	if st.Entries != 0 || !st.Alarm {
+		t.Errorf("status after erase = %+v", st)
+	}
+	if r := mustHandle(t, p, sdls.NewAlarmFlagResetCommand()); r != nil {
+		t.Errorf("alarm flag reset replied %s", r.Humanize())
+	}
+	if p.Log().Status().Alarm {
+		t.Error("alarm not reset")
+	}
+
+	if _, err := p.Handle(sdls.NewCommand(0x3F, nil)); !errors.Is(err, sdls.ErrUnsupportedProcedure) {
+		t.Errorf("expected ErrUnsupportedProcedure, got %v", err)
+	}
+	if _, err := sdls.DecodeSelfTestReply(sdls.NewPingCommand()); !errors.Is(err, sdls.ErrProcedureMismatch) {
+		t.Errorf("expected ErrProcedureMismatch, got %v", err)
+	}
+}
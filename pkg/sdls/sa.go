@@ -0,0 +1,586 @@
+package sdls
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"slices"
+	"sync"
+)
+
+// SAState is the life-cycle state of a Security Association per CCSDS
+// 355.1-B-1 Section 3.
+type SAState uint8
+
+const (
+	SAUnkeyed     SAState = iota + 1 // created, no key bound
+	SAKeyed                          // key bound, not in use
+	SAOperational                    // applying and processing security
+)
+
+// String returns the state name.
+func (s SAState) String() string {
+	switch s {
+	case SAUnkeyed:
+		return "Unkeyed"
+	case SAKeyed:
+		return "Keyed"
+	case SAOperational:
+		return "Operational"
+	default:
+		return "Unknown"
+	}
+}
+
+// SAOption configures a SecurityAssociation.
+type SAOption func(*SecurityAssociation)
+
+// WithIV sets the initial IV and the IV length carried in the Security
+// Header. AES-GCM SAs require a 12-byte IV; others default to none.
+func WithIV(iv []byte) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.iv = slices.Clone(iv)
+	}
+}
+
+// WithSequenceNumberLength sets the length of the Sequence Number field
+// carrying the anti-replay sequence number (ARSN), 0-8 bytes.
+func WithSequenceNumberLength(n int) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.snLen = n
+	}
+}
+
+// WithARSN sets the initial anti-replay sequence number.
+func WithARSN(arsn uint64) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.arsn = arsn
+	}
+}
+
+// WithPadLengthField sets the length of the Pad Length field, 0-2 bytes.
+// The supported algorithms never pad, so the field is sent as zero.
+func WithPadLengthField(n int) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.padLen = n
+	}
+}
+
+// WithMACLength sets the length of the MAC in the Security Trailer. The
+// MAC is truncated to its leftmost n bytes. AES-GCM authenticated
+// encryption accepts 12-16.
+func WithMACLength(n int) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.macLen = n
+	}
+}
+
+// WithReplayWindow sets the anti-replay window: a received sequence
+// number is accepted when it is ahead of the last accepted one by at
+// most w. Zero accepts any newer sequence number.
+func WithReplayWindow(w uint64) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.window = w
+	}
+}
+
+// WithAuthMask sets the authentication bit mask. It is ANDed with the
+// leading bytes of the authenticated data so fields that change in
+// transit, such as the TM Master Channel Frame Count, are excluded.
+func WithAuthMask(mask []byte) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.authMask = slices.Clone(mask)
+	}
+}
+
+// WithKeyID records the key store ID of the SA's key.
+func WithKeyID(id uint16) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.keyID = id
+	}
+}
+
+// WithVCIDs binds the SA to the given virtual channels. An unbound SA
+// protects any virtual channel.
+func WithVCIDs(vcids ...uint8) SAOption {
+	return func(sa *SecurityAssociation) {
+		sa.vcids = slices.Clone(vcids)
+	}
+}
+
+// SecurityAssociation is one SDLS Security Association per CCSDS
+// 355.0-B-2 Section 3: the managed parameters selected by an SPI and
+// the IV and anti-replay sequence number state kept for it.
+//
+// The sender advances the IV and sequence number before each frame it
+// protects. The receiver accepts a frame only when its MAC verifies and
+// its sequence number is ahead of the last accepted one within the
+// replay window. The sequence number comes from the Sequence Number
+// field, or from the IV when the SA carries no Sequence Number field;
+// IVs longer than eight bytes count in their trailing eight bytes and
+// treat the leading bytes as a fixed field.
+//
+// Ground and spacecraft each hold their own SecurityAssociation with
+// the same parameters and key.
+//
+// An SA only applies and processes security while Operational. SAs made
+// with NewSecurityAssociation start Operational; the Extended Procedures
+// move SAs through Unkeyed, Keyed and Operational with Rekey, Start,
+// Stop and Expire.
+type SecurityAssociation struct {
+	mu        sync.Mutex
+	spi       uint16
+	state     SAState
+	keyID     uint16
+	service   ServiceType
+	algorithm Algorithm
+	key       []byte
+	iv        []byte
+	snLen     int
+	padLen    int
+	macLen    int
+	arsn      uint64
+	window    uint64
+	authMask  []byte
+	vcids     []uint8
+}
+
+// NewSecurityAssociation creates an SA. The MAC defaults to 16 bytes.
+// AES-GCM SAs default to a zero 12-byte IV; authentication-only SAs
+// with other algorithms default to a 4-byte Sequence Number field.
+func NewSecurityAssociation(spi uint16, service ServiceType, alg Algorithm, key []byte, opts ...SAOption) (*SecurityAssociation, error) {
+	sa := &SecurityAssociation{
+		spi:       spi,
+		service:   service,
+		algorithm: alg,
+		state:     SAOperational,
+		key:       slices.Clone(key),
+		macLen:    16,
+	}
+	if alg == AlgorithmAESGCM {
+		sa.iv = make([]byte, GCMIVSize)
+	} else {
+		sa.snLen = 4
+	}
+	for _, opt := range opts {
+		opt(sa)
+	}
+	if err := sa.validate(); err != nil {
+		return nil, err
+	}
+	return sa, nil
+}
+
+// validate checks the SA's managed parameters.
+func (sa *SecurityAssociation) validate() error {
+	maxMAC := sa.algorithm.maxMACLength()
+	if maxMAC == 0 {
+		return ErrUnsupportedAlgorithm
+	}
+	switch sa.service {
+	case ServiceAuthentication:
+	case ServiceAuthenticatedEncryption:
+		if sa.algorithm != AlgorithmAESGCM {
+			return ErrUnsupportedService
+		}
+	default:
+		return ErrUnsupportedService
+	}
+	if len(sa.key) > 0 {
+		if err := checkKey(sa.algorithm, sa.key); err != nil {
+			return err
+		}
+	}
+	if len(sa.iv) > MaxIVLength {
+		return ErrInvalidIVLength
+	}
+	if sa.algorithm == AlgorithmAESGCM && len(sa.iv) != GCMIVSize {
+		return ErrInvalidIVLength
+	}
+	if sa.snLen < 0 || sa.snLen > MaxSequenceLength {
+		return ErrInvalidSequenceLength
+	}
+	if sa.padLen < 0 || sa.padLen > MaxPadLength {
+		return ErrInvalidPadLength
+	}
+	if sa.macLen < 1 || sa.macLen > maxMAC {
+		return ErrInvalidMACLength
+	}
+	if sa.service == ServiceAuthenticatedEncryption && sa.macLen < 12 {
+		return ErrInvalidMACLength
+	}
+	return nil
+}
+
+// checkKey reports whether key suits alg.
+func checkKey(alg Algorithm, key []byte) error {
+	switch alg {
+	case AlgorithmAESGCM, AlgorithmAESCMAC:
+		switch len(key) {
+		case 16, 24, 32:
+			return nil
+		}
+		return ErrInvalidKeyLength
+	default:
+		if len(key) == 0 {
+			return ErrInvalidKeyLength
+		}
+		return nil
+	}
+}
+
+// SPI returns the Security Parameter Index.
+func (sa *SecurityAssociation) SPI() uint16 { return sa.spi }
+
+// Service returns the security service.
+func (sa *SecurityAssociation) Service() ServiceType { return sa.service }
+
+// Algorithm returns the cryptographic algorithm.
+func (sa *SecurityAssociation) Algorithm() Algorithm { return sa.algorithm }
+
+// HeaderLength returns the Security Header length in bytes.
+func (sa *SecurityAssociation) HeaderLength() int {
+	return SPISize + len(sa.iv) + sa.snLen + sa.padLen
+}
+
+// TrailerLength returns the Security Trailer (MAC) length in bytes.
+func (sa *SecurityAssociation) TrailerLength() int { return sa.macLen }
+
+// Overhead returns the bytes the SA adds to a frame data field. Size the
+// frame data field this much smaller on fixed-length channels.
+func (sa *SecurityAssociation) Overhead() int {
+	return sa.HeaderLength() + sa.TrailerLength()
+}
+
+// ARSN returns the anti-replay sequence number: the last one sent or
+// the last one accepted.
+func (sa *SecurityAssociation) ARSN() uint64 {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.arsn
+}
+
+// IV returns a copy of the last IV sent or accepted.
+func (sa *SecurityAssociation) IV() []byte {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return slices.Clone(sa.iv)
+}
+
+// SetKey replaces the SA's key material.
+func (sa *SecurityAssociation) SetKey(key []byte) error {
+	if err := checkKey(sa.algorithm, key); err != nil {
+		return err
+	}
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.key = slices.Clone(key)
+	return nil
+}
+
+// State returns the SA's life-cycle state.
+func (sa *SecurityAssociation) State() SAState {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.state
+}
+
+// KeyID returns the key store ID of the SA's key.
+func (sa *SecurityAssociation) KeyID() uint16 {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.keyID
+}
+
+// Rekey binds a new key to an Unkeyed SA and moves it to Keyed. The
+// IV restarts from iv (zero when nil) and the ARSN from zero.
+func (sa *SecurityAssociation) Rekey(keyID uint16, key, iv []byte) error {
+	if err := checkKey(sa.algorithm, key); err != nil {
+		return err
+	}
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if sa.state != SAUnkeyed {
+		return ErrSAState
+	}
+	if iv != nil && len(iv) != len(sa.iv) {
+		return ErrInvalidIVLength
+	}
+	clear(sa.iv)
+	copy(sa.iv, iv)
+	sa.keyID = keyID
+	sa.key = slices.Clone(key)
+	sa.arsn = 0
+	sa.state = SAKeyed
+	return nil
+}
+
+// Start moves a Keyed SA to Operational. When vcids are given they
+// replace the SA's virtual channel binding.
+func (sa *SecurityAssociation) Start(vcids ...uint8) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if sa.state != SAKeyed {
+		return ErrSAState
+	}
+	if len(vcids) > 0 {
+		sa.vcids = slices.Clone(vcids)
+	}
+	sa.state = SAOperational
+	return nil
+}
+
+// Stop moves an Operational SA back to Keyed.
+func (sa *SecurityAssociation) Stop() error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if sa.state != SAOperational {
+		return ErrSAState
+	}
+	sa.state = SAKeyed
+	return nil
+}
+
+// Expire erases the key of a Keyed SA and moves it to Unkeyed.
+func (sa *SecurityAssociation) Expire() error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if sa.state != SAKeyed {
+		return ErrSAState
+	}
+	clear(sa.key)
+	sa.key = nil
+	sa.state = SAUnkeyed
+	return nil
+}
+
+// SetARSN sets the anti-replay sequence number.
+func (sa *SecurityAssociation) SetARSN(arsn uint64) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.arsn = arsn
+}
+
+// SetReplayWindow sets the anti-replay window.
+func (sa *SecurityAssociation) SetReplayWindow(w uint64) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.window = w
+}
+
+// ReplayWindow returns the anti-replay window.
+func (sa *SecurityAssociation) ReplayWindow() uint64 {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.window
+}
+
+// Bound reports whether the SA may protect frames of vcid.
+func (sa *SecurityAssociation) Bound(vcid uint8) bool {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return len(sa.vcids) == 0 || slices.Contains(sa.vcids, vcid)
+}
+
+// DecodeHeader parses the Security Header at the start of a protected
+// data field using the SA's field lengths.
+func (sa *SecurityAssociation) DecodeHeader(data []byte) (*SecurityHeader, error) {
+	if len(data) < sa.HeaderLength() {
+		return nil, ErrDataTooShort
+	}
+	pos := SPISize
+	next := func(n int) []byte {
+		b := slices.Clone(data[pos : pos+n])
+		pos += n
+		return b
+	}
+	return &SecurityHeader{
+		SPI:            binary.BigEndian.Uint16(data),
+		IV:             next(len(sa.iv)),
+		SequenceNumber: next(sa.snLen),
+		PadLength:      next(sa.padLen),
+	}, nil
+}
+
+// protect applies the SA to data. prefix is the frame content ahead of
+// the Security Header that the MAC covers. It returns the protected
+// data field: Security Header, data or ciphertext, Security Trailer.
+func (sa *SecurityAssociation) protect(prefix, data []byte) ([]byte, error) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if err := sa.usable(); err != nil {
+		return nil, err
+	}
+
+	// Advance the counters before use so an IV is never reused, even
+	// if protection fails below.
+	hdr := SecurityHeader{SPI: sa.spi, PadLength: make([]byte, sa.padLen)}
+	if len(sa.iv) > 0 {
+		n := counterValue(sa.iv)
+		if n == counterMax(len(sa.iv)) {
+			return nil, ErrSequenceExhausted
+		}
+		putCounter(sa.iv, n+1)
+		hdr.IV = slices.Clone(sa.iv)
+	}
+	if sa.snLen > 0 {
+		if sa.arsn >= counterMax(sa.snLen) {
+			return nil, ErrSequenceExhausted
+		}
+		sa.arsn++
+		hdr.SequenceNumber = make([]byte, sa.snLen)
+		putCounter(hdr.SequenceNumber, sa.arsn)
+	}
+	header := hdr.Encode()
+
+	if sa.service == ServiceAuthenticatedEncryption {
+		aead, err := sa.aead(sa.macLen)
+		if err != nil {
+			return nil, err
+		}
+		aad := sa.mask(concat(prefix, header))
+		return aead.Seal(header, hdr.IV, data, aad), nil
+	}
+	mac, err := sa.mac(hdr.IV, concat(prefix, header, data))
+	if err != nil {
+		return nil, err
+	}
+	return concat(header, data, mac), nil
+}
+
+// verify checks a protected data field against the SA and returns the
+// recovered data. On success the SA's anti-replay state advances.
+func (sa *SecurityAssociation) verify(prefix, field []byte) ([]byte, error) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if err := sa.usable(); err != nil {
+		return nil, err
+	}
+	hl := sa.HeaderLength()
+	if len(field) < hl+sa.macLen {
+		return nil, ErrDataTooShort
+	}
+	hdr, err := sa.DecodeHeader(field)
+	if err != nil {
+		return nil, err
+	}
+	header := field[:hl]
+	body := field[hl : len(field)-sa.macLen]
+	tag := field[len(field)-sa.macLen:]
+
+	var data []byte
+	if sa.service == ServiceAuthenticatedEncryption {
+		aead, err := sa.aead(sa.macLen)
+		if err != nil {
+			return nil, err
+		}
+		data, err = aead.Open(nil, hdr.IV, field[hl:], sa.mask(concat(prefix, header)))
+		if err != nil {
+			return nil, ErrAuthenticationFailed
+		}
+	} else {
+		mac, err := sa.mac(hdr.IV, concat(prefix, header, body))
+		if err != nil {
+			return nil, err
+		}
+		if !hmac.Equal(mac, tag) {
+			return nil, ErrAuthenticationFailed
+		}
+		data = slices.Clone(body)
+	}
+
+	// Anti-replay check after authentication, so forged frames cannot
+	// move the window.
+	switch {
+	case sa.snLen > 0:
+		n := counterValue(hdr.SequenceNumber)
+		if !sa.inWindow(sa.arsn, n) {
+			return nil, ErrReplay
+		}
+		sa.arsn = n
+	case len(sa.iv) > 0:
+		fixed := len(sa.iv) - min(len(sa.iv), 8)
+		if !bytes.Equal(hdr.IV[:fixed], sa.iv[:fixed]) || !sa.inWindow(counterValue(sa.iv), counterValue(hdr.IV)) {
+			return nil, ErrReplay
+		}
+	}
+	if len(sa.iv) > 0 {
+		copy(sa.iv, hdr.IV)
+	}
+	return data, nil
+}
+
+// usable reports whether the SA can apply or process security. The
+// caller holds sa.mu.
+func (sa *SecurityAssociation) usable() error {
+	if sa.state != SAOperational {
+		return ErrSANotOperational
+	}
+	if len(sa.key) == 0 {
+		return ErrNoKey
+	}
+	return nil
+}
+
+// inWindow reports whether received is ahead of last within the window.
+func (sa *SecurityAssociation) inWindow(last, received uint64) bool {
+	return received > last && (sa.window == 0 || received-last <= sa.window)
+}
+
+// mask applies the authentication bit mask to b in place and returns it.
+func (sa *SecurityAssociation) mask(b []byte) []byte {
+	for i := 0; i < len(sa.authMask) && i < len(b); i++ {
+		b[i] &= sa.authMask[i]
+	}
+	return b
+}
+
+// aead returns an AES-GCM instance with the given tag size.
+func (sa *SecurityAssociation) aead(tagSize int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(sa.key)
+	if err != nil {
+		return nil, ErrInvalidKeyLength
+	}
+	return cipher.NewGCMWithTagSize(block, tagSize)
+}
+
+// mac computes the truncated authentication-only MAC of the masked input.
+func (sa *SecurityAssociation) mac(iv, input []byte) ([]byte, error) {
+	input = sa.mask(input)
+	var full []byte
+	switch sa.algorithm {
+	case AlgorithmAESGCM:
+		aead, err := sa.aead(16)
+		if err != nil {
+			return nil, err
+		}
+		full = aead.Seal(nil, iv, nil, input)
+	case AlgorithmAESCMAC:
+		block, err := aes.NewCipher(sa.key)
+		if err != nil {
+			return nil, ErrInvalidKeyLength
+		}
+		full = cmac(block, input)
+	case AlgorithmHMACSHA256, AlgorithmHMACSHA512:
+		h := sha256.New
+		if sa.algorithm == AlgorithmHMACSHA512 {
+			h = sha512.New
+		}
+		full = hmacSum(h, sa.key, input)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return full[:sa.macLen], nil
+}
+
+// hmacSum returns the HMAC of input with key.
+func hmacSum(h func() hash.Hash, key, input []byte) []byte {
+	m := hmac.New(h, key)
+	m.Write(input)
+	return m.Sum(nil)
+}
@@ -0,0 +1,254 @@
+package sdls_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// saPair returns matching ground and spacecraft SAs and a store holding
+// the spacecraft one.
+func saPair(t *testing.T, spi uint16, svc sdls.ServiceType, alg sdls.Algorithm, opts ...sdls.SAOption) (*sdls.SecurityAssociation, *sdls.SAStore) {
+	t.Helper()
+	tx, err := sdls.NewSecurityAssociation(spi, svc, alg, testKey, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rx, err := sdls.NewSecurityAssociation(spi, svc, alg, testKey, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := sdls.NewSAStore()
+	if err := store.Add(rx); err != nil {
+		t.Fatal(err)
+	}
+	return tx, store
+}
+
+func TestNewSecurityAssociation_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  sdls.ServiceType
+		alg  sdls.Algorithm
+		key  []byte
+		opts []sdls.SAOption
+		want error
+	}{
+		{"encryption needs GCM", sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESCMAC, testKey, nil, sdls.ErrUnsupportedService},
+		{"unknown service", 0, sdls.AlgorithmAESGCM, testKey, nil, sdls.ErrUnsupportedService},
+		{"unknown algorithm", sdls.ServiceAuthentication, 9, testKey, nil, sdls.ErrUnsupportedAlgorithm},
+		{"AES key length", sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, testKey[:20], nil, sdls.ErrInvalidKeyLength},
+		{"GCM IV length", sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM, testKey, []sdls.SAOption{sdls.WithIV(make([]byte, 8))}, sdls.ErrInvalidIVLength},
+		{"GCM tag too short", sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM, testKey, []sdls.SAOption{sdls.WithMACLength(8)}, sdls.ErrInvalidMACLength},
+		{"MAC too long", sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA256, testKey, []sdls.SAOption{sdls.WithMACLength(33)}, sdls.ErrInvalidMACLength},
+		{"sequence too long", sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, testKey, []sdls.SAOption{sdls.WithSequenceNumberLength(9)}, sdls.ErrInvalidSequenceLength},
+		{"pad field too long", sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, testKey, []sdls.SAOption{sdls.WithPadLengthField(3)}, sdls.ErrInvalidPadLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sdls.NewSecurityAssociation(1, tt.svc, tt.alg, tt.key, tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityAssociation_Lengths(t *testing.T) {
+	sa, err := sdls.NewSecurityAssociation(7, sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA512, testKey,
+		sdls.WithSequenceNumberLength(2), sdls.WithPadLengthField(1), sdls.WithMACLength(24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sa.HeaderLength() != 5 || sa.TrailerLength() != 24 || sa.Overhead() != 29 {
+		t.Errorf("header=%d trailer=%d overhead=%d, want 5, 24, 29", sa.HeaderLength(), sa.TrailerLength(), sa.Overhead())
+	}
+
+	gcm, _ := sdls.NewSecurityAssociation(8, sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM, testKey)
+	if gcm.HeaderLength() != 14 || gcm.TrailerLength() != 16 {
+		t.Errorf("GCM header=%d trailer=%d, want 14 and 16", gcm.HeaderLength(), gcm.TrailerLength())
+	}
+}
+
+func TestSecurityAssociation_AllAlgorithms(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  sdls.ServiceType
+		alg  sdls.Algorithm
+	}{
+		{"AES-GCM", sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM},
+		{"GMAC", sdls.ServiceAuthentication, sdls.AlgorithmAESGCM},
+		{"AES-CMAC", sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC},
+		{"HMAC-SHA-256", sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA256},
+		{"HMAC-SHA-512", sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA512},
+	}
+	payload := []byte("SET HEATER 3 ON")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, store := saPair(t, 1, tt.svc, tt.alg)
+			frame, _ := tcdl.NewTCTransferFrame(42, 1, payload)
+			if err := tx.ApplyTC(frame); err != nil {
+				t.Fatal(err)
+			}
+			clear := tt.svc == sdls.ServiceAuthentication
+			if got := bytes.Contains(frame.DataField, payload); got != clear {
+				t.Errorf("payload visible = %v, want %v", got, clear)
+			}
+
+			encoded, _ := frame.Encode()
+			rx, err := tcdl.DecodeTCTransferFrame(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.ProcessTC(rx); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rx.DataField, payload) {
+				t.Errorf("data = %q, want %q", rx.DataField, payload)
+			}
+		})
+	}
+}
+
+func TestSecurityAssociation_AntiReplay(t *testing.T) {
+	tx, store := saPair(t, 3, sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, sdls.WithReplayWindow(2))
+
+	var frames [][]byte
+	for i := range 5 {
+		f, _ := tcdl.NewTCTransferFrame(42, 1, []byte{byte(i)})
+		if err := tx.ApplyTC(f); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := f.Encode()
+		frames = append(frames, b)
+	}
+	process := func(b []byte) error {
+		f, _ := tcdl.DecodeTCTransferFrame(b)
+		_, err := store.ProcessTC(f)
+		return err
+	}
+
+	if err := process(frames[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := process(frames[0]); !errors.Is(err, sdls.ErrReplay) {
+		t.Errorf("replayed frame: expected ErrReplay, got %v", err)
+	}
+	if err := process(frames[4]); !errors.Is(err, sdls.ErrReplay) {
+		t.Errorf("frame beyond window: expected ErrReplay, got %v", err)
+	}
+	if err := process(frames[2]); err != nil {
+		t.Errorf("frame within window rejected: %v", err)
+	}
+	if err := process(frames[1]); !errors.Is(err, sdls.ErrReplay) {
+		t.Errorf("older frame: expected ErrReplay, got %v", err)
+	}
+	rx, _ := store.Get(3)
+	if rx.ARSN() != 3 {
+		t.Errorf("ARSN = %d, want 3", rx.ARSN())
+	}
+}
+
+func TestSecurityAssociation_IVAntiReplay(t *testing.T) {
+	iv := []byte{0xA0, 0xA1, 0xA2, 0xA3, 0, 0, 0, 0, 0, 0, 0, 0}
+	tx, store := saPair(t, 4, sdls.ServiceAuthenticatedEncryption, sdls.AlgorithmAESGCM, sdls.WithIV(iv))
+
+	f, _ := tcdl.NewTCTransferFrame(42, 1, []byte("cmd"))
+	_ = tx.ApplyTC(f)
+	if got := tx.IV(); got[11] != 1 || got[0] != 0xA0 {
+		t.Errorf("IV after first frame = %x", got)
+	}
+	b, _ := f.Encode()
+
+	rx, _ := tcdl.DecodeTCTransferFrame(b)
+	if _, err := store.ProcessTC(rx); err != nil {
+		t.Fatal(err)
+	}
+	rx, _ = tcdl.DecodeTCTransferFrame(b)
+	if _, err := store.ProcessTC(rx); !errors.Is(err, sdls.ErrReplay) {
+		t.Errorf("expected ErrReplay for reused IV, got %v", err)
+	}
+}
+
+func TestSecurityAssociation_SequenceExhausted(t *testing.T) {
+	sa, _ := sdls.NewSecurityAssociation(5, sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA256, testKey,
+		sdls.WithSequenceNumberLength(1), sdls.WithARSN(254))
+	f, _ := tcdl.NewTCTransferFrame(42, 1, []byte{1})
+	if err := sa.ApplyTC(f); err != nil {
+		t.Fatal(err)
+	}
+	f, _ = tcdl.NewTCTransferFrame(42, 1, []byte{2})
+	if err := sa.ApplyTC(f); !errors.Is(err, sdls.ErrSequenceExhausted) {
+		t.Errorf("expected ErrSequenceExhausted, got %v", err)
+	}
+}
+
+func TestSecurityAssociation_DecodeHeader(t *testing.T) {
+	sa, _ := sdls.NewSecurityAssociation(0x0102, sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, testKey,
+		sdls.WithSequenceNumberLength(2), sdls.WithPadLengthField(1))
+	f, _ := tcdl.NewTCTransferFrame(42, 1, []byte{0xAA})
+	_ = sa.ApplyTC(f)
+
+	hdr, err := sa.DecodeHeader(f.DataField)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.SPI != 0x0102 || !bytes.Equal(hdr.SequenceNumber, []byte{0, 1}) || !bytes.Equal(hdr.PadLength, []byte{0}) {
+		t.Errorf("header = %+v", hdr)
+	}
+	if !bytes.Equal(hdr.Encode(), f.DataField[:sa.HeaderLength()]) {
+		t.Error("re-encoded header differs")
+	}
+	if _, err := sa.DecodeHeader(f.DataField[:3]); !errors.Is(err, sdls.ErrDataTooShort) {
+		t.Errorf("expected ErrDataTooShort, got %v", err)
+	}
+}
+
+func TestSecurityAssociation_NoKey(t *testing.T) {
+	sa, err := sdls.NewSecurityAssociation(6, sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := tcdl.NewTCTransferFrame(42, 1, []byte{1})
+	if err := sa.ApplyTC(f); !errors.Is(err, sdls.ErrNoKey) {
+		t.Errorf("expected ErrNoKey, got %v", err)
+	}
+	if err := sa.SetKey(testKey[:16]); err != nil {
+		t.Fatal(err)
+	}
+	if err := sa.ApplyTC(f); err != nil {
+		t.Errorf("apply after SetKey: %v", err)
+	}
+	if err := sa.SetKey([]byte{1, 2, 3}); !errors.Is(err, sdls.ErrInvalidKeyLength) {
+		t.Errorf("expected ErrInvalidKeyLength, got %v", err)
+	}
+}
+
+func TestSecurityAssociation_BoundConcurrent(t *testing.T) {
+	// Start rebinds the SA while frames are checked against it; run with
+	// -race to catch unsynchronized access.
+	sa, _ := sdls.NewSecurityAssociation(9, sdls.ServiceAuthentication, sdls.AlgorithmHMACSHA256, testKey,
+		sdls.WithVCIDs(1))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			_ = sa.Stop()
+			_ = sa.Start(uint8(1 + i%2))
+		}
+	}()
+	for range 100 {
+		sa.Bound(1)
+	}
+	wg.Wait()
+	if !sa.Bound(2) || sa.Bound(1) {
+		t.Error("last Start did not bind the SA to VC 2")
+	}
+}
@@ -0,0 +1,175 @@
+// Package sdls implements the Space Data Link Security (SDLS) Protocol
+// per CCSDS 355.0-B-2.
+//
+// SDLS protects the data field of TC, TM, AOS and USLP Transfer Frames
+// with a Security Header in front of the protected data and a Security
+// Trailer behind it:
+//   - SecurityAssociation holds the managed parameters (service, algorithm,
+//     field lengths, key) and the IV and sequence number state of one SA
+//   - ApplyTC, ApplyTM, ApplyAOS and ApplyUSLP protect a frame (ApplySecurity)
+//   - SAStore finds the SA by the received SPI and verifies, decrypts and
+//     anti-replay checks a frame (ProcessSecurity)
+//
+// Authenticated encryption uses AES-GCM. Authentication-only SAs use
+// AES-CMAC, HMAC-SHA-256, HMAC-SHA-512 or GMAC.
+//
+// The Extended Procedures of CCSDS 355.1-B-1 manage keys and SAs in
+// flight:
+//   - PDU encodes the EP commands and replies carried in Space Packets
+//   - KeyStore holds keys by ID; MemoryKeyStore and FileKeyStore implement it
+//   - EPProcessor executes commands on board and records a SecurityLog
+package sdls
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// ServiceType selects the security service an SA provides.
+type ServiceType uint8
+
+const (
+	ServiceAuthentication          ServiceType = iota + 1 // MAC over header and data, data in the clear
+	ServiceAuthenticatedEncryption                        // data encrypted, MAC over header and ciphertext
+)
+
+// String returns the service name.
+func (s ServiceType) String() string {
+	switch s {
+	case ServiceAuthentication:
+		return "Authentication"
+	case ServiceAuthenticatedEncryption:
+		return "Authenticated Encryption"
+	default:
+		return "Unknown"
+	}
+}
+
+// Algorithm identifies the cryptographic algorithm of an SA.
+type Algorithm uint8
+
+const (
+	AlgorithmAESGCM     Algorithm = iota + 1 // AES-GCM (GMAC for authentication only)
+	AlgorithmAESCMAC                         // AES-CMAC, authentication only
+	AlgorithmHMACSHA256                      // HMAC-SHA-256, authentication only
+	AlgorithmHMACSHA512                      // HMAC-SHA-512, authentication only
+)
+
+// String returns the algorithm name.
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmAESGCM:
+		return "AES-GCM"
+	case AlgorithmAESCMAC:
+		return "AES-CMAC"
+	case AlgorithmHMACSHA256:
+		return "HMAC-SHA-256"
+	case AlgorithmHMACSHA512:
+		return "HMAC-SHA-512"
+	default:
+		return "Unknown"
+	}
+}
+
+// maxMACLength returns the full MAC length produced by the algorithm.
+func (a Algorithm) maxMACLength() int {
+	switch a {
+	case AlgorithmAESGCM, AlgorithmAESCMAC:
+		return 16
+	case AlgorithmHMACSHA256:
+		return 32
+	case AlgorithmHMACSHA512:
+		return 64
+	default:
+		return 0
+	}
+}
+
+const (
+	// SPISize is the size of the Security Parameter Index in bytes.
+	SPISize = 2
+	// GCMIVSize is the IV length required by AES-GCM SAs.
+	GCMIVSize = 12
+	// MaxIVLength is the longest IV an SA may carry.
+	MaxIVLength = 32
+	// MaxSequenceLength is the longest Sequence Number field an SA may carry.
+	MaxSequenceLength = 8
+	// MaxPadLength is the longest Pad Length field an SA may carry.
+	MaxPadLength = 2
+)
+
+// SecurityHeader is the SDLS Security Header per CCSDS 355.0-B-2
+// Section 3.2. Only the SPI is mandatory; the lengths of the other
+// fields are managed parameters of the SA the SPI selects.
+//
+// Layout:
+//
+//	[SPI:2][IV:0-32][Sequence Number:0-8][Pad Length:0-2]
+type SecurityHeader struct {
+	SPI            uint16
+	IV             []byte
+	SequenceNumber []byte
+	PadLength      []byte
+}
+
+// Encode packs the Security Header into a byte slice.
+func (h *SecurityHeader) Encode() []byte {
+	b := make([]byte, SPISize, SPISize+len(h.IV)+len(h.SequenceNumber)+len(h.PadLength))
+	binary.BigEndian.PutUint16(b, h.SPI)
+	b = append(b, h.IV...)
+	b = append(b, h.SequenceNumber...)
+	b = append(b, h.PadLength...)
+	return b
+}
+
+// Humanize returns a human-readable representation of the Security Header.
+func (h *SecurityHeader) Humanize() string {
+	return strings.Join([]string{
+		"  SPI: " + strconv.Itoa(int(h.SPI)),
+		"  IV: " + hex.EncodeToString(h.IV),
+		"  Sequence Number: " + hex.EncodeToString(h.SequenceNumber),
+		"  Pad Length: " + hex.EncodeToString(h.PadLength),
+	}, "\n")
+}
+
+// counterValue returns the big-endian value of the trailing (up to)
+// eight bytes of b.
+func counterValue(b []byte) uint64 {
+	var v uint64
+	for _, c := range b[max(0, len(b)-8):] {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// counterMax returns the largest value a counter of n bytes can hold,
+// counting at most eight bytes.
+func counterMax(n int) uint64 {
+	if n >= 8 {
+		return ^uint64(0)
+	}
+	return 1<<(8*uint(n)) - 1
+}
+
+// putCounter writes v into the trailing (up to) eight bytes of b.
+func putCounter(b []byte, v uint64) {
+	for i := len(b) - 1; i >= max(0, len(b)-8); i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+}
+
+// concat returns a new slice holding the given slices back to back.
+func concat(parts ...[]byte) []byte {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	out := make([]byte, 0, n)
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package sdls
+
+import (
+	"encoding/binary"
+	"slices"
+	"sync"
+
+	"github.com/ravisuhag/astro/pkg/aos"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+	"github.com/ravisuhag/astro/pkg/tmdl"
+	"github.com/ravisuhag/astro/pkg/usdl"
+)
+
+// SAStore holds the Security Associations known to one end of the link,
+// keyed by SPI. Its Process methods implement the ProcessSecurity
+// procedure of CCSDS 355.0-B-2 Section 3.4: the SPI at the start of the
+// frame data field selects the SA, which verifies and unwraps the frame.
+type SAStore struct {
+	mu  sync.Mutex
+	sas map[uint16]*SecurityAssociation
+}
+
+// NewSAStore creates an empty store.
+func NewSAStore() *SAStore {
+	return &SAStore{sas: make(map[uint16]*SecurityAssociation)}
+}
+
+// Add registers sa. It fails with ErrDuplicateSPI if the SPI is taken.
+func (s *SAStore) Add(sa *SecurityAssociation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sas[sa.SPI()]; ok {
+		return ErrDuplicateSPI
+	}
+	s.sas[sa.SPI()] = sa
+	return nil
+}
+
+// Get returns the SA registered for spi.
+func (s *SAStore) Get(spi uint16) (*SecurityAssociation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sa, ok := s.sas[spi]
+	return sa, ok
+}
+
+// Remove unregisters the SA for spi.
+func (s *SAStore) Remove(spi uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sas, spi)
+}
+
+// SPIs returns the registered SPIs in ascending order.
+func (s *SAStore) SPIs() []uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spis := make([]uint16, 0, len(s.sas))
+	for spi := range s.sas {
+		spis = append(spis, spi)
+	}
+	slices.Sort(spis)
+	return spis
+}
+
+// ProcessTC verifies a protected TC Transfer Frame and replaces its data
+// field with the recovered data. Frames using the MAP sublayer must be
+// decoded with tcdl.DecodeTCTransferFrameWithSegmentHeader so the
+// Security Header starts the data field. Returns the SA that accepted
+// the frame.
+func (s *SAStore) ProcessTC(frame *tcdl.TCTransferFrame) (*SecurityAssociation, error) {
+	sa, err := s.lookup(frame.DataField, frame.Header.VirtualChannelID)
+	if err != nil {
+		return nil, err
+	}
+	return sa, sa.processTC(frame)
+}
+
+// ProcessTM verifies a protected TM Transfer Frame and replaces its data
+// field with the recovered data. Returns the SA that accepted the frame.
+func (s *SAStore) ProcessTM(frame *tmdl.TMTransferFrame) (*SecurityAssociation, error) {
+	sa, err := s.lookup(frame.DataField, frame.Header.VirtualChannelID)
+	if err != nil {
+		return nil, err
+	}
+	return sa, sa.processTM(frame)
+}
+
+// ProcessAOS verifies a protected AOS Transfer Frame and replaces its
+// data field with the recovered data. Returns the SA that accepted the
+// frame.
+func (s *SAStore) ProcessAOS(frame *aos.TransferFrame) (*SecurityAssociation, error) {
+	sa, err := s.lookup(frame.DataField, frame.Header.VCID)
+	if err != nil {
+		return nil, err
+	}
+	return sa, sa.processAOS(frame)
+}
+
+// ProcessUSLP verifies a protected USLP Transfer Frame and replaces its
+// data zone with the recovered data. Frames with an OCF must be decoded
+// with usdl.DecodeTransferFrameWithOCF. Returns the SA that accepted the
+// frame.
+func (s *SAStore) ProcessUSLP(frame *usdl.TransferFrame) (*SecurityAssociation, error) {
+	sa, err := s.lookup(frame.DataField, frame.Header.VCID)
+	if err != nil {
+		return nil, err
+	}
+	return sa, sa.processUSLP(frame)
+}
+
+// lookup returns the SA selected by the SPI at the start of field and
+// checks that it is bound to vcid.
+func (s *SAStore) lookup(field []byte, vcid uint8) (*SecurityAssociation, error) {
+	if len(field) < SPISize {
+		return nil, ErrDataTooShort
+	}
+	sa, ok := s.Get(binary.BigEndian.Uint16(field))
+	if !ok {
+		return nil, ErrUnknownSPI
+	}
+	if !sa.Bound(vcid) {
+		return nil, ErrChannelMismatch
+	}
+	return sa, nil
+}
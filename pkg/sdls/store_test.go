@@ -0,0 +1,32 @@
+package sdls_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/sdls"
+	"github.com/ravisuhag/astro/pkg/tcdl"
+)
+
+func TestSAStore(t *testing.T) {
+	store := sdls.NewSAStore()
+	sa, _ := sdls.NewSecurityAssociation(2, sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, testKey[:16])
+	sa1, _ := sdls.NewSecurityAssociation(1, sdls.ServiceAuthentication, sdls.AlgorithmAESCMAC, testKey[:16])
+	_ = store.Add(sa)
+	_ = store.Add(sa1)
+	if err := store.Add(sa); !errors.Is(err, sdls.ErrDuplicateSPI) {
+		t.Errorf("expected ErrDuplicateSPI, got %v", err)
+	}
+	if got := store.SPIs(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("SPIs = %v, want [1 2]", got)
+	}
+
+	frame, _ := tcdl.NewTCTransferFrame(42, 1, []byte{0x00, 0x09, 0xFF})
+	if _, err := store.ProcessTC(frame); !errors.Is(err, sdls.ErrUnknownSPI) {
+		t.Errorf("expected ErrUnknownSPI, got %v", err)
+	}
+	store.Remove(2)
+	if _, ok := store.Get(2); ok {
+		t.Error("SA 2 still registered after Remove")
+	}
+}
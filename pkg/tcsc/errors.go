@@ -16,7 +16,7 @@ var (
 	ErrTailSequenceMismatch = errors.New("CLTU tail sequence mismatch")
 
 	// ErrInvalidCLTULength indicates the CLTU body length (excluding start
-	// and tail sequences) is not a multiple of the codeblock size (8 bytes).
+	// and tail sequences) is not a multiple of the codeblock size.
 	ErrInvalidCLTULength = errors.New("CLTU body length is not a multiple of codeblock size")
 
 	// ErrUncorrectable indicates that a codeblock contains more errors
@@ -29,4 +29,12 @@ var (
 	// ErrInvalidInfoLength indicates that BCHEncode was called with a slice
 	// that is not exactly 7 bytes (InfoBytes).
 	ErrInvalidInfoLength = errors.New("BCH info must be exactly 7 bytes")
+
+	// ErrInvalidBlockLength indicates an LDPC information block or
+	// codeblock does not match the code parameters.
+	ErrInvalidBlockLength = errors.New("block length does not match LDPC code parameters")
+
+	// ErrNotConverged indicates the LDPC decoder reached its iteration
+	// limit without satisfying every parity check.
+	ErrNotConverged = errors.New("LDPC decoder did not converge")
 )
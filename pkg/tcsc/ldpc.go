@@ -0,0 +1,374 @@
+package tcsc
+
+// LDPC codes for CCSDS TC Synchronization and Channel Coding
+// per CCSDS 231.0-B-4.
+//
+// Two systematic codes are defined, each with a parity-check matrix of
+// 4x8 blocks of MxM circulants: the (128,64) code with M = 16 and the
+// (512,256) code with M = 64. A block is either zero, the identity I,
+// a power Φ^k of the first right circular shift of I, or I + Φ^k.
+//
+// A CLTU carrying LDPC codeblocks starts with a 64-bit start sequence
+// and may end with an optional 128-bit tail sequence. The Transfer
+// Frame is always pseudo-randomized before encoding.
+
+import (
+	"bytes"
+	"math"
+	"math/bits"
+)
+
+const (
+	ldpcIterations    = 50
+	ldpcNormalization = 0.75 // min-sum check message scaling
+	ldpcMaxLLR        = 127
+)
+
+// ldpcI marks an identity block in a parity-check matrix definition.
+const ldpcI = -1
+
+// ldpc128Blocks and ldpc512Blocks give each block of the parity-check
+// matrices as the sum of its terms: ldpcI for I, k for Φ^k.
+var (
+	ldpc128Blocks = [4][8][]int{
+		{{ldpcI, 7}, {2}, {14}, {6}, {}, {0}, {13}, {ldpcI}},
+		{{6}, {ldpcI, 15}, {0}, {1}, {ldpcI}, {}, {0}, {7}},
+		{{4}, {1}, {ldpcI, 15}, {14}, {11}, {ldpcI}, {}, {3}},
+		{{0}, {1}, {9}, {ldpcI, 13}, {14}, {1}, {ldpcI}, {}},
+	}
+	ldpc512Blocks = [4][8][]int{
+		{{ldpcI, 63}, {30}, {50}, {25}, {}, {43}, {62}, {ldpcI}},
+		{{56}, {ldpcI, 61}, {50}, {23}, {ldpcI}, {}, {37}, {26}},
+		{{16}, {0}, {ldpcI, 55}, {27}, {56}, {ldpcI}, {}, {43}},
+		{{35}, {56}, {62}, {ldpcI, 11}, {58}, {3}, {ldpcI}, {}},
+	}
+)
+
+// LDPCStartSequence returns the 64-bit CLTU start sequence used with
+// LDPC codes (0x034776C7272895B0). A fresh copy is returned each call.
+func LDPCStartSequence() []byte {
+	return []byte{0x03, 0x47, 0x76, 0xC7, 0x27, 0x28, 0x95, 0xB0}
+}
+
+// LDPCTailSequence returns the optional 128-bit CLTU tail sequence used
+// with LDPC codes (0x55555556AAAAAAAA5555555555555555). A fresh copy
+// is returned each call.
+func LDPCTailSequence() []byte {
+	return []byte{
+		0x55, 0x55, 0x55, 0x56, 0xAA, 0xAA, 0xAA, 0xAA,
+		0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55,
+	}
+}
+
+// LDPCCodec holds the parity-check matrix and precomputed encoder of a
+// CCSDS TC LDPC code.
+type LDPCCodec struct {
+	n, k   int       // codeblock and information bits
+	checks [][]int   // bit indices of each parity check
+	parity [][]uint8 // information bytes masks giving each parity bit
+}
+
+// NewLDPC128_64 returns an LDPCCodec for the (128,64) code: 8 information
+// bytes per 16-byte codeblock.
+func NewLDPC128_64() *LDPCCodec {
+	return newLDPCCodec(16, &ldpc128Blocks)
+}
+
+// NewLDPC512_256 returns an LDPCCodec for the (512,256) code: 32
+// information bytes per 64-byte codeblock.
+func NewLDPC512_256() *LDPCCodec {
+	return newLDPCCodec(64, &ldpc512Blocks)
+}
+
+func newLDPCCodec(m int, blocks *[4][8][]int) *LDPCCodec {
+	l := &LDPCCodec{n: 8 * m, k: 4 * m}
+	for _, row := range blocks {
+		for r := range m {
+			var check []int
+			for j, terms := range row {
+				for _, t := range terms {
+					c := r
+					if t != ldpcI {
+						c = (r + t) % m
+					}
+					check = append(check, j*m+c)
+				}
+			}
+			l.checks = append(l.checks, check)
+		}
+	}
+	l.parity = l.buildEncoder()
+	return l
+}
+
+// buildEncoder row-reduces the parity-check matrix over the parity bits,
+// which is full rank, so that each row gives one parity bit as the
+// parity of a set of information bits.
+func (l *LDPCCodec) buildEncoder() [][]uint8 {
+	rows := make([][]bool, len(l.checks))
+	for i, check := range l.checks {
+		rows[i] = make([]bool, l.n)
+		for _, c := range check {
+			rows[i][c] = !rows[i][c]
+		}
+	}
+	for p := range l.n - l.k {
+		col := l.k + p
+		for r := p; r < len(rows); r++ {
+			if rows[r][col] {
+				rows[p], rows[r] = rows[r], rows[p]
+				break
+			}
+		}
+		for r := range rows {
+			if r != p && rows[r][col] {
+				for c := range rows[r] {
+					rows[r][c] = rows[r][c] != rows[p][c]
+				}
+			}
+		}
+	}
+	masks := make([][]uint8, l.n-l.k)
+	for p := range masks {
+		masks[p] = make([]uint8, l.k/8)
+		for c := range l.k {
+			if rows[p][c] {
+				masks[p][c/8] |= 1 << (7 - c%8)
+			}
+		}
+	}
+	return masks
+}
+
+// InfoLen returns the number of information bytes per codeblock.
+func (l *LDPCCodec) InfoLen() int { return l.k / 8 }
+
+// CodeblockLen returns the number of bytes per codeblock.
+func (l *LDPCCodec) CodeblockLen() int { return l.n / 8 }
+
+// Encode encodes InfoLen() bytes into a CodeblockLen()-byte systematic
+// codeblock: the information followed by the parity. Returns
+// ErrInvalidBlockLength if info has the wrong length.
+func (l *LDPCCodec) Encode(info []byte) ([]byte, error) {
+	if len(info) != l.InfoLen() {
+		return nil, ErrInvalidBlockLength
+	}
+	cb := make([]byte, l.CodeblockLen())
+	copy(cb, info)
+	for p, mask := range l.parity {
+		n := 0
+		for i, m := range mask {
+			n += bits.OnesCount8(m & info[i])
+		}
+		if n&1 == 1 {
+			cb[l.InfoLen()+p/8] |= 1 << (7 - p%8)
+		}
+	}
+	return cb, nil
+}
+
+// Decode decodes a codeblock of soft symbols, one int8 log-likelihood
+// ratio per bit: positive values favour a 0, negative values a 1. It runs
+// normalized min-sum belief propagation until all parity checks hold and
+// returns the information bytes and the number of bits whose hard
+// decision was corrected. Returns ErrNotConverged if the checks still
+// fail after 50 iterations.
+func (l *LDPCCodec) Decode(symbols []int8) ([]byte, int, error) {
+	if len(symbols) != l.n {
+		return nil, 0, ErrInvalidBlockLength
+	}
+	hard := make([]bool, l.n)
+	for i, s := range symbols {
+		hard[i] = s < 0
+	}
+	decoded, ok := l.minSum(symbols)
+	if !ok {
+		return nil, 0, ErrNotConverged
+	}
+	corrected := 0
+	for i := range hard {
+		if hard[i] != decoded[i] {
+			corrected++
+		}
+	}
+	info := make([]byte, l.InfoLen())
+	for i := range l.k {
+		if decoded[i] {
+			info[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return info, corrected, nil
+}
+
+// DecodeHard decodes a codeblock of hard-decision bits and returns the
+// information bytes and the number of corrected bit errors.
+func (l *LDPCCodec) DecodeHard(cb []byte) ([]byte, int, error) {
+	if len(cb) != l.CodeblockLen() {
+		return nil, 0, ErrInvalidBlockLength
+	}
+	symbols := make([]int8, l.n)
+	for i := range symbols {
+		if cb[i/8]>>(7-i%8)&1 == 1 {
+			symbols[i] = -ldpcMaxLLR
+		} else {
+			symbols[i] = ldpcMaxLLR
+		}
+	}
+	return l.Decode(symbols)
+}
+
+// minSum runs normalized min-sum decoding and returns the hard decisions
+// and whether all parity checks hold.
+func (l *LDPCCodec) minSum(symbols []int8) ([]bool, bool) {
+	hard := make([]bool, l.n)
+	total := make([]float32, l.n)
+	for i, s := range symbols {
+		total[i] = float32(s)
+		hard[i] = s < 0
+	}
+	if l.satisfied(hard) {
+		return hard, true
+	}
+
+	c2v := make([][]float32, len(l.checks))
+	for i, check := range l.checks {
+		c2v[i] = make([]float32, len(check))
+	}
+	for range ldpcIterations {
+		for i, check := range l.checks {
+			min1, min2 := float32(math.MaxFloat32), float32(math.MaxFloat32)
+			arg := -1
+			negative := false
+			for j, v := range check {
+				m := total[v] - c2v[i][j]
+				if m < 0 {
+					negative = !negative
+					m = -m
+				}
+				switch {
+				case m < min1:
+					min1, min2, arg = m, min1, j
+				case m < min2:
+					min2 = m
+				}
+			}
+			for j, v := range check {
+				m := total[v] - c2v[i][j]
+				mag := min1
+				if j == arg {
+					mag = min2
+				}
+				msg := ldpcNormalization * mag
+				if negative != (m < 0) {
+					msg = -msg
+				}
+				c2v[i][j] = msg
+			}
+		}
+
+		for i, s := range symbols {
+			total[i] = float32(s)
+		}
+		for i, check := range l.checks {
+			for j, v := range check {
+				total[v] += c2v[i][j]
+			}
+		}
+		for i, t := range total {
+			hard[i] = t < 0
+		}
+		if l.satisfied(hard) {
+			return hard, true
+		}
+	}
+	return hard, false
+}
+
+// satisfied reports whether hard decisions satisfy every parity check.
+func (l *LDPCCodec) satisfied(hard []bool) bool {
+	for _, check := range l.checks {
+		parity := false
+		for _, v := range check {
+			parity = parity != hard[v]
+		}
+		if parity {
+			return false
+		}
+	}
+	return true
+}
+
+// WrapLDPCCLTU produces a CLTU of LDPC codeblocks from TC Transfer Frame
+// data. It:
+//  1. Applies CCSDS pseudo-randomization to the frame data, which is
+//     mandatory with LDPC codes
+//  2. Pads the data to a multiple of InfoLen() bytes (fill: 0x55)
+//  3. Encodes each block into a codeblock
+//  4. Prepends the start sequence and appends the tail sequence, if any
+//
+// If startSeq is nil, LDPCStartSequence is used. The tail sequence is
+// optional with LDPC: if tailSeq is nil, none is appended; pass
+// LDPCTailSequence() to append the standard one.
+func WrapLDPCCLTU(frameData []byte, code *LDPCCodec, startSeq, tailSeq []byte) ([]byte, error) {
+	if len(frameData) == 0 {
+		return nil, ErrEmptyData
+	}
+	if startSeq == nil {
+		startSeq = LDPCStartSequence()
+	}
+	info := code.InfoLen()
+	data := Randomize(frameData)
+	if rem := len(data) % info; rem != 0 {
+		data = append(data, bytes.Repeat([]byte{0x55}, info-rem)...)
+	}
+
+	numBlocks := len(data) / info
+	cltu := make([]byte, 0, len(startSeq)+numBlocks*code.CodeblockLen()+len(tailSeq))
+	cltu = append(cltu, startSeq...)
+	for i := range numBlocks {
+		cb, err := code.Encode(data[i*info : (i+1)*info])
+		if err != nil {
+			return nil, err
+		}
+		cltu = append(cltu, cb...)
+	}
+	return append(cltu, tailSeq...), nil
+}
+
+// UnwrapLDPCCLTU extracts and error-corrects TC Transfer Frame data from
+// a CLTU of LDPC codeblocks. It validates and strips the start sequence
+// and, if tailSeq is not nil, the tail sequence; decodes each codeblock;
+// and de-randomizes the result. Returns the recovered frame data with
+// any fill, the total number of corrected bit errors, and any error.
+// If startSeq is nil, LDPCStartSequence is used.
+func UnwrapLDPCCLTU(cltu []byte, code *LDPCCodec, startSeq, tailSeq []byte) ([]byte, int, error) {
+	if startSeq == nil {
+		startSeq = LDPCStartSequence()
+	}
+	cbLen := code.CodeblockLen()
+	if len(cltu) < len(startSeq)+cbLen+len(tailSeq) {
+		return nil, 0, ErrDataTooShort
+	}
+	if !bytes.Equal(cltu[:len(startSeq)], startSeq) {
+		return nil, 0, ErrStartSequenceMismatch
+	}
+	if !bytes.Equal(cltu[len(cltu)-len(tailSeq):], tailSeq) {
+		return nil, 0, ErrTailSequenceMismatch
+	}
+
+	body := cltu[len(startSeq) : len(cltu)-len(tailSeq)]
+	if len(body)%cbLen != 0 {
+		return nil, 0, ErrInvalidCLTULength
+	}
+	result := make([]byte, 0, len(body)/cbLen*code.InfoLen())
+	totalCorr := 0
+	for i := 0; i < len(body); i += cbLen {
+		info, corr, err := code.DecodeHard(body[i : i+cbLen])
+		if err != nil {
+			return nil, 0, err
+		}
+		totalCorr += corr
+		result = append(result, info...)
+	}
+	return Randomize(result), totalCorr, nil
+}
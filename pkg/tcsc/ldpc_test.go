@@ -0,0 +1,239 @@
+package tcsc_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/tcsc"
+)
+
+func ldpcCodes() map[string]*tcsc.LDPCCodec {
+	return map[string]*tcsc.LDPCCodec{
+		"128_64":  tcsc.NewLDPC128_64(),
+		"512_256": tcsc.NewLDPC512_256(),
+	}
+}
+
+func flipBits(rng *rand.Rand, cb []byte, n int) {
+	for _, p := range rng.Perm(8 * len(cb))[:n] {
+		cb[p/8] ^= 1 << (7 - p%8)
+	}
+}
+
+func TestLDPCSequences(t *testing.T) {
+	if got := tcsc.LDPCStartSequence(); !bytes.Equal(got, []byte{0x03, 0x47, 0x76, 0xC7, 0x27, 0x28, 0x95, 0xB0}) {
+		t.Errorf("LDPCStartSequence() = %x", got)
+	}
+	if got := tcsc.LDPCTailSequence(); len(got) != 16 {
+		t.Errorf("LDPCTailSequence() = %x", got)
+	}
+	ss := tcsc.LDPCStartSequence()
+	ss[0] = 0xFF
+	if tcsc.LDPCStartSequence()[0] != 0x03 {
+		t.Error("LDPCStartSequence must return a fresh copy")
+	}
+}
+
+func TestLDPC_Lengths(t *testing.T) {
+	tests := []struct {
+		code      *tcsc.LDPCCodec
+		info, blk int
+	}{
+		{tcsc.NewLDPC128_64(), 8, 16},
+		{tcsc.NewLDPC512_256(), 32, 64},
+	}
+	for _, tt := range tests {
+		if tt.code.InfoLen() != tt.info || tt.code.CodeblockLen() != tt.blk {
+			t.Errorf("InfoLen() = %d, CodeblockLen() = %d, want %d, %d",
+				tt.code.InfoLen(), tt.code.CodeblockLen(), tt.info, tt.blk)
+		}
+	}
+}
+
+func TestLDPC_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 0))
+	for name, code := range ldpcCodes() {
+		info := make([]byte, code.InfoLen())
+		for i := range info {
+			info[i] = byte(rng.IntN(256))
+		}
+		cb, err := code.Encode(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cb[:len(info)], info) {
+			t.Errorf("%s: codeblock is not systematic", name)
+		}
+		got, corr, err := code.DecodeHard(cb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if corr != 0 || !bytes.Equal(got, info) {
+			t.Errorf("%s: DecodeHard = %x, %d corrections", name, got, corr)
+		}
+	}
+}
+
+func TestLDPC_Linear(t *testing.T) {
+	// The all-zero block encodes to the all-zero codeblock, and the sum
+	// of two codeblocks is the codeblock of the sum.
+	code := tcsc.NewLDPC128_64()
+	zero, _ := code.Encode(make([]byte, 8))
+	if !bytes.Equal(zero, make([]byte, 16)) {
+		t.Errorf("Encode(0) = %x", zero)
+	}
+	a := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	b := []byte{0x80, 0, 0xFF, 0x10, 0, 0, 0x42, 1}
+	sum := make([]byte, 8)
+	for i := range sum {
+		sum[i] = a[i] ^ b[i]
+	}
+	ca, _ := code.Encode(a)
+	cb, _ := code.Encode(b)
+	cs, _ := code.Encode(sum)
+	for i := range cs {
+		if cs[i] != ca[i]^cb[i] {
+			t.Fatalf("Encode is not linear: %x ^ %x != %x", ca, cb, cs)
+		}
+	}
+}
+
+func TestLDPC_CorrectErrors(t *testing.T) {
+	rng := rand.New(rand.NewPCG(2, 0))
+	tests := []struct {
+		code  *tcsc.LDPCCodec
+		nerrs int
+	}{
+		{tcsc.NewLDPC128_64(), 5},
+		{tcsc.NewLDPC512_256(), 12},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			info := make([]byte, tt.code.InfoLen())
+			for i := range info {
+				info[i] = byte(rng.IntN(256))
+			}
+			cb, _ := tt.code.Encode(info)
+			flipBits(rng, cb, tt.nerrs)
+			got, corr, err := tt.code.DecodeHard(cb)
+			if err != nil {
+				t.Fatalf("%d-bit code, %d errors: %v", 8*tt.code.CodeblockLen(), tt.nerrs, err)
+			}
+			if corr != tt.nerrs || !bytes.Equal(got, info) {
+				t.Errorf("%d-bit code: %d corrections, data match %v",
+					8*tt.code.CodeblockLen(), corr, bytes.Equal(got, info))
+			}
+		}
+	}
+}
+
+func TestLDPC_SoftDecode(t *testing.T) {
+	// Low-confidence wrong symbols are corrected even in numbers a
+	// hard-decision decoder could not handle.
+	code := tcsc.NewLDPC128_64()
+	info := []byte("softbits")
+	cb, _ := code.Encode(info)
+	symbols := make([]int8, 128)
+	for i := range symbols {
+		if cb[i/8]>>(7-i%8)&1 == 1 {
+			symbols[i] = -100
+		} else {
+			symbols[i] = 100
+		}
+	}
+	for i := 0; i < 128; i += 8 {
+		symbols[i] = -symbols[i] / 10
+	}
+	got, corr, err := code.Decode(symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 16 || !bytes.Equal(got, info) {
+		t.Errorf("Decode = %q, %d corrections", got, corr)
+	}
+}
+
+func TestLDPC_NotConverged(t *testing.T) {
+	code := tcsc.NewLDPC128_64()
+	cb, _ := code.Encode(make([]byte, 8))
+	flipBits(rand.New(rand.NewPCG(3, 0)), cb, 40)
+	if _, _, err := code.DecodeHard(cb); !errors.Is(err, tcsc.ErrNotConverged) {
+		t.Errorf("got %v, want ErrNotConverged", err)
+	}
+}
+
+func TestLDPC_WrongLength(t *testing.T) {
+	code := tcsc.NewLDPC128_64()
+	if _, err := code.Encode(make([]byte, 7)); !errors.Is(err, tcsc.ErrInvalidBlockLength) {
+		t.Errorf("Encode: got %v", err)
+	}
+	if _, _, err := code.Decode(make([]int8, 127)); !errors.Is(err, tcsc.ErrInvalidBlockLength) {
+		t.Errorf("Decode: got %v", err)
+	}
+	if _, _, err := code.DecodeHard(make([]byte, 15)); !errors.Is(err, tcsc.ErrInvalidBlockLength) {
+		t.Errorf("DecodeHard: got %v", err)
+	}
+}
+
+func TestLDPCCLTU_RoundTrip(t *testing.T) {
+	frame := []byte("TC transfer frame carried in LDPC codeblocks")
+	for name, code := range ldpcCodes() {
+		for _, tail := range [][]byte{nil, tcsc.LDPCTailSequence()} {
+			cltu, err := tcsc.WrapLDPCCLTU(frame, code, nil, tail)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks := (len(frame) + code.InfoLen() - 1) / code.InfoLen()
+			if want := 8 + blocks*code.CodeblockLen() + len(tail); len(cltu) != want {
+				t.Errorf("%s: CLTU length %d, want %d", name, len(cltu), want)
+			}
+			if !bytes.Equal(cltu[:8], tcsc.LDPCStartSequence()) {
+				t.Errorf("%s: start sequence %x", name, cltu[:8])
+			}
+
+			// The frame is randomized before encoding.
+			if bytes.Contains(cltu, frame[:8]) {
+				t.Errorf("%s: frame data is not randomized", name)
+			}
+
+			cltu[10] ^= 0x01
+			got, corr, err := tcsc.UnwrapLDPCCLTU(cltu, code, nil, tail)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if corr != 1 {
+				t.Errorf("%s: corrections = %d, want 1", name, corr)
+			}
+			if !bytes.Equal(got[:len(frame)], frame) {
+				t.Errorf("%s: frame = %q", name, got[:len(frame)])
+			}
+		}
+	}
+}
+
+func TestLDPCCLTU_Errors(t *testing.T) {
+	code := tcsc.NewLDPC128_64()
+	if _, err := tcsc.WrapLDPCCLTU(nil, code, nil, nil); !errors.Is(err, tcsc.ErrEmptyData) {
+		t.Errorf("WrapLDPCCLTU(nil): got %v", err)
+	}
+
+	cltu, _ := tcsc.WrapLDPCCLTU([]byte{1, 2, 3}, code, nil, tcsc.LDPCTailSequence())
+	tests := []struct {
+		name string
+		data []byte
+		tail []byte
+		want error
+	}{
+		{"too short", cltu[:20], tcsc.LDPCTailSequence(), tcsc.ErrDataTooShort},
+		{"start", append([]byte{0}, cltu[1:]...), tcsc.LDPCTailSequence(), tcsc.ErrStartSequenceMismatch},
+		{"tail", cltu, tcsc.DefaultTailSequence(), tcsc.ErrTailSequenceMismatch},
+		{"length", append(cltu[:len(cltu):len(cltu)], 0), nil, tcsc.ErrInvalidCLTULength},
+	}
+	for _, tt := range tests {
+		if _, _, err := tcsc.UnwrapLDPCCLTU(tt.data, code, nil, tt.tail); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
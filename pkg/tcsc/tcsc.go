@@ -5,6 +5,7 @@
 // and the physical layer, providing:
 //   - Command Link Transmission Unit (CLTU) wrapping and unwrapping
 //   - BCH(63,56) forward error correction per codeblock
+//   - LDPC (128,64) and (512,256) codes with soft-decision decoding
 //   - CCSDS pseudo-randomization for bit transition density assurance
 package tcsc
 
@@ -683,6 +683,91 @@ func TestMasterChannel_MultiplexesSendPath(t *testing.T) {
 	}
 }
 
+func TestMasterChannel_OCFProvider(t *testing.T) {
+	config := tmdl.ChannelConfig{FrameLength: 32, HasOCF: true, HasFEC: true}
+	mc := tmdl.NewMasterChannel(933, config)
+	vc := tmdl.NewVirtualChannel(1, 10)
+	mc.AddVirtualChannel(vc, 1)
+
+	report := []byte{0x01, 0x02, 0x03, 0x04}
+	calls := 0
+	mc.SetOCFProvider(func() []byte {
+		calls++
+		return report
+	})
+
+	withOCF, _ := tmdl.NewTMTransferFrame(933, 1, []byte("data"), nil, make([]byte, 4))
+	withoutOCF, _ := tmdl.NewTMTransferFrame(933, 1, []byte("data"), nil, nil)
+	_ = vc.Add(withOCF)
+	_ = vc.Add(withoutOCF)
+
+	got, err := mc.GetNextFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := got.Encode()
+	decoded, err := tmdl.DecodeTMTransferFrame(encoded)
+	if err != nil {
+		t.Fatalf("CRC not recomputed: %v", err)
+	}
+	if !bytes.Equal(decoded.OperationalControl, report) {
+		t.Errorf("OCF = %x, want %x", decoded.OperationalControl, report)
+	}
+
+	got, _ = mc.GetNextFrame()
+	if got.Header.OCFFlag || calls != 1 {
+		t.Errorf("frame without OCF must pass through: OCFFlag=%v calls=%d", got.Header.OCFFlag, calls)
+	}
+
+	idle, err := mc.GetNextFrameOrIdle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tmdl.IsIdleFrame(idle) || !bytes.Equal(idle.OperationalControl, report) {
+		t.Errorf("idle frame OCF = %x, want %x", idle.OperationalControl, report)
+	}
+}
+
+func TestMasterChannel_OCFProviderInvalidLength(t *testing.T) {
+	config := tmdl.ChannelConfig{FrameLength: 32, HasOCF: true, HasFEC: true}
+	mc := tmdl.NewMasterChannel(933, config)
+	vc := tmdl.NewVirtualChannel(1, 10)
+	mc.AddVirtualChannel(vc, 1)
+
+	good := []byte{0x01, 0x02, 0x03, 0x04}
+	report := good
+	mc.SetOCFProvider(func() []byte { return report })
+
+	for range 2 {
+		frame, _ := tmdl.NewTMTransferFrame(933, 1, []byte("data"), nil, make([]byte, 4))
+		_ = vc.Add(frame)
+	}
+	if _, err := mc.GetNextFrame(); err != nil || mc.OCFError() != nil {
+		t.Fatalf("GetNextFrame() err = %v, OCFError() = %v", err, mc.OCFError())
+	}
+
+	// A malformed report must not cost the frame: the last good OCF is reused.
+	report = []byte{0xFF}
+	got, err := mc.GetNextFrame()
+	if err != nil || got == nil {
+		t.Fatalf("GetNextFrame() = %v, %v; want the frame", got, err)
+	}
+	if !errors.Is(mc.OCFError(), tmdl.ErrInvalidOCFLength) {
+		t.Errorf("OCFError() = %v, want ErrInvalidOCFLength", mc.OCFError())
+	}
+	encoded, _ := got.Encode()
+	decoded, err := tmdl.DecodeTMTransferFrame(encoded)
+	if err != nil {
+		t.Fatalf("CRC not recomputed: %v", err)
+	}
+	if !bytes.Equal(decoded.OperationalControl, good) {
+		t.Errorf("OCF = %x, want last good report %x", decoded.OperationalControl, good)
+	}
+	if vc.HasFrames() {
+		t.Error("frame left on the virtual channel")
+	}
+}
+
 func TestMasterChannel_FrameGapDetection(t *testing.T) {
 	mc := tmdl.NewMasterChannel(933, tmdl.ChannelConfig{})
 	vc := tmdl.NewVirtualChannel(1, 100)
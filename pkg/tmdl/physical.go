@@ -82,12 +82,20 @@ func (pc *PhysicalChannel) GetNextFrameOrIdle() (*TMTransferFrame, error) {
 	if pc.config.FrameLength == 0 {
 		return nil, sdl.ErrNoFramesAvailable
 	}
-	var scid uint16
-	for s := range pc.masterChannels {
-		scid = s
+	var mc *MasterChannel
+	for _, m := range pc.masterChannels {
+		mc = m
 		break
 	}
-	return NewIdleFrame(scid, 7, pc.config)
+	if mc == nil {
+		return NewIdleFrame(0, 7, pc.config)
+	}
+	idle, err := NewIdleFrame(mc.scid, 7, pc.config)
+	if err != nil {
+		return nil, err
+	}
+	mc.insertOCF(idle)
+	return idle, nil
 }
 
 // AddFrame demultiplexes an inbound frame to the appropriate Master Channel
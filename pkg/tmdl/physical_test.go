@@ -1,6 +1,7 @@
 package tmdl_test
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 
@@ -185,6 +186,27 @@ func TestPhysicalChannel_GetNextFrameOrIdle(t *testing.T) {
 	}
 }
 
+func TestPhysicalChannel_IdleFrameOCF(t *testing.T) {
+	config := tmdl.ChannelConfig{FrameLength: 32, HasOCF: true, HasFEC: true}
+	pc := tmdl.NewPhysicalChannel("test", config)
+	mc := tmdl.NewMasterChannel(933, config)
+	mc.SetOCFProvider(func() []byte { return []byte{0xAA, 0xBB, 0xCC, 0xDD} })
+	pc.AddMasterChannel(mc, 1)
+
+	frame, err := pc.GetNextFrameOrIdle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := frame.Encode()
+	decoded, err := tmdl.DecodeTMTransferFrame(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.OperationalControl, []byte{0xAA, 0xBB, 0xCC, 0xDD}) {
+		t.Errorf("idle OCF = %x", decoded.OperationalControl)
+	}
+}
+
 func TestPhysicalChannel_NoMasterChannels(t *testing.T) {
 	pc := tmdl.NewPhysicalChannel("empty", tmdl.ChannelConfig{})
 	_, err := pc.GetNextFrame()
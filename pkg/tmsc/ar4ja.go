@@ -0,0 +1,273 @@
+package tmsc
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// LDPCRate identifies an AR4JA LDPC code rate.
+type LDPCRate int
+
+// Supported AR4JA code rates.
+const (
+	LDPCRate1_2 LDPCRate = iota // rate 1/2
+	LDPCRate2_3                 // rate 2/3
+	LDPCRate4_5                 // rate 4/5
+)
+
+// String returns the rate as a fraction, such as "1/2".
+func (r LDPCRate) String() string {
+	switch r {
+	case LDPCRate1_2:
+		return "1/2"
+	case LDPCRate2_3:
+		return "2/3"
+	case LDPCRate4_5:
+		return "4/5"
+	}
+	return "unknown"
+}
+
+// ar4jaBase is the protograph of H_1/2. Each entry lists the
+// permutations Π_k summed in that M×M block, with 0 for the identity.
+var ar4jaBase = [3][5][]int{
+	{nil, nil, {0}, nil, {0, 1}},
+	{{0}, {0}, nil, {0}, {2, 3, 4}},
+	{{0}, {5, 6}, nil, {7, 8}, {0}},
+}
+
+// ar4jaExtensions are the pairs of block columns placed in front of
+// H_1/2: the first gives H_2/3, and all three, the last leftmost, give
+// H_4/5.
+var ar4jaExtensions = [3][3][2][]int{
+	{{nil, nil}, {{9, 10, 11}, {0}}, {{0}, {12, 13, 14}}},
+	{{nil, nil}, {{15, 16, 17}, {0}}, {{0}, {18, 19, 20}}},
+	{{nil, nil}, {{21, 22, 23}, {0}}, {{0}, {24, 25, 26}}},
+}
+
+// ar4jaTheta lists θ_k for the permutations Π_1 to Π_26.
+var ar4jaTheta = [26]int{
+	3, 0, 1, 2, 2, 3, 0, 1, 0, 1, 2, 0, 2,
+	3, 0, 1, 2, 0, 1, 2, 0, 1, 2, 1, 2, 3,
+}
+
+// ar4jaPhi lists φ_k(j, M) for j = 0 to 3, k = 1 to 26 and M = 128, 256,
+// 512, 1024, 2048, 4096 and 8192.
+var ar4jaPhi = [4][26][7]int{
+	{
+		{1, 59, 16, 160, 108, 226, 1148},
+		{22, 18, 103, 241, 126, 618, 2032},
+		{0, 52, 105, 185, 238, 404, 249},
+		{26, 23, 0, 251, 481, 32, 1807},
+		{0, 11, 50, 209, 96, 912, 485},
+		{10, 7, 29, 103, 28, 950, 1044},
+		{5, 22, 115, 90, 59, 534, 717},
+		{18, 25, 30, 184, 225, 63, 873},
+		{3, 27, 92, 248, 323, 971, 364},
+		{22, 30, 78, 12, 28, 304, 1926},
+		{3, 43, 70, 111, 386, 409, 1241},
+		{8, 14, 66, 66, 305, 708, 1769},
+		{25, 46, 39, 173, 34, 719, 532},
+		{25, 62, 84, 42, 510, 176, 768},
+		{2, 44, 79, 157, 147, 743, 1138},
+		{27, 12, 70, 174, 199, 759, 965},
+		{7, 38, 29, 104, 347, 674, 141},
+		{7, 47, 32, 144, 391, 958, 1527},
+		{15, 1, 45, 43, 165, 984, 505},
+		{10, 52, 113, 181, 414, 11, 1312},
+		{4, 61, 86, 250, 97, 413, 1840},
+		{19, 10, 1, 202, 158, 925, 709},
+		{7, 55, 42, 68, 86, 687, 1427},
+		{9, 7, 118, 177, 168, 752, 989},
+		{26, 12, 33, 170, 506, 867, 1925},
+		{17, 2, 126, 89, 489, 323, 270},
+	},
+	{
+		{0, 0, 0, 0, 0, 0, 0},
+		{27, 32, 53, 182, 375, 767, 1822},
+		{30, 21, 74, 249, 436, 227, 203},
+		{28, 36, 45, 65, 350, 247, 882},
+		{7, 30, 47, 70, 260, 284, 1989},
+		{1, 29, 0, 141, 84, 370, 957},
+		{8, 44, 59, 237, 318, 482, 1705},
+		{20, 29, 102, 77, 382, 273, 1083},
+		{26, 39, 25, 55, 169, 886, 1072},
+		{24, 14, 3, 12, 213, 634, 354},
+		{4, 22, 88, 227, 67, 762, 1942},
+		{12, 15, 65, 42, 313, 184, 446},
+		{23, 48, 62, 52, 242, 696, 1456},
+		{15, 55, 68, 243, 188, 413, 1940},
+		{15, 39, 91, 179, 1, 854, 1660},
+		{22, 11, 70, 250, 306, 544, 1813},
+		{31, 1, 115, 247, 397, 864, 1800},
+		{3, 50, 31, 164, 80, 82, 1551},
+		{29, 40, 121, 17, 33, 1009, 1337},
+		{21, 62, 45, 31, 7, 437, 1243},
+		{2, 27, 56, 149, 447, 798, 1687},
+		{5, 38, 54, 105, 336, 201, 1058},
+		{11, 40, 108, 183, 424, 118, 1480},
+		{26, 15, 14, 153, 134, 182, 1586},
+		{9, 11, 30, 177, 152, 919, 1577},
+		{17, 18, 116, 19, 492, 949, 1565},
+	},
+	{
+		{0, 0, 0, 0, 0, 0, 0},
+		{12, 46, 8, 35, 219, 254, 1652},
+		{30, 45, 119, 167, 16, 790, 1801},
+		{18, 27, 89, 214, 263, 642, 1230},
+		{10, 48, 31, 84, 415, 248, 1661},
+		{16, 37, 122, 206, 403, 899, 1461},
+		{13, 41, 1, 122, 184, 328, 1226},
+		{9, 13, 69, 67, 279, 518, 1716},
+		{7, 9, 92, 147, 198, 477, 1788},
+		{15, 49, 47, 54, 307, 404, 1305},
+		{16, 36, 11, 23, 432, 698, 1461},
+		{18, 10, 31, 93, 240, 160, 1718},
+		{4, 11, 19, 20, 454, 497, 1626},
+		{23, 18, 66, 197, 294, 100, 1556},
+		{5, 54, 49, 46, 479, 518, 1731},
+		{3, 40, 81, 162, 289, 92, 1617},
+		{29, 27, 96, 101, 373, 464, 1563},
+		{11, 35, 38, 76, 104, 592, 1621},
+		{4, 25, 83, 78, 141, 198, 1646},
+		{8, 46, 42, 253, 270, 856, 1674},
+		{2, 24, 58, 124, 439, 235, 1579},
+		{11, 33, 24, 143, 333, 134, 1690},
+		{11, 18, 25, 63, 399, 542, 1732},
+		{3, 37, 92, 41, 14, 545, 1588},
+		{15, 35, 38, 214, 277, 777, 1767},
+		{13, 21, 120, 70, 412, 483, 1632},
+	},
+	{
+		{0, 0, 0, 0, 0, 0, 0},
+		{13, 44, 35, 162, 312, 285, 1564},
+		{19, 51, 97, 7, 503, 554, 1795},
+		{14, 12, 112, 31, 388, 809, 1658},
+		{15, 15, 64, 164, 48, 185, 1390},
+		{20, 12, 93, 11, 7, 49, 1506},
+		{17, 4, 99, 237, 185, 101, 1770},
+		{4, 7, 94, 125, 328, 642, 1590},
+		{4, 2, 103, 133, 254, 912, 1660},
+		{11, 30, 91, 123, 60, 185, 1595},
+		{17, 53, 3, 84, 364, 393, 1807},
+		{20, 23, 6, 22, 6, 1001, 1644},
+		{8, 29, 6, 46, 364, 339, 1503},
+		{22, 37, 22, 188, 31, 43, 1424},
+		{19, 42, 0, 241, 178, 567, 1815},
+		{15, 48, 33, 143, 251, 1003, 1801},
+		{5, 4, 91, 99, 98, 227, 1404},
+		{21, 10, 101, 99, 402, 404, 1608},
+		{17, 18, 58, 55, 44, 960, 1502},
+		{9, 56, 42, 163, 166, 600, 1409},
+		{20, 9, 35, 107, 47, 1013, 1511},
+		{18, 11, 29, 163, 24, 42, 1302},
+		{31, 25, 30, 172, 395, 226, 1207},
+		{13, 20, 17, 149, 211, 1001, 1109},
+		{2, 4, 14, 44, 339, 412, 1005},
+		{18, 7, 6, 37, 400, 960, 903},
+	},
+}
+
+// ar4jaExtensionCount is the number of column pairs in front of H_1/2
+// for each rate.
+var ar4jaExtensionCount = map[LDPCRate]int{
+	LDPCRate1_2: 0,
+	LDPCRate2_3: 1,
+	LDPCRate4_5: 3,
+}
+
+var (
+	ar4jaOnce  [3][3]sync.Once
+	ar4jaCodes [3][3]*ldpcCode
+)
+
+// ar4ja returns the AR4JA code for rate and an information block of k
+// bits, building its encoder on first use.
+func ar4ja(rate LDPCRate, k int) *ldpcCode {
+	size := bits.TrailingZeros(uint(k))/2 - 5 // 1024, 4096, 16384 -> 0, 1, 2
+	ar4jaOnce[rate][size].Do(func() {
+		ar4jaCodes[rate][size] = buildAR4JA(rate, k)
+	})
+	return ar4jaCodes[rate][size]
+}
+
+// buildAR4JA expands the protograph of the rate into the parity-check
+// matrix of Section 7. Π_k has a 1 in row i and column
+//
+//	π_k(i) = M/4·((θ_k + ⌊4i/M⌋) mod 4) + (φ_k(⌊4i/M⌋, M) + i) mod M/4,
+//
+// so in blocks of z = M/4 bits, block row j of Π_k holds the circulant
+// shifted by φ_k(j, M) in block column (θ_k + j) mod 4.
+func buildAR4JA(rate LDPCRate, k int) *ldpcCode {
+	ext := ar4jaExtensionCount[rate]
+	infoCols := 2 + 2*ext
+	m := k / infoCols
+	ring := newQCRing(m / 4)
+	col := bits.TrailingZeros(uint(m)) - 7 // M = 128 -> 0, ..., 8192 -> 6
+
+	proto := make([][][]int, 3)
+	for r := range proto {
+		for e := ext - 1; e >= 0; e-- {
+			proto[r] = append(proto[r], ar4jaExtensions[e][r][:]...)
+		}
+		proto[r] = append(proto[r], ar4jaBase[r][:]...)
+	}
+
+	h := make([][]qcPoly, 3*4)
+	for i := range h {
+		h[i] = make([]qcPoly, 4*len(proto[0]))
+		for j := range h[i] {
+			h[i][j] = ring.zero()
+		}
+	}
+	for r, row := range proto {
+		for c, perms := range row {
+			for _, p := range perms {
+				for j := range 4 {
+					if p == 0 {
+						h[4*r+j][4*c+j].add(ring.monomial(0))
+						continue
+					}
+					phi := ar4jaPhi[j][p-1][col]
+					h[4*r+j][4*c+(ar4jaTheta[p-1]+j)%4].add(ring.monomial(-phi))
+				}
+			}
+		}
+	}
+
+	code := &ldpcCode{
+		n:     m * len(proto[0]),
+		k:     k,
+		punct: m,
+		ring:  ring,
+	}
+	code.checks = qcChecks(ring, h)
+	gen, ok := qcSystematic(ring, h)
+	if !ok {
+		panic("tmsc: singular AR4JA parity-check matrix")
+	}
+	code.gen = gen
+	code.rank = len(h) * ring.z
+	return code
+}
+
+// NewAR4JACodec returns an LDPCCodec for the AR4JA code of the given rate
+// and Transfer Frame length in bytes: 128, 512 or 2048, for information
+// blocks of 1024, 4096 or 16384 bits. Returns ErrInvalidCodeRate for an
+// unknown rate and ErrInvalidDataLength for any other frame length.
+// Building the encoder takes a moment on first use.
+func NewAR4JACodec(rate LDPCRate, dataLen int, opts ...LDPCOption) (*LDPCCodec, error) {
+	if _, ok := ar4jaExtensionCount[rate]; !ok {
+		return nil, ErrInvalidCodeRate
+	}
+	switch dataLen {
+	case 128, 512, 2048:
+	default:
+		return nil, ErrInvalidDataLength
+	}
+	l := &LDPCCodec{code: ar4ja(rate, 8*dataLen), iterations: ldpcDefaultIterations}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
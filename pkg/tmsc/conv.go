@@ -0,0 +1,526 @@
+package tmsc
+
+// Convolutional coding for CCSDS TM Synchronization and Channel Coding
+// per CCSDS 131.0-B-4 Section 3.
+//
+// The basic code is rate 1/2, constraint length 7, with connection
+// vectors G1 = 1111001 (octal 171) and G2 = 1011011 (octal 133) and
+// symbol inversion on the G2 output. The punctured codes of rates 2/3,
+// 3/4, 5/6 and 7/8 delete symbols of the basic code, without inversion,
+// following the patterns of Table 3-1.
+//
+// Channel symbols are packed MSB first, C1 before C2 for each input bit.
+// The decoder takes soft symbols as int8 log-likelihood ratios: positive
+// values favour a 0 symbol, negative values a 1, and 0 is an erasure.
+
+import "math/bits"
+
+const (
+	convK      = 7    // constraint length
+	convStates = 64   // 2^(K-1) encoder states
+	convG1     = 0x79 // 1111001, leftmost tap is the current input bit
+	convG2     = 0x5B // 1011011
+	convTail   = convK - 1
+)
+
+// ConvRate identifies a CCSDS convolutional code rate.
+type ConvRate int
+
+// Supported convolutional code rates.
+const (
+	ConvRate1_2 ConvRate = iota // basic rate-1/2 code
+	ConvRate2_3                 // punctured rate 2/3
+	ConvRate3_4                 // punctured rate 3/4
+	ConvRate5_6                 // punctured rate 5/6
+	ConvRate7_8                 // punctured rate 7/8
+)
+
+// String returns the rate as a fraction, such as "1/2".
+func (r ConvRate) String() string {
+	switch r {
+	case ConvRate1_2:
+		return "1/2"
+	case ConvRate2_3:
+		return "2/3"
+	case ConvRate3_4:
+		return "3/4"
+	case ConvRate5_6:
+		return "5/6"
+	case ConvRate7_8:
+		return "7/8"
+	}
+	return "unknown"
+}
+
+// convPuncture holds the puncturing patterns of Table 3-1: for each input
+// bit in the period, whether its C1 and C2 symbols are transmitted.
+var convPuncture = map[ConvRate][2][]bool{
+	ConvRate1_2: {{true}, {true}},
+	ConvRate2_3: {{true, false}, {true, true}},
+	ConvRate3_4: {{true, false, true}, {true, true, false}},
+	ConvRate5_6: {{true, false, true, false, true}, {true, true, false, true, false}},
+	ConvRate7_8: {{true, false, false, false, true, false, true}, {true, true, true, true, false, true, false}},
+}
+
+// ConvCodec encodes and decodes a CCSDS convolutional code.
+type ConvCodec struct {
+	rate    ConvRate
+	c1, c2  []bool    // puncturing pattern
+	invert  bool      // invert the G2 output symbols
+	tail    bool      // terminate each block with K-1 zero bits
+	outputs [128]byte // C1<<1 | C2 for each 7-bit encoder window
+}
+
+// ConvOption configures a ConvCodec.
+type ConvOption func(*ConvCodec)
+
+// WithSymbolInversion sets whether the G2 output symbols are inverted.
+// CCSDS inverts them for the basic rate-1/2 code only, which is the
+// default.
+func WithSymbolInversion(invert bool) ConvOption {
+	return func(c *ConvCodec) {
+		c.invert = invert
+	}
+}
+
+// WithTail sets whether each block is terminated with K-1 = 6 zero bits,
+// returning the encoder to the zero state. The default is true. Without
+// a tail the decoder traces back from the best final state, so the last
+// bits of a block are less reliable; use it for a continuous stream
+// decoded in one call.
+func WithTail(tail bool) ConvOption {
+	return func(c *ConvCodec) {
+		c.tail = tail
+	}
+}
+
+// NewConvCodec returns a ConvCodec for the given rate. It returns
+// ErrInvalidCodeRate for an unknown rate.
+func NewConvCodec(rate ConvRate, opts ...ConvOption) (*ConvCodec, error) {
+	p, ok := convPuncture[rate]
+	if !ok {
+		return nil, ErrInvalidCodeRate
+	}
+	c := &ConvCodec{
+		rate:   rate,
+		c1:     p[0],
+		c2:     p[1],
+		invert: rate == ConvRate1_2,
+		tail:   true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	for w := range c.outputs {
+		g1 := byte(bits.OnesCount8(uint8(w)&convG1) & 1)
+		g2 := byte(bits.OnesCount8(uint8(w)&convG2) & 1)
+		if c.invert {
+			g2 ^= 1
+		}
+		c.outputs[w] = g1<<1 | g2
+	}
+	return c, nil
+}
+
+// Rate returns the code rate.
+func (c *ConvCodec) Rate() ConvRate { return c.rate }
+
+// steps returns the number of trellis steps for n data bytes.
+func (c *ConvCodec) steps(n int) int {
+	if c.tail {
+		return 8*n + convTail
+	}
+	return 8 * n
+}
+
+// symbols returns the number of channel symbols produced by the first
+// steps input bits.
+func (c *ConvCodec) symbols(steps int) int {
+	period := len(c.c1)
+	n := 0
+	for i := range period {
+		count := steps / period
+		if i < steps%period {
+			count++
+		}
+		if c.c1[i] {
+			n += count
+		}
+		if c.c2[i] {
+			n += count
+		}
+	}
+	return n
+}
+
+// EncodedSymbols returns the number of channel symbols Encode produces
+// for n data bytes, before padding to a whole byte.
+func (c *ConvCodec) EncodedSymbols(n int) int {
+	return c.symbols(c.steps(n))
+}
+
+// Encode convolutionally encodes data, starting from the zero state, and
+// returns the channel symbols packed MSB first. The last byte is padded
+// with zero bits. The input slice is not modified.
+func (c *ConvCodec) Encode(data []byte) []byte {
+	e := c.NewEncoder()
+	out := e.encode(nil, data, c.steps(len(data)))
+	return append(out, e.Flush()...)
+}
+
+// Decode runs a soft-decision Viterbi decoder over channel symbols given
+// as int8 log-likelihood ratios, one per symbol. Decoding starts in the
+// zero state and, with a tail, ends in it. It returns the decoded data,
+// as many whole bytes as the symbols carry, and the path metric of the
+// decoded path: the sum of |LLR| over the symbols that disagree with it.
+// A metric of 0 means every symbol matched; with HardSymbols input, the
+// metric is 127 times the number of symbol errors corrected.
+// Symbols past the end of the last whole byte, such as padding, are
+// ignored. Returns ErrTooFewSymbols if not even one byte can be decoded.
+func (c *ConvCodec) Decode(symbols []int8) ([]byte, int, error) {
+	n := c.decodable(len(symbols))
+	if n == 0 {
+		return nil, 0, ErrTooFewSymbols
+	}
+	steps := c.steps(n)
+	period := len(c.c1)
+
+	metrics := initialMetrics()
+	decisions := make([]uint64, steps)
+	pos := 0
+	for i := range steps {
+		var pen [2][2]int
+		pen, pos = c.penalties(symbols, pos, i%period)
+		decisions[i] = c.acs(&metrics, pen)
+	}
+
+	state := 0
+	if !c.tail {
+		state = bestState(&metrics)
+	}
+	metric := metrics[state]
+
+	out := make([]byte, n)
+	traceback(decisions, state, func(i int, b byte) {
+		if i < 8*n {
+			out[i/8] |= b << (7 - i%8)
+		}
+	})
+	return out, metric, nil
+}
+
+// convInf marks an unreachable trellis state.
+const convInf = int(^uint(0) >> 2)
+
+// initialMetrics returns the path metrics of a decoder in the zero state.
+func initialMetrics() [convStates]int {
+	var m [convStates]int
+	for s := range m {
+		m[s] = convInf
+	}
+	m[0] = 0
+	return m
+}
+
+// penalties returns the metric penalties of C1 and C2 being 0 or 1 at a
+// trellis step with the given puncturing phase, reading its symbols from
+// symbols[pos:], and the position of the next step's symbols. Punctured
+// symbols cost nothing either way.
+func (c *ConvCodec) penalties(symbols []int8, pos, phase int) ([2][2]int, int) {
+	var pen [2][2]int
+	if c.c1[phase] {
+		pen[0] = symbolPenalty(symbols[pos])
+		pos++
+	}
+	if c.c2[phase] {
+		pen[1] = symbolPenalty(symbols[pos])
+		pos++
+	}
+	return pen, pos
+}
+
+// stepSymbols returns the number of channel symbols sent for a trellis
+// step with the given puncturing phase.
+func (c *ConvCodec) stepSymbols(phase int) int {
+	n := 0
+	if c.c1[phase] {
+		n++
+	}
+	if c.c2[phase] {
+		n++
+	}
+	return n
+}
+
+// acs runs one add-compare-select step of the Viterbi decoder on
+// metrics and returns the survivor decision of each state: bit s is the
+// low bit of the predecessor of state s.
+func (c *ConvCodec) acs(metrics *[convStates]int, pen [2][2]int) uint64 {
+	var next [convStates]int
+	var dec uint64
+	for ns := range convStates {
+		b := ns >> (convTail - 1)
+		best, choice := convInf, 0
+		for x := range 2 {
+			ps := (ns<<1)&(convStates-1) | x
+			if metrics[ps] == convInf {
+				continue
+			}
+			sym := c.outputs[b<<(convK-1)|ps]
+			m := metrics[ps] + pen[0][sym>>1] + pen[1][sym&1]
+			if m < best {
+				best, choice = m, x
+			}
+		}
+		next[ns] = best
+		dec |= uint64(choice) << ns
+	}
+	*metrics = next
+	return dec
+}
+
+// bestState returns the state with the smallest path metric.
+func bestState(metrics *[convStates]int) int {
+	state := 0
+	for s := range convStates {
+		if metrics[s] < metrics[state] {
+			state = s
+		}
+	}
+	return state
+}
+
+// traceback follows the survivor decisions back from state at the last
+// step and calls emit with the decided input bit of each step, last step
+// first.
+func traceback(decisions []uint64, state int, emit func(i int, b byte)) {
+	for i := len(decisions) - 1; i >= 0; i-- {
+		emit(i, byte(state>>(convTail-1)))
+		x := int(decisions[i]>>state) & 1
+		state = (state<<1)&(convStates-1) | x
+	}
+}
+
+// ConvEncoder encodes a continuous, unterminated convolutional stream,
+// as CCSDS specifies for a TM downlink. The encoder state and the
+// puncturing phase carry from one call to the next, so encoding data in
+// pieces gives the same symbols as encoding it at once.
+type ConvEncoder struct {
+	c     *ConvCodec
+	state int  // last K-1 input bits
+	phase int  // position in the puncturing period
+	acc   byte // channel symbols not yet forming a byte
+	nacc  int
+}
+
+// NewEncoder returns a ConvEncoder for the code, in the zero state. The
+// stream is never terminated, whatever the WithTail setting.
+func (c *ConvCodec) NewEncoder() *ConvEncoder {
+	return &ConvEncoder{c: c}
+}
+
+// Encode encodes data, continuing the stream, and returns the channel
+// symbols that complete whole bytes, packed MSB first. Symbols that do
+// not fill a byte are held for the next call or Flush.
+func (e *ConvEncoder) Encode(data []byte) []byte {
+	return e.encode(nil, data, 8*len(data))
+}
+
+// Flush ends the stream. It returns the held symbols padded with zero
+// bits to a whole byte, or nil if there are none, and returns the
+// encoder to the zero state.
+func (e *ConvEncoder) Flush() []byte {
+	var out []byte
+	if e.nacc > 0 {
+		out = []byte{e.acc}
+	}
+	*e = ConvEncoder{c: e.c}
+	return out
+}
+
+// encode appends to out the packed symbols of the first steps input
+// bits of data, zero bits past its end.
+func (e *ConvEncoder) encode(out, data []byte, steps int) []byte {
+	emit := func(sym byte) {
+		e.acc |= sym << (7 - e.nacc)
+		e.nacc++
+		if e.nacc == 8 {
+			out = append(out, e.acc)
+			e.acc, e.nacc = 0, 0
+		}
+	}
+	for i := range steps {
+		var b int
+		if i < 8*len(data) {
+			b = int(data[i/8]>>(7-i%8)) & 1
+		}
+		e.push(b, emit)
+	}
+	return out
+}
+
+// push shifts input bit b into the encoder and passes the transmitted
+// symbols of the step to emit.
+func (e *ConvEncoder) push(b int, emit func(sym byte)) {
+	w := b<<(convK-1) | e.state
+	sym := e.c.outputs[w]
+	if e.c.c1[e.phase] {
+		emit(sym >> 1)
+	}
+	if e.c.c2[e.phase] {
+		emit(sym & 1)
+	}
+	e.state = w >> 1
+	e.phase = (e.phase + 1) % len(e.c.c1)
+}
+
+// convTraceback is the number of trellis steps a ConvDecoder receives
+// after a bit before deciding it: enough for the survivor paths of the
+// punctured rates to merge.
+const convTraceback = 128
+
+// ConvDecoder is a streaming soft-decision Viterbi decoder for a
+// continuous, unterminated convolutional stream. It decides each bit
+// once convTraceback = 128 further trellis steps have been received,
+// tracing back from the best state, so a stream of any length is
+// decoded in bounded memory.
+type ConvDecoder struct {
+	c         *ConvCodec
+	metrics   [convStates]int
+	decisions []uint64 // survivor decisions of the steps not yet decided
+	phase     int      // puncturing position of the next step
+	held      []int8   // symbols of an incomplete step
+	acc       byte     // decided bits not yet forming a byte
+	nacc      int
+}
+
+// NewDecoder returns a ConvDecoder for the code, in the zero state.
+func (c *ConvCodec) NewDecoder() *ConvDecoder {
+	return &ConvDecoder{c: c, metrics: initialMetrics()}
+}
+
+// Decode feeds channel symbols, int8 log-likelihood ratios as for
+// ConvCodec.Decode, continuing the stream. It returns the data bytes
+// decided so far; bits are decided convTraceback steps behind the last
+// symbol, so the output lags the input.
+func (d *ConvDecoder) Decode(symbols []int8) []byte {
+	var out []byte
+	symbols = append(d.held, symbols...)
+	pos := 0
+	for len(symbols)-pos >= d.c.stepSymbols(d.phase) {
+		var pen [2][2]int
+		pen, pos = d.c.penalties(symbols, pos, d.phase)
+		d.decisions = append(d.decisions, d.c.acs(&d.metrics, pen))
+		d.phase = (d.phase + 1) % len(d.c.c1)
+
+		if len(d.decisions) == 2*convTraceback {
+			out = d.decide(out, convTraceback)
+			// Keep the metrics small on an endless stream.
+			low := d.metrics[bestState(&d.metrics)]
+			for s := range d.metrics {
+				d.metrics[s] -= low
+			}
+		}
+	}
+	d.held = append([]int8(nil), symbols[pos:]...)
+	return out
+}
+
+// Flush ends the stream. It decides the remaining bits, tracing back
+// from the best final state, and returns those that complete whole
+// bytes; the last bits of an unterminated stream are less reliable than
+// the rest. The decoder returns to the zero state.
+func (d *ConvDecoder) Flush() []byte {
+	out := d.decide(nil, len(d.decisions))
+	*d = ConvDecoder{c: d.c, metrics: initialMetrics()}
+	return out
+}
+
+// decide traces back from the best state and appends the first n
+// undecided bits to out as they complete bytes.
+func (d *ConvDecoder) decide(out []byte, n int) []byte {
+	bits := make([]byte, n)
+	traceback(d.decisions, bestState(&d.metrics), func(i int, b byte) {
+		if i < n {
+			bits[i] = b
+		}
+	})
+	for _, b := range bits {
+		d.acc |= b << (7 - d.nacc)
+		d.nacc++
+		if d.nacc == 8 {
+			out = append(out, d.acc)
+			d.acc, d.nacc = 0, 0
+		}
+	}
+	d.decisions = append(d.decisions[:0], d.decisions[n:]...)
+	return out
+}
+
+// decodable returns the number of whole data bytes carried by the given
+// number of channel symbols.
+func (c *ConvCodec) decodable(symbols int) int {
+	n := 0
+	for c.symbols(c.steps(n+1)) <= symbols {
+		n++
+	}
+	return n
+}
+
+// symbolPenalty returns the metric penalties of a soft symbol being 0
+// and being 1.
+func symbolPenalty(llr int8) [2]int {
+	if llr >= 0 {
+		return [2]int{0, int(llr)}
+	}
+	return [2]int{-int(llr), 0}
+}
+
+// HardSymbols converts packed hard-decision channel symbols, MSB first,
+// into soft symbols for ConvCodec.Decode: +127 for a 0 bit and -127 for
+// a 1 bit.
+func HardSymbols(data []byte) []int8 {
+	out := make([]int8, 8*len(data))
+	for i := range out {
+		if data[i/8]>>(7-i%8)&1 == 0 {
+			out[i] = 127
+		} else {
+			out[i] = -127
+		}
+	}
+	return out
+}
+
+// QuantizedSymbols converts offset-binary soft symbols of the given
+// width, one per byte, into soft symbols for ConvCodec.Decode. A value
+// of 0 is the most confident 0 symbol and 2^width-1 the most confident
+// 1, as produced by common receivers with 3-bit or 8-bit soft outputs.
+// Returns ErrInvalidSymbolWidth unless 1 <= width <= 8.
+func QuantizedSymbols(q []byte, width int) ([]int8, error) {
+	if width < 1 || width > 8 {
+		return nil, ErrInvalidSymbolWidth
+	}
+	top := 1<<width - 1
+	out := make([]int8, len(q))
+	for i, v := range q {
+		v := min(int(v), top)
+		out[i] = int8((top - 2*v) * 127 / top)
+	}
+	return out, nil
+}
+
+// ByteReliability returns the reliability of each byte of soft symbols,
+// eight per byte MSB first: the smallest symbol magnitude, 0 for a byte
+// with an erased symbol up to 128. A trailing partial byte is ignored.
+// The result suits RSCodec.DecodeWithReliability.
+func ByteReliability(symbols []int8) []byte {
+	out := make([]byte, len(symbols)/8)
+	for i := range out {
+		r := 128
+		for _, s := range symbols[8*i : 8*i+8] {
+			r = min(r, max(int(s), -int(s)))
+		}
+		out[i] = byte(r)
+	}
+	return out
+}
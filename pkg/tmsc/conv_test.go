@@ -0,0 +1,388 @@
+package tmsc_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/tmsc"
+)
+
+var convRates = []tmsc.ConvRate{
+	tmsc.ConvRate1_2, tmsc.ConvRate2_3, tmsc.ConvRate3_4, tmsc.ConvRate5_6, tmsc.ConvRate7_8,
+}
+
+func newConv(t *testing.T, rate tmsc.ConvRate, opts ...tmsc.ConvOption) *tmsc.ConvCodec {
+	t.Helper()
+	c, err := tmsc.NewConvCodec(rate, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// awgn returns BPSK soft symbols for packed channel symbols received
+// with Gaussian noise of standard deviation sigma.
+func awgn(rng *rand.Rand, data []byte, sigma float64) []int8 {
+	out := make([]int8, 8*len(data))
+	for i := range out {
+		s := 1.0
+		if data[i/8]>>(7-i%8)&1 == 1 {
+			s = -1
+		}
+		v := math.Round((s + sigma*rng.NormFloat64()) * 48)
+		out[i] = int8(max(-127, min(127, v)))
+	}
+	return out
+}
+
+func TestConv_ImpulseResponse(t *testing.T) {
+	// A single 1 followed by the tail produces G1 and G2 interleaved:
+	// 11 10 11 11 00 01 11, then zeros.
+	c := newConv(t, tmsc.ConvRate1_2, tmsc.WithSymbolInversion(false))
+	got := c.Encode([]byte{0x80})
+	want := []byte{0xEF, 0x1C, 0x00, 0x00}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Encode = %x, want %x", got, want)
+	}
+
+	// Inversion flips every C2 symbol of the 28 encoded symbols.
+	c = newConv(t, tmsc.ConvRate1_2)
+	got = c.Encode([]byte{0x80})
+	want = []byte{0xEF ^ 0x55, 0x1C ^ 0x55, 0x55, 0x50}
+	if !bytes.Equal(got, want) {
+		t.Errorf("inverted Encode = %x, want %x", got, want)
+	}
+}
+
+func TestConv_EncodedSymbols(t *testing.T) {
+	tests := []struct {
+		rate tmsc.ConvRate
+		want int
+	}{
+		{tmsc.ConvRate1_2, 28}, // 14 bits x 2
+		{tmsc.ConvRate2_3, 21}, // 7 periods of 3
+		{tmsc.ConvRate3_4, 19}, // 4 periods of 4, plus 2 bits -> 3
+		{tmsc.ConvRate5_6, 17}, // 2 periods of 6, plus 4 bits -> 5
+		{tmsc.ConvRate7_8, 16}, // 2 periods of 8
+	}
+	for _, tt := range tests {
+		c := newConv(t, tt.rate)
+		if got := c.EncodedSymbols(1); got != tt.want {
+			t.Errorf("rate %s: EncodedSymbols(1) = %d, want %d", tt.rate, got, tt.want)
+		}
+		if got := len(c.Encode([]byte{0xA5})); got != (tt.want+7)/8 {
+			t.Errorf("rate %s: encoded %d bytes", tt.rate, got)
+		}
+	}
+}
+
+func TestConv_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 0))
+	data := make([]byte, 64)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	for _, rate := range convRates {
+		for _, tail := range []bool{true, false} {
+			c := newConv(t, rate, tmsc.WithTail(tail))
+			got, metric, err := c.Decode(tmsc.HardSymbols(c.Encode(data)))
+			if err != nil {
+				t.Fatalf("rate %s tail %v: %v", rate, tail, err)
+			}
+			if metric != 0 {
+				t.Errorf("rate %s tail %v: metric %d, want 0", rate, tail, metric)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("rate %s tail %v: decoded data differs", rate, tail)
+			}
+		}
+	}
+}
+
+func TestConv_CorrectHardErrors(t *testing.T) {
+	data := []byte("convolutional coding corrects scattered symbol errors")
+	for _, rate := range convRates {
+		c := newConv(t, rate)
+		symbols := tmsc.HardSymbols(c.Encode(data))
+		// Flip one symbol in every 100; even rate 7/8 corrects them.
+		nerrs := 0
+		for i := 10; i < c.EncodedSymbols(len(data)); i += 100 {
+			symbols[i] = -symbols[i]
+			nerrs++
+		}
+		got, metric, err := c.Decode(symbols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("rate %s: decoded %q", rate, got)
+		}
+		if metric != 127*nerrs {
+			t.Errorf("rate %s: metric %d, want %d", rate, metric, 127*nerrs)
+		}
+	}
+}
+
+func TestConvEncoder_Stream(t *testing.T) {
+	// Encoding in pieces gives the symbols of one unterminated block.
+	rng := rand.New(rand.NewPCG(8, 0))
+	data := make([]byte, 301)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	for _, rate := range convRates {
+		c := newConv(t, rate, tmsc.WithTail(false))
+		want := c.Encode(data)
+		e := c.NewEncoder()
+		var got []byte
+		for rest := data; len(rest) > 0; {
+			n := min(len(rest), 1+rng.IntN(40))
+			got = append(got, e.Encode(rest[:n])...)
+			rest = rest[n:]
+		}
+		got = append(got, e.Flush()...)
+		if !bytes.Equal(got, want) {
+			t.Errorf("rate %s: streamed symbols differ from the block encoding", rate)
+		}
+
+		// Flush returns the encoder to the zero state.
+		if again := append(e.Encode(data), e.Flush()...); !bytes.Equal(again, want) {
+			t.Errorf("rate %s: encoder not reset by Flush", rate)
+		}
+	}
+}
+
+func TestConvDecoder_Stream(t *testing.T) {
+	rng := rand.New(rand.NewPCG(9, 0))
+	data := make([]byte, 2000)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	for _, rate := range convRates {
+		c := newConv(t, rate, tmsc.WithTail(false))
+		symbols := tmsc.HardSymbols(c.Encode(data))[:c.EncodedSymbols(len(data))]
+		for i := 10; i < len(symbols)-100; i += 100 {
+			symbols[i] = -symbols[i]
+		}
+
+		d := c.NewDecoder()
+		var got []byte
+		for rest := symbols; len(rest) > 0; {
+			n := min(len(rest), 1+rng.IntN(3000))
+			out := d.Decode(rest[:n])
+			if len(got)+len(out) > len(data) {
+				t.Fatalf("rate %s: decoded past the data", rate)
+			}
+			got = append(got, out...)
+			rest = rest[n:]
+		}
+		if len(got) == 0 || len(got) == len(data) {
+			t.Errorf("rate %s: decoded %d bytes before Flush", rate, len(got))
+		}
+		got = append(got, d.Flush()...)
+		if !bytes.Equal(got, data) {
+			t.Errorf("rate %s: streamed decoding differs from the data", rate)
+		}
+
+		// Flush returns the decoder to the zero state.
+		if again := append(d.Decode(symbols), d.Flush()...); !bytes.Equal(again, data) {
+			t.Errorf("rate %s: decoder not reset by Flush", rate)
+		}
+	}
+}
+
+func TestConv_SoftDecision(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 0))
+	data := make([]byte, 200)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	c := newConv(t, tmsc.ConvRate1_2)
+	symbols := awgn(rng, c.Encode(data), 0.6)
+
+	// Hard decisions on these symbols leave errors in the channel.
+	hard := 0
+	for i, s := range tmsc.HardSymbols(c.Encode(data))[:c.EncodedSymbols(len(data))] {
+		if (s > 0) != (symbols[i] >= 0) {
+			hard++
+		}
+	}
+	if hard == 0 {
+		t.Fatal("channel introduced no errors")
+	}
+
+	got, metric, err := c.Decode(symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("soft decoding left errors (%d channel symbol errors)", hard)
+	}
+	if metric == 0 {
+		t.Error("metric 0 for a noisy channel")
+	}
+}
+
+func TestConv_Erasures(t *testing.T) {
+	data := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	c := newConv(t, tmsc.ConvRate1_2)
+	symbols := tmsc.HardSymbols(c.Encode(data))
+	for i := 0; i < len(symbols); i += 4 {
+		symbols[i] = 0
+	}
+	got, metric, err := c.Decode(symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) || metric != 0 {
+		t.Errorf("Decode = %x, metric %d", got, metric)
+	}
+}
+
+func TestConv_IgnoresPadding(t *testing.T) {
+	// Symbols short of another whole byte do not add one.
+	c := newConv(t, tmsc.ConvRate7_8)
+	data := []byte{1, 2, 3}
+	symbols := tmsc.HardSymbols(c.Encode(data))[:c.EncodedSymbols(3)]
+	symbols = append(symbols, make([]int8, c.EncodedSymbols(4)-c.EncodedSymbols(3)-1)...)
+	got, _, err := c.Decode(symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Decode = %x, want %x", got, data)
+	}
+}
+
+func TestConv_InversionMismatch(t *testing.T) {
+	data := []byte("symbol inversion")
+	enc := newConv(t, tmsc.ConvRate1_2)
+	dec := newConv(t, tmsc.ConvRate1_2, tmsc.WithSymbolInversion(false))
+	got, _, err := dec.Decode(tmsc.HardSymbols(enc.Encode(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got, data) {
+		t.Error("decoding without inversion should not recover inverted symbols")
+	}
+}
+
+func TestConv_Errors(t *testing.T) {
+	if _, err := tmsc.NewConvCodec(tmsc.ConvRate(9)); !errors.Is(err, tmsc.ErrInvalidCodeRate) {
+		t.Errorf("NewConvCodec: got %v, want ErrInvalidCodeRate", err)
+	}
+	c := newConv(t, tmsc.ConvRate1_2)
+	if _, _, err := c.Decode(make([]int8, 27)); !errors.Is(err, tmsc.ErrTooFewSymbols) {
+		t.Errorf("Decode: got %v, want ErrTooFewSymbols", err)
+	}
+}
+
+func TestConvRate_String(t *testing.T) {
+	want := []string{"1/2", "2/3", "3/4", "5/6", "7/8"}
+	for i, rate := range convRates {
+		if rate.String() != want[i] {
+			t.Errorf("String() = %q, want %q", rate.String(), want[i])
+		}
+	}
+}
+
+func TestQuantizedSymbols(t *testing.T) {
+	got, err := tmsc.QuantizedSymbols([]byte{0, 3, 4, 7, 9}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int8{127, 18, -18, -127, -127}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("QuantizedSymbols = %v, want %v", got, want)
+			break
+		}
+	}
+
+	got, _ = tmsc.QuantizedSymbols([]byte{0, 255}, 8)
+	if got[0] != 127 || got[1] != -127 {
+		t.Errorf("8-bit QuantizedSymbols = %v", got)
+	}
+
+	for _, width := range []int{0, 9} {
+		if _, err := tmsc.QuantizedSymbols(nil, width); !errors.Is(err, tmsc.ErrInvalidSymbolWidth) {
+			t.Errorf("width %d: got %v, want ErrInvalidSymbolWidth", width, err)
+		}
+	}
+}
+
+func TestByteReliability(t *testing.T) {
+	symbols := tmsc.HardSymbols([]byte{0x0F, 0xA5, 0x00})
+	symbols[9] = -20
+	symbols[14] = 5
+	symbols[16] = 0
+	got := tmsc.ByteReliability(append(symbols, -128, -128))
+	want := []byte{127, 5, 0}
+	if len(got) != len(want) {
+		t.Fatalf("ByteReliability = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ByteReliability = %v, want %v", got, want)
+			break
+		}
+	}
+
+	// The magnitude of -128 does not fit an int8.
+	if got := tmsc.ByteReliability([]int8{-128, -128, -128, -128, -128, -128, -128, -128}); got[0] != 128 {
+		t.Errorf("ByteReliability(-128) = %d, want 128", got[0])
+	}
+}
+
+func TestConv_ConcatenatedChain(t *testing.T) {
+	// RS(255,223) with interleave depth 5 as outer code, randomized and
+	// wrapped into a CADU, then convolutionally encoded as inner code.
+	rng := rand.New(rand.NewPCG(42, 0))
+	rs := tmsc.NewRS255_223()
+	const depth = 5
+	data := make([]byte, depth*rs.DataLen())
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	block, err := rs.EncodeInterleaved(data, depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cadu := tmsc.WrapCADU(block, nil, true)
+
+	c := newConv(t, tmsc.ConvRate1_2)
+	symbols := awgn(rng, c.Encode(cadu), 0.8)
+	decoded, _, err := c.Decode(symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Viterbi errors come in short bursts; interleaving spreads them
+	// across the RS codewords.
+	burst := 0
+	for i := range cadu {
+		if decoded[i] != cadu[i] {
+			burst++
+		}
+	}
+	if burst == 0 {
+		t.Fatal("Viterbi output has no errors for the outer code to correct")
+	}
+	frame, err := tmsc.UnwrapCADU(decoded, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, corrected, err := rs.DecodeInterleaved(frame, depth)
+	if err != nil {
+		t.Fatalf("RS decode after %d byte errors: %v", burst, err)
+	}
+	if corrected == 0 {
+		t.Error("RS corrected nothing")
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("concatenated chain did not recover the data")
+	}
+}
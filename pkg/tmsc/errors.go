@@ -1,6 +1,10 @@
 package tmsc
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/ravisuhag/astro/pkg/rs"
+)
 
 var (
 	// ErrDataTooShort indicates the provided CADU is too short to contain the ASM.
@@ -9,12 +13,31 @@ var (
 	// ErrSyncMarkerMismatch indicates the CADU does not start with the expected ASM.
 	ErrSyncMarkerMismatch = errors.New("attached sync marker mismatch")
 
-	// ErrInvalidDataLength indicates the data length does not match the RS code parameters.
-	ErrInvalidDataLength = errors.New("data length does not match RS code parameters")
+	// ErrInvalidDataLength indicates the data length does not match the code parameters.
+	ErrInvalidDataLength = errors.New("data length does not match code parameters")
 
 	// ErrInvalidInterleaveDepth indicates an unsupported interleaving depth.
 	ErrInvalidInterleaveDepth = errors.New("unsupported interleaving depth: must be 1, 2, 3, 4, 5, or 8")
 
 	// ErrUncorrectable indicates the codeword has more errors than the code can correct.
-	ErrUncorrectable = errors.New("uncorrectable errors: exceeds RS correction capability")
+	ErrUncorrectable = rs.ErrUncorrectable
+
+	// ErrInvalidErasure indicates an erasure position outside the RS codeword.
+	ErrInvalidErasure = rs.ErrInvalidErasure
+
+	// ErrInvalidCorrection indicates an RS error correction capability other than 8 or 16.
+	ErrInvalidCorrection = errors.New("unsupported RS error correction capability: must be 8 or 16")
+
+	// ErrInvalidCodeRate indicates an unsupported convolutional or turbo code rate.
+	ErrInvalidCodeRate = errors.New("unsupported code rate")
+
+	// ErrTooFewSymbols indicates there are too few channel symbols to decode a byte.
+	ErrTooFewSymbols = errors.New("too few channel symbols to decode")
+
+	// ErrInvalidSymbolWidth indicates a soft symbol width outside 1 to 8 bits.
+	ErrInvalidSymbolWidth = errors.New("soft symbol width must be 1 to 8 bits")
+
+	// ErrNotConverged indicates an iterative LDPC or turbo decoder reached its
+	// iteration limit without settling on a valid codeword.
+	ErrNotConverged = errors.New("decoder did not converge")
 )
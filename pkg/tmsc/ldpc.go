@@ -0,0 +1,400 @@
+package tmsc
+
+// LDPC coding for CCSDS TM Synchronization and Channel Coding
+// per CCSDS 131.0-B-4 Section 7.
+//
+// The C2 code is built from a 2x16 array of 511x511 circulants of
+// weight 2 (Table 7-1), giving an (8176,7154) code. It is shortened to
+// (8160,7136) by 18 bits of virtual fill at the start of the information
+// block, which are zero and not transmitted, and 2 zero bits appended
+// after the 8158 transmitted codeword bits.
+//
+// The AR4JA codes for deep-space links (Section 7.4) are built from a
+// protograph at rates 1/2, 2/3 and 4/5, expanded by sums of M×M
+// permutation matrices, for information blocks of 1024, 4096 and 16384
+// bits. The last M codeword bits are punctured.
+//
+// Both families are quasi-cyclic, and their systematic encoders are
+// generators made of circulants, derived from the parity-check matrix
+// on first use.
+//
+// Decoding is normalized min-sum belief propagation on int8 soft
+// symbols, with early termination as soon as all parity checks hold.
+
+import "sync"
+
+const (
+	ldpcDefaultIterations = 50
+	ldpcNormalization     = 0.75 // min-sum check message scaling
+	ldpcKnownLLR          = 1000 // confidence of virtual fill bits
+)
+
+// c2Circulants lists the positions of the two 1s in the first row of each
+// circulant A(i,j) of the C2 parity-check matrix.
+var c2Circulants = [2][16][2]int{
+	{
+		{0, 176}, {12, 239}, {0, 352}, {24, 431}, {0, 392}, {151, 409}, {0, 351}, {9, 359},
+		{0, 307}, {53, 329}, {0, 207}, {18, 281}, {0, 399}, {202, 457}, {0, 247}, {36, 261},
+	},
+	{
+		{99, 471}, {130, 473}, {198, 435}, {260, 478}, {215, 420}, {282, 481}, {48, 396}, {193, 445},
+		{273, 430}, {302, 451}, {96, 379}, {191, 386}, {244, 467}, {364, 470}, {51, 382}, {192, 414},
+	},
+}
+
+// ldpcCode is a systematic binary quasi-cyclic LDPC code with its
+// parity-check matrix and a precomputed encoder.
+type ldpcCode struct {
+	n, k   int        // codeword and information bits, punctured bits included
+	fill   int        // leading information bits of virtual fill
+	punct  int        // trailing codeword bits that are not transmitted
+	pad    int        // zero bits appended after the transmitted codeword
+	checks [][]int32  // variable indices of each parity check
+	ring   qcRing     // circulants of z×z bits
+	gen    [][]qcPoly // parity block j is the sum over information blocks i of gen[i][j] times block i
+	rank   int        // rank of the parity-check matrix
+}
+
+var (
+	c2Once sync.Once
+	c2Code *ldpcCode
+)
+
+// c2 returns the C2 code, building its encoder on first use.
+func c2() *ldpcCode {
+	c2Once.Do(func() {
+		ring := newQCRing(511)
+		h := make([][]qcPoly, len(c2Circulants))
+		for i, row := range c2Circulants {
+			h[i] = make([]qcPoly, len(row))
+			for j, pos := range row {
+				h[i][j] = ring.zero()
+				for _, p := range pos {
+					h[i][j].add(ring.monomial(-p))
+				}
+			}
+		}
+		c := &ldpcCode{n: 16 * ring.z, k: 14 * ring.z, fill: 18, pad: 2, ring: ring}
+		c.checks = qcChecks(ring, h)
+		c.buildC2Encoder()
+		c2Code = c
+	})
+	return c2Code
+}
+
+// buildC2Encoder derives the generator circulants of the C2 code. Its
+// parity-check matrix has rank 1020 over the 1022 parity columns, so the
+// parity for each information block is found by row reduction over
+// GF(2): solving for the first bit of block i gives the first row of the
+// circulants gen[i][j], with the 2 parity bits that the matrix leaves
+// undetermined set to zero. Every row of the generator is a cyclic shift
+// of these, so the code is quasi-cyclic like the parity-check matrix.
+func (c *ldpcCode) buildC2Encoder() {
+	z := c.ring.z
+	words := (c.n + 63) / 64
+	rows := make([][]uint64, len(c.checks))
+	for i, check := range c.checks {
+		rows[i] = make([]uint64, words)
+		for _, v := range check {
+			rows[i][v/64] ^= 1 << (v % 64)
+		}
+	}
+	pivots := make(map[int][]uint64)
+	for col := c.k; col < c.n; col++ {
+		w, bit := col/64, uint64(1)<<(col%64)
+		pivot := -1
+		for r := c.rank; r < len(rows); r++ {
+			if rows[r][w]&bit != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		rows[c.rank], rows[pivot] = rows[pivot], rows[c.rank]
+		for r := range rows {
+			if r != c.rank && rows[r][w]&bit != 0 {
+				for i := range words {
+					rows[r][i] ^= rows[c.rank][i]
+				}
+			}
+		}
+		pivots[col] = rows[c.rank]
+		c.rank++
+	}
+
+	// Each reduced row sets its pivot parity bit to the parity of the
+	// information bits it covers; for the first bit of block i that is
+	// the row's bit at column i·z.
+	c.gen = make([][]qcPoly, c.k/z)
+	for i := range c.gen {
+		c.gen[i] = make([]qcPoly, (c.n-c.k)/z)
+		for j := range c.gen[i] {
+			c.gen[i][j] = c.ring.zero()
+			for b := range z {
+				if row, ok := pivots[c.k+j*z+b]; ok && row[i*z/64]>>(i*z%64)&1 == 1 {
+					c.gen[i][j].flip(b)
+				}
+			}
+		}
+	}
+}
+
+// encode sets the parity bits of word, one bit per codeword bit, from
+// its information bits.
+func (c *ldpcCode) encode(word []uint64) {
+	z := c.ring.z
+	parity := make([]qcPoly, (c.n-c.k)/z)
+	for j := range parity {
+		parity[j] = c.ring.zero()
+	}
+	block := c.ring.zero()
+	for i, gen := range c.gen {
+		clear(block)
+		for b := range z {
+			v := i*z + b
+			if word[v/64]>>(v%64)&1 == 1 {
+				block.flip(b)
+			}
+		}
+		if block.isZero() {
+			continue
+		}
+		for j, g := range gen {
+			parity[j].add(c.ring.mul(g, block))
+		}
+	}
+	for j, p := range parity {
+		for b := range z {
+			if p.bit(b) {
+				v := c.k + j*z + b
+				word[v/64] |= 1 << (v % 64)
+			}
+		}
+	}
+}
+
+// LDPCCodec encodes and decodes a CCSDS LDPC code.
+type LDPCCodec struct {
+	code       *ldpcCode
+	iterations int
+}
+
+// LDPCOption configures an LDPCCodec.
+type LDPCOption func(*LDPCCodec)
+
+// WithMaxIterations sets the maximum number of decoder iterations per
+// codeblock. The default is 50.
+func WithMaxIterations(n int) LDPCOption {
+	return func(l *LDPCCodec) {
+		l.iterations = n
+	}
+}
+
+// NewLDPC8160_7136 returns an LDPCCodec for the CCSDS C2 (8160,7136)
+// code, which carries a 892-byte Transfer Frame in a 1020-byte codeblock.
+// Building the encoder takes a moment on first use.
+func NewLDPC8160_7136(opts ...LDPCOption) *LDPCCodec {
+	l := &LDPCCodec{code: c2(), iterations: ldpcDefaultIterations}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// DataLen returns the information bytes per codeblock.
+func (l *LDPCCodec) DataLen() int { return (l.code.k - l.code.fill) / 8 }
+
+// CodeblockLen returns the transmitted bytes per codeblock.
+func (l *LDPCCodec) CodeblockLen() int {
+	return (l.code.n - l.code.fill - l.code.punct + l.code.pad) / 8
+}
+
+// ASM returns the Attached Sync Marker that precedes each codeblock: the
+// 32-bit 0x1ACFFC1D for C2 and for every AR4JA rate.
+func (l *LDPCCodec) ASM() []byte { return DefaultASM() }
+
+// Encode encodes DataLen() bytes into a CodeblockLen()-byte codeblock.
+// The input slice is not modified.
+func (l *LDPCCodec) Encode(data []byte) ([]byte, error) {
+	c := l.code
+	if len(data) != l.DataLen() {
+		return nil, ErrInvalidDataLength
+	}
+	word := make([]uint64, (c.n+63)/64)
+	for i := range 8 * len(data) {
+		if data[i/8]>>(7-i%8)&1 == 1 {
+			v := c.fill + i
+			word[v/64] |= 1 << (v % 64)
+		}
+	}
+	c.encode(word)
+
+	out := make([]byte, l.CodeblockLen())
+	for v := c.fill; v < c.n-c.punct; v++ {
+		if word[v/64]>>(v%64)&1 == 1 {
+			i := v - c.fill
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out, nil
+}
+
+// Decode decodes a codeblock of soft symbols, one int8 log-likelihood
+// ratio per transmitted bit as for ConvCodec.Decode, and returns the
+// information bytes and the number of iterations used. Decoding stops
+// as soon as all parity checks hold; for C2, 0 iterations means the
+// codeblock was received without errors. The punctured bits of an AR4JA
+// code start as erasures, so even a clean codeblock takes an iteration
+// or more. Returns ErrNotConverged if the checks still fail after the
+// maximum number of iterations.
+func (l *LDPCCodec) Decode(symbols []int8) ([]byte, int, error) {
+	c := l.code
+	if len(symbols) != 8*l.CodeblockLen() {
+		return nil, 0, ErrInvalidDataLength
+	}
+	channel := make([]float32, c.n)
+	for v := range c.fill {
+		channel[v] = ldpcKnownLLR
+	}
+	for v := c.fill; v < c.n-c.punct; v++ {
+		channel[v] = float32(symbols[v-c.fill])
+	}
+
+	hard, iterations, ok := c.minSum(channel, l.iterations)
+	if !ok {
+		return nil, iterations, ErrNotConverged
+	}
+	out := make([]byte, l.DataLen())
+	for i := range 8 * len(out) {
+		if hard[c.fill+i] {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out, iterations, nil
+}
+
+// minSum runs normalized min-sum decoding on channel LLRs and returns
+// the hard decisions, the iterations used and whether all parity checks
+// hold.
+func (c *ldpcCode) minSum(channel []float32, maxIterations int) ([]bool, int, bool) {
+	hard := make([]bool, c.n)
+	for v, llr := range channel {
+		hard[v] = llr < 0
+	}
+	if c.satisfied(hard) {
+		return hard, 0, true
+	}
+
+	edges := 0
+	for _, check := range c.checks {
+		edges += len(check)
+	}
+	c2v := make([]float32, edges)
+	total := make([]float32, c.n)
+	copy(total, channel)
+
+	for it := 1; it <= maxIterations; it++ {
+		e := 0
+		for _, check := range c.checks {
+			min1, min2 := float32(ldpcKnownLLR*2), float32(ldpcKnownLLR*2)
+			arg := -1
+			negative := false
+			for i, v := range check {
+				m := total[v] - c2v[e+i]
+				if m < 0 {
+					negative = !negative
+					m = -m
+				}
+				switch {
+				case m < min1:
+					min1, min2, arg = m, min1, i
+				case m < min2:
+					min2 = m
+				}
+			}
+			for i, v := range check {
+				m := total[v] - c2v[e+i]
+				mag := min1
+				if i == arg {
+					mag = min2
+				}
+				msg := ldpcNormalization * mag
+				if negative != (m < 0) {
+					msg = -msg
+				}
+				c2v[e+i] = msg
+			}
+			e += len(check)
+		}
+
+		copy(total, channel)
+		e = 0
+		for _, check := range c.checks {
+			for _, v := range check {
+				total[v] += c2v[e]
+				e++
+			}
+		}
+		for v, llr := range total {
+			hard[v] = llr < 0
+		}
+		if c.satisfied(hard) {
+			return hard, it, true
+		}
+	}
+	return hard, maxIterations, false
+}
+
+// satisfied reports whether hard decisions satisfy every parity check.
+func (c *ldpcCode) satisfied(hard []bool) bool {
+	for _, check := range c.checks {
+		parity := false
+		for _, v := range check {
+			parity = parity != hard[v]
+		}
+		if parity {
+			return false
+		}
+	}
+	return true
+}
+
+// WrapCADU encodes a Transfer Frame of DataLen() bytes into a codeblock
+// and wraps it into a CADU with the code's ASM, randomizing the
+// codeblock if requested.
+func (l *LDPCCodec) WrapCADU(frame []byte, randomize bool) ([]byte, error) {
+	block, err := l.Encode(frame)
+	if err != nil {
+		return nil, err
+	}
+	return WrapCADU(block, l.ASM(), randomize), nil
+}
+
+// UnwrapCADU decodes a CADU received as soft symbols, one per bit. It
+// checks the ASM on hard decisions, de-randomizes the codeblock symbols
+// if requested, and decodes them. It returns the Transfer Frame and the
+// decoder iterations used.
+func (l *LDPCCodec) UnwrapCADU(symbols []int8, randomize bool) ([]byte, int, error) {
+	asm := l.ASM()
+	if len(symbols) < 8*len(asm) {
+		return nil, 0, ErrDataTooShort
+	}
+	for i := range 8 * len(asm) {
+		if (asm[i/8]>>(7-i%8)&1 == 1) != (symbols[i] < 0) {
+			return nil, 0, ErrSyncMarkerMismatch
+		}
+	}
+	block := make([]int8, len(symbols)-8*len(asm))
+	copy(block, symbols[8*len(asm):])
+	if randomize {
+		pn := GeneratePNSequence((len(block) + 7) / 8)
+		for i := range block {
+			if pn[i/8]>>(7-i%8)&1 == 1 {
+				block[i] = -max(block[i], -127)
+			}
+		}
+	}
+	return l.Decode(block)
+}
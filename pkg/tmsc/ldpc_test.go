@@ -0,0 +1,286 @@
+package tmsc_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/tmsc"
+)
+
+func randomFrame(rng *rand.Rand, n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	return data
+}
+
+func TestLDPC8160_7136_Lengths(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136()
+	if l.DataLen() != 892 {
+		t.Errorf("DataLen() = %d, want 892", l.DataLen())
+	}
+	if l.CodeblockLen() != 1020 {
+		t.Errorf("CodeblockLen() = %d, want 1020", l.CodeblockLen())
+	}
+	if !bytes.Equal(l.ASM(), tmsc.DefaultASM()) {
+		t.Errorf("ASM() = %x", l.ASM())
+	}
+}
+
+func TestLDPC8160_7136_RoundTrip(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136()
+	rng := rand.New(rand.NewPCG(3, 0))
+	for range 4 {
+		data := randomFrame(rng, l.DataLen())
+		block, err := l.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block[len(block)-1]&0x03 != 0 {
+			t.Error("trailing fill bits are not zero")
+		}
+
+		// A clean codeblock satisfies every check without iterating.
+		got, iterations, err := l.Decode(tmsc.HardSymbols(block))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iterations != 0 {
+			t.Errorf("iterations = %d, want 0", iterations)
+		}
+		if !bytes.Equal(got, data) {
+			t.Error("decoded data differs from original")
+		}
+	}
+}
+
+func TestLDPC8160_7136_Systematic(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136()
+	data := randomFrame(rand.New(rand.NewPCG(4, 0)), l.DataLen())
+	block, _ := l.Encode(data)
+	// The first 7136 transmitted bits are the frame itself.
+	if !bytes.Equal(block[:l.DataLen()], data) {
+		t.Error("codeblock does not start with the frame")
+	}
+}
+
+func TestLDPC8160_7136_SoftDecision(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136()
+	rng := rand.New(rand.NewPCG(5, 0))
+	data := randomFrame(rng, l.DataLen())
+	block, _ := l.Encode(data)
+
+	// Eb/N0 of about 5 dB leaves dozens of bit errors per codeblock.
+	symbols := awgn(rng, block, 0.425)
+	errs := 0
+	for i, s := range tmsc.HardSymbols(block) {
+		if (s > 0) != (symbols[i] >= 0) {
+			errs++
+		}
+	}
+	if errs < 20 {
+		t.Fatalf("channel introduced only %d bit errors", errs)
+	}
+
+	got, iterations, err := l.Decode(symbols)
+	if err != nil {
+		t.Fatalf("after %d bit errors: %v", errs, err)
+	}
+	if iterations == 0 {
+		t.Error("iterations = 0 for a noisy codeblock")
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("decoded data differs from original")
+	}
+}
+
+func TestLDPC8160_7136_NotConverged(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136(tmsc.WithMaxIterations(5))
+	rng := rand.New(rand.NewPCG(6, 0))
+	block, _ := l.Encode(randomFrame(rng, l.DataLen()))
+	_, iterations, err := l.Decode(awgn(rng, block, 1.2))
+	if !errors.Is(err, tmsc.ErrNotConverged) {
+		t.Fatalf("got %v, want ErrNotConverged", err)
+	}
+	if iterations != 5 {
+		t.Errorf("iterations = %d, want 5", iterations)
+	}
+}
+
+func TestLDPC8160_7136_CADU(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136()
+	rng := rand.New(rand.NewPCG(8, 0))
+	frame := randomFrame(rng, l.DataLen())
+
+	for _, randomize := range []bool{true, false} {
+		cadu, err := l.WrapCADU(frame, randomize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cadu) != 4+l.CodeblockLen() {
+			t.Fatalf("CADU length = %d", len(cadu))
+		}
+		symbols := tmsc.HardSymbols(cadu)
+		for i := 40; i < len(symbols); i += 97 {
+			symbols[i] = -symbols[i]
+		}
+		got, _, err := l.UnwrapCADU(symbols, randomize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, frame) {
+			t.Errorf("randomize %v: frame differs", randomize)
+		}
+	}
+
+	cadu, _ := l.WrapCADU(frame, true)
+	cadu[0] ^= 0xFF
+	if _, _, err := l.UnwrapCADU(tmsc.HardSymbols(cadu), true); !errors.Is(err, tmsc.ErrSyncMarkerMismatch) {
+		t.Errorf("got %v, want ErrSyncMarkerMismatch", err)
+	}
+	if _, _, err := l.UnwrapCADU(make([]int8, 16), true); !errors.Is(err, tmsc.ErrDataTooShort) {
+		t.Errorf("got %v, want ErrDataTooShort", err)
+	}
+}
+
+func TestLDPC8160_7136_WrongLength(t *testing.T) {
+	l := tmsc.NewLDPC8160_7136()
+	if _, err := l.Encode(make([]byte, 891)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Encode: got %v, want ErrInvalidDataLength", err)
+	}
+	if _, _, err := l.Decode(make([]int8, 8159)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Decode: got %v, want ErrInvalidDataLength", err)
+	}
+	if _, err := l.WrapCADU(nil, false); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("WrapCADU: got %v, want ErrInvalidDataLength", err)
+	}
+}
+
+var ar4jaCodes = []struct {
+	rate         tmsc.LDPCRate
+	dataLen      int
+	codeblockLen int
+}{
+	{tmsc.LDPCRate1_2, 128, 256},
+	{tmsc.LDPCRate2_3, 128, 192},
+	{tmsc.LDPCRate4_5, 128, 160},
+	{tmsc.LDPCRate1_2, 512, 1024},
+	{tmsc.LDPCRate2_3, 512, 768},
+	{tmsc.LDPCRate4_5, 512, 640},
+	{tmsc.LDPCRate1_2, 2048, 4096},
+	{tmsc.LDPCRate2_3, 2048, 3072},
+	{tmsc.LDPCRate4_5, 2048, 2560},
+}
+
+func TestAR4JA_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(9, 0))
+	for _, tc := range ar4jaCodes {
+		l, err := tmsc.NewAR4JACodec(tc.rate, tc.dataLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.DataLen() != tc.dataLen || l.CodeblockLen() != tc.codeblockLen {
+			t.Errorf("rate %v, %d bytes: lengths %d/%d, want %d/%d", tc.rate, tc.dataLen,
+				l.DataLen(), l.CodeblockLen(), tc.dataLen, tc.codeblockLen)
+		}
+		if !bytes.Equal(l.ASM(), tmsc.DefaultASM()) {
+			t.Errorf("ASM() = %x", l.ASM())
+		}
+
+		data := randomFrame(rng, l.DataLen())
+		block, err := l.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(block[:l.DataLen()], data) {
+			t.Errorf("rate %v, %d bytes: codeblock does not start with the frame", tc.rate, tc.dataLen)
+		}
+		got, _, err := l.Decode(tmsc.HardSymbols(block))
+		if err != nil {
+			t.Fatalf("rate %v, %d bytes: %v", tc.rate, tc.dataLen, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("rate %v, %d bytes: decoded data differs from original", tc.rate, tc.dataLen)
+		}
+	}
+}
+
+func TestAR4JA_SoftDecision(t *testing.T) {
+	rng := rand.New(rand.NewPCG(10, 0))
+	for _, tc := range []struct {
+		rate  tmsc.LDPCRate
+		sigma float64
+	}{
+		{tmsc.LDPCRate1_2, 0.6},
+		{tmsc.LDPCRate2_3, 0.5},
+		{tmsc.LDPCRate4_5, 0.45},
+	} {
+		l, _ := tmsc.NewAR4JACodec(tc.rate, 512)
+		data := randomFrame(rng, l.DataLen())
+		block, _ := l.Encode(data)
+		symbols := awgn(rng, block, tc.sigma)
+		errs := 0
+		for i, s := range tmsc.HardSymbols(block) {
+			if (s > 0) != (symbols[i] >= 0) {
+				errs++
+			}
+		}
+		if errs < 20 {
+			t.Fatalf("rate %v: channel introduced only %d bit errors", tc.rate, errs)
+		}
+
+		got, iterations, err := l.Decode(symbols)
+		if err != nil {
+			t.Fatalf("rate %v after %d bit errors: %v", tc.rate, errs, err)
+		}
+		if iterations == 0 {
+			t.Errorf("rate %v: iterations = 0 for a noisy codeblock", tc.rate)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("rate %v: decoded data differs from original", tc.rate)
+		}
+	}
+}
+
+func TestAR4JA_CADU(t *testing.T) {
+	l, _ := tmsc.NewAR4JACodec(tmsc.LDPCRate2_3, 128)
+	rng := rand.New(rand.NewPCG(11, 0))
+	frame := randomFrame(rng, l.DataLen())
+
+	for _, randomize := range []bool{true, false} {
+		cadu, err := l.WrapCADU(frame, randomize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cadu) != 4+192 {
+			t.Fatalf("CADU length = %d", len(cadu))
+		}
+		symbols := tmsc.HardSymbols(cadu)
+		for i := 40; i < len(symbols); i += 61 {
+			symbols[i] = -symbols[i]
+		}
+		got, _, err := l.UnwrapCADU(symbols, randomize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, frame) {
+			t.Errorf("randomize %v: frame differs", randomize)
+		}
+	}
+}
+
+func TestNewAR4JACodec_Errors(t *testing.T) {
+	if _, err := tmsc.NewAR4JACodec(tmsc.LDPCRate(7), 128); !errors.Is(err, tmsc.ErrInvalidCodeRate) {
+		t.Errorf("got %v, want ErrInvalidCodeRate", err)
+	}
+	if _, err := tmsc.NewAR4JACodec(tmsc.LDPCRate1_2, 256); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("got %v, want ErrInvalidDataLength", err)
+	}
+	l, _ := tmsc.NewAR4JACodec(tmsc.LDPCRate1_2, 128)
+	if _, _, err := l.Decode(make([]int8, 8*256-1)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Decode: got %v, want ErrInvalidDataLength", err)
+	}
+}
@@ -0,0 +1,408 @@
+package tmsc
+
+// Coding profiles for CCSDS TM Synchronization and Channel Coding
+// per CCSDS 131.0-B-4.
+//
+// A CodingProfile chains the sublayer functions in the order of the
+// standard:
+//
+//	Frame → RS encode (interleaved, virtual fill) → Randomize → ASM → Convolutional encode
+//	Convolutional decode → ASM strip → De-randomize → RS decode → Frame
+//
+// Every stage but the ASM is optional. The convolutional code runs over
+// the CADUs as one continuous stream, so a CodingProfile keeps the state
+// of the stream it encodes and the stream it decodes; it is not safe for
+// concurrent use.
+
+import "math/bits"
+
+// CodingProfile describes a TM channel coding chain and converts between
+// Transfer Frames and CADUs.
+type CodingProfile struct {
+	frameLen  int
+	asm       []byte
+	randomize bool
+	rsE       int // RS error correction capability; 0 disables RS
+	depth     int
+	rsOpts    []RSOption
+	rs        *RSCodec
+	convRate  ConvRate
+	hasConv   bool
+	convOpts  []ConvOption
+	conv      *ConvCodec
+
+	// Continuous convolutional stream state.
+	enc     *ConvEncoder
+	dec     *ConvDecoder
+	check   *ConvEncoder // re-encodes decoded CADUs to measure the path metric
+	rx      []int8       // received symbols not yet part of a decoded CADU
+	decoded []byte       // decoded bytes not yet forming a whole CADU
+}
+
+// ProfileOption configures a CodingProfile.
+type ProfileOption func(*CodingProfile)
+
+// WithProfileASM sets the Attached Sync Marker. The default is
+// DefaultASM().
+func WithProfileASM(asm []byte) ProfileOption {
+	return func(p *CodingProfile) {
+		p.asm = append([]byte(nil), asm...)
+	}
+}
+
+// WithRandomization sets whether the codeblock is pseudo-randomized. The
+// default is false.
+func WithRandomization(randomize bool) ProfileOption {
+	return func(p *CodingProfile) {
+		p.randomize = randomize
+	}
+}
+
+// WithReedSolomon enables Reed-Solomon coding with error correction
+// capability e (8 or 16) and interleaving depth depth. RS options such as
+// WithDualBasis apply to every codeword; the virtual fill is set by the
+// profile. By default the profile has no RS code.
+func WithReedSolomon(e, depth int, opts ...RSOption) ProfileOption {
+	return func(p *CodingProfile) {
+		p.rsE = e
+		p.depth = depth
+		p.rsOpts = opts
+	}
+}
+
+// WithConvolutional enables the convolutional inner code at the given
+// rate. As CCSDS specifies, the CADUs form one continuous, unterminated
+// stream: the encoder state carries from one CADU to the next and no
+// tail bits are added. WithTail(true) opts into encoding each CADU, ASM
+// included, as its own terminated block instead. Other ConvOptions, such
+// as WithSymbolInversion, apply to the code. By default the profile has
+// no inner code.
+func WithConvolutional(rate ConvRate, opts ...ConvOption) ProfileOption {
+	return func(p *CodingProfile) {
+		p.convRate = rate
+		p.hasConv = true
+		p.convOpts = opts
+	}
+}
+
+// NewCodingProfile returns a CodingProfile for Transfer Frames of frameLen
+// bytes. With RS coding, frames shorter than depth codewords are carried
+// in shortened codewords: each codeword gets the same number of virtual
+// fill symbols, which are encoded as zeros but not transmitted.
+//
+// Returns ErrInvalidCorrection for an RS capability other than 8 or 16,
+// ErrInvalidInterleaveDepth for an unsupported depth, ErrInvalidCodeRate
+// for an unknown convolutional rate, and ErrInvalidDataLength if frameLen
+// does not fit the RS codewords or does not divide evenly among them.
+func NewCodingProfile(frameLen int, opts ...ProfileOption) (*CodingProfile, error) {
+	p := &CodingProfile{
+		frameLen: frameLen,
+		asm:      DefaultASM(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if frameLen <= 0 {
+		return nil, ErrInvalidDataLength
+	}
+
+	if p.rsE != 0 {
+		if p.rsE != 8 && p.rsE != 16 {
+			return nil, ErrInvalidCorrection
+		}
+		if !validInterleaveDepth(p.depth) {
+			return nil, ErrInvalidInterleaveDepth
+		}
+		k := p.depth * (rsNN - 2*p.rsE)
+		if frameLen > k || (k-frameLen)%p.depth != 0 {
+			return nil, ErrInvalidDataLength
+		}
+		opts := append(p.rsOpts[:len(p.rsOpts):len(p.rsOpts)], WithVirtualFill((k-frameLen)/p.depth))
+		if p.rsE == 8 {
+			p.rs = NewRS255_239(opts...)
+		} else {
+			p.rs = NewRS255_223(opts...)
+		}
+	}
+
+	if p.hasConv {
+		opts := append([]ConvOption{WithTail(false)}, p.convOpts...)
+		conv, err := NewConvCodec(p.convRate, opts...)
+		if err != nil {
+			return nil, err
+		}
+		p.conv = conv
+		if !conv.tail {
+			p.enc = conv.NewEncoder()
+			p.dec = conv.NewDecoder()
+			p.check = conv.NewEncoder()
+		}
+	}
+	return p, nil
+}
+
+// FrameLen returns the Transfer Frame length in bytes.
+func (p *CodingProfile) FrameLen() int { return p.frameLen }
+
+// ASM returns a copy of the Attached Sync Marker.
+func (p *CodingProfile) ASM() []byte { return append([]byte(nil), p.asm...) }
+
+// Randomized reports whether the codeblock is pseudo-randomized.
+func (p *CodingProfile) Randomized() bool { return p.randomize }
+
+// RS returns the RS error correction capability and interleaving depth,
+// or 0, 0 without an RS code.
+func (p *CodingProfile) RS() (e, depth int) { return p.rsE, p.depth }
+
+// VirtualFill returns the number of virtual fill symbols per RS codeword.
+func (p *CodingProfile) VirtualFill() int {
+	if p.rs == nil {
+		return 0
+	}
+	return p.rs.VirtualFill()
+}
+
+// DualBasis reports whether RS symbols use the dual-basis representation.
+func (p *CodingProfile) DualBasis() bool { return p.rs != nil && p.rs.DualBasis() }
+
+// Convolutional returns the convolutional code rate and whether the
+// profile has an inner code.
+func (p *CodingProfile) Convolutional() (ConvRate, bool) { return p.convRate, p.hasConv }
+
+// ConvTerminated reports whether each CADU is a terminated convolutional
+// block rather than part of a continuous stream.
+func (p *CodingProfile) ConvTerminated() bool { return p.conv != nil && p.conv.tail }
+
+// CADULen returns the CADU length in bytes, ASM included.
+func (p *CodingProfile) CADULen() int {
+	n := len(p.asm) + p.frameLen
+	if p.rs != nil {
+		n += p.depth * p.rs.NRoots()
+	}
+	return n
+}
+
+// ChannelLen returns the length in bytes of the channel symbols of one
+// CADU: the CADU itself without an inner code, or its convolutionally
+// encoded symbols padded to a whole byte. On a continuous stream the
+// symbols of consecutive CADUs are not byte aligned; ChannelLen is then
+// the length of a stream of one CADU, from Encode and FlushEncoder.
+func (p *CodingProfile) ChannelLen() int {
+	if p.conv == nil {
+		return p.CADULen()
+	}
+	return (p.conv.EncodedSymbols(p.CADULen()) + 7) / 8
+}
+
+// CADUResult is the outcome of decoding one CADU with a CodingProfile.
+type CADUResult struct {
+	Frame         []byte // decoded Transfer Frame, virtual fill removed
+	ASMErrors     int    // bit errors in the received ASM
+	Corrected     []int  // RS symbols corrected in each codeword
+	Uncorrectable []bool // RS codewords with more errors than the code corrects
+	PathMetric    int    // Viterbi path metric over the CADU's symbols, 0 without a convolutional code
+}
+
+// OK reports whether every RS codeword was decoded. It is always true
+// without an RS code.
+func (r *CADUResult) OK() bool {
+	for _, u := range r.Uncorrectable {
+		if u {
+			return false
+		}
+	}
+	return true
+}
+
+// TotalCorrected returns the number of RS symbols corrected in the CADU.
+func (r *CADUResult) TotalCorrected() int {
+	n := 0
+	for _, c := range r.Corrected {
+		n += c
+	}
+	return n
+}
+
+// Encode turns a Transfer Frame into the next CADU of the channel
+// stream. With a convolutional code the result is channel symbols,
+// packed MSB first. On a continuous stream Encode returns the whole
+// bytes completed so far and holds the remaining symbols for the next
+// call or FlushEncoder. Returns ErrInvalidDataLength if frame is not
+// FrameLen() bytes.
+func (p *CodingProfile) Encode(frame []byte) ([]byte, error) {
+	if len(frame) != p.frameLen {
+		return nil, ErrInvalidDataLength
+	}
+	block := frame
+	if p.rs != nil {
+		encoded, err := p.rs.EncodeInterleaved(frame, p.depth)
+		if err != nil {
+			return nil, err
+		}
+		block = encoded
+	}
+	cadu := WrapCADU(block, p.asm, p.randomize)
+	switch {
+	case p.enc != nil:
+		return p.enc.Encode(cadu), nil
+	case p.conv != nil:
+		return p.conv.Encode(cadu), nil
+	}
+	return cadu, nil
+}
+
+// FlushEncoder ends the encoded stream. On a continuous stream it
+// returns the symbols held by Encode, padded with zero bits to a whole
+// byte, and returns the encoder to the zero state for the next stream.
+// Otherwise it returns nil.
+func (p *CodingProfile) FlushEncoder() []byte {
+	if p.enc == nil {
+		return nil
+	}
+	return p.enc.Flush()
+}
+
+// DecodeHard is Decode for hard-decision channel bytes, as produced by
+// Encode.
+func (p *CodingProfile) DecodeHard(data []byte) []*CADUResult {
+	return p.Decode(HardSymbols(data))
+}
+
+// Decode feeds received soft symbols, one int8 log-likelihood ratio per
+// channel bit, and returns the CADUs they complete, in order. The stream
+// starts with the first symbol of a CADU; symbols that do not complete a
+// CADU are held for the next call.
+//
+// Without a convolutional code the symbols are the CADU bits: they are
+// hard-decided, and their ByteReliability guides RS erasure decoding of
+// codewords with more errors than errors-only decoding corrects. With
+// terminated blocks each CADU takes 8*ChannelLen() symbols, padding
+// included. On a continuous stream the Viterbi decoder decides each bit
+// 128 trellis steps after receiving it, so a CADU is returned once
+// enough of the next one arrives, or by FlushDecoder.
+//
+// Uncorrectable RS codewords are reported in the results; their symbols
+// are returned as received.
+func (p *CodingProfile) Decode(symbols []int8) []*CADUResult {
+	p.rx = append(p.rx, symbols...)
+	if p.dec != nil {
+		p.decoded = append(p.decoded, p.dec.Decode(symbols)...)
+		return p.streamCADUs()
+	}
+
+	var results []*CADUResult
+	n := 8 * p.ChannelLen()
+	used := 0
+	for ; len(p.rx)-used >= n; used += n {
+		results = append(results, p.decodeBlock(p.rx[used:used+n]))
+	}
+	p.rx = append(p.rx[:0], p.rx[used:]...)
+	return results
+}
+
+// FlushDecoder ends the received stream. On a continuous stream it
+// decides the remaining bits, tracing back from the best final state,
+// and returns the CADUs they complete; the last bits of the stream are
+// less reliable than the rest. Held symbols that do not complete a CADU
+// are discarded, and the decoder is ready for the next stream.
+func (p *CodingProfile) FlushDecoder() []*CADUResult {
+	var results []*CADUResult
+	if p.dec != nil {
+		p.decoded = append(p.decoded, p.dec.Flush()...)
+		results = p.streamCADUs()
+		p.check = p.conv.NewEncoder()
+	}
+	p.rx, p.decoded = nil, nil
+	return results
+}
+
+// decodeBlock decodes the symbols of one CADU without an inner code or
+// with a terminated one.
+func (p *CodingProfile) decodeBlock(symbols []int8) *CADUResult {
+	if p.conv == nil {
+		cadu := make([]byte, p.CADULen())
+		for i, s := range symbols {
+			if s < 0 {
+				cadu[i/8] |= 1 << (7 - i%8)
+			}
+		}
+		return p.decodeCADU(cadu, ByteReliability(symbols), 0)
+	}
+	cadu, metric, _ := p.conv.Decode(symbols)
+	return p.decodeCADU(cadu[:p.CADULen()], nil, metric)
+}
+
+// streamCADUs decodes the whole CADUs among the bytes decided on a
+// continuous stream. The path metric of each is measured by re-encoding
+// it and comparing the symbols with those received.
+func (p *CodingProfile) streamCADUs() []*CADUResult {
+	var results []*CADUResult
+	n := p.CADULen()
+	used, pos := 0, 0
+	for ; len(p.decoded)-used >= n; used += n {
+		cadu := p.decoded[used : used+n]
+		metric := 0
+		emit := func(sym byte) {
+			if pos < len(p.rx) {
+				metric += symbolPenalty(p.rx[pos])[sym]
+			}
+			pos++
+		}
+		for i := range 8 * n {
+			p.check.push(int(cadu[i/8]>>(7-i%8))&1, emit)
+		}
+		results = append(results, p.decodeCADU(cadu, nil, metric))
+	}
+	p.decoded = append(p.decoded[:0], p.decoded[used:]...)
+	p.rx = append(p.rx[:0], p.rx[min(pos, len(p.rx)):]...)
+	return results
+}
+
+// decodeCADU strips the ASM, de-randomizes and RS decodes a CADU of
+// CADULen() bytes. Without per-byte reliability of the CADU, codewords
+// get errors-only decoding.
+func (p *CodingProfile) decodeCADU(cadu, reliability []byte, metric int) *CADUResult {
+	r := &CADUResult{PathMetric: metric}
+	for i, b := range p.asm {
+		r.ASMErrors += bits.OnesCount8(cadu[i] ^ b)
+	}
+	block := cadu[len(p.asm):]
+	if p.randomize {
+		block = Randomize(block)
+	}
+	if p.rs == nil {
+		r.Frame = append([]byte(nil), block...)
+		return r
+	}
+
+	// Decode each codeword separately so one failure does not hide the
+	// others.
+	n := p.rs.CodewordLen()
+	k := p.rs.DataLen()
+	r.Corrected = make([]int, p.depth)
+	r.Uncorrectable = make([]bool, p.depth)
+	r.Frame = make([]byte, p.depth*k)
+	for d := range p.depth {
+		cw := make([]byte, n)
+		for i := range n {
+			cw[i] = block[i*p.depth+d]
+		}
+		// Uniform reliability gives errors-only decoding.
+		rel := make([]byte, n)
+		if reliability != nil {
+			for i := range n {
+				rel[i] = reliability[len(p.asm)+i*p.depth+d]
+			}
+		}
+		corrected, count, err := p.rs.DecodeWithReliability(cw, rel)
+		if err != nil {
+			r.Uncorrectable[d] = true
+			corrected = cw[:k]
+		}
+		r.Corrected[d] = count
+		for i := range k {
+			r.Frame[i*p.depth+d] = corrected[i]
+		}
+	}
+	return r
+}
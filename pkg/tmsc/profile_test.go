@@ -0,0 +1,353 @@
+package tmsc_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/tmsc"
+)
+
+func newProfile(t *testing.T, frameLen int, opts ...tmsc.ProfileOption) *tmsc.CodingProfile {
+	t.Helper()
+	p, err := tmsc.NewCodingProfile(frameLen, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// decodeStream decodes a whole received stream.
+func decodeStream(p *tmsc.CodingProfile, symbols []int8) []*tmsc.CADUResult {
+	return append(p.Decode(symbols), p.FlushDecoder()...)
+}
+
+// decodeOne decodes a stream that carries a single CADU.
+func decodeOne(t *testing.T, p *tmsc.CodingProfile, symbols []int8) *tmsc.CADUResult {
+	t.Helper()
+	results := decodeStream(p, symbols)
+	if len(results) != 1 {
+		t.Fatalf("decoded %d CADUs, want 1", len(results))
+	}
+	return results[0]
+}
+
+func TestCodingProfile_Lengths(t *testing.T) {
+	tests := []struct {
+		name     string
+		frameLen int
+		opts     []tmsc.ProfileOption
+		fill     int
+		caduLen  int
+		chanLen  int
+	}{
+		{"uncoded", 100, nil, 0, 104, 104},
+		{"E=16 I=5", 1115, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 5)}, 0, 1279, 1279},
+		{"E=8 I=4", 956, []tmsc.ProfileOption{tmsc.WithReedSolomon(8, 4)}, 0, 1024, 1024},
+		{"shortened", 128, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 1)}, 95, 164, 164},
+		{"conv 1/2", 100, []tmsc.ProfileOption{tmsc.WithConvolutional(tmsc.ConvRate1_2)}, 0, 104, 208},
+		{"conv 1/2 terminated", 100, []tmsc.ProfileOption{tmsc.WithConvolutional(tmsc.ConvRate1_2, tmsc.WithTail(true))}, 0, 104, 210},
+		{"conv 7/8", 100, []tmsc.ProfileOption{tmsc.WithConvolutional(tmsc.ConvRate7_8)}, 0, 104, 119},
+	}
+	for _, tt := range tests {
+		p := newProfile(t, tt.frameLen, tt.opts...)
+		if p.FrameLen() != tt.frameLen || p.VirtualFill() != tt.fill || p.CADULen() != tt.caduLen || p.ChannelLen() != tt.chanLen {
+			t.Errorf("%s: FrameLen() = %d, VirtualFill() = %d, CADULen() = %d, ChannelLen() = %d",
+				tt.name, p.FrameLen(), p.VirtualFill(), p.CADULen(), p.ChannelLen())
+		}
+	}
+}
+
+func TestCodingProfile_MatchesChain(t *testing.T) {
+	// A profile produces the same CADU as chaining the primitives by hand.
+	rs := tmsc.NewRS255_223()
+	frame := randomFrame(rand.New(rand.NewPCG(40, 0)), 2*rs.DataLen())
+	block, _ := rs.EncodeInterleaved(frame, 2)
+	want := tmsc.WrapCADU(block, nil, true)
+
+	p := newProfile(t, len(frame), tmsc.WithReedSolomon(16, 2), tmsc.WithRandomization(true))
+	got, err := p.Encode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("profile CADU differs from the hand-chained CADU")
+	}
+}
+
+func TestCodingProfile_VirtualFill(t *testing.T) {
+	// Shortened codewords carry the parity of the full codeword with the
+	// fill symbols set to zero.
+	rs := tmsc.NewRS255_223()
+	frame := randomFrame(rand.New(rand.NewPCG(41, 0)), 128)
+	full, _ := rs.Encode(append(make([]byte, 95), frame...))
+
+	p := newProfile(t, 128, tmsc.WithReedSolomon(16, 1))
+	cadu, _ := p.Encode(frame)
+	if !bytes.Equal(cadu[4:], full[95:]) {
+		t.Error("shortened codeword differs from the full codeword")
+	}
+}
+
+func TestCodingProfile_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 0))
+	tests := []struct {
+		name     string
+		frameLen int
+		opts     []tmsc.ProfileOption
+	}{
+		{"uncoded", 64, nil},
+		{"randomized", 64, []tmsc.ProfileOption{tmsc.WithRandomization(true)}},
+		{"custom ASM", 64, []tmsc.ProfileOption{tmsc.WithProfileASM([]byte{0xDE, 0xAD, 0xBE, 0xEF})}},
+		{"E=8 I=2 shortened", 400, []tmsc.ProfileOption{tmsc.WithReedSolomon(8, 2), tmsc.WithRandomization(true)}},
+		{"E=16 I=5", 1115, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 5), tmsc.WithRandomization(true)}},
+		{"E=16 I=5 dual basis", 1115, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 5, tmsc.WithDualBasis(true)), tmsc.WithRandomization(true)}},
+		{"conv 1/2", 223, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 1), tmsc.WithConvolutional(tmsc.ConvRate1_2)}},
+		{"conv 7/8", 200, []tmsc.ProfileOption{tmsc.WithConvolutional(tmsc.ConvRate7_8), tmsc.WithRandomization(true)}},
+		{"conv 3/4 terminated", 223, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 1), tmsc.WithConvolutional(tmsc.ConvRate3_4, tmsc.WithTail(true))}},
+	}
+	for _, tt := range tests {
+		p := newProfile(t, tt.frameLen, tt.opts...)
+		frame := randomFrame(rng, tt.frameLen)
+		encoded, err := p.Encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, p.FlushEncoder()...)
+		if len(encoded) != p.ChannelLen() {
+			t.Errorf("%s: encoded length %d, want %d", tt.name, len(encoded), p.ChannelLen())
+		}
+		r := decodeOne(t, p, tmsc.HardSymbols(encoded))
+		if !r.OK() || r.TotalCorrected() != 0 || r.ASMErrors != 0 || r.PathMetric != 0 {
+			t.Errorf("%s: clean CADU reported %+v", tt.name, r)
+		}
+		if !bytes.Equal(r.Frame, frame) {
+			t.Errorf("%s: frame differs", tt.name)
+		}
+	}
+}
+
+func TestCodingProfile_Corrections(t *testing.T) {
+	p := newProfile(t, 3*223, tmsc.WithReedSolomon(16, 3), tmsc.WithRandomization(true))
+	frame := randomFrame(rand.New(rand.NewPCG(43, 0)), p.FrameLen())
+	cadu, _ := p.Encode(frame)
+
+	// With depth 3, byte i of the codeblock belongs to codeword i%3.
+	// Codeword 0 gets 5 errors, codeword 1 gets 17 and codeword 2 none.
+	for i := range 5 {
+		cadu[4+3*(10*i)] ^= 0x5A
+	}
+	for i := range 17 {
+		cadu[4+3*(10*i)+1] ^= 0xA5
+	}
+	cadu[0] ^= 0x81
+
+	r := decodeOne(t, p, tmsc.HardSymbols(cadu))
+	if r.ASMErrors != 2 {
+		t.Errorf("ASMErrors = %d, want 2", r.ASMErrors)
+	}
+	if r.Corrected[0] != 5 || r.Corrected[2] != 0 {
+		t.Errorf("Corrected = %v", r.Corrected)
+	}
+	if r.Uncorrectable[0] || !r.Uncorrectable[1] || r.Uncorrectable[2] || r.OK() {
+		t.Errorf("Uncorrectable = %v", r.Uncorrectable)
+	}
+
+	// The symbols of the decodable codewords are still corrected.
+	for i := range frame {
+		if i%3 != 1 && r.Frame[i] != frame[i] {
+			t.Fatalf("frame byte %d of codeword %d not corrected", i, i%3)
+		}
+	}
+}
+
+func TestCodingProfile_SoftDecision(t *testing.T) {
+	p := newProfile(t, 223, tmsc.WithReedSolomon(16, 1), tmsc.WithRandomization(true),
+		tmsc.WithConvolutional(tmsc.ConvRate1_2))
+	rng := rand.New(rand.NewPCG(44, 0))
+	frame := randomFrame(rng, 223)
+	symbols, _ := p.Encode(frame)
+	symbols = append(symbols, p.FlushEncoder()...)
+
+	r := decodeOne(t, p, awgn(rng, symbols, 0.7))
+	if r.PathMetric == 0 {
+		t.Error("PathMetric = 0 for a noisy channel")
+	}
+	if !r.OK() || !bytes.Equal(r.Frame, frame) {
+		t.Errorf("frame not recovered: %+v", r.Corrected)
+	}
+}
+
+func TestCodingProfile_SoftReliability(t *testing.T) {
+	// Without an inner code, weak soft symbols mark the bytes to erase.
+	p := newProfile(t, 223, tmsc.WithReedSolomon(16, 1), tmsc.WithRandomization(true))
+	frame := randomFrame(rand.New(rand.NewPCG(45, 0)), 223)
+	cadu, _ := p.Encode(frame)
+
+	// 20 byte errors, 10 of them received with low confidence.
+	for i := range 20 {
+		cadu[4+10*i] ^= 0xFF
+	}
+	symbols := tmsc.HardSymbols(cadu)
+	for i := range 10 {
+		for b := range 8 {
+			symbols[8*(4+10*i)+b] /= 16
+		}
+	}
+
+	hard := decodeOne(t, p, tmsc.HardSymbols(cadu))
+	if hard.OK() {
+		t.Error("DecodeHard corrected 20 errors")
+	}
+	r := decodeOne(t, p, symbols)
+	if !r.OK() || r.TotalCorrected() != 20 || !bytes.Equal(r.Frame, frame) {
+		t.Errorf("frame not recovered: %+v", r.Corrected)
+	}
+}
+
+func TestCodingProfile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		frameLen int
+		opts     []tmsc.ProfileOption
+		want     error
+	}{
+		{"zero length", 0, nil, tmsc.ErrInvalidDataLength},
+		{"capability", 223, []tmsc.ProfileOption{tmsc.WithReedSolomon(10, 1)}, tmsc.ErrInvalidCorrection},
+		{"depth", 223, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 6)}, tmsc.ErrInvalidInterleaveDepth},
+		{"too long", 224, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 1)}, tmsc.ErrInvalidDataLength},
+		{"uneven fill", 445, []tmsc.ProfileOption{tmsc.WithReedSolomon(16, 2)}, tmsc.ErrInvalidDataLength},
+		{"rate", 223, []tmsc.ProfileOption{tmsc.WithConvolutional(tmsc.ConvRate(9))}, tmsc.ErrInvalidCodeRate},
+	}
+	for _, tt := range tests {
+		if _, err := tmsc.NewCodingProfile(tt.frameLen, tt.opts...); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	p := newProfile(t, 223, tmsc.WithReedSolomon(16, 1))
+	if _, err := p.Encode(make([]byte, 222)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Encode: got %v", err)
+	}
+}
+
+func TestCodingProfile_PartialCADU(t *testing.T) {
+	// Symbols short of a CADU are held until the rest arrives, and
+	// discarded when the stream ends.
+	p := newProfile(t, 223, tmsc.WithReedSolomon(16, 1))
+	frame := randomFrame(rand.New(rand.NewPCG(46, 0)), 223)
+	cadu, _ := p.Encode(frame)
+	symbols := tmsc.HardSymbols(cadu)
+
+	if r := p.Decode(symbols[:100]); len(r) != 0 {
+		t.Fatalf("partial CADU decoded to %d CADUs", len(r))
+	}
+	r := p.Decode(symbols[100:])
+	if len(r) != 1 || !bytes.Equal(r[0].Frame, frame) {
+		t.Fatalf("held symbols not completed: %d CADUs", len(r))
+	}
+
+	if r := p.DecodeHard(cadu[:258]); len(r) != 0 {
+		t.Fatalf("partial CADU decoded to %d CADUs", len(r))
+	}
+	if r := p.FlushDecoder(); len(r) != 0 {
+		t.Fatalf("FlushDecoder returned %d CADUs for a partial CADU", len(r))
+	}
+	if r := p.DecodeHard(cadu); len(r) != 1 || !bytes.Equal(r[0].Frame, frame) {
+		t.Error("FlushDecoder did not discard the partial CADU")
+	}
+}
+
+func TestCodingProfile_ContinuousStream(t *testing.T) {
+	// At rate 2/3 a 4+223+32 byte CADU is 3108 symbols, so consecutive
+	// CADUs are not byte aligned on the channel.
+	p := newProfile(t, 223, tmsc.WithReedSolomon(16, 1), tmsc.WithRandomization(true),
+		tmsc.WithConvolutional(tmsc.ConvRate2_3))
+	rng := rand.New(rand.NewPCG(47, 0))
+
+	var frames [][]byte
+	var caduStream, channel []byte
+	for range 6 {
+		frame := randomFrame(rng, 223)
+		frames = append(frames, frame)
+		block, _ := tmsc.NewRS255_223().EncodeInterleaved(frame, 1)
+		caduStream = append(caduStream, tmsc.WrapCADU(block, nil, true)...)
+		encoded, err := p.Encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		channel = append(channel, encoded...)
+	}
+	channel = append(channel, p.FlushEncoder()...)
+
+	// The channel is one unterminated encoding of the CADUs back to back.
+	conv := newConv(t, tmsc.ConvRate2_3, tmsc.WithTail(false))
+	if !bytes.Equal(channel, conv.Encode(caduStream)) {
+		t.Fatal("channel differs from a continuous encoding of the CADUs")
+	}
+
+	// Decode noisy symbols fed in uneven pieces.
+	symbols := awgn(rng, channel, 0.6)
+	var results []*tmsc.CADUResult
+	for len(symbols) > 0 {
+		n := min(len(symbols), 1+rng.IntN(5000))
+		results = append(results, p.Decode(symbols[:n])...)
+		symbols = symbols[n:]
+	}
+	results = append(results, p.FlushDecoder()...)
+
+	if len(results) != len(frames) {
+		t.Fatalf("decoded %d CADUs, want %d", len(results), len(frames))
+	}
+	for i, r := range results {
+		if !r.OK() || !bytes.Equal(r.Frame, frames[i]) {
+			t.Errorf("CADU %d: frame not recovered: %+v", i, r.Corrected)
+		}
+		if r.PathMetric == 0 {
+			t.Errorf("CADU %d: PathMetric = 0 for a noisy channel", i)
+		}
+	}
+
+	// A clean stream has a zero path metric throughout.
+	for i, r := range decodeStream(p, tmsc.HardSymbols(channel)) {
+		if r.PathMetric != 0 || r.ASMErrors != 0 {
+			t.Errorf("clean CADU %d: PathMetric = %d, ASMErrors = %d", i, r.PathMetric, r.ASMErrors)
+		}
+	}
+}
+
+func TestCodingProfile_Terminated(t *testing.T) {
+	// With WithTail(true), each CADU is its own terminated block.
+	p := newProfile(t, 100, tmsc.WithConvolutional(tmsc.ConvRate1_2, tmsc.WithTail(true)))
+	if !p.ConvTerminated() {
+		t.Error("ConvTerminated() = false")
+	}
+	conv := newConv(t, tmsc.ConvRate1_2)
+	rng := rand.New(rand.NewPCG(48, 0))
+
+	var channel []byte
+	var frames [][]byte
+	for range 3 {
+		frame := randomFrame(rng, 100)
+		frames = append(frames, frame)
+		encoded, _ := p.Encode(frame)
+		if !bytes.Equal(encoded, conv.Encode(tmsc.WrapCADU(frame, nil, false))) {
+			t.Fatal("CADU not encoded as a terminated block")
+		}
+		channel = append(channel, encoded...)
+	}
+	if p.FlushEncoder() != nil {
+		t.Error("FlushEncoder returned symbols for terminated blocks")
+	}
+
+	results := p.DecodeHard(channel)
+	if len(results) != len(frames) {
+		t.Fatalf("decoded %d CADUs, want %d", len(results), len(frames))
+	}
+	for i, r := range results {
+		if !bytes.Equal(r.Frame, frames[i]) {
+			t.Errorf("CADU %d: frame differs", i)
+		}
+	}
+}
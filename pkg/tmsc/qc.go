@@ -0,0 +1,228 @@
+package tmsc
+
+// Arithmetic on the circulants of quasi-cyclic LDPC codes.
+//
+// A z×z circulant is identified with a polynomial over GF(2) modulo
+// x^z + 1, and a block of z codeword bits with the polynomial whose
+// coefficient of x^c is bit c. The circulant whose row r has 1s in
+// columns (r+s) mod z, s in S, acts on a block as multiplication by the
+// sum of x^-s over S. A parity-check or generator matrix made of
+// circulants is then a small matrix over this ring.
+
+import "math/bits"
+
+// qcPoly is a polynomial modulo x^z + 1; bit c holds the coefficient of
+// x^c.
+type qcPoly []uint64
+
+// qcRing is the ring of polynomials modulo x^z + 1.
+type qcRing struct {
+	z     int
+	words int
+	top   uint64 // bits of the last word below x^z
+}
+
+func newQCRing(z int) qcRing {
+	r := qcRing{z: z, words: (z + 63) / 64, top: ^uint64(0)}
+	if z%64 != 0 {
+		r.top = 1<<(z%64) - 1
+	}
+	return r
+}
+
+func (r qcRing) zero() qcPoly { return make(qcPoly, r.words) }
+
+// monomial returns x^e, with e taken modulo z.
+func (r qcRing) monomial(e int) qcPoly {
+	a := r.zero()
+	e = ((e % r.z) + r.z) % r.z
+	a[e/64] = 1 << (e % 64)
+	return a
+}
+
+func (a qcPoly) bit(c int) bool { return a[c/64]>>(c%64)&1 == 1 }
+
+func (a qcPoly) flip(c int) { a[c/64] ^= 1 << (c % 64) }
+
+func (a qcPoly) isZero() bool {
+	for _, w := range a {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (a qcPoly) weight() int {
+	n := 0
+	for _, w := range a {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// add sets a to a + b.
+func (a qcPoly) add(b qcPoly) {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+}
+
+// addRotated sets dst to dst + x^s·a, for 0 <= s < z.
+func (r qcRing) addRotated(dst, a qcPoly, s int) {
+	// x^s·a is a shifted left by s, with the bits that pass x^z wrapped
+	// around: a shifted right by z-s.
+	shl(dst, a, s)
+	if s > 0 {
+		shr(dst, a, r.z-s)
+	}
+	dst[r.words-1] &= r.top
+}
+
+// shl xors a shifted left by s bits into dst, dropping bits past the
+// last word.
+func shl(dst, a qcPoly, s int) {
+	ws, bs := s/64, uint(s%64)
+	for i := len(dst) - 1; i >= ws; i-- {
+		v := a[i-ws] << bs
+		if bs > 0 && i-ws > 0 {
+			v |= a[i-ws-1] >> (64 - bs)
+		}
+		dst[i] ^= v
+	}
+}
+
+// shr xors a shifted right by s bits into dst.
+func shr(dst, a qcPoly, s int) {
+	ws, bs := s/64, uint(s%64)
+	for i := 0; i+ws < len(a); i++ {
+		v := a[i+ws] >> bs
+		if bs > 0 && i+ws+1 < len(a) {
+			v |= a[i+ws+1] << (64 - bs)
+		}
+		dst[i] ^= v
+	}
+}
+
+// mul returns a·b.
+func (r qcRing) mul(a, b qcPoly) qcPoly {
+	if a.weight() > b.weight() {
+		a, b = b, a
+	}
+	out := r.zero()
+	for i, w := range a {
+		for w != 0 {
+			s := 64*i + bits.TrailingZeros64(w)
+			w &= w - 1
+			r.addRotated(out, b, s)
+		}
+	}
+	return out
+}
+
+// inverse returns the inverse of a, or false if a is not a unit. z must
+// be a power of two: then x^z + 1 = (x + 1)^z, the units are the
+// polynomials of odd weight, and a^z = a(x^z) = 1, so the inverse is
+// a^(z-1), the product of the squares a(x^(2^i)) for 2^i < z.
+func (r qcRing) inverse(a qcPoly) (qcPoly, bool) {
+	if a.weight()%2 == 0 {
+		return nil, false
+	}
+	inv := r.monomial(0)
+	sq := append(qcPoly(nil), a...)
+	for e := 1; e < r.z; e *= 2 {
+		inv = r.mul(inv, sq)
+		next := r.zero()
+		for c := range r.z {
+			if sq.bit(c) {
+				next.flip(2 * c % r.z)
+			}
+		}
+		sq = next
+	}
+	return inv, true
+}
+
+// qcChecks lists the variables of each parity check of the block matrix
+// h, whose entry (i, j) acts on block j of the codeword for checks
+// i·z to i·z+z-1.
+func qcChecks(r qcRing, h [][]qcPoly) [][]int32 {
+	checks := make([][]int32, len(h)*r.z)
+	for i, row := range h {
+		for j, a := range row {
+			for e := range r.z {
+				if !a.bit(e) {
+					continue
+				}
+				// The term x^e = x^-s links check row c to variable
+				// column c+s.
+				s := (r.z - e) % r.z
+				for c := range r.z {
+					checks[i*r.z+c] = append(checks[i*r.z+c], int32(j*r.z+(c+s)%r.z))
+				}
+			}
+		}
+	}
+	return checks
+}
+
+// qcSystematic solves h = [U | P], with P made of the last len(h) block
+// columns, for the generator of the systematic encoder: the returned
+// gen[i][j] gives parity block j as the sum over information blocks i
+// of gen[i][j] times block i, that is P^-1·U transposed. z must be a
+// power of two. Returns false if P is singular.
+func qcSystematic(r qcRing, h [][]qcPoly) ([][]qcPoly, bool) {
+	rows := len(h)
+	info := len(h[0]) - rows
+	m := make([][]qcPoly, rows)
+	for i := range h {
+		m[i] = make([]qcPoly, len(h[i]))
+		for j, a := range h[i] {
+			m[i][j] = append(qcPoly(nil), a...)
+		}
+	}
+
+	// Gauss-Jordan elimination over the parity columns. In a local ring
+	// an invertible matrix always has a unit pivot.
+	for t := range rows {
+		col := info + t
+		pivot := -1
+		for i := t; i < rows; i++ {
+			if m[i][col].weight()%2 == 1 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, false
+		}
+		m[t], m[pivot] = m[pivot], m[t]
+		inv, _ := r.inverse(m[t][col])
+		for j, a := range m[t] {
+			if !a.isZero() {
+				m[t][j] = r.mul(inv, a)
+			}
+		}
+		for i := range rows {
+			f := m[i][col]
+			if i == t || f.isZero() {
+				continue
+			}
+			f = append(qcPoly(nil), f...)
+			for j, a := range m[t] {
+				if !a.isZero() {
+					m[i][j].add(r.mul(f, a))
+				}
+			}
+		}
+	}
+
+	gen := make([][]qcPoly, info)
+	for i := range gen {
+		gen[i] = make([]qcPoly, rows)
+		for j := range rows {
+			gen[i][j] = m[j][i]
+		}
+	}
+	return gen, true
+}
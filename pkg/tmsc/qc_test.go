@@ -0,0 +1,137 @@
+package tmsc
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func randomPoly(rng *rand.Rand, r qcRing) qcPoly {
+	a := r.zero()
+	for c := range r.z {
+		if rng.IntN(2) == 1 {
+			a.flip(c)
+		}
+	}
+	return a
+}
+
+func TestQCRing_Mul(t *testing.T) {
+	for _, z := range []int{32, 511, 2048} {
+		r := newQCRing(z)
+		rng := rand.New(rand.NewPCG(1, uint64(z)))
+		a, b := randomPoly(rng, r), randomPoly(rng, r)
+
+		// Schoolbook product modulo x^z + 1.
+		want := r.zero()
+		for i := range z {
+			for j := range z {
+				if a.bit(i) && b.bit(j) {
+					want.flip((i + j) % z)
+				}
+			}
+		}
+		got := r.mul(a, b)
+		for c := range z {
+			if got.bit(c) != want.bit(c) {
+				t.Fatalf("z=%d: coefficient %d differs", z, c)
+			}
+		}
+	}
+}
+
+func TestQCRing_Inverse(t *testing.T) {
+	for _, z := range []int{32, 128, 2048} {
+		r := newQCRing(z)
+		rng := rand.New(rand.NewPCG(2, uint64(z)))
+		a := randomPoly(rng, r)
+		if a.weight()%2 == 0 {
+			a.flip(0)
+		}
+		inv, ok := r.inverse(a)
+		if !ok {
+			t.Fatalf("z=%d: odd-weight polynomial is not a unit", z)
+		}
+		if one := r.mul(a, inv); !equalPoly(one, r.monomial(0)) {
+			t.Errorf("z=%d: a·a^-1 != 1", z)
+		}
+		a.flip(0)
+		if _, ok := r.inverse(a); ok {
+			t.Errorf("z=%d: even-weight polynomial reported as a unit", z)
+		}
+	}
+}
+
+func equalPoly(a, b qcPoly) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// codeword encodes random information bits with c, leaving virtual fill
+// as set by the caller, and returns the codeword bits.
+func codeword(c *ldpcCode, rng *rand.Rand) []bool {
+	word := make([]uint64, (c.n+63)/64)
+	for v := c.fill; v < c.k; v++ {
+		if rng.IntN(2) == 1 {
+			word[v/64] |= 1 << (v % 64)
+		}
+	}
+	c.encode(word)
+	out := make([]bool, c.n)
+	for v := range out {
+		out[v] = word[v/64]>>(v%64)&1 == 1
+	}
+	return out
+}
+
+func TestC2_Encoder(t *testing.T) {
+	c := c2()
+	if c.rank != 1020 {
+		t.Errorf("rank = %d, want 1020", c.rank)
+	}
+	if len(c.gen) != 14 || len(c.gen[0]) != 2 {
+		t.Fatalf("generator is %dx%d circulants, want 14x2", len(c.gen), len(c.gen[0]))
+	}
+
+	// Without virtual fill, shifting every information block by one bit
+	// shifts every parity block by one bit, as for the circulant
+	// generator of the standard.
+	full := *c
+	full.fill = 0
+	rng := rand.New(rand.NewPCG(3, 0))
+	word := codeword(&full, rng)
+	if !c.satisfied(word) {
+		t.Fatal("codeword fails the parity checks")
+	}
+	z := c.ring.z
+	shifted := make([]uint64, (c.n+63)/64)
+	for v := range c.k {
+		if word[v/z*z+(v%z+z-1)%z] {
+			shifted[v/64] |= 1 << (v % 64)
+		}
+	}
+	c.encode(shifted)
+	for v := c.k; v < c.n; v++ {
+		want := word[v/z*z+(v%z+z-1)%z]
+		if got := shifted[v/64]>>(v%64)&1 == 1; got != want {
+			t.Fatalf("parity bit %d of the shifted word differs", v)
+		}
+	}
+}
+
+func TestAR4JA_Encoder(t *testing.T) {
+	for _, rate := range []LDPCRate{LDPCRate1_2, LDPCRate2_3, LDPCRate4_5} {
+		for _, k := range []int{1024, 4096, 16384} {
+			c := ar4ja(rate, k)
+			if c.rank != c.n-c.k {
+				t.Errorf("rate %v, k=%d: rank = %d, want %d", rate, k, c.rank, c.n-c.k)
+			}
+			if !c.satisfied(codeword(c, rand.New(rand.NewPCG(4, uint64(k))))) {
+				t.Errorf("rate %v, k=%d: codeword fails the parity checks", rate, k)
+			}
+		}
+	}
+}
@@ -1,273 +1,186 @@
 package tmsc
 
 // Reed-Solomon codec for CCSDS TM Synchronization and Channel Coding
-// per CCSDS 131.0-B-4.
+// per CCSDS 131.0-B-4, built on the generic GF(2^m) codec of package rs.
 //
 // Supports (255,223) with 32 parity symbols (corrects up to 16 errors)
 // and (255,239) with 16 parity symbols (corrects up to 8 errors).
 //
 // Field: GF(2^8) with primitive polynomial 0x187.
-// First consecutive root (FCR): 112.
-// Generator: g(x) = ∏(x - α^(112+i)) for i = 0..nroots-1.
+// Generator: g(x) = ∏(x - β^j) for j = 128-E..127+E, where β = α^11
+// and E = nroots/2 is the error correction capability. The first
+// consecutive root (FCR) is 112 for E=16 and 120 for E=8.
+//
+// Besides errors-only decoding, the decoder corrects erasures at known
+// positions: ν errors and ρ erasures as long as 2ν + ρ <= nroots.
+//
+// Shortened codewords (virtual fill) and the Berlekamp dual-basis symbol
+// representation of Annex F are available as options.
+
+import "github.com/ravisuhag/astro/pkg/rs"
 
 const (
-	rsNN  = 255 // codeword length
-	rsFCR = 112 // first consecutive root
+	rsNN   = 255   // codeword length
+	rsPoly = 0x187 // field polynomial x^8 + x^7 + x^2 + x + 1
+	rsPrim = 11    // generator roots are powers of β = α^11
 )
 
 // RSCodec holds precomputed state for a CCSDS Reed-Solomon code.
 type RSCodec struct {
-	nroots int    // number of parity symbols
-	gen    []byte // generator polynomial coefficients (nroots+1 entries, monic)
+	code   *rs.Codec // nil if the virtual fill leaves no data symbols
+	nroots int       // number of parity symbols
+	fill   int       // virtual fill symbols per codeword
+	dual   bool      // symbols are in dual-basis representation
+}
+
+// RSOption configures an RSCodec.
+type RSOption func(*RSCodec)
+
+// WithVirtualFill shortens each codeword by n symbols of virtual fill:
+// leading zero data symbols that are encoded but not transmitted. Encode
+// then takes DataLen() = 255 - nroots - n bytes and returns CodewordLen()
+// = 255 - n bytes. The default is 0. A fill outside 0 to 254 - nroots
+// leaves the codec unable to encode: Encode and Decode return
+// ErrInvalidDataLength.
+func WithVirtualFill(n int) RSOption {
+	return func(rs *RSCodec) {
+		rs.fill = n
+	}
+}
+
+// WithDualBasis sets whether data and codeword symbols use the Berlekamp
+// dual-basis representation that CCSDS 131.0-B-4 mandates on the channel.
+// The default is false: symbols are in the conventional representation.
+func WithDualBasis(dual bool) RSOption {
+	return func(rs *RSCodec) {
+		rs.dual = dual
+	}
 }
 
 // NewRS255_223 returns an RSCodec for CCSDS (255,223) with 32 parity symbols.
 // This code can correct up to 16 symbol errors per codeword.
-func NewRS255_223() *RSCodec {
-	return newRSCodec(32)
+func NewRS255_223(opts ...RSOption) *RSCodec {
+	return newRSCodec(32, opts)
 }
 
 // NewRS255_239 returns an RSCodec for CCSDS (255,239) with 16 parity symbols.
 // This code can correct up to 8 symbol errors per codeword.
-func NewRS255_239() *RSCodec {
-	return newRSCodec(16)
+func NewRS255_239(opts ...RSOption) *RSCodec {
+	return newRSCodec(16, opts)
 }
 
-func newRSCodec(nroots int) *RSCodec {
-	// Build generator polynomial:
-	// g(x) = (x - α^FCR)(x - α^(FCR+1))...(x - α^(FCR+nroots-1))
-	gen := make([]byte, nroots+1)
-	gen[0] = 1
-
-	for i := range nroots {
-		root := gfPow(rsFCR + i)
-		// Multiply gen by (x - root) = (x + root) in GF(2^8)
-		for j := i + 1; j > 0; j-- {
-			gen[j] = gen[j-1] ^ gfMul(gen[j], root)
-		}
-		gen[0] = gfMul(gen[0], root)
+func newRSCodec(nroots int, opts []RSOption) *RSCodec {
+	c := &RSCodec{nroots: nroots}
+	for _, opt := range opts {
+		opt(c)
 	}
-
-	return &RSCodec{nroots: nroots, gen: gen}
+	// The parameters are fixed; only an out-of-range fill fails.
+	c.code, _ = rs.NewCodec(8, rsPoly, 128-nroots/2, rsPrim, nroots, rs.WithShortening(c.fill))
+	return c
 }
 
 // NRoots returns the number of parity symbols.
 func (rs *RSCodec) NRoots() int { return rs.nroots }
 
-// DataLen returns the data length per codeword (255 - nroots).
-func (rs *RSCodec) DataLen() int { return rsNN - rs.nroots }
+// DataLen returns the data length per codeword (255 - nroots - virtual fill).
+func (rs *RSCodec) DataLen() int { return rsNN - rs.nroots - rs.fill }
 
-// Encode appends nroots parity symbols to data and returns a 255-byte codeword.
-// The input must be exactly DataLen() bytes. The input slice is not modified.
-func (rs *RSCodec) Encode(data []byte) ([]byte, error) {
-	k := rs.DataLen()
-	if len(data) != k {
-		return nil, ErrInvalidDataLength
-	}
-
-	codeword := make([]byte, rsNN)
-	copy(codeword, data)
-
-	// Systematic encoding: compute remainder of data * x^nroots / g(x)
-	parity := make([]byte, rs.nroots)
-	for i := range k {
-		feedback := data[i] ^ parity[0]
-		if feedback != 0 {
-			for j := range rs.nroots - 1 {
-				parity[j] = parity[j+1] ^ gfMul(feedback, rs.gen[rs.nroots-1-j])
-			}
-			parity[rs.nroots-1] = gfMul(feedback, rs.gen[0])
-		} else {
-			copy(parity, parity[1:])
-			parity[rs.nroots-1] = 0
-		}
-	}
+// CodewordLen returns the transmitted codeword length (255 - virtual fill).
+func (rs *RSCodec) CodewordLen() int { return rsNN - rs.fill }
 
-	copy(codeword[k:], parity)
-	return codeword, nil
-}
+// VirtualFill returns the number of virtual fill symbols per codeword.
+func (rs *RSCodec) VirtualFill() int { return rs.fill }
 
-// Decode corrects errors in a 255-byte codeword and returns the corrected
-// data (first DataLen() bytes), the number of corrected symbol errors,
-// and any error. Returns ErrUncorrectable if errors exceed correction capability.
-// The input slice is not modified.
-func (rs *RSCodec) Decode(codeword []byte) ([]byte, int, error) {
-	if len(codeword) != rsNN {
-		return nil, 0, ErrInvalidDataLength
-	}
+// DualBasis reports whether symbols use the dual-basis representation.
+func (rs *RSCodec) DualBasis() bool { return rs.dual }
 
-	work := make([]byte, rsNN)
-	copy(work, codeword)
-
-	// Step 1: Compute syndromes S_i = R(α^(FCR+i))
-	syndromes := make([]byte, rs.nroots)
-	allZero := true
-	for i := range rs.nroots {
-		s := byte(0)
-		for j := range rsNN {
-			s = gfMul(s, gfPow(rsFCR+i)) ^ work[j]
-		}
-		syndromes[i] = s
-		if s != 0 {
-			allZero = false
-		}
+// Encode appends nroots parity symbols to data and returns a codeword of
+// CodewordLen() bytes, 255 without virtual fill. The input must be exactly
+// DataLen() bytes. The input slice is not modified.
+func (rs *RSCodec) Encode(data []byte) ([]byte, error) {
+	if rs.code == nil || len(data) != rs.DataLen() {
+		return nil, ErrInvalidDataLength
 	}
-
-	if allZero {
-		return work[:rs.DataLen()], 0, nil
+	if !rs.dual {
+		return rs.code.Encode(data)
 	}
 
-	// Step 2: Berlekamp-Massey → error-locator polynomial σ(x)
-	sigma, nerrs, err := rs.berlekampMassey(syndromes)
+	codeword, err := rs.code.Encode(fromDualBasis(data))
 	if err != nil {
-		return nil, 0, err
-	}
-
-	// Step 3: Chien search → error positions
-	errPos := rs.chienSearch(sigma, nerrs)
-	if errPos == nil {
-		return nil, 0, ErrUncorrectable
+		return nil, err
 	}
-
-	// Step 4: Forney algorithm → error magnitudes, correct in-place
-	rs.forney(work, syndromes, sigma, errPos)
-
-	return work[:rs.DataLen()], len(errPos), nil
+	return toDualBasis(codeword), nil
 }
 
-// berlekampMassey computes the error-locator polynomial using the
-// Berlekamp-Massey algorithm. Returns the polynomial, degree (number
-// of errors), and any error.
-func (rs *RSCodec) berlekampMassey(syndromes []byte) ([]byte, int, error) {
-	n := rs.nroots
-	// σ(x): error-locator polynomial
-	sigma := make([]byte, n+1)
-	sigma[0] = 1
-	// B(x): auxiliary polynomial
-	B := make([]byte, n+1)
-	B[0] = 1
-
-	L := 0 // current number of assumed errors
-
-	for k := range n {
-		// Compute discrepancy Δ
-		delta := syndromes[k]
-		for j := 1; j <= L; j++ {
-			delta ^= gfMul(sigma[j], syndromes[k-j])
-		}
-
-		// Shift B: B(x) = x * B(x)
-		copy(B[1:], B)
-		B[0] = 0
-
-		if delta != 0 {
-			T := make([]byte, n+1)
-			copy(T, sigma)
-
-			// σ(x) = σ(x) - Δ * B(x)
-			for j := range n + 1 {
-				sigma[j] ^= gfMul(delta, B[j])
-			}
-
-			if 2*L <= k {
-				L = k + 1 - L
-				// B(x) = Δ^{-1} * T(x)
-				inv := gfInv(delta)
-				for j := range n + 1 {
-					B[j] = gfMul(T[j], inv)
-				}
-			}
-		}
-	}
-
-	if L > rs.nroots/2 {
-		return nil, 0, ErrUncorrectable
-	}
-
-	return sigma, L, nil
+// Decode corrects errors in a codeword of CodewordLen() bytes and returns
+// the corrected data (first DataLen() bytes), the number of corrected
+// symbol errors, and any error. Returns ErrUncorrectable if errors exceed
+// correction capability, or if the decoder places an error in the virtual
+// fill, which is known to be zero. The input slice is not modified.
+func (rs *RSCodec) Decode(codeword []byte) ([]byte, int, error) {
+	return rs.DecodeWithErasures(codeword, nil)
 }
 
-// chienSearch finds the roots of the error-locator polynomial σ(x)
-// by exhaustive evaluation. If σ(α^{-i}) == 0, the error is at codeword
-// byte index (254-i), since byte j corresponds to the coefficient of x^(254-j).
-// Returns the codeword byte indices or nil if the count doesn't match.
-func (rs *RSCodec) chienSearch(sigma []byte, nerrs int) []int {
-	var positions []int
-
-	for i := range rsNN {
-		xiInv := gfPow(255 - i) // α^{-i}
-		if evalPoly(sigma, xiInv) == 0 {
-			pos := (rsNN - 1) - i // map to codeword byte index
-			if pos >= 0 && pos < rsNN {
-				positions = append(positions, pos)
-			}
-		}
-	}
-
-	if len(positions) != nerrs {
-		return nil
+// DecodeWithErasures corrects errors and erasures in a codeword of
+// CodewordLen() bytes. Erasures are byte indices into the transmitted
+// codeword of symbols known to be unreliable, for example flagged by an
+// inner decoder; their received values are ignored. The code corrects ν
+// errors and ρ erasures as long as 2ν + ρ <= NRoots(), so up to NRoots()
+// erasures without errors. Duplicate positions count once.
+//
+// Returns the corrected data, the number of symbols whose value changed,
+// and any error. Returns ErrInvalidErasure for a position outside the
+// codeword and ErrUncorrectable if the errors and erasures exceed the
+// correction capability. The input slice is not modified.
+func (rs *RSCodec) DecodeWithErasures(codeword []byte, erasures []int) ([]byte, int, error) {
+	if rs.code == nil || len(codeword) != rs.CodewordLen() {
+		return nil, 0, ErrInvalidDataLength
 	}
-	return positions
+	return rs.data(rs.code.DecodeWithErasures(rs.conventional(codeword), erasures))
 }
 
-// evalPoly evaluates polynomial p at point x using direct power accumulation.
-// p[0] is the constant term.
-func evalPoly(p []byte, x byte) byte {
-	val := byte(0)
-	xPow := byte(1)
-	for _, coeff := range p {
-		val ^= gfMul(coeff, xPow)
-		xPow = gfMul(xPow, x)
+// DecodeWithReliability corrects a codeword of CodewordLen() bytes using
+// per-byte reliability, higher meaning more reliable, such as the value
+// returned by ByteReliability. If errors-only decoding fails, it retries
+// with the least reliable symbols erased, two more at a time, up to
+// NRoots()/2 erasures so that half of the parity is left to detect a
+// miscorrection. Only symbols less reliable than the most reliable one
+// are erased, so uniform reliability gives errors-only decoding.
+//
+// Returns the corrected data, the number of symbols whose value changed,
+// and any error. Returns ErrInvalidDataLength if reliability does not
+// match the codeword length. The input slices are not modified.
+func (rs *RSCodec) DecodeWithReliability(codeword, reliability []byte) ([]byte, int, error) {
+	if rs.code == nil || len(codeword) != rs.CodewordLen() || len(reliability) != len(codeword) {
+		return nil, 0, ErrInvalidDataLength
 	}
-	return val
+	return rs.data(rs.code.DecodeWithReliability(rs.conventional(codeword), reliability))
 }
 
-// forney computes error magnitudes using the Forney algorithm and
-// corrects the codeword in-place.
-func (rs *RSCodec) forney(codeword []byte, syndromes []byte, sigma []byte, errPos []int) {
-	n := rs.nroots
-
-	// Compute error-evaluator polynomial:
-	// Ω(x) = S(x) · σ(x) mod x^nroots
-	omega := make([]byte, n)
-	for i := range n {
-		val := byte(0)
-		for j := range i + 1 {
-			if j < len(sigma) {
-				val ^= gfMul(syndromes[i-j], sigma[j])
-			}
-		}
-		omega[i] = val
+// conventional returns received symbols in conventional representation.
+func (rs *RSCodec) conventional(symbols []byte) []byte {
+	if rs.dual {
+		return fromDualBasis(symbols)
 	}
+	return symbols
+}
 
-	// Formal derivative of σ(x) in characteristic 2:
-	// σ'(x) = σ_1 + σ_3·x^2 + σ_5·x^4 + ... (only odd-indexed coefficients)
-	sigmaD := make([]byte, len(sigma))
-	for j := 1; j < len(sigma); j += 2 {
-		sigmaD[j-1] = sigma[j]
+// data passes on the result of the generic decoder, with the corrected
+// data converted back to dual basis if needed.
+func (rs *RSCodec) data(data []byte, corrected int, err error) ([]byte, int, error) {
+	if err != nil {
+		return nil, 0, err
 	}
-
-	for _, pos := range errPos {
-		// Byte at codeword[pos] corresponds to x^(254-pos)
-		power := (rsNN - 1) - pos
-		xiInv := gfPow(255 - power) // X_i^{-1}
-
-		omegaVal := evalPoly(omega, xiInv)
-		sigmaDVal := evalPoly(sigmaD, xiInv)
-
-		if sigmaDVal == 0 {
-			continue
-		}
-
-		// Forney: e_i = X_i^{1-FCR} · Ω(X_i^{-1}) / σ'(X_i^{-1})
-		magnitude := gfMul(gfMul(gfPow(power*(1-rsFCR)), omegaVal), gfInv(sigmaDVal))
-		codeword[pos] ^= magnitude
+	if rs.dual {
+		data = toDualBasis(data)
 	}
+	return data, corrected, nil
 }
 
 // EncodeInterleaved encodes data using symbol interleaving at the given depth.
 // Input length must be exactly depth * DataLen() bytes.
-// Returns a slice of length depth * 255.
+// Returns a slice of length depth * CodewordLen(). Virtual fill and dual
+// basis apply to each codeword.
 func (rs *RSCodec) EncodeInterleaved(data []byte, depth int) ([]byte, error) {
 	if !validInterleaveDepth(depth) {
 		return nil, ErrInvalidInterleaveDepth
@@ -290,8 +203,9 @@ func (rs *RSCodec) EncodeInterleaved(data []byte, depth int) ([]byte, error) {
 		codewords[d] = cw
 	}
 
-	out := make([]byte, depth*rsNN)
-	for i := range rsNN {
+	n := rs.CodewordLen()
+	out := make([]byte, depth*n)
+	for i := range n {
 		for d := range depth {
 			out[i*depth+d] = codewords[d][i]
 		}
@@ -300,13 +214,63 @@ func (rs *RSCodec) EncodeInterleaved(data []byte, depth int) ([]byte, error) {
 }
 
 // DecodeInterleaved decodes interleaved data, correcting errors.
-// Input length must be exactly depth * 255 bytes.
+// Input length must be exactly depth * CodewordLen() bytes.
 // Returns corrected data of length depth * DataLen(), total corrections, and error.
 func (rs *RSCodec) DecodeInterleaved(data []byte, depth int) ([]byte, int, error) {
+	return rs.decodeInterleaved(data, depth, func(cw []byte, _ int) ([]byte, int, error) {
+		return rs.Decode(cw)
+	})
+}
+
+// DecodeInterleavedWithErasures decodes interleaved data, correcting
+// errors and erasures. Erasures are byte indices into data; byte i
+// belongs to codeword i%depth. Each codeword corrects ν errors and ρ
+// erasures as long as 2ν + ρ <= NRoots().
+// Returns corrected data of length depth * DataLen(), total corrections,
+// and error.
+func (rs *RSCodec) DecodeInterleavedWithErasures(data []byte, erasures []int, depth int) ([]byte, int, error) {
 	if !validInterleaveDepth(depth) {
 		return nil, 0, ErrInvalidInterleaveDepth
 	}
-	if len(data) != depth*rsNN {
+	perCodeword := make([][]int, depth)
+	for _, pos := range erasures {
+		if pos < 0 || pos >= len(data) {
+			return nil, 0, ErrInvalidErasure
+		}
+		perCodeword[pos%depth] = append(perCodeword[pos%depth], pos/depth)
+	}
+	return rs.decodeInterleaved(data, depth, func(cw []byte, d int) ([]byte, int, error) {
+		return rs.DecodeWithErasures(cw, perCodeword[d])
+	})
+}
+
+// DecodeInterleavedWithReliability decodes interleaved data using
+// per-byte reliability, one value per byte of data, as described for
+// DecodeWithReliability.
+// Returns corrected data of length depth * DataLen(), total corrections,
+// and error.
+func (rs *RSCodec) DecodeInterleavedWithReliability(data, reliability []byte, depth int) ([]byte, int, error) {
+	if len(reliability) != len(data) {
+		return nil, 0, ErrInvalidDataLength
+	}
+	n := rs.CodewordLen()
+	return rs.decodeInterleaved(data, depth, func(cw []byte, d int) ([]byte, int, error) {
+		rel := make([]byte, n)
+		for i := range n {
+			rel[i] = reliability[i*depth+d]
+		}
+		return rs.DecodeWithReliability(cw, rel)
+	})
+}
+
+// decodeInterleaved de-interleaves data into depth codewords, decodes
+// codeword d with decode, and re-interleaves the corrected data.
+func (rs *RSCodec) decodeInterleaved(data []byte, depth int, decode func(cw []byte, d int) ([]byte, int, error)) ([]byte, int, error) {
+	if !validInterleaveDepth(depth) {
+		return nil, 0, ErrInvalidInterleaveDepth
+	}
+	n := rs.CodewordLen()
+	if len(data) != depth*n {
 		return nil, 0, ErrInvalidDataLength
 	}
 
@@ -315,8 +279,8 @@ func (rs *RSCodec) DecodeInterleaved(data []byte, depth int) ([]byte, int, error
 
 	codewords := make([][]byte, depth)
 	for d := range depth {
-		cw := make([]byte, rsNN)
-		for i := range rsNN {
+		cw := make([]byte, n)
+		for i := range n {
 			cw[i] = data[i*depth+d]
 		}
 		codewords[d] = cw
@@ -324,7 +288,7 @@ func (rs *RSCodec) DecodeInterleaved(data []byte, depth int) ([]byte, int, error
 
 	decoded := make([][]byte, depth)
 	for d := range depth {
-		corrected, corr, err := rs.Decode(codewords[d])
+		corrected, corr, err := decode(codewords[d], d)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -348,3 +312,47 @@ func validInterleaveDepth(depth int) bool {
 	}
 	return false
 }
+
+// rsDualBasis holds the rows of the Annex F matrix that converts a symbol
+// from conventional to dual-basis representation, least significant input
+// bit last.
+var rsDualBasis = [8]byte{0x8D, 0xEF, 0xEC, 0x86, 0xFA, 0x99, 0xAF, 0x7B}
+
+// Conversion tables between conventional and dual-basis symbols.
+var (
+	rsToDual   [256]byte
+	rsFromDual [256]byte
+)
+
+func init() {
+	for v := range 256 {
+		var d byte
+		for bit := range 8 {
+			if v>>bit&1 == 1 {
+				d ^= rsDualBasis[7-bit]
+			}
+		}
+		rsToDual[v] = d
+		rsFromDual[d] = byte(v)
+	}
+}
+
+// toDualBasis returns symbols converted from conventional to dual-basis
+// representation.
+func toDualBasis(symbols []byte) []byte {
+	out := make([]byte, len(symbols))
+	for i, s := range symbols {
+		out[i] = rsToDual[s]
+	}
+	return out
+}
+
+// fromDualBasis returns symbols converted from dual-basis to conventional
+// representation.
+func fromDualBasis(symbols []byte) []byte {
+	out := make([]byte, len(symbols))
+	for i, s := range symbols {
+		out[i] = rsFromDual[s]
+	}
+	return out
+}
@@ -347,3 +347,343 @@ func TestRS_Interleave_WrongDataLength(t *testing.T) {
 		t.Errorf("expected ErrInvalidDataLength, got %v", err)
 	}
 }
+
+// --- Generator Tests ---
+
+func TestRS_Generator(t *testing.T) {
+	// The parity of a unit data symbol in the last position is the generator
+	// polynomial without its leading coefficient. CCSDS chose roots β^j,
+	// j = 128-E..127+E with β = α^11, so the generator is self-reciprocal.
+	rs := tmsc.NewRS255_223()
+	data := make([]byte, 223)
+	data[222] = 1
+	cw, _ := rs.Encode(data)
+	want := []byte{
+		0x5B, 0x7F, 0x56, 0x10, 0x1E, 0x0D, 0xEB, 0x61, 0xA5, 0x08, 0x2A, 0x36, 0x56, 0xAB, 0x20, 0x71,
+		0x20, 0xAB, 0x56, 0x36, 0x2A, 0x08, 0xA5, 0x61, 0xEB, 0x0D, 0x1E, 0x10, 0x56, 0x7F, 0x5B, 0x01,
+	}
+	if !bytes.Equal(cw[223:], want) {
+		t.Errorf("generator = %x, want %x", cw[223:], want)
+	}
+}
+
+func TestRS_ReversedCodeword(t *testing.T) {
+	// With a self-reciprocal generator, a reversed codeword is a codeword.
+	rng := rand.New(rand.NewPCG(789, 0))
+	for _, rs := range []*tmsc.RSCodec{tmsc.NewRS255_223(), tmsc.NewRS255_239(), tmsc.NewRS255_223(tmsc.WithDualBasis(true))} {
+		data := make([]byte, rs.DataLen())
+		for i := range data {
+			data[i] = byte(rng.IntN(256))
+		}
+		cw, _ := rs.Encode(data)
+		for i, j := 0, len(cw)-1; i < j; i, j = i+1, j-1 {
+			cw[i], cw[j] = cw[j], cw[i]
+		}
+		if _, corr, err := rs.Decode(cw); err != nil || corr != 0 {
+			t.Errorf("%d roots: reversed codeword has %d errors, %v", rs.NRoots(), corr, err)
+		}
+	}
+}
+
+// --- Dual Basis Tests ---
+
+func TestRS_DualBasis(t *testing.T) {
+	rng := rand.New(rand.NewPCG(790, 0))
+	conv := tmsc.NewRS255_223()
+	dual := tmsc.NewRS255_223(tmsc.WithDualBasis(true))
+	if conv.DualBasis() || !dual.DualBasis() {
+		t.Fatal("DualBasis() does not report the option")
+	}
+
+	// Zero is zero in both representations.
+	zero, _ := dual.Encode(make([]byte, 223))
+	if !bytes.Equal(zero, make([]byte, 255)) {
+		t.Error("zero data does not encode to a zero codeword")
+	}
+
+	// The dual-basis image of 1 is 0x7B, so a unit data symbol gives the
+	// generator in dual basis, which keeps its symmetry.
+	data := make([]byte, 223)
+	data[222] = 0x7B
+	cw, _ := dual.Encode(data)
+	if cw[254] != 0x7B || cw[223] != cw[253] {
+		t.Errorf("dual-basis generator = %x", cw[223:])
+	}
+
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	cwConv, _ := conv.Encode(data)
+	cwDual, _ := dual.Encode(data)
+	if !bytes.Equal(cwDual[:223], data) {
+		t.Error("dual-basis codeword is not systematic")
+	}
+	if bytes.Equal(cwConv[223:], cwDual[223:]) {
+		t.Error("dual-basis parity equals conventional parity")
+	}
+	if _, _, err := conv.Decode(cwDual); err == nil {
+		t.Error("conventional decoder accepted a dual-basis codeword")
+	}
+
+	for _, pos := range rng.Perm(255)[:16] {
+		cwDual[pos] ^= byte(rng.IntN(255) + 1)
+	}
+	decoded, corr, err := dual.Decode(cwDual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 16 || !bytes.Equal(decoded, data) {
+		t.Errorf("corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+}
+
+// --- Virtual Fill Tests ---
+
+func TestRS_VirtualFill(t *testing.T) {
+	rng := rand.New(rand.NewPCG(791, 0))
+	full := tmsc.NewRS255_223()
+	short := tmsc.NewRS255_223(tmsc.WithVirtualFill(95))
+	if short.DataLen() != 128 || short.CodewordLen() != 160 || short.VirtualFill() != 95 {
+		t.Fatalf("DataLen() = %d, CodewordLen() = %d, VirtualFill() = %d",
+			short.DataLen(), short.CodewordLen(), short.VirtualFill())
+	}
+
+	data := make([]byte, 128)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	cw, err := short.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwFull, _ := full.Encode(append(make([]byte, 95), data...))
+	if !bytes.Equal(cw, cwFull[95:]) {
+		t.Error("shortened codeword differs from the full codeword without its fill")
+	}
+
+	for _, pos := range rng.Perm(160)[:16] {
+		cw[pos] ^= byte(rng.IntN(255) + 1)
+	}
+	decoded, corr, err := short.Decode(cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 16 || !bytes.Equal(decoded, data) {
+		t.Errorf("corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+
+	if _, _, err := short.Decode(cwFull); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Decode(255 bytes): got %v, want ErrInvalidDataLength", err)
+	}
+	bad := tmsc.NewRS255_239(tmsc.WithVirtualFill(239))
+	if _, err := bad.Encode(nil); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Encode with no data symbols: got %v, want ErrInvalidDataLength", err)
+	}
+}
+
+func TestRS_Interleave_VirtualFillDualBasis(t *testing.T) {
+	rng := rand.New(rand.NewPCG(792, 0))
+	tests := []struct {
+		name     string
+		frameLen int
+		fill     int
+	}{
+		{"1115-byte frame", 1115, 0},
+		{"1000-byte frame", 1000, 23},
+	}
+	for _, tt := range tests {
+		rs := tmsc.NewRS255_223(tmsc.WithVirtualFill(tt.fill), tmsc.WithDualBasis(true))
+		data := make([]byte, tt.frameLen)
+		for i := range data {
+			data[i] = byte(rng.IntN(256))
+		}
+		encoded, err := rs.EncodeInterleaved(data, 5)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(encoded) != tt.frameLen+5*32 {
+			t.Fatalf("%s: encoded length = %d", tt.name, len(encoded))
+		}
+
+		// A burst of 80 bytes puts 16 errors in each codeword.
+		for i := 100; i < 180; i++ {
+			encoded[i] ^= 0xFF
+		}
+		decoded, corr, err := rs.DecodeInterleaved(encoded, 5)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if corr != 80 || !bytes.Equal(decoded, data) {
+			t.Errorf("%s: corrections = %d, data match %v", tt.name, corr, bytes.Equal(decoded, data))
+		}
+	}
+}
+
+func TestRS_DecodeWithErasures(t *testing.T) {
+	rng := rand.New(rand.NewPCG(793, 0))
+	tests := []struct {
+		name      string
+		rs        *tmsc.RSCodec
+		errors    int
+		erasures  int
+		wantError bool
+	}{
+		{"32 erasures", tmsc.NewRS255_223(), 0, 32, false},
+		{"10 errors 12 erasures", tmsc.NewRS255_223(), 10, 12, false},
+		{"16 errors no erasures", tmsc.NewRS255_223(), 16, 0, false},
+		{"1 error 31 erasures", tmsc.NewRS255_223(), 1, 31, true},
+		{"33 erasures", tmsc.NewRS255_223(), 0, 33, true},
+		{"16 erasures E=8", tmsc.NewRS255_239(), 0, 16, false},
+		{"3 errors 10 erasures E=8", tmsc.NewRS255_239(), 3, 10, false},
+		{"shortened dual basis", tmsc.NewRS255_223(tmsc.WithVirtualFill(95), tmsc.WithDualBasis(true)), 6, 20, false},
+	}
+	for _, tt := range tests {
+		data := make([]byte, tt.rs.DataLen())
+		for i := range data {
+			data[i] = byte(rng.IntN(256))
+		}
+		codeword, _ := tt.rs.Encode(data)
+
+		// Erased symbols are overwritten; errors go to other positions.
+		positions := rng.Perm(len(codeword))[:tt.errors+tt.erasures]
+		erasures := positions[tt.errors:]
+		for _, pos := range positions[:tt.errors] {
+			codeword[pos] ^= byte(1 + rng.IntN(255))
+		}
+		for _, pos := range erasures {
+			codeword[pos] = 0
+		}
+
+		decoded, corr, err := tt.rs.DecodeWithErasures(codeword, erasures)
+		if tt.wantError {
+			if !errors.Is(err, tmsc.ErrUncorrectable) {
+				t.Errorf("%s: got %v, want ErrUncorrectable", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%s: data mismatch", tt.name)
+		}
+		if corr < tt.errors || corr > tt.errors+tt.erasures {
+			t.Errorf("%s: corrections = %d", tt.name, corr)
+		}
+	}
+}
+
+func TestRS_DecodeWithErasures_CorrectSymbols(t *testing.T) {
+	// Erasing symbols that were received correctly changes nothing.
+	rs := tmsc.NewRS255_223()
+	data := make([]byte, rs.DataLen())
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	codeword, _ := rs.Encode(data)
+	codeword[3] ^= 0x11
+
+	decoded, corr, err := rs.DecodeWithErasures(codeword, []int{3, 40, 40, 254})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 1 || !bytes.Equal(decoded, data) {
+		t.Errorf("corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+}
+
+func TestRS_DecodeWithErasures_InvalidPosition(t *testing.T) {
+	rs := tmsc.NewRS255_223(tmsc.WithVirtualFill(10))
+	codeword := make([]byte, rs.CodewordLen())
+	for _, pos := range []int{-1, rs.CodewordLen()} {
+		if _, _, err := rs.DecodeWithErasures(codeword, []int{pos}); !errors.Is(err, tmsc.ErrInvalidErasure) {
+			t.Errorf("position %d: got %v, want ErrInvalidErasure", pos, err)
+		}
+	}
+	if _, _, err := rs.DecodeInterleavedWithErasures(make([]byte, 2*rs.CodewordLen()), []int{2 * rs.CodewordLen()}, 2); !errors.Is(err, tmsc.ErrInvalidErasure) {
+		t.Errorf("interleaved: got %v, want ErrInvalidErasure", err)
+	}
+}
+
+func TestRS_DecodeWithReliability(t *testing.T) {
+	rs := tmsc.NewRS255_223()
+	data := make([]byte, rs.DataLen())
+	for i := range data {
+		data[i] = byte(i)
+	}
+	codeword, _ := rs.Encode(data)
+
+	// 20 errors are too many for errors-only decoding, but with 8 of them
+	// flagged as unreliable the remaining 12 errors and 8 erasures fit.
+	reliability := bytes.Repeat([]byte{100}, len(codeword))
+	for i := range 20 {
+		codeword[10*i] ^= 0xC3
+		if i < 8 {
+			reliability[10*i] = byte(i)
+		}
+	}
+	if _, _, err := rs.Decode(codeword); !errors.Is(err, tmsc.ErrUncorrectable) {
+		t.Fatalf("Decode: got %v, want ErrUncorrectable", err)
+	}
+	decoded, corr, err := rs.DecodeWithReliability(codeword, reliability)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 20 || !bytes.Equal(decoded, data) {
+		t.Errorf("corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+
+	// Uniform reliability gives errors-only decoding.
+	if _, _, err := rs.DecodeWithReliability(codeword, bytes.Repeat([]byte{100}, len(codeword))); !errors.Is(err, tmsc.ErrUncorrectable) {
+		t.Errorf("uniform: got %v, want ErrUncorrectable", err)
+	}
+	if _, _, err := rs.DecodeWithReliability(codeword, reliability[1:]); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("short reliability: got %v, want ErrInvalidDataLength", err)
+	}
+}
+
+func TestRS_Interleave_Erasures(t *testing.T) {
+	rs := tmsc.NewRS255_223()
+	data := make([]byte, 4*rs.DataLen())
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	encoded, _ := rs.EncodeInterleaved(data, 4)
+
+	// A burst of 120 bytes puts 30 errors in each codeword: too many to
+	// correct, but not to fill as erasures.
+	var erasures []int
+	reliability := bytes.Repeat([]byte{127}, len(encoded))
+	for i := 200; i < 320; i++ {
+		encoded[i] ^= 0x3C
+		erasures = append(erasures, i)
+		reliability[i] = 0
+	}
+	if _, _, err := rs.DecodeInterleaved(encoded, 4); !errors.Is(err, tmsc.ErrUncorrectable) {
+		t.Fatalf("DecodeInterleaved: got %v, want ErrUncorrectable", err)
+	}
+
+	decoded, corr, err := rs.DecodeInterleavedWithErasures(encoded, erasures, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 120 || !bytes.Equal(decoded, data) {
+		t.Errorf("erasures: corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+
+	// Reliability-guided decoding erases at most 16 symbols per codeword,
+	// so it needs fewer errors: 20 per codeword, 16 of them erased.
+	if _, _, err := rs.DecodeInterleavedWithReliability(encoded, reliability, 4); !errors.Is(err, tmsc.ErrUncorrectable) {
+		t.Errorf("reliability: got %v, want ErrUncorrectable", err)
+	}
+	for i := 280; i < 320; i++ {
+		encoded[i] ^= 0x3C
+	}
+	decoded, corr, err = rs.DecodeInterleavedWithReliability(encoded, reliability, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr != 80 || !bytes.Equal(decoded, data) {
+		t.Errorf("reliability: corrections = %d, data match %v", corr, bytes.Equal(decoded, data))
+	}
+}
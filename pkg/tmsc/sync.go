@@ -0,0 +1,315 @@
+package tmsc
+
+// Frame synchronization for CCSDS TM Synchronization and Channel Coding
+// per CCSDS 131.0-B-4 Section 9 and CCSDS 130.1-G.
+//
+// The Synchronizer finds CADUs in an unaligned bitstream by correlating
+// against the ASM at every bit offset, tolerating bit errors and inverted
+// polarity. Once a marker is found it follows the usual state machine:
+//
+//	Search   --marker found---------------------------> Check
+//	Check    --marker at expected position------------> Lock (after N checks)
+//	Check    --marker missing-------------------------> Search
+//	Lock     --marker missing-------------------------> Flywheel
+//	Flywheel --marker at expected position------------> Lock
+//	Flywheel --marker missing more than M times-------> Search
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+)
+
+const (
+	syncReadSize       = 4096
+	syncLockErrors     = 4 // default ASM bit errors tolerated once acquired
+	syncCheckFrames    = 1 // default markers confirmed before lock
+	syncFlywheelFrames = 3 // default missed markers tolerated in lock
+)
+
+// SyncState is the state of a Synchronizer.
+type SyncState int
+
+// Synchronizer states.
+const (
+	SyncSearch   SyncState = iota // correlating at every bit offset
+	SyncCheck                     // marker found, confirming the frame period
+	SyncLock                      // markers found at every expected position
+	SyncFlywheel                  // markers missed, still trusting the period
+)
+
+// String returns the state name in lower case, such as "lock".
+func (s SyncState) String() string {
+	switch s {
+	case SyncSearch:
+		return "search"
+	case SyncCheck:
+		return "check"
+	case SyncLock:
+		return "lock"
+	case SyncFlywheel:
+		return "flywheel"
+	}
+	return "unknown"
+}
+
+// SyncedCADU is a CADU located by a Synchronizer, with its sync quality.
+type SyncedCADU struct {
+	CADU      []byte    // ASM and frame, byte-aligned and polarity corrected
+	BitOffset int64     // stream position of the first ASM bit
+	ASMErrors int       // bit errors in the ASM
+	Inverted  bool      // the stream has inverted polarity
+	State     SyncState // synchronizer state after this CADU
+}
+
+// Synchronizer extracts fixed-length CADUs from a bitstream.
+type Synchronizer struct {
+	r         io.Reader
+	asm       []byte
+	caduLen   int // CADU length in bytes, ASM included
+	search    int // ASM bit errors tolerated in search
+	lock      int // ASM bit errors tolerated once acquired
+	checks    int
+	flywheel  int
+	polarity  bool
+	buf       []byte
+	base      int64 // stream byte offset of buf[0]
+	eof       bool
+	state     SyncState
+	pos       int64 // next bit to search, or the next expected ASM
+	inverted  bool
+	confirmed int // markers confirmed in check
+	missed    int // consecutive markers missed in lock and flywheel
+}
+
+// SyncOption configures a Synchronizer.
+type SyncOption func(*Synchronizer)
+
+// WithSyncASM sets the Attached Sync Marker to search for. The default is
+// DefaultASM().
+func WithSyncASM(asm []byte) SyncOption {
+	return func(s *Synchronizer) {
+		s.asm = append([]byte(nil), asm...)
+	}
+}
+
+// WithSearchErrors sets the number of ASM bit errors tolerated while
+// searching. The default is 0: acquisition needs an exact marker.
+func WithSearchErrors(n int) SyncOption {
+	return func(s *Synchronizer) {
+		s.search = n
+	}
+}
+
+// WithLockErrors sets the number of ASM bit errors tolerated at the
+// expected position once a marker has been found. The default is 4.
+func WithLockErrors(n int) SyncOption {
+	return func(s *Synchronizer) {
+		s.lock = n
+	}
+}
+
+// WithCheckFrames sets how many further markers must be found at the
+// expected positions before the Synchronizer locks. The default is 1.
+func WithCheckFrames(n int) SyncOption {
+	return func(s *Synchronizer) {
+		s.checks = n
+	}
+}
+
+// WithFlywheelFrames sets how many consecutive markers may be missed in
+// lock before the Synchronizer returns to search. The default is 3.
+func WithFlywheelFrames(n int) SyncOption {
+	return func(s *Synchronizer) {
+		s.flywheel = n
+	}
+}
+
+// WithPolarityDetection sets whether the inverted ASM is also searched
+// for, in which case CADUs from an inverted stream are inverted back.
+// The default is true.
+func WithPolarityDetection(detect bool) SyncOption {
+	return func(s *Synchronizer) {
+		s.polarity = detect
+	}
+}
+
+// NewSynchronizer returns a Synchronizer reading a bitstream from r, MSB
+// first, and extracting CADUs of caduLen bytes including the ASM.
+// Returns ErrInvalidDataLength if caduLen is not longer than the ASM.
+func NewSynchronizer(r io.Reader, caduLen int, opts ...SyncOption) (*Synchronizer, error) {
+	s := &Synchronizer{
+		r:        r,
+		asm:      DefaultASM(),
+		caduLen:  caduLen,
+		lock:     syncLockErrors,
+		checks:   syncCheckFrames,
+		flywheel: syncFlywheelFrames,
+		polarity: true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if caduLen <= len(s.asm) {
+		return nil, ErrInvalidDataLength
+	}
+	return s, nil
+}
+
+// State returns the current synchronizer state.
+func (s *Synchronizer) State() SyncState { return s.state }
+
+// Next returns the next CADU in the stream. CADUs are returned in every
+// state but search, including flywheel CADUs whose marker was missed;
+// check State and ASMErrors to filter them. Returns io.EOF when the
+// stream ends before another complete CADU.
+func (s *Synchronizer) Next() (*SyncedCADU, error) {
+	s.compact()
+	frameBits := int64(8 * s.caduLen)
+	for {
+		if s.state == SyncSearch {
+			pos, errs, inverted, err := s.find()
+			if err != nil {
+				return nil, err
+			}
+			s.inverted = inverted
+			s.confirmed, s.missed = 0, 0
+			s.state = SyncCheck
+			if s.checks == 0 {
+				s.state = SyncLock
+			}
+			return s.emit(pos, errs)
+		}
+
+		pos := s.pos
+		if err := s.fill(pos + frameBits); err != nil {
+			return nil, err
+		}
+		errs := s.distance(pos)
+		if s.inverted {
+			errs = 8*len(s.asm) - errs
+		}
+		found := errs <= s.lock
+
+		switch {
+		case found && s.state == SyncCheck:
+			s.confirmed++
+			if s.confirmed >= s.checks {
+				s.state = SyncLock
+			}
+		case found:
+			s.missed = 0
+			s.state = SyncLock
+		case s.state == SyncCheck:
+			// The marker that started the check was a false match.
+			s.state = SyncSearch
+			s.pos = pos - frameBits + 1
+			continue
+		default:
+			s.missed++
+			if s.missed > s.flywheel {
+				s.state = SyncSearch
+				continue
+			}
+			s.state = SyncFlywheel
+		}
+		return s.emit(pos, errs)
+	}
+}
+
+// find searches for the ASM from s.pos, returning its position, bit
+// errors and polarity.
+func (s *Synchronizer) find() (int64, int, bool, error) {
+	asmBits := 8 * len(s.asm)
+	for pos := s.pos; ; pos++ {
+		if err := s.fill(pos + int64(asmBits)); err != nil {
+			return 0, 0, false, err
+		}
+		d := s.distance(pos)
+		if d <= s.search {
+			return pos, d, false, nil
+		}
+		if s.polarity && asmBits-d <= s.search {
+			return pos, asmBits - d, true, nil
+		}
+		if pos%syncReadSize == 0 {
+			s.pos = pos
+			s.compact()
+		}
+	}
+}
+
+// emit extracts the CADU at bit position pos and advances to the next
+// expected marker.
+func (s *Synchronizer) emit(pos int64, errs int) (*SyncedCADU, error) {
+	frameBits := int64(8 * s.caduLen)
+	if err := s.fill(pos + frameBits); err != nil {
+		return nil, err
+	}
+	cadu := make([]byte, s.caduLen)
+	for i := range cadu {
+		cadu[i] = s.byteAt(pos + int64(8*i))
+		if s.inverted {
+			cadu[i] = ^cadu[i]
+		}
+	}
+	s.pos = pos + frameBits
+	return &SyncedCADU{
+		CADU:      cadu,
+		BitOffset: pos,
+		ASMErrors: errs,
+		Inverted:  s.inverted,
+		State:     s.state,
+	}, nil
+}
+
+// distance returns the number of bits in which the stream at bit
+// position pos differs from the ASM.
+func (s *Synchronizer) distance(pos int64) int {
+	d := 0
+	for i, b := range s.asm {
+		d += bits.OnesCount8(s.byteAt(pos+int64(8*i)) ^ b)
+	}
+	return d
+}
+
+// byteAt returns the 8 stream bits starting at bit position pos, which
+// must be buffered.
+func (s *Synchronizer) byteAt(pos int64) byte {
+	i := int(pos/8 - s.base)
+	shift := pos % 8
+	if shift == 0 {
+		return s.buf[i]
+	}
+	return s.buf[i]<<shift | s.buf[i+1]>>(8-shift)
+}
+
+// fill reads until the stream is buffered up to bit position end.
+// Returns io.EOF if the stream ends first.
+func (s *Synchronizer) fill(end int64) error {
+	for s.base+int64(len(s.buf)) < (end+7)/8 {
+		if s.eof {
+			return io.EOF
+		}
+		chunk := make([]byte, syncReadSize)
+		n, err := s.r.Read(chunk)
+		s.buf = append(s.buf, chunk[:n]...)
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compact discards buffered bytes more than one CADU before the current
+// position. That CADU is kept so that a failed check can search it again.
+func (s *Synchronizer) compact() {
+	drop := int((s.pos-int64(8*s.caduLen))/8 - s.base)
+	if drop <= 0 {
+		return
+	}
+	s.buf = append(s.buf[:0], s.buf[drop:]...)
+	s.base += int64(drop)
+}
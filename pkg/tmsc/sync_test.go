@@ -0,0 +1,332 @@
+package tmsc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ravisuhag/astro/pkg/tmsc"
+)
+
+const syncCADULen = 64
+
+// bitWriter builds an unaligned test bitstream.
+type bitWriter struct {
+	bits []byte
+}
+
+func (w *bitWriter) write(data []byte) {
+	for i := range 8 * len(data) {
+		w.bits = append(w.bits, data[i/8]>>(7-i%8)&1)
+	}
+}
+
+func (w *bitWriter) junk(rng *rand.Rand, n int) {
+	for range n {
+		w.bits = append(w.bits, byte(rng.IntN(2)))
+	}
+}
+
+func (w *bitWriter) flip(pos int) { w.bits[pos] ^= 1 }
+
+func (w *bitWriter) bytes() []byte {
+	out := make([]byte, (len(w.bits)+7)/8)
+	for i, b := range w.bits {
+		out[i/8] |= b << (7 - i%8)
+	}
+	return out
+}
+
+// syncStream writes n CADUs after a junk prefix of lead bits and returns
+// the CADUs and their bit offsets.
+func syncStream(rng *rand.Rand, w *bitWriter, lead, n int) ([][]byte, []int64) {
+	w.junk(rng, lead)
+	var cadus [][]byte
+	var offsets []int64
+	for range n {
+		offsets = append(offsets, int64(len(w.bits)))
+		cadu := tmsc.WrapCADU(randomFrame(rng, syncCADULen-4), nil, true)
+		cadus = append(cadus, cadu)
+		w.write(cadu)
+	}
+	return cadus, offsets
+}
+
+func syncAll(t *testing.T, data []byte, opts ...tmsc.SyncOption) []*tmsc.SyncedCADU {
+	t.Helper()
+	return syncAllLen(t, data, syncCADULen, opts...)
+}
+
+func syncAllLen(t *testing.T, data []byte, caduLen int, opts ...tmsc.SyncOption) []*tmsc.SyncedCADU {
+	t.Helper()
+	s, err := tmsc.NewSynchronizer(bytes.NewReader(data), caduLen, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []*tmsc.SyncedCADU
+	for {
+		c, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, c)
+	}
+}
+
+func TestSynchronizer_BitOffsets(t *testing.T) {
+	rng := rand.New(rand.NewPCG(30, 0))
+	for lead := range 17 {
+		var w bitWriter
+		cadus, offsets := syncStream(rng, &w, 40+lead, 5)
+		got := syncAll(t, w.bytes())
+		if len(got) != len(cadus) {
+			t.Fatalf("lead %d: got %d CADUs, want %d", lead, len(got), len(cadus))
+		}
+		for i, c := range got {
+			if !bytes.Equal(c.CADU, cadus[i]) || c.BitOffset != offsets[i] || c.ASMErrors != 0 || c.Inverted {
+				t.Errorf("lead %d, CADU %d: offset %d (want %d), %d ASM errors, inverted %v, data match %v",
+					lead, i, c.BitOffset, offsets[i], c.ASMErrors, c.Inverted, bytes.Equal(c.CADU, cadus[i]))
+			}
+		}
+	}
+}
+
+func TestSynchronizer_LongStream(t *testing.T) {
+	// Short reads and a stream much longer than the read buffer.
+	rng := rand.New(rand.NewPCG(38, 0))
+	var w bitWriter
+	cadus, offsets := syncStream(rng, &w, 3, 300)
+	s, _ := tmsc.NewSynchronizer(iotest.HalfReader(bytes.NewReader(w.bytes())), syncCADULen)
+	for i := range cadus {
+		c, err := s.Next()
+		if err != nil {
+			t.Fatalf("CADU %d: %v", i, err)
+		}
+		if c.BitOffset != offsets[i] || !bytes.Equal(c.CADU, cadus[i]) {
+			t.Fatalf("CADU %d: offset %d, want %d", i, c.BitOffset, offsets[i])
+		}
+	}
+	if _, err := s.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+func TestSynchronizer_States(t *testing.T) {
+	rng := rand.New(rand.NewPCG(31, 0))
+	var w bitWriter
+	syncStream(rng, &w, 13, 6)
+	got := syncAll(t, w.bytes(), tmsc.WithCheckFrames(2))
+	want := []tmsc.SyncState{tmsc.SyncCheck, tmsc.SyncCheck, tmsc.SyncLock, tmsc.SyncLock, tmsc.SyncLock, tmsc.SyncLock}
+	if len(got) != len(want) {
+		t.Fatalf("got %d CADUs, want %d", len(got), len(want))
+	}
+	for i, c := range got {
+		if c.State != want[i] {
+			t.Errorf("CADU %d: state %v, want %v", i, c.State, want[i])
+		}
+	}
+}
+
+func TestSynchronizer_ASMErrors(t *testing.T) {
+	rng := rand.New(rand.NewPCG(32, 0))
+	var w bitWriter
+	cadus, offsets := syncStream(rng, &w, 21, 5)
+	// A corrupted first marker is skipped in search; later ones are
+	// accepted up to the lock tolerance.
+	w.flip(int(offsets[0]) + 3)
+	w.flip(int(offsets[2]) + 5)
+	w.flip(int(offsets[2]) + 30)
+	w.flip(int(offsets[3]) + 0)
+
+	got := syncAll(t, w.bytes())
+	if len(got) != 4 {
+		t.Fatalf("got %d CADUs, want 4", len(got))
+	}
+	wantErrs := []int{0, 2, 1, 0}
+	for i, c := range got {
+		if c.BitOffset != offsets[i+1] || c.ASMErrors != wantErrs[i] {
+			t.Errorf("CADU %d: offset %d, %d ASM errors, want %d, %d",
+				i, c.BitOffset, c.ASMErrors, offsets[i+1], wantErrs[i])
+		}
+	}
+	if !bytes.Equal(got[3].CADU, cadus[4]) {
+		t.Error("last CADU differs")
+	}
+
+	// With a search tolerance, the first marker is found too.
+	if got := syncAll(t, w.bytes(), tmsc.WithSearchErrors(1)); len(got) != 5 || got[0].ASMErrors != 1 {
+		t.Errorf("WithSearchErrors(1): got %d CADUs", len(got))
+	}
+}
+
+func TestSynchronizer_Flywheel(t *testing.T) {
+	rng := rand.New(rand.NewPCG(33, 0))
+	var w bitWriter
+	cadus, offsets := syncStream(rng, &w, 5, 8)
+	for _, i := range []int{2, 3} {
+		for b := range 32 {
+			w.flip(int(offsets[i]) + b)
+		}
+	}
+
+	got := syncAll(t, w.bytes())
+	want := []tmsc.SyncState{tmsc.SyncCheck, tmsc.SyncLock, tmsc.SyncFlywheel, tmsc.SyncFlywheel,
+		tmsc.SyncLock, tmsc.SyncLock, tmsc.SyncLock, tmsc.SyncLock}
+	if len(got) != len(want) {
+		t.Fatalf("got %d CADUs, want %d", len(got), len(want))
+	}
+	for i, c := range got {
+		if c.State != want[i] || c.BitOffset != offsets[i] {
+			t.Errorf("CADU %d: state %v at %d, want %v at %d", i, c.State, c.BitOffset, want[i], offsets[i])
+		}
+	}
+	if got[2].ASMErrors != 32 {
+		t.Errorf("flywheel CADU ASM errors = %d, want 32", got[2].ASMErrors)
+	}
+	if !bytes.Equal(got[5].CADU, cadus[5]) {
+		t.Error("CADU after flywheel differs")
+	}
+}
+
+func TestSynchronizer_Slip(t *testing.T) {
+	// Dropping bits shifts every later marker. The synchronizer flywheels
+	// past the expected positions, returns to search, and reacquires.
+	rng := rand.New(rand.NewPCG(34, 0))
+	var w bitWriter
+	_, offsets := syncStream(rng, &w, 7, 3)
+	slip := len(w.bits)
+	after, _ := syncStream(rng, &w, 0, 6)
+	w.bits = append(w.bits[:slip-3], w.bits[slip:]...)
+
+	got := syncAll(t, w.bytes(), tmsc.WithFlywheelFrames(1))
+	var locked []*tmsc.SyncedCADU
+	for _, c := range got {
+		if c.State != tmsc.SyncFlywheel {
+			locked = append(locked, c)
+		}
+	}
+	if len(locked) < 6 {
+		t.Fatalf("got %d CADUs outside flywheel, want at least 6", len(locked))
+	}
+	for i := range 3 {
+		if locked[i].BitOffset != offsets[i] {
+			t.Errorf("CADU %d: offset %d, want %d", i, locked[i].BitOffset, offsets[i])
+		}
+	}
+	last := locked[len(locked)-1]
+	if !bytes.Equal(last.CADU, after[len(after)-1]) || last.State != tmsc.SyncLock {
+		t.Errorf("last CADU not recovered after slip: state %v", last.State)
+	}
+	if last.BitOffset%8 != int64(slip-3)%8 {
+		t.Errorf("last CADU at bit offset %d does not reflect the slip", last.BitOffset)
+	}
+}
+
+func TestSynchronizer_FalseMarker(t *testing.T) {
+	// An ASM in the junk is followed by no marker one period later, so
+	// the check fails and search resumes right after it.
+	rng := rand.New(rand.NewPCG(35, 0))
+	var w bitWriter
+	w.junk(rng, 100)
+	w.write(tmsc.DefaultASM())
+	w.junk(rng, 37)
+	cadus, offsets := syncStream(rng, &w, 0, 4)
+
+	got := syncAll(t, w.bytes())
+	if len(got) != 5 {
+		t.Fatalf("got %d CADUs, want 5", len(got))
+	}
+	if got[0].BitOffset != 100 || got[0].State != tmsc.SyncCheck {
+		t.Errorf("false marker: offset %d, state %v", got[0].BitOffset, got[0].State)
+	}
+	for i, c := range got[1:] {
+		if c.BitOffset != offsets[i] || !bytes.Equal(c.CADU, cadus[i]) {
+			t.Errorf("CADU %d: offset %d, want %d", i, c.BitOffset, offsets[i])
+		}
+	}
+}
+
+func TestSynchronizer_InvertedPolarity(t *testing.T) {
+	rng := rand.New(rand.NewPCG(36, 0))
+	var w bitWriter
+	cadus, _ := syncStream(rng, &w, 11, 4)
+	for i := range w.bits {
+		w.flip(i)
+	}
+	w.flip(int(3*8*syncCADULen) + 11 + 7) // one ASM error in the last marker
+
+	got := syncAll(t, w.bytes())
+	if len(got) != 4 {
+		t.Fatalf("got %d CADUs, want 4", len(got))
+	}
+	for i, c := range got {
+		if !c.Inverted || !bytes.Equal(c.CADU[4:], cadus[i][4:]) {
+			t.Errorf("CADU %d: inverted %v, data match %v", i, c.Inverted, bytes.Equal(c.CADU[4:], cadus[i][4:]))
+		}
+	}
+	if got[3].ASMErrors != 1 {
+		t.Errorf("ASM errors = %d, want 1", got[3].ASMErrors)
+	}
+
+	if got := syncAll(t, w.bytes(), tmsc.WithPolarityDetection(false)); len(got) != 0 {
+		t.Errorf("without polarity detection: got %d CADUs", len(got))
+	}
+}
+
+func TestSynchronizer_CustomASM(t *testing.T) {
+	rng := rand.New(rand.NewPCG(37, 0))
+	turbo, _ := tmsc.NewTurboCodec(tmsc.TurboRate1_2, 223)
+	frame := randomFrame(rng, 223)
+	cadu, _ := turbo.WrapCADU(frame, true)
+
+	var w bitWriter
+	w.junk(rng, 29)
+	w.write(cadu)
+	w.write(cadu)
+	got := syncAllLen(t, w.bytes(), len(cadu), tmsc.WithSyncASM(turbo.ASM()))
+	if len(got) != 2 || !bytes.Equal(got[1].CADU, cadu) {
+		t.Fatalf("got %d CADUs", len(got))
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("device gone") }
+
+func TestSynchronizer_Errors(t *testing.T) {
+	if _, err := tmsc.NewSynchronizer(bytes.NewReader(nil), 4); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("got %v, want ErrInvalidDataLength", err)
+	}
+	s, _ := tmsc.NewSynchronizer(errReader{}, syncCADULen)
+	if _, err := s.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("reader error not returned: %v", err)
+	}
+	s, _ = tmsc.NewSynchronizer(bytes.NewReader(tmsc.DefaultASM()), syncCADULen)
+	if _, err := s.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("truncated CADU: got %v, want io.EOF", err)
+	}
+	if s.State() != tmsc.SyncCheck {
+		t.Errorf("State() = %v, want check", s.State())
+	}
+}
+
+func TestSyncState_String(t *testing.T) {
+	want := map[tmsc.SyncState]string{
+		tmsc.SyncSearch:   "search",
+		tmsc.SyncCheck:    "check",
+		tmsc.SyncLock:     "lock",
+		tmsc.SyncFlywheel: "flywheel",
+		tmsc.SyncState(9): "unknown",
+	}
+	for s, name := range want {
+		if s.String() != name {
+			t.Errorf("String() = %q, want %q", s.String(), name)
+		}
+	}
+}
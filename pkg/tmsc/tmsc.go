@@ -4,8 +4,14 @@
 // This sublayer sits between the TM Data Link Protocol (CCSDS 132.0-B-3)
 // and the physical layer, providing:
 //   - Attached Sync Marker (ASM) for frame synchronization
+//   - Streaming frame synchronization with bit-level ASM search
 //   - CCSDS pseudo-randomization for bit transition density assurance
 //   - Channel Access Data Unit (CADU) wrapping and unwrapping
+//   - Reed-Solomon outer coding with symbol interleaving
+//   - Convolutional inner coding with soft-decision Viterbi decoding
+//   - LDPC coding with the C2 (8160,7136) and AR4JA codes and min-sum decoding
+//   - Turbo coding at rates 1/2 to 1/6 with max-log-MAP decoding
+//   - Coding profiles chaining RS, randomization, ASM and convolutional coding
 package tmsc
 
 import "bytes"
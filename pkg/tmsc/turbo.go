@@ -0,0 +1,465 @@
+package tmsc
+
+// Turbo coding for CCSDS TM Synchronization and Channel Coding
+// per CCSDS 131.0-B-4 Section 6.
+//
+// The turbo encoder runs two identical 16-state recursive systematic
+// convolutional encoders, a on the information block and b on its
+// permutation. Each has backward connection vector G0 = 10011 and
+// forward connection vectors G1 = 11011, G2 = 10101 and G3 = 11111.
+// After the last information bit both encoders are terminated with
+// 4 bits that return them to the zero state, so a codeblock holds
+// (k+4)/r symbols. Only the systematic output of encoder a is sent.
+//
+// Information blocks are 1784, 3568, 7136 or 8920 bits: a Transfer
+// Frame of 223 times the interleaving depth 1, 2, 4 or 5 bytes.
+//
+// Decoding alternates max-log-MAP decoders for the two component codes,
+// exchanging scaled extrinsic information, on int8 soft symbols as for
+// ConvCodec.Decode.
+
+import "math"
+
+const (
+	turboStates     = 16
+	turboTail       = 4   // termination bits per component encoder
+	turboIterations = 10  // default decoder iterations
+	turboScale      = 0.7 // max-log-MAP extrinsic scaling
+	turboK1         = 8   // permutation rows
+)
+
+// turboPrimes are the permutation parameters p1 to p8.
+var turboPrimes = [8]int{31, 37, 43, 47, 53, 59, 61, 67}
+
+// TurboRate identifies a CCSDS turbo code rate.
+type TurboRate int
+
+// Supported turbo code rates.
+const (
+	TurboRate1_2 TurboRate = iota // rate 1/2, parity punctured alternately
+	TurboRate1_3                  // rate 1/3
+	TurboRate1_4                  // rate 1/4
+	TurboRate1_6                  // rate 1/6
+)
+
+// String returns the rate as a fraction, such as "1/2".
+func (r TurboRate) String() string {
+	switch r {
+	case TurboRate1_2:
+		return "1/2"
+	case TurboRate1_3:
+		return "1/3"
+	case TurboRate1_4:
+		return "1/4"
+	case TurboRate1_6:
+		return "1/6"
+	}
+	return "unknown"
+}
+
+// turboSymbol names one encoder output: the component encoder (0 for a,
+// 1 for b) and the connection vector (0 for the systematic bit, or 1 to 3
+// for G1 to G3).
+type turboSymbol struct {
+	enc, gen int
+}
+
+// turboOutputs lists the symbols sent for each trellis step, in order.
+// Rate 1/2 alternates between its two patterns.
+var turboOutputs = map[TurboRate][][]turboSymbol{
+	TurboRate1_2: {{{0, 0}, {0, 1}}, {{0, 0}, {1, 1}}},
+	TurboRate1_3: {{{0, 0}, {0, 1}, {1, 1}}},
+	TurboRate1_4: {{{0, 0}, {0, 2}, {0, 3}, {1, 1}}},
+	TurboRate1_6: {{{0, 0}, {0, 1}, {0, 2}, {0, 3}, {1, 1}, {1, 3}}},
+}
+
+// turboASMs holds the Attached Sync Marker for each rate. The rate 1/4
+// and 1/6 markers are the rate 1/2 and 1/3 markers followed by their
+// complements.
+var turboASMs = map[TurboRate][]byte{
+	TurboRate1_2: {0x03, 0x47, 0x76, 0xC7, 0x27, 0x28, 0x95, 0xB0},
+	TurboRate1_3: {0x25, 0xD5, 0xC0, 0xCE, 0x89, 0x90, 0xF6, 0xC9, 0x46, 0x1B, 0xF7, 0x9C},
+	TurboRate1_4: {
+		0x03, 0x47, 0x76, 0xC7, 0x27, 0x28, 0x95, 0xB0,
+		0xFC, 0xB8, 0x89, 0x38, 0xD8, 0xD7, 0x6A, 0x4F,
+	},
+	TurboRate1_6: {
+		0x25, 0xD5, 0xC0, 0xCE, 0x89, 0x90, 0xF6, 0xC9, 0x46, 0x1B, 0xF7, 0x9C,
+		0xDA, 0x2A, 0x3F, 0x31, 0x76, 0x6F, 0x09, 0x36, 0xB9, 0xE4, 0x08, 0x63,
+	},
+}
+
+// turboTrellis gives, for each state and input bit, the next state and
+// the G1, G2 and G3 outputs as bits 0 to 2. A state holds the most
+// recent register bit in bit 3.
+var turboTrellis [turboStates][2]struct {
+	next, out int
+}
+
+func init() {
+	for s := range turboStates {
+		s1, s2, s3, s4 := s>>3&1, s>>2&1, s>>1&1, s&1
+		for u := range 2 {
+			w := u ^ s3 ^ s4 // G0 = 10011
+			g1 := w ^ s1 ^ s3 ^ s4
+			g2 := w ^ s2 ^ s4
+			g3 := w ^ s1 ^ s2 ^ s3 ^ s4
+			turboTrellis[s][u].next = w<<3 | s>>1
+			turboTrellis[s][u].out = g1 | g2<<1 | g3<<2
+		}
+	}
+}
+
+// turboTermination returns the input bit that drives the feedback to
+// zero from state s, so that 4 such bits return the encoder to state 0.
+func turboTermination(s int) int {
+	return (s>>1 ^ s) & 1
+}
+
+// TurboCodec encodes and decodes a CCSDS turbo code.
+type TurboCodec struct {
+	rate       TurboRate
+	k          int   // information bits
+	perm       []int // encoder b reads information bit perm[s] at step s
+	iterations int
+}
+
+// TurboOption configures a TurboCodec.
+type TurboOption func(*TurboCodec)
+
+// WithTurboIterations sets the maximum number of decoder iterations per
+// codeblock. The default is 10.
+func WithTurboIterations(n int) TurboOption {
+	return func(t *TurboCodec) {
+		t.iterations = n
+	}
+}
+
+// NewTurboCodec returns a TurboCodec for the given rate and Transfer Frame
+// length in bytes: 223, 446, 892 or 1115. Returns ErrInvalidCodeRate for
+// an unknown rate and ErrInvalidDataLength for any other frame length.
+func NewTurboCodec(rate TurboRate, dataLen int, opts ...TurboOption) (*TurboCodec, error) {
+	if _, ok := turboOutputs[rate]; !ok {
+		return nil, ErrInvalidCodeRate
+	}
+	switch dataLen {
+	case 223, 446, 892, 1115:
+	default:
+		return nil, ErrInvalidDataLength
+	}
+	t := &TurboCodec{
+		rate:       rate,
+		k:          8 * dataLen,
+		perm:       turboPermutation(8 * dataLen),
+		iterations: turboIterations,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// turboPermutation computes the permutation of Section 6.3 for a block of
+// k = k1*k2 bits, converted to 0-based indices.
+func turboPermutation(k int) []int {
+	k2 := k / turboK1
+	perm := make([]int, k)
+	for s := 1; s <= k; s++ {
+		m := (s - 1) % 2
+		i := (s - 1) / (2 * k2)
+		j := (s-1)/2 - i*k2
+		t := (19*i + 1) % (turboK1 / 2)
+		q := t%8 + 1
+		c := (turboPrimes[q-1]*j + 21*m) % k2
+		perm[s-1] = 2*(t+c*turboK1/2+1) - m - 1
+	}
+	return perm
+}
+
+// Rate returns the code rate.
+func (t *TurboCodec) Rate() TurboRate { return t.rate }
+
+// DataLen returns the information bytes per codeblock.
+func (t *TurboCodec) DataLen() int { return t.k / 8 }
+
+// CodeblockBits returns the number of symbols per codeblock, (k+4)/r.
+func (t *TurboCodec) CodeblockBits() int {
+	return (t.k + turboTail) * len(turboOutputs[t.rate][0])
+}
+
+// CodeblockLen returns the bytes needed to hold a codeblock. Rate 1/3
+// codeblocks end in the middle of a byte; the last 4 bits are zero fill.
+func (t *TurboCodec) CodeblockLen() int { return (t.CodeblockBits() + 7) / 8 }
+
+// ASM returns the Attached Sync Marker for the code rate.
+func (t *TurboCodec) ASM() []byte {
+	return append([]byte(nil), turboASMs[t.rate]...)
+}
+
+// Encode encodes DataLen() bytes into a codeblock of CodeblockBits()
+// symbols, packed MSB first into CodeblockLen() bytes. The input slice
+// is not modified.
+func (t *TurboCodec) Encode(data []byte) ([]byte, error) {
+	if len(data) != t.DataLen() {
+		return nil, ErrInvalidDataLength
+	}
+	bit := func(i int) int { return int(data[i/8]>>(7-i%8)) & 1 }
+
+	out := make([]byte, t.CodeblockLen())
+	pos := 0
+	var state [2]int
+	patterns := turboOutputs[t.rate]
+	for step := range t.k + turboTail {
+		var in, gens [2]int
+		for e := range 2 {
+			switch {
+			case step >= t.k:
+				in[e] = turboTermination(state[e])
+			case e == 0:
+				in[e] = bit(step)
+			default:
+				in[e] = bit(t.perm[step])
+			}
+			tr := turboTrellis[state[e]][in[e]]
+			gens[e] = tr.out
+			state[e] = tr.next
+		}
+		for _, sym := range patterns[step%len(patterns)] {
+			v := in[sym.enc]
+			if sym.gen > 0 {
+				v = gens[sym.enc] >> (sym.gen - 1) & 1
+			}
+			if v == 1 {
+				out[pos/8] |= 1 << (7 - pos%8)
+			}
+			pos++
+		}
+	}
+	return out, nil
+}
+
+// Decode decodes a codeblock of soft symbols, one int8 log-likelihood
+// ratio per symbol as for ConvCodec.Decode, and returns the information
+// bytes and the number of iterations used. Symbols beyond
+// CodeblockBits(), such as the fill of a packed rate 1/3 codeblock, are
+// ignored. Decoding stops once an iteration leaves every hard decision
+// unchanged; ErrNotConverged is returned if the decisions are still
+// changing after the maximum number of iterations.
+func (t *TurboCodec) Decode(symbols []int8) ([]byte, int, error) {
+	if len(symbols) < t.CodeblockBits() || len(symbols) > 8*t.CodeblockLen() {
+		return nil, 0, ErrInvalidDataLength
+	}
+	steps := t.k + turboTail
+
+	// Demultiplex the channel symbols into the systematic and parity
+	// inputs of each component decoder. Punctured and untransmitted
+	// symbols stay at 0, an erasure.
+	var sys, ext [2][]float32
+	var parity [2][3][]float32
+	for e := range 2 {
+		sys[e] = make([]float32, steps)
+		ext[e] = make([]float32, steps)
+		for g := range 3 {
+			parity[e][g] = make([]float32, steps)
+		}
+	}
+	patterns := turboOutputs[t.rate]
+	pos := 0
+	for step := range steps {
+		for _, sym := range patterns[step%len(patterns)] {
+			llr := float32(symbols[pos])
+			pos++
+			if sym.gen == 0 {
+				sys[0][step] = llr
+			} else {
+				parity[sym.enc][sym.gen-1][step] = llr
+			}
+		}
+	}
+	for s := range t.k {
+		sys[1][s] = sys[0][t.perm[s]]
+	}
+
+	hard := make([]bool, t.k)
+	for i := range hard {
+		hard[i] = sys[0][i] < 0
+	}
+	apriori := make([]float32, steps)
+	for it := 1; it <= t.iterations; it++ {
+		clear(apriori)
+		for s := range t.k {
+			apriori[t.perm[s]] = turboScale * ext[1][s]
+		}
+		turboMaxLogMAP(sys[0], apriori, &parity[0], ext[0], t.k)
+
+		clear(apriori)
+		for s := range t.k {
+			apriori[s] = turboScale * ext[0][t.perm[s]]
+		}
+		turboMaxLogMAP(sys[1], apriori, &parity[1], ext[1], t.k)
+
+		changed := false
+		for s := range t.k {
+			d := sys[1][s]+apriori[s]+ext[1][s] < 0
+			if hard[t.perm[s]] != d {
+				hard[t.perm[s]] = d
+				changed = true
+			}
+		}
+		if !changed {
+			return packBits(hard), it, nil
+		}
+	}
+	return nil, t.iterations, ErrNotConverged
+}
+
+// turboMaxLogMAP runs the max-log-MAP algorithm over one component code
+// and writes the extrinsic log-likelihood ratio of each information bit
+// to ext. The trellis starts and ends in state 0; steps from k onward
+// are termination steps whose input is fixed by the state.
+func turboMaxLogMAP(sys, apriori []float32, parity *[3][]float32, ext []float32, k int) {
+	steps := len(sys)
+	inf := float32(math.Inf(-1))
+
+	// gamma returns the branch metric for input u from state s at step i.
+	gamma := func(i, s, u int) float32 {
+		g := sys[i] + apriori[i]
+		if u == 1 {
+			g = -g
+		}
+		out := turboTrellis[s][u].out
+		for j := range 3 {
+			if out>>j&1 == 0 {
+				g += parity[j][i]
+			} else {
+				g -= parity[j][i]
+			}
+		}
+		return g / 2
+	}
+	allowed := func(i, s, u int) bool {
+		return i < k || u == turboTermination(s)
+	}
+
+	alpha := make([][turboStates]float32, steps+1)
+	for s := 1; s < turboStates; s++ {
+		alpha[0][s] = inf
+	}
+	for i := range steps {
+		next := &alpha[i+1]
+		for s := range next {
+			next[s] = inf
+		}
+		for s := range turboStates {
+			if alpha[i][s] == inf {
+				continue
+			}
+			for u := range 2 {
+				if !allowed(i, s, u) {
+					continue
+				}
+				ns := turboTrellis[s][u].next
+				next[ns] = max(next[ns], alpha[i][s]+gamma(i, s, u))
+			}
+		}
+		normalizeMetrics(next)
+	}
+
+	var beta, prev [turboStates]float32
+	for s := 1; s < turboStates; s++ {
+		beta[s] = inf
+	}
+	for i := steps - 1; i >= 0; i-- {
+		best := [2]float32{inf, inf}
+		for s := range prev {
+			prev[s] = inf
+		}
+		for s := range turboStates {
+			for u := range 2 {
+				if !allowed(i, s, u) {
+					continue
+				}
+				b := beta[turboTrellis[s][u].next]
+				if b == inf {
+					continue
+				}
+				m := gamma(i, s, u) + b
+				prev[s] = max(prev[s], m)
+				if alpha[i][s] != inf {
+					best[u] = max(best[u], alpha[i][s]+m)
+				}
+			}
+		}
+		if i < k {
+			ext[i] = best[0] - best[1] - sys[i] - apriori[i]
+		}
+		beta = prev
+		normalizeMetrics(&beta)
+	}
+}
+
+// normalizeMetrics subtracts the largest state metric from all of them
+// to keep the recursion bounded.
+func normalizeMetrics(m *[turboStates]float32) {
+	top := float32(math.Inf(-1))
+	for _, v := range m {
+		top = max(top, v)
+	}
+	for s := range m {
+		m[s] -= top
+	}
+}
+
+// packBits packs bit decisions MSB first.
+func packBits(bits []bool) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+// WrapCADU encodes a Transfer Frame of DataLen() bytes into a codeblock
+// and wraps it into a CADU with the rate's ASM, randomizing the codeblock
+// if requested. A rate 1/3 CADU ends with 4 zero fill bits that are not
+// part of the transmitted bitstream.
+func (t *TurboCodec) WrapCADU(frame []byte, randomize bool) ([]byte, error) {
+	block, err := t.Encode(frame)
+	if err != nil {
+		return nil, err
+	}
+	cadu := WrapCADU(block, t.ASM(), randomize)
+	if fill := 8*t.CodeblockLen() - t.CodeblockBits(); fill > 0 {
+		cadu[len(cadu)-1] &^= 1<<fill - 1
+	}
+	return cadu, nil
+}
+
+// UnwrapCADU decodes a CADU received as soft symbols, one per bit. It
+// checks the ASM on hard decisions, de-randomizes the codeblock symbols
+// if requested, and decodes them. It returns the Transfer Frame and the
+// decoder iterations used.
+func (t *TurboCodec) UnwrapCADU(symbols []int8, randomize bool) ([]byte, int, error) {
+	asm := t.ASM()
+	if len(symbols) < 8*len(asm)+t.CodeblockBits() {
+		return nil, 0, ErrDataTooShort
+	}
+	for i := range 8 * len(asm) {
+		if (asm[i/8]>>(7-i%8)&1 == 1) != (symbols[i] < 0) {
+			return nil, 0, ErrSyncMarkerMismatch
+		}
+	}
+	block := make([]int8, t.CodeblockBits())
+	copy(block, symbols[8*len(asm):])
+	if randomize {
+		pn := GeneratePNSequence(t.CodeblockLen())
+		for i := range block {
+			if pn[i/8]>>(7-i%8)&1 == 1 {
+				block[i] = -max(block[i], -127)
+			}
+		}
+	}
+	return t.Decode(block)
+}
@@ -0,0 +1,247 @@
+package tmsc_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ravisuhag/astro/pkg/tmsc"
+)
+
+var turboRates = []tmsc.TurboRate{tmsc.TurboRate1_2, tmsc.TurboRate1_3, tmsc.TurboRate1_4, tmsc.TurboRate1_6}
+
+func newTurbo(t *testing.T, rate tmsc.TurboRate, dataLen int, opts ...tmsc.TurboOption) *tmsc.TurboCodec {
+	t.Helper()
+	c, err := tmsc.NewTurboCodec(rate, dataLen, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestTurbo_Lengths(t *testing.T) {
+	tests := []struct {
+		rate    tmsc.TurboRate
+		dataLen int
+		bits    int
+		asmLen  int
+	}{
+		{tmsc.TurboRate1_2, 223, 3576, 8},
+		{tmsc.TurboRate1_3, 223, 5364, 12},
+		{tmsc.TurboRate1_4, 446, 14288, 16},
+		{tmsc.TurboRate1_6, 1115, 53544, 24},
+	}
+	for _, tt := range tests {
+		c := newTurbo(t, tt.rate, tt.dataLen)
+		if c.DataLen() != tt.dataLen || c.CodeblockBits() != tt.bits || c.CodeblockLen() != (tt.bits+7)/8 {
+			t.Errorf("rate %v: DataLen() = %d, CodeblockBits() = %d, CodeblockLen() = %d",
+				tt.rate, c.DataLen(), c.CodeblockBits(), c.CodeblockLen())
+		}
+		if len(c.ASM()) != tt.asmLen {
+			t.Errorf("rate %v: ASM() = %x", tt.rate, c.ASM())
+		}
+	}
+}
+
+func TestTurbo_ASM(t *testing.T) {
+	c := newTurbo(t, tmsc.TurboRate1_2, 223)
+	if !bytes.Equal(c.ASM(), []byte{0x03, 0x47, 0x76, 0xC7, 0x27, 0x28, 0x95, 0xB0}) {
+		t.Errorf("rate 1/2 ASM = %x", c.ASM())
+	}
+
+	// The rate 1/4 and 1/6 markers extend the 1/2 and 1/3 markers with
+	// their complements.
+	for _, pair := range [][2]tmsc.TurboRate{{tmsc.TurboRate1_2, tmsc.TurboRate1_4}, {tmsc.TurboRate1_3, tmsc.TurboRate1_6}} {
+		short := newTurbo(t, pair[0], 223).ASM()
+		long := newTurbo(t, pair[1], 223).ASM()
+		for i, b := range short {
+			if long[i] != b || long[len(short)+i] != ^b {
+				t.Errorf("rate %v ASM %x does not extend %x", pair[1], long, short)
+				break
+			}
+		}
+	}
+
+	asm := c.ASM()
+	asm[0] = 0xFF
+	if c.ASM()[0] != 0x03 {
+		t.Error("ASM must return a fresh copy")
+	}
+}
+
+func TestTurbo_Systematic(t *testing.T) {
+	// At rate 1/3 every third symbol is the information bit, followed by
+	// the four termination bits of encoder a.
+	c := newTurbo(t, tmsc.TurboRate1_3, 223)
+	data := randomFrame(rand.New(rand.NewPCG(20, 0)), 223)
+	block, _ := c.Encode(data)
+	for i := range 8 * len(data) {
+		got := block[3*i/8] >> (7 - 3*i%8) & 1
+		if got != data[i/8]>>(7-i%8)&1 {
+			t.Fatalf("symbol %d is not information bit %d", 3*i, i)
+		}
+	}
+	if block[len(block)-1]&0x0F != 0 {
+		t.Error("fill bits are not zero")
+	}
+}
+
+func TestTurbo_ZeroBlock(t *testing.T) {
+	for _, rate := range turboRates {
+		c := newTurbo(t, rate, 223)
+		block, _ := c.Encode(make([]byte, 223))
+		if !bytes.Equal(block, make([]byte, c.CodeblockLen())) {
+			t.Errorf("rate %v: zero frame does not encode to zeros", rate)
+		}
+	}
+}
+
+func TestTurbo_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(21, 0))
+	for _, rate := range turboRates {
+		for _, dataLen := range []int{223, 446, 892, 1115} {
+			c := newTurbo(t, rate, dataLen)
+			data := randomFrame(rng, dataLen)
+			block, err := c.Encode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, iterations, err := c.Decode(tmsc.HardSymbols(block))
+			if err != nil {
+				t.Fatalf("rate %v, %d bytes: %v", rate, dataLen, err)
+			}
+			if iterations != 1 {
+				t.Errorf("rate %v, %d bytes: iterations = %d, want 1", rate, dataLen, iterations)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("rate %v, %d bytes: decoded data differs", rate, dataLen)
+			}
+		}
+	}
+}
+
+func TestTurbo_SoftDecision(t *testing.T) {
+	tests := []struct {
+		rate  tmsc.TurboRate
+		sigma float64
+	}{
+		{tmsc.TurboRate1_2, 0.8},
+		{tmsc.TurboRate1_3, 0.95},
+		{tmsc.TurboRate1_4, 1.05},
+		{tmsc.TurboRate1_6, 1.2},
+	}
+	rng := rand.New(rand.NewPCG(22, 0))
+	for _, tt := range tests {
+		c := newTurbo(t, tt.rate, 223)
+		data := randomFrame(rng, 223)
+		block, _ := c.Encode(data)
+		symbols := awgn(rng, block, tt.sigma)[:c.CodeblockBits()]
+
+		errs := 0
+		for i, s := range tmsc.HardSymbols(block)[:len(symbols)] {
+			if (s > 0) != (symbols[i] >= 0) {
+				errs++
+			}
+		}
+		if errs < len(symbols)/20 {
+			t.Fatalf("rate %v: channel introduced only %d symbol errors", tt.rate, errs)
+		}
+
+		got, iterations, err := c.Decode(symbols)
+		if err != nil {
+			t.Fatalf("rate %v after %d symbol errors: %v", tt.rate, errs, err)
+		}
+		if iterations < 2 {
+			t.Errorf("rate %v: iterations = %d for a noisy codeblock", tt.rate, iterations)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("rate %v: decoded data differs after %d symbol errors", tt.rate, errs)
+		}
+	}
+}
+
+func TestTurbo_NotConverged(t *testing.T) {
+	c := newTurbo(t, tmsc.TurboRate1_2, 223, tmsc.WithTurboIterations(3))
+	rng := rand.New(rand.NewPCG(23, 0))
+	block, _ := c.Encode(randomFrame(rng, 223))
+	_, iterations, err := c.Decode(awgn(rng, block, 2))
+	if !errors.Is(err, tmsc.ErrNotConverged) {
+		t.Fatalf("got %v, want ErrNotConverged", err)
+	}
+	if iterations != 3 {
+		t.Errorf("iterations = %d, want 3", iterations)
+	}
+}
+
+func TestTurbo_CADU(t *testing.T) {
+	rng := rand.New(rand.NewPCG(24, 0))
+	for _, rate := range []tmsc.TurboRate{tmsc.TurboRate1_2, tmsc.TurboRate1_3} {
+		c := newTurbo(t, rate, 223)
+		frame := randomFrame(rng, 223)
+		for _, randomize := range []bool{true, false} {
+			cadu, err := c.WrapCADU(frame, randomize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cadu) != len(c.ASM())+c.CodeblockLen() {
+				t.Fatalf("rate %v: CADU length = %d", rate, len(cadu))
+			}
+			if !bytes.HasPrefix(cadu, c.ASM()) {
+				t.Errorf("rate %v: CADU does not start with the ASM", rate)
+			}
+			symbols := tmsc.HardSymbols(cadu)
+			for i := 8 * len(c.ASM()); i < len(symbols); i += 41 {
+				symbols[i] = -symbols[i]
+			}
+			got, _, err := c.UnwrapCADU(symbols, randomize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, frame) {
+				t.Errorf("rate %v, randomize %v: frame differs", rate, randomize)
+			}
+		}
+	}
+
+	c := newTurbo(t, tmsc.TurboRate1_2, 223)
+	cadu, _ := c.WrapCADU(make([]byte, 223), true)
+	cadu[0] ^= 0xFF
+	if _, _, err := c.UnwrapCADU(tmsc.HardSymbols(cadu), true); !errors.Is(err, tmsc.ErrSyncMarkerMismatch) {
+		t.Errorf("got %v, want ErrSyncMarkerMismatch", err)
+	}
+	if _, _, err := c.UnwrapCADU(make([]int8, 64), true); !errors.Is(err, tmsc.ErrDataTooShort) {
+		t.Errorf("got %v, want ErrDataTooShort", err)
+	}
+}
+
+func TestTurbo_Errors(t *testing.T) {
+	if _, err := tmsc.NewTurboCodec(tmsc.TurboRate(7), 223); !errors.Is(err, tmsc.ErrInvalidCodeRate) {
+		t.Errorf("NewTurboCodec rate: got %v, want ErrInvalidCodeRate", err)
+	}
+	if _, err := tmsc.NewTurboCodec(tmsc.TurboRate1_2, 255); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("NewTurboCodec length: got %v, want ErrInvalidDataLength", err)
+	}
+	c := newTurbo(t, tmsc.TurboRate1_3, 223)
+	if _, err := c.Encode(make([]byte, 222)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Encode: got %v, want ErrInvalidDataLength", err)
+	}
+	if _, _, err := c.Decode(make([]int8, c.CodeblockBits()-1)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Decode: got %v, want ErrInvalidDataLength", err)
+	}
+	if _, _, err := c.Decode(make([]int8, 8*c.CodeblockLen()+1)); !errors.Is(err, tmsc.ErrInvalidDataLength) {
+		t.Errorf("Decode: got %v, want ErrInvalidDataLength", err)
+	}
+}
+
+func TestTurboRate_String(t *testing.T) {
+	want := []string{"1/2", "1/3", "1/4", "1/6"}
+	for i, rate := range turboRates {
+		if rate.String() != want[i] {
+			t.Errorf("String() = %q, want %q", rate.String(), want[i])
+		}
+	}
+	if tmsc.TurboRate(9).String() != "unknown" {
+		t.Error("unknown rate not reported")
+	}
+}
@@ -26,7 +26,7 @@ func (c ChannelConfig) DataFieldCapacity(secondaryHeaderLen int) int {
 	capacity -= c.InsertZoneLen
 	capacity -= DataFieldHeaderSize
 	if c.HasOCF {
-		capacity -= 4
+		capacity -= OCFSize
 	}
 	if c.HasFECF {
 		if c.UseCRC32 {
@@ -99,6 +99,10 @@ func (d *FrameGapDetector) VCFrameGap() int {
 	return d.lastVCGap
 }
 
+// OCFProvider supplies the Operational Control Field of outgoing frames.
+// See MasterChannel.SetOCFProvider.
+type OCFProvider = sdl.OCFProvider
+
 // MasterChannel manages USLP Transfer Frames for a Master Channel identified by SCID.
 type MasterChannel struct {
 	scid     uint16
@@ -106,6 +110,9 @@ type MasterChannel struct {
 	mux      *VirtualChannelMultiplexer
 	channels map[uint8]*VirtualChannel
 	detector *FrameGapDetector
+	ocf      OCFProvider
+	lastOCF  []byte // last valid provider report
+	ocfErr   error  // last OCF insertion problem, see OCFError
 }
 
 // NewMasterChannel creates a new Master Channel for the given spacecraft ID.
@@ -141,14 +148,31 @@ func (mc *MasterChannel) AddFrame(frame *TransferFrame) error {
 	return vc.Add(frame)
 }
 
-// GetNextFrame retrieves the next frame from the multiplexer.
+// SetOCFProvider installs a provider that fills the OCF of every frame
+// leaving this Master Channel, idle frames included. Frames without an
+// OCF are passed through unchanged. Pass nil to remove the provider.
+//
+// On the spacecraft side this closes the COP-1 loop: pass the OCF method
+// of a cop.CLCWReporter and every frame reports the current CLCW.
+func (mc *MasterChannel) SetOCFProvider(p OCFProvider) {
+	mc.ocf = p
+}
+
+// GetNextFrame retrieves the next frame from the multiplexer. A frame
+// taken off the multiplexer is always returned: a problem filling its
+// OCF is reported by OCFError instead.
 func (mc *MasterChannel) GetNextFrame() (*TransferFrame, error) {
-	return mc.mux.Next()
+	frame, err := mc.mux.Next()
+	if err != nil {
+		return nil, err
+	}
+	mc.insertOCF(frame)
+	return frame, nil
 }
 
 // GetNextFrameOrIdle returns the next frame or an idle frame if none available.
 func (mc *MasterChannel) GetNextFrameOrIdle() (*TransferFrame, error) {
-	frame, err := mc.mux.Next()
+	frame, err := mc.GetNextFrame()
 	if err == nil {
 		return frame, nil
 	}
@@ -158,7 +182,41 @@ func (mc *MasterChannel) GetNextFrameOrIdle() (*TransferFrame, error) {
 	if mc.config.FrameLength == 0 {
 		return nil, sdl.ErrNoFramesAvailable
 	}
-	return NewIdleFrame(mc.scid, 63, mc.config)
+	idle, err := NewIdleFrame(mc.scid, 63, mc.config)
+	if err != nil {
+		return nil, err
+	}
+	mc.insertOCF(idle)
+	return idle, nil
+}
+
+// OCFError returns the problem met by the last OCF insertion, or nil if
+// the provider's report was inserted. A report that is not OCFSize bytes
+// gives ErrInvalidOCFLength, and the last valid report is inserted
+// instead. A frame whose FECF cannot be recomputed keeps its previous OCF.
+func (mc *MasterChannel) OCFError() error { return mc.ocfErr }
+
+// insertOCF overwrites the frame's OCF with the provider's report and
+// recomputes the FECF. It never fails the frame; see OCFError.
+func (mc *MasterChannel) insertOCF(frame *TransferFrame) {
+	if mc.ocf == nil || len(frame.OCF) != OCFSize {
+		return
+	}
+	mc.ocfErr = nil
+	if ocf := mc.ocf(); len(ocf) == OCFSize {
+		mc.lastOCF = append([]byte(nil), ocf...)
+	} else {
+		mc.ocfErr = ErrInvalidOCFLength
+	}
+	if mc.lastOCF == nil {
+		return
+	}
+	prevOCF, prevFEC := frame.OCF, frame.FECF
+	frame.OCF = append([]byte(nil), mc.lastOCF...)
+	if err := recomputeFECF(frame); err != nil {
+		frame.OCF, frame.FECF = prevOCF, prevFEC
+		mc.ocfErr = err
+	}
 }
 
 // HasPendingFrames checks if any Virtual Channel has pending frames.
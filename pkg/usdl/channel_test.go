@@ -1,6 +1,8 @@
 package usdl_test
 
 import (
+	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/ravisuhag/astro/pkg/usdl"
@@ -98,6 +100,69 @@ func TestMasterChannel_GetNextFrameOrIdle(t *testing.T) {
 	}
 }
 
+func TestMasterChannel_OCFProvider(t *testing.T) {
+	config := usdl.ChannelConfig{FrameLength: 64, HasOCF: true, HasFECF: true}
+	mc := usdl.NewMasterChannel(100, config)
+	vc := usdl.NewVirtualChannel(1, 10)
+	mc.AddVirtualChannel(vc, 1)
+
+	report := []byte{0x01, 0x02, 0x03, 0x04}
+	mc.SetOCFProvider(func() []byte { return report })
+
+	frame, _ := usdl.NewTransferFrame(100, 1, 0, []byte{0x01}, usdl.WithOCF(make([]byte, 4)))
+	_ = vc.Add(frame)
+
+	for _, name := range []string{"data", "idle"} {
+		got, err := mc.GetNextFrameOrIdle()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, _ := got.Encode()
+		decoded, err := usdl.DecodeTransferFrameWithOCF(encoded, 2, 0)
+		if err != nil {
+			t.Fatalf("%s frame: FECF not recomputed: %v", name, err)
+		}
+		if !bytes.Equal(decoded.OCF, report) {
+			t.Errorf("%s frame OCF = %x, want %x", name, decoded.OCF, report)
+		}
+	}
+}
+
+func TestMasterChannel_OCFProviderInvalidLength(t *testing.T) {
+	config := usdl.ChannelConfig{FrameLength: 64, HasOCF: true, HasFECF: true}
+	mc := usdl.NewMasterChannel(100, config)
+	vc := usdl.NewVirtualChannel(1, 10)
+	mc.AddVirtualChannel(vc, 1)
+
+	good := []byte{0x01, 0x02, 0x03, 0x04}
+	report := good
+	mc.SetOCFProvider(func() []byte { return report })
+
+	if _, err := mc.GetNextFrameOrIdle(); err != nil || mc.OCFError() != nil {
+		t.Fatalf("GetNextFrameOrIdle() err = %v, OCFError() = %v", err, mc.OCFError())
+	}
+
+	// A malformed report must not cost the frame: the last good OCF is reused.
+	report = []byte{0xFF}
+	frame, _ := usdl.NewTransferFrame(100, 1, 0, []byte{0x01}, usdl.WithOCF(make([]byte, usdl.OCFSize)))
+	_ = vc.Add(frame)
+	got, err := mc.GetNextFrame()
+	if err != nil || got == nil {
+		t.Fatalf("GetNextFrame() = %v, %v; want the frame", got, err)
+	}
+	if !errors.Is(mc.OCFError(), usdl.ErrInvalidOCFLength) {
+		t.Errorf("OCFError() = %v, want ErrInvalidOCFLength", mc.OCFError())
+	}
+	encoded, _ := got.Encode()
+	decoded, err := usdl.DecodeTransferFrameWithOCF(encoded, 2, 0)
+	if err != nil {
+		t.Fatalf("FECF not recomputed: %v", err)
+	}
+	if !bytes.Equal(decoded.OCF, good) {
+		t.Errorf("OCF = %x, want last good report %x", decoded.OCF, good)
+	}
+}
+
 func TestFrameGapDetector(t *testing.T) {
 	det := usdl.NewFrameGapDetector()
 
@@ -259,6 +259,9 @@ const FECSize16 = 2
 // FECSize32 is the size of a 32-bit Frame Error Control field.
 const FECSize32 = 4
 
+// OCFSize is the size of the Operational Control Field in bytes.
+const OCFSize = 4
+
 // TransferFrame represents a USLP Transfer Frame per CCSDS 732.1-B-2.
 type TransferFrame struct {
 	Header          PrimaryHeader
@@ -424,7 +427,7 @@ func (f *TransferFrame) encodeWithoutFECF() ([]byte, error) {
 	buf = append(buf, f.DataField...)
 
 	if len(f.OCF) > 0 {
-		if len(f.OCF) != 4 {
+		if len(f.OCF) != OCFSize {
 			return nil, ErrInvalidOCFLength
 		}
 		buf = append(buf, f.OCF...)
@@ -531,11 +534,11 @@ func DecodeTransferFrameWithOCF(data []byte, fecSize int, insertZoneLen int) (*T
 	}
 
 	// Extract OCF from the end of the data field
-	if len(frame.DataField) < 4 {
+	if len(frame.DataField) < OCFSize {
 		return nil, ErrDataTooShort
 	}
-	ocfStart := len(frame.DataField) - 4
-	frame.OCF = make([]byte, 4)
+	ocfStart := len(frame.DataField) - OCFSize
+	frame.OCF = make([]byte, OCFSize)
 	copy(frame.OCF, frame.DataField[ocfStart:])
 	frame.DataField = frame.DataField[:ocfStart]
 
@@ -563,7 +566,7 @@ func NewIdleFrame(scid uint16, vcid uint8, config ChannelConfig) (*TransferFrame
 		WithEndOfFPH(),
 	}
 	if config.HasOCF {
-		opts = append(opts, WithOCF(make([]byte, 4)))
+		opts = append(opts, WithOCF(make([]byte, OCFSize)))
 	}
 	if config.UseCRC32 {
 		opts = append(opts, WithCRC32())